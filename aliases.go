@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"mcp-x11-controller/x11"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// windowArgs are the tool arguments that take a window ID or, with
+// resolveWindowAliases, an alias; window_ids takes a list of them
+var windowArgs = []string{"window_id", "window_ids", "id", "id_a", "id_b"}
+
+// nestedWindowArgs are the tool arguments holding a list of objects whose
+// window arguments are resolved too, like x11_verify's assertions
+var nestedWindowArgs = []string{"assertions"}
+
+// windowAliases are the names given to windows with x11_alias_window. They
+// last as long as the server and follow their window across application
+// restarts.
+type windowAliases struct {
+	mu     sync.Mutex
+	byName map[string]x11.WindowIdentity
+}
+
+var aliases = newWindowAliases()
+
+// newWindowAliases returns an empty alias table
+func newWindowAliases() *windowAliases {
+	return &windowAliases{byName: make(map[string]x11.WindowIdentity)}
+}
+
+// validAliasName checks that name can't be mistaken for a window ID
+func validAliasName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("alias name is empty")
+	}
+	if _, err := strconv.ParseUint(name, 0, 32); err == nil {
+		return fmt.Errorf("alias %q looks like a window ID", name)
+	}
+	return nil
+}
+
+// set names the window of id, replacing an earlier alias of the same name.
+// It reports whether one was replaced.
+func (a *windowAliases) set(name string, id x11.WindowIdentity) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, replaced := a.byName[name]
+	a.byName[name] = id
+	return replaced
+}
+
+// get returns the identity last recorded for name
+func (a *windowAliases) get(name string) (x11.WindowIdentity, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	id, ok := a.byName[name]
+	return id, ok
+}
+
+// names returns the aliases in order
+func (a *windowAliases) names() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Sorted(maps.Keys(a.byName))
+}
+
+// resolve returns the window of alias name, finding it again by class and
+// title if its ID changed. moved is the ID it had before, if it changed.
+func (a *windowAliases) resolve(ctx context.Context, name string) (win, moved x.Window, err error) {
+	id, ok := a.get(name)
+	if !ok {
+		if names := a.names(); len(names) > 0 {
+			return 0, 0, fmt.Errorf("unknown window alias %q (known: %s)", name, strings.Join(names, ", "))
+		}
+		return 0, 0, fmt.Errorf("unknown window alias %q; name windows with x11_alias_window", name)
+	}
+	found, err := client.ResolveWindow(ctx, id)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to resolve window alias %q: %w", name, err)
+	}
+	a.mu.Lock()
+	if current, ok := a.byName[name]; ok && current.Window == id.Window {
+		a.byName[name] = found
+	}
+	a.mu.Unlock()
+	if found.Window != id.Window {
+		slog.Info("window alias moved to a new window", "alias", name, "was", fmt.Sprintf("0x%x", uint32(id.Window)), "now", fmt.Sprintf("0x%x", uint32(found.Window)))
+		moved = id.Window
+	}
+	return found.Window, moved, nil
+}
+
+// aliasMove is an alias whose window got a new ID
+type aliasMove struct {
+	name     string
+	was, now x.Window
+}
+
+// resolveWindowArg replaces a window argument given as a string, either an
+// alias or a window ID such as "0x1a00003", by the window ID
+func resolveWindowArg(ctx context.Context, raw json.RawMessage, moves *[]aliasMove) (json.RawMessage, error) {
+	var ref string
+	if json.Unmarshal(raw, &ref) != nil {
+		return raw, nil
+	}
+	if id, err := strconv.ParseUint(ref, 0, 32); err == nil {
+		return json.Marshal(uint32(id))
+	}
+	win, moved, err := aliases.resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if moved != 0 {
+		*moves = append(*moves, aliasMove{name: ref, was: moved, now: win})
+	}
+	return json.Marshal(uint32(win))
+}
+
+// resolveWindowArgs rewrites the window arguments of a tool call that name
+// windows by alias to their window IDs, within nestedWindowArgs lists too
+func resolveWindowArgs(ctx context.Context, args json.RawMessage) (json.RawMessage, []aliasMove, error) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(args, &fields) != nil {
+		return args, nil, nil
+	}
+	var moves []aliasMove
+	changed := false
+	for _, key := range nestedWindowArgs {
+		var list []json.RawMessage
+		if json.Unmarshal(fields[key], &list) != nil {
+			continue
+		}
+		for i, item := range list {
+			resolved, itemMoves, err := resolveWindowArgs(ctx, item)
+			if err != nil {
+				return nil, nil, err
+			}
+			list[i] = resolved
+			moves = append(moves, itemMoves...)
+		}
+		if raw, _ := json.Marshal(list); string(raw) != string(fields[key]) {
+			fields[key] = raw
+			changed = true
+		}
+	}
+	for _, key := range windowArgs {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		var list []json.RawMessage
+		if json.Unmarshal(raw, &list) == nil {
+			for i, item := range list {
+				resolved, err := resolveWindowArg(ctx, item, &moves)
+				if err != nil {
+					return nil, nil, err
+				}
+				list[i] = resolved
+			}
+			raw, _ = json.Marshal(list)
+		} else {
+			resolved, err := resolveWindowArg(ctx, raw, &moves)
+			if err != nil {
+				return nil, nil, err
+			}
+			raw = resolved
+		}
+		if string(raw) != string(fields[key]) {
+			fields[key] = raw
+			changed = true
+		}
+	}
+	if !changed {
+		return args, nil, nil
+	}
+	rewritten, err := json.Marshal(fields)
+	return rewritten, moves, err
+}
+
+// aliasSchemas returns the tools with their window arguments advertised as
+// taking an alias as well as an ID. The tools are copied, not changed.
+func aliasSchemas(tools []*mcp.Tool) []*mcp.Tool {
+	out := make([]*mcp.Tool, len(tools))
+	for i, tool := range tools {
+		out[i] = tool
+		if tool.InputSchema == nil {
+			continue
+		}
+		if schema, ok := aliasSchema(tool.InputSchema); ok {
+			copied := *tool
+			copied.InputSchema = schema
+			out[i] = &copied
+		}
+	}
+	return out
+}
+
+// aliasSchema returns a copy of an object schema with its window arguments,
+// and those of the objects in its nestedWindowArgs lists, taking an alias
+// as well as an ID. ok is false if it has none.
+func aliasSchema(s *jsonschema.Schema) (*jsonschema.Schema, bool) {
+	nested := make(map[string]*jsonschema.Schema)
+	for _, key := range nestedWindowArgs {
+		if prop := s.Properties[key]; prop != nil && prop.Items != nil {
+			if items, ok := aliasSchema(prop.Items); ok {
+				copied := *prop
+				copied.Items = items
+				nested[key] = &copied
+			}
+		}
+	}
+	if len(nested) == 0 && !slices.ContainsFunc(windowArgs, func(key string) bool { return s.Properties[key] != nil }) {
+		return nil, false
+	}
+
+	schema := *s
+	schema.Properties = maps.Clone(schema.Properties)
+	maps.Copy(schema.Properties, nested)
+	for _, key := range windowArgs {
+		prop, ok := schema.Properties[key]
+		if !ok {
+			continue
+		}
+		copied := *prop
+		target := &copied // The schema of one window, the items for a list
+		if copied.Items != nil {
+			items := *copied.Items
+			copied.Items = &items
+			target = &items
+		}
+		target.Type = ""
+		target.Types = []string{"integer", "string"}
+		schema.Properties[key] = &copied
+	}
+	return &schema, true
+}
+
+// resolveWindowAliases is receiving middleware that lets every window
+// argument name windows by their x11_alias_window alias, and says so in the
+// tool list. A result whose alias had to be found again under a new ID notes
+// the move.
+func resolveWindowAliases(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if method == "tools/list" {
+			result, err := next(ctx, session, method, params)
+			if list, ok := result.(*mcp.ListToolsResult); ok && err == nil {
+				copied := *list
+				copied.Tools = aliasSchemas(list.Tools)
+				return &copied, nil
+			}
+			return result, err
+		}
+		call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok || client == nil {
+			return next(ctx, session, method, params)
+		}
+
+		args, moves, err := resolveWindowArgs(ctx, call.Arguments)
+		if err != nil {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}, IsError: true}, nil
+		}
+		call.Arguments = args
+		result, err := next(ctx, session, method, params)
+		res, ok := result.(*mcp.CallToolResult)
+		if err != nil || !ok || len(moves) == 0 {
+			return result, err
+		}
+		for _, m := range moves {
+			res.Content = append(res.Content, &mcp.TextContent{
+				Text: fmt.Sprintf("Window alias %q was window 0x%x, which is gone; it now refers to its replacement 0x%x", m.name, uint32(m.was), uint32(m.now)),
+			})
+		}
+		return result, err
+	}
+}
+
+// aliasText describes a new alias
+func aliasText(name string, id x11.WindowIdentity, replaced bool) string {
+	text := fmt.Sprintf("Alias %q now refers to window 0x%x (class %s", name, uint32(id.Window), id.Class)
+	if id.Title != "" {
+		text += fmt.Sprintf(", title %q", id.Title)
+	}
+	text += "); use it as a window ID in any tool"
+	if replaced {
+		text += ", replacing the alias's previous window"
+	}
+	return text
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestValidAliasName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"editor", false},
+		{"main browser", false},
+		{"", true},
+		{"  ", true},
+		{"12345", true},
+		{"0x1a00003", true},
+	}
+	for _, tt := range tests {
+		if err := validAliasName(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("validAliasName(%q) = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveWindowArgs(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    string
+		wantErr string
+	}{
+		{`{"window_id":42,"x":1}`, `{"window_id":42,"x":1}`, ""},
+		{`{"window_id":"0x2a","x":1}`, `{"window_id":42,"x":1}`, ""},
+		{`{"window_ids":["0x10",17]}`, `{"window_ids":[16,17]}`, ""},
+		{`{"id_a":"1","id_b":"2"}`, `{"id_a":1,"id_b":2}`, ""},
+		{`{"text":"0x10"}`, `{"text":"0x10"}`, ""},
+		{`{"assertions":[{"type":"title_matches","window_id":"0x2a"},{"type":"pixel_color"}]}`, `{"assertions":[{"type":"title_matches","window_id":42},{"type":"pixel_color"}]}`, ""},
+		{`{"assertions":[{"window_id":"editor"}]}`, "", `unknown window alias "editor"`},
+		{`{"window_id":"editor"}`, "", `unknown window alias "editor"`},
+	}
+	for _, tt := range tests {
+		got, moves, err := resolveWindowArgs(context.Background(), json.RawMessage(tt.args))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("resolveWindowArgs(%s) = %v, want error %q", tt.args, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || string(got) != tt.want || len(moves) != 0 {
+			t.Errorf("resolveWindowArgs(%s) = %s, %v, %v, want %s", tt.args, got, moves, err, tt.want)
+		}
+	}
+}
+
+func TestAliasSchemas(t *testing.T) {
+	schema, err := jsonschema.For[struct {
+		WindowID  uint32   `json:"window_id"`
+		WindowIDs []uint32 `json:"window_ids"`
+		X         int      `json:"x"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := []*mcp.Tool{{Name: "with_window", InputSchema: schema}, {Name: "without"}}
+
+	got := aliasSchemas(tools)
+	props := got[0].InputSchema.Properties
+	if types := props["window_id"].Types; len(types) != 2 || types[1] != "string" {
+		t.Errorf("window_id types = %v, want integer and string", types)
+	}
+	if types := props["window_ids"].Items.Types; len(types) != 2 || props["window_ids"].Type != "array" {
+		t.Errorf("window_ids = %+v, want an array of integers and strings", props["window_ids"])
+	}
+	if props["x"].Type != "integer" {
+		t.Errorf("x type = %q, want it untouched", props["x"].Type)
+	}
+	if got[1] != tools[1] {
+		t.Error("tool without window arguments was copied")
+	}
+
+	// Window arguments of nested lists take aliases too
+	nested, err := jsonschema.For[struct {
+		Assertions []struct {
+			Type     string `json:"type"`
+			WindowID uint32 `json:"window_id"`
+		} `json:"assertions"`
+	}]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = aliasSchemas([]*mcp.Tool{{Name: "x11_verify", InputSchema: nested}})
+	if types := got[0].InputSchema.Properties["assertions"].Items.Properties["window_id"].Types; len(types) != 2 {
+		t.Errorf("assertions[].window_id types = %v, want integer and string", types)
+	}
+	if nested.Properties["assertions"].Items.Properties["window_id"].Type != "integer" {
+		t.Error("aliasSchemas changed the registered nested schema")
+	}
+	if schema.Properties["window_id"].Type != "integer" || schema.Properties["window_ids"].Items.Type != "integer" {
+		t.Error("aliasSchemas changed the registered schema")
+	}
+}
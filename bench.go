@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"mcp-x11-controller/x11"
+	"os"
+	"regexp"
+	"time"
+)
+
+// benchResolutions are measured when bench is run without arguments
+var benchResolutions = []string{"1024x768", "1920x1080", "2560x1440"}
+
+// benchEncodings are the PNG compression levels compared by bench
+var benchEncodings = []string{"fast", "default", "best", "none"}
+
+// benchDuration is how long each measurement runs
+const benchDuration = 2 * time.Second
+
+// benchKeys is typed repeatedly to measure keystroke throughput
+const benchKeys = "abcdefghij"
+
+var resolutionPattern = regexp.MustCompile(`^[0-9]+x[0-9]+$`)
+
+// measure calls fn repeatedly for about d and returns the calls per second
+func measure(d time.Duration, fn func() error) (float64, error) {
+	start := time.Now()
+	calls := 0
+	for time.Since(start) < d {
+		if err := fn(); err != nil {
+			return 0, err
+		}
+		calls++
+	}
+	return float64(calls) / time.Since(start).Seconds(), nil
+}
+
+// measurePipeline captures screenshots back to back while the encode
+// workers encode the ones before, as concurrent tool calls do, and returns
+// the screenshots captured and encoded per second
+func measurePipeline(ctx context.Context, c *x11.Client, d time.Duration) (float64, error) {
+	type inFlight struct {
+		img image.Image
+		png *x11.PendingPNG
+	}
+	var pending []inFlight
+	finish := func() error {
+		f := pending[0]
+		pending = pending[1:]
+		_, err := f.png.Wait()
+		c.RecycleScreenshot(f.img)
+		return err
+	}
+
+	start := time.Now()
+	shots := 0
+	var err error
+	for time.Since(start) < d && err == nil {
+		var img image.Image
+		if img, err = c.ScreenshotContext(ctx); err != nil {
+			break
+		}
+		pending = append(pending, inFlight{img, c.EncodePNGAsync(img)})
+		shots++
+		if len(pending) >= x11.EncodeWorkers() {
+			err = finish()
+		}
+	}
+	for len(pending) > 0 {
+		if ferr := finish(); err == nil {
+			err = ferr
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return float64(shots) / time.Since(start).Seconds(), nil
+}
+
+// runBench measures capture, encoding and input throughput on temporary
+// Xvfb displays and returns the exit code
+func runBench(opts x11.ConnectOptions, resolutions []string, out io.Writer) int {
+	if len(resolutions) == 0 {
+		resolutions = benchResolutions
+	}
+	for _, res := range resolutions {
+		if !resolutionPattern.MatchString(res) {
+			fmt.Fprintf(os.Stderr, "Invalid resolution %q, expected WIDTHxHEIGHT\n", res)
+			return 2
+		}
+	}
+
+	// Measure on a fresh, idle display so results don't depend on the desktop
+	os.Unsetenv("DISPLAY")
+	opts.Display = ""
+	opts.StartXvfb = true
+	opts.StartWM = false
+
+	fmt.Fprintf(out, "%-10s  %-12s  %10s  %10s  %10s  %11s  %10s\n", "resolution", "encoding", "capture/s", "encode/s", "serial/s", "pipelined/s", "KiB/shot")
+	var keysPerSec float64
+	for i, res := range resolutions {
+		opts.Resolution = res
+		rate, err := benchResolution(opts, i == 0, out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: benchmark at %s failed: %v\n", res, err)
+			return 1
+		}
+		if i == 0 {
+			keysPerSec = rate
+		}
+	}
+	fmt.Fprintf(out, "\nkeystrokes/s: %.0f\n", keysPerSec)
+	fmt.Fprintf(out, "encode workers: %d\n", x11.EncodeWorkers())
+	return 0
+}
+
+// benchResolution runs all measurements on one display. Keystrokes are only
+// measured if withInput is set, since they don't depend on the resolution.
+func benchResolution(opts x11.ConnectOptions, withInput bool, out io.Writer) (float64, error) {
+	c, err := x11.ConnectWithOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	captureRate, err := measure(benchDuration, func() error {
+		img, err := c.ScreenshotContext(ctx)
+		if err == nil {
+			c.RecycleScreenshot(img)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	img, err := c.ScreenshotContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range benchEncodings {
+		level, _ := x11.ParsePNGCompression(name)
+		c.SetPNGCompression(level)
+
+		var size int
+		encodeRate, err := measure(benchDuration, func() error {
+			data, err := c.EncodePNG(img)
+			size = len(data)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		serialRate, err := measure(benchDuration, func() error {
+			shot, err := c.ScreenshotContext(ctx)
+			if err != nil {
+				return err
+			}
+			defer c.RecycleScreenshot(shot)
+			_, err = c.EncodePNG(shot)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		pipelinedRate, err := measurePipeline(ctx, c, benchDuration)
+		if err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(out, "%-10s  %-12s  %10.1f  %10.1f  %10.1f  %11.1f  %10d\n", opts.Resolution, name, captureRate, encodeRate, serialRate, pipelinedRate, size/1024)
+	}
+
+	if !withInput {
+		return 0, nil
+	}
+	typeRate, err := measure(benchDuration, func() error {
+		return c.TypeContext(ctx, benchKeys)
+	})
+	return typeRate * float64(len(benchKeys)), err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"mcp-x11-controller/x11"
+	"testing"
+	"time"
+)
+
+func TestMeasure(t *testing.T) {
+	calls := 0
+	rate, err := measure(50*time.Millisecond, func() error {
+		calls++
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("measure() error = %v", err)
+	}
+	if calls == 0 || rate <= 0 || rate > 200 {
+		t.Errorf("measure() = %.1f/s after %d calls, want roughly 200/s or less", rate, calls)
+	}
+
+	failure := errors.New("broken")
+	if _, err := measure(time.Second, func() error { return failure }); err != failure {
+		t.Errorf("measure() error = %v, want %v", err, failure)
+	}
+}
+
+func TestRunBenchInvalidResolution(t *testing.T) {
+	var out bytes.Buffer
+	if code := runBench(x11.ConnectOptions{}, []string{"big"}, &out); code != 2 {
+		t.Errorf("runBench() = %d, want 2", code)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mcp-x11-controller/x11"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cliUsage describes the one-shot subcommands
+const cliUsage = `Commands (run a single action against DISPLAY and exit):
+  screenshot FILE      Save a PNG screenshot to FILE ("-" for stdout)
+  click X Y [BUTTON]   Move the mouse to X,Y and click (button: number or name like back, default 1)
+  type TEXT            Type TEXT
+  key KEY|COMBO        Press a key like Enter or a combo like ctrl+c
+  windows              List visible windows
+  tree                 Print the i3 window tree (requires i3)
+  doctor               Check the environment on a temporary display
+  bench [WxH...]       Measure capture, encoding and typing speed on temporary displays`
+
+// cliCommands are the subcommand names accepted by runCommand
+var cliCommands = map[string]bool{
+	"screenshot": true,
+	"click":      true,
+	"type":       true,
+	"key":        true,
+	"windows":    true,
+	"tree":       true,
+}
+
+// runCLI connects to the display, runs one subcommand and returns the exit code
+func runCLI(opts x11.ConnectOptions, args []string) int {
+	if args[0] == "doctor" {
+		return runDoctor(opts, os.Stdout)
+	}
+	if args[0] == "bench" {
+		return runBench(opts, args[1:], os.Stdout)
+	}
+	if !cliCommands[args[0]] {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n%s\n", args[0], cliUsage)
+		return 2
+	}
+
+	// One-shot commands act on an existing display and never start anything
+	opts.StartXvfb = false
+	opts.StartWM = false
+
+	c, err := x11.ConnectWithOptions(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to X11: %v\n", err)
+		return 1
+	}
+	defer c.Close()
+
+	if err := runCommand(context.Background(), c, args, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runCommand executes a single command and writes its result to out
+func runCommand(ctx context.Context, c *x11.Client, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no command given")
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "screenshot", "shot":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: screenshot FILE")
+		}
+		pngData, err := c.ScreenshotPNGContext(ctx)
+		if err != nil {
+			return err
+		}
+		if args[0] == "-" {
+			_, err = out.Write(pngData)
+			return err
+		}
+		if err := os.WriteFile(args[0], pngData, 0644); err != nil {
+			return fmt.Errorf("failed to write screenshot: %w", err)
+		}
+		fmt.Fprintf(out, "Saved screenshot to %s\n", args[0])
+
+	case "click":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: click X Y [BUTTON]")
+		}
+		coords := make([]float64, 2)
+		for i, arg := range args[:2] {
+			v, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return fmt.Errorf("invalid coordinate %q", arg)
+			}
+			coords[i] = v
+		}
+		button := 1
+		if len(args) == 3 {
+			b, err := x11.ParseButton(args[2])
+			if err != nil {
+				return err
+			}
+			button = b
+		}
+
+		px, py, err := c.ValidatePoint(coords[0], coords[1])
+		if err != nil {
+			return err
+		}
+		if err := c.MouseMoveContext(ctx, px, py); err != nil {
+			return err
+		}
+		if err := c.MouseClickContext(ctx, button); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Clicked at (%d, %d) with button %d\n", px, py, button)
+
+	case "type":
+		if len(args) == 0 {
+			return fmt.Errorf("usage: type TEXT")
+		}
+		text := strings.Join(args, " ")
+		if err := c.TypeContext(ctx, text); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Typed: %s\n", text)
+
+	case "key":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: key KEY|COMBO")
+		}
+		var err error
+		if strings.Contains(args[0], "+") {
+			err = c.KeyComboContext(ctx, args[0])
+		} else {
+			err = c.KeyPressContext(ctx, args[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Pressed: %s\n", args[0])
+
+	case "windows":
+		windows, err := c.ListWindowsWithDetailsContext(ctx)
+		if err != nil {
+			return err
+		}
+		for _, win := range windows {
+			fmt.Fprintf(out, "0x%08x  %-20s  %-16s  %s\n", uint32(win.ID), win.Class, win.Geometry(), win.Title)
+		}
+
+	case "tree":
+		if !c.I3Enabled() {
+			return fmt.Errorf("i3 is not connected")
+		}
+		treeJSON, err := c.I3GetTreeContext(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, treeJSON)
+
+	default:
+		return fmt.Errorf("unknown command: %s", cmd)
+	}
+	return nil
+}
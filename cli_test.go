@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunCommandUsageErrors(t *testing.T) {
+	// All of these fail argument checks before touching the display
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "no command given"},
+		{[]string{"screenshot"}, "usage: screenshot FILE"},
+		{[]string{"click", "10"}, "usage: click X Y [BUTTON]"},
+		{[]string{"click", "ten", "20"}, "invalid coordinate"},
+		{[]string{"click", "10", "20", "sideways"}, "invalid button"},
+		{[]string{"type"}, "usage: type TEXT"},
+		{[]string{"key"}, "usage: key KEY|COMBO"},
+		{[]string{"dance"}, "unknown command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			var out bytes.Buffer
+			err := runCommand(context.Background(), nil, tt.args, &out)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runCommand(%q) error = %v, want %q", tt.args, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCLICommandsMatchUsage(t *testing.T) {
+	for name := range cliCommands {
+		if !strings.Contains(cliUsage, "  "+name+" ") {
+			t.Errorf("command %s is missing from the usage text", name)
+		}
+	}
+}
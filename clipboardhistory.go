@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"mcp-x11-controller/x11"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxClipboardHistory is how many copies the clipboard history keeps
+	maxClipboardHistory = 20
+	// maxClipboardText bounds the text kept of each copy
+	maxClipboardText = 64 << 10
+)
+
+// clipboardCopy is a clipboard history entry
+type clipboardCopy struct {
+	x11.ClipboardEntry
+	Bytes     int  // Length of the whole text
+	Truncated bool // Text was cut to maxClipboardText
+}
+
+// clipboardHistory keeps the texts recently copied to the clipboard, so an
+// agent can get back what was copied a few steps ago
+type clipboardHistory struct {
+	mu      sync.Mutex
+	copies  []clipboardCopy // Oldest first
+	watched bool
+	err     error // Why the clipboard isn't watched
+}
+
+var clipboardLog clipboardHistory
+
+// watching records whether the clipboard is watched, and why not
+func (h *clipboardHistory) watching(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watched, h.err = err == nil, err
+}
+
+// add records a copy, unless it is the same text as the last one
+func (h *clipboardHistory) add(entry x11.ClipboardEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.copies); n > 0 && h.copies[n-1].Bytes == len(entry.Text) && h.copies[n-1].Text == truncateText(entry.Text) {
+		return
+	}
+	cp := clipboardCopy{ClipboardEntry: entry, Bytes: len(entry.Text)}
+	if text := truncateText(entry.Text); text != entry.Text {
+		cp.Text, cp.Truncated = text, true
+	}
+	h.copies = append(h.copies, cp)
+	if len(h.copies) > maxClipboardHistory {
+		h.copies = h.copies[len(h.copies)-maxClipboardHistory:]
+	}
+}
+
+// recent returns up to limit copies, newest first, all of them for a limit
+// of 0
+func (h *clipboardHistory) recent(limit int) ([]clipboardCopy, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.watched {
+		if h.err != nil {
+			return nil, fmt.Errorf("the clipboard history is unavailable: %w", h.err)
+		}
+		return nil, fmt.Errorf("the clipboard history is unavailable")
+	}
+	n := len(h.copies)
+	if limit > 0 {
+		n = min(n, limit)
+	}
+	copies := make([]clipboardCopy, n)
+	for i := range copies {
+		copies[i] = h.copies[len(h.copies)-1-i]
+	}
+	return copies, nil
+}
+
+// truncateText cuts text to maxClipboardText bytes without splitting a
+// character
+func truncateText(text string) string {
+	if len(text) <= maxClipboardText {
+		return text
+	}
+	cut := maxClipboardText
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// clipboardHistoryText lists copies newest first with their text
+func clipboardHistoryText(copies []clipboardCopy, now time.Time) string {
+	if len(copies) == 0 {
+		return "Nothing was copied to the clipboard since the server started"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d clipboard entries, newest first:", len(copies))
+	for i, cp := range copies {
+		fmt.Fprintf(&b, "\n\n%d. copied %s ago", i+1, now.Sub(cp.Time).Round(time.Second))
+		if cp.Class != "" {
+			fmt.Fprintf(&b, " from %s", cp.Class)
+		}
+		fmt.Fprintf(&b, " (%d bytes", cp.Bytes)
+		if cp.Truncated {
+			fmt.Fprintf(&b, ", first %d shown", len(cp.Text))
+		}
+		b.WriteString("):\n")
+		b.WriteString(cp.Text)
+	}
+	return b.String()
+}
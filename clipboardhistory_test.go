@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClipboardHistory(t *testing.T) {
+	var h clipboardHistory
+	if _, err := h.recent(0); err == nil {
+		t.Error("expected an error before the clipboard is watched")
+	}
+	h.watching(errors.New("the XFIXES extension is not available"))
+	if _, err := h.recent(0); err == nil || !strings.Contains(err.Error(), "XFIXES") {
+		t.Errorf("recent() = %v, want the reason it is unavailable", err)
+	}
+	h.watching(nil)
+
+	start := time.Now()
+	for i := 0; i < maxClipboardHistory+2; i++ {
+		h.add(x11.ClipboardEntry{Time: start.Add(time.Duration(i) * time.Second), Text: fmt.Sprintf("copy %d", i)})
+	}
+	// Copying the same text again is not a new entry
+	h.add(x11.ClipboardEntry{Time: start.Add(time.Hour), Text: fmt.Sprintf("copy %d", maxClipboardHistory+1)})
+
+	copies, err := h.recent(0)
+	if err != nil {
+		t.Fatalf("recent() failed: %v", err)
+	}
+	if len(copies) != maxClipboardHistory || copies[0].Text != fmt.Sprintf("copy %d", maxClipboardHistory+1) || copies[len(copies)-1].Text != "copy 2" {
+		t.Errorf("recent() kept %d copies from %q to %q", len(copies), copies[0].Text, copies[len(copies)-1].Text)
+	}
+	if copies, _ := h.recent(2); len(copies) != 2 || copies[1].Text != fmt.Sprintf("copy %d", maxClipboardHistory) {
+		t.Errorf("recent(2) = %+v", copies)
+	}
+
+	// Long text is cut without splitting a character
+	long := strings.Repeat("x", maxClipboardText-1) + "é and more"
+	h.add(x11.ClipboardEntry{Text: long})
+	if copies, _ := h.recent(1); !copies[0].Truncated || copies[0].Bytes != len(long) || copies[0].Text != long[:maxClipboardText-1] {
+		t.Errorf("long copy kept %d of %d bytes, truncated %v", len(copies[0].Text), copies[0].Bytes, copies[0].Truncated)
+	}
+}
+
+func TestClipboardHistoryText(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	copies := []clipboardCopy{
+		{ClipboardEntry: x11.ClipboardEntry{Time: now.Add(-5 * time.Second), Text: "https://example.com", Class: "firefox"}, Bytes: 19},
+		{ClipboardEntry: x11.ClipboardEntry{Time: now.Add(-2 * time.Minute), Text: "draft"}, Bytes: 5},
+		{ClipboardEntry: x11.ClipboardEntry{Time: now.Add(-time.Hour), Text: "lo"}, Bytes: 70000, Truncated: true},
+	}
+	want := "3 clipboard entries, newest first:\n\n" +
+		"1. copied 5s ago from firefox (19 bytes):\nhttps://example.com\n\n" +
+		"2. copied 2m0s ago (5 bytes):\ndraft\n\n" +
+		"3. copied 1h0m0s ago (70000 bytes, first 2 shown):\nlo"
+	if got := clipboardHistoryText(copies, now); got != want {
+		t.Errorf("clipboardHistoryText() = %q, want %q", got, want)
+	}
+	if got := clipboardHistoryText(nil, now); !strings.HasPrefix(got, "Nothing was copied") {
+		t.Errorf("clipboardHistoryText(nil) = %q", got)
+	}
+}
@@ -0,0 +1,401 @@
+// Package config holds the settings of mcp-x11-controller. The binary fills
+// a Config from a JSON config file, MCP_X11_* environment variables and
+// command line flags; programs embedding the X11 layer can do the same or
+// fill one in directly and pass ConnectOptions to x11.ConnectWithOptions.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"mcp-x11-controller/x11"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the environment variables that set flags: the flag name
+// upper-cased with dashes as underscores, e.g. MCP_X11_WM_NAME for --wm-name
+const EnvPrefix = "MCP_X11_"
+
+// SecretEnvPrefix starts the environment variables holding secrets for
+// x11_type_secret, e.g. MCP_X11_SECRET_DB_PASSWORD for the secret db-password;
+// they set no flag
+const SecretEnvPrefix = EnvPrefix + "SECRET_"
+
+// Config holds every setting of the controller
+type Config struct {
+	// Display and window manager
+	Display         string   // X display to use; empty uses DISPLAY
+	Xvfb            bool     // Start Xvfb if there is no display
+	Resolution      string   // Resolution of a started Xvfb
+	NoWM            bool     // Don't start a window manager
+	WMName          string   // Window manager command, split with shell quoting rules
+	WMArgs          []string // Extra window manager arguments, passed unsplit
+	WMConfig        string   // Config file for the window manager
+	StartupPrograms []string // Programs started once connected
+	IsolatedHome    bool     // Give launched programs a throwaway HOME
+	LaunchTemplates string   // JSON file of named templates for x11_launch
+
+	// X11 behavior
+	XTimeout       time.Duration     // Timeout for blocking X server requests
+	I3Timeout      time.Duration     // Timeout for i3 IPC calls
+	PNGCompression string            // fast, default, best or none
+	PNGColors      string            // full, palette or gray
+	TypingProfile  string            // Default typing profile of x11_type_text
+	Motion         x11.MotionProfile // Pointer motion of drags
+	FixedPacing    bool              // Don't slow input down while the X server is slow to answer
+	XErrorLog      string            // X errors logged as warnings: unchecked, all or off
+	Observer       bool              // Screenshot-only mode without XTEST
+	RestoreHidden  bool              // Restore windows tools target instead of failing
+	Confine        string            // Pointer confinement, see x11.ParseConfinement
+	RedactRegions  []string          // Regions blacked out of every capture, as WIDTHxHEIGHT+X+Y
+	RedactClasses  []string          // Window classes blacked out of every capture
+	AbortHotkey    string            // Global hotkey that aborts all actions
+
+	// Server behavior
+	ActionDelay       time.Duration            // Wait after input actions before the result screenshot
+	EchoTypedText     bool                     // Repeat typed text in x11_type_text results
+	PointerTrail      bool                     // Draw recent injected pointer events into result screenshots
+	ScreenshotDelay   time.Duration            // Wait before screenshots of tools without a delay argument
+	ToolDelays        map[string]time.Duration // Per-tool overrides of both delays
+	MaxToolTimeout    time.Duration            // Bound of every tool call, 0 for none
+	HistorySize       int                      // Result screenshots kept for x11_get_screenshot
+	HistoryMaxMB      int                      // Memory budget of the screenshot history
+	MaxImageBytes     int                      // Largest PNG sent in a result before it is re-encoded smaller, 0 for no limit
+	Popups            string                   // notify, capture or off
+	WMBinding         string                   // warn, route or refuse
+	WindowsOfInterest []string                 // Window patterns whose thumbnails results carry
+	SecretsFile       string                   // NAME=VALUE file of secrets for x11_type_secret
+	REPL              bool                     // Read commands from stdin instead of serving MCP
+	LogLevel          string                   // debug, info, warn or error
+	LogFormat         string                   // text or json
+}
+
+// Default returns the configuration used when nothing is set
+func Default() Config {
+	return Config{
+		Xvfb:           true,
+		Resolution:     "1920x1080",
+		WMName:         "i3 -a",
+		XTimeout:       x11.DefaultRequestTimeout,
+		I3Timeout:      x11.DefaultI3Timeout,
+		PNGCompression: "fast",
+		PNGColors:      "full",
+		TypingProfile:  "instant",
+		Motion:         x11.DefaultMotionProfile,
+		ActionDelay:    100 * time.Millisecond,
+		EchoTypedText:  true,
+		ToolDelays:     map[string]time.Duration{},
+		MaxToolTimeout: 5 * time.Minute,
+		HistorySize:    20,
+		HistoryMaxMB:   64,
+		Popups:         "notify",
+		WMBinding:      "warn",
+		LogLevel:       "info",
+		LogFormat:      "text",
+		XErrorLog:      "unchecked",
+	}
+}
+
+// aliases maps the flag names of earlier versions to the current ones
+var aliases = map[string]string{
+	"wm":      "wm-name",
+	"program": "startup-program",
+}
+
+// RegisterFlags defines a flag on fs for every setting, with c's values as
+// defaults, along with --config and the legacy aliases
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	if c.ToolDelays == nil {
+		c.ToolDelays = map[string]time.Duration{}
+	}
+	fs.String("config", "", "JSON config file whose keys are flag names, read before the environment and the other flags (env: "+EnvPrefix+"CONFIG)")
+
+	fs.StringVar(&c.Display, "display", c.Display, "X display to connect to (default: DISPLAY)")
+	fs.BoolVar(&c.Xvfb, "xvfb", c.Xvfb, "Start Xvfb if no display is given or set in DISPLAY")
+	fs.StringVar(&c.Resolution, "resolution", c.Resolution, "Resolution of a started Xvfb")
+	fs.BoolVar(&c.NoWM, "no-wm", c.NoWM, "Disable window manager startup")
+	fs.StringVar(&c.WMName, "wm-name", c.WMName, "Window manager command to start, split with shell quoting rules")
+	fs.Var((*stringList)(&c.WMArgs), "wm-arg", "Extra window manager argument, passed as is without splitting (repeatable)")
+	fs.StringVar(&c.WMConfig, "wm-config", c.WMConfig, "Config file for the window manager, passed with its config option (e.g. -c for i3)")
+	fs.Var((*stringList)(&c.StartupPrograms), "startup-program", "Program to start once connected, with arguments split by shell quoting rules, e.g. \"xterm -e bash\" (repeatable)")
+	fs.BoolVar(&c.IsolatedHome, "isolated-home", c.IsolatedHome, "Give launched programs a throwaway HOME and XDG directories instead of the user's dotfiles")
+	fs.StringVar(&c.LaunchTemplates, "launch-templates", c.LaunchTemplates, "JSON file of named application templates (command, args, env, window_class, ready, timeout_ms) that x11_launch starts")
+
+	fs.DurationVar(&c.XTimeout, "x-timeout", c.XTimeout, "Timeout for blocking X server requests")
+	fs.DurationVar(&c.I3Timeout, "i3-timeout", c.I3Timeout, "Timeout for i3 IPC calls")
+	fs.StringVar(&c.PNGCompression, "png-compression", c.PNGCompression, "PNG compression for screenshots: fast, default, best or none")
+	fs.StringVar(&c.PNGColors, "png-colors", c.PNGColors, "PNG colors for screenshots: full, palette (8-bit, 256 colors) or gray")
+	fs.StringVar(&c.TypingProfile, "typing-profile", c.TypingProfile, "Default typing profile: "+strings.Join(x11.TypingProfileNames(), ", "))
+	fs.IntVar(&c.Motion.Step, "drag-step", c.Motion.Step, "Pixels between the pointer motion events of drags")
+	fs.IntVar(&c.Motion.Rate, "drag-rate", c.Motion.Rate, "Pointer motion events per second of drags at most, 0 for no limit")
+	fs.IntVar(&c.Motion.Batch, "drag-batch", c.Motion.Batch, "Motion events of drags sent before waiting for the X server")
+	fs.BoolVar(&c.FixedPacing, "fixed-pacing", c.FixedPacing, "Keep input at the configured pace instead of pausing before key and button presses while the X server is slow to answer")
+	fs.BoolVar(&c.Observer, "observer", c.Observer, "Screenshot-only mode: skip XTEST and register only observation tools")
+	fs.BoolVar(&c.RestoreHidden, "restore-hidden", c.RestoreHidden, "Restore minimized windows and switch workspaces when a tool targets a window that isn't shown, instead of failing")
+	fs.StringVar(&c.Confine, "confine", c.Confine, "Confine injected pointer motion to a WIDTHxHEIGHT+X+Y region or a window ID; clicks outside it are refused")
+	fs.Var((*stringList)(&c.RedactRegions), "redact-region", "Black out a WIDTHxHEIGHT+X+Y region of every screenshot (repeatable)")
+	fs.Var((*stringList)(&c.RedactClasses), "redact-class", "Black out the windows of a WM_CLASS, e.g. KeePassXC, in every screenshot (repeatable)")
+	fs.StringVar(&c.AbortHotkey, "abort-hotkey", c.AbortHotkey, "Global hotkey that aborts all actions and freezes input, e.g. ctrl+alt+Pause")
+
+	fs.DurationVar(&c.ActionDelay, "default-action-delay", c.ActionDelay, "Wait after an input action before the result screenshot")
+	fs.DurationVar(&c.ScreenshotDelay, "post-screenshot-delay", c.ScreenshotDelay, "Wait before the result screenshot of tools that take no delay argument (i3_cmd)")
+	fs.BoolVar(&c.EchoTypedText, "echo-typed-text", c.EchoTypedText, "Repeat the typed text in x11_type_text results; with false they only give its length")
+	fs.BoolVar(&c.PointerTrail, "pointer-trail", c.PointerTrail, "Draw the pointer path and clicks of the last 2 seconds into result screenshots, which show neither the cursor nor what was clicked")
+	fs.Var(toolDelays(c.ToolDelays), "tool-delay", "Per-tool delay overrides as tool=duration, e.g. x11_type_text=0s,x11_click_at=500ms (repeatable)")
+	fs.DurationVar(&c.MaxToolTimeout, "max-tool-timeout", c.MaxToolTimeout, "Longest a tool call may take, with or without its timeout_ms argument, before it fails with a timeout error (0 for no limit)")
+	fs.IntVar(&c.HistorySize, "history-size", c.HistorySize, "Number of result screenshots kept for x11_get_screenshot (0 disables)")
+	fs.IntVar(&c.HistoryMaxMB, "history-max-mb", c.HistoryMaxMB, "Memory budget of the screenshot history in MiB")
+	fs.IntVar(&c.MaxImageBytes, "max-image-bytes", c.MaxImageBytes, "Re-encode result images larger than this many bytes with fewer colors and then smaller, for MCP clients that cap message size (0 for no limit)")
+	fs.StringVar(&c.Popups, "popups", c.Popups, "What to do when a dialog or transient window appears: notify, capture or off")
+	fs.StringVar(&c.WMBinding, "wm-binding", c.WMBinding, "What x11_key_press does with keys bound by the window manager: warn, route or refuse")
+	fs.Var((*stringList)(&c.WindowsOfInterest), "window-of-interest", "Attach a thumbnail of windows whose class or title match to every action result, as class=REGEX, title=REGEX or REGEX for either (repeatable)")
+	fs.StringVar(&c.SecretsFile, "secrets-file", c.SecretsFile, "File of NAME=VALUE lines with the secrets x11_type_secret may type, read on every call; "+SecretEnvPrefix+"NAME variables are secrets too")
+	fs.BoolVar(&c.REPL, "repl", c.REPL, "Read commands from stdin instead of serving MCP, for debugging automations")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Log level: debug, info, warn or error")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "Log format on stderr: text or json")
+	fs.StringVar(&c.XErrorLog, "log-x-errors", c.XErrorLog, "X protocol errors logged as warnings: unchecked (those of requests nothing waits for; the others at debug level), all or off")
+
+	for alias, name := range aliases {
+		f := fs.Lookup(name)
+		fs.Var(f.Value, alias, "Alias of -"+name)
+	}
+}
+
+// Load fills c from the config file, the environment and the command line
+// args, each overriding the one before; repeatable flags add to the values
+// set before. The flags of c must not be registered on fs yet, while flags
+// of the caller's own must be.
+func (c *Config) Load(fs *flag.FlagSet, args, environ []string) error {
+	c.RegisterFlags(fs)
+	if path := configPath(fs, args, environ); path != "" {
+		if err := ApplyFile(fs, path); err != nil {
+			return err
+		}
+	}
+	if err := ApplyEnv(fs, environ); err != nil {
+		return err
+	}
+	return fs.Parse(args)
+}
+
+// ConnectOptions returns the options to connect to X11 with
+func (c *Config) ConnectOptions() x11.ConnectOptions {
+	return x11.ConnectOptions{
+		Display:        c.Display,
+		StartXvfb:      c.Xvfb,
+		Resolution:     c.Resolution,
+		StartWM:        !c.NoWM,
+		WMName:         c.WMName,
+		WMArgs:         c.WMArgs,
+		WMConfig:       c.WMConfig,
+		RequestTimeout: c.XTimeout,
+		I3Timeout:      c.I3Timeout,
+		PNGCompression: c.PNGCompression,
+		PNGColors:      c.PNGColors,
+		TypingProfile:  c.TypingProfile,
+		Motion:         c.Motion,
+		Observer:       c.Observer,
+		IsolatedHome:   c.IsolatedHome,
+		RestoreHidden:  c.RestoreHidden,
+		Confine:        c.Confine,
+		RedactRegions:  c.RedactRegions,
+		RedactClasses:  c.RedactClasses,
+		FixedPacing:    c.FixedPacing,
+		XErrorLog:      c.XErrorLog,
+	}
+}
+
+// ApplyFile sets the flags of fs from a JSON config file. Its keys are flag
+// names; arrays set a repeatable flag once per element and objects set
+// tool=duration flags once per key.
+func ApplyFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("config file %s: unknown setting %q", path, name)
+		}
+		values, err := flagValues(settings[name])
+		if err != nil {
+			return fmt.Errorf("config file %s: invalid %s: %w", path, name, err)
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("config file %s: invalid %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// flagValues converts a JSON value to the flag values it stands for
+func flagValues(raw json.RawMessage) ([]string, error) {
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case len(raw) > 0 && raw[0] == '[':
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, elem := range elems {
+			v, err := scalarValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case len(raw) > 0 && raw[0] == '{':
+		var pairs map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &pairs); err != nil {
+			return nil, err
+		}
+		var values []string
+		for key, elem := range pairs {
+			v, err := scalarValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, key+"="+v)
+		}
+		sort.Strings(values)
+		return values, nil
+	}
+	v, err := scalarValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{v}, nil
+}
+
+// scalarValue converts a JSON string, number or boolean to a flag value
+func scalarValue(raw json.RawMessage) (string, error) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case float64, bool:
+		return string(bytes.TrimSpace(raw)), nil
+	}
+	return "", fmt.Errorf("expected a string, number or boolean, got %s", raw)
+}
+
+// ApplyEnv sets the flags of fs from MCP_X11_* variables in environ.
+// Variables naming no flag are an error so typos don't go unnoticed.
+func ApplyEnv(fs *flag.FlagSet, environ []string) error {
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, EnvPrefix) || key == EnvPrefix+"CONFIG" || strings.HasPrefix(key, SecretEnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(key, EnvPrefix), "_", "-"))
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown environment variable %s", key)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// configPath returns the config file named by --config in args, which wins,
+// or MCP_X11_CONFIG in environ. Flags are scanned the way fs parses them so
+// a flag's value is never taken for a flag.
+func configPath(fs *flag.FlagSet, args, environ []string) string {
+	var path string
+	for _, kv := range environ {
+		if key, value, _ := strings.Cut(kv, "="); key == EnvPrefix+"CONFIG" {
+			path = value
+		}
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !hasValue && !isBoolFlag(fs.Lookup(name)) && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if name == "config" {
+			path = value
+		}
+	}
+	return path
+}
+
+// isBoolFlag returns true for flags that take no separate value
+func isBoolFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// toolDelays is a repeatable flag of tool=duration pairs
+type toolDelays map[string]time.Duration
+
+func (t toolDelays) String() string {
+	var pairs []string
+	for tool, delay := range t {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", tool, delay))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (t toolDelays) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		tool, raw, ok := strings.Cut(pair, "=")
+		if !ok || tool == "" {
+			return fmt.Errorf("expected tool=duration, got %q", pair)
+		}
+		delay, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid delay for %s: %w", tool, err)
+		}
+		if delay < 0 {
+			return fmt.Errorf("delay for %s cannot be negative", tool)
+		}
+		t[strings.TrimSpace(tool)] = delay
+	}
+	return nil
+}
+
+// stringList is a repeatable flag collecting its values in order
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// load runs Config.Load on a fresh flag set
+func load(t *testing.T, args, environ []string) (Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	c := Default()
+	err := c.Load(fs, args, environ)
+	return c, err
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	c, err := load(t, nil, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	opts := c.ConnectOptions()
+	if !opts.StartXvfb || !opts.StartWM || opts.WMName != "i3 -a" || opts.Resolution != "1920x1080" {
+		t.Errorf("unexpected default connect options: %+v", opts)
+	}
+	if c.ActionDelay != 100*time.Millisecond || c.Popups != "notify" || c.HistorySize != 20 {
+		t.Errorf("unexpected defaults: %+v", c)
+	}
+}
+
+func TestLoadLegacyAliases(t *testing.T) {
+	c, err := load(t, []string{"-program=xterm -e bash", "-wm=openbox", "-xvfb=false", "-display", ":0", "-resolution", "1024x768"}, nil)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.WMName != "openbox" {
+		t.Errorf("WMName = %q, want openbox", c.WMName)
+	}
+	if len(c.StartupPrograms) != 1 || c.StartupPrograms[0] != "xterm -e bash" {
+		t.Errorf("StartupPrograms = %q", c.StartupPrograms)
+	}
+	opts := c.ConnectOptions()
+	if opts.StartXvfb || opts.Display != ":0" || opts.Resolution != "1024x768" {
+		t.Errorf("unexpected connect options: %+v", opts)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"wm-name": "openbox",
+		"popups": "capture",
+		"history-size": 5,
+		"observer": true,
+		"x-timeout": "3s",
+		"startup-program": ["xterm", "xclock -digital"],
+		"redact-class": ["KeePassXC"],
+		"tool-delay": {"x11_type_text": "0s", "i3_cmd": "1s"}
+	}`)
+	env := []string{"HOME=/root", EnvPrefix + "CONFIG=" + path, EnvPrefix + "POPUPS=off", EnvPrefix + "HISTORY_SIZE=7", SecretEnvPrefix + "DB_PASSWORD=hunter2"}
+	c, err := load(t, []string{"-history-size", "9", "-startup-program", "xeyes", "screenshot", "-"}, env)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"file", c.WMName, "openbox"},
+		{"file bool", c.Observer, true},
+		{"file duration", c.XTimeout, 3 * time.Second},
+		{"env over file", c.Popups, "off"},
+		{"flag over env", c.HistorySize, 9},
+		{"file object", c.ToolDelays["i3_cmd"], time.Second},
+		{"repeatable flags add", len(c.StartupPrograms), 3},
+		{"list order", c.StartupPrograms[1], "xclock -digital"},
+		{"connect options", c.ConnectOptions().RedactClasses[0], "KeePassXC"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFlag(t *testing.T) {
+	path := writeConfig(t, `{"wm-name": "openbox"}`)
+	other := writeConfig(t, `{"wm-name": "fluxbox"}`)
+	for _, args := range [][]string{
+		{"-no-wm", "-wm-arg", "-config", "-config", path},
+		{"--config=" + path},
+	} {
+		c, err := load(t, args, []string{EnvPrefix + "CONFIG=" + other})
+		if err != nil {
+			t.Fatalf("Load(%q) failed: %v", args, err)
+		}
+		if c.WMName != "openbox" {
+			t.Errorf("Load(%q): WMName = %q, want the flag's config file to win", args, c.WMName)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		environ []string
+	}{
+		{"unknown file key", `{"wm_name": "openbox"}`, nil},
+		{"invalid file value", `{"history-size": "many"}`, nil},
+		{"nested list", `{"startup-program": [["xterm"]]}`, nil},
+		{"malformed file", `{"wm-name": `, nil},
+		{"unknown env", "", []string{EnvPrefix + "WM_NAM=openbox"}},
+		{"invalid env", "", []string{EnvPrefix + "X_TIMEOUT=soon"}},
+	}
+	for _, tt := range tests {
+		var args []string
+		if tt.file != "" {
+			args = []string{"-config", writeConfig(t, tt.file)}
+		}
+		if _, err := load(t, args, tt.environ); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestToolDelaysSet(t *testing.T) {
+	delays := toolDelays{}
+	if err := delays.Set("x11_type_text=0s,x11_click_at=500ms"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := delays.Set("i3_cmd=1s"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	want := toolDelays{
+		"x11_type_text": 0,
+		"x11_click_at":  500 * time.Millisecond,
+		"i3_cmd":        time.Second,
+	}
+	for tool, delay := range want {
+		if got, ok := delays[tool]; !ok || got != delay {
+			t.Errorf("delay for %s = %v, want %v", tool, got, delay)
+		}
+	}
+
+	for _, bad := range []string{"x11_click_at", "=1s", "x11_click_at=fast", "x11_click_at=-1s"} {
+		if err := (toolDelays{}).Set(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestStringList(t *testing.T) {
+	var l stringList
+	for _, v := range []string{"--startup", "xterm -e top"} {
+		if err := l.Set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(l) != 2 || l[1] != "xterm -e top" {
+		t.Errorf("stringList = %q, want the values unsplit in order", l)
+	}
+}
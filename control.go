@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultControlLease is how long x11_acquire_control holds the
+	// display without input by default
+	defaultControlLease = 60 * time.Second
+	// maxControlLease bounds the lease, so a crashed agent can't keep the
+	// display for long
+	maxControlLease = time.Hour
+)
+
+// controlExempt are input tools that work while another agent has control:
+// the emergency stop must always be available
+var controlExempt = []string{"x11_abort_all"}
+
+// controlLeases remembers the leases this server's sessions acquired, to
+// renew them on every input tool call
+type controlLeases struct {
+	mu     sync.Mutex
+	leases map[string]heldLease // By owner
+}
+
+// heldLease is what to renew a lease with
+type heldLease struct {
+	agent string
+	lease time.Duration
+}
+
+var controls = &controlLeases{leases: map[string]heldLease{}}
+
+// set records owner's lease
+func (l *controlLeases) set(owner, agent string, lease time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leases[owner] = heldLease{agent: agent, lease: lease}
+}
+
+// get returns owner's lease, false if it holds none
+func (l *controlLeases) get(owner string) (heldLease, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	held, ok := l.leases[owner]
+	return held, ok
+}
+
+// forget drops owner's lease
+func (l *controlLeases) forget(owner string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.leases, owner)
+}
+
+// owners returns the owners holding leases
+func (l *controlLeases) owners() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	owners := make([]string, 0, len(l.leases))
+	for owner := range l.leases {
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// controlOwner names a session uniquely among all servers on the display:
+// host and PID, and the session ID for transports with several sessions
+func controlOwner(session *mcp.ServerSession) string {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", host, os.Getpid())
+	if session != nil && session.ID() != "" {
+		owner += "/" + session.ID()
+	}
+	return owner
+}
+
+// controlLease converts lease_seconds of x11_acquire_control
+func controlLease(seconds int) (time.Duration, error) {
+	lease := time.Duration(seconds) * time.Second
+	switch {
+	case seconds < 0:
+		return 0, fmt.Errorf("lease_seconds cannot be negative")
+	case seconds == 0:
+		return defaultControlLease, nil
+	case lease > maxControlLease:
+		return 0, fmt.Errorf("lease_seconds is at most %d", int(maxControlLease.Seconds()))
+	}
+	return lease, nil
+}
+
+// controlText describes a lease acquired by x11_acquire_control
+func controlText(lease x11.ControlLease, renewed bool) string {
+	verb := "acquired"
+	if renewed {
+		verb = "renewed"
+	}
+	return fmt.Sprintf("Control of the display %s until %s; every input tool call renews it, x11_release_control gives it up",
+		verb, lease.Expires.Format("15:04:05"))
+}
+
+// enforceControl is receiving middleware that refuses input tool calls while
+// another agent controls the display, and renews the lease of a session
+// that controls it. Reading the lease failing doesn't block input, as the
+// lock is advisory.
+func enforceControl(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok || client == nil || !slices.Contains(inputTools, call.Name) || slices.Contains(controlExempt, call.Name) {
+			return next(ctx, session, method, params)
+		}
+
+		owner := controlOwner(session)
+		var err error
+		if held, ok := controls.get(owner); ok {
+			_, err = client.AcquireControl(ctx, owner, held.agent, held.lease)
+		} else {
+			err = client.CheckControl(ctx, owner)
+		}
+		var taken *x11.ControlHeldError
+		switch {
+		case errors.As(err, &taken):
+			controls.forget(owner)
+			text := err.Error() + "; wait for it to be released or to lapse, then call x11_acquire_control"
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}, IsError: true}, nil
+		case err != nil:
+			slog.WarnContext(ctx, "failed to check display control", "tool", call.Name, "err", err)
+		}
+		return next(ctx, session, method, params)
+	}
+}
+
+// releaseControls gives up the leases of this server's sessions on exit, so
+// other agents don't wait for them to lapse
+func releaseControls() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, owner := range controls.owners() {
+		if _, err := client.ReleaseControl(ctx, owner); err != nil {
+			slog.Warn("failed to release display control", "err", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"mcp-x11-controller/x11"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestControlLease(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    time.Duration
+		wantErr bool
+	}{
+		{0, defaultControlLease, false},
+		{30, 30 * time.Second, false},
+		{3600, time.Hour, false},
+		{3601, 0, true},
+		{-1, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := controlLease(tt.seconds)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("controlLease(%d) = %v, %v, want %v", tt.seconds, got, err, tt.want)
+		}
+	}
+}
+
+func TestControlOwner(t *testing.T) {
+	if owner := controlOwner(nil); !strings.HasSuffix(owner, ":"+strconv.Itoa(os.Getpid())) {
+		t.Errorf("controlOwner(nil) = %q, want it to end in the PID", owner)
+	}
+}
+
+func TestControlLeases(t *testing.T) {
+	l := &controlLeases{leases: map[string]heldLease{}}
+	if _, ok := l.get("a"); ok {
+		t.Fatal("lease before set")
+	}
+	l.set("a", "planner", time.Minute)
+	l.set("b", "", time.Second)
+	if held, ok := l.get("a"); !ok || held.agent != "planner" || held.lease != time.Minute {
+		t.Errorf("get(a) = %+v, %v", held, ok)
+	}
+	l.forget("a")
+	if owners := l.owners(); !slices.Equal(owners, []string{"b"}) {
+		t.Errorf("owners = %v, want [b]", owners)
+	}
+}
+
+func TestEnforceControlPassesThrough(t *testing.T) {
+	// Without a display there is no lease to check; calls go through
+	called := 0
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		called++
+		return &mcp.CallToolResult{}, nil
+	}
+	handler := enforceControl(next)
+	for _, tool := range []string{"x11_click_at", "x11_take_screenshot", "x11_abort_all"} {
+		params := &mcp.CallToolParamsFor[json.RawMessage]{Name: tool}
+		if res, err := handler(context.Background(), nil, "tools/call", params); err != nil || res.(*mcp.CallToolResult).IsError {
+			t.Errorf("%s: %v, %+v", tool, err, res)
+		}
+	}
+	if called != 3 {
+		t.Errorf("next called %d times, want 3", called)
+	}
+}
+
+func TestControlText(t *testing.T) {
+	lease := x11.ControlLease{Owner: "host:1", Expires: time.Date(2026, 1, 2, 15, 4, 5, 0, time.Local)}
+	if got := controlText(lease, false); !strings.HasPrefix(got, "Control of the display acquired until 15:04:05") {
+		t.Errorf("controlText = %q", got)
+	}
+	if got := controlText(lease, true); !strings.Contains(got, "renewed") {
+		t.Errorf("controlText of a renewal = %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "time"
+
+// delayConfig holds the waits applied before a tool captures its result screenshot
+type delayConfig struct {
+	action     time.Duration            // After input actions, unless the call passes a delay
+	screenshot time.Duration            // Before screenshots of tools without a delay argument
+	perTool    map[string]time.Duration // Per-tool overrides of both
+}
+
+// forTool returns the delay in milliseconds for an action tool. A non-zero
+// requested delay from the tool call wins over the configured ones.
+func (d *delayConfig) forTool(tool string, requested int) int {
+	if requested != 0 {
+		return requested
+	}
+	if delay, ok := d.perTool[tool]; ok {
+		return int(delay.Milliseconds())
+	}
+	return int(d.action.Milliseconds())
+}
+
+// forScreenshot returns the delay in milliseconds before a tool without a
+// delay argument takes its screenshot
+func (d *delayConfig) forScreenshot(tool string) int {
+	if delay, ok := d.perTool[tool]; ok {
+		return int(delay.Milliseconds())
+	}
+	return int(d.screenshot.Milliseconds())
+}
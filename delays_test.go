@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelayConfig(t *testing.T) {
+	d := delayConfig{
+		action:     100 * time.Millisecond,
+		screenshot: 50 * time.Millisecond,
+		perTool:    map[string]time.Duration{"x11_type_text": 0, "i3_cmd": 300 * time.Millisecond},
+	}
+
+	tests := []struct {
+		name      string
+		got, want int
+	}{
+		{"default action delay", d.forTool("x11_click_at", 0), 100},
+		{"requested delay wins", d.forTool("x11_type_text", 250), 250},
+		{"per-tool override", d.forTool("x11_type_text", 0), 0},
+		{"default screenshot delay", d.forScreenshot("x11_focus_window"), 50},
+		{"per-tool screenshot override", d.forScreenshot("i3_cmd"), 300},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %dms, want %dms", tt.name, tt.got, tt.want)
+		}
+	}
+}
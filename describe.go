@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mcp-x11-controller/x11"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Limits of the accessibility tree dump
+const (
+	atspiTimeout  = 5 * time.Second
+	atspiMaxDepth = 6
+	atspiMaxLines = 80
+	maxOCRText    = 2000 // Characters of recognized text kept in a description
+)
+
+// atspiScript prints the accessibility tree of the application with the
+// given PID, starting at its frame with the given name, one node per line as
+// "role: name" indented by depth. It exits non-zero if AT-SPI is unavailable
+// or the application is not on the accessibility bus.
+const atspiScript = `
+import sys
+import gi
+gi.require_version("Atspi", "2.0")
+from gi.repository import Atspi
+
+pid, title = int(sys.argv[1]), sys.argv[2]
+max_depth, max_lines = int(sys.argv[3]), int(sys.argv[4])
+lines = []
+
+def walk(node, depth):
+    if len(lines) >= max_lines:
+        return
+    try:
+        role, name = node.get_role_name(), node.get_name() or ""
+    except Exception:
+        return
+    lines.append("  " * depth + role + (": " + name if name else ""))
+    if depth < max_depth:
+        for i in range(min(node.get_child_count(), 50)):
+            child = node.get_child_at_index(i)
+            if child is not None:
+                walk(child, depth + 1)
+
+desktop = Atspi.get_desktop(0)
+for i in range(desktop.get_child_count()):
+    app = desktop.get_child_at_index(i)
+    if app is None or app.get_process_id() != pid:
+        continue
+    frames = [f for f in (app.get_child_at_index(j) for j in range(app.get_child_count())) if f is not None]
+    matching = [f for f in frames if f.get_name() == title] or frames
+    if matching:
+        walk(matching[0], 0)
+    break
+else:
+    sys.exit("application %d is not on the accessibility bus" % pid)
+print("\n".join(lines))
+`
+
+// atspiTree returns the accessibility tree of a window's application through
+// python3 and the Atspi GObject bindings
+func atspiTree(ctx context.Context, pid uint32, title string) (string, error) {
+	return runAtspi(ctx, atspiScript, pid, title, strconv.Itoa(atspiMaxDepth), strconv.Itoa(atspiMaxLines))
+}
+
+// runAtspi runs an AT-SPI python script for the application with the given
+// PID and returns its output. Errors carry the script's last line of stderr,
+// usually the exception or exit message.
+func runAtspi(ctx context.Context, script string, pid uint32, args ...string) (string, error) {
+	if pid == 0 {
+		return "", fmt.Errorf("window has no _NET_WM_PID")
+	}
+	if _, err := exec.LookPath("python3"); err != nil {
+		return "", fmt.Errorf("python3 not found")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, atspiTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "python3", append([]string{"-c", script, strconv.Itoa(int(pid))}, args...)...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if lines := strings.Split(msg, "\n"); msg != "" {
+			// Keep the last line, the exception or exit message
+			return "", fmt.Errorf("%s", lines[len(lines)-1])
+		}
+		return "", fmt.Errorf("AT-SPI query failed: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// windowDescription is everything x11_describe_window reports
+type windowDescription struct {
+	x11.WindowDescription
+	tree    string // Accessibility tree, empty if unavailable
+	treeErr error
+	ocrRan  bool   // Text recognition ran, even if it found nothing
+	ocr     string // Recognized text
+	ocrErr  error
+}
+
+// describeWindow gathers a window's properties, accessibility tree and
+// recognized text. Missing AT-SPI or tesseract only leave their parts out.
+func describeWindow(ctx context.Context, c *x11.Client, win uint32, ocr bool) (windowDescription, error) {
+	_, tesseractErr := exec.LookPath("tesseract")
+	capture := ocr && tesseractErr == nil
+
+	desc, err := c.DescribeWindow(ctx, x.Window(win), capture)
+	if err != nil {
+		return windowDescription{}, err
+	}
+	result := windowDescription{WindowDescription: desc}
+	result.tree, result.treeErr = atspiTree(ctx, desc.PID, desc.Title)
+
+	switch {
+	case !ocr:
+	case tesseractErr != nil:
+		result.ocrErr = fmt.Errorf("tesseract not found")
+	default:
+		text, err := ocrImage(ctx, c, desc.Image)
+		result.ocrRan, result.ocr, result.ocrErr = err == nil, strings.TrimSpace(text), err
+	}
+	return result, nil
+}
+
+// String formats the description compactly for text-only models
+func (d windowDescription) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Window 0x%x: %q\n", uint32(d.ID), d.Title)
+	fmt.Fprintf(&b, "Class: %s\n", orNone(d.Class))
+	if d.Role != "" {
+		fmt.Fprintf(&b, "Role: %s\n", d.Role)
+	}
+	if len(d.Types) > 0 {
+		fmt.Fprintf(&b, "Type: %s\n", strings.ToLower(strings.Join(d.Types, ", ")))
+	}
+	fmt.Fprintf(&b, "Geometry: %dx%d at (%d, %d)\n", d.Rect.Dx(), d.Rect.Dy(), d.Rect.Min.X, d.Rect.Min.Y)
+	if d.PID != 0 {
+		fmt.Fprintf(&b, "PID: %d\n", d.PID)
+	}
+	fmt.Fprintf(&b, "X child windows: %d\n", d.Children)
+
+	if d.tree != "" {
+		fmt.Fprintf(&b, "\nAccessibility tree:\n%s\n", d.tree)
+	} else if d.treeErr != nil {
+		fmt.Fprintf(&b, "\nAccessibility tree unavailable: %v\n", d.treeErr)
+	}
+
+	switch {
+	case d.ocr != "":
+		text := d.ocr
+		if len(text) > maxOCRText {
+			text = text[:maxOCRText] + "..."
+		}
+		fmt.Fprintf(&b, "\nText (OCR):\n%s\n", text)
+	case d.ocrRan:
+		b.WriteString("\nText (OCR): none recognized\n")
+	case d.ocrErr != nil:
+		fmt.Fprintf(&b, "\nText recognition unavailable: %v\n", d.ocrErr)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+)
+
+func TestWindowDescriptionString(t *testing.T) {
+	desc := windowDescription{
+		WindowDescription: x11.WindowDescription{
+			ID:       0x400001,
+			Title:    "Restore session?",
+			Class:    "Firefox",
+			Role:     "dialog",
+			PID:      4242,
+			Types:    []string{"DIALOG"},
+			Rect:     image.Rect(100, 50, 500, 250),
+			Children: 2,
+		},
+		tree:   "dialog: Restore session?\n  push button: Restore\n  push button: Start New Session",
+		ocrRan: true,
+		ocr:    "Restore session?\nRestore  Start New Session",
+	}
+
+	got := desc.String()
+	for _, want := range []string{
+		`Window 0x400001: "Restore session?"`,
+		"Class: Firefox",
+		"Role: dialog",
+		"Type: dialog",
+		"Geometry: 400x200 at (100, 50)",
+		"PID: 4242",
+		"Accessibility tree:\ndialog: Restore session?\n  push button: Restore",
+		"Text (OCR):\nRestore session?",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("description missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestWindowDescriptionUnavailable(t *testing.T) {
+	desc := windowDescription{
+		treeErr: errors.New("python3 not found"),
+		ocrErr:  errors.New("tesseract not found"),
+	}
+	got := desc.String()
+	for _, want := range []string{
+		"Class: none",
+		"Accessibility tree unavailable: python3 not found",
+		"Text recognition unavailable: tesseract not found",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("description missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "PID") || strings.Contains(got, "Role") {
+		t.Errorf("unset fields should be left out:\n%s", got)
+	}
+
+	desc = windowDescription{ocrRan: true}
+	if got := desc.String(); !strings.Contains(got, "none recognized") {
+		t.Errorf("empty OCR result not reported:\n%s", got)
+	}
+}
+
+func TestAtspiTreeWithoutPID(t *testing.T) {
+	if _, err := atspiTree(context.Background(), 0, "title"); err == nil {
+		t.Error("expected an error without a PID")
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"mcp-x11-controller/x11"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// doctorMarker is typed into xterm and looked for in the screenshot
+const doctorMarker = "doctor-check-4711"
+
+// checkStatus is the outcome of a single doctor check
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "OK"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+// doctorReport collects check results and prints them as they come in
+type doctorReport struct {
+	out    io.Writer
+	failed int
+}
+
+// add records a check result
+func (r *doctorReport) add(status checkStatus, name, detail string) {
+	if status == checkFail {
+		r.failed++
+	}
+	fmt.Fprintf(r.out, "[%-4s] %-22s %s\n", status, name, detail)
+}
+
+// lookPath reports whether a program is installed
+func (r *doctorReport) lookPath(name, program string, required bool) bool {
+	path, err := exec.LookPath(program)
+	if err == nil {
+		r.add(checkOK, name, path)
+		return true
+	}
+	status := checkSkip
+	if required {
+		status = checkFail
+	}
+	r.add(status, name, fmt.Sprintf("%s not found in PATH", program))
+	return false
+}
+
+// runDoctor checks the environment end to end on a temporary display and
+// returns the exit code
+func runDoctor(opts x11.ConnectOptions, out io.Writer) int {
+	report := &doctorReport{out: out}
+	fmt.Fprintln(out, "Checking the X11 controller environment...")
+
+	hasXvfb := report.lookPath("Xvfb", "Xvfb", true)
+	wmProgram := ""
+	if !opts.StartWM || opts.WMName == "" {
+		report.add(checkSkip, "window manager", "disabled")
+	} else if program, _, err := opts.WMCommand(); err != nil {
+		report.add(checkFail, "window manager", err.Error())
+	} else {
+		wmProgram = program
+		report.lookPath("window manager", wmProgram, false)
+	}
+	hasXterm := report.lookPath("xterm", "xterm", false)
+	hasTesseract := report.lookPath("tesseract (OCR)", "tesseract", false)
+	checkFonts(report)
+
+	if !hasXvfb {
+		return finishDoctor(report)
+	}
+
+	// Always use a fresh display, never the user's
+	os.Unsetenv("DISPLAY")
+	opts.Display = ""
+	opts.StartXvfb = true
+	opts.Resolution = "1024x768"
+	if wmProgram == "" {
+		opts.StartWM = false
+	}
+
+	c, err := x11.ConnectWithOptions(opts)
+	if err != nil {
+		// Connecting also verifies XTEST, so name it in the failure
+		report.add(checkFail, "temporary display", fmt.Sprintf("Xvfb or XTEST failed: %v", err))
+		return finishDoctor(report)
+	}
+	defer c.Close()
+	report.add(checkOK, "temporary display", fmt.Sprintf("started Xvfb on %s", c.GetDisplay()))
+	report.add(checkOK, "XTEST", "extension present")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if wmProgram != "" {
+		c.WaitContext(ctx, 500)
+		if wm := c.DetectWM(ctx); wm != "" {
+			report.add(checkOK, "window manager running", wm)
+		} else {
+			report.add(checkFail, "window manager running", fmt.Sprintf("%s did not register with the display", wmProgram))
+		}
+	}
+
+	if !hasXterm {
+		report.add(checkSkip, "input injection", "needs xterm")
+		return finishDoctor(report)
+	}
+	checkInput(ctx, c, report, hasTesseract)
+	return finishDoctor(report)
+}
+
+// checkFonts verifies that fontconfig knows at least one font
+func checkFonts(report *doctorReport) {
+	if _, err := exec.LookPath("fc-list"); err != nil {
+		report.add(checkSkip, "fonts", "fc-list not found, cannot check fonts")
+		return
+	}
+	output, err := exec.Command("fc-list").Output()
+	if err != nil {
+		report.add(checkFail, "fonts", fmt.Sprintf("fc-list failed: %v", err))
+		return
+	}
+	fonts := strings.TrimSpace(string(output))
+	if fonts == "" {
+		report.add(checkFail, "fonts", "no fonts installed, applications will render blank text")
+		return
+	}
+	report.add(checkOK, "fonts", fmt.Sprintf("%d fonts installed", strings.Count(fonts, "\n")+1))
+}
+
+// checkInput types a marker into an xterm and verifies it shows up
+func checkInput(ctx context.Context, c *x11.Client, report *doctorReport, ocr bool) {
+	pid, err := c.StartApp("xterm", []string{"-title", "doctor", "-geometry", "80x10+0+0", "-fa", "Monospace", "-fs", "14"})
+	if err != nil {
+		report.add(checkFail, "start xterm", err.Error())
+		return
+	}
+	defer c.StopApp(pid)
+
+	// Wait for the window to be mapped
+	var found bool
+	for i := 0; i < 50 && !found; i++ {
+		c.WaitContext(ctx, 100)
+		windows, _ := c.ListWindowsContext(ctx)
+		for _, win := range windows {
+			if win.Title == "doctor" {
+				c.FocusWindowContext(ctx, win.ID)
+				found = true
+			}
+		}
+	}
+	if !found {
+		report.add(checkFail, "start xterm", "window did not appear within 5s")
+		return
+	}
+	report.add(checkOK, "start xterm", fmt.Sprintf("pid %d", pid))
+
+	c.WaitContext(ctx, 300)
+	before, err := c.ScreenshotContext(ctx)
+	if err != nil {
+		report.add(checkFail, "screenshot", err.Error())
+		return
+	}
+	report.add(checkOK, "screenshot", fmt.Sprintf("%dx%d", before.Bounds().Dx(), before.Bounds().Dy()))
+
+	if err := c.TypeContext(ctx, "echo "+doctorMarker+"\n"); err != nil {
+		report.add(checkFail, "input injection", err.Error())
+		return
+	}
+	c.WaitContext(ctx, 500)
+	after, err := c.ScreenshotContext(ctx)
+	if err != nil {
+		report.add(checkFail, "input injection", err.Error())
+		return
+	}
+	changed := changedPixels(before, after)
+	if changed <= 0 {
+		report.add(checkFail, "input injection", "typing did not change the screen")
+		return
+	}
+	report.add(checkOK, "input injection", fmt.Sprintf("%d pixels changed after typing", changed))
+
+	if !ocr {
+		report.add(checkSkip, "OCR check", "needs tesseract")
+		return
+	}
+	text, err := ocrImage(ctx, c, after)
+	if err != nil {
+		report.add(checkFail, "OCR check", err.Error())
+		return
+	}
+	if !strings.Contains(text, doctorMarker) {
+		report.add(checkFail, "OCR check", fmt.Sprintf("%q not found in screen text", doctorMarker))
+		return
+	}
+	report.add(checkOK, "OCR check", "typed text is readable on screen")
+}
+
+// changedPixels counts the pixels that differ between two images of the same size
+func changedPixels(a, b image.Image) int {
+	if a.Bounds() != b.Bounds() {
+		return -1
+	}
+	changed := 0
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+// ocrImage runs tesseract on an image and returns the recognized text
+func ocrImage(ctx context.Context, c *x11.Client, img image.Image) (string, error) {
+	return runTesseract(ctx, c, img)
+}
+
+// runTesseract runs tesseract on an image with extra arguments, such as an
+// output format, and returns its output
+func runTesseract(ctx context.Context, c *x11.Client, img image.Image, args ...string) (string, error) {
+	pngData, err := c.EncodePNG(img)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "mcp-x11-ocr")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "screen.png")
+	if err := os.WriteFile(path, pngData, 0644); err != nil {
+		return "", err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "tesseract", append([]string{path, "stdout"}, args...)...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
+
+// finishDoctor prints the summary and returns the exit code
+func finishDoctor(report *doctorReport) int {
+	if report.failed > 0 {
+		fmt.Fprintf(report.out, "\n%d check(s) failed\n", report.failed)
+		return 1
+	}
+	fmt.Fprintln(report.out, "\nAll checks passed")
+	return 0
+}
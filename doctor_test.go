@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDoctorReport(t *testing.T) {
+	var out bytes.Buffer
+	report := &doctorReport{out: &out}
+	report.add(checkOK, "Xvfb", "/usr/bin/Xvfb")
+	report.add(checkSkip, "OCR check", "needs tesseract")
+	report.add(checkFail, "fonts", "no fonts installed")
+
+	if report.failed != 1 {
+		t.Errorf("failed = %d, want 1", report.failed)
+	}
+	if code := finishDoctor(report); code != 1 {
+		t.Errorf("finishDoctor() = %d, want 1", code)
+	}
+	for _, want := range []string{"[OK  ] Xvfb", "[SKIP] OCR check", "[FAIL] fonts", "1 check(s) failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := finishDoctor(&doctorReport{out: &out}); code != 0 {
+		t.Errorf("finishDoctor() with no failures = %d, want 0", code)
+	}
+}
+
+func TestChangedPixels(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	b := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	if n := changedPixels(a, b); n != 0 {
+		t.Errorf("identical images: changed = %d, want 0", n)
+	}
+
+	b.Set(1, 2, color.RGBA{255, 0, 0, 255})
+	b.Set(3, 3, color.RGBA{0, 255, 0, 255})
+	if n := changedPixels(a, b); n != 2 {
+		t.Errorf("changed = %d, want 2", n)
+	}
+
+	if n := changedPixels(a, image.NewRGBA(image.Rect(0, 0, 2, 2))); n != -1 {
+		t.Errorf("different sizes: changed = %d, want -1", n)
+	}
+}
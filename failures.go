@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// failureEvents is how many recent window events are attached to failed
+// tool results
+const failureEvents = 8
+
+const (
+	// birthThumbWidth is how wide the thumbnails of newly mapped windows are
+	birthThumbWidth = 240
+	// maxWindowBirths is how many thumbnails of newly mapped windows are kept
+	maxWindowBirths = 8
+)
+
+// windowEventLog keeps the most recent window events, and thumbnails of
+// the windows mapped recently
+type windowEventLog struct {
+	mu     sync.Mutex
+	events []x11.WindowEvent // Oldest first, without thumbnails
+	births []windowBirth
+}
+
+// windowBirth is the event of a window being mapped, with its thumbnail
+type windowBirth struct {
+	x11.WindowEvent
+	gone time.Time // When the window was unmapped or destroyed, zero while shown
+}
+
+var windowEvents windowEventLog
+
+// add records a window event, dropping the oldest beyond failureEvents.
+// Mapped events are delivered late, after their thumbnail is taken, so
+// events are kept in the order they happened rather than arrived.
+func (l *windowEventLog) add(ev x11.WindowEvent) {
+	slog.Debug("window event", "kind", ev.Kind, "window", fmt.Sprintf("0x%x", uint32(ev.Window)), "title", ev.Title)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ev.Kind == "mapped" && ev.Thumbnail != nil {
+		birth := windowBirth{WindowEvent: ev}
+		for _, later := range l.events {
+			if later.Window == ev.Window && later.Time.After(ev.Time) && windowGone(later) {
+				birth.gone = later.Time
+				break
+			}
+		}
+		l.births = append(l.births, birth)
+		if len(l.births) > maxWindowBirths {
+			l.births = slices.Clone(l.births[len(l.births)-maxWindowBirths:])
+		}
+	}
+	if windowGone(ev) {
+		for i := range l.births {
+			if b := &l.births[i]; b.Window == ev.Window && b.gone.IsZero() && b.Time.Before(ev.Time) {
+				b.gone = ev.Time
+			}
+		}
+	}
+
+	ev.Thumbnail = nil
+	i := len(l.events)
+	for i > 0 && l.events[i-1].Time.After(ev.Time) {
+		i--
+	}
+	l.events = slices.Insert(l.events, i, ev)
+	if len(l.events) > failureEvents {
+		l.events = slices.Clone(l.events[len(l.events)-failureEvents:])
+	}
+}
+
+// windowGone reports whether ev is a window going away
+func windowGone(ev x11.WindowEvent) bool {
+	return ev.Kind == "unmapped" || ev.Kind == "destroyed"
+}
+
+// recent returns the logged events as text, oldest first
+func (l *windowEventLog) recent() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := make([]string, len(l.events))
+	for i, ev := range l.events {
+		lines[i] = ev.String()
+	}
+	return lines
+}
+
+// closed returns the windows mapped after since that went away again,
+// oldest first
+func (l *windowEventLog) closed(since time.Time) []windowBirth {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closedAfter(since)
+}
+
+// closedSinceEvents returns the windows that went away again among the
+// logged events
+func (l *windowEventLog) closedSinceEvents() []windowBirth {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) == 0 {
+		return nil
+	}
+	return l.closedAfter(l.events[0].Time)
+}
+
+// closedAfter is closed with mu held
+func (l *windowEventLog) closedAfter(since time.Time) []windowBirth {
+	var closed []windowBirth
+	for _, b := range l.births {
+		if !b.gone.IsZero() && !b.Time.Before(since) {
+			closed = append(closed, b)
+		}
+	}
+	return closed
+}
+
+// closedMatch returns the latest window matching m that was mapped after
+// since and went away again
+func closedMatch(since time.Time, m x11.WindowMatcher) (windowBirth, bool) {
+	closed := windowEvents.closed(since)
+	for i := len(closed) - 1; i >= 0; i-- {
+		if m.Match(closed[i].Title, closed[i].Class) {
+			return closed[i], true
+		}
+	}
+	return windowBirth{}, false
+}
+
+// String describes the window and how long it was shown
+func (b windowBirth) String() string {
+	s := fmt.Sprintf("window 0x%x", uint32(b.Window))
+	if b.Title != "" {
+		s += fmt.Sprintf(" %q", b.Title)
+	}
+	if b.Class != "" {
+		s += " (" + b.Class + ")"
+	}
+	return s + fmt.Sprintf(" appeared at %s and was gone %s later", b.Time.Format("15:04:05.000"), b.gone.Sub(b.Time).Round(time.Millisecond))
+}
+
+// content shows the window's thumbnail with its description
+func (b windowBirth) content(prefix string) []mcp.Content {
+	return []mcp.Content{
+		&mcp.TextContent{Text: prefix + b.String() + ":"},
+		&mcp.ImageContent{Data: b.Thumbnail, MIMEType: "image/png"},
+	}
+}
+
+// reportFailures is receiving middleware that attaches a screenshot taken
+// at the moment of failure and the most recent window events to failed tool
+// results, so agents and humans can see what the screen looked like. Failed
+// input tools also report grabs that swallow injected input.
+func reportFailures(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		if method != "tools/call" || err != nil {
+			return result, err
+		}
+		res, ok := result.(*mcp.CallToolResult)
+		if !ok || !res.IsError {
+			return result, err
+		}
+
+		meta := make(map[string]any)
+		events := windowEvents.recent()
+		if len(events) > 0 {
+			res.Content = append(res.Content, &mcp.TextContent{
+				Text: "Recent window events:\n" + strings.Join(events, "\n"),
+			})
+			meta["window_events"] = events
+		}
+		if closed := windowEvents.closedSinceEvents(); len(closed) > 0 {
+			var gone []string
+			for _, b := range closed {
+				res.Content = append(res.Content, b.content("Closed again: ")...)
+				gone = append(gone, b.String())
+			}
+			meta["closed_windows"] = gone
+		}
+		if client != nil {
+			res.Content = append(res.Content, failureScreenshot(ctx, meta)...)
+		}
+
+		if len(meta) > 0 {
+			if res.Meta == nil {
+				res.Meta = mcp.Meta{}
+			}
+			res.Meta["failure"] = meta
+		}
+		return result, err
+	}
+}
+
+// failureScreenshot captures the screen for a failed tool result, recording
+// it in meta. Calls that failed because they were aborted still get one.
+func failureScreenshot(ctx context.Context, meta map[string]any) []mcp.Content {
+	timer := newToolTimer()
+	pngData, err := screenshotPNG(context.WithoutCancel(ctx), timer, false)
+	if err != nil {
+		slog.Debug("failed to take failure screenshot", "err", err)
+		return nil
+	}
+	text := "Screenshot at failure"
+	if timer.shot.Index != 0 {
+		text += fmt.Sprintf(", kept as screenshot index %d", timer.shot.Index)
+	}
+	if shot := screenshotMeta(*timer.shot); len(shot) > 0 {
+		meta["screenshot"] = shot
+	}
+	return []mcp.Content{
+		&mcp.TextContent{Text: text},
+		&mcp.ImageContent{Data: pngData, MIMEType: "image/png"},
+	}
+}
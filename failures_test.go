@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestWindowEventLogBounded(t *testing.T) {
+	var log windowEventLog
+	for i := 0; i < failureEvents+3; i++ {
+		log.add(x11.WindowEvent{Time: time.Now(), Kind: "mapped", Window: x.Window(0x400000 + i)})
+	}
+	lines := log.recent()
+	if len(lines) != failureEvents {
+		t.Fatalf("got %d lines, want %d", len(lines), failureEvents)
+	}
+	if want := fmt.Sprintf("0x%x", 0x400000+failureEvents+2); !strings.Contains(lines[len(lines)-1], want) {
+		t.Errorf("newest line = %q, want window %s", lines[len(lines)-1], want)
+	}
+}
+
+func TestWindowEventLogBirths(t *testing.T) {
+	var log windowEventLog
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	thumb := []byte("png")
+
+	// The mapped event of a window that closed quickly arrives after its
+	// unmapped event, once the thumbnail is taken
+	log.add(x11.WindowEvent{Time: at(10), Kind: "unmapped", Window: 0x400001, Title: "Error"})
+	log.add(x11.WindowEvent{Time: at(0), Kind: "mapped", Window: 0x400001, Title: "Error", Class: "Zenity", Thumbnail: thumb})
+	log.add(x11.WindowEvent{Time: at(20), Kind: "mapped", Window: 0x400002, Title: "Editor", Class: "Gedit", Thumbnail: thumb})
+	log.add(x11.WindowEvent{Time: at(30), Kind: "mapped", Window: 0x400003, Title: "Saved", Class: "Gedit", Thumbnail: thumb})
+	log.add(x11.WindowEvent{Time: at(40), Kind: "destroyed", Window: 0x400003, Title: "Saved"})
+
+	lines := log.recent()
+	if len(lines) != 5 || !strings.Contains(lines[0], "mapped window 0x400001") || !strings.Contains(lines[1], "unmapped window 0x400001") {
+		t.Errorf("recent() = %q, want events in the order they happened", lines)
+	}
+	for _, ev := range log.events {
+		if ev.Thumbnail != nil {
+			t.Errorf("event %s keeps its thumbnail", ev)
+		}
+	}
+
+	closed := log.closed(start)
+	if len(closed) != 2 || closed[0].Window != 0x400001 || closed[1].Window != 0x400003 {
+		t.Fatalf("closed() = %v, want windows 0x400001 and 0x400003", closed)
+	}
+	if want := `window 0x400001 "Error" (Zenity) appeared at ` + start.Format("15:04:05.000") + " and was gone 10ms later"; closed[0].String() != want {
+		t.Errorf("String() = %q, want %q", closed[0].String(), want)
+	}
+	if got := log.closed(at(5)); len(got) != 1 {
+		t.Errorf("closed() since 5ms = %v, want only window 0x400003", got)
+	}
+}
+
+func TestClosedMatch(t *testing.T) {
+	events, births := windowEvents.events, windowEvents.births
+	defer func() { windowEvents.events, windowEvents.births = events, births }()
+	windowEvents.events, windowEvents.births = nil, nil
+	start := time.Now()
+	windowEvents.add(x11.WindowEvent{Time: start.Add(time.Millisecond), Kind: "mapped", Window: 0x400001, Title: "Error", Class: "Zenity", Thumbnail: []byte("png")})
+	windowEvents.add(x11.WindowEvent{Time: start.Add(2 * time.Millisecond), Kind: "destroyed", Window: 0x400001, Title: "Error"})
+
+	tests := []struct {
+		name    string
+		matcher x11.WindowMatcher
+		since   time.Time
+		want    bool
+	}{
+		{"title", x11.WindowMatcher{Title: "^Error$"}, start, true},
+		{"class", x11.WindowMatcher{Class: "zenity"}, start, true},
+		{"other title", x11.WindowMatcher{Title: "Warning"}, start, false},
+		{"before the wait", x11.WindowMatcher{Title: "Error"}, start.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ok := closedMatch(tt.since, tt.matcher)
+			if ok != tt.want || (ok && b.Window != 0x400001) {
+				t.Errorf("closedMatch() = %v, %v, want %v", b, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportFailures(t *testing.T) {
+	saved := windowEvents.events
+	defer func() { windowEvents.events = saved }()
+	windowEvents.events = []x11.WindowEvent{{Time: time.Now(), Kind: "mapped", Window: 0x400001, Title: "Save As"}}
+
+	failed := false
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "failed to type"}}, IsError: failed}, nil
+	}
+	handler := reportFailures(next)
+
+	// Successful results are unchanged
+	result, err := handler(context.Background(), nil, "tools/call", nil)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if res := result.(*mcp.CallToolResult); len(res.Content) != 1 || res.Meta != nil {
+		t.Errorf("unexpected additions to a successful result: %+v", res)
+	}
+
+	failed = true
+	result, err = handler(context.Background(), nil, "tools/call", nil)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	res := result.(*mcp.CallToolResult)
+	if len(res.Content) < 2 {
+		t.Fatalf("got %d content items, want at least 2", len(res.Content))
+	}
+	if text := res.Content[1].(*mcp.TextContent).Text; !strings.Contains(text, `"Save As"`) {
+		t.Errorf("events text = %q", text)
+	}
+	failure, ok := res.Meta["failure"].(map[string]any)
+	if !ok {
+		t.Fatalf("failure meta = %#v", res.Meta["failure"])
+	}
+	if events, _ := failure["window_events"].([]string); len(events) != 1 {
+		t.Errorf("window_events meta = %#v", failure["window_events"])
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"mcp-x11-controller/x11/vision"
+	"os"
+	"strings"
+)
+
+// loadTemplate returns the PNG x11_find_image looks for, from exactly one
+// of its template, path and from_screenshot arguments
+func loadTemplate(args FindImageInput) ([]byte, error) {
+	sources := 0
+	for _, set := range []bool{args.Template != "", args.Path != "", args.FromScreenshot != 0} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("give exactly one of template, path or from_screenshot")
+	}
+
+	switch {
+	case args.Template != "":
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(args.Template, "data:image/png;base64,"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode template: %w", err)
+		}
+		return data, nil
+	case args.Path != "":
+		data, err := os.ReadFile(args.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		return data, nil
+	}
+
+	entry, err := history.byIndex(args.FromScreenshot)
+	if err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(entry.PNG))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode screenshot %d: %w", entry.Index, err)
+	}
+	crop := image.Rect(args.CropX, args.CropY, args.CropX+args.CropWidth, args.CropY+args.CropHeight)
+	shown := img.Bounds().Add(entry.Origin)
+	if args.CropWidth <= 0 || args.CropHeight <= 0 || !crop.In(shown) {
+		return nil, fmt.Errorf("crop %dx%d+%d+%d is not within screenshot %d, which shows %dx%d+%d+%d",
+			args.CropWidth, args.CropHeight, args.CropX, args.CropY, entry.Index, shown.Dx(), shown.Dy(), shown.Min.X, shown.Min.Y)
+	}
+	cropper, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, fmt.Errorf("cannot crop screenshot %d", entry.Index)
+	}
+	sub := cropper.SubImage(crop.Sub(entry.Origin).Add(img.Bounds().Min))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub); err != nil {
+		return nil, fmt.Errorf("failed to encode template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// findText lists the matches of x11_find_image
+func findText(matches []vision.Match, threshold float64) string {
+	if len(matches) == 0 {
+		return fmt.Sprintf("No match with a score of at least %.2f", threshold)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d match(es) with a score of at least %.2f, best first:", len(matches), threshold)
+	for i, m := range matches {
+		c := m.Center()
+		fmt.Fprintf(&b, "\n%d. center %d,%d (area %dx%d+%d+%d), score %.3f",
+			i+1, c.X, c.Y, m.Rect.Dx(), m.Rect.Dy(), m.Rect.Min.X, m.Rect.Min.Y, m.Score)
+	}
+	return b.String()
+}
+
+// findMeta is _meta.matches of x11_find_image
+func findMeta(matches []vision.Match) []map[string]any {
+	meta := make([]map[string]any, len(matches))
+	for i, m := range matches {
+		c := m.Center()
+		meta[i] = map[string]any{
+			"x":        m.Rect.Min.X,
+			"y":        m.Rect.Min.Y,
+			"width":    m.Rect.Dx(),
+			"height":   m.Rect.Dy(),
+			"center_x": c.X,
+			"center_y": c.Y,
+			"score":    m.Score,
+		}
+	}
+	return meta
+}
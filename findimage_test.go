@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"mcp-x11-controller/x11/vision"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	saved := history
+	defer func() { history = saved }()
+	history = newScreenshotHistory(5, 0)
+
+	// A window screenshot at 100,50 with one red pixel at screen 110,60
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	img.Set(10, 10, color.RGBA{0xff, 0, 0, 0xff})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	shot := history.add(historyEntry{PNG: buf.Bytes(), Window: 7, Origin: image.Pt(100, 50)})
+	path := filepath.Join(t.TempDir(), "button.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    FindImageInput
+		size    image.Point
+		wantErr string
+	}{
+		{"base64", FindImageInput{Template: base64.StdEncoding.EncodeToString(buf.Bytes())}, image.Pt(40, 30), ""},
+		{"data URL", FindImageInput{Template: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())}, image.Pt(40, 30), ""},
+		{"path", FindImageInput{Path: path}, image.Pt(40, 30), ""},
+		{"crop", FindImageInput{FromScreenshot: shot.Index, CropX: 108, CropY: 58, CropWidth: 5, CropHeight: 4}, image.Pt(5, 4), ""},
+		{"latest", FindImageInput{FromScreenshot: -1, CropX: 100, CropY: 50, CropWidth: 40, CropHeight: 30}, image.Pt(40, 30), ""},
+		{"crop outside", FindImageInput{FromScreenshot: -1, CropX: 0, CropY: 0, CropWidth: 20, CropHeight: 20}, image.Point{}, "not within screenshot"},
+		{"no source", FindImageInput{}, image.Point{}, "exactly one"},
+		{"two sources", FindImageInput{Path: path, FromScreenshot: -1}, image.Point{}, "exactly one"},
+		{"bad base64", FindImageInput{Template: "%%%"}, image.Point{}, "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := loadTemplate(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadTemplate = %v, want error %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadTemplate failed: %v", err)
+			}
+			got, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := got.Bounds().Size(); size != tt.size {
+				t.Errorf("template size = %v, want %v", size, tt.size)
+			}
+			if tt.name == "crop" {
+				if r, _, _, _ := got.At(got.Bounds().Min.X+2, got.Bounds().Min.Y+2).RGBA(); r != 0xffff {
+					t.Error("crop doesn't hold the red pixel at its offset")
+				}
+			}
+		})
+	}
+}
+
+func TestFindText(t *testing.T) {
+	if got := findText(nil, 0.9); got != "No match with a score of at least 0.90" {
+		t.Errorf("findText(nil) = %q", got)
+	}
+	matches := []vision.Match{{Rect: image.Rect(100, 50, 140, 70), Score: 0.987}}
+	if got := findText(matches, 0.9); !strings.Contains(got, "1. center 120,60 (area 40x20+100+50), score 0.987") {
+		t.Errorf("findText = %q", got)
+	}
+}
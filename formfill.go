@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"mcp-x11-controller/x11"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fieldSettle is how long a field gets to take the focus after a click or
+// Tab, and to publish its selection after ctrl+a
+const fieldSettle = 150 * time.Millisecond
+
+// Outcomes of a form field in x11_fill_form
+const (
+	fieldFilled   = "filled"   // Typed and read back as the value
+	fieldTyped    = "typed"    // Typed, without reading it back
+	fieldMismatch = "mismatch" // Typed, but the field reads differently
+	fieldFailed   = "failed"   // Not located or not typed
+	fieldSkipped  = "skipped"  // Left alone after an earlier field failed
+)
+
+// fieldReport is what happened to one field of x11_fill_form
+type fieldReport struct {
+	Locator string // How the field was found
+	Status  string
+	Detail  string      // Error or the text read back, for failures
+	At      image.Point // Where it was clicked, if it was
+	Clicked bool
+}
+
+// ok reports whether the field was filled without a known problem
+func (r fieldReport) ok() bool {
+	return r.Status == fieldFilled || r.Status == fieldTyped
+}
+
+// validateForm checks x11_fill_form's arguments before anything is typed
+func validateForm(args FillFormInput) error {
+	if len(args.Fields) == 0 {
+		return fmt.Errorf("give at least one field")
+	}
+	for i, f := range args.Fields {
+		if f.Text != "" && f.At != nil {
+			return fmt.Errorf("field %d: give text or at, not both", i+1)
+		}
+		if f.Text == "" && (f.OffsetX != 0 || f.OffsetY != 0) {
+			return fmt.Errorf("field %d: offset_x and offset_y need text", i+1)
+		}
+	}
+	if s := args.Submit; s != nil {
+		set := 0
+		for _, given := range []bool{s.Text != "", s.At != nil, s.Key != ""} {
+			if given {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("submit needs exactly one of text, at or key")
+		}
+		if s.Key != "" {
+			if _, err := x11.ParseKeysym(s.Key); err != nil {
+				return fmt.Errorf("submit key: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// formFiller fills the fields of x11_fill_form one after the other
+type formFiller struct {
+	client *x11.Client
+	ocr    func(ctx context.Context) ([]ocrWord, error)
+	echo   bool      // Reports may repeat what fields read
+	words  []ocrWord // Text on the screen, recognized on first need
+	read   bool
+}
+
+// formOCR returns text recognition of the screen for text locators, nil
+// without tesseract
+func formOCR() func(ctx context.Context) ([]ocrWord, error) {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil
+	}
+	return func(ctx context.Context) ([]ocrWord, error) {
+		img, err := client.ScreenshotContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer client.RecycleScreenshot(img)
+		return screenWords(ctx, img)
+	}
+}
+
+// screenWords recognizes the words on a screenshot, placed in screen
+// coordinates
+func screenWords(ctx context.Context, img image.Image) ([]ocrWord, error) {
+	tsv, err := runTesseract(ctx, client, img, "tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to recognize text: %w", err)
+	}
+	words := parseTSV(tsv)
+	for i := range words {
+		words[i].Rect = words[i].Rect.Add(img.Bounds().Min)
+	}
+	return words, nil
+}
+
+// locate returns the screen point of a text locator, the center of the
+// text moved by the offset
+func (f *formFiller) locate(ctx context.Context, text string, dx, dy int) (image.Point, error) {
+	if !f.read {
+		if f.ocr == nil {
+			return image.Point{}, fmt.Errorf("locating fields by text needs tesseract, which is not installed")
+		}
+		words, err := f.ocr(ctx)
+		if err != nil {
+			return image.Point{}, err
+		}
+		f.words, f.read = words, true
+	}
+	rect, ok := findPhrase(f.words, text)
+	if !ok {
+		return image.Point{}, fmt.Errorf("text %q not found on the screen", text)
+	}
+	center := image.Pt((rect.Min.X+rect.Max.X)/2+dx, (rect.Min.Y+rect.Max.Y)/2+dy)
+	if _, _, err := f.client.ValidatePoint(float64(center.X), float64(center.Y)); err != nil {
+		return image.Point{}, err
+	}
+	return center, nil
+}
+
+// click moves the pointer to p, clicks and lets the field take the focus
+func (f *formFiller) click(ctx context.Context, p image.Point) error {
+	if err := f.client.MouseMoveContext(ctx, p.X, p.Y); err != nil {
+		return err
+	}
+	if err := f.client.MouseClickContext(ctx, 1); err != nil {
+		return err
+	}
+	return f.client.WaitContext(ctx, int(fieldSettle.Milliseconds()))
+}
+
+// point converts an at locator to screen pixels
+func (f *formFiller) point(at *FormPointInput) (image.Point, error) {
+	px, py, err := f.client.ValidatePoint(at.X, at.Y)
+	return image.Pt(px, py), err
+}
+
+// fill focuses field i, by its locator or with Tab from the previous one,
+// types its value and reads it back
+func (f *formFiller) fill(ctx context.Context, i int, field FormFieldInput) fieldReport {
+	report := fieldReport{Locator: fieldLocator(i, field)}
+	fail := func(err error) fieldReport {
+		report.Status, report.Detail = fieldFailed, err.Error()
+		return report
+	}
+
+	switch {
+	case field.Text != "" || field.At != nil:
+		var p image.Point
+		var err error
+		if field.At != nil {
+			p, err = f.point(field.At)
+		} else {
+			p, err = f.locate(ctx, field.Text, field.OffsetX, field.OffsetY)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if err := f.click(ctx, p); err != nil {
+			return fail(err)
+		}
+		report.At, report.Clicked = p, true
+	case i > 0:
+		if err := f.client.KeyPressContext(ctx, "Tab"); err != nil {
+			return fail(err)
+		}
+		if err := f.client.WaitContext(ctx, int(fieldSettle.Milliseconds())); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Typing over the selection replaces what the field held
+	if !field.Append {
+		if err := f.client.KeyComboContext(ctx, "ctrl+a"); err != nil {
+			return fail(err)
+		}
+	}
+	if err := f.client.TypeContext(ctx, field.Value); err != nil {
+		// The error can name a character of the value
+		if field.Sensitive {
+			report.Status, report.Detail = fieldFailed, "typing failed"
+			return report
+		}
+		return fail(err)
+	}
+	// Password fields don't give their text away, and appended text only
+	// reads back with what was there
+	if field.Sensitive || field.Append || field.NoVerify {
+		report.Status = fieldTyped
+		return report
+	}
+
+	got, err := f.readBack(ctx)
+	if err != nil {
+		report.Status, report.Detail = fieldTyped, "not read back: "+err.Error()
+		return report
+	}
+	if got == "" && field.Value != "" {
+		report.Status, report.Detail = fieldTyped, "not read back: the field did not select its text"
+		return report
+	}
+	if got != field.Value {
+		report.Status, report.Detail = fieldMismatch, fmt.Sprintf("reads %d other characters", utf8.RuneCountInString(got))
+		if f.echo {
+			report.Detail = fmt.Sprintf("reads %q", got)
+		}
+		return report
+	}
+	report.Status = fieldFilled
+	return report
+}
+
+// readBack selects the focused field's text, reads it from PRIMARY and puts
+// the cursor at the end again. PRIMARY is emptied first, so a field that
+// doesn't publish its selection reads as empty rather than as an earlier
+// field.
+func (f *formFiller) readBack(ctx context.Context) (string, error) {
+	if err := f.client.SetSelectionContext(ctx, x11.SelectionPrimary, ""); err != nil {
+		return "", err
+	}
+	if err := f.client.KeyComboContext(ctx, "ctrl+a"); err != nil {
+		return "", err
+	}
+	if err := f.client.WaitContext(ctx, int(fieldSettle.Milliseconds())); err != nil {
+		return "", err
+	}
+	text, err := f.client.ReadSelectionContext(ctx, x11.SelectionPrimary)
+	if err != nil {
+		return "", err
+	}
+	if err := f.client.KeyPressContext(ctx, "End"); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// submit presses the submit key or clicks the submit button
+func (f *formFiller) submit(ctx context.Context, s *FormSubmitInput) error {
+	switch {
+	case s.Key != "":
+		return f.client.KeyPressContext(ctx, s.Key)
+	case s.At != nil:
+		p, err := f.point(s.At)
+		if err != nil {
+			return err
+		}
+		return f.click(ctx, p)
+	}
+	// The button may only be readable now that the fields are filled
+	f.read = false
+	p, err := f.locate(ctx, s.Text, 0, 0)
+	if err != nil {
+		return err
+	}
+	return f.click(ctx, p)
+}
+
+// fieldLocator names a field for the report
+func fieldLocator(i int, field FormFieldInput) string {
+	switch {
+	case field.Text != "":
+		return fmt.Sprintf("text %q", field.Text)
+	case field.At != nil:
+		return fmt.Sprintf("at (%g, %g)", field.At.X, field.At.Y)
+	case i == 0:
+		return "focused field"
+	}
+	return "next field (Tab)"
+}
+
+// formText is the per-field report of x11_fill_form
+func formText(reports []fieldReport, submitted bool, submitErr error, echo bool, fields []FormFieldInput) string {
+	var b strings.Builder
+	filled := 0
+	for _, r := range reports {
+		if r.ok() {
+			filled++
+		}
+	}
+	fmt.Fprintf(&b, "Filled %d of %d field(s)", filled, len(fields))
+	switch {
+	case submitted:
+		b.WriteString(", submitted")
+	case submitErr != nil:
+		fmt.Fprintf(&b, ", submit failed: %v", submitErr)
+	}
+	for i, r := range reports {
+		fmt.Fprintf(&b, "\n%d. %s: %s", i+1, r.Locator, r.Status)
+		if r.Status != fieldSkipped {
+			value := fields[i].Value
+			if echo && !fields[i].Sensitive {
+				fmt.Fprintf(&b, " %q", value)
+			} else {
+				fmt.Fprintf(&b, " (%d characters)", utf8.RuneCountInString(value))
+			}
+		}
+		if r.Clicked {
+			fmt.Fprintf(&b, ", clicked at (%d, %d)", r.At.X, r.At.Y)
+		}
+		if r.Detail != "" {
+			b.WriteString(", " + r.Detail)
+		}
+	}
+	return b.String()
+}
+
+// formMeta is _meta.fields of x11_fill_form
+func formMeta(reports []fieldReport) []map[string]any {
+	meta := make([]map[string]any, len(reports))
+	for i, r := range reports {
+		meta[i] = map[string]any{"status": r.Status}
+		if r.Clicked {
+			meta[i]["x"], meta[i]["y"] = r.At.X, r.At.Y
+		}
+		if r.Detail != "" {
+			meta[i]["detail"] = r.Detail
+		}
+	}
+	return meta
+}
+
+// ocrWord is a word tesseract recognized, with its place on the image
+type ocrWord struct {
+	Text string
+	Rect image.Rectangle
+	Line string // Block, paragraph and line number, equal for words on a line
+}
+
+// parseTSV reads the words of tesseract's tsv output, whose columns are
+// level, page, block, paragraph, line and word number, left, top, width,
+// height, confidence and text
+func parseTSV(tsv string) []ocrWord {
+	var words []ocrWord
+	for _, line := range strings.Split(tsv, "\n") {
+		cols := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(cols) != 12 || cols[0] != "5" || strings.TrimSpace(cols[11]) == "" {
+			continue
+		}
+		var box [4]int
+		valid := true
+		for j := range box {
+			n, err := strconv.Atoi(cols[6+j])
+			if err != nil {
+				valid = false
+				break
+			}
+			box[j] = n
+		}
+		if !valid {
+			continue
+		}
+		words = append(words, ocrWord{
+			Text: strings.TrimSpace(cols[11]),
+			Rect: image.Rect(box[0], box[1], box[0]+box[2], box[1]+box[3]),
+			Line: strings.Join(cols[2:5], "."),
+		})
+	}
+	return words
+}
+
+// findPhrase returns the area of the first run of words on one line that
+// reads phrase, ignoring case and punctuation around words, like the colon
+// after a label
+func findPhrase(words []ocrWord, phrase string) (image.Rectangle, bool) {
+	found := findPhrases(words, phrase)
+	if len(found) == 0 {
+		return image.Rectangle{}, false
+	}
+	return found[0], true
+}
+
+// findPhrases returns the areas of all runs of words that read phrase, as
+// findPhrase matches them
+func findPhrases(words []ocrWord, phrase string) []image.Rectangle {
+	want := strings.Fields(phrase)
+	for i := range want {
+		want[i] = normalizeWord(want[i])
+	}
+	if len(want) == 0 {
+		return nil
+	}
+	var found []image.Rectangle
+	for start := 0; start+len(want) <= len(words); start++ {
+		rect := words[start].Rect
+		matched := true
+		for j, w := range want {
+			word := words[start+j]
+			if word.Line != words[start].Line || normalizeWord(word.Text) != w {
+				matched = false
+				break
+			}
+			rect = rect.Union(word.Rect)
+		}
+		if matched {
+			found = append(found, rect)
+		}
+	}
+	return found
+}
+
+// wordsIn returns the words that lie within area
+func wordsIn(words []ocrWord, area image.Rectangle) []ocrWord {
+	var inside []ocrWord
+	for _, w := range words {
+		if w.Rect.In(area) {
+			inside = append(inside, w)
+		}
+	}
+	return inside
+}
+
+// normalizeWord lowercases a word and trims the punctuation around it
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return unicode.IsPunct(r) }))
+}
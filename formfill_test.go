@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestValidateForm(t *testing.T) {
+	at := &FormPointInput{X: 10, Y: 20}
+	tests := []struct {
+		name    string
+		args    FillFormInput
+		wantErr string
+	}{
+		{"tab through", FillFormInput{Fields: []FormFieldInput{{Value: "a"}, {Value: "b"}}}, ""},
+		{"no fields", FillFormInput{}, "at least one field"},
+		{"text and at", FillFormInput{Fields: []FormFieldInput{{Text: "Name", At: at, Value: "a"}}}, "not both"},
+		{"offset without text", FillFormInput{Fields: []FormFieldInput{{At: at, OffsetX: 5, Value: "a"}}}, "need text"},
+		{"submit key", FillFormInput{Fields: []FormFieldInput{{Value: "a"}}, Submit: &FormSubmitInput{Key: "Return"}}, ""},
+		{"submit twice", FillFormInput{Fields: []FormFieldInput{{Value: "a"}}, Submit: &FormSubmitInput{Key: "Return", Text: "OK"}}, "exactly one"},
+		{"submit nothing", FillFormInput{Fields: []FormFieldInput{{Value: "a"}}, Submit: &FormSubmitInput{}}, "exactly one"},
+		{"bad key", FillFormInput{Fields: []FormFieldInput{{Value: "a"}}, Submit: &FormSubmitInput{Key: "NoSuchKey"}}, "submit key"},
+	}
+	for _, tt := range tests {
+		err := validateForm(tt.args)
+		if (tt.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: validateForm = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+const sampleTSV = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t800\t600\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t20\t30\t200\t20\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t20\t30\t40\t20\t96.5\tEmail\n" +
+	"5\t1\t1\t1\t1\t2\t64\t30\t70\t20\t95.1\taddress:\n" +
+	"5\t1\t1\t1\t2\t1\t20\t80\t60\t20\t93.0\tPassword\n" +
+	"5\t1\t2\t1\t1\t1\t300\t500\t50\t24\t91.2\tSign\n" +
+	"5\t1\t2\t1\t1\t2\t354\t500\t30\t24\t90.8\tin\n" +
+	"5\t1\t2\t1\t1\t3\t400\t500\t30\t24\t90.8\t \n"
+
+func TestParseTSV(t *testing.T) {
+	words := parseTSV(sampleTSV)
+	if len(words) != 5 {
+		t.Fatalf("got %d words, want 5: %+v", len(words), words)
+	}
+	if w := words[1]; w.Text != "address:" || w.Rect != image.Rect(64, 30, 134, 50) || w.Line != words[0].Line {
+		t.Errorf("second word = %+v", w)
+	}
+	if words[2].Line == words[0].Line {
+		t.Error("words on different lines share a line")
+	}
+}
+
+func TestFindPhrase(t *testing.T) {
+	words := parseTSV(sampleTSV)
+	tests := []struct {
+		phrase string
+		want   image.Rectangle
+		wantOK bool
+	}{
+		{"Email address", image.Rect(20, 30, 134, 50), true},
+		{"email ADDRESS:", image.Rect(20, 30, 134, 50), true},
+		{"password", image.Rect(20, 80, 80, 100), true},
+		{"Sign in", image.Rect(300, 500, 384, 524), true},
+		// Words on different lines don't form a phrase
+		{"address Password", image.Rectangle{}, false},
+		{"Username", image.Rectangle{}, false},
+		{"  ", image.Rectangle{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := findPhrase(words, tt.phrase)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("findPhrase(%q) = %v, %v, want %v, %v", tt.phrase, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFormText(t *testing.T) {
+	fields := []FormFieldInput{
+		{Text: "Email", Value: "a@b.c"},
+		{Value: "hunter2", Sensitive: true},
+		{At: &FormPointInput{X: 1, Y: 2}, Value: "x"},
+	}
+	reports := []fieldReport{
+		{Locator: fieldLocator(0, fields[0]), Status: fieldFilled, At: image.Pt(50, 40), Clicked: true},
+		{Locator: fieldLocator(1, fields[1]), Status: fieldFailed, Detail: "no keycode"},
+		{Locator: fieldLocator(2, fields[2]), Status: fieldSkipped},
+	}
+	got := formText(reports, false, nil, true, fields)
+	for _, want := range []string{
+		"Filled 1 of 3 field(s)",
+		`1. text "Email": filled "a@b.c", clicked at (50, 40)`,
+		"2. next field (Tab): failed (7 characters), no keycode",
+		"3. at (1, 2): skipped",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("sensitive value in report:\n%s", got)
+	}
+	if got := formText(reports[:1], false, nil, false, fields); strings.Contains(got, "a@b.c") {
+		t.Errorf("value echoed without echo:\n%s", got)
+	}
+	if got := formText(reports[:1], false, errors.New("text \"OK\" not found"), true, fields); !strings.Contains(got, "submit failed") {
+		t.Errorf("submit failure missing:\n%s", got)
+	}
+}
@@ -8,6 +8,7 @@ require (
 	github.com/linuxdeepin/go-x11-client v0.0.0-00010101000000-000000000000
 	github.com/modelcontextprotocol/go-sdk v0.2.0
 	go.i3wm.org/i3/v4 v4.24.0
+	golang.org/x/image v0.28.0
 )
 
 require (
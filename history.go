@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"mcp-x11-controller/x11"
+	"strings"
+	"sync"
+	"time"
+)
+
+// historyEntry is one screenshot kept by screenshotHistory
+type historyEntry struct {
+	Index  int // Increases with every screenshot taken, never reused
+	Time   time.Time
+	PNG    []byte
+	Window uint32      // Window the image was cropped to, 0 for the whole screen
+	Origin image.Point // Screen position of the image's top-left corner
+
+	Hash     uint64 // Perceptual hash of the image
+	Distance int    // Bits Hash differs from the previous screenshot of the same window or screen, -1 if there was none
+}
+
+// screenshotHistory keeps recent result screenshots so agents can look at
+// them again without holding the images in their context. The oldest
+// entries are dropped once either limit is exceeded.
+type screenshotHistory struct {
+	mu       sync.Mutex
+	maxCount int // 0 disables the history
+	maxBytes int
+	entries  []historyEntry
+	bytes    int
+	next     int
+	hashes   map[uint32]uint64 // Hash of the latest screenshot per window, 0 for the screen
+}
+
+// newScreenshotHistory creates a history bounded by count and total size
+func newScreenshotHistory(maxCount, maxBytes int) *screenshotHistory {
+	return &screenshotHistory{maxCount: maxCount, maxBytes: maxBytes, next: 1}
+}
+
+// add records a screenshot, assigning its index and time and comparing its
+// hash with the previous one of the same window or screen, and returns the
+// entry. If the history is disabled the entry gets index 0 and is not kept.
+func (h *screenshotHistory) add(entry historyEntry) historyEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry.Time = time.Now()
+	entry.Distance = -1
+	if prev, ok := h.hashes[entry.Window]; ok {
+		entry.Distance = x11.HashDistance(prev, entry.Hash)
+	}
+	if h.hashes == nil {
+		h.hashes = make(map[uint32]uint64)
+	}
+	h.hashes[entry.Window] = entry.Hash
+
+	if h.maxCount <= 0 {
+		return entry
+	}
+	entry.Index = h.next
+	h.next++
+	h.entries = append(h.entries, entry)
+	h.bytes += len(entry.PNG)
+
+	// Always keep the newest entry, even if it alone exceeds the budget
+	for len(h.entries) > 1 && (len(h.entries) > h.maxCount || (h.maxBytes > 0 && h.bytes > h.maxBytes)) {
+		h.bytes -= len(h.entries[0].PNG)
+		h.entries[0] = historyEntry{}
+		h.entries = h.entries[1:]
+	}
+	return entry
+}
+
+// byIndex returns a screenshot by its index. Negative indexes count back
+// from the newest screenshot, so -1 is the latest.
+func (h *screenshotHistory) byIndex(index int) (historyEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.entries) == 0 {
+		return historyEntry{}, fmt.Errorf("no screenshots in history")
+	}
+	if index < 0 {
+		if -index > len(h.entries) {
+			return historyEntry{}, fmt.Errorf("only %d screenshots in history", len(h.entries))
+		}
+		return h.entries[len(h.entries)+index], nil
+	}
+	first := h.entries[0].Index
+	if index < first || index-first >= len(h.entries) {
+		return historyEntry{}, fmt.Errorf("screenshot %d is not in history (have %d to %d)",
+			index, first, h.entries[len(h.entries)-1].Index)
+	}
+	return h.entries[index-first], nil
+}
+
+// byTime returns the newest screenshot taken at or before t
+func (h *screenshotHistory) byTime(t time.Time) (historyEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if !h.entries[i].Time.After(t) {
+			return h.entries[i], nil
+		}
+	}
+	return historyEntry{}, fmt.Errorf("no screenshot in history was taken at or before %s", t.Format(time.RFC3339))
+}
+
+// describe lists the screenshots in the history, oldest first
+func (h *screenshotHistory) describe() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.entries) == 0 {
+		return "No screenshots in history"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d screenshots in history:\n", len(h.entries))
+	for _, entry := range h.entries {
+		fmt.Fprintf(&b, "  index %d  %s  %d KiB  hash %016x", entry.Index, entry.Time.Format(time.RFC3339Nano), len(entry.PNG)/1024, entry.Hash)
+		if entry.Window != 0 {
+			fmt.Fprintf(&b, "  window 0x%x at (%d, %d)", entry.Window, entry.Origin.X, entry.Origin.Y)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScreenshotHistoryCountLimit(t *testing.T) {
+	h := newScreenshotHistory(3, 0)
+	for i := 0; i < 5; i++ {
+		h.add(historyEntry{PNG: []byte{byte(i)}})
+	}
+
+	if _, err := h.byIndex(2); err == nil {
+		t.Error("index 2 should have been dropped")
+	}
+	for index := 3; index <= 5; index++ {
+		entry, err := h.byIndex(index)
+		if err != nil {
+			t.Fatalf("byIndex(%d) error = %v", index, err)
+		}
+		if entry.Index != index || entry.PNG[0] != byte(index-1) {
+			t.Errorf("byIndex(%d) = index %d data %v", index, entry.Index, entry.PNG)
+		}
+	}
+
+	latest, err := h.byIndex(-1)
+	if err != nil || latest.Index != 5 {
+		t.Errorf("byIndex(-1) = %d, %v; want 5", latest.Index, err)
+	}
+	if entry, _ := h.byIndex(-3); entry.Index != 3 {
+		t.Errorf("byIndex(-3) = %d, want 3", entry.Index)
+	}
+	if _, err := h.byIndex(-4); err == nil {
+		t.Error("byIndex(-4) should fail with 3 entries")
+	}
+}
+
+func TestScreenshotHistoryByteLimit(t *testing.T) {
+	h := newScreenshotHistory(10, 250)
+	for i := 0; i < 3; i++ {
+		h.add(historyEntry{PNG: make([]byte, 100)})
+	}
+	if _, err := h.byIndex(1); err == nil {
+		t.Error("the oldest entry should have been dropped to fit the byte budget")
+	}
+	if h.bytes != 200 {
+		t.Errorf("bytes = %d, want 200", h.bytes)
+	}
+
+	// A single oversized screenshot is still kept
+	entry := h.add(historyEntry{PNG: make([]byte, 1000)})
+	if got, err := h.byIndex(-1); err != nil || got.Index != entry.Index {
+		t.Errorf("oversized screenshot was not kept: %v", err)
+	}
+	if len(h.entries) != 1 {
+		t.Errorf("entries = %d, want 1", len(h.entries))
+	}
+}
+
+func TestScreenshotHistoryByTime(t *testing.T) {
+	h := newScreenshotHistory(10, 0)
+	first := h.add(historyEntry{PNG: []byte("a")})
+	time.Sleep(2 * time.Millisecond)
+	second := h.add(historyEntry{PNG: []byte("b")})
+
+	if entry, err := h.byTime(second.Time.Add(-time.Microsecond)); err != nil || entry.Index != first.Index {
+		t.Errorf("byTime(before second) = %d, %v; want %d", entry.Index, err, first.Index)
+	}
+	if entry, err := h.byTime(time.Now()); err != nil || entry.Index != second.Index {
+		t.Errorf("byTime(now) = %d, %v; want %d", entry.Index, err, second.Index)
+	}
+	if _, err := h.byTime(first.Time.Add(-time.Second)); err == nil {
+		t.Error("byTime before the first screenshot should fail")
+	}
+}
+
+func TestScreenshotHistoryDisabled(t *testing.T) {
+	h := newScreenshotHistory(0, 0)
+	if entry := h.add(historyEntry{PNG: []byte("a")}); entry.Index != 0 {
+		t.Errorf("disabled history assigned index %d", entry.Index)
+	}
+	if _, err := h.byIndex(-1); err == nil {
+		t.Error("disabled history should be empty")
+	}
+	if !strings.Contains(h.describe(), "No screenshots") {
+		t.Errorf("describe() = %q", h.describe())
+	}
+}
+
+func TestScreenshotHistoryHashDistance(t *testing.T) {
+	// Distances are tracked even with the history disabled
+	h := newScreenshotHistory(0, 0)
+	if e := h.add(historyEntry{Hash: 0b1011}); e.Distance != -1 {
+		t.Errorf("first screenshot distance = %d, want -1", e.Distance)
+	}
+	if e := h.add(historyEntry{Hash: 0b1011}); e.Distance != 0 {
+		t.Errorf("unchanged screenshot distance = %d, want 0", e.Distance)
+	}
+	// Window crops are compared with earlier crops of the same window only
+	if e := h.add(historyEntry{Hash: 0, Window: 7}); e.Distance != -1 {
+		t.Errorf("first window crop distance = %d, want -1", e.Distance)
+	}
+	if e := h.add(historyEntry{Hash: 0b0001}); e.Distance != 2 {
+		t.Errorf("changed screenshot distance = %d, want 2", e.Distance)
+	}
+}
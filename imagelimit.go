@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/x11"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxImageBytes is the largest PNG sent in a tool result before it is
+// re-encoded smaller (--max-image-bytes), 0 for no limit
+var maxImageBytes int
+
+// fitImages is receiving middleware that re-encodes result images over
+// maxImageBytes with a palette and then at lower scales, since many MCP
+// clients cap the message size and break on 4K screenshots. Scaled images
+// get a note on how their coordinates map to the screen.
+func fitImages(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		if method != "tools/call" || err != nil || maxImageBytes <= 0 || client == nil {
+			return result, err
+		}
+		res, ok := result.(*mcp.CallToolResult)
+		if !ok {
+			return result, err
+		}
+
+		var fits []map[string]any
+		var notes []mcp.Content
+		for i, c := range res.Content {
+			img, ok := c.(*mcp.ImageContent)
+			if !ok || img.MIMEType != "image/png" || len(img.Data) <= maxImageBytes {
+				continue
+			}
+			fitted, err := client.FitPNG(img.Data, maxImageBytes)
+			if err != nil {
+				slog.Warn("failed to shrink an oversized image", "bytes", len(img.Data), "err", err)
+				continue
+			}
+			notes = append(notes, &mcp.TextContent{Text: fitText(len(img.Data), fitted)})
+			fits = append(fits, map[string]any{
+				"content": i,
+				"bytes":   len(fitted.PNG),
+				"scale":   fitted.Scale,
+				"width":   fitted.Width,
+				"height":  fitted.Height,
+				"fits":    fitted.Fits,
+			})
+			img.Data = fitted.PNG
+		}
+		if len(fits) == 0 {
+			return result, err
+		}
+		res.Content = append(res.Content, notes...)
+		if res.Meta == nil {
+			res.Meta = mcp.Meta{}
+		}
+		res.Meta["image_fit"] = fits
+		return result, err
+	}
+}
+
+// fitText describes an image re-encoded to fit maxImageBytes
+func fitText(original int, f x11.FittedPNG) string {
+	text := fmt.Sprintf("An image of %d bytes exceeded --max-image-bytes %d and was re-encoded with 256 colors", original, maxImageBytes)
+	if f.Scale < 1 {
+		text += fmt.Sprintf(" at %d%% size (%dx%d); divide its coordinates by %g for screen coordinates", int(f.Scale*100), f.Width, f.Height, f.Scale)
+	}
+	text += fmt.Sprintf(", now %d bytes", len(f.PNG))
+	if !f.Fits {
+		text += ", still over the limit at the smallest size"
+	}
+	return text
+}
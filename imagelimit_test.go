@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"math/rand"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestFitText(t *testing.T) {
+	saved := maxImageBytes
+	defer func() { maxImageBytes = saved }()
+	maxImageBytes = 1000
+
+	tests := []struct {
+		fitted x11.FittedPNG
+		want   []string
+	}{
+		{x11.FittedPNG{PNG: make([]byte, 900), Scale: 1, Fits: true}, []string{"256 colors, now 900 bytes"}},
+		{x11.FittedPNG{PNG: make([]byte, 800), Scale: 0.5, Width: 960, Height: 540, Fits: true}, []string{"at 50% size (960x540)", "divide its coordinates by 0.5"}},
+		{x11.FittedPNG{PNG: make([]byte, 1200), Scale: 0.25, Width: 480, Height: 270}, []string{"still over the limit"}},
+	}
+	for _, tt := range tests {
+		text := fitText(5000, tt.fitted)
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("fitText(%+v) = %q, want %q in it", tt.fitted, text, want)
+			}
+		}
+	}
+}
+
+func TestFitImages(t *testing.T) {
+	savedLimit, savedClient := maxImageBytes, client
+	defer func() { maxImageBytes, client = savedLimit, savedClient }()
+	client = &x11.Client{}
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	rand.New(rand.NewSource(1)).Read(img.Pix)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			&mcp.TextContent{Text: "Clicked"},
+			&mcp.ImageContent{Data: bytes.Clone(buf.Bytes()), MIMEType: "image/png"},
+		}}, nil
+	}
+	handler := fitImages(next)
+
+	// Without a limit images pass unchanged
+	maxImageBytes = 0
+	result, _ := handler(context.Background(), nil, "tools/call", nil)
+	if res := result.(*mcp.CallToolResult); len(res.Content) != 2 || res.Meta != nil {
+		t.Errorf("unexpected changes without a limit: %+v", res)
+	}
+
+	maxImageBytes = buf.Len() / 10
+	result, _ = handler(context.Background(), nil, "tools/call", nil)
+	res := result.(*mcp.CallToolResult)
+	if len(res.Content) != 3 {
+		t.Fatalf("got %d content items, want the note added", len(res.Content))
+	}
+	if got := len(res.Content[1].(*mcp.ImageContent).Data); got > maxImageBytes {
+		t.Errorf("image is %d bytes, over the limit %d", got, maxImageBytes)
+	}
+	if _, ok := res.Meta["image_fit"]; !ok {
+		t.Error("image_fit missing from meta")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"mcp-x11-controller/x11"
+)
+
+// Typing methods of x11_type_text
+const (
+	typeMethodXTest     = x11.InputXTest     // XTEST key events, as for the other input tools
+	typeMethodSendEvent = x11.InputSendEvent // Synthetic key events sent to the focused window
+	typeMethodIM        = "im"               // Commit through the running input method
+	typeMethodPaste     = "paste"            // Put the text on the clipboard and press ctrl+v
+	typeMethodAuto      = "auto"             // The input method if one that can commit runs and answers, else xtest
+)
+
+// typeMethods are the accepted values of x11_type_text's method
+var typeMethods = []string{typeMethodXTest, typeMethodSendEvent, typeMethodIM, typeMethodPaste, typeMethodAuto}
+
+// imCommitTimeout bounds one input method commit, engine switches included
+const imCommitTimeout = 5 * time.Second
+
+// imDaemons maps process names of input method daemons to their names
+var imDaemons = map[string]string{
+	"ibus-daemon": "ibus",
+	"fcitx5":      "fcitx5",
+	"fcitx":       "fcitx",
+}
+
+// ibusCommitScript commits its stdin to the application with IBus input focus.
+// The text isn't an argument, which any local user could read from /proc.
+// IBus only lets engines commit text, so it registers a throwaway engine,
+// switches to it, commits on focus and switches back to the previous engine.
+const ibusCommitScript = `
+import sys
+import gi
+gi.require_version("IBus", "1.0")
+from gi.repository import GLib, GObject, IBus
+
+text = sys.stdin.buffer.read().decode("utf-8")
+IBus.init()
+bus = IBus.Bus()
+if not bus.is_connected():
+    sys.exit("IBus daemon is not reachable")
+if not bus.current_input_context():
+    sys.exit("no application has IBus input focus")
+previous = bus.get_global_engine()
+loop = GLib.MainLoop()
+state = {"done": False}
+
+def finish():
+    if previous is not None:
+        bus.set_global_engine(previous.get_name())
+    loop.quit()
+    return False
+
+class CommitEngine(IBus.Engine):
+    __gtype_name__ = "McpCommitEngine"
+
+    def do_focus_in(self):
+        if not state["done"]:
+            state["done"] = True
+            self.commit_text(IBus.Text.new_from_string(text))
+            GLib.timeout_add(100, finish)
+
+factory = IBus.Factory.new(bus.get_connection())
+factory.add_engine("mcp-commit", GObject.type_from_name("McpCommitEngine"))
+component = IBus.Component(name="org.mcpx11.Commit", description="mcp-x11-controller text commit",
+                           version="1", license="MIT", author="", homepage="", command_line="", textdomain="")
+component.add_engine(IBus.EngineDesc(name="mcp-commit", longname="mcp-x11-controller commit",
+                                     description="", language="other", license="MIT", author="",
+                                     icon="", layout="default"))
+bus.register_component(component)
+if not bus.set_global_engine("mcp-commit"):
+    sys.exit("failed to switch IBus to the commit engine")
+GLib.timeout_add(int(sys.argv[1]), finish)
+loop.run()
+if not state["done"]:
+    sys.exit("IBus did not focus the commit engine")
+`
+
+// parseTypeMethod validates an x11_type_text method, defaulting to xtest
+func parseTypeMethod(method string) (string, error) {
+	if method == "" {
+		return typeMethodXTest, nil
+	}
+	method = strings.ToLower(method)
+	for _, m := range typeMethods {
+		if m == method {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unknown typing method: %s (available: %s)", method, strings.Join(typeMethods, ", "))
+}
+
+// runningInputMethod returns the name of the input method daemon that this
+// user runs for display, or "" if there is none. Daemons of other users or
+// other displays don't serve the applications we type into.
+func runningInputMethod(display string) string {
+	comms, _ := filepath.Glob("/proc/[0-9]*/comm")
+	for _, path := range comms {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		name, ok := imDaemons[strings.TrimSpace(string(data))]
+		if !ok {
+			continue
+		}
+		dir := filepath.Dir(path)
+		if info, err := os.Stat(dir); err != nil || info.Sys().(*syscall.Stat_t).Uid != uint32(os.Getuid()) {
+			continue
+		}
+		environ, err := os.ReadFile(filepath.Join(dir, "environ"))
+		if err != nil || !sameDisplay(environDisplay(environ), display) {
+			continue
+		}
+		return name
+	}
+	return ""
+}
+
+// environDisplay returns DISPLAY from the NUL separated contents of a
+// /proc/<pid>/environ file
+func environDisplay(environ []byte) string {
+	for _, kv := range bytes.Split(environ, []byte{0}) {
+		if value, ok := bytes.CutPrefix(kv, []byte("DISPLAY=")); ok {
+			return string(value)
+		}
+	}
+	return ""
+}
+
+// sameDisplay reports whether two DISPLAY values name the same display,
+// whatever screen they select
+func sameDisplay(a, b string) bool {
+	trim := func(d string) string {
+		if i := strings.LastIndex(d, ":"); i >= 0 {
+			if j := strings.Index(d[i:], "."); j >= 0 {
+				d = d[:i+j]
+			}
+		}
+		return strings.TrimPrefix(d, "unix")
+	}
+	return a != "" && trim(a) == trim(b)
+}
+
+// imEnv is the environment of input method helpers, with DISPLAY set to the
+// display we control, which IBus derives its bus address from
+func imEnv(display string) []string {
+	return append(os.Environ(), "DISPLAY="+display)
+}
+
+// ibusReachable reports whether the IBus daemon answers on display; a daemon
+// can run without a bus this process can connect to
+func ibusReachable(ctx context.Context, display string) bool {
+	if _, err := exec.LookPath("python3"); err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, imCommitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "python3", "-c", ibusProbeScript)
+	cmd.Env = imEnv(display)
+	return cmd.Run() == nil
+}
+
+// ibusProbeScript exits with an error if the IBus daemon isn't reachable
+const ibusProbeScript = `
+import sys
+import gi
+gi.require_version("IBus", "1.0")
+from gi.repository import IBus
+
+IBus.init()
+if not IBus.Bus().is_connected():
+    sys.exit("IBus daemon is not reachable")
+`
+
+// autoTypeMethod resolves method auto: the input method if one that can
+// commit runs for display and answers, else xtest
+func autoTypeMethod(ctx context.Context, display string) string {
+	if im := runningInputMethod(display); imCanCommit(im) && ibusReachable(ctx, display) {
+		return typeMethodIM
+	}
+	return typeMethodXTest
+}
+
+// imCanCommit reports whether text can be committed through the input
+// method; only IBus lets other programs do that
+func imCanCommit(im string) bool {
+	return im == "ibus"
+}
+
+// imCommit commits text to the focused application through the running
+// input method and returns the input method's name. Applications that ignore
+// injected keysyms, like many CJK ones, accept such commits.
+func imCommit(ctx context.Context, display, text string) (string, error) {
+	im := runningInputMethod(display)
+	switch {
+	case im == "":
+		return "", fmt.Errorf("no input method is running for display %s (looked for ibus-daemon, fcitx5 and fcitx)", display)
+	case !imCanCommit(im):
+		return im, fmt.Errorf("%s does not let other programs commit text; use method %q", im, typeMethodXTest)
+	}
+	if _, err := exec.LookPath("python3"); err != nil {
+		return im, fmt.Errorf("python3 not found")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, imCommitTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	wait := imCommitTimeout - time.Second
+	cmd := exec.CommandContext(ctx, "python3", "-c", ibusCommitScript, fmt.Sprint(wait.Milliseconds()))
+	cmd.Env = imEnv(display)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if lines := strings.Split(msg, "\n"); msg != "" {
+			// Keep the last line, the exception or exit message
+			return im, fmt.Errorf("failed to commit text through %s: %s", im, lines[len(lines)-1])
+		}
+		return im, fmt.Errorf("failed to commit text through %s: %w", im, err)
+	}
+	return im, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestParseTypeMethod(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", typeMethodXTest, false},
+		{"XTest", typeMethodXTest, false},
+		{"keys", "", true},
+		{"IM", typeMethodIM, false},
+		{"SendEvent", typeMethodSendEvent, false},
+		{"paste", typeMethodPaste, false},
+		{"auto", typeMethodAuto, false},
+		{"xim", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseTypeMethod(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseTypeMethod(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestIMCanCommit(t *testing.T) {
+	for im, want := range map[string]bool{"ibus": true, "fcitx5": false, "fcitx": false, "": false} {
+		if got := imCanCommit(im); got != want {
+			t.Errorf("imCanCommit(%q) = %t, want %t", im, got, want)
+		}
+	}
+}
+
+func TestEnvironDisplay(t *testing.T) {
+	if got := environDisplay([]byte("HOME=/root\x00DISPLAY=:3\x00TERM=xterm\x00")); got != ":3" {
+		t.Errorf("environDisplay = %q, want :3", got)
+	}
+	if got := environDisplay([]byte("HOME=/root\x00XDISPLAY=:3\x00")); got != "" {
+		t.Errorf("environDisplay without DISPLAY = %q, want empty", got)
+	}
+}
+
+func TestSameDisplay(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{":0", ":0", true},
+		{":0.0", ":0", true},
+		{"unix:1", ":1", true},
+		{":1", ":10", false},
+		{"host:0", ":0", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := sameDisplay(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameDisplay(%q, %q) = %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
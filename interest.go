@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Limits of the windows of interest attached to results
+const (
+	maxInterestWindows = 4
+	interestThumbWidth = 320
+)
+
+// windowMatcher selects windows by a regular expression on their class,
+// title or either
+type windowMatcher struct {
+	field string // "class", "title" or "" for either
+	re    *regexp.Regexp
+}
+
+// interestMatchers are the --window-of-interest patterns
+var interestMatchers []windowMatcher
+
+// parseWindowMatcher parses class=REGEX, title=REGEX or a bare REGEX that
+// matches the class or the title
+func parseWindowMatcher(spec string) (windowMatcher, error) {
+	var m windowMatcher
+	pattern := spec
+	if field, rest, ok := strings.Cut(spec, "="); ok && (field == "class" || field == "title") {
+		m.field, pattern = field, rest
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return windowMatcher{}, fmt.Errorf("invalid window pattern %q: %w", spec, err)
+	}
+	m.re = re
+	return m, nil
+}
+
+// matches returns true if the window's class or title, as selected, match
+func (m windowMatcher) matches(win x11.Window) bool {
+	switch m.field {
+	case "class":
+		return m.re.MatchString(win.Class)
+	case "title":
+		return m.re.MatchString(win.Title)
+	}
+	return m.re.MatchString(win.Class) || m.re.MatchString(win.Title)
+}
+
+// windowsOfInterest returns the windows matched by any of matchers, at most
+// maxInterestWindows
+func windowsOfInterest(windows []x11.Window, matchers []windowMatcher) []x11.Window {
+	var matched []x11.Window
+	for _, win := range windows {
+		for _, m := range matchers {
+			if m.matches(win) {
+				matched = append(matched, win)
+				break
+			}
+		}
+		if len(matched) == maxInterestWindows {
+			break
+		}
+	}
+	return matched
+}
+
+// reportInterest is receiving middleware that appends thumbnails of the
+// windows of interest to every successful result carrying a screenshot, so
+// the app under test stays in view when other windows take the focus
+func reportInterest(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		if method != "tools/call" || err != nil || len(interestMatchers) == 0 {
+			return result, err
+		}
+		res, ok := result.(*mcp.CallToolResult)
+		if !ok || res.IsError || !hasImage(res.Content) {
+			return result, err
+		}
+
+		windows, lerr := client.ListWindowsContext(ctx)
+		if lerr != nil {
+			slog.Debug("failed to list windows of interest", "err", lerr)
+			return result, err
+		}
+		var meta []map[string]any
+		for _, win := range windowsOfInterest(windows, interestMatchers) {
+			entry := map[string]any{"window_id": uint32(win.ID), "title": win.Title, "class": win.Class}
+			text := fmt.Sprintf("Window of interest 0x%x %q (%s)", uint32(win.ID), win.Title, orNone(win.Class))
+			pngData, terr := client.WindowThumbnailPNG(ctx, win.ID, interestThumbWidth)
+			if terr != nil {
+				entry["error"] = terr.Error()
+				res.Content = append(res.Content, &mcp.TextContent{Text: text + ": " + terr.Error()})
+			} else {
+				res.Content = append(res.Content,
+					&mcp.TextContent{Text: text},
+					&mcp.ImageContent{Data: pngData, MIMEType: "image/png"})
+			}
+			meta = append(meta, entry)
+		}
+		if len(meta) > 0 {
+			if res.Meta == nil {
+				res.Meta = mcp.Meta{}
+			}
+			res.Meta["windows_of_interest"] = meta
+		}
+		return result, err
+	}
+}
+
+// hasImage returns true if the content includes an image
+func hasImage(content []mcp.Content) bool {
+	for _, c := range content {
+		if _, ok := c.(*mcp.ImageContent); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"mcp-x11-controller/x11"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseWindowMatcher(t *testing.T) {
+	windows := []x11.Window{
+		{ID: 1, Title: "Untitled - gedit", Class: "gedit"},
+		{ID: 2, Title: "Mozilla Firefox", Class: "firefox"},
+		{ID: 3, Title: "xterm", Class: "XTerm"},
+	}
+	tests := []struct {
+		spec string
+		want []uint32
+	}{
+		{"class=^firefox$", []uint32{2}},
+		{"title=gedit", []uint32{1}},
+		{"(?i)xterm", []uint32{3}},
+		{"class=Mozilla", nil},
+		{"e", []uint32{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		m, err := parseWindowMatcher(tt.spec)
+		if err != nil {
+			t.Fatalf("parseWindowMatcher(%q) failed: %v", tt.spec, err)
+		}
+		var got []uint32
+		for _, win := range windowsOfInterest(windows, []windowMatcher{m}) {
+			got = append(got, uint32(win.ID))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q matched %v, want %v", tt.spec, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q matched %v, want %v", tt.spec, got, tt.want)
+			}
+		}
+	}
+	if _, err := parseWindowMatcher("class=("); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
+
+func TestWindowsOfInterestLimit(t *testing.T) {
+	var windows []x11.Window
+	for i := 0; i < 10; i++ {
+		windows = append(windows, x11.Window{ID: 1, Class: "app"})
+	}
+	m, _ := parseWindowMatcher("class=app")
+	if got := windowsOfInterest(windows, []windowMatcher{m}); len(got) != maxInterestWindows {
+		t.Errorf("got %d windows, want at most %d", len(got), maxInterestWindows)
+	}
+}
+
+func TestReportInterestWithoutMatchers(t *testing.T) {
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.ImageContent{MIMEType: "image/png"}}}, nil
+	}
+	result, err := reportInterest(next)(context.Background(), nil, "tools/call", nil)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if res := result.(*mcp.CallToolResult); len(res.Content) != 1 || res.Meta != nil {
+		t.Errorf("result changed without windows of interest: %+v", res)
+	}
+}
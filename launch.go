@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mcp-x11-controller/x11"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// launchTemplates are the templates x11_launch starts, from --launch-templates
+var launchTemplates map[string]x11.LaunchTemplate
+
+// templateNamePattern is what launch template names may look like; they
+// are typed in x11_launch calls, so they stay short identifiers
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// loadLaunchTemplates reads a JSON object of named launch templates
+func loadLaunchTemplates(path string) (map[string]x11.LaunchTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read launch templates: %w", err)
+	}
+	templates, err := parseLaunchTemplates(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse launch templates %s: %w", path, err)
+	}
+	return templates, nil
+}
+
+// parseLaunchTemplates parses and validates launch templates; unknown keys
+// are an error so typos don't go unnoticed
+func parseLaunchTemplates(data []byte) (map[string]x11.LaunchTemplate, error) {
+	var templates map[string]x11.LaunchTemplate
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&templates); err != nil {
+		return nil, err
+	}
+	for name, t := range templates {
+		if !templateNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid template name %q: use letters, digits, - and _", name)
+		}
+		if err := t.Validate(); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return templates, nil
+}
+
+// templateNames returns the names of the launch templates, sorted
+func templateNames(templates map[string]x11.LaunchTemplate) []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// launchTemplate returns the template called name
+func launchTemplate(name string) (x11.LaunchTemplate, error) {
+	t, ok := launchTemplates[name]
+	if !ok {
+		if len(launchTemplates) == 0 {
+			return t, fmt.Errorf("unknown template %q: no --launch-templates file is configured", name)
+		}
+		return t, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(templateNames(launchTemplates), ", "))
+	}
+	return t, nil
+}
+
+// launchText describes a launched template for the x11_launch result
+func launchText(name string, l x11.Launched) string {
+	text := fmt.Sprintf("Launched %s with PID %d", name, l.PID)
+	if l.Window != 0 {
+		text += fmt.Sprintf(", window 0x%x %q ready after %s", uint32(l.Window), l.Title, l.Elapsed.Round(time.Millisecond))
+	}
+	if l.PageLoad != nil && !l.PageLoad.Loaded {
+		text += fmt.Sprintf(", page still loading (%s)", strings.Join(l.PageLoad.Busy, ", "))
+	}
+	return text
+}
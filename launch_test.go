@@ -0,0 +1,54 @@
+package main
+
+import (
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+)
+
+func TestParseLaunchTemplates(t *testing.T) {
+	templates, err := parseLaunchTemplates([]byte(`{
+		"firefox": {"command": "firefox", "args": ["--kiosk"], "window_class": "firefox", "ready": "pageload", "timeout_ms": 8000},
+		"term": {"command": "xterm", "env": {"LANG": "C"}}
+	}`))
+	if err != nil {
+		t.Fatalf("parseLaunchTemplates failed: %v", err)
+	}
+	if got := templateNames(templates); strings.Join(got, ",") != "firefox,term" {
+		t.Errorf("templateNames = %v", got)
+	}
+	if ff := templates["firefox"]; ff.Readiness() != x11.ReadyPageLoad || ff.TimeoutMS != 8000 || ff.Args[0] != "--kiosk" {
+		t.Errorf("firefox template = %+v", ff)
+	}
+	if templates["term"].Env["LANG"] != "C" {
+		t.Errorf("term template = %+v", templates["term"])
+	}
+
+	for _, data := range []string{
+		`{"a": {"command": "xterm", "windowclass": "XTerm"}}`,
+		`{"a": {"args": ["x"]}}`,
+		`{"a b": {"command": "xterm"}}`,
+		`["xterm"]`,
+	} {
+		if _, err := parseLaunchTemplates([]byte(data)); err == nil {
+			t.Errorf("parseLaunchTemplates(%s) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestLaunchTemplate(t *testing.T) {
+	saved := launchTemplates
+	defer func() { launchTemplates = saved }()
+
+	launchTemplates = nil
+	if _, err := launchTemplate("firefox"); err == nil || !strings.Contains(err.Error(), "--launch-templates") {
+		t.Errorf("launchTemplate without templates = %v", err)
+	}
+	launchTemplates = map[string]x11.LaunchTemplate{"term": {Command: "xterm"}}
+	if _, err := launchTemplate("firefox"); err == nil || !strings.Contains(err.Error(), "available: term") {
+		t.Errorf("launchTemplate of an unknown name = %v", err)
+	}
+	if got, err := launchTemplate("term"); err != nil || got.Command != "xterm" {
+		t.Errorf("launchTemplate(term) = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxLoggedText bounds how much of a text payload is written to the log
+const maxLoggedText = 200
+
+// mcpLevels maps slog levels to MCP logging levels
+var mcpLevels = map[slog.Level]mcp.LoggingLevel{
+	slog.LevelDebug: "debug",
+	slog.LevelInfo:  "info",
+	slog.LevelWarn:  "warning",
+	slog.LevelError: "error",
+}
+
+// parseLogLevel parses a --log-level value such as "debug" or "warn"
+func parseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level: %s", name)
+	}
+	return level, nil
+}
+
+// newLogHandler creates the stderr handler for a --log-format value
+func newLogHandler(w io.Writer, level slog.Level, format string) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("unknown log format: %s", format)
+}
+
+// sessionHandler writes records to a base handler and forwards records at
+// Info and above to every connected MCP client as logging notifications.
+// Clients only receive them after setting a log level.
+type sessionHandler struct {
+	base   slog.Handler
+	server *atomic.Pointer[mcp.Server]
+	attrs  []slog.Attr
+	group  string
+}
+
+// newSessionHandler wraps base; the server is attached later with attach
+func newSessionHandler(base slog.Handler) *sessionHandler {
+	return &sessionHandler{base: base, server: new(atomic.Pointer[mcp.Server])}
+}
+
+// attach starts forwarding records to the sessions of server
+func (h *sessionHandler) attach(server *mcp.Server) {
+	h.server.Store(server)
+}
+
+func (h *sessionHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.base.Enabled(ctx, level)
+}
+
+func (h *sessionHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.base.Handle(ctx, r)
+
+	server := h.server.Load()
+	if server == nil || r.Level < slog.LevelInfo {
+		return err
+	}
+	params := &mcp.LoggingMessageParams{
+		Logger: "x11-controller",
+		Level:  mcpLevel(r.Level),
+		Data:   h.recordData(r),
+	}
+	for session := range server.Sessions() {
+		// Best effort: a client that went away must not break logging
+		session.Log(ctx, params)
+	}
+	return err
+}
+
+func (h *sessionHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.base = h.base.WithAttrs(attrs)
+	h2.attrs = append(append([]slog.Attr(nil), h.attrs...), h.prefixed(attrs)...)
+	return &h2
+}
+
+func (h *sessionHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.base = h.base.WithGroup(name)
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// prefixed qualifies attribute keys with the current group
+func (h *sessionHandler) prefixed(attrs []slog.Attr) []slog.Attr {
+	if h.group == "" {
+		return attrs
+	}
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = slog.Attr{Key: h.group + a.Key, Value: a.Value}
+	}
+	return out
+}
+
+// recordData flattens a record into the JSON object sent to clients
+func (h *sessionHandler) recordData(r slog.Record) map[string]any {
+	data := map[string]any{"msg": r.Message}
+	for _, a := range h.attrs {
+		data[a.Key] = logValue(a.Value.Resolve())
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		data[h.group+a.Key] = logValue(a.Value.Resolve())
+		return true
+	})
+	return data
+}
+
+// logValue converts a value to something JSON can encode; errors would
+// otherwise marshal as empty objects
+func logValue(v slog.Value) any {
+	if err, ok := v.Any().(error); ok {
+		return err.Error()
+	}
+	return v.Any()
+}
+
+// mcpLevel converts a slog level to the closest MCP logging level
+func mcpLevel(level slog.Level) mcp.LoggingLevel {
+	switch {
+	case level >= slog.LevelError:
+		return mcpLevels[slog.LevelError]
+	case level >= slog.LevelWarn:
+		return mcpLevels[slog.LevelWarn]
+	case level >= slog.LevelInfo:
+		return mcpLevels[slog.LevelInfo]
+	}
+	return mcpLevels[slog.LevelDebug]
+}
+
+// nameXActions is receiving middleware that names the tool in the context
+// of its X requests, so the X errors they cause tell which call made them
+func nameXActions(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage]); ok && method == "tools/call" {
+			ctx = x11.WithAction(ctx, call.Name)
+		}
+		return next(ctx, session, method, params)
+	}
+}
+
+// logToolCalls is receiving middleware that logs every tool call and its
+// result at debug level, eliding image payloads
+func logToolCalls(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok || !slog.Default().Enabled(ctx, slog.LevelDebug) {
+			return next(ctx, session, method, params)
+		}
+
+		slog.DebugContext(ctx, "tool call", "tool", call.Name, "args", truncate(string(loggedArgs(call.Arguments))))
+		start := time.Now()
+		result, err := next(ctx, session, method, params)
+		elapsed := time.Since(start).Milliseconds()
+		if err != nil {
+			slog.DebugContext(ctx, "tool failed", "tool", call.Name, "ms", elapsed, "err", err)
+			return result, err
+		}
+		attrs := []any{"tool", call.Name, "ms", elapsed}
+		if res, ok := result.(*mcp.CallToolResult); ok {
+			attrs = append(attrs, "is_error", res.IsError, "content", summarizeContent(res.Content))
+		}
+		slog.DebugContext(ctx, "tool result", attrs...)
+		return result, err
+	}
+}
+
+// loggedArgs returns tool call arguments as they are logged: with
+// "sensitive": true, the "text" argument is replaced by its length, as is
+// the "value" of x11_fill_form fields marked sensitive
+func loggedArgs(args json.RawMessage) json.RawMessage {
+	var fields map[string]any
+	if json.Unmarshal(args, &fields) != nil || !redactArgs(fields, false) {
+		return args
+	}
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return args
+	}
+	return redacted
+}
+
+// redactArgs replaces typed text in decoded tool arguments by its length,
+// all of it or only where "sensitive" is true, and reports whether it did
+func redactArgs(fields map[string]any, all bool) bool {
+	redact := func(m map[string]any, key string) bool {
+		text, ok := m[key].(string)
+		if !ok || (!all && m["sensitive"] != true) {
+			return false
+		}
+		m[key] = fmt.Sprintf("[%d characters]", utf8.RuneCountInString(text))
+		return true
+	}
+	redacted := redact(fields, "text")
+	if form, ok := fields["fields"].([]any); ok {
+		for _, f := range form {
+			if field, ok := f.(map[string]any); ok && redact(field, "value") {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// summarizeContent describes result content for the log, replacing binary
+// payloads with their type and size
+func summarizeContent(content []mcp.Content) []string {
+	summary := make([]string, 0, len(content))
+	for _, c := range content {
+		switch c := c.(type) {
+		case *mcp.TextContent:
+			summary = append(summary, truncate(c.Text))
+		case *mcp.ImageContent:
+			summary = append(summary, fmt.Sprintf("<%s, %d bytes>", c.MIMEType, len(c.Data)))
+		case *mcp.AudioContent:
+			summary = append(summary, fmt.Sprintf("<%s, %d bytes>", c.MIMEType, len(c.Data)))
+		default:
+			summary = append(summary, fmt.Sprintf("<%T>", c))
+		}
+	}
+	return summary
+}
+
+// truncate shortens text for the log
+func truncate(text string) string {
+	if len(text) <= maxLoggedText {
+		return text
+	}
+	return text[:maxLoggedText] + "..."
+}
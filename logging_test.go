@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"loud", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLogLevel(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseLogLevel(%q) = %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNewLogHandlerJSON(t *testing.T) {
+	var out bytes.Buffer
+	handler, err := newLogHandler(&out, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatalf("newLogHandler() error = %v", err)
+	}
+	logger := slog.New(newSessionHandler(handler))
+	logger.Debug("hidden")
+	logger.Info("shown", "pid", 42)
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", out.String(), err)
+	}
+	if record["msg"] != "shown" || record["pid"] != float64(42) {
+		t.Errorf("record = %v", record)
+	}
+
+	if _, err := newLogHandler(&out, slog.LevelInfo, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestSessionHandlerRecordData(t *testing.T) {
+	h := newSessionHandler(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	child := h.WithAttrs([]slog.Attr{slog.String("tool", "x11_click_at")}).WithGroup("x11").(*sessionHandler)
+
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "click failed", 0)
+	r.AddAttrs(slog.Any("err", errors.New("boom")))
+	data := child.recordData(r)
+
+	want := map[string]any{"msg": "click failed", "tool": "x11_click_at", "x11.err": "boom"}
+	for key, value := range want {
+		if data[key] != value {
+			t.Errorf("data[%q] = %v, want %v", key, data[key], value)
+		}
+	}
+}
+
+func TestMCPLevel(t *testing.T) {
+	tests := map[slog.Level]mcp.LoggingLevel{
+		slog.LevelDebug:     "debug",
+		slog.LevelInfo:      "info",
+		slog.LevelInfo + 2:  "info",
+		slog.LevelWarn:      "warning",
+		slog.LevelError:     "error",
+		slog.LevelError + 4: "error",
+	}
+	for level, want := range tests {
+		if got := mcpLevel(level); got != want {
+			t.Errorf("mcpLevel(%v) = %q, want %q", level, got, want)
+		}
+	}
+}
+
+func TestSummarizeContent(t *testing.T) {
+	content := []mcp.Content{
+		&mcp.TextContent{Text: "Clicked at (10, 20)"},
+		&mcp.ImageContent{MIMEType: "image/png", Data: make([]byte, 2048)},
+		&mcp.TextContent{Text: strings.Repeat("x", maxLoggedText+50)},
+	}
+	got := summarizeContent(content)
+
+	if got[0] != "Clicked at (10, 20)" {
+		t.Errorf("text summary = %q", got[0])
+	}
+	if got[1] != "<image/png, 2048 bytes>" {
+		t.Errorf("image summary = %q", got[1])
+	}
+	if len(got[2]) != maxLoggedText+3 || !strings.HasSuffix(got[2], "...") {
+		t.Errorf("long text was not truncated: %d bytes", len(got[2]))
+	}
+}
+
+func TestLogToolCallsPassesThrough(t *testing.T) {
+	called := false
+	next := func(ctx context.Context, _ *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		called = true
+		return nil, nil
+	}
+	if _, err := logToolCalls(next)(context.Background(), nil, "tools/list", nil); err != nil || !called {
+		t.Errorf("middleware did not pass the request through: called=%v err=%v", called, err)
+	}
+}
+
+func TestLoggedArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{`{"text":"hello"}`, `{"text":"hello"}`},
+		{`{"sensitive":true,"text":"hunter2"}`, `{"sensitive":true,"text":"[7 characters]"}`},
+		{`{"sensitive":false,"text":"hello"}`, `{"sensitive":false,"text":"hello"}`},
+		{`not json`, `not json`},
+		{`{"fields":[{"text":"User","value":"bob"},{"sensitive":true,"value":"hunter2"}]}`, `{"fields":[{"text":"User","value":"bob"},{"sensitive":true,"value":"[7 characters]"}]}`},
+	}
+	for _, tt := range tests {
+		if got := string(loggedArgs(json.RawMessage(tt.args))); got != tt.want {
+			t.Errorf("loggedArgs(%s) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
@@ -1,71 +1,577 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"image"
+	"log/slog"
+	"maps"
+	"mcp-x11-controller/config"
 	"mcp-x11-controller/x11"
+	"mcp-x11-controller/x11/vision"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
+	x "github.com/linuxdeepin/go-x11-client"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 var client *x11.Client
 
+var delays delayConfig
+
+var history = newScreenshotHistory(0, 0)
+
+var wmBindingDefault = "warn"
+
+// echoTypedText is whether x11_type_text results repeat the text (--echo-typed-text)
+var echoTypedText = true
+
+// pointerTrail is whether result screenshots show recent pointer events (--pointer-trail)
+var pointerTrail bool
+
+// defaultTerminalLines is how many lines x11_read_terminal returns by default
+const defaultTerminalLines = 50
+
+// defaultScrollSteps is how far x11_scroll turns the wheel by default
+const defaultScrollSteps = 3
+
+// defaultImageMatches is how many matches x11_find_image returns by default
+const defaultImageMatches = 5
+
+// Defaults of x11_wait_page_loaded
+const (
+	defaultPageLoadTimeout = 30 * time.Second
+	defaultPageLoadQuiet   = 1500 * time.Millisecond
+)
+
+// defaultWindowWaitTimeout is how long x11_wait_for_window waits by default
+const defaultWindowWaitTimeout = 30 * time.Second
+
+// inputTools are the tools that change the display, left out in observer mode
+var inputTools = []string{
+	"x11_click_at",
+	"x11_scroll",
+	"x11_drag",
+	"x11_select_text",
+	"x11_paste_primary_at",
+	"x11_set_clipboard",
+	"x11_type_text",
+	"x11_type_secret",
+	"x11_start_program",
+	"x11_launch",
+	"x11_open_url",
+	"x11_key_press",
+	"x11_key_sequence",
+	"x11_dismiss",
+	"x11_abort_all",
+	"x11_select_file_in_dialog",
+	"x11_fill_form",
+	"x11_layout",
+	"x11_move_resize_window",
+	"x11_focus_window",
+	"x11_iconify_window",
+	"x11_deiconify_window",
+	"x11_confine_pointer",
+	"i3_cmd",
+	"i3_fullscreen",
+	"i3_border",
+	"i3_screenshot_workspace",
+}
+
 // Tool input types
-type GetScreenInfoInput struct{}
+type GetScreenInfoInput struct {
+	IncludeScreenshot bool `json:"include_screenshot,omitempty" jsonschema:"description,Also return a screenshot (default false)"`
+	FocusedWindowOnly bool `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type TakeScreenshotInput struct {
+	FocusedWindowOnly bool `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
 
-type TakeScreenshotInput struct{}
+type ScreenshotRegionInput struct {
+	X        int    `json:"x,omitempty" jsonschema:"description,Left edge of the region in screen coordinates"`
+	Y        int    `json:"y,omitempty" jsonschema:"description,Top edge of the region"`
+	Width    int    `json:"width,omitempty" jsonschema:"description,Width of the region in pixels"`
+	Height   int    `json:"height,omitempty" jsonschema:"description,Height of the region in pixels"`
+	WindowID uint32 `json:"window_id,omitempty" jsonschema:"description,Capture this window instead of a region"`
+}
 
 type ClickAtInput struct {
-	X      float64 `json:"x" jsonschema:"required"`
-	Y      float64 `json:"y" jsonschema:"required"`
-	Button int     `json:"button,omitempty"`
-	Delay  int     `json:"delay,omitempty"`
+	X                 float64 `json:"x" jsonschema:"required"`
+	Y                 float64 `json:"y" jsonschema:"required"`
+	Button            int     `json:"button,omitempty" jsonschema:"description,Button number: 1=left (default), 2=middle, 3=right, 4-7=scroll, 8=back, 9=forward or higher for extra buttons"`
+	Delay             int     `json:"delay,omitempty"`
+	WindowID          uint32  `json:"window_id,omitempty" jsonschema:"description,If set, x and y are relative to this window"`
+	Method            string  `json:"method,omitempty" jsonschema:"description,xtest fakes the click as if from the mouse (default); sendevent sends synthetic button events to the window under the point for applications or nested X servers that don't see XTEST input"`
+	CapturePopups     bool    `json:"capture_popups,omitempty" jsonschema:"description,Also capture the screen the moment a menu or tooltip opens after the click, before it can close again"`
+	FocusedWindowOnly bool    `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type ScrollInput struct {
+	X                 float64 `json:"x" jsonschema:"required,description,Where to scroll: the window under this point gets the wheel events"`
+	Y                 float64 `json:"y" jsonschema:"required"`
+	Direction         string  `json:"direction" jsonschema:"required,description,up, down, left or right"`
+	Steps             int     `json:"steps,omitempty" jsonschema:"description,Wheel clicks to scroll (default 3)"`
+	WindowID          uint32  `json:"window_id,omitempty" jsonschema:"description,If set, x and y are relative to this window"`
+	Delay             int     `json:"delay,omitempty"`
+	FocusedWindowOnly bool    `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type DragInput struct {
+	FromX             float64 `json:"from_x" jsonschema:"required,description,Where to press the button"`
+	FromY             float64 `json:"from_y" jsonschema:"required"`
+	ToX               float64 `json:"to_x" jsonschema:"required,description,Where to release it"`
+	ToY               float64 `json:"to_y" jsonschema:"required"`
+	Button            int     `json:"button,omitempty" jsonschema:"description,Button to hold: 1=left (default), 2=middle, 3=right"`
+	Steps             int     `json:"steps,omitempty" jsonschema:"description,Motion events between the ends (default: one every --drag-step pixels)"`
+	StepDelay         int     `json:"step_delay,omitempty" jsonschema:"description,Milliseconds between the motion events when steps is set"`
+	WindowID          uint32  `json:"window_id,omitempty" jsonschema:"description,If set, the coordinates are relative to this window"`
+	Delay             int     `json:"delay,omitempty"`
+	FocusedWindowOnly bool    `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type SelectTextInput struct {
+	FromX             float64 `json:"from_x" jsonschema:"required"`
+	FromY             float64 `json:"from_y" jsonschema:"required"`
+	ToX               float64 `json:"to_x" jsonschema:"required"`
+	ToY               float64 `json:"to_y" jsonschema:"required"`
+	ShiftClick        bool    `json:"shift_click,omitempty" jsonschema:"description,Click the start and shift-click the end instead of dragging"`
+	Delay             int     `json:"delay,omitempty"`
+	FocusedWindowOnly bool    `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type GetClipboardInput struct {
+	Selection string `json:"selection,omitempty" jsonschema:"description,clipboard (default) or primary"`
+}
+
+type SetClipboardInput struct {
+	Text      string `json:"text" jsonschema:"required,description,Text to put on the clipboard"`
+	Selection string `json:"selection,omitempty" jsonschema:"description,clipboard (default) or primary"`
+}
+
+type ClipboardHistoryInput struct {
+	Limit int `json:"limit,omitempty" jsonschema:"description,Most entries to return, newest first (default: all kept)"`
+}
+
+type PastePrimaryInput struct {
+	X                 float64 `json:"x" jsonschema:"required"`
+	Y                 float64 `json:"y" jsonschema:"required"`
+	Text              string  `json:"text,omitempty" jsonschema:"description,If set, put this text in the PRIMARY selection first; otherwise paste what it holds"`
+	Delay             int     `json:"delay,omitempty"`
+	WindowID          uint32  `json:"window_id,omitempty" jsonschema:"description,If set, x and y are relative to this window"`
+	FocusedWindowOnly bool    `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
 }
 
 type TypeTextInput struct {
-	Text  string `json:"text" jsonschema:"required"`
-	Delay int    `json:"delay,omitempty"`
+	Text              string `json:"text" jsonschema:"required"`
+	Delay             int    `json:"delay,omitempty"`
+	Profile           string `json:"profile,omitempty" jsonschema:"description,Typing speed profile: instant fast or human (default from --typing-profile)"`
+	Sensitive         bool   `json:"sensitive,omitempty" jsonschema:"description,The text is confidential: the result gives only its length and logs leave it out"`
+	Method            string `json:"method,omitempty" jsonschema:"description,xtest fakes the keys as if typed (default); sendevent sends synthetic key events to the focused window; im commits the text through a running IBus input method which CJK applications accept; paste puts the text on the clipboard and presses ctrl+v which drops no characters in Electron and Firefox apps and handles any Unicode; auto uses im when IBus runs and answers and xtest otherwise"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type TypeSecretInput struct {
+	SecretRef         string `json:"secret_ref" jsonschema:"required,description,Name of a secret the operator provided in the secrets file or as an MCP_X11_SECRET_ environment variable; the value is typed but never returned"`
+	Delay             int    `json:"delay,omitempty"`
+	Profile           string `json:"profile,omitempty" jsonschema:"description,Typing speed profile: instant fast or human (default from --typing-profile)"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type LaunchInput struct {
+	Template          string   `json:"template" jsonschema:"required,description,Name of a launch template from --launch-templates"`
+	Args              []string `json:"args,omitempty" jsonschema:"description,Arguments appended to the template's, e.g. a URL or file to open"`
+	Delay             int      `json:"delay,omitempty"`
+	FocusedWindowOnly bool     `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
 }
 
 type StartProgramInput struct {
-	Program string   `json:"program" jsonschema:"required"`
-	Args    []string `json:"args,omitempty"`
-	Delay   int      `json:"delay,omitempty"`
+	Program           string      `json:"program" jsonschema:"required"`
+	Args              []string    `json:"args,omitempty"`
+	Transcript        bool        `json:"transcript,omitempty" jsonschema:"description,For terminal emulators like xterm: record the shell's output so x11_read_terminal can read it as text"`
+	Command           string      `json:"command,omitempty" jsonschema:"description,With transcript: shell command to run in the terminal instead of an interactive shell"`
+	KeepProfile       bool        `json:"keep_profile,omitempty" jsonschema:"description,For Firefox and Chromium: use the normal profile instead of a throwaway one without first-run and restore prompts"`
+	Ready             *ReadyInput `json:"ready,omitempty" jsonschema:"description,Checks that must pass before the screenshot is taken; replaces the delay"`
+	Delay             int         `json:"delay,omitempty"`
+	FocusedWindowOnly bool        `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type ReadyInput struct {
+	Window      bool   `json:"window,omitempty" jsonschema:"description,A new window is mapped"`
+	WindowClass string `json:"window_class,omitempty" jsonschema:"description,A new window of this WM_CLASS is mapped"`
+	Title       string `json:"title,omitempty" jsonschema:"description,Regular expression the title of a mapped window matches; the new window's if window or window_class is set"`
+	Text        string `json:"text,omitempty" jsonschema:"description,Text shown on the screen, recognized by OCR (needs tesseract)"`
+	Port        int    `json:"port,omitempty" jsonschema:"description,TCP port on localhost that accepts connections"`
+	TimeoutMS   int    `json:"timeout_ms,omitempty" jsonschema:"description,Milliseconds to wait for the checks to pass (default 30000)"`
+}
+type KeySequenceInput struct {
+	Sequence          string `json:"sequence" jsonschema:"required,description,Chords separated by spaces like ctrl+k ctrl+s or in emacs notation C-x C-f; each chord is a combo or a key name as for x11_key_press"`
+	ChordDelay        int    `json:"chord_delay,omitempty" jsonschema:"description,Milliseconds between chords (default 100)"`
+	Delay             int    `json:"delay,omitempty"`
+	WMBinding         string `json:"wm_binding,omitempty" jsonschema:"description,If a chord is bound by the window manager: warn sends it and warns, route runs the i3 binding over IPC in its place, refuse fails before anything is sent (default from --wm-binding)"`
+	Method            string `json:"method,omitempty" jsonschema:"description,xtest fakes the keys as if typed (default); sendevent sends synthetic key events to the focused window"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
 }
+
 type KeyPressInput struct {
-	Key   string `json:"key,omitempty" jsonschema:"description,Special key name like Enter Tab Escape"`
-	Combo string `json:"combo,omitempty" jsonschema:"description,Key combination like ctrl+c alt+tab"`
-	Delay int    `json:"delay,omitempty"`
+	Key               string `json:"key,omitempty" jsonschema:"description,Key name: any X keysym name in any case like Enter Tab F11 KP_Enter XF86AudioMute or a single character or U+XXXX"`
+	Combo             string `json:"combo,omitempty" jsonschema:"description,Key combination like ctrl+c alt+tab"`
+	Delay             int    `json:"delay,omitempty"`
+	WMBinding         string `json:"wm_binding,omitempty" jsonschema:"description,If the keys are bound by the window manager: warn sends them and warns, route runs the i3 binding over IPC instead, refuse fails (default from --wm-binding)"`
+	Method            string `json:"method,omitempty" jsonschema:"description,xtest fakes the keys as if typed (default); sendevent sends synthetic key events to the focused window for applications or nested X servers that don't see XTEST input"`
+	CapturePopups     bool   `json:"capture_popups,omitempty" jsonschema:"description,Also capture the screen the moment a menu opens after the keys (e.g. alt+f or F10), before it can close again"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type AbortAllInput struct{}
+
+type StatusInput struct {
+	CheckGrabs bool `json:"check_grabs,omitempty" jsonschema:"description,Probe for pointer and keyboard grabs held by other clients by briefly grabbing each device; this sends focus and crossing events to applications and is refused in observer mode"`
+}
+
+type GetScreenshotInput struct {
+	Index     int    `json:"index,omitempty" jsonschema:"description,Index from a result's _meta.screenshot; negative values count back from the latest (-1)"`
+	Timestamp string `json:"timestamp,omitempty" jsonschema:"description,RFC 3339 time; returns the latest screenshot taken at or before it"`
+}
+
+type WindowGalleryInput struct {
+	ThumbWidth int `json:"thumb_width,omitempty" jsonschema:"description,Thumbnail width in pixels (default 320)"`
+}
+
+type ShortcutsInput struct {
+	WindowID uint32 `json:"window_id,omitempty" jsonschema:"description,Window whose application to report (default: the focused window)"`
+}
+
+type ReadTerminalInput struct {
+	PID   int `json:"pid" jsonschema:"required,description,PID from x11_start_program with transcript"`
+	Lines int `json:"lines,omitempty" jsonschema:"description,Number of lines from the end to return (default 50)"`
+}
+
+type CompareWindowsInput struct {
+	IDA uint32 `json:"id_a" jsonschema:"required,description,First window ID"`
+	IDB uint32 `json:"id_b" jsonschema:"required,description,Second window ID"`
+}
+
+type FindImageInput struct {
+	Template       string  `json:"template,omitempty" jsonschema:"description,Base64 PNG of what to look for"`
+	Path           string  `json:"path,omitempty" jsonschema:"description,PNG file of what to look for"`
+	FromScreenshot int     `json:"from_screenshot,omitempty" jsonschema:"description,Crop what to look for from this screenshot history index; negative values count back from the latest (-1)"`
+	CropX          int     `json:"crop_x,omitempty" jsonschema:"description,With from_screenshot: left edge of the crop in screen coordinates"`
+	CropY          int     `json:"crop_y,omitempty" jsonschema:"description,With from_screenshot: top edge of the crop in screen coordinates"`
+	CropWidth      int     `json:"crop_width,omitempty"`
+	CropHeight     int     `json:"crop_height,omitempty"`
+	Threshold      float64 `json:"threshold,omitempty" jsonschema:"description,Least similarity score from 0 to 1 that counts as a match (default 0.9)"`
+	MaxMatches     int     `json:"max_matches,omitempty" jsonschema:"description,Most matches to return (default 5)"`
+	WindowID       uint32  `json:"window_id,omitempty" jsonschema:"description,Only search this window"`
+}
+
+type WatchRegionInput struct {
+	X         int     `json:"x" jsonschema:"required,description,Left edge of the region in screen coordinates"`
+	Y         int     `json:"y" jsonschema:"required,description,Top edge of the region in screen coordinates"`
+	Width     int     `json:"width" jsonschema:"required"`
+	Height    int     `json:"height" jsonschema:"required"`
+	Interval  int     `json:"interval,omitempty" jsonschema:"description,Milliseconds between captures (default 500)"`
+	Threshold float64 `json:"threshold,omitempty" jsonschema:"description,Fraction of the region's pixels that must change to count as a change (default 0.01)"`
+}
+
+type WaitRegionChangeInput struct {
+	WatchID int  `json:"watch_id" jsonschema:"required,description,ID returned by x11_watch_region"`
+	Timeout int  `json:"timeout,omitempty" jsonschema:"description,Milliseconds to wait for a change (default 30000)"`
+	Stop    bool `json:"stop,omitempty" jsonschema:"description,Stop the watch after this call"`
+}
+
+type ReadProgressBarInput struct {
+	X           int    `json:"x" jsonschema:"required,description,Left edge of the bar in screen coordinates"`
+	Y           int    `json:"y" jsonschema:"required,description,Top edge of the bar in screen coordinates"`
+	Width       int    `json:"width" jsonschema:"required"`
+	Height      int    `json:"height" jsonschema:"required"`
+	Orientation string `json:"orientation,omitempty" jsonschema:"description,horizontal (fills left to right) or vertical (fills bottom to top); default by the region's shape"`
+}
+
+type DismissInput struct {
+	ClickX            float64 `json:"click_x,omitempty" jsonschema:"description,A neutral spot to click if Escape doesn't close the popup (default: no click)"`
+	ClickY            float64 `json:"click_y,omitempty"`
+	Delay             int     `json:"delay,omitempty"`
+	FocusedWindowOnly bool    `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type OpenURLInput struct {
+	URL               string `json:"url" jsonschema:"required,description,Address to open"`
+	Browser           string `json:"browser,omitempty" jsonschema:"description,Browser to use such as firefox or chromium (default: any open browser window, else firefox is started)"`
+	Timeout           int    `json:"timeout,omitempty" jsonschema:"description,Milliseconds to wait at most for the page to load (default 30000)"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type WaitPageLoadedInput struct {
+	WindowID          uint32 `json:"window_id,omitempty" jsonschema:"description,Browser window (default: the focused window)"`
+	Timeout           int    `json:"timeout,omitempty" jsonschema:"description,Milliseconds to wait at most (default 30000)"`
+	Quiet             int    `json:"quiet,omitempty" jsonschema:"description,Milliseconds all signals must stay quiet to count as loaded (default 1500)"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty"`
+}
+
+type WaitForWindowInput struct {
+	Title             string `json:"title,omitempty" jsonschema:"description,Regular expression the window title must match"`
+	Class             string `json:"class,omitempty" jsonschema:"description,Regular expression the WM_CLASS must match, case-insensitively"`
+	Timeout           int    `json:"timeout,omitempty" jsonschema:"description,Milliseconds to wait at most (default 30000)"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type ConfinePointerInput struct {
+	WindowID uint32 `json:"window_id,omitempty" jsonschema:"description,Confine the pointer to this window's frame, following it as it moves"`
+	X        int    `json:"x,omitempty" jsonschema:"description,Left edge of the region in screen coordinates"`
+	Y        int    `json:"y,omitempty" jsonschema:"description,Top edge of the region in screen coordinates"`
+	Width    int    `json:"width,omitempty" jsonschema:"description,Width of the region"`
+	Height   int    `json:"height,omitempty" jsonschema:"description,Height of the region"`
+	Release  bool   `json:"release,omitempty" jsonschema:"description,Lift the confinement instead of setting one"`
+}
+
+type VerifyInput struct {
+	Assertions []AssertionInput `json:"assertions" jsonschema:"required,description,Checks to evaluate; all run even if some fail"`
+	NoEvidence bool             `json:"no_evidence,omitempty" jsonschema:"description,Leave out the evidence images of what each assertion checked (default false)"`
+}
+
+type AssertionInput struct {
+	Type      string `json:"type" jsonschema:"required,description,window_exists text_visible pixel_color title_matches or program_running"`
+	Not       bool   `json:"not,omitempty" jsonschema:"description,Pass when the check fails, e.g. that no error dialog is open"`
+	Title     string `json:"title,omitempty" jsonschema:"description,window_exists and title_matches: regular expression the window title must match"`
+	Class     string `json:"class,omitempty" jsonschema:"description,window_exists: regular expression the WM_CLASS must match, case-insensitively"`
+	Timeout   int    `json:"timeout,omitempty" jsonschema:"description,window_exists: milliseconds to wait for the window to appear (default 0, it must be open already)"`
+	WindowID  uint32 `json:"window_id,omitempty" jsonschema:"description,title_matches: window to check; text_visible: only look in this window"`
+	Text      string `json:"text,omitempty" jsonschema:"description,text_visible: words that must be on the screen, recognized by OCR and matched ignoring case"`
+	X         int    `json:"x,omitempty" jsonschema:"description,pixel_color: screen X coordinate"`
+	Y         int    `json:"y,omitempty" jsonschema:"description,pixel_color: screen Y coordinate"`
+	Color     string `json:"color,omitempty" jsonschema:"description,pixel_color: expected color as #RRGGBB"`
+	Tolerance *int   `json:"tolerance,omitempty" jsonschema:"description,pixel_color: how far each channel may be off from 0 for an exact match to 255 (default 16)"`
+	PID       int    `json:"pid,omitempty" jsonschema:"description,program_running: process ID"`
+	Program   string `json:"program,omitempty" jsonschema:"description,program_running: process name"`
+}
+
+type FindTextInput struct {
+	Text     string `json:"text" jsonschema:"required,description,Words to look for, matched on one line ignoring case and punctuation around words"`
+	WindowID uint32 `json:"window_id,omitempty" jsonschema:"description,Only look within this window"`
+}
+
+type WindowInfoInput struct {
+	WindowID uint32 `json:"window_id" jsonschema:"required,description,Window ID"`
+}
+
+type WindowAtPointInput struct {
+	X float64 `json:"x" jsonschema:"required,description,Screen X coordinate"`
+	Y float64 `json:"y" jsonschema:"required,description,Screen Y coordinate"`
+}
+
+type HighlightInput struct {
+	WindowID uint32 `json:"window_id,omitempty" jsonschema:"description,Outline this window instead of a region"`
+	X        int    `json:"x,omitempty" jsonschema:"description,Left edge of the region in screen coordinates"`
+	Y        int    `json:"y,omitempty" jsonschema:"description,Top edge of the region in screen coordinates"`
+	Width    int    `json:"width,omitempty" jsonschema:"description,Width of the region"`
+	Height   int    `json:"height,omitempty" jsonschema:"description,Height of the region"`
+	Duration int    `json:"duration,omitempty" jsonschema:"description,How long the outline stays in milliseconds (default 1500, at most 10000)"`
+	Color    string `json:"color,omitempty" jsonschema:"description,Outline color as #RRGGBB or red green blue yellow orange magenta or cyan (default red)"`
+}
+
+type SelectFileInput struct {
+	Path              string `json:"path" jsonschema:"required,description,File path to enter, preferably absolute"`
+	Mode              string `json:"mode,omitempty" jsonschema:"description,open checks that the file exists and save that its directory exists before touching the dialog"`
+	Delay             int    `json:"delay,omitempty"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type FillFormInput struct {
+	Fields            []FormFieldInput `json:"fields" jsonschema:"required,description,Fields in the order to fill them"`
+	Submit            *FormSubmitInput `json:"submit,omitempty" jsonschema:"description,How to submit the form once every field is filled (default: not submitted)"`
+	Delay             int              `json:"delay,omitempty"`
+	FocusedWindowOnly bool             `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type FormFieldInput struct {
+	Text      string          `json:"text,omitempty" jsonschema:"description,Text on the screen to click to focus the field such as its placeholder or label, found by OCR (needs tesseract)"`
+	At        *FormPointInput `json:"at,omitempty" jsonschema:"description,Point to click to focus the field"`
+	OffsetX   int             `json:"offset_x,omitempty" jsonschema:"description,Pixels right of the text's center to click, e.g. to reach the input beside a label"`
+	OffsetY   int             `json:"offset_y,omitempty" jsonschema:"description,Pixels below the text's center to click, e.g. to reach the input under a label"`
+	Value     string          `json:"value" jsonschema:"required,description,Text to enter"`
+	Append    bool            `json:"append,omitempty" jsonschema:"description,Add to the field's text instead of replacing it"`
+	Sensitive bool            `json:"sensitive,omitempty" jsonschema:"description,The value is confidential like a password: it is not read back or repeated in the result"`
+	NoVerify  bool            `json:"no_verify,omitempty" jsonschema:"description,Skip reading the field back after typing"`
+}
+
+type FormSubmitInput struct {
+	Text string          `json:"text,omitempty" jsonschema:"description,Text of the button to click, found by OCR (needs tesseract)"`
+	At   *FormPointInput `json:"at,omitempty" jsonschema:"description,Point to click"`
+	Key  string          `json:"key,omitempty" jsonschema:"description,Key to press instead of clicking, e.g. Return"`
+}
+
+type FormPointInput struct {
+	X float64 `json:"x" jsonschema:"required"`
+	Y float64 `json:"y" jsonschema:"required"`
+}
+
+type AliasWindowInput struct {
+	ID   uint32 `json:"id" jsonschema:"required,description,Window ID"`
+	Name string `json:"name" jsonschema:"required,description,Alias for the window, e.g. editor"`
+}
+
+type AcquireControlInput struct {
+	Agent        string `json:"agent,omitempty" jsonschema:"description,Name for this agent shown to others while it has control, e.g. planner"`
+	LeaseSeconds int    `json:"lease_seconds,omitempty" jsonschema:"description,Seconds the control lasts without input tool calls (default 60, at most 3600)"`
+}
+
+type ReleaseControlInput struct{}
+
+type DescribeWindowInput struct {
+	ID    uint32 `json:"id" jsonschema:"required,description,Window ID"`
+	NoOCR bool   `json:"no_ocr,omitempty" jsonschema:"description,Skip recognizing the text in the window, which can take a few seconds (default false)"`
+}
+
+type LayoutInput struct {
+	Preset            string   `json:"preset" jsonschema:"required,description,Layout preset: maximize left-half right-half top-half bottom-half side-by-side or grid-2x2"`
+	WindowIDs         []uint32 `json:"window_ids,omitempty" jsonschema:"description,Windows to arrange in cell order (default: the focused window or the first visible windows)"`
+	Delay             int      `json:"delay,omitempty"`
+	FocusedWindowOnly bool     `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type MoveResizeWindowInput struct {
+	WindowID          uint32 `json:"window_id" jsonschema:"required,description,Window to move and resize"`
+	X                 int    `json:"x" jsonschema:"required,description,Left edge of the window frame in screen coordinates"`
+	Y                 int    `json:"y" jsonschema:"required,description,Top edge of the window frame in screen coordinates"`
+	Width             int    `json:"width" jsonschema:"required,description,Width including decorations"`
+	Height            int    `json:"height" jsonschema:"required,description,Height including decorations"`
+	Delay             int    `json:"delay,omitempty"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type FocusWindowInput struct {
+	WindowID          uint32 `json:"window_id,omitempty" jsonschema:"description,Window to focus"`
+	Title             string `json:"title,omitempty" jsonschema:"description,Instead of window_id: regular expression the window title must match"`
+	Class             string `json:"class,omitempty" jsonschema:"description,Instead of window_id: regular expression the WM_CLASS must match, case-insensitively"`
+	Delay             int    `json:"delay,omitempty"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type IconifyWindowInput struct {
+	WindowID          uint32 `json:"window_id" jsonschema:"required,description,Window to minimize"`
+	Delay             int    `json:"delay,omitempty"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type DeiconifyWindowInput struct {
+	WindowID          uint32 `json:"window_id" jsonschema:"required,description,Minimized window to show again"`
+	Delay             int    `json:"delay,omitempty"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
 }
 
 type I3GetTreeInput struct{}
 
+type I3GetFocusedInput struct{}
+
 type I3CmdInput struct {
-	Command string `json:"command" jsonschema:"required"`
+	Command           string `json:"command" jsonschema:"required"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type I3ScreenshotWorkspaceInput struct {
+	Num   int `json:"num" jsonschema:"required,description,Number of the workspace to capture"`
+	Delay int `json:"delay,omitempty" jsonschema:"description,Milliseconds to let the workspace's windows repaint before capturing (default from --default-action-delay)"`
+}
+
+type I3FullscreenInput struct {
+	WindowID          uint32 `json:"window_id,omitempty" jsonschema:"description,Window whose container to change (default: the focused one)"`
+	Mode              string `json:"mode,omitempty" jsonschema:"description,toggle (default), enable or disable"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
+}
+
+type I3BorderInput struct {
+	WindowID          uint32 `json:"window_id,omitempty" jsonschema:"description,Window whose container to change (default: the focused one)"`
+	Style             string `json:"style" jsonschema:"required,description,none, pixel or normal (with title bar)"`
+	Width             int    `json:"width,omitempty" jsonschema:"description,Border width in pixels for pixel and normal (default: i3's)"`
+	FocusedWindowOnly bool   `json:"focused_window_only,omitempty" jsonschema:"description,Crop the screenshot to the focused window"`
 }
 
 func main() {
-	// Parse command line flags
+	// Read the settings from the config file, environment and flags
 	var (
-		noWM    = flag.Bool("no-wm", false, "Disable window manager startup")
-		wmName  = flag.String("wm-name", "i3 -a", "Window manager to start")
 		help    = flag.Bool("help", false, "Show help message")
 		version = flag.Bool("version", false, "Show version")
 	)
-	flag.Parse()
+	cfg := config.Default()
+	if err := cfg.Load(flag.CommandLine, os.Args[1:], os.Environ()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	
+	// Set up logging to stderr; stdout carries the MCP protocol
+	level, err := parseLogLevel(cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	baseHandler, err := newLogHandler(os.Stderr, level, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	logHandler := newSessionHandler(baseHandler)
+	slog.SetDefault(slog.New(logHandler))
+	
+	delays = delayConfig{
+		action:     cfg.ActionDelay,
+		screenshot: cfg.ScreenshotDelay,
+		perTool:    cfg.ToolDelays,
+	}
+	toolTimeoutCap = cfg.MaxToolTimeout
+	secretsFile = cfg.SecretsFile
+	echoTypedText = cfg.EchoTypedText
+	pointerTrail = cfg.PointerTrail
+	maxImageBytes = cfg.MaxImageBytes
+	if cfg.LaunchTemplates != "" {
+		if launchTemplates, err = loadLaunchTemplates(cfg.LaunchTemplates); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	history = newScreenshotHistory(cfg.HistorySize, cfg.HistoryMaxMB<<20)
+	wmBindingDefault, err = parseWMBindingPolicy(cfg.WMBinding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.Popups, err = parsePopupMode(cfg.Popups); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	for _, spec := range cfg.WindowsOfInterest {
+		m, err := parseWindowMatcher(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		interestMatchers = append(interestMatchers, m)
+	}
 	
 	// Show help
 	if *help {
 		fmt.Println("MCP X11 Controller")
-		fmt.Println("\nUsage: mcp-x11-controller [options]")
+		fmt.Println("\nUsage: mcp-x11-controller [options] [command [args...]]")
+		fmt.Println("\nWithout a command, runs the MCP server on stdin/stdout.")
+		fmt.Println("\n" + cliUsage)
 		fmt.Println("\nOptions:")
 		flag.PrintDefaults()
 		fmt.Println("\nEnvironment variables:")
 		fmt.Println("  DISPLAY        X11 display to connect to (if not set, Xvfb will be started)")
+		fmt.Println("  " + config.EnvPrefix + "*      Any option, named in upper case with underscores, e.g. " + config.EnvPrefix + "WM_NAME")
+		fmt.Println("  " + config.EnvPrefix + "CONFIG JSON config file, like -config")
+		fmt.Println("  " + config.SecretEnvPrefix + "* Secrets for x11_type_secret, e.g. " + config.SecretEnvPrefix + "DB_PASSWORD for db-password")
 		os.Exit(0)
 	}
 	
@@ -75,30 +581,78 @@ func main() {
 		os.Exit(0)
 	}
 	
-	// Log startup to stderr
-	log.SetOutput(os.Stderr)
-	log.Println("Starting MCP X11 Controller...")
-	if os.Getenv("DISPLAY") != "" {
-		log.Printf("Using existing DISPLAY: %s", os.Getenv("DISPLAY"))
-	} else {
-		log.Println("No DISPLAY set, will start Xvfb")
+	// Connect to X11 with options
+	opts := cfg.ConnectOptions()
+	
+	// Run a one-shot command instead of the server
+	if flag.NArg() > 0 {
+		os.Exit(runCLI(opts, flag.Args()))
 	}
 	
-	// Connect to X11 with options
-	opts := x11.ConnectOptions{
-		StartXvfb:  os.Getenv("DISPLAY") == "",
-		Resolution: "1920x1080",
-		StartWM:    !*noWM,
-		WMName:     *wmName,
+	slog.Info("starting MCP X11 controller")
+	if display := cmp.Or(cfg.Display, os.Getenv("DISPLAY")); display != "" {
+		slog.Info("using existing display", "display", display)
+	} else if cfg.Xvfb {
+		slog.Info("no DISPLAY set, will start Xvfb")
 	}
 	
-	var err error
 	client, err = x11.ConnectWithOptions(opts)
 	if err != nil {
-		log.Fatalf("Failed to connect to X11: %v", err)
+		slog.Error("failed to connect to X11", "err", err)
+		os.Exit(1)
 	}
 	defer client.Close()
 	
+	// Start the programs the deployment is about
+	for _, command := range cfg.StartupPrograms {
+		if err := startProgram(client, command); err != nil {
+			slog.Error("failed to start startup program", "command", command, "err", err)
+			// Deferred calls don't run on exit, and Close stops the managed
+			// Xvfb along with the window manager and programs on it
+			client.Close()
+			os.Exit(1)
+		}
+	}
+	
+	// Grab the emergency stop hotkey if requested
+	if cfg.AbortHotkey != "" {
+		if err := client.EnableAbortHotkey(cfg.AbortHotkey); err != nil {
+			slog.Warn("failed to enable abort hotkey", "err", err)
+		} else {
+			slog.Info("emergency stop hotkey enabled", "hotkey", cfg.AbortHotkey)
+		}
+	}
+	
+	// Watch for dialogs and other popups appearing
+	if cfg.Popups != "off" {
+		if err := client.WatchPopups(cfg.Popups == "capture", popups.add); err != nil {
+			slog.Warn("failed to watch for popups", "err", err)
+		}
+	}
+	
+	// Keep recent window events for failed tool results and the transcript
+	if err := client.WatchWindowEvents(birthThumbWidth, func(ev x11.WindowEvent) {
+		windowEvents.add(ev)
+		transcript.addWindowEvent(ev)
+	}); err != nil {
+		slog.Warn("failed to watch window events", "err", err)
+	}
+	
+	// Keep what is copied for x11_clipboard_history
+	err = client.WatchClipboard(clipboardLog.add)
+	if err != nil {
+		slog.Warn("failed to watch the clipboard", "err", err)
+	}
+	clipboardLog.watching(err)
+	
+	// Run the debug REPL instead of the MCP server
+	if cfg.REPL {
+		if err := runREPL(context.Background(), client, os.Stdin, os.Stdout); err != nil {
+			slog.Error("REPL failed", "err", err)
+		}
+		return
+	}
+	
 	// Create MCP server
 	server := mcp.NewServer(
 		&mcp.Implementation{
@@ -129,6 +683,21 @@ Example workflow:
 2. Use i3_cmd with [con_id=ID] focus to switch to that window`,
 		},
 	)
+	server.AddReceivingMiddleware(countToolCalls, recordTranscript, fitImages, logToolCalls, reportPopups, reportInterest, reportFailures, enforceTimeouts, enforceControl, resolveWindowAliases, nameXActions)
+	logHandler.attach(server)
+	
+	server.AddResource(&mcp.Resource{
+		URI:         runtimeStatsURI,
+		Name:        "runtime",
+		Description: "Statistics of this session as JSON: tool calls by tool, input actions, failed calls, screenshots and bytes sent, the average capture time and X protocol errors",
+		MIMEType:    "application/json",
+	}, readRuntimeStats)
+	server.AddResource(&mcp.Resource{
+		URI:         transcriptURI,
+		Name:        "transcript",
+		Description: "What happened so far in this session as text: the recent tool calls with their arguments and first line of result, and windows mapped, unmapped, destroyed or focused in between",
+		MIMEType:    "text/plain",
+	}, readTranscript)
 	
 	// Add tools to the server
 	
@@ -137,36 +706,56 @@ Example workflow:
 		&mcp.Tool{
 			Name:        "x11_get_screen_info",
 			Title:       "X11 Get Screen Info",
-			Description: "Get X11 screen information including dimensions and screenshot",
+			Description: "Get X11 screen information: dimensions, color depth, display, window manager and workspace. Set include_screenshot to also get a screenshot",
 		},
 		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[GetScreenInfoInput]) (*mcp.CallToolResultFor[any], error) {
-			info, err := client.GetScreenInfo()
+			timer := newToolTimer()
+			
+			info, err := client.GetScreenInfoContext(ctx)
 			if err != nil {
 				return nil, err
 			}
+			timer.mark("query_ms")
 			
-			// Take screenshot
-			pngData, err := client.ScreenshotPNG()
-			if err != nil {
-				return nil, fmt.Errorf("failed to take screenshot: %w", err)
+			lines := []string{
+				fmt.Sprintf("Screen: %dx%d", info.Width, info.Height),
+				fmt.Sprintf("Depth: %d", info.Depth),
+				fmt.Sprintf("Display: %s", info.Display),
+			}
+			if info.WMName != "" {
+				lines = append(lines, fmt.Sprintf("Window manager: %s", info.WMName))
+			}
+			if info.Workspace != "" {
+				lines = append(lines, fmt.Sprintf("Workspace: %s", info.Workspace))
 			}
 			
 			content := []mcp.Content{
 				&mcp.TextContent{
-					Text: fmt.Sprintf("Screen: %dx%d", info.Width, info.Height),
+					Text: strings.Join(lines, "\n"),
 				},
-				&mcp.ImageContent{
+			}
+			
+			if params.Arguments.IncludeScreenshot {
+				pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+				if err != nil {
+					return nil, err
+				}
+				content = append(content, &mcp.ImageContent{
 					Data:     pngData,
 					MIMEType: "image/png",
-				},
+				})
 			}
 			
 			return &mcp.CallToolResultFor[any]{
 				Content: content,
-				Meta: map[string]any{
-					"width":  info.Width,
-					"height": info.Height,
-				},
+				Meta: timer.meta(map[string]any{
+					"width":     info.Width,
+					"height":    info.Height,
+					"depth":     info.Depth,
+					"display":   info.Display,
+					"wm":        info.WMName,
+					"workspace": info.Workspace,
+				}),
 			}, nil
 		},
 	)
@@ -179,7 +768,9 @@ Example workflow:
 			Description: "Take a screenshot of the X11 display",
 		},
 		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[TakeScreenshotInput]) (*mcp.CallToolResultFor[any], error) {
-			pngData, err := client.ScreenshotPNG()
+			timer := newToolTimer()
+			
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
 			if err != nil {
 				return nil, err
 			}
@@ -193,6 +784,68 @@ Example workflow:
 			
 			return &mcp.CallToolResultFor[any]{
 				Content: content,
+				Meta:    timer.meta(nil),
+			}, nil
+		},
+	)
+	
+	// x11_screenshot_region tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_screenshot_region",
+			Title:       "X11 Screenshot Region",
+			Description: "Capture a rectangle of the screen or a single window at full resolution, e.g. just a dialog, for detail that a downscaled full-screen image loses",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ScreenshotRegionInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			var img image.Image
+			var err error
+			var entry historyEntry
+			start := time.Now()
+			switch {
+			case args.WindowID != 0:
+				img, err = client.ScreenshotWindowContext(ctx, x.Window(args.WindowID))
+				entry.Window = args.WindowID
+			case args.Width > 0 && args.Height > 0:
+				img, err = client.ScreenshotRegionContext(ctx, args.X, args.Y, args.Width, args.Height)
+			default:
+				return nil, fmt.Errorf("give a window_id or a region with width and height")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to take screenshot: %w", err)
+			}
+			stats.addCapture(time.Since(start))
+			timer.mark("capture_ms")
+			defer client.RecycleScreenshot(img)
+			
+			bounds := img.Bounds()
+			entry.Origin = bounds.Min
+			pngData, err := encodeShot(timer, img, entry)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := fmt.Sprintf("Captured %dx%d at (%d, %d); add (%d, %d) to coordinates in the image for screen coordinates",
+				bounds.Dx(), bounds.Dy(), bounds.Min.X, bounds.Min.Y, bounds.Min.X, bounds.Min.Y)
+			if args.WindowID != 0 {
+				text = fmt.Sprintf("Window 0x%x: %s", args.WindowID, text)
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"x":      bounds.Min.X,
+					"y":      bounds.Min.Y,
+					"width":  bounds.Dx(),
+					"height": bounds.Dy(),
+				}),
 			}, nil
 		},
 	)
@@ -205,36 +858,83 @@ Example workflow:
 			Description: "Move mouse to coordinates and click, returns screenshot after delay",
 		},
 		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ClickAtInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
 			button := params.Arguments.Button
 			if button == 0 {
 				button = 1
 			}
 			
-			delay := params.Arguments.Delay
-			if delay == 0 {
-				delay = 100 // Default 100ms delay
+			delay := delays.forTool("x11_click_at", params.Arguments.Delay)
+			
+			method, err := x11.ParseInputMethod(params.Arguments.Method)
+			if err != nil {
+				return nil, err
+			}
+			
+			// Validate coordinates against the screen or target window
+			var px, py int
+			if params.Arguments.WindowID != 0 {
+				px, py, err = client.ValidateWindowPoint(ctx, x.Window(params.Arguments.WindowID), params.Arguments.X, params.Arguments.Y)
+			} else {
+				px, py, err = client.ValidatePoint(params.Arguments.X, params.Arguments.Y)
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			timer.captureBefore(ctx, params.Arguments.FocusedWindowOnly)
+			menus := watchMenus(ctx, params.Arguments.CapturePopups)
+			if menus != nil {
+				defer menus.Stop()
 			}
 			
 			// Move and click
-			if err := client.MouseMove(int(params.Arguments.X), int(params.Arguments.Y)); err != nil {
+			if method == x11.InputSendEvent {
+				if err := client.SendClickAtContext(ctx, px, py, button); err != nil {
+					return nil, err
+				}
+			} else {
+				if err := client.MouseMoveContext(ctx, px, py); err != nil {
+					return nil, err
+				}
+				if err := client.MouseClickContext(ctx, button); err != nil {
+					return nil, err
+				}
+			}
+			
+			// Report where the pointer actually landed
+			finalX, finalY, err := client.PointerPositionContext(ctx)
+			if err != nil {
 				return nil, err
 			}
-			if err := client.MouseClick(button); err != nil {
+			timer.mark("input_ms")
+			
+			meta := map[string]any{
+				"pointer_x": finalX,
+				"pointer_y": finalY,
+				"method":    method,
+			}
+			menuParts, err := menuContent(ctx, menus, timer, meta)
+			if err != nil {
 				return nil, err
 			}
 			
 			// Wait for the specified delay
-			time.Sleep(time.Duration(delay) * time.Millisecond)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
 			
 			// Take screenshot
-			pngData, err := client.ScreenshotPNG()
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
 			if err != nil {
-				return nil, fmt.Errorf("failed to take screenshot: %w", err)
+				return nil, err
 			}
 			
 			content := []mcp.Content{
 				&mcp.TextContent{
-					Text: fmt.Sprintf("Clicked at (%d, %d) with button %d", int(params.Arguments.X), int(params.Arguments.Y), button),
+					Text: timer.withWarning(clickText(px, py, finalX, finalY, button)),
 				},
 				&mcp.ImageContent{
 					Data:     pngData,
@@ -243,146 +943,2330 @@ Example workflow:
 			}
 			
 			return &mcp.CallToolResultFor[any]{
-				Content: content,
+				Content: append(content, menuParts...),
+				Meta:    timer.meta(meta),
 			}, nil
 		},
 	)
 	
-	// x11_type_text tool
+	// x11_scroll tool
 	mcp.AddTool(server,
 		&mcp.Tool{
-			Name:        "x11_type_text",
-			Title:       "X11 Type Text",
-			Description: "Type text by sending key events, returns screenshot after delay",
+			Name:        "x11_scroll",
+			Title:       "X11 Scroll",
+			Description: "Move the mouse to coordinates and turn the scroll wheel, for scrolling windows that ignore Page_Up and Page_Down, returns screenshot after delay",
 		},
-		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[TypeTextInput]) (*mcp.CallToolResultFor[any], error) {
-			if err := client.Type(params.Arguments.Text); err != nil {
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ScrollInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			steps := args.Steps
+			if steps == 0 {
+				steps = defaultScrollSteps
+			}
+			if _, err := x11.ScrollButton(args.Direction); err != nil {
 				return nil, err
 			}
 			
-			delay := params.Arguments.Delay
-			if delay == 0 {
-				delay = 100 // Default 100ms delay
+			var px, py int
+			var err error
+			if args.WindowID != 0 {
+				px, py, err = client.ValidateWindowPoint(ctx, x.Window(args.WindowID), args.X, args.Y)
+			} else {
+				px, py, err = client.ValidatePoint(args.X, args.Y)
 			}
-			
-			// Wait for the specified delay
-			time.Sleep(time.Duration(delay) * time.Millisecond)
-			
-			// Take screenshot
-			pngData, err := client.ScreenshotPNG()
 			if err != nil {
-				return nil, fmt.Errorf("failed to take screenshot: %w", err)
+				return nil, err
 			}
 			
-			content := []mcp.Content{
-				&mcp.TextContent{
-					Text: fmt.Sprintf("Typed: %s", params.Arguments.Text),
-				},
-				&mcp.ImageContent{
-					Data:     pngData,
-					MIMEType: "image/png",
-				},
+			timer.captureBefore(ctx, args.FocusedWindowOnly)
+			if err := client.MouseMoveContext(ctx, px, py); err != nil {
+				return nil, err
+			}
+			if err := client.MouseScrollContext(ctx, args.Direction, steps); err != nil {
+				return nil, err
 			}
+			timer.mark("input_ms")
 			
+			if err := client.WaitContext(ctx, delays.forTool("x11_scroll", args.Delay)); err != nil {
+				return nil, err
+			}
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
 			return &mcp.CallToolResultFor[any]{
-				Content: content,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(fmt.Sprintf("Scrolled %s %d steps at (%d, %d)", strings.ToLower(args.Direction), steps, px, py))},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"direction": strings.ToLower(args.Direction),
+					"steps":     steps,
+				}),
 			}, nil
 		},
 	)
 	
-	// x11_start_program tool
+	// x11_drag tool
 	mcp.AddTool(server,
 		&mcp.Tool{
-			Name:        "x11_start_program",
-			Title:       "X11 Start Program",
-			Description: "Start a desktop program in the background, returns screenshot after delay",
+			Name:        "x11_drag",
+			Title:       "X11 Drag",
+			Description: "Press a mouse button at one point, move to another with intermediate motion and release it there, for sliders, drag and drop and rearranging items. Returns screenshot after delay",
 		},
-		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[StartProgramInput]) (*mcp.CallToolResultFor[any], error) {
-			pid, err := client.StartApp(params.Arguments.Program, params.Arguments.Args)
-			if err != nil {
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[DragInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			button := args.Button
+			if button == 0 {
+				button = 1
+			}
+			
+			var fromX, fromY, toX, toY int
+			var err error
+			if args.WindowID != 0 {
+				win := x.Window(args.WindowID)
+				if fromX, fromY, err = client.ValidateWindowPoint(ctx, win, args.FromX, args.FromY); err == nil {
+					toX, toY, err = client.ValidateWindowPoint(ctx, win, args.ToX, args.ToY)
+				}
+			} else {
+				if fromX, fromY, err = client.ValidatePoint(args.FromX, args.FromY); err == nil {
+					toX, toY, err = client.ValidatePoint(args.ToX, args.ToY)
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			timer.captureBefore(ctx, args.FocusedWindowOnly)
+			if err := client.DragContext(ctx, fromX, fromY, toX, toY, button, args.Steps, args.StepDelay); err != nil {
+				return nil, err
+			}
+			finalX, finalY, err := client.PointerPositionContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			if err := client.WaitContext(ctx, delays.forTool("x11_drag", args.Delay)); err != nil {
+				return nil, err
+			}
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := fmt.Sprintf("Dragged with button %d from (%d, %d) to (%d, %d)", button, fromX, fromY, toX, toY)
+			if finalX != toX || finalY != toY {
+				text += fmt.Sprintf(" (pointer landed at (%d, %d))", finalX, finalY)
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(text)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"pointer_x": finalX,
+					"pointer_y": finalY,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_select_text tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_select_text",
+			Title:       "X11 Select Text",
+			Description: "Select text by dragging from one point to another (or click and shift-click), returns the selected text and a screenshot",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[SelectTextInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			fromX, fromY, err := client.ValidatePoint(args.FromX, args.FromY)
+			if err != nil {
+				return nil, err
+			}
+			toX, toY, err := client.ValidatePoint(args.ToX, args.ToY)
+			if err != nil {
+				return nil, err
+			}
+			
+			// A failed readback still leaves the selection on screen
+			text, selErr := client.SelectTextContext(ctx, fromX, fromY, toX, toY, args.ShiftClick)
+			if selErr != nil && !errors.Is(selErr, x11.ErrSelectionUnreadable) {
+				return nil, selErr
+			}
+			timer.mark("input_ms")
+			
+			if err := client.WaitContext(ctx, delays.forTool("x11_select_text", args.Delay)); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			summary := fmt.Sprintf("Selected from (%d, %d) to (%d, %d): %q", fromX, fromY, toX, toY, text)
+			if selErr != nil {
+				summary = fmt.Sprintf("Selected from (%d, %d) to (%d, %d), but %v", fromX, fromY, toX, toY, selErr)
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: summary},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{"selected_text": text}),
+			}, nil
+		},
+	)
+	
+	// x11_paste_primary_at tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_paste_primary_at",
+			Title:       "X11 Paste Primary At",
+			Description: "Middle-click at coordinates to paste the PRIMARY selection, as terminals and other X applications do. Pass text to set the selection first, which is much faster than typing long commands",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[PastePrimaryInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			var px, py int
+			var err error
+			if args.WindowID != 0 {
+				px, py, err = client.ValidateWindowPoint(ctx, x.Window(args.WindowID), args.X, args.Y)
+			} else {
+				px, py, err = client.ValidatePoint(args.X, args.Y)
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			timer.captureBefore(ctx, args.FocusedWindowOnly)
+			
+			text := fmt.Sprintf("Pasted PRIMARY at (%d, %d)", px, py)
+			if args.Text != "" {
+				if err := client.SetSelectionContext(ctx, "PRIMARY", args.Text); err != nil {
+					return nil, err
+				}
+				text = fmt.Sprintf("Pasted %d characters at (%d, %d)", len([]rune(args.Text)), px, py)
+			}
+			if err := client.PastePrimaryAt(ctx, px, py); err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			if err := client.WaitContext(ctx, delays.forTool("x11_paste_primary_at", args.Delay)); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(text)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(nil),
+			}, nil
+		},
+	)
+	
+	// x11_get_clipboard tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_get_clipboard",
+			Title:       "X11 Get Clipboard",
+			Description: "Return the text on the clipboard, e.g. after pressing ctrl+c in an application, or in the PRIMARY selection",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[GetClipboardInput]) (*mcp.CallToolResultFor[any], error) {
+			selection, err := x11.ParseSelection(params.Arguments.Selection)
+			if err != nil {
+				return nil, err
+			}
+			text, err := client.GetSelectionContext(ctx, selection)
+			if err != nil {
+				return nil, err
+			}
+			
+			result := text
+			if text == "" {
+				result = fmt.Sprintf("%s is empty", selection)
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: result},
+				},
+				Meta: map[string]any{
+					"selection": selection,
+					"bytes":     len(text),
+					"empty":     text == "",
+				},
+			}, nil
+		},
+	)
+	
+	// x11_set_clipboard tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_set_clipboard",
+			Title:       "X11 Set Clipboard",
+			Description: "Put text on the clipboard (or in the PRIMARY selection) to paste it with ctrl+v, which is far faster and more reliable for long text than typing it",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[SetClipboardInput]) (*mcp.CallToolResultFor[any], error) {
+			args := params.Arguments
+			selection, err := x11.ParseSelection(args.Selection)
+			if err != nil {
+				return nil, err
+			}
+			if err := client.SetSelectionContext(ctx, selection, args.Text); err != nil {
+				return nil, err
+			}
+			
+			paste := "ctrl+v"
+			if selection == x11.SelectionPrimary {
+				paste = "a middle click"
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Put %d characters in %s; paste them with %s", utf8.RuneCountInString(args.Text), selection, paste)},
+				},
+				Meta: map[string]any{
+					"selection": selection,
+					"bytes":     len(args.Text),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_clipboard_history tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_clipboard_history",
+			Title:       "X11 Clipboard History",
+			Description: fmt.Sprintf("Return the texts copied to the clipboard since the server started, newest first, e.g. to get back what was copied two steps ago. The last %d copies are kept; passwords copied from password managers are not", maxClipboardHistory),
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ClipboardHistoryInput]) (*mcp.CallToolResultFor[any], error) {
+			copies, err := clipboardLog.recent(params.Arguments.Limit)
+			if err != nil {
+				return nil, err
+			}
+			
+			entries := make([]map[string]any, len(copies))
+			for i, cp := range copies {
+				entries[i] = map[string]any{
+					"time":      cp.Time.Format(time.RFC3339Nano),
+					"text":      cp.Text,
+					"bytes":     cp.Bytes,
+					"truncated": cp.Truncated,
+					"window_id": uint32(cp.Owner),
+					"class":     cp.Class,
+				}
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: clipboardHistoryText(copies, time.Now())},
+				},
+				Meta: map[string]any{"entries": entries},
+			}, nil
+		},
+	)
+	
+	// x11_type_text tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_type_text",
+			Title:       "X11 Type Text",
+			Description: "Type text by sending key events, committing it through the input method or pasting it from the clipboard, returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[TypeTextInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			profile := client.TypingProfile()
+			if params.Arguments.Profile != "" {
+				var err error
+				profile, err = x11.ParseTypingProfile(params.Arguments.Profile)
+				if err != nil {
+					return nil, err
+				}
+			}
+			
+			method, err := parseTypeMethod(params.Arguments.Method)
+			if err != nil {
+				return nil, err
+			}
+			if method == typeMethodAuto {
+				method = autoTypeMethod(ctx, client.GetDisplay())
+			}
+			
+			timer.captureBefore(ctx, params.Arguments.FocusedWindowOnly)
+			echo := echoTypedText && !params.Arguments.Sensitive
+			text := typedText("Typed", params.Arguments.Text, echo)
+			if method == typeMethodIM {
+				if client.Frozen() {
+					return nil, x11.ErrFrozen
+				}
+				im, err := imCommit(ctx, client.GetDisplay(), params.Arguments.Text)
+				if err != nil {
+					return nil, err
+				}
+				text = typedText("Committed through "+im, params.Arguments.Text, echo)
+			} else if method == typeMethodPaste {
+				paste := client.PasteTextContext
+				if params.Arguments.Sensitive {
+					paste = func(ctx context.Context, text string) error {
+						return pasteSecret(ctx, text, client.PasteSecretContext, func(ctx context.Context) error {
+							return client.SetSelectionContext(ctx, x11.SelectionClipboard, "")
+						})
+					}
+				}
+				if err := paste(ctx, params.Arguments.Text); err != nil {
+					return nil, err
+				}
+				text = typedText("Pasted", params.Arguments.Text, echo)
+			} else if method == typeMethodSendEvent {
+				if err := client.SendTypeContext(ctx, params.Arguments.Text, profile); err != nil {
+					return nil, typingError(err, params.Arguments.Sensitive)
+				}
+			} else if err := client.TypeWithProfileContext(ctx, params.Arguments.Text, profile); err != nil {
+				return nil, typingError(err, params.Arguments.Sensitive)
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_type_text", params.Arguments.Delay)
+			
+			// Wait for the specified delay
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			// Take screenshot
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			content := []mcp.Content{
+				&mcp.TextContent{
+					Text: timer.withWarning(text),
+				},
+				&mcp.ImageContent{
+					Data:     pngData,
+					MIMEType: "image/png",
+				},
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: content,
+				Meta:    timer.meta(map[string]any{"method": method}),
+			}, nil
+		},
+	)
+	
+	// x11_type_secret tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_type_secret",
+			Title:       "X11 Type Secret",
+			Description: "Type a password or other secret the operator provided, by name, without it appearing in the conversation. Returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[TypeSecretInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			profile := client.TypingProfile()
+			if params.Arguments.Profile != "" {
+				var err error
+				profile, err = x11.ParseTypingProfile(params.Arguments.Profile)
+				if err != nil {
+					return nil, err
+				}
+			}
+			
+			secret, err := lookupSecret(params.Arguments.SecretRef, secretsFile, os.LookupEnv)
+			if err != nil {
+				return nil, err
+			}
+			
+			timer.captureBefore(ctx, params.Arguments.FocusedWindowOnly)
+			if err := client.TypeSecretContext(ctx, secret, profile); err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_type_secret", params.Arguments.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Text: timer.withWarning(fmt.Sprintf("Typed secret %s", params.Arguments.SecretRef)),
+					},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{"secret_ref": params.Arguments.SecretRef}),
+			}, nil
+		},
+	)
+	
+	// x11_start_program tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_start_program",
+			Title:       "X11 Start Program",
+			Description: "Start a desktop program in the background, returns screenshot once its ready checks pass (a new window, a title, text on screen, an open port) or after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[StartProgramInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			args := params.Arguments
+			start := func() (int, error) {
+				switch {
+				case args.Transcript:
+					return client.StartTerminal(args.Program, args.Args, args.Command)
+				case args.Command != "":
+					return 0, fmt.Errorf("command needs transcript, for other programs pass args")
+				case x11.IsBrowser(args.Program) && !args.KeepProfile:
+					return client.StartBrowser(args.Program, args.Args, "")
+				}
+				return client.StartApp(args.Program, args.Args)
+			}
+			
+			var pid int
+			var ready *x11.Ready
+			if probe := readyProbe(args.Ready); !probe.Empty() {
+				r, err := client.StartReady(ctx, probe, readyOCR(), start)
+				if err != nil {
+					return nil, err
+				}
+				pid, ready = r.PID, &r
+				timer.mark("ready_ms")
+			} else {
+				var err error
+				if pid, err = start(); err != nil {
+					return nil, err
+				}
+				timer.mark("launch_ms")
+				
+				delay := delays.forTool("x11_start_program", params.Arguments.Delay)
+				
+				// Wait for the specified delay
+				if err := client.WaitContext(ctx, delay); err != nil {
+					return nil, err
+				}
+				timer.mark("wait_ms")
+			}
+			
+			// Take screenshot
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			content := []mcp.Content{
+				&mcp.TextContent{
+					Text: startText(args.Program, pid, args.Transcript) + readyText(ready),
+				},
+				&mcp.ImageContent{
+					Data:     pngData,
+					MIMEType: "image/png",
+				},
+			}
+			
+			meta := map[string]any{
+				"pid": pid,
+			}
+			if ready != nil {
+				meta["ready"] = readyMeta(*ready)
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: content,
+				Meta:    timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_launch tool
+	launchDescription := "Start an application from a launch template the operator configured, with its flags and environment, and wait until its window is shown (and, for browsers, the page loaded) instead of sleeping. Returns a screenshot"
+	if len(launchTemplates) > 0 {
+		launchDescription += ". Templates: " + strings.Join(templateNames(launchTemplates), ", ")
+	}
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_launch",
+			Title:       "X11 Launch",
+			Description: launchDescription,
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[LaunchInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			template, err := launchTemplate(args.Template)
+			if err != nil {
+				return nil, err
+			}
+			launched, err := client.Launch(ctx, template, args.Args)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("launch_ms")
+			
+			if err := client.WaitContext(ctx, delays.forTool("x11_launch", args.Delay)); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			meta := map[string]any{
+				"pid":      launched.PID,
+				"template": args.Template,
+				"ready":    template.Readiness(),
+			}
+			if launched.Window != 0 {
+				meta["window_id"] = uint32(launched.Window)
+				meta["title"] = launched.Title
+			}
+			if launched.PageLoad != nil {
+				meta["loaded"] = launched.PageLoad.Loaded
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: launchText(args.Template, launched)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_key_press tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_key_press",
+			Title:       "X11 Key Press",
+			Description: "Press special keys or key combinations, returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[KeyPressInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			keys := params.Arguments.Combo
+			if keys == "" {
+				keys = params.Arguments.Key
+			}
+			if keys == "" {
+				return nil, fmt.Errorf("either 'key' or 'combo' must be specified")
+			}
+			
+			method, err := x11.ParseInputMethod(params.Arguments.Method)
+			if err != nil {
+				return nil, err
+			}
+			
+			policy := wmBindingDefault
+			if params.Arguments.WMBinding != "" {
+				var err error
+				if policy, err = parseWMBindingPolicy(params.Arguments.WMBinding); err != nil {
+					return nil, err
+				}
+			}
+			
+			// Check for window manager bindings that would swallow the keys
+			guard, err := guardWMBinding(ctx, keys, policy)
+			if err != nil {
+				return nil, err
+			}
+			
+			timer.captureBefore(ctx, params.Arguments.FocusedWindowOnly)
+			menus := watchMenus(ctx, params.Arguments.CapturePopups && !guard.routed)
+			if menus != nil {
+				defer menus.Stop()
+			}
+			
+			// Handle either single key or key combo
+			switch {
+			case guard.routed:
+				// The binding's i3 command already ran
+			case method == x11.InputSendEvent && params.Arguments.Combo != "":
+				if err := client.SendKeyComboContext(ctx, params.Arguments.Combo); err != nil {
+					return nil, err
+				}
+			case method == x11.InputSendEvent:
+				if err := client.SendKeyPressContext(ctx, params.Arguments.Key); err != nil {
+					return nil, err
+				}
+			case params.Arguments.Combo != "":
+				if err := client.KeyComboContext(ctx, params.Arguments.Combo); err != nil {
+					return nil, err
+				}
+			default:
+				if err := client.KeyPressContext(ctx, params.Arguments.Key); err != nil {
+					return nil, err
+				}
+			}
+			timer.mark("input_ms")
+			
+			meta := guard.meta
+			if menus != nil && meta == nil {
+				meta = map[string]any{}
+			}
+			menuParts, err := menuContent(ctx, menus, timer, meta)
+			if err != nil {
+				return nil, err
+			}
+			
+			delay := delays.forTool("x11_key_press", params.Arguments.Delay)
+			
+			// Wait for the specified delay
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			// Take screenshot
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := fmt.Sprintf("Pressed: %s", keys)
+			if guard.routed {
+				text = guard.note
+			} else if guard.note != "" {
+				text += "\n" + guard.note
+			}
+			
+			content := []mcp.Content{
+				&mcp.TextContent{
+					Text: timer.withWarning(text),
+				},
+				&mcp.ImageContent{
+					Data:     pngData,
+					MIMEType: "image/png",
+				},
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: append(content, menuParts...),
+				Meta:    timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_key_sequence tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_key_sequence",
+			Title:       "X11 Key Sequence",
+			Description: "Press a sequence of key chords in order, like ctrl+k ctrl+s (VS Code) or C-x C-s (emacs), with a pause between chords; returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[KeySequenceInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			chords, err := x11.ParseKeySequence(args.Sequence)
+			if err != nil {
+				return nil, err
+			}
+			method, err := x11.ParseInputMethod(args.Method)
+			if err != nil {
+				return nil, err
+			}
+			if args.ChordDelay < 0 {
+				return nil, fmt.Errorf("chord_delay cannot be negative")
+			}
+			gap := x11.DefaultChordDelay
+			if args.ChordDelay > 0 {
+				gap = time.Duration(args.ChordDelay) * time.Millisecond
+			}
+			policy := wmBindingDefault
+			if args.WMBinding != "" {
+				if policy, err = parseWMBindingPolicy(args.WMBinding); err != nil {
+					return nil, err
+				}
+			}
+			// Refuse before the first chord rather than halfway
+			if policy == "refuse" {
+				for _, chord := range chords {
+					if _, err := guardWMBinding(ctx, chord, policy); err != nil {
+						return nil, err
+					}
+				}
+			}
+			
+			timer.captureBefore(ctx, args.FocusedWindowOnly)
+			
+			var notes []string
+			meta := map[string]any{"chords": chords}
+			for i, chord := range chords {
+				if i > 0 {
+					if err := client.WaitContext(ctx, int(gap.Milliseconds())); err != nil {
+						return nil, err
+					}
+				}
+				guard, err := guardWMBinding(ctx, chord, policy)
+				if err != nil {
+					return nil, err
+				}
+				if guard.note != "" {
+					notes = append(notes, guard.note)
+				}
+				for k, v := range guard.meta {
+					meta[k] = v
+				}
+				if guard.routed {
+					continue
+				}
+				if err := client.PressChordContext(ctx, chord, method); err != nil {
+					return nil, fmt.Errorf("failed at chord %d (%s): %w", i+1, chord, err)
+				}
+			}
+			timer.mark("input_ms")
+			
+			if err := client.WaitContext(ctx, delays.forTool("x11_key_sequence", args.Delay)); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := fmt.Sprintf("Pressed sequence: %s", strings.Join(chords, " "))
+			if len(notes) > 0 {
+				text += "\n" + strings.Join(notes, "\n")
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(text)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_abort_all tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_abort_all",
+			Title:       "X11 Abort All",
+			Description: "Emergency stop: cancel all in-flight waits and release every held key and mouse button",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[AbortAllInput]) (*mcp.CallToolResultFor[any], error) {
+			if err := client.AbortAll(); err != nil {
+				return nil, err
+			}
+			
+			text := "Aborted all in-flight actions and released held input"
+			if client.Frozen() {
+				text += " (input is frozen by the emergency stop hotkey)"
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+				},
+				Meta: map[string]any{
+					"frozen": client.Frozen(),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_status tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_status",
+			Title:       "X11 Status",
+			Description: "Report the controller's state: display, window manager, i3 connection, emergency stop, launched programs and, with check_grabs, pointer and keyboard grabs held by other clients",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[StatusInput]) (*mcp.CallToolResultFor[any], error) {
+			wm := client.DetectWM(ctx)
+			apps := client.ListApps()
+			grabsText := "not checked (pass check_grabs)"
+			var grabsMeta any
+			if params.Arguments.CheckGrabs {
+				grabs, err := client.DetectGrabs(ctx)
+				if err != nil {
+					return nil, err
+				}
+				grabsText, grabsMeta = orNone(grabs.String()), grabs.String()
+			}
+			pacing := client.Pacing()
+			xErrors := client.XErrorSummary()
+			
+			lines := []string{
+				fmt.Sprintf("Display: %s (Xvfb managed: %t)", client.GetDisplay(), client.IsXvfbManaged()),
+				fmt.Sprintf("Window manager: %s", orNone(wm)),
+				fmt.Sprintf("i3 connected: %t", client.I3Enabled()),
+				fmt.Sprintf("Input frozen: %t", client.Frozen()),
+				fmt.Sprintf("Input grabs: %s", grabsText),
+				pacingText(pacing),
+				xErrorsText(xErrors, time.Now()),
+				fmt.Sprintf("Observer mode: %t", client.Observer()),
+				fmt.Sprintf("Isolated home: %s", orNone(client.IsolatedHome())),
+				fmt.Sprintf("Pointer confined to: %s", confinementText(client.PointerConfinement(), client.ConfinementFloor())),
+				fmt.Sprintf("Redacted from screenshots: %s", orNone(client.Redaction().String())),
+				fmt.Sprintf("Programs started: %d", len(apps)),
+			}
+			for _, app := range apps {
+				state := "running"
+				if app.Exited {
+					state = fmt.Sprintf("exited with code %d", app.ExitCode)
+					if app.Signal != "" {
+						state = fmt.Sprintf("killed by %s", app.Signal)
+					}
+				}
+				lines = append(lines, fmt.Sprintf("  pid %d %s: %s", app.PID, app.Program, state))
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: strings.Join(lines, "\n")},
+				},
+				Meta: map[string]any{
+					"display":      client.GetDisplay(),
+					"xvfb_managed": client.IsXvfbManaged(),
+					"wm":           wm,
+					"i3_connected": client.I3Enabled(),
+					"frozen":       client.Frozen(),
+					"grabs":        grabsMeta,
+					"latency_ms":   float64(pacing.Latency.Microseconds()) / 1000,
+					"pacing_ms":    pacing.Extra.Milliseconds(),
+					"x_errors":     map[string]any{"total": xErrors.Total, "by_name": xErrors.Counts},
+					"observer":     client.Observer(),
+					"home":         client.IsolatedHome(),
+					"confine":      client.PointerConfinement().String(),
+					"redact":       client.Redaction().String(),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_get_screenshot tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_get_screenshot",
+			Title:       "X11 Get Screenshot",
+			Description: "Return an earlier result screenshot from the history by index or timestamp, to compare before and after; without arguments lists the history",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[GetScreenshotInput]) (*mcp.CallToolResultFor[any], error) {
+			args := params.Arguments
+			
+			var entry historyEntry
+			var err error
+			switch {
+			case args.Index != 0:
+				entry, err = history.byIndex(args.Index)
+			case args.Timestamp != "":
+				t, perr := time.Parse(time.RFC3339Nano, args.Timestamp)
+				if perr != nil {
+					return nil, fmt.Errorf("invalid timestamp %q, expected RFC 3339", args.Timestamp)
+				}
+				entry, err = history.byTime(t)
+			default:
+				return &mcp.CallToolResultFor[any]{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: history.describe()},
+					},
+				}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Screenshot %d taken at %s", entry.Index, entry.Time.Format(time.RFC3339Nano))},
+					&mcp.ImageContent{
+						Data:     entry.PNG,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: map[string]any{
+					"screenshot": screenshotMeta(entry),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_window_gallery tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_window_gallery",
+			Title:       "X11 Window Gallery",
+			Description: "Get one thumbnail per visible window with ID, title and class captions composited into a single image",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WindowGalleryInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			pngData, entries, err := client.WindowGalleryPNG(ctx, params.Arguments.ThumbWidth)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("capture_ms")
+			
+			var lines []string
+			for _, entry := range entries {
+				line := fmt.Sprintf("#%d id=%d class=%q title=%q", entry.Index, entry.Window.ID, entry.Window.Class, entry.Window.Title)
+				if entry.Error != "" {
+					line += fmt.Sprintf(" (capture failed: %s)", entry.Error)
+				}
+				lines = append(lines, line)
+			}
+			
+			content := []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("%d windows:\n%s", len(entries), strings.Join(lines, "\n")),
+				},
+				&mcp.ImageContent{
+					Data:     pngData,
+					MIMEType: "image/png",
+				},
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: content,
+				Meta:    timer.meta(nil),
+			}, nil
+		},
+	)
+	
+	// x11_select_file_in_dialog tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_select_file_in_dialog",
+			Title:       "X11 Select File In Dialog",
+			Description: "Enter a path into the focused GTK or Qt open/save dialog via its location bar (Ctrl+L, type, Enter), verify it and report whether the dialog closed, returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[SelectFileInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			path := params.Arguments.Path
+			
+			if err := checkDialogPath(path, params.Arguments.Mode); err != nil {
+				return nil, err
+			}
+			
+			res, err := client.SelectFileInDialog(ctx, path)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_select_file_in_dialog", params.Arguments.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := fmt.Sprintf("Entered %s in %q", path, res.Title)
+			if res.Verified {
+				text += " (read back from the location bar)"
+			} else {
+				text += " (could not read the location bar back)"
+			}
+			switch {
+			case res.Closed:
+				text += "\nThe dialog closed"
+			case res.Focused != 0 && res.Focused != res.Dialog:
+				text += fmt.Sprintf("\nThe dialog is still open and %q (window 0x%x) has the focus, e.g. a confirmation", res.FocusedTitle, uint32(res.Focused))
+			default:
+				text += "\nThe dialog is still open: the path may be a directory it navigated into, or it was rejected"
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"dialog_id": uint32(res.Dialog),
+					"verified":  res.Verified,
+					"closed":    res.Closed,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_fill_form tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_fill_form",
+			Title:       "X11 Fill Form",
+			Description: "Fill a form in one call: focus each field by clicking on text or a point, or with Tab, type its value and read it back, then optionally submit. Returns a per-field report and a screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[FillFormInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			if err := validateForm(args); err != nil {
+				return nil, err
+			}
+			if client.Frozen() {
+				return nil, x11.ErrFrozen
+			}
+			
+			timer.captureBefore(ctx, args.FocusedWindowOnly)
+			filler := &formFiller{client: client, ocr: formOCR(), echo: echoTypedText}
+			reports := make([]fieldReport, len(args.Fields))
+			failed := false
+			for i, field := range args.Fields {
+				if failed {
+					reports[i] = fieldReport{Locator: fieldLocator(i, field), Status: fieldSkipped}
+					continue
+				}
+				reports[i] = filler.fill(ctx, i, field)
+				failed = !reports[i].ok()
+				// Don't carry on typing into whatever has the focus
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+			}
+			submitted := false
+			var submitErr error
+			if args.Submit != nil && !failed {
+				if submitErr = filler.submit(ctx, args.Submit); submitErr == nil {
+					submitted = true
+				}
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_fill_form", args.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(formText(reports, submitted, submitErr, echoTypedText, args.Fields))},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				IsError: failed || submitErr != nil,
+				Meta: timer.meta(map[string]any{
+					"fields":    formMeta(reports),
+					"submitted": submitted,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_layout tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_layout",
+			Title:       "X11 Layout",
+			Description: "Arrange windows with a placement preset like left-half, right-half, maximize or grid-2x2, returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[LayoutInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			var windows []x.Window
+			for _, id := range params.Arguments.WindowIDs {
+				windows = append(windows, x.Window(id))
+			}
+			placements, err := client.ApplyLayout(ctx, params.Arguments.Preset, windows)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_layout", params.Arguments.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			var b strings.Builder
+			fmt.Fprintf(&b, "Applied layout %s:", params.Arguments.Preset)
+			placed := make([]map[string]any, 0, len(placements))
+			for _, p := range placements {
+				fmt.Fprintf(&b, "\n  window 0x%x at (%d, %d) size %dx%d", uint32(p.Window), p.Rect.Min.X, p.Rect.Min.Y, p.Rect.Dx(), p.Rect.Dy())
+				placed = append(placed, map[string]any{
+					"window_id": uint32(p.Window),
+					"x":         p.Rect.Min.X,
+					"y":         p.Rect.Min.Y,
+					"width":     p.Rect.Dx(),
+					"height":    p.Rect.Dy(),
+				})
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: b.String()},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{"placements": placed}),
+			}, nil
+		},
+	)
+	
+	// x11_move_resize_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_move_resize_window",
+			Title:       "X11 Move Resize Window",
+			Description: "Move a window and set its size, decorations included, to arrange windows at exact positions for screenshot-based automation. Returns the geometry the window got and a screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[MoveResizeWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			win := x.Window(args.WindowID)
+			got, err := client.MoveResizeWindowContext(ctx, win, args.X, args.Y, args.Width, args.Height)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_move_resize_window", args.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := fmt.Sprintf("Moved window 0x%x to (%d, %d) size %dx%d", uint32(win), got.Min.X, got.Min.Y, got.Dx(), got.Dy())
+			if want := image.Rect(args.X, args.Y, args.X+args.Width, args.Y+args.Height); got != want {
+				text += fmt.Sprintf("; the window manager did not allow %dx%d+%d+%d", want.Dx(), want.Dy(), want.Min.X, want.Min.Y)
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(text)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"window_id": uint32(win),
+					"x":         got.Min.X,
+					"y":         got.Min.Y,
+					"width":     got.Dx(),
+					"height":    got.Dy(),
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_alias_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_alias_window",
+			Title:       "X11 Alias Window",
+			Description: "Name a window so the name works wherever a window ID is accepted. If the window's ID changes, e.g. after its application restarts, the alias finds the window again by class and title",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[AliasWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			args := params.Arguments
+			if err := validAliasName(args.Name); err != nil {
+				return nil, err
+			}
+			id, err := client.IdentifyWindow(ctx, x.Window(args.ID))
+			if err != nil {
+				return nil, err
+			}
+			replaced := aliases.set(args.Name, id)
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: aliasText(args.Name, id, replaced)},
+				},
+				Meta: map[string]any{
+					"alias":     args.Name,
+					"window_id": uint32(id.Window),
+					"class":     id.Class,
+					"title":     id.Title,
+					"aliases":   aliases.names(),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_acquire_control tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_acquire_control",
+			Title:       "X11 Acquire Control",
+			Description: "Take advisory control of the display so other agents' input tools are refused until it is released or the lease lapses. Calling it again renews the lease",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[AcquireControlInput]) (*mcp.CallToolResultFor[any], error) {
+			args := params.Arguments
+			lease, err := controlLease(args.LeaseSeconds)
+			if err != nil {
+				return nil, err
+			}
+			owner := controlOwner(session)
+			_, renewed := controls.get(owner)
+			acquired, err := client.AcquireControl(ctx, owner, args.Agent, lease)
+			if err != nil {
+				return nil, err
+			}
+			controls.set(owner, args.Agent, lease)
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: controlText(acquired, renewed)},
+				},
+				Meta: map[string]any{
+					"owner":         acquired.Owner,
+					"expires":       acquired.Expires.Format(time.RFC3339),
+					"lease_seconds": int(lease.Seconds()),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_release_control tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_release_control",
+			Title:       "X11 Release Control",
+			Description: "Give up control of the display taken with x11_acquire_control, so other agents can use input tools again",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ReleaseControlInput]) (*mcp.CallToolResultFor[any], error) {
+			owner := controlOwner(session)
+			controls.forget(owner)
+			released, err := client.ReleaseControl(ctx, owner)
+			if err != nil {
+				return nil, err
+			}
+			text := "Released control of the display"
+			if !released {
+				text = "This session did not control the display"
+				if lease, ok, err := client.ControlHolder(ctx); err == nil && ok {
+					text += "; " + (&x11.ControlHeldError{Lease: lease}).Error()
+				}
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+				},
+				Meta: map[string]any{"released": released},
+			}, nil
+		},
+	)
+	
+	// x11_describe_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_describe_window",
+			Title:       "X11 Describe Window",
+			Description: "Describe a window as text: title, class, role, geometry, its accessibility tree from AT-SPI and the text in it via OCR, when available. No image is returned",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[DescribeWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			desc, err := describeWindow(ctx, client, params.Arguments.ID, !params.Arguments.NoOCR)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("describe_ms")
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: desc.String()},
+				},
+				Meta: timer.meta(map[string]any{
+					"accessibility": desc.tree != "",
+					"ocr":           desc.ocrRan,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_shortcuts tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_shortcuts",
+			Title:       "X11 Shortcuts",
+			Description: "List keyboard shortcuts of the focused or given window's application: accelerators it exposes over AT-SPI, plus well-known ones by WM_CLASS (e.g. ctrl+l for a browser's address bar)",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ShortcutsInput]) (*mcp.CallToolResultFor[any], error) {
+			win := x.Window(params.Arguments.WindowID)
+			if win == 0 {
+				var err error
+				if win, err = client.FocusedWindowContext(ctx); err != nil {
+					return nil, err
+				}
+			}
+			report, err := findShortcuts(ctx, client, win)
+			if err != nil {
+				return nil, err
+			}
+			
+			meta := map[string]any{
+				"window_id": uint32(win),
+				"class":     report.window.Class,
+				"exposed":   report.exposed,
+				"known":     report.known,
+			}
+			if report.app != "" {
+				meta["app"] = report.app
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{&mcp.TextContent{Text: report.String()}},
+				Meta:    meta,
+			}, nil
+		},
+	)
+	
+	// x11_read_terminal tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_read_terminal",
+			Title:       "X11 Read Terminal",
+			Description: "Read the text output of a terminal started with x11_start_program and transcript, instead of reading it from a screenshot. Works best for line-oriented output; full-screen programs like editors read poorly",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ReadTerminalInput]) (*mcp.CallToolResultFor[any], error) {
+			n := params.Arguments.Lines
+			if n <= 0 {
+				n = defaultTerminalLines
+			}
+			lines, err := client.ReadTerminal(params.Arguments.PID, n)
+			if err != nil {
+				return nil, err
+			}
+			
+			text := strings.Join(lines, "\n")
+			if len(lines) == 0 {
+				text = "(no output yet)"
+			}
+			status, _ := client.AppStatus(params.Arguments.PID)
+			if status.Exited {
+				text += fmt.Sprintf("\n[terminal exited with code %d]", status.ExitCode)
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{&mcp.TextContent{Text: text}},
+				Meta: map[string]any{
+					"pid":    params.Arguments.PID,
+					"lines":  len(lines),
+					"exited": status.Exited,
+				},
+			}, nil
+		},
+	)
+	
+	// x11_wait_page_loaded tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_wait_page_loaded",
+			Title:       "X11 Wait Page Loaded",
+			Description: "Wait until the page in a browser window has loaded, instead of sleeping for a fixed time: the title must stop changing and the toolbar (where the loading indicator spins) stay still, and for Chromium browsers started by x11_start_program the document must be complete with no new network requests. Returns a screenshot",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WaitPageLoadedInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			win := x.Window(args.WindowID)
+			if win == 0 {
+				var err error
+				if win, err = client.FocusedWindowContext(ctx); err != nil {
+					return nil, err
+				}
+			}
+			timeout, quiet := defaultPageLoadTimeout, defaultPageLoadQuiet
+			if args.Timeout > 0 {
+				timeout = time.Duration(args.Timeout) * time.Millisecond
+			}
+			if args.Quiet > 0 {
+				quiet = time.Duration(args.Quiet) * time.Millisecond
+			}
+			
+			load, err := client.WaitPageLoaded(ctx, win, timeout, quiet)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			text := fmt.Sprintf("Page loaded after %s: %q", load.Elapsed.Round(time.Millisecond), load.Title)
+			if !load.Loaded {
+				text = fmt.Sprintf("Page still loading after %s (%s): %q", timeout, strings.Join(load.Busy, ", "), load.Title)
+			}
+			meta := map[string]any{
+				"window_id": uint32(win),
+				"loaded":    load.Loaded,
+				"title":     load.Title,
+				"devtools":  load.DevTools,
+			}
+			if load.DevTools {
+				meta["ready_state"] = load.ReadyState
+				meta["resources"] = load.Resources
+			}
+			if len(load.Busy) > 0 {
+				meta["busy"] = load.Busy
+			}
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_wait_for_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_wait_for_window",
+			Title:       "X11 Wait For Window",
+			Description: "Wait until a window whose title and/or class match regular expressions is mapped, such as after x11_start_program, instead of sleeping for a fixed time. A matching window that is already open returns at once. Returns the window ID and a screenshot",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WaitForWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			timeout := defaultWindowWaitTimeout
+			if args.Timeout > 0 {
+				timeout = time.Duration(args.Timeout) * time.Millisecond
+			}
+			matcher := x11.WindowMatcher{Title: args.Title, Class: args.Class}
+			start := time.Now()
+			win, err := client.WaitForWindow(ctx, matcher, timeout)
+			if err != nil {
+				// A window that closed again between polls still shows
+				// up in the window events
+				if b, ok := closedMatch(start, matcher); ok && ctx.Err() == nil {
+					return &mcp.CallToolResultFor[any]{
+						Content: append([]mcp.Content{&mcp.TextContent{Text: err.Error()}}, b.content("The window closed again before it was seen open: ")...),
+						IsError: true,
+						Meta:    timer.meta(map[string]any{"window_id": uint32(b.Window), "title": b.Title, "class": b.Class, "closed": true}),
+					}, nil
+				}
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Window 0x%x appeared: %q (%s)", uint32(win.ID), win.Title, win.Class)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"window_id": uint32(win.ID),
+					"title":     win.Title,
+					"class":     win.Class,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_find_text tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_find_text",
+			Title:       "X11 Find Text",
+			Description: "Find where text is on the screen by OCR and return the screen coordinates to click. The recognized text is kept and only the parts of the screen that changed are recognized again, so repeated queries answer in milliseconds while the screen is still. Needs tesseract",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[FindTextInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			if strings.TrimSpace(args.Text) == "" {
+				return nil, fmt.Errorf("text is required")
+			}
+			if _, err := exec.LookPath("tesseract"); err != nil {
+				return nil, fmt.Errorf("x11_find_text needs tesseract, which is not installed")
+			}
+			var area image.Rectangle
+			if args.WindowID != 0 {
+				info, err := client.GetWindowInfoContext(ctx, x.Window(args.WindowID))
+				if err != nil {
+					return nil, err
+				}
+				area = image.Rect(info.X, info.Y, info.X+info.Width, info.Y+info.Height)
+			}
+			
+			found, refresh, err := screenText.find(ctx, args.Text, area)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("ocr_ms")
+			
+			matches := make([]map[string]any, len(found))
+			for i, m := range found {
+				matches[i] = map[string]any{
+					"x":        m.Min.X,
+					"y":        m.Min.Y,
+					"width":    m.Dx(),
+					"height":   m.Dy(),
+					"center_x": (m.Min.X + m.Max.X) / 2,
+					"center_y": (m.Min.Y + m.Max.Y) / 2,
+				}
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: findTextText(args.Text, found, refresh)},
+				},
+				Meta: timer.meta(map[string]any{
+					"matches": matches,
+					"index":   map[string]any{"refresh": refresh.Mode, "bands": refresh.Bands},
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_verify tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_verify",
+			Title:       "X11 Verify",
+			Description: "Evaluate assertions about the desktop in one call, for end-to-end tests: window_exists (title/class regex, optionally waiting), text_visible (OCR), pixel_color, title_matches and program_running, each optionally negated with not. Returns pass/fail per assertion with details and evidence images; the result is an error if any assertion failed",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[VerifyInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			if err := validateAssertions(args.Assertions); err != nil {
+				return nil, err
+			}
+			
+			v := &verifier{evidence: !args.NoEvidence}
+			defer v.close()
+			results := make([]assertionResult, len(args.Assertions))
+			passed := true
+			for i, a := range args.Assertions {
+				results[i] = v.run(ctx, a)
+				passed = passed && results[i].Passed
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			timer.mark("verify_ms")
+			
+			content := []mcp.Content{&mcp.TextContent{Text: verifyText(args.Assertions, results)}}
+			for i, r := range results {
+				if r.Evidence != nil {
+					content = append(content,
+						&mcp.TextContent{Text: fmt.Sprintf("Evidence for assertion %d (%s):", i+1, args.Assertions[i].Type)},
+						&mcp.ImageContent{Data: r.Evidence, MIMEType: "image/png"},
+					)
+				}
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: content,
+				IsError: !passed,
+				Meta: timer.meta(map[string]any{
+					"passed":     passed,
+					"assertions": verifyMeta(args.Assertions, results),
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_dismiss tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_dismiss",
+			Title:       "X11 Dismiss",
+			Description: "Close the topmost menu or tooltip, or else the focused dialog or transient window: presses Escape, checks the popup is gone and, if not, clicks the given neutral spot and checks again. Returns a screenshot",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[DismissInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			var neutral *image.Point
+			if args.ClickX != 0 || args.ClickY != 0 {
+				px, py, err := client.ValidatePoint(args.ClickX, args.ClickY)
+				if err != nil {
+					return nil, err
+				}
+				neutral = &image.Point{X: px, Y: py}
+			}
+			d, err := client.Dismiss(ctx, neutral)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			meta := map[string]any{"dismissed": d.Window != 0}
+			if d.Window != 0 {
+				meta["window_id"] = uint32(d.Window)
+				meta["kind"] = d.Kind
+				meta["clicked"] = d.Clicked
+				if d.Clicked {
+					meta["clicked_at"] = []int{d.ClickedAt.X, d.ClickedAt.Y}
+				}
+				if d.Remaining != 0 {
+					meta["remaining"] = uint32(d.Remaining)
+				}
+			}
+			
+			if err := client.WaitContext(ctx, delays.forTool("x11_dismiss", args.Delay)); err != nil {
+				return nil, err
+			}
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: dismissText(d)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_open_url tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_open_url",
+			Title:       "X11 Open URL",
+			Description: "Open a URL in a browser and wait for the page to load, in one reliable step instead of clicking the address bar, typing and sleeping: reuses an open browser window (focusing the address bar with ctrl+l and checking it took, retrying, and pasting the URL if typing it didn't take) or starts the browser. Returns a screenshot",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[OpenURLInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			timeout := defaultPageLoadTimeout
+			if args.Timeout > 0 {
+				timeout = time.Duration(args.Timeout) * time.Millisecond
+			}
+			opened, err := client.OpenURL(ctx, args.URL, args.Browser, timeout)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			meta := map[string]any{
+				"window_id": uint32(opened.Window),
+				"loaded":    opened.PageLoad.Loaded,
+				"title":     opened.PageLoad.Title,
+				"started":   opened.PID != 0,
+				"attempts":  opened.Attempts,
+				"pasted":    opened.Pasted,
+			}
+			if opened.PID != 0 {
+				meta["pid"] = opened.PID
+			}
+			if len(opened.PageLoad.Busy) > 0 {
+				meta["busy"] = opened.PageLoad.Busy
+			}
+			
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: openURLText(args.URL, opened)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_confine_pointer tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_confine_pointer",
+			Title:       "X11 Confine Pointer",
+			Description: "Confine injected pointer movement to a window or region, clamping targets outside it and refusing clicks there, so stray clicks can't hit other applications",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ConfinePointerInput]) (*mcp.CallToolResultFor[any], error) {
+			args := params.Arguments
+			var conf x11.Confinement
+			switch {
+			case args.Release:
+			case args.WindowID != 0:
+				conf.Window = x.Window(args.WindowID)
+			case args.Width > 0 && args.Height > 0:
+				conf.Rect = image.Rect(args.X, args.Y, args.X+args.Width, args.Y+args.Height)
+			default:
+				return nil, fmt.Errorf("give a window_id, a region with width and height, or release")
+			}
+			if err := client.SetConfinement(ctx, conf); err != nil {
+				return nil, err
+			}
+			
+			text := "Pointer confinement lifted"
+			if conf.Active() {
+				text = fmt.Sprintf("Pointer confined to %s", confinementText(conf, client.ConfinementFloor()))
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+				},
+				Meta: map[string]any{
+					"confine": conf.String(),
+				},
+			}, nil
+		},
+	)
+	
+	// x11_get_window_info tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_get_window_info",
+			Title:       "X11 Get Window Info",
+			Description: "Return a window's title, class, geometry in screen coordinates, PID, workspace and state (e.g. MAXIMIZED_VERT, HIDDEN, FULLSCREEN). Add the window's x and y to window-relative coordinates to get the screen coordinates to click",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WindowInfoInput]) (*mcp.CallToolResultFor[any], error) {
+			info, err := client.GetWindowInfoContext(ctx, x.Window(params.Arguments.WindowID))
+			if err != nil {
+				return nil, err
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: windowInfoText(info)},
+				},
+				Meta: map[string]any{
+					"window_id": uint32(info.ID),
+					"title":     info.Title,
+					"class":     info.Class,
+					"geometry":  map[string]int{"x": info.X, "y": info.Y, "width": info.Width, "height": info.Height},
+					"pid":       info.PID,
+					"workspace": info.Workspace,
+					"state":     info.State,
+				},
+			}, nil
+		},
+	)
+	
+	// x11_get_window_at_point tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_get_window_at_point",
+			Title:       "X11 Get Window At Point",
+			Description: "Return the topmost window (ID, class, title, geometry) at screen coordinates, to confirm what a click there would hit",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WindowAtPointInput]) (*mcp.CallToolResultFor[any], error) {
+			px, py, err := client.ValidatePoint(params.Arguments.X, params.Arguments.Y)
+			if err != nil {
+				return nil, err
+			}
+			win, err := client.WindowAtPoint(ctx, px, py)
+			if err != nil {
+				return nil, err
+			}
+			
+			meta := map[string]any{"x": px, "y": py}
+			if win.ID != 0 {
+				meta["window_id"] = uint32(win.ID)
+				meta["frame_id"] = uint32(win.Frame)
+				meta["subwindow_id"] = uint32(win.Deepest)
+				meta["title"] = win.Title
+				meta["class"] = win.Class
+				meta["pid"] = win.PID
+				meta["geometry"] = map[string]int{"x": win.Rect.Min.X, "y": win.Rect.Min.Y, "width": win.Rect.Dx(), "height": win.Rect.Dy()}
+			}
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: windowAtText(px, py, win)},
+				},
+				Meta: meta,
+			}, nil
+		},
+	)
+	
+	// x11_highlight tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_highlight",
+			Title:       "X11 Highlight",
+			Description: "Briefly outline a window or screen region so a human watching the display can see what you are about to interact with. Returns right away; the outline disappears on its own",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[HighlightInput]) (*mcp.CallToolResultFor[any], error) {
+			args := params.Arguments
+			rgb, err := x11.ParseHighlightColor(args.Color)
+			if err != nil {
+				return nil, err
+			}
+			duration := time.Duration(args.Duration) * time.Millisecond
+			
+			rect := image.Rect(args.X, args.Y, args.X+args.Width, args.Y+args.Height)
+			switch {
+			case args.WindowID != 0:
+				rect, err = client.HighlightWindow(ctx, x.Window(args.WindowID), rgb, duration)
+			case args.Width > 0 && args.Height > 0:
+				err = client.Highlight(ctx, rect, rgb, duration)
+			default:
+				return nil, fmt.Errorf("give a window_id or a region with width and height")
+			}
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Highlighted %dx%d+%d+%d for %s", rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y, x11.HighlightDuration(duration))},
+				},
+			}, nil
+		},
+	)
+	
+	// x11_focus_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_focus_window",
+			Title:       "X11 Focus Window",
+			Description: "Raise a window and give it the keyboard focus, by ID or by title or class, so that typed text goes to it. Minimized windows are restored and the window's workspace is switched to. Returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[FocusWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			win := x.Window(args.WindowID)
+			if matcher := (x11.WindowMatcher{Title: args.Title, Class: args.Class}); args.Title != "" || args.Class != "" {
+				if win != 0 {
+					return nil, fmt.Errorf("give either window_id or title and class")
+				}
+				found, err := client.WaitForWindow(ctx, matcher, 0)
+				if err != nil {
+					return nil, err
+				}
+				win = found.ID
+			} else if win == 0 {
+				return nil, fmt.Errorf("give a window_id, title or class")
+			}
+			
+			if err := client.FocusWindowContext(ctx, win); err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_focus_window", args.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			info, err := client.GetWindowInfoContext(ctx, win)
+			if err != nil {
+				return nil, err
+			}
+			pngData, err := screenshotPNG(ctx, timer, args.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(fmt.Sprintf("Focused window 0x%x %q (%s)", uint32(info.ID), info.Title, info.Class))},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(map[string]any{
+					"window_id": uint32(info.ID),
+					"title":     info.Title,
+					"class":     info.Class,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_iconify_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_iconify_window",
+			Title:       "X11 Iconify Window",
+			Description: "Minimize a window to get it out of the way without closing it; x11_deiconify_window shows it again. Returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[IconifyWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			win := x.Window(params.Arguments.WindowID)
+			if err := client.IconifyWindow(ctx, win); err != nil {
+				return nil, err
+			}
+			timer.mark("input_ms")
+			
+			delay := delays.forTool("x11_iconify_window", params.Arguments.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
+			}
+			timer.mark("wait_ms")
+			
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(fmt.Sprintf("Iconified window 0x%x", uint32(win)))},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(nil),
+			}, nil
+		},
+	)
+	
+	// x11_deiconify_window tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_deiconify_window",
+			Title:       "X11 Deiconify Window",
+			Description: "Show a minimized window again without focusing it, returns screenshot after delay",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[DeiconifyWindowInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			win := x.Window(params.Arguments.WindowID)
+			if err := client.DeiconifyWindow(ctx, win); err != nil {
 				return nil, err
 			}
+			timer.mark("input_ms")
 			
-			delay := params.Arguments.Delay
-			if delay == 0 {
-				delay = 100 // Default 100ms delay
+			delay := delays.forTool("x11_deiconify_window", params.Arguments.Delay)
+			if err := client.WaitContext(ctx, delay); err != nil {
+				return nil, err
 			}
+			timer.mark("wait_ms")
 			
-			// Wait for the specified delay
-			time.Sleep(time.Duration(delay) * time.Millisecond)
+			pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
+			if err != nil {
+				return nil, err
+			}
 			
-			// Take screenshot
-			pngData, err := client.ScreenshotPNG()
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: timer.withWarning(fmt.Sprintf("Deiconified window 0x%x", uint32(win)))},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
+				},
+				Meta: timer.meta(nil),
+			}, nil
+		},
+	)
+	
+	// x11_compare_windows tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_compare_windows",
+			Title:       "X11 Compare Windows",
+			Description: "Capture two windows and return pixel and structural similarity scores plus a diff image with changed pixels in red",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[CompareWindowsInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			
+			cmp, err := client.CompareWindows(ctx, x.Window(params.Arguments.IDA), x.Window(params.Arguments.IDB))
 			if err != nil {
-				return nil, fmt.Errorf("failed to take screenshot: %w", err)
+				return nil, err
 			}
+			timer.mark("capture_ms")
 			
-			content := []mcp.Content{
-				&mcp.TextContent{
-					Text: fmt.Sprintf("Started %s with PID %d", params.Arguments.Program, pid),
+			pngData, err := client.EncodePNG(cmp.Diff)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("encode_ms")
+			
+			text := fmt.Sprintf("Pixel similarity: %.1f%% (%d pixels differ)\nStructural similarity (SSIM): %.3f",
+				cmp.PixelSimilarity*100, cmp.ChangedPixels, cmp.StructuralSimilarity)
+			if cmp.SizeMismatch {
+				text += "\nThe windows differ in size; they were aligned at the top left"
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
 				},
-				&mcp.ImageContent{
-					Data:     pngData,
-					MIMEType: "image/png",
+				Meta: timer.meta(map[string]any{
+					"pixel_similarity":      cmp.PixelSimilarity,
+					"structural_similarity": cmp.StructuralSimilarity,
+					"changed_pixels":        cmp.ChangedPixels,
+					"size_mismatch":         cmp.SizeMismatch,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_find_image tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_find_image",
+			Title:       "X11 Find Image",
+			Description: "Find where a PNG template, e.g. a button cropped from an earlier screenshot, appears on the screen and return the screen coordinates of each match, best first, to click it without hardcoded positions",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[FindImageInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			template, err := loadTemplate(args)
+			if err != nil {
+				return nil, err
+			}
+			threshold := args.Threshold
+			if threshold == 0 {
+				threshold = vision.DefaultThreshold
+			}
+			limit := args.MaxMatches
+			if limit <= 0 {
+				limit = defaultImageMatches
+			}
+			
+			matches, err := client.FindImageContext(ctx, template, threshold, x.Window(args.WindowID))
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("search_ms")
+			if len(matches) > limit {
+				matches = matches[:limit]
+			}
+			
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: findText(matches, threshold)},
 				},
+				Meta: timer.meta(map[string]any{
+					"matches":   findMeta(matches),
+					"threshold": threshold,
+				}),
+			}, nil
+		},
+	)
+	
+	// x11_watch_region tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_watch_region",
+			Title:       "X11 Watch Region",
+			Description: "Start watching a screen region, such as a progress bar, build log or chat window, for content changes. Changes are logged as notifications and can be waited for with x11_wait_region_change",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WatchRegionInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			interval := defaultWatchInterval
+			if args.Interval > 0 {
+				interval = time.Duration(args.Interval) * time.Millisecond
+			}
+			threshold := defaultWatchThreshold
+			if args.Threshold > 0 {
+				threshold = args.Threshold
+			}
+			
+			id, watch, err := regionWatches.start(image.Rect(args.X, args.Y, args.X+args.Width, args.Y+args.Height), interval, threshold)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("capture_ms")
+			
+			pngData, err := client.EncodePNG(watch.Baseline)
+			if err != nil {
+				return nil, err
 			}
+			timer.mark("encode_ms")
 			
+			r := watch.Rect
 			return &mcp.CallToolResultFor[any]{
-				Content: content,
-				Meta: map[string]any{
-					"pid": pid,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Watching region %dx%d+%d+%d as watch %d (every %s, threshold %.1f%%)",
+						r.Dx(), r.Dy(), r.Min.X, r.Min.Y, id, interval, threshold*100)},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
 				},
+				Meta: timer.meta(map[string]any{
+					"watch_id": id,
+					"region":   map[string]int{"x": r.Min.X, "y": r.Min.Y, "width": r.Dx(), "height": r.Dy()},
+				}),
 			}, nil
 		},
 	)
 	
-	// x11_key_press tool
+	// x11_wait_region_change tool
 	mcp.AddTool(server,
 		&mcp.Tool{
-			Name:        "x11_key_press",
-			Title:       "X11 Key Press",
-			Description: "Press special keys or key combinations, returns screenshot after delay",
+			Name:        "x11_wait_region_change",
+			Title:       "X11 Wait Region Change",
+			Description: "Wait until a region watched with x11_watch_region changes and return its new content. Returns at once if it changed since the previous call",
 		},
-		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[KeyPressInput]) (*mcp.CallToolResultFor[any], error) {
-			// Handle either single key or key combo
-			if params.Arguments.Combo != "" {
-				if err := client.KeyCombo(params.Arguments.Combo); err != nil {
-					return nil, err
-				}
-			} else if params.Arguments.Key != "" {
-				if err := client.KeyPress(params.Arguments.Key); err != nil {
-					return nil, err
-				}
-			} else {
-				return nil, fmt.Errorf("either 'key' or 'combo' must be specified")
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[WaitRegionChangeInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			watch, err := regionWatches.get(args.WatchID)
+			if err != nil {
+				return nil, err
+			}
+			if args.Stop {
+				defer regionWatches.stop(args.WatchID)
 			}
 			
-			delay := params.Arguments.Delay
-			if delay == 0 {
-				delay = 100 // Default 100ms delay
+			timeout := defaultWatchTimeout
+			if args.Timeout > 0 {
+				timeout = time.Duration(args.Timeout) * time.Millisecond
+			}
+			change, ok, err := watch.Wait(ctx, timeout)
+			if err != nil {
+				return nil, err
 			}
+			timer.mark("wait_ms")
 			
-			// Wait for the specified delay
-			time.Sleep(time.Duration(delay) * time.Millisecond)
+			meta := map[string]any{"watch_id": args.WatchID, "changed": ok, "changes": watch.Changes()}
+			if !ok {
+				return &mcp.CallToolResultFor[any]{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("No change in watch %d within %s", args.WatchID, timeout)},
+					},
+					Meta: timer.meta(meta),
+				}, nil
+			}
 			
-			// Take screenshot
-			pngData, err := client.ScreenshotPNG()
+			pngData, err := client.EncodePNG(change.Image)
 			if err != nil {
-				return nil, fmt.Errorf("failed to take screenshot: %w", err)
+				return nil, err
 			}
+			timer.mark("encode_ms")
 			
-			content := []mcp.Content{
-				&mcp.TextContent{
-					Text: fmt.Sprintf("Pressed: %s%s", params.Arguments.Key, params.Arguments.Combo),
-				},
-				&mcp.ImageContent{
-					Data:     pngData,
-					MIMEType: "image/png",
+			meta["changed_fraction"] = change.Changed
+			meta["time"] = change.Time.Format(time.RFC3339Nano)
+			return &mcp.CallToolResultFor[any]{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Watch %d changed: %.1f%% of the region differs (%d changes so far)",
+						args.WatchID, change.Changed*100, watch.Changes())},
+					&mcp.ImageContent{
+						Data:     pngData,
+						MIMEType: "image/png",
+					},
 				},
+				Meta: timer.meta(meta),
+			}, nil
+		},
+	)
+	
+	// x11_read_progress_bar tool
+	mcp.AddTool(server,
+		&mcp.Tool{
+			Name:        "x11_read_progress_bar",
+			Title:       "X11 Read Progress Bar",
+			Description: "Estimate the completion percentage of a progress bar in a screen region by color analysis, without returning a screenshot. The region should cover the bar's trough and little else",
+		},
+		func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[ReadProgressBarInput]) (*mcp.CallToolResultFor[any], error) {
+			timer := newToolTimer()
+			args := params.Arguments
+			
+			reading, err := client.ReadProgressBar(ctx, image.Rect(args.X, args.Y, args.X+args.Width, args.Y+args.Height), args.Orientation)
+			if err != nil {
+				return nil, err
+			}
+			timer.mark("capture_ms")
+			
+			fill := fmt.Sprintf("#%02x%02x%02x", reading.Fill.R, reading.Fill.G, reading.Fill.B)
+			track := fmt.Sprintf("#%02x%02x%02x", reading.Track.R, reading.Track.G, reading.Track.B)
+			text := fmt.Sprintf("Progress: %.1f%% (%s, fill %s, track %s)", reading.Percent, reading.Orientation, fill, track)
+			if !reading.Confident {
+				text = fmt.Sprintf("Progress unknown: the region is a single color (%s), so the bar is empty, full or not in the region", fill)
 			}
 			
 			return &mcp.CallToolResultFor[any]{
-				Content: content,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text},
+				},
+				Meta: timer.meta(map[string]any{
+					"percent":     reading.Percent,
+					"orientation": reading.Orientation,
+					"fill":        fill,
+					"track":       track,
+					"contrast":    reading.Contrast,
+					"confident":   reading.Confident,
+				}),
 			}, nil
 		},
 	)
@@ -396,7 +3280,7 @@ Example workflow:
 				Description: "Get the i3 window tree as JSON. Use this to find window IDs and container structure for window management.",
 			},
 			func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[I3GetTreeInput]) (*mcp.CallToolResultFor[any], error) {
-				treeJSON, err := client.I3GetTree()
+				treeJSON, err := client.I3GetTreeContext(ctx)
 				if err != nil {
 					return nil, err
 				}
@@ -413,6 +3297,34 @@ Example workflow:
 			},
 		)
 		
+		// i3_get_focused tool
+		mcp.AddTool(server,
+			&mcp.Tool{
+				Name:        "i3_get_focused",
+				Title:       "i3 Get Focused",
+				Description: "Get the focused i3 container: its con_id, window ID, class, title and workspace. Much smaller than i3_get_tree when that is all you need.",
+			},
+			func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[I3GetFocusedInput]) (*mcp.CallToolResultFor[any], error) {
+				focused, err := client.I3FocusedContainer(ctx)
+				if err != nil {
+					return nil, err
+				}
+				
+				return &mcp.CallToolResultFor[any]{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: i3FocusedText(focused)},
+					},
+					Meta: map[string]any{
+						"con_id":    focused.ConID,
+						"window_id": uint32(focused.Window),
+						"class":     focused.Class,
+						"title":     focused.Title,
+						"workspace": focused.Workspace,
+					},
+				}, nil
+			},
+		)
+		
 		// i3_cmd tool
 		mcp.AddTool(server,
 			&mcp.Tool{
@@ -421,15 +3333,24 @@ Example workflow:
 				Description: "Send a command to i3 window manager. Examples: '[con_id=1234] focus' to focus a window, 'workspace 2' to switch workspace, '[class=\"Firefox\"] move to workspace 3' to move windows.",
 			},
 			func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[I3CmdInput]) (*mcp.CallToolResultFor[any], error) {
-				result, err := client.I3Command(params.Arguments.Command)
+				timer := newToolTimer()
+				
+				result, err := client.I3CommandContext(ctx, params.Arguments.Command)
 				if err != nil {
 					return nil, err
 				}
+				timer.mark("i3_ms")
+				
+				// Give i3 time to redraw before the screenshot
+				if err := client.WaitContext(ctx, delays.forScreenshot("i3_cmd")); err != nil {
+					return nil, err
+				}
+				timer.mark("wait_ms")
 				
 				// Take screenshot to show result
-				pngData, err := client.ScreenshotPNG()
+				pngData, err := screenshotPNG(ctx, timer, params.Arguments.FocusedWindowOnly)
 				if err != nil {
-					return nil, fmt.Errorf("failed to take screenshot: %w", err)
+					return nil, err
 				}
 				
 				content := []mcp.Content{
@@ -444,14 +3365,314 @@ Example workflow:
 				
 				return &mcp.CallToolResultFor[any]{
 					Content: content,
+					Meta:    timer.meta(nil),
+				}, nil
+			},
+		)
+		
+		// i3_screenshot_workspace tool
+		mcp.AddTool(server,
+			&mcp.Tool{
+				Name:        "i3_screenshot_workspace",
+				Title:       "i3 Screenshot Workspace",
+				Description: "Capture another i3 workspace by switching to it briefly and back, to look at background workspaces without changing focus for good",
+			},
+			func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[I3ScreenshotWorkspaceInput]) (*mcp.CallToolResultFor[any], error) {
+				timer := newToolTimer()
+				settle := time.Duration(delays.forTool("i3_screenshot_workspace", params.Arguments.Delay)) * time.Millisecond
+				img, name, err := client.I3ScreenshotWorkspace(ctx, params.Arguments.Num, settle)
+				if err != nil {
+					return nil, err
+				}
+				defer client.RecycleScreenshot(img)
+				timer.mark("capture_ms")
+				
+				pngData, err := client.EncodePNG(img)
+				if err != nil {
+					return nil, err
+				}
+				timer.mark("encode_ms")
+				
+				return &mcp.CallToolResultFor[any]{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Captured workspace %s", name)},
+						&mcp.ImageContent{Data: pngData, MIMEType: "image/png"},
+					},
+					Meta: timer.meta(map[string]any{
+						"workspace": name,
+						"origin":    map[string]int{"x": img.Rect.Min.X, "y": img.Rect.Min.Y},
+					}),
 				}, nil
 			},
 		)
+		
+		// i3_fullscreen tool
+		mcp.AddTool(server,
+			&mcp.Tool{
+				Name:        "i3_fullscreen",
+				Title:       "i3 Fullscreen",
+				Description: "Toggle, enable or disable fullscreen for a window's container, returns screenshot",
+			},
+			func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[I3FullscreenInput]) (*mcp.CallToolResultFor[any], error) {
+				timer := newToolTimer()
+				args := params.Arguments
+				if err := client.I3Fullscreen(ctx, x.Window(args.WindowID), args.Mode); err != nil {
+					return nil, err
+				}
+				timer.mark("i3_ms")
+				mode := args.Mode
+				if mode == "" {
+					mode = "toggle"
+				}
+				return i3Result(ctx, timer, "i3_fullscreen", fmt.Sprintf("Fullscreen %s for %s", mode, containerName(args.WindowID)), args.FocusedWindowOnly)
+			},
+		)
+		
+		// i3_border tool
+		mcp.AddTool(server,
+			&mcp.Tool{
+				Name:        "i3_border",
+				Title:       "i3 Border",
+				Description: "Set the border style of a window's container: none, pixel N or normal. Removing borders and title bars keeps pixel coordinates stable across machines. Returns screenshot",
+			},
+			func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[I3BorderInput]) (*mcp.CallToolResultFor[any], error) {
+				timer := newToolTimer()
+				args := params.Arguments
+				if err := client.I3Border(ctx, x.Window(args.WindowID), args.Style, args.Width); err != nil {
+					return nil, err
+				}
+				timer.mark("i3_ms")
+				text := fmt.Sprintf("Border %s", args.Style)
+				if args.Width > 0 {
+					text += fmt.Sprintf(" %d", args.Width)
+				}
+				return i3Result(ctx, timer, "i3_border", text+" for "+containerName(args.WindowID), args.FocusedWindowOnly)
+			},
+		)
 	}
 	
-	// Run the server
+	// Observers only look, so leave out everything that needs XTEST or
+	// changes the display
+	if client.Observer() {
+		server.RemoveTools(inputTools...)
+		slog.Info("observer mode, input tools disabled")
+	}
+	
+	// Run the server until stdin closes or we are told to stop; returning
+	// instead of exiting lets Close release held keys and buttons
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	defer releaseControls()
 	transport := mcp.NewStdioTransport()
-	if err := server.Run(context.Background(), transport); err != nil {
-		log.Fatalf("Server error: %v", err)
+	if err := server.Run(ctx, transport); err != nil && ctx.Err() == nil {
+		slog.Error("server failed", "err", err)
+		releaseControls()
+		client.Close()
+		os.Exit(1)
+	}
+}
+
+// i3Result waits for i3 to redraw and returns text with a screenshot, the
+// result of the i3 convenience tools
+func i3Result(ctx context.Context, timer *toolTimer, tool, text string, focusedOnly bool) (*mcp.CallToolResultFor[any], error) {
+	if err := client.WaitContext(ctx, delays.forScreenshot(tool)); err != nil {
+		return nil, err
+	}
+	timer.mark("wait_ms")
+	pngData, err := screenshotPNG(ctx, timer, focusedOnly)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+			&mcp.ImageContent{Data: pngData, MIMEType: "image/png"},
+		},
+		Meta: timer.meta(nil),
+	}, nil
+}
+
+// containerName names the container an i3 tool changed
+func containerName(win uint32) string {
+	if win == 0 {
+		return "the focused container"
+	}
+	return fmt.Sprintf("window 0x%x", win)
+}
+
+// clickText describes a click, noting when the pointer didn't land on target
+func clickText(x, y, finalX, finalY, button int) string {
+	text := fmt.Sprintf("Clicked at (%d, %d) with button %d", x, y, button)
+	if name := x11.ButtonName(button); name != "" && button > x11.ButtonRight {
+		text += fmt.Sprintf(" (%s)", name)
+	}
+	if finalX != x || finalY != y {
+		text += fmt.Sprintf(" (pointer landed at (%d, %d))", finalX, finalY)
+	}
+	return text
+}
+
+// windowAtText describes the window found at a point
+func windowAtText(x, y int, win x11.WindowAt) string {
+	if win.ID == 0 {
+		return fmt.Sprintf("No window at (%d, %d), only the desktop", x, y)
+	}
+	text := fmt.Sprintf("Window 0x%x at (%d, %d): %q (class: %s), %dx%d at (%d, %d)",
+		uint32(win.ID), x, y, win.Title, orNone(win.Class), win.Rect.Dx(), win.Rect.Dy(), win.Rect.Min.X, win.Rect.Min.Y)
+	if win.Frame != win.ID {
+		text += fmt.Sprintf(", frame 0x%x", uint32(win.Frame))
+	}
+	return text
+}
+
+// windowInfoText describes a window for x11_get_window_info
+func windowInfoText(w x11.Window) string {
+	text := fmt.Sprintf("Window 0x%x: %q (class: %s), %dx%d at (%d, %d)",
+		uint32(w.ID), w.Title, orNone(w.Class), w.Width, w.Height, w.X, w.Y)
+	if w.PID != 0 {
+		text += fmt.Sprintf(", PID %d", w.PID)
+	}
+	if w.Workspace >= 0 {
+		text += fmt.Sprintf(", workspace %d", w.Workspace)
+	}
+	if len(w.State) > 0 {
+		text += ", state " + strings.Join(w.State, " ")
+	}
+	return text
+}
+
+// typedText reports text that was typed, repeating it if echo is set and
+// otherwise giving only its length
+func typedText(verb, text string, echo bool) string {
+	if echo {
+		return fmt.Sprintf("%s: %s", verb, text)
+	}
+	return fmt.Sprintf("%s %d characters", verb, utf8.RuneCountInString(text))
+}
+
+// typingError returns an error of typing text, withholding the character
+// that failed if the text is confidential
+func typingError(err error, sensitive bool) error {
+	if sensitive {
+		return x11.WithholdChar(err)
+	}
+	return err
+}
+
+// confinementText describes the pointer confinement, with the one set with
+// --confine if a tool call narrowed it
+func confinementText(conf, floor x11.Confinement) string {
+	if floor.Active() && conf != floor {
+		return fmt.Sprintf("%s within %s set with --confine", conf, floor)
+	}
+	return orNone(conf.String())
+}
+
+// i3FocusedText describes the focused i3 container for i3_get_focused
+func i3FocusedText(f x11.I3Focused) string {
+	if f.Window == 0 {
+		return fmt.Sprintf("Focused container %d %q without a window on workspace %s", f.ConID, f.Title, f.Workspace)
+	}
+	return fmt.Sprintf("Focused window 0x%x: %q (class: %s), container %d on workspace %s",
+		uint32(f.Window), f.Title, orNone(f.Class), f.ConID, f.Workspace)
+}
+
+// openURLText describes an opened URL for the x11_open_url result
+func openURLText(url string, o x11.OpenedURL) string {
+	how := fmt.Sprintf("in window 0x%x", uint32(o.Window))
+	if o.PID != 0 {
+		how = fmt.Sprintf("in a new browser with PID %d", o.PID)
+	}
+	if !o.PageLoad.Loaded {
+		return fmt.Sprintf("Opened %s %s, page still loading (%s): %q", url, how, strings.Join(o.PageLoad.Busy, ", "), o.PageLoad.Title)
+	}
+	return fmt.Sprintf("Opened %s %s, loaded after %s: %q", url, how, o.PageLoad.Elapsed.Round(time.Millisecond), o.PageLoad.Title)
+}
+
+// dismissText describes a dismissed popup for the x11_dismiss result
+func dismissText(d x11.Dismissal) string {
+	if d.Window == 0 {
+		return "No menu, tooltip or popup window to dismiss"
+	}
+	text := fmt.Sprintf("Dismissed %s 0x%x (class: %s) %q", d.Kind, uint32(d.Window), orNone(d.Class), d.Title)
+	if d.Clicked {
+		text += fmt.Sprintf(" with a click at %d,%d after Escape didn't close it", d.ClickedAt.X, d.ClickedAt.Y)
+	} else {
+		text += " with Escape"
+	}
+	if d.Remaining != 0 {
+		text += fmt.Sprintf("; window 0x%x is still open, call again to close it too", uint32(d.Remaining))
+	}
+	return text
+}
+
+// startText describes a started program for the x11_start_program result
+func startText(program string, pid int, transcript bool) string {
+	text := fmt.Sprintf("Started %s with PID %d", program, pid)
+	if transcript {
+		text += ", read its output with x11_read_terminal"
+	}
+	return text
+}
+
+// pacingText describes the X server latency for x11_status
+func pacingText(p x11.Pacing) string {
+	text := fmt.Sprintf("X server round trip: %s (best %s)", p.Latency.Round(10*time.Microsecond), p.Baseline.Round(10*time.Microsecond))
+	switch {
+	case p.Fixed:
+		text += ", adaptive pacing off"
+	case p.Extra > 0:
+		text += fmt.Sprintf(", server saturated: pausing %s before each key and button press", p.Extra)
+	}
+	return text
+}
+
+// xErrorsText summarizes the X errors for x11_status: their number by name
+// and the latest
+func xErrorsText(s x11.XErrorSummary, now time.Time) string {
+	if s.Total == 0 {
+		return "X errors: none"
+	}
+	names := slices.Sorted(maps.Keys(s.Counts))
+	counts := make([]string, len(names))
+	for i, name := range names {
+		counts[i] = fmt.Sprintf("%s %d", name, s.Counts[name])
 	}
-}
\ No newline at end of file
+	text := fmt.Sprintf("X errors: %d (%s)", s.Total, strings.Join(counts, ", "))
+	if n := len(s.Recent); n > 0 {
+		last := s.Recent[n-1]
+		text += fmt.Sprintf(", latest %s ago: %s", now.Sub(last.Time).Round(time.Second), last)
+	}
+	return text
+}
+
+// orNone returns s, or "none" if it is empty
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// checkDialogPath checks a path for x11_select_file_in_dialog before the
+// dialog is touched: "open" needs an existing file, "save" an existing
+// directory to save into
+func checkDialogPath(path, mode string) error {
+	switch strings.ToLower(mode) {
+	case "":
+		return nil
+	case "open":
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("cannot open %s: %w", path, err)
+		}
+		return nil
+	case "save":
+		dir := filepath.Dir(path)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("cannot save %s: directory %s does not exist", path, dir)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mode: %s (use open or save)", mode)
+	}
+}
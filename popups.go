@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxPendingPopups bounds how many unreported popups are kept
+const maxPendingPopups = 10
+
+// popupModes are the accepted values of --popups
+var popupModes = []string{"notify", "capture", "off"}
+
+// popupReport is a popup waiting to be reported in a tool result
+type popupReport struct {
+	popup x11.Popup
+	png   []byte       // Capture of the popup, nil if not captured
+	shot  historyEntry // The capture's history entry
+}
+
+// popupTracker queues popups seen by the watcher until the next tool result
+// reports them
+type popupTracker struct {
+	mu      sync.Mutex
+	pending []popupReport
+}
+
+var popups popupTracker
+
+// parsePopupMode validates a --popups value
+func parsePopupMode(mode string) (string, error) {
+	mode = strings.ToLower(mode)
+	for _, m := range popupModes {
+		if m == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown popups mode: %s (available: %s)", mode, strings.Join(popupModes, ", "))
+}
+
+// add queues a popup, encoding and keeping its capture if it has one. The
+// log record also reaches MCP clients as a logging notification.
+func (p *popupTracker) add(popup x11.Popup) {
+	report := popupReport{popup: popup}
+	if popup.Image != nil {
+		pngData, err := client.EncodePNG(popup.Image)
+		if err != nil {
+			slog.Warn("failed to encode popup capture", "err", err)
+		} else {
+			report.png = pngData
+			report.shot = history.add(historyEntry{
+				PNG:    pngData,
+				Window: uint32(popup.Frame),
+				Origin: popup.Image.Bounds().Min,
+				Hash:   x11.PerceptualHash(popup.Image),
+			})
+		}
+	}
+	slog.Warn("popup appeared", "popup", popup.String())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, report)
+	if len(p.pending) > maxPendingPopups {
+		p.pending = p.pending[len(p.pending)-maxPendingPopups:]
+	}
+}
+
+// take returns and clears the unreported popups
+func (p *popupTracker) take() []popupReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	reports := p.pending
+	p.pending = nil
+	return reports
+}
+
+// reportPopups is receiving middleware that appends popups seen since the
+// last tool call to the next successful tool result, so agents notice
+// dialogs they did not expect
+func reportPopups(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		if method != "tools/call" || err != nil {
+			return result, err
+		}
+		res, ok := result.(*mcp.CallToolResult)
+		if !ok {
+			return result, err
+		}
+		reports := popups.take()
+		if len(reports) == 0 {
+			return result, err
+		}
+
+		meta := make([]map[string]any, 0, len(reports))
+		for _, r := range reports {
+			text := "Popup appeared: " + r.popup.String()
+			entry := map[string]any{
+				"window_id": uint32(r.popup.Window),
+				"title":     r.popup.Title,
+				"class":     r.popup.Class,
+				"dialog":    r.popup.Dialog,
+				"time":      r.popup.Time.Format(time.RFC3339Nano),
+			}
+			if r.popup.TransientFor != 0 {
+				entry["transient_for"] = uint32(r.popup.TransientFor)
+			}
+			if r.png != nil {
+				if shot := screenshotMeta(r.shot); len(shot) > 0 {
+					entry["screenshot"] = shot
+				}
+				if r.shot.Index != 0 {
+					text += fmt.Sprintf(", capture kept as screenshot index %d", r.shot.Index)
+				}
+			}
+			res.Content = append(res.Content, &mcp.TextContent{Text: text})
+			if r.png != nil {
+				res.Content = append(res.Content, &mcp.ImageContent{Data: r.png, MIMEType: "image/png"})
+			}
+			meta = append(meta, entry)
+		}
+		if res.Meta == nil {
+			res.Meta = mcp.Meta{}
+		}
+		res.Meta["popups"] = meta
+		return result, err
+	}
+}
+
+// watchMenus starts catching a menu opened by the coming action if enabled,
+// returning nil otherwise or if watching fails
+func watchMenus(ctx context.Context, enabled bool) *x11.MenuCapture {
+	if !enabled {
+		return nil
+	}
+	watch, err := client.WatchMenus(ctx)
+	if err != nil {
+		slog.Warn("failed to watch for menus", "err", err)
+		return nil
+	}
+	return watch
+}
+
+// menuContent waits for the menu watch to catch a menu and returns its
+// capture for the tool result, keeping it in the history and describing it
+// in meta. It returns nothing if watch is nil.
+func menuContent(ctx context.Context, watch *x11.MenuCapture, timer *toolTimer, meta map[string]any) ([]mcp.Content, error) {
+	if watch == nil {
+		return nil, nil
+	}
+	menu, err := watch.Capture(ctx, x11.DefaultMenuWait)
+	timer.mark("menu_ms")
+	if err != nil {
+		return nil, err
+	}
+	if menu == nil {
+		meta["menu"] = nil
+		return []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("No menu or tooltip opened within %s", x11.DefaultMenuWait)}}, nil
+	}
+	defer client.RecycleScreenshot(menu.Image)
+	pngData, err := client.EncodePNG(menu.Image)
+	if err != nil {
+		return nil, err
+	}
+	shot := history.add(historyEntry{
+		PNG:    pngData,
+		Window: uint32(menu.Window),
+		Origin: menu.Image.Bounds().Min,
+		Hash:   x11.PerceptualHash(menu.Image),
+	})
+	meta["menu"] = map[string]any{"window_id": uint32(menu.Window), "class": menu.Class}
+	text := fmt.Sprintf("Menu 0x%x (class: %s) captured as it opened", uint32(menu.Window), orNone(menu.Class))
+	if shot.Index != 0 {
+		text += fmt.Sprintf(", kept as screenshot index %d", shot.Index)
+	}
+	return []mcp.Content{
+		&mcp.TextContent{Text: text},
+		&mcp.ImageContent{Data: pngData, MIMEType: "image/png"},
+	}, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParsePopupMode(t *testing.T) {
+	for _, mode := range []string{"notify", "Capture", "off"} {
+		if _, err := parsePopupMode(mode); err != nil {
+			t.Errorf("parsePopupMode(%q) failed: %v", mode, err)
+		}
+	}
+	if _, err := parsePopupMode("auto"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestReportPopups(t *testing.T) {
+	defer popups.take()
+
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Clicked"}}}, nil
+	}
+	handler := reportPopups(next)
+
+	// Nothing pending: the result is unchanged
+	result, err := handler(context.Background(), nil, "tools/call", nil)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if res := result.(*mcp.CallToolResult); len(res.Content) != 1 || res.Meta != nil {
+		t.Errorf("unexpected additions without popups: %+v", res)
+	}
+
+	popups.mu.Lock()
+	popups.pending = []popupReport{{popup: x11.Popup{
+		Window:       0x400001,
+		Title:        "Restore session?",
+		TransientFor: 0x200001,
+		Dialog:       true,
+		Time:         time.Now(),
+	}}}
+	popups.mu.Unlock()
+
+	// Other methods leave popups queued
+	if _, err := handler(context.Background(), nil, "tools/list", nil); err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+
+	result, err = handler(context.Background(), nil, "tools/call", nil)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	res := result.(*mcp.CallToolResult)
+	if len(res.Content) != 2 {
+		t.Fatalf("got %d content items, want 2", len(res.Content))
+	}
+	text := res.Content[1].(*mcp.TextContent).Text
+	if !strings.Contains(text, `Dialog "Restore session?"`) || !strings.Contains(text, "transient for 0x200001") {
+		t.Errorf("popup text = %q", text)
+	}
+	reported, ok := res.Meta["popups"].([]map[string]any)
+	if !ok || len(reported) != 1 || reported[0]["window_id"] != uint32(0x400001) {
+		t.Errorf("popups meta = %#v", res.Meta["popups"])
+	}
+
+	// Reported popups are not repeated
+	result, _ = handler(context.Background(), nil, "tools/call", nil)
+	if res := result.(*mcp.CallToolResult); len(res.Content) != 1 {
+		t.Errorf("popup reported twice: %+v", res.Content)
+	}
+}
+
+func TestPopupTrackerBounded(t *testing.T) {
+	var tracker popupTracker
+	for i := 0; i < maxPendingPopups+5; i++ {
+		tracker.pending = append(tracker.pending, popupReport{popup: x11.Popup{Title: strings.Repeat("x", i)}})
+	}
+	// add trims to the newest entries
+	tracker.add(x11.Popup{Title: "newest"})
+	reports := tracker.take()
+	if len(reports) != maxPendingPopups || reports[len(reports)-1].popup.Title != "newest" {
+		t.Errorf("got %d reports, last %q", len(reports), reports[len(reports)-1].popup.Title)
+	}
+}
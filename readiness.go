@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"mcp-x11-controller/x11"
+	"os/exec"
+	"time"
+)
+
+// readyProbe converts the ready argument of x11_start_program
+func readyProbe(in *ReadyInput) x11.ReadyProbe {
+	if in == nil {
+		return x11.ReadyProbe{}
+	}
+	return x11.ReadyProbe{
+		Window:      in.Window,
+		WindowClass: in.WindowClass,
+		Title:       in.Title,
+		Text:        in.Text,
+		Port:        in.Port,
+		TimeoutMS:   in.TimeoutMS,
+	}
+}
+
+// readyOCR returns text recognition for the text check, nil without tesseract
+func readyOCR() x11.OCRFunc {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil
+	}
+	return func(ctx context.Context, img image.Image) (string, error) {
+		return ocrImage(ctx, client, img)
+	}
+}
+
+// readyText describes how a program became ready, empty without checks
+func readyText(r *x11.Ready) string {
+	if r == nil {
+		return ""
+	}
+	text := fmt.Sprintf(", ready after %s", r.Elapsed.Round(time.Millisecond))
+	if r.Window != 0 {
+		text += fmt.Sprintf(" with window 0x%x %q", uint32(r.Window), r.Title)
+	}
+	return text
+}
+
+// readyMeta is the _meta.ready of x11_start_program
+func readyMeta(r x11.Ready) map[string]any {
+	meta := map[string]any{"elapsed_ms": r.Elapsed.Milliseconds()}
+	if r.Window != 0 {
+		meta["window_id"] = uint32(r.Window)
+		meta["title"] = r.Title
+	}
+	return meta
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"mcp-x11-controller/x11"
+	"testing"
+	"time"
+)
+
+func TestReadyText(t *testing.T) {
+	tests := []struct {
+		ready *x11.Ready
+		want  string
+	}{
+		{nil, ""},
+		{&x11.Ready{Elapsed: 1234567 * time.Microsecond}, ", ready after 1.235s"},
+		{&x11.Ready{Window: 0x1a00003, Title: "Example", Elapsed: 2 * time.Second}, ", ready after 2s with window 0x1a00003 \"Example\""},
+	}
+	for _, tt := range tests {
+		if got := readyText(tt.ready); got != tt.want {
+			t.Errorf("readyText(%+v) = %q, want %q", tt.ready, got, tt.want)
+		}
+	}
+}
+
+func TestReadyProbe(t *testing.T) {
+	if !readyProbe(nil).Empty() {
+		t.Error("readyProbe(nil) is not empty")
+	}
+	probe := readyProbe(&ReadyInput{Title: "Example", Port: 8080, TimeoutMS: 5000})
+	if probe.Title != "Example" || probe.Port != 8080 || probe.TimeoutMS != 5000 || probe.Empty() {
+		t.Errorf("readyProbe = %+v", probe)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"sync"
+	"time"
+)
+
+// Region watch limits and defaults
+const (
+	maxRegionWatches      = 8
+	defaultWatchInterval  = 500 * time.Millisecond
+	minWatchInterval      = 50 * time.Millisecond
+	defaultWatchThreshold = 0.01
+	defaultWatchTimeout   = 30 * time.Second
+)
+
+// regionWatchSet holds the running region watches by ID
+type regionWatchSet struct {
+	mu      sync.Mutex
+	watches map[int]*x11.RegionWatch
+	next    int
+}
+
+var regionWatches = regionWatchSet{watches: make(map[int]*x11.RegionWatch), next: 1}
+
+// start begins watching rect. Every change is logged, which reaches MCP
+// clients as a logging notification.
+func (s *regionWatchSet) start(rect image.Rectangle, interval time.Duration, threshold float64) (int, *x11.RegionWatch, error) {
+	if threshold < 0 || threshold >= 1 {
+		return 0, nil, fmt.Errorf("threshold must be at least 0 and below 1, got %g", threshold)
+	}
+	if interval < minWatchInterval {
+		return 0, nil, fmt.Errorf("interval must be at least %s, got %s", minWatchInterval, interval)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.watches) >= maxRegionWatches {
+		return 0, nil, fmt.Errorf("already watching %d regions, stop one first", maxRegionWatches)
+	}
+
+	id := s.next
+	watch, err := client.WatchRegion(rect, interval, threshold, func(change x11.RegionChange) {
+		slog.Info("watched region changed", "watch_id", id, "changed", fmt.Sprintf("%.1f%%", change.Changed*100))
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	s.next++
+	s.watches[id] = watch
+	return id, watch, nil
+}
+
+// get returns a running watch
+func (s *regionWatchSet) get(id int) (*x11.RegionWatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watch, ok := s.watches[id]
+	if !ok {
+		return nil, fmt.Errorf("no region watch with ID %d", id)
+	}
+	return watch, nil
+}
+
+// stop ends a watch and forgets it
+func (s *regionWatchSet) stop(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	watch, ok := s.watches[id]
+	if !ok {
+		return fmt.Errorf("no region watch with ID %d", id)
+	}
+	watch.Stop()
+	delete(s.watches, id)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestRegionWatchValidation(t *testing.T) {
+	rect := image.Rect(0, 0, 100, 20)
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		threshold float64
+	}{
+		{"negative threshold", defaultWatchInterval, -0.1},
+		{"threshold of one", defaultWatchInterval, 1},
+		{"interval too short", time.Millisecond, defaultWatchThreshold},
+	}
+	for _, tt := range tests {
+		if _, _, err := regionWatches.start(rect, tt.interval, tt.threshold); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestRegionWatchUnknownID(t *testing.T) {
+	if _, err := regionWatches.get(999); err == nil {
+		t.Error("get: expected error for unknown watch")
+	}
+	if err := regionWatches.stop(999); err == nil {
+		t.Error("stop: expected error for unknown watch")
+	}
+}
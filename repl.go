@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mcp-x11-controller/x11"
+	"strings"
+	"unicode"
+)
+
+// replHelp lists the commands understood by the REPL
+const replHelp = `Commands:
+  click X Y [BUTTON]   Move the mouse to X,Y and click
+  type TEXT            Type TEXT (quote it to keep spacing)
+  key KEY|COMBO        Press a key or key combination
+  shot FILE            Save a PNG screenshot to FILE
+  windows              List visible windows
+  tree                 Print the i3 window tree
+  help                 Show this help
+  quit                 Exit`
+
+// runREPL reads commands line by line from in and runs them against c until
+// EOF or quit
+func runREPL(ctx context.Context, c *x11.Client, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintln(out, `X11 controller REPL, type "help" for commands`)
+
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		args, err := splitCommandLine(scanner.Text())
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		switch args[0] {
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Fprintln(out, replHelp)
+		default:
+			if err := runCommand(ctx, c, args, out); err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// splitCommandLine splits a line into words, honoring double and single quotes
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{"click 10 20", []string{"click", "10", "20"}, false},
+		{"  type   hi  ", []string{"type", "hi"}, false},
+		{`type "hello  world"`, []string{"type", "hello  world"}, false},
+		{`type 'it"s'`, []string{"type", `it"s`}, false},
+		{`type ""`, []string{"type", ""}, false},
+		{"", nil, false},
+		{`type "open`, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitCommandLine(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitCommandLine(%q) error = %v, wantErr %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommandLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestREPLLoop(t *testing.T) {
+	in := strings.NewReader("help\n\ndance\nclick 1\nquit\ntype never reached\n")
+	var out bytes.Buffer
+
+	if err := runREPL(context.Background(), nil, in, &out); err != nil {
+		t.Fatalf("runREPL failed: %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{"Commands:", "Error: unknown command: dance", "Error: usage: click X Y [BUTTON]"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Typed") {
+		t.Error("commands after quit must not run")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/config"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// secretsFile is the --secrets-file x11_type_secret reads secrets from
+var secretsFile string
+
+// secretRefPattern is what secret names may look like
+var secretRefPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// pasteSecret pastes secret with paste and empties the clipboard with clear
+// however the paste ends, so a paste that fails, times out or is cancelled
+// doesn't leave the secret on the clipboard for any application to read
+func pasteSecret(ctx context.Context, secret string, paste func(context.Context, string) error, clear func(context.Context) error) (err error) {
+	defer func() {
+		cerr := clear(context.WithoutCancel(ctx))
+		switch {
+		case cerr == nil:
+		case err == nil:
+			err = fmt.Errorf("failed to clear the clipboard: %w", cerr)
+		default:
+			slog.Warn("failed to clear the clipboard after a failed paste", "err", cerr)
+		}
+	}()
+	return paste(ctx, secret)
+}
+
+// secretEnvName returns the environment variable holding the secret ref
+func secretEnvName(ref string) string {
+	return config.SecretEnvPrefix + strings.ToUpper(strings.ReplaceAll(ref, "-", "_"))
+}
+
+// parseSecrets parses NAME=VALUE lines, skipping blank lines and # comments.
+// Values are taken as they are, up to the end of the line. Errors name the
+// line but never quote it.
+func parseSecrets(data []byte) (map[string]string, error) {
+	secrets := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !secretRefPattern.MatchString(name) {
+			return nil, fmt.Errorf("line %d is not NAME=VALUE", n)
+		}
+		secrets[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// lookupSecret returns the secret named ref: the MCP_X11_SECRET_ variable
+// for it if set, else its entry in the secrets file at path. The file is
+// read on every lookup so the operator can change it without a restart.
+func lookupSecret(ref, path string, getenv func(string) (string, bool)) (string, error) {
+	if !secretRefPattern.MatchString(ref) {
+		return "", fmt.Errorf("invalid secret_ref %q: use letters, digits, - and _", ref)
+	}
+	if value, ok := getenv(secretEnvName(ref)); ok {
+		return value, nil
+	}
+	if path == "" {
+		return "", fmt.Errorf("unknown secret %q: %s is not set and there is no --secrets-file", ref, secretEnvName(ref))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	secrets, err := parseSecrets(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse secrets file %s: %w", path, err)
+	}
+	value, ok := secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("unknown secret %q: not in %s and %s is not set", ref, path, secretEnvName(ref))
+	}
+	return value, nil
+}
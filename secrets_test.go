@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSecrets(t *testing.T) {
+	secrets, err := parseSecrets([]byte("# operator secrets\n\ndb-password=p=ss word \nAPI_TOKEN=abc\r\n"))
+	if err != nil {
+		t.Fatalf("parseSecrets failed: %v", err)
+	}
+	if secrets["db-password"] != "p=ss word " || secrets["API_TOKEN"] != "abc" || len(secrets) != 2 {
+		t.Errorf("parseSecrets = %q", secrets)
+	}
+
+	_, err = parseSecrets([]byte("ok=1\nhunter2\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("parseSecrets error = %v, want line 2 named without its content", err)
+	}
+}
+
+func TestLookupSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+	if err := os.WriteFile(path, []byte("db-password=from-file\nmail=mail-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"MCP_X11_SECRET_DB_PASSWORD": "from-env"}
+	getenv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	tests := []struct {
+		ref, path string
+		want      string
+		wantErr   bool
+	}{
+		{"db-password", path, "from-env", false},
+		{"mail", path, "mail-secret", false},
+		{"db-password", "", "from-env", false},
+		{"mail", "", "", true},
+		{"missing", path, "", true},
+		{"../etc/passwd", path, "", true},
+		{"", path, "", true},
+	}
+	for _, tt := range tests {
+		got, err := lookupSecret(tt.ref, tt.path, getenv)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("lookupSecret(%q, %q) = %q, %v; want %q", tt.ref, tt.path, got, err, tt.want)
+		}
+		if err != nil && (strings.Contains(err.Error(), "from-file") || strings.Contains(err.Error(), "mail-secret")) {
+			t.Errorf("lookupSecret(%q) error reveals a secret: %v", tt.ref, err)
+		}
+	}
+}
+
+func TestPasteSecretClears(t *testing.T) {
+	var cleared []error
+	clear := func(ctx context.Context) error {
+		cleared = append(cleared, ctx.Err())
+		return nil
+	}
+
+	if err := pasteSecret(context.Background(), "hunter2", func(context.Context, string) error { return nil }, clear); err != nil {
+		t.Fatalf("pasteSecret failed: %v", err)
+	}
+
+	// A paste that fails or is cancelled still clears, with a live context
+	ctx, cancel := context.WithCancel(context.Background())
+	failed := errors.New("no application fetched the clipboard")
+	err := pasteSecret(ctx, "hunter2", func(context.Context, string) error {
+		cancel()
+		return failed
+	}, clear)
+	if !errors.Is(err, failed) {
+		t.Errorf("pasteSecret = %v, want the paste's error", err)
+	}
+	if len(cleared) != 2 || cleared[0] != nil || cleared[1] != nil {
+		t.Errorf("clear calls saw context errors %v, want two live contexts", cleared)
+	}
+
+	// Failing to clear after a successful paste is an error
+	err = pasteSecret(context.Background(), "hunter2", func(context.Context, string) error { return nil }, func(context.Context) error {
+		return errors.New("BadWindow")
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to clear the clipboard") {
+		t.Errorf("pasteSecret with a failing clear = %v, want a clear error", err)
+	}
+}
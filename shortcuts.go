@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"mcp-x11-controller/x11"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// atspiMaxShortcuts bounds how many accelerators are read from an application
+const atspiMaxShortcuts = 200
+
+// atspiShortcutScript prints the keyboard shortcuts an application exposes
+// through the AT-SPI Action interface, usually its menu items, one per line
+// as "accelerator<TAB>label". GTK reports bindings as
+// "mnemonic;path;accelerator" and Qt as the accelerator alone.
+const atspiShortcutScript = `
+import sys
+import gi
+gi.require_version("Atspi", "2.0")
+from gi.repository import Atspi
+
+pid, max_shortcuts = int(sys.argv[1]), int(sys.argv[2])
+seen, visited = {}, [0]
+
+def walk(node, depth):
+    if len(seen) >= max_shortcuts or visited[0] > 5000 or depth > 12:
+        return
+    visited[0] += 1
+    try:
+        action = node.get_action_iface()
+        if action is not None:
+            for i in range(action.get_n_actions()):
+                binding = action.get_key_binding(i) or ""
+                accel = binding.split(";")[-1].strip()
+                name = node.get_name() or ""
+                if accel and name and accel not in seen:
+                    seen[accel] = name
+        count = node.get_child_count()
+    except Exception:
+        return
+    for i in range(min(count, 100)):
+        child = node.get_child_at_index(i)
+        if child is not None:
+            walk(child, depth + 1)
+
+desktop = Atspi.get_desktop(0)
+for i in range(desktop.get_child_count()):
+    app = desktop.get_child_at_index(i)
+    if app is not None and app.get_process_id() == pid:
+        walk(app, 0)
+        break
+else:
+    sys.exit("application %d is not on the accessibility bus" % pid)
+for accel, name in seen.items():
+    print(accel + "\t" + name.replace("\n", " "))
+`
+
+// shortcut is a key combination and what it does
+type shortcut struct {
+	Keys        string `json:"keys"`
+	Description string `json:"description"`
+}
+
+// appShortcuts are the well-known shortcuts of an application, matched by
+// its WM_CLASS class or instance name
+type appShortcuts struct {
+	name      string
+	classes   []string // Lower case
+	shortcuts []shortcut
+}
+
+// shortcutDB lists shortcuts agents commonly need, so they don't have to be
+// rediscovered for every task
+var shortcutDB = []appShortcuts{
+	{"Firefox", []string{"firefox", "firefox-esr", "navigator"}, []shortcut{
+		{"ctrl+l", "Focus the address bar"},
+		{"ctrl+t", "New tab"},
+		{"ctrl+w", "Close tab"},
+		{"ctrl+tab", "Next tab"},
+		{"ctrl+shift+t", "Reopen closed tab"},
+		{"ctrl+f", "Find in page"},
+		{"ctrl+r", "Reload"},
+		{"alt+Left", "Back"},
+		{"alt+Right", "Forward"},
+		{"ctrl+plus", "Zoom in"},
+		{"ctrl+minus", "Zoom out"},
+		{"ctrl+0", "Reset zoom"},
+		{"F12", "Developer tools"},
+		{"ctrl+shift+p", "New private window"},
+	}},
+	{"Chromium", []string{"chromium", "chromium-browser", "google-chrome", "brave-browser"}, []shortcut{
+		{"ctrl+l", "Focus the address bar"},
+		{"ctrl+t", "New tab"},
+		{"ctrl+w", "Close tab"},
+		{"ctrl+tab", "Next tab"},
+		{"ctrl+shift+t", "Reopen closed tab"},
+		{"ctrl+f", "Find in page"},
+		{"ctrl+r", "Reload"},
+		{"alt+Left", "Back"},
+		{"alt+Right", "Forward"},
+		{"ctrl+shift+i", "Developer tools"},
+		{"ctrl+shift+n", "New incognito window"},
+	}},
+	{"xterm", []string{"xterm", "uxterm"}, []shortcut{
+		{"shift+Insert", "Paste the PRIMARY selection"},
+		{"shift+Prior", "Scroll back one page"},
+		{"shift+Next", "Scroll forward one page"},
+		{"ctrl+left-click", "Main options menu"},
+		{"ctrl+right-click", "VT fonts menu"},
+	}},
+	{"GNOME Terminal", []string{"gnome-terminal", "gnome-terminal-server", "org.gnome.terminal", "org.gnome.console", "kgx"}, []shortcut{
+		{"ctrl+shift+c", "Copy"},
+		{"ctrl+shift+v", "Paste"},
+		{"ctrl+shift+t", "New tab"},
+		{"ctrl+shift+n", "New window"},
+		{"ctrl+shift+w", "Close tab"},
+		{"ctrl+shift+f", "Find"},
+		{"ctrl+Page_Down", "Next tab"},
+	}},
+	{"Konsole", []string{"konsole"}, []shortcut{
+		{"ctrl+shift+c", "Copy"},
+		{"ctrl+shift+v", "Paste"},
+		{"ctrl+shift+t", "New tab"},
+		{"ctrl+shift+w", "Close tab"},
+		{"ctrl+shift+f", "Find"},
+	}},
+	{"Visual Studio Code", []string{"code", "code-oss", "vscodium"}, []shortcut{
+		{"ctrl+shift+p", "Command palette"},
+		{"ctrl+p", "Quick open file"},
+		{"ctrl+s", "Save"},
+		{"ctrl+grave", "Toggle terminal"},
+		{"ctrl+b", "Toggle sidebar"},
+		{"ctrl+shift+f", "Search in files"},
+		{"ctrl+g", "Go to line"},
+		{"F12", "Go to definition"},
+	}},
+	{"LibreOffice", []string{"libreoffice", "soffice", "libreoffice-writer", "libreoffice-calc", "libreoffice-impress"}, []shortcut{
+		{"ctrl+s", "Save"},
+		{"ctrl+shift+s", "Save as"},
+		{"ctrl+o", "Open"},
+		{"ctrl+f", "Find toolbar"},
+		{"ctrl+h", "Find and replace"},
+		{"ctrl+shift+F12", "Hide or show the sidebar"},
+		{"ctrl+F5", "Navigator (Calc: name box with ctrl+shift+F5)"},
+	}},
+	{"Thunderbird", []string{"thunderbird", "mail"}, []shortcut{
+		{"ctrl+n", "New message"},
+		{"ctrl+r", "Reply"},
+		{"ctrl+shift+r", "Reply all"},
+		{"ctrl+l", "Forward"},
+		{"ctrl+k", "Quick filter / search"},
+		{"ctrl+Return", "Send message (in the compose window)"},
+	}},
+	{"Files", []string{"nautilus", "org.gnome.nautilus", "thunar", "nemo", "pcmanfm", "dolphin"}, []shortcut{
+		{"ctrl+l", "Type a location"},
+		{"ctrl+h", "Show hidden files"},
+		{"ctrl+shift+n", "New folder"},
+		{"F2", "Rename"},
+		{"alt+Up", "Parent folder"},
+		{"alt+Left", "Back"},
+	}},
+	{"Text editor", []string{"gedit", "org.gnome.gedit", "gnome-text-editor", "org.gnome.texteditor", "mousepad", "kate", "kwrite", "pluma", "xed"}, []shortcut{
+		{"ctrl+s", "Save"},
+		{"ctrl+shift+s", "Save as"},
+		{"ctrl+o", "Open"},
+		{"ctrl+f", "Find"},
+		{"ctrl+h", "Find and replace"},
+		{"ctrl+i", "Go to line (gedit, gnome-text-editor)"},
+		{"ctrl+g", "Go to line (kate, mousepad)"},
+	}},
+}
+
+// fileDialogShortcuts apply to GTK and Qt file choosers in any application
+var fileDialogShortcuts = []shortcut{
+	{"ctrl+l", "Type a path in a file dialog (see x11_select_file_in_dialog)"},
+	{"ctrl+h", "Show hidden files in a file dialog"},
+}
+
+// knownShortcuts returns the name and shortcuts of the database entry for
+// the first of the given WM_CLASS class or instance names that has one
+func knownShortcuts(names ...string) (string, []shortcut, bool) {
+	for _, name := range names {
+		name = strings.ToLower(name)
+		for _, app := range shortcutDB {
+			if name != "" && slices.Contains(app.classes, name) {
+				return app.name, app.shortcuts, true
+			}
+		}
+	}
+	return "", nil, false
+}
+
+// atspiShortcuts reads the accelerators of an application's menus and
+// actions over AT-SPI, sorted by label
+func atspiShortcuts(ctx context.Context, pid uint32) ([]shortcut, error) {
+	output, err := runAtspi(ctx, atspiShortcutScript, pid, strconv.Itoa(atspiMaxShortcuts))
+	if err != nil {
+		return nil, err
+	}
+	var shortcuts []shortcut
+	for _, line := range strings.Split(output, "\n") {
+		keys, label, ok := strings.Cut(line, "\t")
+		if ok && keys != "" {
+			shortcuts = append(shortcuts, shortcut{Keys: normalizeAccelerator(keys), Description: label})
+		}
+	}
+	sort.Slice(shortcuts, func(i, j int) bool { return shortcuts[i].Description < shortcuts[j].Description })
+	return shortcuts, nil
+}
+
+// normalizeAccelerator turns GTK accelerators like "<Control><Shift>t" and
+// Qt ones like "Ctrl+Shift+T" into the combo syntax of x11_key_press
+func normalizeAccelerator(accel string) string {
+	var mods []string
+	for strings.HasPrefix(accel, "<") {
+		end := strings.Index(accel, ">")
+		if end < 0 {
+			break
+		}
+		mods = append(mods, accel[1:end])
+		accel = accel[end+1:]
+	}
+	if len(mods) == 0 && strings.Contains(accel, "+") && len(accel) > 1 {
+		parts := strings.Split(accel, "+")
+		mods, accel = parts[:len(parts)-1], parts[len(parts)-1]
+	}
+
+	var combo []string
+	for _, mod := range mods {
+		switch strings.ToLower(mod) {
+		case "control", "ctrl", "primary":
+			combo = append(combo, "ctrl")
+		case "shift":
+			combo = append(combo, "shift")
+		case "alt", "mod1":
+			combo = append(combo, "alt")
+		case "super", "meta", "mod4":
+			combo = append(combo, "super")
+		default:
+			combo = append(combo, strings.ToLower(mod))
+		}
+	}
+	if len(accel) == 1 {
+		accel = strings.ToLower(accel)
+	}
+	return strings.Join(append(combo, accel), "+")
+}
+
+// shortcutReport is what x11_shortcuts returns for a window
+type shortcutReport struct {
+	window   x11.WindowDescription
+	app      string     // Name of the database entry, empty if none matched
+	known    []shortcut // From the database
+	exposed  []shortcut // From AT-SPI
+	atspiErr error
+}
+
+// findShortcuts collects the shortcuts of a window's application from
+// AT-SPI and the built-in database
+func findShortcuts(ctx context.Context, c *x11.Client, win x.Window) (shortcutReport, error) {
+	desc, err := c.DescribeWindow(ctx, win, false)
+	if err != nil {
+		return shortcutReport{}, err
+	}
+	report := shortcutReport{window: desc}
+	report.app, report.known, _ = knownShortcuts(desc.Class, desc.Instance)
+	report.exposed, report.atspiErr = atspiShortcuts(ctx, desc.PID)
+	return report, nil
+}
+
+// String lists the shortcuts, those the application exposes first
+func (r shortcutReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Shortcuts for window 0x%x %q (class %s)\n", uint32(r.window.ID), r.window.Title, orNone(r.window.Class))
+
+	writeList := func(title string, shortcuts []shortcut) {
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, s := range shortcuts {
+			fmt.Fprintf(&b, "  %-16s %s\n", s.Keys, s.Description)
+		}
+	}
+	if len(r.exposed) > 0 {
+		writeList("From the application (AT-SPI)", r.exposed)
+	} else if r.atspiErr != nil {
+		fmt.Fprintf(&b, "\nApplication shortcuts unavailable: %v\n", r.atspiErr)
+	} else {
+		b.WriteString("\nThe application exposes no shortcuts over AT-SPI\n")
+	}
+	if len(r.known) > 0 {
+		writeList("Known shortcuts for "+r.app, r.known)
+	}
+	writeList("File dialogs", fileDialogShortcuts)
+	return strings.TrimRight(b.String(), "\n")
+}
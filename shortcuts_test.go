@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAccelerator(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"<Control>l", "ctrl+l"},
+		{"<Primary><Shift>T", "ctrl+shift+t"},
+		{"<Alt>Left", "alt+Left"},
+		{"Ctrl+Shift+T", "ctrl+shift+t"},
+		{"Meta+F2", "super+F2"},
+		{"F5", "F5"},
+		{"+", "+"},
+	}
+	for _, tt := range tests {
+		if got := normalizeAccelerator(tt.in); got != tt.want {
+			t.Errorf("normalizeAccelerator(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKnownShortcuts(t *testing.T) {
+	name, shortcuts, ok := knownShortcuts("Firefox")
+	if !ok || name != "Firefox" || len(shortcuts) == 0 {
+		t.Fatalf("knownShortcuts(Firefox) = %q, %d shortcuts, %v", name, len(shortcuts), ok)
+	}
+	if _, _, ok := knownShortcuts("NoSuchApp"); ok {
+		t.Error("expected no entry for an unknown class")
+	}
+	if name, _, ok := knownShortcuts("NoSuchApp", "Navigator"); !ok || name != "Firefox" {
+		t.Errorf("knownShortcuts(NoSuchApp, Navigator) = %q, %v, want the instance name to find Firefox", name, ok)
+	}
+
+	for _, app := range shortcutDB {
+		for _, class := range app.classes {
+			if class != strings.ToLower(class) {
+				t.Errorf("%s: class %q is not lower case", app.name, class)
+			}
+		}
+	}
+}
+
+func TestShortcutReportString(t *testing.T) {
+	report := shortcutReport{
+		window:   x11.WindowDescription{ID: 0x400001, Title: "Mozilla Firefox", Class: "firefox"},
+		app:      "Firefox",
+		known:    []shortcut{{"ctrl+l", "Focus the address bar"}},
+		atspiErr: errors.New("python3 not found"),
+	}
+	text := report.String()
+	for _, want := range []string{
+		`window 0x400001 "Mozilla Firefox"`,
+		"Application shortcuts unavailable: python3 not found",
+		"Known shortcuts for Firefox:",
+		"ctrl+l",
+		"File dialogs:",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report lacks %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"mcp-x11-controller/x11"
+)
+
+// startProgram starts a --startup-program command line
+func startProgram(c *x11.Client, command string) error {
+	fields, err := x11.SplitCommand(command)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	pid, err := c.StartApp(fields[0], fields[1:])
+	if err != nil {
+		return err
+	}
+	slog.Info("started startup program", "command", command, "pid", pid)
+	return nil
+}
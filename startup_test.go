@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStartProgramInvalid(t *testing.T) {
+	// These fail before a program is started, so no display is needed
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"", "empty command"},
+		{"   ", "empty command"},
+		{"xterm -e 'bash", "quote"},
+	}
+	for _, tt := range tests {
+		err := startProgram(nil, tt.command)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("startProgram(%q) = %v, want an error containing %q", tt.command, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// runtimeStatsURI is the resource with the session's runtime statistics
+const runtimeStatsURI = "stats://runtime"
+
+// runtimeStats counts what the server did since it started
+type runtimeStats struct {
+	mu          sync.Mutex
+	started     time.Time
+	calls       map[string]int // Tool calls by tool
+	failures    int            // Tool calls that returned an error
+	screenshots int            // Images sent in tool results
+	bytesSent   int64          // Size of result content as sent, images base64 encoded
+	captures    int            // Screen captures taken for results
+	captureTime time.Duration  // Total time those captures took
+}
+
+var stats = newRuntimeStats()
+
+// newRuntimeStats starts counting
+func newRuntimeStats() *runtimeStats {
+	return &runtimeStats{started: time.Now(), calls: make(map[string]int)}
+}
+
+// runtimeSnapshot is the JSON of the stats://runtime resource
+type runtimeSnapshot struct {
+	UptimeSeconds float64        `json:"uptime_s"`
+	ToolCalls     map[string]int `json:"tool_calls"`
+	Actions       int            `json:"actions"`
+	FailedCalls   int            `json:"failed_calls"`
+	Screenshots   int            `json:"screenshots"`
+	BytesSent     int64          `json:"bytes_sent"`
+	Captures      int            `json:"captures"`
+	AvgCaptureMS  float64        `json:"avg_capture_ms"`
+	XErrors       uint64         `json:"x_errors"`
+}
+
+// addCall counts a tool call and what its result sent
+func (s *runtimeStats) addCall(tool string, res *mcp.CallToolResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[tool]++
+	if res == nil {
+		s.failures++
+		return
+	}
+	if res.IsError {
+		s.failures++
+	}
+	for _, c := range res.Content {
+		switch c := c.(type) {
+		case *mcp.TextContent:
+			s.bytesSent += int64(len(c.Text))
+		case *mcp.ImageContent:
+			s.screenshots++
+			s.bytesSent += int64(base64.StdEncoding.EncodedLen(len(c.Data)))
+		}
+	}
+}
+
+// addCapture records how long a capture for a result took
+func (s *runtimeStats) addCapture(d time.Duration) {
+	s.mu.Lock()
+	s.captures++
+	s.captureTime += d
+	s.mu.Unlock()
+}
+
+// snapshot returns the statistics so far
+func (s *runtimeStats) snapshot() runtimeSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := runtimeSnapshot{
+		UptimeSeconds: time.Since(s.started).Round(time.Millisecond).Seconds(),
+		ToolCalls:     make(map[string]int, len(s.calls)),
+		FailedCalls:   s.failures,
+		Screenshots:   s.screenshots,
+		BytesSent:     s.bytesSent,
+		Captures:      s.captures,
+	}
+	for tool, n := range s.calls {
+		snap.ToolCalls[tool] = n
+		if slices.Contains(inputTools, tool) {
+			snap.Actions += n
+		}
+	}
+	if s.captures > 0 {
+		snap.AvgCaptureMS = float64((s.captureTime / time.Duration(s.captures)).Microseconds()) / 1000
+	}
+	if client != nil {
+		snap.XErrors = client.XErrors()
+	}
+	return snap
+}
+
+// countToolCalls is receiving middleware that counts tool calls and their
+// results for the stats://runtime resource
+func countToolCalls(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok {
+			return result, err
+		}
+		res, _ := result.(*mcp.CallToolResult)
+		if err != nil {
+			res = nil
+		}
+		stats.addCall(call.Name, res)
+		return result, err
+	}
+}
+
+// readRuntimeStats serves the stats://runtime resource
+func readRuntimeStats(ctx context.Context, session *mcp.ServerSession, params *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	data, err := json.MarshalIndent(stats.snapshot(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode runtime statistics: %w", err)
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: runtimeStatsURI, MIMEType: "application/json", Text: string(data)},
+		},
+	}, nil
+}
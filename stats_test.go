@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestCountToolCalls(t *testing.T) {
+	saved := stats
+	defer func() { stats = saved }()
+	stats = newRuntimeStats()
+
+	var result *mcp.CallToolResult
+	var fail error
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return result, fail
+	}
+	handler := countToolCalls(next)
+	call := func(tool string) {
+		params := &mcp.CallToolParamsFor[json.RawMessage]{Name: tool}
+		handler(context.Background(), nil, "tools/call", params)
+	}
+
+	result = &mcp.CallToolResult{Content: []mcp.Content{
+		&mcp.TextContent{Text: "Clicked"},
+		&mcp.ImageContent{Data: make([]byte, 3), MIMEType: "image/png"},
+	}}
+	call("x11_click_at")
+	call("x11_click_at")
+	result = &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "no"}}}
+	call("x11_list_windows")
+	result, fail = nil, errors.New("bad arguments")
+	call("x11_type_text")
+	stats.addCapture(10 * time.Millisecond)
+	stats.addCapture(20 * time.Millisecond)
+
+	snap := stats.snapshot()
+	if snap.ToolCalls["x11_click_at"] != 2 || snap.ToolCalls["x11_list_windows"] != 1 || snap.ToolCalls["x11_type_text"] != 1 {
+		t.Errorf("tool calls = %v", snap.ToolCalls)
+	}
+	if snap.Actions != 3 {
+		t.Errorf("actions = %d, want 3 (clicks and typing)", snap.Actions)
+	}
+	if snap.FailedCalls != 2 {
+		t.Errorf("failed calls = %d, want 2", snap.FailedCalls)
+	}
+	if snap.Screenshots != 2 {
+		t.Errorf("screenshots = %d, want 2", snap.Screenshots)
+	}
+	// "Clicked" and 4 base64 characters, twice, and "no"
+	if snap.BytesSent != 2*(7+4)+2 {
+		t.Errorf("bytes sent = %d, want %d", snap.BytesSent, 2*(7+4)+2)
+	}
+	if snap.Captures != 2 || snap.AvgCaptureMS != 15 {
+		t.Errorf("captures = %d averaging %vms, want 2 averaging 15ms", snap.Captures, snap.AvgCaptureMS)
+	}
+}
+
+func TestReadRuntimeStats(t *testing.T) {
+	res, err := readRuntimeStats(context.Background(), nil, &mcp.ReadResourceParams{URI: runtimeStatsURI})
+	if err != nil {
+		t.Fatalf("readRuntimeStats failed: %v", err)
+	}
+	if len(res.Contents) != 1 || res.Contents[0].URI != runtimeStatsURI || res.Contents[0].MIMEType != "application/json" {
+		t.Fatalf("unexpected contents %+v", res.Contents)
+	}
+	var snap runtimeSnapshot
+	if err := json.Unmarshal([]byte(res.Contents[0].Text), &snap); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if snap.ToolCalls == nil {
+		t.Error("tool_calls missing")
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// indexMargin is how far above and below a damaged area text is
+	// recognized again, so lines the damage touches are read whole
+	indexMargin = 8
+	// indexFullShare is the share of the screen height that may be damaged
+	// before the whole screen is recognized again instead of bands of it
+	indexFullShare = 0.5
+	// maxDirtyRects bounds the damaged areas kept between queries; beyond
+	// it they are merged into their bounding box
+	maxDirtyRects = 64
+)
+
+// Ways a query brought the text index up to date
+const (
+	refreshCached  = "cached"
+	refreshPartial = "partial"
+	refreshFull    = "full"
+)
+
+// textIndex is the text on the screen recognized by OCR, kept current for
+// x11_find_text by recognizing again only the full-width bands of the
+// screen that DAMAGE reported as changed. Without DAMAGE every query
+// recognizes the whole screen.
+type textIndex struct {
+	mu       sync.Mutex // Held while refreshing, so queries don't recognize the same change twice
+	words    []ocrWord
+	screen   image.Rectangle // Zero until the screen was recognized
+	watching bool
+	tracked  bool // Damage is reported
+	runs     int  // OCR runs, to keep the line keys of different runs apart
+
+	// Screen capture, its release, OCR and damage reports; replaced in tests.
+	// capture gets the whole screen for an empty rect.
+	capture   func(ctx context.Context, rect image.Rectangle) (image.Image, error)
+	release   func(img image.Image)
+	recognize func(ctx context.Context, img image.Image) ([]ocrWord, error)
+	watch     func(fn func(image.Rectangle)) error
+
+	dmu   sync.Mutex
+	dirty []image.Rectangle // Damaged since the last refresh
+}
+
+// indexRefresh is what a query had to recognize again
+type indexRefresh struct {
+	Mode  string // refreshCached, refreshPartial or refreshFull
+	Bands int    // Bands recognized again by a partial refresh
+	Took  time.Duration
+}
+
+var screenText = &textIndex{
+	capture: func(ctx context.Context, rect image.Rectangle) (image.Image, error) {
+		if rect.Empty() {
+			return client.ScreenshotContext(ctx)
+		}
+		return client.ScreenshotRegionContext(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	},
+	release:   func(img image.Image) { client.RecycleScreenshot(img) },
+	recognize: screenWords,
+	watch:     func(fn func(image.Rectangle)) error { return client.WatchDamage(fn) },
+}
+
+// damaged records a changed area of the screen
+func (t *textIndex) damaged(r image.Rectangle) {
+	t.dmu.Lock()
+	defer t.dmu.Unlock()
+	t.dirty = append(t.dirty, r)
+	if len(t.dirty) > maxDirtyRects {
+		union := t.dirty[0]
+		for _, d := range t.dirty[1:] {
+			union = union.Union(d)
+		}
+		t.dirty = []image.Rectangle{union}
+	}
+}
+
+// takeDirty returns and forgets the damaged areas
+func (t *textIndex) takeDirty() []image.Rectangle {
+	t.dmu.Lock()
+	defer t.dmu.Unlock()
+	dirty := t.dirty
+	t.dirty = nil
+	return dirty
+}
+
+// find brings the index up to date and returns the areas reading phrase,
+// within area unless it is empty, top to bottom
+func (t *textIndex) find(ctx context.Context, phrase string, area image.Rectangle) ([]image.Rectangle, indexRefresh, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	refresh, err := t.refresh(ctx)
+	if err != nil {
+		return nil, refresh, err
+	}
+	words := t.words
+	if !area.Empty() {
+		words = wordsIn(words, area)
+	}
+	found := findPhrases(words, phrase)
+	slices.SortFunc(found, func(a, b image.Rectangle) int {
+		if a.Min.Y != b.Min.Y {
+			return a.Min.Y - b.Min.Y
+		}
+		return a.Min.X - b.Min.X
+	})
+	return found, refresh, nil
+}
+
+// refresh recognizes the text of the bands damaged since the last refresh,
+// or of the whole screen the first time, without damage reports or when
+// much of it changed. Damage reported while it runs is left for the next.
+func (t *textIndex) refresh(ctx context.Context) (indexRefresh, error) {
+	start := time.Now()
+	if !t.watching {
+		t.watching = true
+		t.tracked = t.watch(t.damaged) == nil
+	}
+
+	dirty := t.takeDirty()
+	if t.screen.Empty() || !t.tracked {
+		err := t.recognizeAll(ctx)
+		return indexRefresh{Mode: refreshFull, Took: time.Since(start)}, err
+	}
+	bands := dirtyBands(dirty, t.words, t.screen)
+	if len(bands) == 0 {
+		return indexRefresh{Mode: refreshCached, Took: time.Since(start)}, nil
+	}
+	height := 0
+	for _, band := range bands {
+		height += band.Dy()
+	}
+	if float64(height) > indexFullShare*float64(t.screen.Dy()) {
+		err := t.recognizeAll(ctx)
+		return indexRefresh{Mode: refreshFull, Took: time.Since(start)}, err
+	}
+
+	for i, band := range bands {
+		words, err := t.recognizeArea(ctx, band)
+		if err != nil {
+			// Recognize the bands left over next time
+			for _, left := range bands[i:] {
+				t.damaged(left)
+			}
+			return indexRefresh{}, err
+		}
+		t.words = replaceBand(t.words, band, words)
+	}
+	return indexRefresh{Mode: refreshPartial, Bands: len(bands), Took: time.Since(start)}, nil
+}
+
+// recognizeAll replaces the index with the text of the whole screen. If it
+// fails, the next refresh recognizes the whole screen again.
+func (t *textIndex) recognizeAll(ctx context.Context) error {
+	t.screen = image.Rectangle{}
+	img, err := t.capture(ctx, image.Rectangle{})
+	if err != nil {
+		return err
+	}
+	defer t.release(img)
+	words, err := t.recognize(ctx, img)
+	if err != nil {
+		return err
+	}
+	t.screen = img.Bounds()
+	t.words = t.keyLines(words)
+	return nil
+}
+
+// recognizeArea returns the text of an area of the screen
+func (t *textIndex) recognizeArea(ctx context.Context, area image.Rectangle) ([]ocrWord, error) {
+	img, err := t.capture(ctx, area)
+	if err != nil {
+		return nil, err
+	}
+	defer t.release(img)
+	words, err := t.recognize(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	return t.keyLines(words), nil
+}
+
+// keyLines prefixes the line keys of one OCR run's words with the run
+func (t *textIndex) keyLines(words []ocrWord) []ocrWord {
+	t.runs++
+	for i := range words {
+		words[i].Line = fmt.Sprintf("%d/%s", t.runs, words[i].Line)
+	}
+	return words
+}
+
+// dirtyBands turns damaged areas into the full-width bands of the screen to
+// recognize again: each area grown by indexMargin and then to the words it
+// cuts, with overlapping bands merged
+func dirtyBands(dirty []image.Rectangle, words []ocrWord, screen image.Rectangle) []image.Rectangle {
+	var bands []image.Rectangle
+	for _, d := range dirty {
+		d = d.Intersect(screen)
+		if d.Empty() {
+			continue
+		}
+		lo, hi := max(d.Min.Y-indexMargin, screen.Min.Y), min(d.Max.Y+indexMargin, screen.Max.Y)
+		for _, w := range words {
+			if w.Rect.Min.Y < hi && w.Rect.Max.Y > lo {
+				lo, hi = min(lo, w.Rect.Min.Y), max(hi, w.Rect.Max.Y)
+			}
+		}
+		bands = append(bands, image.Rect(screen.Min.X, lo, screen.Max.X, hi))
+	}
+	slices.SortFunc(bands, func(a, b image.Rectangle) int { return a.Min.Y - b.Min.Y })
+
+	var merged []image.Rectangle
+	for _, band := range bands {
+		if n := len(merged); n > 0 && band.Min.Y <= merged[n-1].Max.Y {
+			merged[n-1] = merged[n-1].Union(band)
+			continue
+		}
+		merged = append(merged, band)
+	}
+	return merged
+}
+
+// replaceBand drops the words overlapping band and adds those recognized in it
+func replaceBand(words []ocrWord, band image.Rectangle, fresh []ocrWord) []ocrWord {
+	kept := slices.DeleteFunc(slices.Clone(words), func(w ocrWord) bool { return w.Rect.Overlaps(band) })
+	return append(kept, fresh...)
+}
+
+// findTextText lists the matches of x11_find_text and how current the
+// index was
+func findTextText(phrase string, matches []image.Rectangle, refresh indexRefresh) string {
+	var b strings.Builder
+	if len(matches) == 0 {
+		fmt.Fprintf(&b, "Text %q not found on the screen", phrase)
+	} else {
+		fmt.Fprintf(&b, "Found %q %d time(s), top to bottom:", phrase, len(matches))
+		for i, m := range matches {
+			fmt.Fprintf(&b, "\n%d. center %d,%d (area %dx%d+%d+%d)",
+				i+1, (m.Min.X+m.Max.X)/2, (m.Min.Y+m.Max.Y)/2, m.Dx(), m.Dy(), m.Min.X, m.Min.Y)
+		}
+	}
+	took := refresh.Took.Round(time.Millisecond)
+	switch refresh.Mode {
+	case refreshCached:
+		fmt.Fprintf(&b, "\n(screen unchanged since the last query, answered from the index in %s)", took)
+	case refreshPartial:
+		fmt.Fprintf(&b, "\n(recognized %d changed band(s) of the screen again in %s)", refresh.Bands, took)
+	default:
+		fmt.Fprintf(&b, "\n(recognized the whole screen in %s)", took)
+	}
+	return b.String()
+}
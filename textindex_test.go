@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeScreen is the text on a simulated screen for textIndex tests
+type fakeScreen struct {
+	words []ocrWord
+	areas []image.Rectangle // Areas recognized, in order
+}
+
+// index returns a textIndex over the fake screen, with damage reports if
+// tracked
+func (s *fakeScreen) index(tracked bool) *textIndex {
+	screen := image.Rect(0, 0, 800, 600)
+	return &textIndex{
+		capture: func(ctx context.Context, rect image.Rectangle) (image.Image, error) {
+			if rect.Empty() {
+				rect = screen
+			}
+			return image.NewRGBA(rect), nil
+		},
+		release: func(image.Image) {},
+		recognize: func(ctx context.Context, img image.Image) ([]ocrWord, error) {
+			s.areas = append(s.areas, img.Bounds())
+			return wordsIn(s.words, img.Bounds()), nil
+		},
+		watch: func(fn func(image.Rectangle)) error {
+			if !tracked {
+				return errors.New("no DAMAGE")
+			}
+			return nil
+		},
+	}
+}
+
+func word(text string, x, y int) ocrWord {
+	return ocrWord{Text: text, Rect: image.Rect(x, y, x+10*len(text), y+16), Line: "1.1.1"}
+}
+
+func TestTextIndex(t *testing.T) {
+	ctx := context.Background()
+	screen := &fakeScreen{words: []ocrWord{word("Save", 20, 100), word("changes?", 70, 100), word("Cancel", 20, 400)}}
+	idx := screen.index(true)
+
+	found, refresh, err := idx.find(ctx, "save changes", image.Rectangle{})
+	if err != nil || refresh.Mode != refreshFull || len(found) != 1 || found[0] != image.Rect(20, 100, 150, 116) {
+		t.Fatalf("first find = %v, %+v, %v, want one match after a full recognition", found, refresh, err)
+	}
+
+	// Nothing damaged: answered without recognizing anything
+	found, refresh, err = idx.find(ctx, "Cancel", image.Rectangle{})
+	if err != nil || refresh.Mode != refreshCached || len(found) != 1 || len(screen.areas) != 1 {
+		t.Errorf("unchanged find = %v, %+v, %v after %d recognitions", found, refresh, err, len(screen.areas))
+	}
+
+	// The dialog text changes: only its band is recognized again
+	screen.words[0], screen.words[1] = word("Saved", 20, 100), word("OK", 80, 100)
+	idx.damaged(image.Rect(20, 100, 60, 110))
+	found, refresh, err = idx.find(ctx, "saved", image.Rectangle{})
+	if err != nil || refresh.Mode != refreshPartial || refresh.Bands != 1 || len(found) != 1 {
+		t.Fatalf("find after damage = %v, %+v, %v, want one match from a partial refresh", found, refresh, err)
+	}
+	if band := screen.areas[len(screen.areas)-1]; band != image.Rect(0, 92, 800, 118) {
+		t.Errorf("recognized band %v, want the damaged lines across the screen", band)
+	}
+	if found, _, _ := idx.find(ctx, "save changes", image.Rectangle{}); len(found) != 0 {
+		t.Errorf("replaced text still found at %v", found)
+	}
+	if found, _, _ := idx.find(ctx, "cancel", image.Rect(0, 0, 800, 300)); len(found) != 0 {
+		t.Errorf("text outside the area found at %v", found)
+	}
+
+	// Damage to most of the screen recognizes all of it
+	idx.damaged(image.Rect(0, 0, 800, 500))
+	if _, refresh, _ := idx.find(ctx, "OK", image.Rectangle{}); refresh.Mode != refreshFull {
+		t.Errorf("refresh after large damage = %+v, want full", refresh)
+	}
+}
+
+func TestTextIndexUntracked(t *testing.T) {
+	screen := &fakeScreen{words: []ocrWord{word("OK", 20, 100)}}
+	idx := screen.index(false)
+	for i := 0; i < 2; i++ {
+		if _, refresh, err := idx.find(context.Background(), "OK", image.Rectangle{}); err != nil || refresh.Mode != refreshFull {
+			t.Errorf("find %d without damage reports = %+v, %v, want a full recognition", i, refresh, err)
+		}
+	}
+}
+
+func TestDirtyBands(t *testing.T) {
+	screen := image.Rect(0, 0, 800, 600)
+	words := []ocrWord{word("Tall", 10, 200)} // Lines 200 to 216
+	tests := []struct {
+		name  string
+		dirty []image.Rectangle
+		want  []image.Rectangle
+	}{
+		{"none", nil, nil},
+		{"margin", []image.Rectangle{image.Rect(100, 50, 120, 60)}, []image.Rectangle{image.Rect(0, 42, 800, 68)}},
+		{"clipped", []image.Rectangle{image.Rect(0, -20, 50, 4), image.Rect(0, 900, 10, 910)}, []image.Rectangle{image.Rect(0, 0, 800, 12)}},
+		{"grown to cut words", []image.Rectangle{image.Rect(0, 218, 10, 220)}, []image.Rectangle{image.Rect(0, 200, 800, 228)}},
+		{"merged", []image.Rectangle{image.Rect(0, 300, 10, 310), image.Rect(0, 50, 10, 60), image.Rect(0, 315, 10, 320)},
+			[]image.Rectangle{image.Rect(0, 42, 800, 68), image.Rect(0, 292, 800, 328)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dirtyBands(tt.dirty, words, screen)
+			if len(got) != len(tt.want) {
+				t.Fatalf("dirtyBands() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("dirtyBands() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDamagedBounded(t *testing.T) {
+	var idx textIndex
+	for i := 0; i <= maxDirtyRects; i++ {
+		idx.damaged(image.Rect(0, i, 10, i+1))
+	}
+	if dirty := idx.takeDirty(); len(dirty) != 1 || dirty[0] != image.Rect(0, 0, 10, maxDirtyRects+1) {
+		t.Errorf("dirty = %v, want their bounding box", dirty)
+	}
+	if dirty := idx.takeDirty(); dirty != nil {
+		t.Errorf("dirty after taking = %v", dirty)
+	}
+}
+
+func TestFindTextText(t *testing.T) {
+	matches := []image.Rectangle{image.Rect(20, 100, 60, 116)}
+	got := findTextText("Save", matches, indexRefresh{Mode: refreshCached, Took: 2 * time.Millisecond})
+	want := "Found \"Save\" 1 time(s), top to bottom:\n1. center 40,108 (area 40x16+20+100)\n" +
+		"(screen unchanged since the last query, answered from the index in 2ms)"
+	if got != want {
+		t.Errorf("findTextText() = %q, want %q", got, want)
+	}
+	got = findTextText("Quit", nil, indexRefresh{Mode: refreshPartial, Bands: 2, Took: 300 * time.Millisecond})
+	if !strings.HasPrefix(got, `Text "Quit" not found`) || !strings.Contains(got, "2 changed band(s)") {
+		t.Errorf("findTextText() = %q", got)
+	}
+}
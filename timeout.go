@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// timeoutArg is the argument every tool accepts to bound its call
+const timeoutArg = "timeout_ms"
+
+// toolTimeoutCap bounds every tool call, with or without timeout_ms;
+// 0 leaves calls without timeout_ms unbounded (--max-tool-timeout)
+var toolTimeoutCap time.Duration
+
+// timeoutSchema describes timeoutArg in the tools' input schemas
+var timeoutSchema = &jsonschema.Schema{
+	Type:        "integer",
+	Description: "Give up on the call after this many milliseconds and return a timeout error with a screenshot (capped by the server's --max-tool-timeout)",
+	Minimum:     jsonschema.Ptr(1.0),
+}
+
+// toolTimeout returns the time a call may take: the requested one, capped
+func toolTimeout(requested, limit time.Duration) time.Duration {
+	if requested <= 0 || (limit > 0 && requested > limit) {
+		return limit
+	}
+	return requested
+}
+
+// takeTimeoutArg removes timeout_ms from a call's arguments, which the
+// tools' own schemas don't allow, and returns its value
+func takeTimeoutArg(args json.RawMessage) (json.RawMessage, time.Duration, error) {
+	var fields map[string]json.RawMessage
+	if len(args) == 0 || json.Unmarshal(args, &fields) != nil {
+		return args, 0, nil
+	}
+	raw, ok := fields[timeoutArg]
+	if !ok {
+		return args, 0, nil
+	}
+	var ms int64
+	if err := json.Unmarshal(raw, &ms); err != nil || ms <= 0 {
+		return nil, 0, fmt.Errorf("%s must be a positive number of milliseconds, got %s", timeoutArg, raw)
+	}
+	delete(fields, timeoutArg)
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return nil, 0, err
+	}
+	return stripped, time.Duration(ms) * time.Millisecond, nil
+}
+
+// withTimeoutArg returns a copy of the tool with timeout_ms in its schema
+func withTimeoutArg(tool *mcp.Tool) *mcp.Tool {
+	if tool.InputSchema == nil {
+		return tool
+	}
+	t := *tool
+	schema := *tool.InputSchema
+	schema.Properties = maps.Clone(schema.Properties)
+	if schema.Properties == nil {
+		schema.Properties = map[string]*jsonschema.Schema{}
+	}
+	schema.Properties[timeoutArg] = timeoutSchema
+	t.InputSchema = &schema
+	return &t
+}
+
+// timeoutResult is the error result of a call that ran out of time
+func timeoutResult(tool string, timeout time.Duration) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("%s timed out after %s", tool, timeout)},
+		},
+		IsError: true,
+		Meta: mcp.Meta{
+			"timeout": map[string]any{"tool": tool, "timeout_ms": timeout.Milliseconds()},
+		},
+	}
+}
+
+// enforceTimeouts is receiving middleware that adds timeout_ms to every
+// tool and bounds each call by it and --max-tool-timeout. A call still
+// running when its time is up is answered with a timeout error right away,
+// even if it's stuck somewhere that ignores its context; reportFailures
+// then attaches the final screenshot.
+func enforceTimeouts(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		if method == "tools/list" {
+			result, err := next(ctx, session, method, params)
+			if res, ok := result.(*mcp.ListToolsResult); ok && err == nil {
+				for i, tool := range res.Tools {
+					res.Tools[i] = withTimeoutArg(tool)
+				}
+			}
+			return result, err
+		}
+
+		call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok {
+			return next(ctx, session, method, params)
+		}
+		args, requested, err := takeTimeoutArg(call.Arguments)
+		if err != nil {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}, IsError: true}, nil
+		}
+		call.Arguments = args
+		timeout := toolTimeout(requested, toolTimeoutCap)
+		if timeout <= 0 {
+			return next(ctx, session, method, params)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		type outcome struct {
+			result mcp.Result
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			result, err := next(ctx, session, method, params)
+			done <- outcome{result, err}
+		}()
+
+		select {
+		case out := <-done:
+			// A tool that gave up on its context failed because of the timeout
+			res, isResult := out.result.(*mcp.CallToolResult)
+			failed := out.err != nil || (isResult && res.IsError)
+			if failed && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return timeoutResult(call.Name, timeout), nil
+			}
+			return out.result, out.err
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				slog.Warn("tool call timed out", "tool", call.Name, "timeout", timeout)
+				return timeoutResult(call.Name, timeout), nil
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
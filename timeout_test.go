@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolTimeout(t *testing.T) {
+	tests := []struct {
+		requested, limit, want time.Duration
+	}{
+		{0, time.Minute, time.Minute},
+		{time.Second, time.Minute, time.Second},
+		{time.Hour, time.Minute, time.Minute},
+		{time.Second, 0, time.Second},
+		{0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := toolTimeout(tt.requested, tt.limit); got != tt.want {
+			t.Errorf("toolTimeout(%v, %v) = %v, want %v", tt.requested, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestTakeTimeoutArg(t *testing.T) {
+	args, timeout, err := takeTimeoutArg(json.RawMessage(`{"x": 10, "timeout_ms": 250}`))
+	if err != nil {
+		t.Fatalf("takeTimeoutArg failed: %v", err)
+	}
+	if timeout != 250*time.Millisecond || string(args) != `{"x":10}` {
+		t.Errorf("takeTimeoutArg = %s, %v, want the arguments without timeout_ms and 250ms", args, timeout)
+	}
+
+	args, timeout, err = takeTimeoutArg(json.RawMessage(`{"x": 10}`))
+	if err != nil || timeout != 0 || string(args) != `{"x": 10}` {
+		t.Errorf("takeTimeoutArg without timeout_ms = %s, %v, %v, want the arguments unchanged", args, timeout, err)
+	}
+
+	for _, bad := range []string{`{"timeout_ms": 0}`, `{"timeout_ms": "soon"}`} {
+		if _, _, err := takeTimeoutArg(json.RawMessage(bad)); err == nil {
+			t.Errorf("takeTimeoutArg(%s): expected error", bad)
+		}
+	}
+}
+
+func TestWithTimeoutArg(t *testing.T) {
+	tool := &mcp.Tool{Name: "x11_click_at", InputSchema: &jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{"x": {Type: "number"}},
+	}}
+	got := withTimeoutArg(tool)
+	if got.InputSchema.Properties[timeoutArg] == nil || got.InputSchema.Properties["x"] == nil {
+		t.Errorf("schema properties = %v, want x and %s", got.InputSchema.Properties, timeoutArg)
+	}
+	if _, ok := tool.InputSchema.Properties[timeoutArg]; ok {
+		t.Error("withTimeoutArg modified the registered tool's schema")
+	}
+}
+
+func TestEnforceTimeouts(t *testing.T) {
+	saved := toolTimeoutCap
+	defer func() { toolTimeoutCap = saved }()
+	toolTimeoutCap = time.Minute
+
+	release := make(chan struct{})
+	defer close(release)
+	var mu sync.Mutex
+	var gotArgs string
+	handler := enforceTimeouts(func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		call := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		mu.Lock()
+		gotArgs = string(call.Arguments)
+		mu.Unlock()
+		if call.Name == "stuck" {
+			<-release // Ignores its context, like a wedged X request
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil
+	})
+	call := func(name, args string) *mcp.CallToolResult {
+		t.Helper()
+		params := &mcp.CallToolParamsFor[json.RawMessage]{Name: name, Arguments: json.RawMessage(args)}
+		result, err := handler(context.Background(), nil, "tools/call", params)
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		return result.(*mcp.CallToolResult)
+	}
+
+	start := time.Now()
+	res := call("stuck", `{"timeout_ms": 50}`)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("stuck call returned after %v, want about 50ms", elapsed)
+	}
+	meta, _ := res.Meta["timeout"].(map[string]any)
+	if !res.IsError || meta["tool"] != "stuck" || meta["timeout_ms"] != int64(50) {
+		t.Errorf("stuck call result = %+v, want a timeout error", res)
+	}
+
+	res = call("quick", `{"x": 1, "timeout_ms": 1000}`)
+	mu.Lock()
+	args := gotArgs
+	mu.Unlock()
+	if res.IsError || args != `{"x":1}` {
+		t.Errorf("quick call: error %v with arguments %s, want success without timeout_ms", res.IsError, args)
+	}
+
+	if res = call("quick", `{"timeout_ms": -5}`); !res.IsError {
+		t.Error("expected an error result for a negative timeout_ms")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"mcp-x11-controller/x11"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// toolTimer records how long each phase of a tool call takes
+type toolTimer struct {
+	start  time.Time
+	last   time.Time
+	phases map[string]float64
+	shot   *historyEntry // Screenshot recorded in the history during the call
+	before *uint64       // Content hash of the screen before the action, if taken
+	noop   bool          // The result screenshot is identical to the one before
+}
+
+// noopWarning is added to results of actions after which the screen didn't
+// change at all
+const noopWarning = "The screen is exactly the same as before this action, so it probably had no effect. Check the coordinates, the focused window or the keys before repeating it"
+
+// newToolTimer starts timing a tool call
+func newToolTimer() *toolTimer {
+	now := time.Now()
+	return &toolTimer{start: now, last: now, phases: make(map[string]float64)}
+}
+
+// mark adds the time since the previous mark to the named phase
+func (t *toolTimer) mark(phase string) {
+	now := time.Now()
+	t.phases[phase] += float64(now.Sub(t.last).Microseconds()) / 1000
+	t.last = now
+}
+
+// meta adds the timings, and the history entry of the call's screenshot, to
+// a result's Meta map, creating it if needed
+func (t *toolTimer) meta(m map[string]any) map[string]any {
+	if m == nil {
+		m = make(map[string]any)
+	}
+	timing := make(map[string]float64, len(t.phases)+1)
+	for phase, ms := range t.phases {
+		timing[phase] = ms
+	}
+	timing["total_ms"] = float64(time.Since(t.start).Microseconds()) / 1000
+	m["timing"] = timing
+	if t.noop {
+		m["warning"] = noopWarning
+	}
+	if t.shot != nil {
+		if shot := screenshotMeta(*t.shot); len(shot) > 0 {
+			m["screenshot"] = shot
+		}
+	}
+	return m
+}
+
+// captureBefore records the content hash of the screen, or of the focused
+// window if focusedOnly is set, before an action that should change what is
+// shown, so the result screenshot can tell whether it did. Failures only
+// skip the check.
+func (t *toolTimer) captureBefore(ctx context.Context, focusedOnly bool) {
+	var img image.Image
+	var err error
+	if focusedOnly {
+		img, _, err = client.ScreenshotFocusedWindowContext(ctx)
+	}
+	if img == nil {
+		img, err = client.ScreenshotContext(ctx)
+	}
+	if err != nil {
+		slog.Debug("failed to capture the screen before the action", "err", err)
+		return
+	}
+	hash := x11.ContentHash(img)
+	client.RecycleScreenshot(img)
+	t.before = &hash
+	t.mark("capture_before_ms")
+}
+
+// withWarning appends the no-op warning to a result text if the action
+// didn't change the screen
+func (t *toolTimer) withWarning(text string) string {
+	if t.noop {
+		return text + "\nWarning: " + noopWarning
+	}
+	return text
+}
+
+// screenshotMeta describes a screenshot for result metadata: its history
+// index, if kept, the crop when only a window was captured, and its
+// perceptual hash with the distance to the previous one, which is 0 when
+// nothing visible changed
+func screenshotMeta(entry historyEntry) map[string]any {
+	meta := map[string]any{"phash": fmt.Sprintf("%016x", entry.Hash)}
+	if entry.Distance >= 0 {
+		meta["phash_distance"] = entry.Distance
+	}
+	if entry.Index > 0 {
+		meta["index"] = entry.Index
+		meta["time"] = entry.Time.Format(time.RFC3339Nano)
+	}
+	if entry.Window != 0 {
+		meta["window_id"] = entry.Window
+		meta["x"] = entry.Origin.X
+		meta["y"] = entry.Origin.Y
+	}
+	return meta
+}
+
+// screenshotPNG captures and encodes the screen, or just the focused
+// window if focusedOnly is set, timing both steps separately, and records
+// the result in the screenshot history. If the focused window can't be
+// captured it falls back to the whole screen.
+func screenshotPNG(ctx context.Context, timer *toolTimer, focusedOnly bool) ([]byte, error) {
+	var entry historyEntry
+	var img image.Image
+	var err error
+	start := time.Now()
+	if focusedOnly {
+		var win x.Window
+		img, win, err = client.ScreenshotFocusedWindowContext(ctx)
+		if err == nil {
+			entry.Window = uint32(win)
+			entry.Origin = img.Bounds().Min
+		} else if ctx.Err() == nil {
+			slog.Warn("focused window capture failed, capturing the whole screen", "err", err)
+		}
+	}
+	if img == nil {
+		img, err = client.ScreenshotContext(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take screenshot: %w", err)
+	}
+	stats.addCapture(time.Since(start))
+	timer.mark("capture_ms")
+	defer client.RecycleScreenshot(img)
+	return encodeShot(timer, img, entry)
+}
+
+// encodeShot encodes a capture for a tool result and records it in the
+// screenshot history with entry's window and origin. The image is hashed
+// while the encode workers compress it, so encode_ms is only the part of
+// encoding that hashing didn't cover.
+func encodeShot(timer *toolTimer, img image.Image, entry historyEntry) ([]byte, error) {
+	var err error
+	// The hashes see the screen itself, so a trail alone is no change
+	shown := img
+	if pointerTrail {
+		shown = client.WithPointerTrail(img)
+	}
+	pending := client.EncodePNGAsync(shown)
+	entry.Hash = x11.PerceptualHash(img)
+	if timer.before != nil {
+		timer.noop = x11.ContentHash(img) == *timer.before
+	}
+	timer.mark("hash_ms")
+	entry.PNG, err = pending.Wait()
+	if err != nil {
+		return nil, err
+	}
+	timer.mark("encode_ms")
+
+	entry = history.add(entry)
+	timer.shot = &entry
+	return entry.PNG, nil
+}
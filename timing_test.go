@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToolTimer(t *testing.T) {
+	timer := newToolTimer()
+	time.Sleep(5 * time.Millisecond)
+	timer.mark("input_ms")
+	timer.mark("wait_ms")
+	timer.mark("wait_ms")
+
+	meta := timer.meta(map[string]any{"pid": 42})
+	if meta["pid"] != 42 {
+		t.Error("existing meta entries must be kept")
+	}
+	timing, ok := meta["timing"].(map[string]float64)
+	if !ok {
+		t.Fatalf("expected timing map, got %T", meta["timing"])
+	}
+	if timing["input_ms"] < 5 {
+		t.Errorf("input_ms = %v, want at least 5", timing["input_ms"])
+	}
+	if timing["total_ms"] < timing["input_ms"]+timing["wait_ms"] {
+		t.Errorf("total_ms %v is smaller than the sum of its phases", timing["total_ms"])
+	}
+	if _, ok := timer.meta(nil)["timing"]; !ok {
+		t.Error("meta(nil) must create a map with timing")
+	}
+}
+
+func TestToolTimerScreenshotMeta(t *testing.T) {
+	timer := newToolTimer()
+	if _, ok := timer.meta(nil)["screenshot"]; ok {
+		t.Error("no screenshot meta expected before a screenshot was recorded")
+	}
+
+	entry := newScreenshotHistory(5, 0).add(historyEntry{PNG: []byte("png")})
+	timer.shot = &entry
+	shot, ok := timer.meta(nil)["screenshot"].(map[string]any)
+	if !ok || shot["index"] != entry.Index {
+		t.Errorf("screenshot meta = %v, want index %d", shot, entry.Index)
+	}
+}
+
+func TestScreenshotMetaCrop(t *testing.T) {
+	meta := screenshotMeta(historyEntry{Window: 0x400001, Origin: image.Pt(10, 20)})
+	if meta["window_id"] != uint32(0x400001) || meta["x"] != 10 || meta["y"] != 20 {
+		t.Errorf("crop meta = %v", meta)
+	}
+	if _, ok := meta["index"]; ok {
+		t.Error("index must be omitted for screenshots that are not in the history")
+	}
+	if meta := screenshotMeta(historyEntry{Hash: 0xff, Distance: -1}); len(meta) != 1 || meta["phash"] != "00000000000000ff" {
+		t.Errorf("a full-screen screenshot outside the history only has its hash, got %v", meta)
+	}
+}
+
+func TestScreenshotMetaHash(t *testing.T) {
+	meta := screenshotMeta(historyEntry{Hash: 0x0123456789abcdef, Distance: 3})
+	if meta["phash"] != "0123456789abcdef" || meta["phash_distance"] != 3 {
+		t.Errorf("hash meta = %v", meta)
+	}
+}
+
+func TestNoopWarning(t *testing.T) {
+	timer := newToolTimer()
+	if got := timer.withWarning("Typed: x"); got != "Typed: x" {
+		t.Errorf("withWarning() without no-op = %q", got)
+	}
+	if _, ok := timer.meta(nil)["warning"]; ok {
+		t.Error("warning set although the screen changed")
+	}
+
+	timer.noop = true
+	if got := timer.withWarning("Typed: x"); !strings.HasPrefix(got, "Typed: x\nWarning: ") {
+		t.Errorf("withWarning() after no-op = %q", got)
+	}
+	if timer.meta(nil)["warning"] != noopWarning {
+		t.Error("warning missing from meta after a no-op")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"mcp-x11-controller/x11"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// transcriptURI is the resource with the rolling session transcript
+const transcriptURI = "session://transcript"
+
+const (
+	// maxTranscriptLines is how many actions and window changes the
+	// transcript keeps
+	maxTranscriptLines = 100
+	// maxTranscriptValue bounds each argument and result shown
+	maxTranscriptValue = 80
+)
+
+// sessionTranscript keeps a line per recent tool call and window change,
+// so a fresh turn or a second agent can catch up without replaying images
+type sessionTranscript struct {
+	mu      sync.Mutex
+	started time.Time
+	lines   []string
+	last    string // Last line without its time
+	dropped int    // Lines dropped beyond maxTranscriptLines
+}
+
+var transcript = newSessionTranscript()
+
+// newSessionTranscript starts an empty transcript
+func newSessionTranscript() *sessionTranscript {
+	return &sessionTranscript{started: time.Now()}
+}
+
+// add appends a line, skipping a repeat of the last one such as a
+// screenshot taken again of an unchanged screen
+func (t *sessionTranscript) add(at time.Time, line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if line == t.last {
+		return
+	}
+	t.last = line
+	t.lines = append(t.lines, at.Format("15:04:05")+" "+line)
+	if len(t.lines) > maxTranscriptLines {
+		t.dropped += len(t.lines) - maxTranscriptLines
+		t.lines = slices.Clone(t.lines[len(t.lines)-maxTranscriptLines:])
+	}
+}
+
+// contentTools are the tools whose results carry text read from the
+// screen, a selection or a terminal; the transcript only gives its size
+var contentTools = map[string]bool{
+	"x11_get_clipboard":     true,
+	"x11_clipboard_history": true,
+	"x11_read_terminal":     true,
+	"x11_select_text":       true,
+}
+
+// addCall records a tool call with its arguments and the first line of
+// its result, or its size for contentTools
+func (t *sessionTranscript) addCall(tool string, args json.RawMessage, res *mcp.CallToolResult, err error) {
+	line := tool
+	if summary := transcriptArgs(args); summary != "" {
+		line += " " + summary
+	}
+	switch {
+	case err != nil:
+		line += " -> failed: " + clip(err.Error())
+	case res == nil:
+	case res.IsError:
+		line += " -> failed: " + clip(firstText(res.Content))
+	case contentTools[tool]:
+		line += fmt.Sprintf(" -> [%d bytes]", textBytes(res.Content))
+	default:
+		if text := firstText(res.Content); text != "" {
+			line += " -> " + clip(text)
+		}
+	}
+	t.add(time.Now(), line)
+}
+
+// addWindowEvent records a window being mapped, unmapped, destroyed or focused
+func (t *sessionTranscript) addWindowEvent(ev x11.WindowEvent) {
+	line := fmt.Sprintf("[window %s 0x%x", ev.Kind, uint32(ev.Window))
+	if ev.Title != "" {
+		line += fmt.Sprintf(" %q", clip(ev.Title))
+	}
+	t.add(ev.Time, line+"]")
+}
+
+// String returns the transcript as text, oldest first
+func (t *sessionTranscript) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session started %s (%s ago)", t.started.Format("15:04:05"), time.Since(t.started).Round(time.Second))
+	if len(t.lines) == 0 {
+		b.WriteString("; nothing happened yet\n")
+		return b.String()
+	}
+	if t.dropped > 0 {
+		fmt.Fprintf(&b, "; %d older entries dropped", t.dropped)
+	}
+	b.WriteString(". Tool calls with their result; window changes in brackets:\n")
+	for _, line := range t.lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// transcriptArgs formats tool arguments compactly as key=value pairs. Text
+// is redacted as in the log, and always without --echo-typed-text.
+func transcriptArgs(args json.RawMessage) string {
+	var fields map[string]any
+	if json.Unmarshal(loggedArgs(args), &fields) != nil || len(fields) == 0 {
+		return ""
+	}
+	if !echoTypedText {
+		redactArgs(fields, true)
+	}
+	var pairs []string
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		value, err := json.Marshal(fields[key])
+		if err != nil {
+			continue
+		}
+		pairs = append(pairs, key+"="+clip(string(value)))
+	}
+	return strings.Join(pairs, " ")
+}
+
+// firstText returns the first line of the first text content
+func firstText(content []mcp.Content) string {
+	for _, c := range content {
+		if text, ok := c.(*mcp.TextContent); ok {
+			line, _, _ := strings.Cut(text.Text, "\n")
+			return line
+		}
+	}
+	return ""
+}
+
+// textBytes returns the size of all text content
+func textBytes(content []mcp.Content) int {
+	n := 0
+	for _, c := range content {
+		if text, ok := c.(*mcp.TextContent); ok {
+			n += len(text.Text)
+		}
+	}
+	return n
+}
+
+// clip shortens s to maxTranscriptValue characters
+func clip(s string) string {
+	if utf8.RuneCountInString(s) <= maxTranscriptValue {
+		return s
+	}
+	return string([]rune(s)[:maxTranscriptValue]) + "..."
+}
+
+// recordTranscript is receiving middleware that adds every tool call to the
+// session://transcript resource
+func recordTranscript(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		result, err := next(ctx, session, method, params)
+		call, ok := params.(*mcp.CallToolParamsFor[json.RawMessage])
+		if method != "tools/call" || !ok {
+			return result, err
+		}
+		res, _ := result.(*mcp.CallToolResult)
+		transcript.addCall(call.Name, call.Arguments, res, err)
+		return result, err
+	}
+}
+
+// readTranscript serves the session://transcript resource
+func readTranscript(ctx context.Context, session *mcp.ServerSession, params *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: transcriptURI, MIMEType: "text/plain", Text: transcript.String()},
+		},
+	}, nil
+}
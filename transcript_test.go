@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mcp-x11-controller/x11"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRecordTranscript(t *testing.T) {
+	saved := transcript
+	defer func() { transcript = saved }()
+	transcript = newSessionTranscript()
+
+	var result *mcp.CallToolResult
+	var fail error
+	next := func(ctx context.Context, session *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+		return result, fail
+	}
+	handler := recordTranscript(next)
+	call := func(tool, args string) {
+		params := &mcp.CallToolParamsFor[json.RawMessage]{Name: tool, Arguments: json.RawMessage(args)}
+		handler(context.Background(), nil, "tools/call", params)
+	}
+
+	result = &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "Clicked at 10, 20\nmore detail"}}}
+	call("x11_click_at", `{"x":10,"y":20}`)
+	call("x11_click_at", `{"x":10,"y":20}`)
+	transcript.addWindowEvent(x11.WindowEvent{Time: time.Now(), Kind: "mapped", Window: 0x400001, Title: "Save As"})
+	result = &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "no such window"}}}
+	call("x11_focus_window", `{"window_id":5}`)
+	result, fail = nil, errors.New("bad arguments")
+	call("x11_type_text", `{"text":"hunter2","sensitive":true}`)
+	result, fail = &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "s3cret token"}}}, nil
+	call("x11_get_clipboard", `{}`)
+
+	got := transcript.String()
+	for _, want := range []string{
+		`x11_click_at x=10 y=20 -> Clicked at 10, 20` + "\n",
+		`[window mapped 0x400001 "Save As"]`,
+		`x11_focus_window window_id=5 -> failed: no such window`,
+		`x11_type_text sensitive=true text="[7 characters]" -> failed: bad arguments`,
+		`x11_get_clipboard -> [12 bytes]`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("transcript lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "x11_click_at") != 1 {
+		t.Errorf("repeated call not collapsed:\n%s", got)
+	}
+	if strings.Contains(got, "hunter2") || strings.Contains(got, "s3cret") {
+		t.Errorf("sensitive text in transcript:\n%s", got)
+	}
+}
+
+func TestTranscriptArgs(t *testing.T) {
+	saved := echoTypedText
+	defer func() { echoTypedText = saved }()
+
+	tests := []struct {
+		args string
+		echo bool
+		want string
+	}{
+		{`{}`, true, ""},
+		{`not json`, true, ""},
+		{`{"text":"hello","delay":100}`, true, `delay=100 text="hello"`},
+		{`{"text":"hello"}`, false, `text="[5 characters]"`},
+		{`{"text":"` + strings.Repeat("a", 200) + `"}`, true, `text="` + strings.Repeat("a", maxTranscriptValue-1) + `...`},
+	}
+	for _, tt := range tests {
+		echoTypedText = tt.echo
+		if got := transcriptArgs(json.RawMessage(tt.args)); got != tt.want {
+			t.Errorf("transcriptArgs(%s) with echo %v = %q, want %q", tt.args, tt.echo, got, tt.want)
+		}
+	}
+}
+
+func TestTranscriptKeepsRecentLines(t *testing.T) {
+	tr := newSessionTranscript()
+	if got := tr.String(); !strings.Contains(got, "nothing happened yet") {
+		t.Errorf("empty transcript = %q", got)
+	}
+	for i := 0; i < maxTranscriptLines+5; i++ {
+		tr.add(time.Now(), strings.Repeat("x", i+1))
+	}
+	got := tr.String()
+	if !strings.Contains(got, "5 older entries dropped") {
+		t.Errorf("dropped lines not reported:\n%s", got)
+	}
+	if n := strings.Count(got, "\n"); n != maxTranscriptLines+1 {
+		t.Errorf("transcript has %d lines, want %d", n, maxTranscriptLines+1)
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	res, err := readTranscript(context.Background(), nil, &mcp.ReadResourceParams{URI: transcriptURI})
+	if err != nil {
+		t.Fatalf("readTranscript failed: %v", err)
+	}
+	if len(res.Contents) != 1 || res.Contents[0].URI != transcriptURI || res.Contents[0].MIMEType != "text/plain" {
+		t.Fatalf("unexpected contents %+v", res.Contents)
+	}
+	if !strings.HasPrefix(res.Contents[0].Text, "Session started") {
+		t.Errorf("unexpected text %q", res.Contents[0].Text)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"mcp-x11-controller/x11"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Assertion types of x11_verify
+const (
+	assertWindowExists   = "window_exists"
+	assertTextVisible    = "text_visible"
+	assertPixelColor     = "pixel_color"
+	assertTitleMatches   = "title_matches"
+	assertProgramRunning = "program_running"
+)
+
+var assertionTypes = []string{assertWindowExists, assertTextVisible, assertPixelColor, assertTitleMatches, assertProgramRunning}
+
+const (
+	// defaultColorTolerance is how far each channel of a pixel_color
+	// assertion may be off by default
+	defaultColorTolerance = 16
+	// evidenceMargin is how much of the screen around the text or pixel
+	// checked an evidence image shows
+	evidenceMargin = 24
+	// evidenceThumbWidth bounds the window thumbnails in evidence
+	evidenceThumbWidth = 320
+	// commLength is how much of a program name /proc/PID/comm keeps
+	commLength = 15
+)
+
+// assertionResult is the outcome of one x11_verify assertion
+type assertionResult struct {
+	Passed   bool
+	Detail   string
+	Evidence []byte // PNG of what was checked, if there is something to show
+}
+
+// validateAssertions checks the assertions of x11_verify before any runs
+func validateAssertions(assertions []AssertionInput) error {
+	if len(assertions) == 0 {
+		return fmt.Errorf("give at least one assertion")
+	}
+	for i, a := range assertions {
+		if err := validateAssertion(a); err != nil {
+			return fmt.Errorf("assertion %d (%s): %w", i+1, a.Type, err)
+		}
+	}
+	return nil
+}
+
+// validateAssertion checks that an assertion has the arguments of its type
+func validateAssertion(a AssertionInput) error {
+	switch a.Type {
+	case assertWindowExists:
+		if a.Timeout < 0 {
+			return fmt.Errorf("timeout cannot be negative")
+		}
+		return x11.WindowMatcher{Title: a.Title, Class: a.Class}.Validate()
+	case assertTextVisible:
+		if strings.TrimSpace(a.Text) == "" {
+			return fmt.Errorf("text is required")
+		}
+	case assertPixelColor:
+		if _, err := parseHexColor(a.Color); err != nil {
+			return err
+		}
+		if a.Tolerance != nil && (*a.Tolerance < 0 || *a.Tolerance > 255) {
+			return fmt.Errorf("tolerance must be between 0 and 255")
+		}
+	case assertTitleMatches:
+		if a.WindowID == 0 || a.Title == "" {
+			return fmt.Errorf("window_id and title are required")
+		}
+		if _, err := regexp.Compile(a.Title); err != nil {
+			return fmt.Errorf("invalid title pattern: %w", err)
+		}
+	case assertProgramRunning:
+		if (a.PID == 0) == (a.Program == "") {
+			return fmt.Errorf("give exactly one of pid or program")
+		}
+	default:
+		return fmt.Errorf("unknown type (available: %s)", strings.Join(assertionTypes, ", "))
+	}
+	return nil
+}
+
+// parseHexColor parses #RRGGBB
+func parseHexColor(s string) (color.RGBA, error) {
+	hex, ok := strings.CutPrefix(strings.TrimSpace(s), "#")
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, want #RRGGBB", s)
+	}
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+// verifier runs the assertions of one x11_verify call. The screen is
+// captured and its text recognized once, on first need, so all assertions
+// see the same moment.
+type verifier struct {
+	evidence bool
+	screen   image.Image
+	words    []ocrWord
+	read     bool
+}
+
+// close recycles the screen capture
+func (v *verifier) close() {
+	if v.screen != nil {
+		client.RecycleScreenshot(v.screen)
+	}
+}
+
+// capture returns the screen as it was at the first assertion needing it
+func (v *verifier) capture(ctx context.Context) (image.Image, error) {
+	if v.screen == nil {
+		img, err := client.ScreenshotContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		v.screen = img
+	}
+	return v.screen, nil
+}
+
+// recognize returns the words on the screen
+func (v *verifier) recognize(ctx context.Context) ([]ocrWord, error) {
+	if v.read {
+		return v.words, nil
+	}
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil, fmt.Errorf("text_visible needs tesseract, which is not installed")
+	}
+	img, err := v.capture(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if v.words, err = screenWords(ctx, img); err != nil {
+		return nil, err
+	}
+	v.read = true
+	return v.words, nil
+}
+
+// run evaluates an assertion. A check that can't be made, such as for a
+// window that doesn't exist, fails the assertion with the reason.
+func (v *verifier) run(ctx context.Context, a AssertionInput) assertionResult {
+	var res assertionResult
+	var err error
+	switch a.Type {
+	case assertWindowExists:
+		res, err = v.windowExists(ctx, a)
+	case assertTextVisible:
+		res, err = v.textVisible(ctx, a)
+	case assertPixelColor:
+		res, err = v.pixelColor(ctx, a)
+	case assertTitleMatches:
+		res, err = v.titleMatches(ctx, a)
+	case assertProgramRunning:
+		res, err = programRunning(a)
+	}
+	if err != nil {
+		return assertionResult{Detail: err.Error()}
+	}
+	if a.Not {
+		res.Passed = !res.Passed
+		res.Detail = "expected not: " + res.Detail
+	}
+	return res
+}
+
+// windowExists checks for a mapped window matching the title and class,
+// waiting up to the timeout for one to appear
+func (v *verifier) windowExists(ctx context.Context, a AssertionInput) (assertionResult, error) {
+	timeout := time.Duration(a.Timeout) * time.Millisecond
+	win, err := client.WaitForWindow(ctx, x11.WindowMatcher{Title: a.Title, Class: a.Class}, timeout)
+	// Only a missing window is a result; an X error or an abort fails the
+	// assertion even with not
+	var missing *x11.NoWindowError
+	if errors.As(err, &missing) {
+		return assertionResult{Detail: err.Error()}, nil
+	}
+	if err != nil {
+		return assertionResult{}, err
+	}
+	res := assertionResult{Passed: true, Detail: fmt.Sprintf("window 0x%x %q (%s) is open", uint32(win.ID), win.Title, orNone(win.Class))}
+	res.Evidence = v.thumbnail(ctx, win.ID)
+	return res, nil
+}
+
+// textVisible checks that OCR finds the text on the screen, or within the
+// window given
+func (v *verifier) textVisible(ctx context.Context, a AssertionInput) (assertionResult, error) {
+	words, err := v.recognize(ctx)
+	if err != nil {
+		return assertionResult{}, err
+	}
+	where := "on the screen"
+	if a.WindowID != 0 {
+		info, err := client.GetWindowInfoContext(ctx, x.Window(a.WindowID))
+		if err != nil {
+			return assertionResult{}, err
+		}
+		words = wordsIn(words, image.Rect(info.X, info.Y, info.X+info.Width, info.Y+info.Height))
+		where = fmt.Sprintf("in window 0x%x", a.WindowID)
+	}
+	rect, ok := findPhrase(words, a.Text)
+	if !ok {
+		return assertionResult{Detail: fmt.Sprintf("text %q not found %s", a.Text, where)}, nil
+	}
+	return assertionResult{
+		Passed:   true,
+		Detail:   fmt.Sprintf("text %q found %s at %dx%d+%d+%d", a.Text, where, rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y),
+		Evidence: v.crop(rect),
+	}, nil
+}
+
+// pixelColor checks the color of one screen pixel within a tolerance per
+// channel
+func (v *verifier) pixelColor(ctx context.Context, a AssertionInput) (assertionResult, error) {
+	px, py, err := client.ValidatePoint(float64(a.X), float64(a.Y))
+	if err != nil {
+		return assertionResult{}, err
+	}
+	img, err := v.capture(ctx)
+	if err != nil {
+		return assertionResult{}, err
+	}
+	want, _ := parseHexColor(a.Color)
+	tolerance := colorTolerance(a)
+	got := color.RGBAModel.Convert(img.At(px, py)).(color.RGBA)
+	diff := colorDiff(got, want)
+	return assertionResult{
+		Passed: diff <= tolerance,
+		Detail: fmt.Sprintf("pixel at (%d, %d) is #%02x%02x%02x, want %s within %d (off by %d)",
+			px, py, got.R, got.G, got.B, strings.ToLower(a.Color), tolerance, diff),
+		Evidence: v.crop(image.Rect(px, py, px+1, py+1)),
+	}, nil
+}
+
+// colorTolerance returns the tolerance of a pixel_color assertion; 0 asks
+// for an exact match, so only a missing tolerance gets the default
+func colorTolerance(a AssertionInput) int {
+	if a.Tolerance == nil {
+		return defaultColorTolerance
+	}
+	return *a.Tolerance
+}
+
+// colorDiff is how far the most different channel of two colors is off
+func colorDiff(a, b color.RGBA) int {
+	return max(channelDiff(a.R, b.R), channelDiff(a.G, b.G), channelDiff(a.B, b.B))
+}
+
+// channelDiff is how far apart two color channel values are
+func channelDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// titleMatches checks a window's title against a regular expression
+func (v *verifier) titleMatches(ctx context.Context, a AssertionInput) (assertionResult, error) {
+	info, err := client.GetWindowInfoContext(ctx, x.Window(a.WindowID))
+	if err != nil {
+		return assertionResult{}, err
+	}
+	res := assertionResult{
+		Passed:   regexp.MustCompile(a.Title).MatchString(info.Title),
+		Detail:   fmt.Sprintf("window 0x%x has title %q, want it to match %q", uint32(info.ID), info.Title, a.Title),
+		Evidence: v.thumbnail(ctx, info.ID),
+	}
+	return res, nil
+}
+
+// programRunning checks for a running process by PID or by name. Programs
+// started by x11_start_program are known even after they exited.
+func programRunning(a AssertionInput) (assertionResult, error) {
+	if a.PID != 0 {
+		if status, ok := client.AppStatus(a.PID); ok {
+			if status.Exited {
+				return assertionResult{Detail: fmt.Sprintf("%s (PID %d) exited with code %d", status.Program, a.PID, status.ExitCode)}, nil
+			}
+			return assertionResult{Passed: true, Detail: fmt.Sprintf("%s (PID %d) is running", status.Program, a.PID)}, nil
+		}
+		if !processExists(a.PID) {
+			return assertionResult{Detail: fmt.Sprintf("no process with PID %d", a.PID)}, nil
+		}
+		return assertionResult{Passed: true, Detail: fmt.Sprintf("process %d is running", a.PID)}, nil
+	}
+
+	pids := processesNamed(a.Program)
+	if len(pids) == 0 {
+		return assertionResult{Detail: fmt.Sprintf("no process named %s", a.Program)}, nil
+	}
+	return assertionResult{Passed: true, Detail: fmt.Sprintf("%s is running as PID %s", a.Program, strings.Join(pids, ", "))}, nil
+}
+
+// processExists reports whether a process with the PID exists; signal 0
+// only checks that, and processes of other users refuse it
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processesNamed returns the PIDs of processes whose name is the base name
+// of program, as the kernel keeps it
+func processesNamed(program string) []string {
+	name := filepath.Base(program)
+	if len(name) > commLength {
+		name = name[:commLength]
+	}
+	comms, _ := filepath.Glob("/proc/[0-9]*/comm")
+	var pids []string
+	for _, path := range comms {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.TrimSpace(string(data)) == name {
+			pids = append(pids, filepath.Base(filepath.Dir(path)))
+		}
+	}
+	return pids
+}
+
+// crop encodes the area of the screen capture around rect
+func (v *verifier) crop(rect image.Rectangle) []byte {
+	if !v.evidence || v.screen == nil {
+		return nil
+	}
+	cropper, ok := v.screen.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil
+	}
+	area := rect.Inset(-evidenceMargin).Intersect(v.screen.Bounds())
+	data, err := client.EncodePNG(cropper.SubImage(area))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// thumbnail captures a small image of a window, nil if it can't be captured
+func (v *verifier) thumbnail(ctx context.Context, win x.Window) []byte {
+	if !v.evidence {
+		return nil
+	}
+	data, err := client.WindowThumbnailPNG(ctx, win, evidenceThumbWidth)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// verifyText summarizes the results of x11_verify, one line per assertion
+func verifyText(assertions []AssertionInput, results []assertionResult) string {
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	var b strings.Builder
+	if failed == 0 {
+		fmt.Fprintf(&b, "All %d assertion(s) passed", len(results))
+	} else {
+		fmt.Fprintf(&b, "Verification failed: %d of %d assertion(s) failed", failed, len(results))
+	}
+	for i, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "\n%d. %s %s: %s", i+1, status, assertions[i].Type, r.Detail)
+	}
+	return b.String()
+}
+
+// verifyMeta is _meta.assertions of x11_verify
+func verifyMeta(assertions []AssertionInput, results []assertionResult) []map[string]any {
+	meta := make([]map[string]any, len(results))
+	for i, r := range results {
+		meta[i] = map[string]any{
+			"type":     assertions[i].Type,
+			"passed":   r.Passed,
+			"detail":   r.Detail,
+			"evidence": r.Evidence != nil,
+		}
+		if assertions[i].Not {
+			meta[i]["not"] = true
+		}
+	}
+	return meta
+}
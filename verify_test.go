@@ -0,0 +1,127 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateAssertions(t *testing.T) {
+	tests := []struct {
+		name       string
+		assertions []AssertionInput
+		wantErr    string
+	}{
+		{"window", []AssertionInput{{Type: "window_exists", Title: "- Mozilla Firefox$"}}, ""},
+		{"several", []AssertionInput{{Type: "text_visible", Text: "Saved"}, {Type: "program_running", Program: "gedit"}}, ""},
+		{"none", nil, "at least one"},
+		{"unknown type", []AssertionInput{{Type: "file_exists"}}, "unknown type"},
+		{"window without pattern", []AssertionInput{{Type: "window_exists"}}, "title or class"},
+		{"bad class", []AssertionInput{{Type: "window_exists", Class: "["}}, "invalid class"},
+		{"negative timeout", []AssertionInput{{Type: "window_exists", Class: "xterm", Timeout: -1}}, "negative"},
+		{"empty text", []AssertionInput{{Type: "text_visible", Text: " "}}, "text is required"},
+		{"pixel", []AssertionInput{{Type: "pixel_color", X: 5, Y: 5, Color: "#FF8000"}}, ""},
+		{"color name", []AssertionInput{{Type: "pixel_color", Color: "red"}}, "#RRGGBB"},
+		{"tolerance", []AssertionInput{{Type: "pixel_color", Color: "#000000", Tolerance: intPtr(300)}}, "tolerance"},
+		{"title without window", []AssertionInput{{Type: "title_matches", Title: "Saved"}}, "window_id"},
+		{"bad title", []AssertionInput{{Type: "title_matches", WindowID: 0x400001, Title: "("}}, "invalid title"},
+		{"pid and program", []AssertionInput{{Type: "program_running", PID: 1, Program: "init"}}, "exactly one"},
+		{"second invalid", []AssertionInput{{Type: "text_visible", Text: "OK"}, {Type: "program_running"}}, "assertion 2 (program_running)"},
+	}
+	for _, tt := range tests {
+		err := validateAssertions(tt.assertions)
+		if (tt.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: validateAssertions = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{"#ff8000", color.RGBA{R: 0xff, G: 0x80, A: 0xff}, false},
+		{" #00A0fF ", color.RGBA{G: 0xa0, B: 0xff, A: 0xff}, false},
+		{"ff8000", color.RGBA{}, true},
+		{"#fff", color.RGBA{}, true},
+		{"#gg0000", color.RGBA{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseHexColor(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseHexColor(%q) = %v, %v, want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestProcesses(t *testing.T) {
+	if !processExists(os.Getpid()) {
+		t.Errorf("processExists(%d) = false for this process", os.Getpid())
+	}
+	if processExists(1 << 30) {
+		t.Error("processExists for an impossible PID = true")
+	}
+
+	// The test binary's name is longer than the kernel keeps
+	if pids := processesNamed(os.Args[0]); !slices.Contains(pids, strconv.Itoa(os.Getpid())) {
+		t.Errorf("processesNamed(%q) = %v, want this process", filepath.Base(os.Args[0]), pids)
+	}
+	if pids := processesNamed("no-such-program"); len(pids) != 0 {
+		t.Errorf("processesNamed for a missing program = %v", pids)
+	}
+
+	res, err := programRunning(AssertionInput{Type: "program_running", Program: "no-such-program"})
+	if err != nil || res.Passed || res.Detail != "no process named no-such-program" {
+		t.Errorf("programRunning for a missing program = %+v, %v", res, err)
+	}
+}
+
+func TestVerifyText(t *testing.T) {
+	assertions := []AssertionInput{
+		{Type: "window_exists", Class: "gedit"},
+		{Type: "text_visible", Text: "Saved", Not: true},
+	}
+	results := []assertionResult{
+		{Passed: true, Detail: `window 0x400001 "notes.txt" (Gedit) is open`, Evidence: []byte("png")},
+		{Detail: `expected not: text "Saved" found on the screen at 40x12+10+20`},
+	}
+	want := "Verification failed: 1 of 2 assertion(s) failed\n" +
+		"1. PASS window_exists: window 0x400001 \"notes.txt\" (Gedit) is open\n" +
+		"2. FAIL text_visible: expected not: text \"Saved\" found on the screen at 40x12+10+20"
+	if got := verifyText(assertions, results); got != want {
+		t.Errorf("verifyText() = %q, want %q", got, want)
+	}
+	if got := verifyText(assertions[:1], results[:1]); !strings.HasPrefix(got, "All 1 assertion(s) passed\n") {
+		t.Errorf("verifyText() = %q, want all passed", got)
+	}
+
+	meta := verifyMeta(assertions, results)
+	if meta[0]["evidence"] != true || meta[0]["not"] != nil || meta[1]["passed"] != false || meta[1]["not"] != true {
+		t.Errorf("verifyMeta() = %v", meta)
+	}
+}
+
+func TestColorTolerance(t *testing.T) {
+	if got := colorTolerance(AssertionInput{}); got != defaultColorTolerance {
+		t.Errorf("colorTolerance without tolerance = %d, want %d", got, defaultColorTolerance)
+	}
+	exact := AssertionInput{Type: "pixel_color", Color: "#102030", Tolerance: intPtr(0)}
+	if err := validateAssertions([]AssertionInput{exact}); err != nil {
+		t.Fatalf("tolerance 0 rejected: %v", err)
+	}
+	want := color.RGBA{0x10, 0x20, 0x30, 0xff}
+	if tolerance := colorTolerance(exact); tolerance != 0 || colorDiff(want, want) > tolerance || colorDiff(color.RGBA{0x10, 0x21, 0x30, 0xff}, want) <= tolerance {
+		t.Errorf("tolerance 0 = %d, want only the exact color to match", tolerance)
+	}
+}
+
+// intPtr returns a pointer to n, for optional integer arguments
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// wmBindingPolicies are the accepted values of --wm-binding and the
+// wm_binding argument of x11_key_press
+var wmBindingPolicies = []string{"warn", "route", "refuse"}
+
+// parseWMBindingPolicy validates a policy name. An empty name selects "warn".
+func parseWMBindingPolicy(name string) (string, error) {
+	if name == "" {
+		return "warn", nil
+	}
+	name = strings.ToLower(name)
+	for _, policy := range wmBindingPolicies {
+		if policy == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown wm_binding policy: %s (available: %s)", name, strings.Join(wmBindingPolicies, ", "))
+}
+
+// wmGuard is the outcome of checking keys against the window manager's bindings
+type wmGuard struct {
+	note   string         // Explanation for the tool result, empty if the keys are not bound
+	routed bool           // The binding was run over i3 IPC, so the keys must not be sent
+	meta   map[string]any // Result metadata describing the binding
+}
+
+// guardWMBinding checks whether keys would be swallowed by a window manager
+// binding and applies the policy: "warn" sends the keys and explains, "route"
+// runs the binding's i3 command instead, "refuse" fails without sending.
+// Failing to look up the bindings only logs a warning.
+func guardWMBinding(ctx context.Context, keys, policy string) (wmGuard, error) {
+	binding, err := client.WMBindingFor(ctx, keys)
+	if err != nil {
+		slog.Warn("failed to check window manager bindings", "keys", keys, "err", err)
+		return wmGuard{}, nil
+	}
+	if binding == nil {
+		return wmGuard{}, nil
+	}
+
+	guard := wmGuard{meta: map[string]any{"wm_binding": binding.Keys}}
+	if binding.Routable() {
+		guard.meta["wm_command"] = binding.Command
+	}
+
+	switch policy {
+	case "refuse":
+		if binding.Routable() {
+			return guard, fmt.Errorf("%s is bound by i3 (%s) to %q and would not reach the application", keys, binding.Keys, binding.Command)
+		}
+		return guard, fmt.Errorf("%s is grabbed by the window manager and would not reach the application", keys)
+	case "route":
+		if binding.Routable() {
+			result, err := client.RunWMBinding(ctx, *binding)
+			if err != nil {
+				return guard, err
+			}
+			guard.routed = true
+			guard.note = fmt.Sprintf("Ran i3 binding %s instead of sending keys: %s (%s)", binding.Keys, binding.Command, result)
+			return guard, nil
+		}
+		guard.note = fmt.Sprintf("Warning: %s is grabbed by the window manager, which is not i3, so it could not be routed; the keys were sent but the application probably did not receive them", keys)
+		return guard, nil
+	}
+
+	if binding.Routable() {
+		guard.note = fmt.Sprintf("Warning: %s is bound by i3 (%s) to %q; i3 handled it and the application did not receive the keys. Use wm_binding \"route\" to run the i3 command on purpose", keys, binding.Keys, binding.Command)
+	} else {
+		guard.note = fmt.Sprintf("Warning: %s is grabbed by the window manager; the application probably did not receive the keys", keys)
+	}
+	return guard, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestParseWMBindingPolicy(t *testing.T) {
+	tests := map[string]string{
+		"":       "warn",
+		"warn":   "warn",
+		"Route":  "route",
+		"refuse": "refuse",
+	}
+	for name, want := range tests {
+		got, err := parseWMBindingPolicy(name)
+		if err != nil || got != want {
+			t.Errorf("parseWMBindingPolicy(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := parseWMBindingPolicy("ignore"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,152 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+// ErrAborted is returned by waits that were interrupted by AbortAll
+var ErrAborted = errors.New("aborted by emergency stop")
+
+// ErrObserver is returned by input methods of clients connected in observer mode
+var ErrObserver = errors.New("input is not available in observer mode")
+
+// ErrFrozen is returned by input methods while the emergency stop hotkey is engaged
+var ErrFrozen = errors.New("input is frozen by the emergency stop hotkey")
+
+// abortState tracks cancellation of in-flight waits and the frozen flag
+type abortState struct {
+	mu         sync.Mutex
+	ch         chan struct{}
+	frozen     bool
+	hotkey     x.Keycode // Keycode grabbed by EnableAbortHotkey, 0 if none
+	hotkeyMask uint16
+}
+
+// abortChan returns a channel that is closed on the next AbortAll
+func (c *Client) abortChan() <-chan struct{} {
+	c.abort.mu.Lock()
+	defer c.abort.mu.Unlock()
+
+	if c.abort.ch == nil {
+		c.abort.ch = make(chan struct{})
+	}
+	return c.abort.ch
+}
+
+// AbortAll cancels all in-flight waits and releases every held key and button
+func (c *Client) AbortAll() error {
+	c.abort.mu.Lock()
+	if c.abort.ch != nil {
+		close(c.abort.ch)
+	}
+	c.abort.ch = make(chan struct{})
+	c.abort.mu.Unlock()
+
+	return c.ReleaseAll()
+}
+
+// Frozen returns true if the emergency stop hotkey has frozen input injection
+func (c *Client) Frozen() bool {
+	c.abort.mu.Lock()
+	defer c.abort.mu.Unlock()
+	return c.abort.frozen
+}
+
+// SetFrozen freezes or unfreezes input injection
+func (c *Client) SetFrozen(frozen bool) {
+	c.abort.mu.Lock()
+	c.abort.frozen = frozen
+	c.abort.mu.Unlock()
+}
+
+// checkFrozen returns ErrFrozen if input injection is currently frozen, or
+// ErrObserver if it is not available at all
+func (c *Client) checkFrozen() error {
+	if c.observer {
+		return ErrObserver
+	}
+	if c.Frozen() {
+		return ErrFrozen
+	}
+	return nil
+}
+
+// EnableAbortHotkey grabs a global key combination (e.g. "ctrl+alt+Pause") on
+// the root window. Pressing it aborts everything and freezes input until it is
+// pressed again.
+func (c *Client) EnableAbortHotkey(combo string) error {
+	mods, mainKey, err := parseKeyCombo(combo)
+	if err != nil {
+		return err
+	}
+
+	keysym, err := c.keyNameToKeysym(mainKey)
+	if err != nil {
+		return fmt.Errorf("unknown hotkey key: %s", mainKey)
+	}
+
+	ctx := context.Background()
+	keycode, err := c.keysymToKeycode(ctx, keysym)
+	if err != nil {
+		return err
+	}
+
+	var mask uint16
+	for _, mod := range mods {
+		mask |= mod.mask
+	}
+
+	// Grab with every combination of lock modifiers so NumLock/CapsLock don't
+	// prevent the hotkey from firing
+	lockMods := append([]uint16{0}, keysyms.LockMods...)
+	for _, lock := range lockMods {
+		err := awaitCheck(c, ctx, func() error {
+			return x.GrabKeyChecked(c.conn, false, c.root, mask|lock, keycode,
+				x.GrabModeAsync, x.GrabModeAsync).Check(c.conn)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to grab hotkey %s: %w", combo, err)
+		}
+	}
+
+	c.abort.mu.Lock()
+	c.abort.hotkey, c.abort.hotkeyMask = keycode, mask
+	c.abort.mu.Unlock()
+
+	c.addEventHandler(func(ev x.GenericEvent) {
+		if ev.GetEventCode() != x.KeyPressEventCode {
+			return
+		}
+		kev, err := x.NewKeyPressEvent(ev)
+		if err != nil || kev.Detail != keycode {
+			return
+		}
+		if kev.State&^(keysyms.ModMaskCapsLock|keysyms.ModMaskNumLock) != mask {
+			return
+		}
+
+		if c.Frozen() {
+			c.SetFrozen(false)
+			slog.Info("emergency stop released, input unfrozen")
+			return
+		}
+		// Freezing refuses new input right away; releasing what is held
+		// takes round trips, which must not block the event loop
+		c.SetFrozen(true)
+		go func() {
+			if err := c.AbortAll(); err != nil {
+				slog.Warn("emergency stop failed to release input", "err", err)
+			}
+			slog.Warn("emergency stop engaged, input frozen", "resume", combo)
+		}()
+	})
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package x11
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitAborted(t *testing.T) {
+	client := &Client{}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Wait(5000)
+	}()
+
+	// Give the waiter time to block
+	time.Sleep(50 * time.Millisecond)
+	if err := client.AbortAll(); err != nil {
+		t.Fatalf("AbortAll failed: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrAborted) {
+			t.Errorf("expected ErrAborted, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait was not interrupted by AbortAll")
+	}
+
+	// Waits started after the abort must run normally
+	if err := client.Wait(10); err != nil {
+		t.Errorf("expected nil error after abort, got %v", err)
+	}
+}
+
+func TestFrozenInput(t *testing.T) {
+	client := &Client{}
+	client.SetFrozen(true)
+
+	if err := client.MouseMove(10, 10); !errors.Is(err, ErrFrozen) {
+		t.Errorf("MouseMove: expected ErrFrozen, got %v", err)
+	}
+	if err := client.MouseClick(1); !errors.Is(err, ErrFrozen) {
+		t.Errorf("MouseClick: expected ErrFrozen, got %v", err)
+	}
+	if err := client.Type("hello"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("Type: expected ErrFrozen, got %v", err)
+	}
+	if err := client.KeyPress("Enter"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("KeyPress: expected ErrFrozen, got %v", err)
+	}
+	if err := client.KeyCombo("ctrl+c"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("KeyCombo: expected ErrFrozen, got %v", err)
+	}
+
+	client.SetFrozen(false)
+	if client.Frozen() {
+		t.Error("expected client to be unfrozen")
+	}
+}
+
+func TestObserverInput(t *testing.T) {
+	client := &Client{observer: true}
+
+	if err := client.MouseMove(10, 10); !errors.Is(err, ErrObserver) {
+		t.Errorf("MouseMove: expected ErrObserver, got %v", err)
+	}
+	if err := client.Type("hello"); !errors.Is(err, ErrObserver) {
+		t.Errorf("Type: expected ErrObserver, got %v", err)
+	}
+	if err := client.KeyCombo("ctrl+c"); !errors.Is(err, ErrObserver) {
+		t.Errorf("KeyCombo: expected ErrObserver, got %v", err)
+	}
+	if !client.Observer() {
+		t.Error("expected client to be in observer mode")
+	}
+}
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		combo     string
+		modifiers int
+		mainKey   string
+		wantErr   bool
+	}{
+		{"ctrl+c", 1, "c", false},
+		{"Ctrl+Alt+Pause", 2, "Pause", false},
+		{"super+shift+Return", 2, "Return", false},
+		{"ctrl+U+00e9", 1, "U+00e9", false},
+		{"ctrl+u", 1, "u", false},
+		{"c", 0, "", true},
+		{"hyper+c", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.combo, func(t *testing.T) {
+			mods, mainKey, err := parseKeyCombo(tt.combo)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(mods) != tt.modifiers {
+				t.Errorf("expected %d modifiers, got %d", tt.modifiers, len(mods))
+			}
+			if mainKey != tt.mainKey {
+				t.Errorf("expected main key %q, got %q", tt.mainKey, mainKey)
+			}
+		})
+	}
+}
@@ -1,12 +1,48 @@
 package x11
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
+	"sort"
+	"sync"
 	"syscall"
+	"time"
 )
 
+// stopTimeout is how long StopApp waits after SIGTERM before sending SIGKILL
+const stopTimeout = 5 * time.Second
+
+// AppStatus describes a program started by StartApp
+type AppStatus struct {
+	PID      int
+	Program  string
+	Args     []string
+	Started  time.Time
+	Exited   bool
+	ExitCode int       // Exit code, or -1 if the program was killed by a signal
+	Signal   string    // Signal that terminated the program, if any
+	Ended    time.Time // When the program exited
+
+	Transcript string // Output recorded by StartTerminal, empty for other programs
+	Profile    string // Throwaway profile created by StartBrowser, removed on exit
+}
+
+// trackedApp is a launched program and the channel closed once it is reaped
+type trackedApp struct {
+	status  AppStatus
+	process *os.Process
+	done    chan struct{}
+}
+
+// appTracker records every program we started
+type appTracker struct {
+	mu   sync.Mutex
+	apps map[int]*trackedApp
+}
+
 // StartApp starts an application on the X display
 func (c *Client) StartApp(app string, args []string) (int, error) {
 	return c.StartAppWithEnv(app, args, nil)
@@ -14,6 +50,12 @@ func (c *Client) StartApp(app string, args []string) (int, error) {
 
 // StartAppWithEnv starts an application with custom environment variables
 func (c *Client) StartAppWithEnv(app string, args []string, env map[string]string) (int, error) {
+	return c.startApp(app, args, env, AppStatus{})
+}
+
+// startApp starts and tracks an application. Helpers such as StartTerminal
+// pass the files they set up for it in status.
+func (c *Client) startApp(app string, args []string, env map[string]string, status AppStatus) (int, error) {
 	// Check if the app exists
 	appPath, err := exec.LookPath(app)
 	if err != nil {
@@ -29,6 +71,11 @@ func (c *Client) StartAppWithEnv(app string, args []string, env map[string]strin
 	// Ensure DISPLAY is set to our display
 	cmd.Env = setEnv(cmd.Env, "DISPLAY", c.display)
 	
+	// Point HOME and the XDG directories into the isolated home, if any
+	for k, v := range c.homeEnv {
+		cmd.Env = setEnv(cmd.Env, k, v)
+	}
+	
 	// Add custom environment variables
 	for k, v := range env {
 		cmd.Env = setEnv(cmd.Env, k, v)
@@ -44,11 +91,90 @@ func (c *Client) StartAppWithEnv(app string, args []string, env map[string]strin
 		return 0, fmt.Errorf("failed to start application: %w", err)
 	}
 	
-	return cmd.Process.Pid, nil
+	status.PID = cmd.Process.Pid
+	status.Program = app
+	status.Args = args
+	status.Started = time.Now()
+	tracked := &trackedApp{
+		status:  status,
+		process: cmd.Process,
+		done:    make(chan struct{}),
+	}
+	c.apps.mu.Lock()
+	if c.apps.apps == nil {
+		c.apps.apps = make(map[int]*trackedApp)
+	}
+	c.apps.apps[tracked.status.PID] = tracked
+	c.apps.mu.Unlock()
+
+	// Reap the child as soon as it exits so it never lingers as a zombie
+	go c.reapApp(cmd, tracked)
+
+	return tracked.status.PID, nil
+}
+
+// reapApp waits for a launched program and records how it exited
+func (c *Client) reapApp(cmd *exec.Cmd, app *trackedApp) {
+	err := cmd.Wait()
+
+	c.apps.mu.Lock()
+	app.status.Exited = true
+	app.status.Ended = time.Now()
+	profile := app.status.Profile
+	app.status.ExitCode = cmd.ProcessState.ExitCode()
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		app.status.Signal = ws.Signal().String()
+	}
+	c.apps.mu.Unlock()
+	if profile != "" {
+		if err := os.RemoveAll(profile); err != nil {
+			slog.Warn("failed to remove browser profile", "profile", profile, "err", err)
+		}
+	}
+	close(app.done)
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		slog.Warn("failed to wait for app", "program", app.status.Program, "pid", app.status.PID, "err", err)
+	}
+}
+
+// AppStatus returns the status of a program started by StartApp
+func (c *Client) AppStatus(pid int) (AppStatus, bool) {
+	c.apps.mu.Lock()
+	defer c.apps.mu.Unlock()
+
+	app, ok := c.apps.apps[pid]
+	if !ok {
+		return AppStatus{}, false
+	}
+	return app.status, true
+}
+
+// ListApps returns the status of every program started by StartApp, oldest first
+func (c *Client) ListApps() []AppStatus {
+	c.apps.mu.Lock()
+	defer c.apps.mu.Unlock()
+
+	list := make([]AppStatus, 0, len(c.apps.apps))
+	for _, app := range c.apps.apps {
+		list = append(list, app.status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Started.Before(list[j].Started)
+	})
+	return list
 }
 
 // StopApp stops an application by PID
 func (c *Client) StopApp(pid int) error {
+	c.apps.mu.Lock()
+	app, tracked := c.apps.apps[pid]
+	c.apps.mu.Unlock()
+	if tracked {
+		return c.stopTrackedApp(app)
+	}
+
 	// Find the process
 	process, err := os.FindProcess(pid)
 	if err != nil {
@@ -69,6 +195,45 @@ func (c *Client) StopApp(pid int) error {
 	return nil
 }
 
+// stopTrackedApp terminates a program we started and waits for its reaper
+func (c *Client) stopTrackedApp(app *trackedApp) error {
+	select {
+	case <-app.done:
+		return nil
+	default:
+	}
+
+	// Signal errors mean the process is already gone; the reaper handles that
+	app.process.Signal(syscall.SIGTERM)
+
+	select {
+	case <-app.done:
+		return nil
+	case <-time.After(stopTimeout):
+	}
+
+	if err := app.process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill process: %w", err)
+	}
+	<-app.done
+	return nil
+}
+
+// removeAppFiles deletes the transcripts and browser profiles of all
+// programs we started
+func (c *Client) removeAppFiles() {
+	c.apps.mu.Lock()
+	defer c.apps.mu.Unlock()
+	for _, app := range c.apps.apps {
+		if app.status.Transcript != "" {
+			os.Remove(app.status.Transcript)
+		}
+		if app.status.Profile != "" {
+			os.RemoveAll(app.status.Profile)
+		}
+	}
+}
+
 // setEnv sets or updates an environment variable in a slice
 func setEnv(env []string, key, value string) []string {
 	prefix := key + "="
@@ -2,6 +2,7 @@ package x11
 
 import (
 	"os"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -119,4 +120,59 @@ func TestStartAppWithEnv(t *testing.T) {
 	
 	// Clean up
 	client.StopApp(pid)
-}
\ No newline at end of file
+}
+
+// TestAppReaping checks that exited children are reaped and their status recorded
+func TestAppReaping(t *testing.T) {
+	client := &Client{}
+
+	pid, err := client.StartApp("sh", []string{"-c", "exit 3"})
+	if err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, ok := client.AppStatus(pid)
+		if !ok {
+			t.Fatal("started app is not tracked")
+		}
+		if status.Exited {
+			if status.ExitCode != 3 {
+				t.Errorf("expected exit code 3, got %d", status.ExitCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("app was not reaped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The zombie must be gone: waiting on it again fails
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, syscall.WNOHANG, nil); err == nil {
+		t.Error("child was still waitable after reaping")
+	}
+}
+
+// TestStopTrackedApp checks that StopApp records the terminating signal
+func TestStopTrackedApp(t *testing.T) {
+	client := &Client{}
+
+	pid, err := client.StartApp("sleep", []string{"30"})
+	if err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+	if err := client.StopApp(pid); err != nil {
+		t.Fatalf("StopApp failed: %v", err)
+	}
+
+	status, _ := client.AppStatus(pid)
+	if !status.Exited || status.Signal != syscall.SIGTERM.String() {
+		t.Errorf("expected app to exit from SIGTERM, got %+v", status)
+	}
+	if apps := client.ListApps(); len(apps) != 1 || apps[0].PID != pid {
+		t.Errorf("unexpected app list: %+v", apps)
+	}
+}
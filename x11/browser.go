@@ -0,0 +1,170 @@
+package x11
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// browserKinds maps browser executables to the profile format they use
+var browserKinds = map[string]string{
+	"firefox":              "firefox",
+	"firefox-esr":          "firefox",
+	"librewolf":            "firefox",
+	"chromium":             "chromium",
+	"chromium-browser":     "chromium",
+	"google-chrome":        "chromium",
+	"google-chrome-stable": "chromium",
+	"brave-browser":        "chromium",
+	"microsoft-edge":       "chromium",
+}
+
+// firefoxPrefs go into the user.js of throwaway Firefox profiles. They turn
+// off everything that shows up uninvited on a fresh profile: the default
+// browser check, welcome and what's-new pages, the data reporting notice,
+// session restore after a crash, update checks and the quit warnings.
+var firefoxPrefs = map[string]any{
+	"browser.shell.checkDefaultBrowser":                                  false,
+	"browser.startup.homepage_override.mstone":                           "ignore",
+	"startup.homepage_welcome_url":                                       "",
+	"startup.homepage_welcome_url.additional":                            "",
+	"browser.startup.firstrunSkipsHomepage":                              true,
+	"browser.aboutwelcome.enabled":                                       false,
+	"trailhead.firstrun.didSeeAboutWelcome":                              true,
+	"browser.messaging-system.whatsNewPanel.enabled":                     false,
+	"browser.uitour.enabled":                                             false,
+	"datareporting.policy.dataSubmissionEnabled":                         false,
+	"datareporting.policy.dataSubmissionPolicyBypassNotification":        true,
+	"toolkit.telemetry.reportingpolicy.firstRun":                         false,
+	"browser.sessionstore.resume_from_crash":                             false,
+	"browser.sessionstore.max_resumed_crashes":                           0,
+	"toolkit.startup.max_resumed_crashes":                                -1,
+	"browser.startup.page":                                               0,
+	"app.update.auto":                                                    false,
+	"app.update.checkInstallTime":                                        false,
+	"app.update.disabledForTesting":                                      true,
+	"extensions.update.enabled":                                          false,
+	"extensions.getAddons.cache.enabled":                                 false,
+	"browser.tabs.warnOnClose":                                           false,
+	"browser.tabs.warnOnCloseOtherTabs":                                  false,
+	"browser.warnOnQuit":                                                 false,
+	"browser.warnOnQuitShortcut":                                         false,
+	"browser.newtabpage.activity-stream.feeds.section.topstories":        false,
+	"browser.newtabpage.activity-stream.showSponsoredTopSites":           false,
+	"signon.rememberSignons":                                             false,
+	"browser.translations.automaticallyPopup":                            false,
+	"browser.contentblocking.introCount":                                 99,
+	"privacy.trackingprotection.introCount":                              99,
+	"browser.download.panel.shown":                                       true,
+	"browser.safebrowsing.downloads.remote.enabled":                      false,
+	"network.captive-portal-service.enabled":                             false,
+	"browser.startup.homepage":                                           "about:blank",
+	"browser.newtabpage.enabled":                                         false,
+	"browser.newtabpage.activity-stream.asrouter.userprefs.cfr.addons":   false,
+	"browser.newtabpage.activity-stream.asrouter.userprefs.cfr.features": false,
+}
+
+// chromiumFlags keep Chromium-based browsers from showing first-run,
+// default browser, crash restore and keyring prompts on a fresh profile
+var chromiumFlags = []string{
+	"--no-first-run",
+	"--no-default-browser-check",
+	"--disable-session-crashed-bubble",
+	"--hide-crash-restore-bubble",
+	"--disable-component-update",
+	"--disable-background-networking",
+	"--disable-sync",
+	"--disable-features=Translate,MediaRouter,OptimizationHints",
+	"--password-store=basic",
+	"--check-for-update-interval=31536000",
+}
+
+// BrowserNames returns the browsers StartBrowser knows
+func BrowserNames() []string {
+	names := make([]string, 0, len(browserKinds))
+	for name := range browserKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsBrowser returns true if StartBrowser knows how to set up program
+func IsBrowser(program string) bool {
+	_, ok := browserKinds[filepath.Base(program)]
+	return ok
+}
+
+// StartBrowser starts Firefox or a Chromium-based browser with a throwaway
+// profile that has first-run wizards, session restore prompts and update
+// nags turned off, opening url if it is not empty. The profile is deleted
+// when the browser exits or the client is closed. args are passed before
+// the profile arguments.
+func (c *Client) StartBrowser(program string, args []string, url string) (int, error) {
+	return c.startBrowser(program, args, url, nil)
+}
+
+// startBrowser is StartBrowser with extra environment variables
+func (c *Client) startBrowser(program string, args []string, url string, extraEnv map[string]string) (int, error) {
+	kind, ok := browserKinds[filepath.Base(program)]
+	if !ok {
+		return 0, fmt.Errorf("don't know how to set up a profile for %s (supported: %s)", program, strings.Join(BrowserNames(), ", "))
+	}
+
+	profile, err := os.MkdirTemp("", "mcp-x11-"+kind+"-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create browser profile: %w", err)
+	}
+
+	full := append([]string{}, args...)
+	env := make(map[string]string, len(extraEnv)+1)
+	for k, v := range extraEnv {
+		env[k] = v
+	}
+	switch kind {
+	case "firefox":
+		if err := os.WriteFile(filepath.Join(profile, "user.js"), []byte(firefoxUserJS()), 0o600); err != nil {
+			os.RemoveAll(profile)
+			return 0, fmt.Errorf("failed to write Firefox preferences: %w", err)
+		}
+		full = append(full, "--profile", profile, "--no-remote", "--new-instance")
+		env["MOZ_CRASHREPORTER_DISABLE"] = "1"
+	case "chromium":
+		// Port 0 lets Chromium pick a free port on 127.0.0.1, which it writes
+		// to DevToolsActivePort in the profile for WaitPageLoaded
+		full = append(full, "--user-data-dir="+profile, "--remote-debugging-port=0")
+		full = append(full, chromiumFlags...)
+	}
+	if url != "" {
+		full = append(full, url)
+	}
+
+	pid, err := c.startApp(program, full, env, AppStatus{Profile: profile})
+	if err != nil {
+		os.RemoveAll(profile)
+		return 0, err
+	}
+	return pid, nil
+}
+
+// firefoxUserJS renders firefoxPrefs as a user.js file, sorted so the file
+// is stable
+func firefoxUserJS() string {
+	names := make([]string, 0, len(firefoxPrefs))
+	for name := range firefoxPrefs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := firefoxPrefs[name]
+		if s, ok := value.(string); ok {
+			value = fmt.Sprintf("%q", s)
+		}
+		fmt.Fprintf(&b, "user_pref(%q, %v);\n", name, value)
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package x11
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFirefoxUserJS(t *testing.T) {
+	js := firefoxUserJS()
+	for _, want := range []string{
+		`user_pref("browser.shell.checkDefaultBrowser", false);`,
+		`user_pref("browser.startup.homepage_override.mstone", "ignore");`,
+		`user_pref("toolkit.startup.max_resumed_crashes", -1);`,
+	} {
+		if !strings.Contains(js, want) {
+			t.Errorf("user.js lacks %s", want)
+		}
+	}
+	if strings.Count(js, "\n") != len(firefoxPrefs) {
+		t.Errorf("user.js has %d lines, want %d", strings.Count(js, "\n"), len(firefoxPrefs))
+	}
+}
+
+func TestIsBrowser(t *testing.T) {
+	tests := map[string]bool{
+		"firefox":               true,
+		"/usr/bin/chromium":     true,
+		"google-chrome-stable":  true,
+		"xterm":                 false,
+		"/opt/firefox/firefox2": false,
+	}
+	for program, want := range tests {
+		if got := IsBrowser(program); got != want {
+			t.Errorf("IsBrowser(%q) = %v, want %v", program, got, want)
+		}
+	}
+}
+
+func TestStartBrowserCleansUpOnFailure(t *testing.T) {
+	if _, err := exec.LookPath("firefox"); err == nil {
+		t.Skip("firefox is installed")
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	c := &Client{}
+	if _, err := c.StartBrowser("firefox", nil, ""); err == nil {
+		t.Fatal("expected error for a missing browser")
+	}
+	if _, err := c.StartBrowser("xterm", nil, ""); err == nil {
+		t.Fatal("expected error for a program that is not a browser")
+	}
+	left, _ := filepath.Glob(filepath.Join(tmp, "mcp-x11-*"))
+	if len(left) != 0 {
+		t.Errorf("profiles left behind: %v", left)
+	}
+}
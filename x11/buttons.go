@@ -0,0 +1,111 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Pointer button numbers. 4-7 are the scroll wheel, 8 and 9 the side
+// buttons browsers and file managers bind to back and forward.
+const (
+	ButtonLeft        = 1
+	ButtonMiddle      = 2
+	ButtonRight       = 3
+	ButtonScrollUp    = 4
+	ButtonScrollDown  = 5
+	ButtonScrollLeft  = 6
+	ButtonScrollRight = 7
+	ButtonBack        = 8
+	ButtonForward     = 9
+)
+
+// maxButton is the highest button XTEST can fake; the detail field is a byte
+const maxButton = 255
+
+// buttonNames maps the names ParseButton accepts to button numbers
+var buttonNames = map[string]int{
+	"left":         ButtonLeft,
+	"middle":       ButtonMiddle,
+	"right":        ButtonRight,
+	"scroll_up":    ButtonScrollUp,
+	"scroll_down":  ButtonScrollDown,
+	"scroll_left":  ButtonScrollLeft,
+	"scroll_right": ButtonScrollRight,
+	"back":         ButtonBack,
+	"forward":      ButtonForward,
+}
+
+// ParseButton parses a button number or one of the names left, middle,
+// right, scroll_up, scroll_down, scroll_left, scroll_right, back and forward
+func ParseButton(s string) (int, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if button, ok := buttonNames[strings.NewReplacer("-", "_", " ", "_").Replace(name)]; ok {
+		return button, nil
+	}
+	button, err := strconv.Atoi(name)
+	if err != nil {
+		return 0, fmt.Errorf("invalid button %q: use a number or one of left, middle, right, scroll_up, scroll_down, scroll_left, scroll_right, back, forward", s)
+	}
+	return button, nil
+}
+
+// ButtonName returns the name of a button, or "" if it has none
+func ButtonName(button int) string {
+	for name, b := range buttonNames {
+		if b == button {
+			return name
+		}
+	}
+	return ""
+}
+
+// ValidateButton checks that button is one the pointer has. Buttons 1-3
+// always exist; for higher ones the server's pointer mapping is asked, and
+// if that fails only the XTEST range is checked.
+func (c *Client) ValidateButton(ctx context.Context, button int) error {
+	if button < 1 || button > maxButton {
+		return fmt.Errorf("button %d is out of range 1-%d", button, maxButton)
+	}
+	if button <= ButtonRight {
+		return nil
+	}
+	count, err := c.pointerButtons(ctx)
+	if err != nil {
+		slog.Debug("failed to get the pointer mapping", "err", err)
+		return nil
+	}
+	if button > count {
+		return fmt.Errorf("button %d is out of range: the pointer has %d buttons", button, count)
+	}
+	return nil
+}
+
+// pointerButtons returns how many buttons the core pointer has. The client
+// library lacks GetPointerMapping, so the request is sent by hand.
+func (c *Client) pointerButtons(ctx context.Context) (int, error) {
+	reply, err := await(c, ctx, func() ([]byte, error) {
+		seq := c.conn.SendRequest(x.RequestChecked, &x.ProtocolRequest{
+			Header: x.RequestHeader{Opcode: x.GetPointerMappingOpcode},
+		})
+		return c.conn.WaitForReply(seq)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return parsePointerMapping(reply)
+}
+
+// parsePointerMapping returns the number of buttons in a GetPointerMapping
+// reply, whose second byte is the length of the map that follows the 32-byte
+// header
+func parsePointerMapping(reply []byte) (int, error) {
+	if len(reply) < 32 || len(reply) < 32+int(reply[1]) {
+		return 0, fmt.Errorf("pointer mapping reply is too short (%d bytes)", len(reply))
+	}
+	return int(reply[1]), nil
+}
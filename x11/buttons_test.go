@@ -0,0 +1,72 @@
+package x11
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseButton(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"1", 1, false},
+		{"9", 9, false},
+		{"12", 12, false},
+		{"left", ButtonLeft, false},
+		{"Back", ButtonBack, false},
+		{"forward", ButtonForward, false},
+		{"scroll-up", ButtonScrollUp, false},
+		{"scroll down", ButtonScrollDown, false},
+		{"sideways", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseButton(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseButton(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestButtonName(t *testing.T) {
+	for name, button := range buttonNames {
+		if got := ButtonName(button); got != name {
+			t.Errorf("ButtonName(%d) = %q, want %q", button, got, name)
+		}
+	}
+	if got := ButtonName(12); got != "" {
+		t.Errorf("ButtonName(12) = %q, want no name", got)
+	}
+}
+
+func TestValidateButtonRange(t *testing.T) {
+	// Out-of-range and basic buttons are decided without asking the server
+	c := &Client{}
+	for _, button := range []int{0, -1, 256} {
+		if err := c.ValidateButton(context.Background(), button); err == nil {
+			t.Errorf("ValidateButton(%d) succeeded, want an error", button)
+		}
+	}
+	for _, button := range []int{ButtonLeft, ButtonMiddle, ButtonRight} {
+		if err := c.ValidateButton(context.Background(), button); err != nil {
+			t.Errorf("ValidateButton(%d) = %v", button, err)
+		}
+	}
+}
+
+func TestParsePointerMapping(t *testing.T) {
+	reply := make([]byte, 32+12)
+	reply[1] = 10
+	if n, err := parsePointerMapping(reply); err != nil || n != 10 {
+		t.Errorf("parsePointerMapping() = %d, %v; want 10", n, err)
+	}
+	reply[1] = 20
+	if _, err := parsePointerMapping(reply); err == nil {
+		t.Error("a reply shorter than its map must be rejected")
+	}
+	if _, err := parsePointerMapping(reply[:8]); err == nil {
+		t.Error("a reply without a full header must be rejected")
+	}
+}
@@ -0,0 +1,253 @@
+package x11
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cdpTimeout bounds each DevTools call
+const cdpTimeout = 3 * time.Second
+
+// cdpTarget is a page listed by the DevTools HTTP endpoint
+type cdpTarget struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"webSocketDebuggerUrl"`
+}
+
+// devToolsPage returns the DevTools WebSocket URL of the page shown in a
+// browser started by StartBrowser, preferring the page whose title starts
+// the window title. Only Chromium-based browsers expose DevTools this way.
+func (c *Client) devToolsPage(ctx context.Context, pid int, windowTitle string) (string, error) {
+	status, ok := c.AppStatus(pid)
+	if !ok || status.Profile == "" {
+		return "", fmt.Errorf("PID %d is not a browser started with a throwaway profile", pid)
+	}
+	// Chromium writes the port it picked for --remote-debugging-port=0 here
+	data, err := os.ReadFile(filepath.Join(status.Profile, "DevToolsActivePort"))
+	if err != nil {
+		return "", fmt.Errorf("DevTools not available: %w", err)
+	}
+	port, _, _ := strings.Cut(string(data), "\n")
+
+	ctx, cancel := context.WithTimeout(ctx, cdpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:"+strings.TrimSpace(port)+"/json/list", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to list DevTools targets: %w", err)
+	}
+	defer resp.Body.Close()
+	var targets []cdpTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return "", fmt.Errorf("failed to list DevTools targets: %w", err)
+	}
+
+	var first string
+	for _, t := range targets {
+		if t.Type != "page" || t.URL == "" {
+			continue
+		}
+		if t.Title != "" && strings.HasPrefix(windowTitle, t.Title) {
+			return t.URL, nil
+		}
+		if first == "" {
+			first = t.URL
+		}
+	}
+	if first == "" {
+		return "", fmt.Errorf("browser has no open pages")
+	}
+	return first, nil
+}
+
+// cdpEvaluate evaluates a JavaScript expression in a DevTools page and
+// returns its value as JSON
+func cdpEvaluate(ctx context.Context, wsURL, expression string) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, cdpTimeout)
+	defer cancel()
+	conn, err := dialWebSocket(ctx, wsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request, err := json.Marshal(map[string]any{
+		"id":     1,
+		"method": "Runtime.evaluate",
+		"params": map[string]any{"expression": expression, "returnByValue": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.write(request); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := conn.read()
+		if err != nil {
+			return nil, err
+		}
+		var reply struct {
+			ID     int `json:"id"`
+			Result struct {
+				Result struct {
+					Value json.RawMessage `json:"value"`
+				} `json:"result"`
+				Exception *struct {
+					Text string `json:"text"`
+				} `json:"exceptionDetails"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(msg, &reply); err != nil || reply.ID != 1 {
+			continue // An event
+		}
+		switch {
+		case reply.Error != nil:
+			return nil, fmt.Errorf("DevTools error: %s", reply.Error.Message)
+		case reply.Result.Exception != nil:
+			return nil, fmt.Errorf("script failed: %s", reply.Result.Exception.Text)
+		}
+		return reply.Result.Result.Value, nil
+	}
+}
+
+// webSocket is the minimal client side of RFC 6455 that DevTools needs:
+// unfragmented text messages out, possibly fragmented messages in
+type webSocket struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialWebSocket opens a WebSocket connection to a ws:// URL
+func dialWebSocket(ctx context.Context, rawURL string) (*webSocket, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "ws" {
+		return nil, fmt.Errorf("invalid DevTools URL: %s", rawURL)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DevTools: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", u.RequestURI(), u.Host, key)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("DevTools handshake failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("DevTools handshake failed: %s", resp.Status)
+	}
+	return &webSocket{conn: conn, r: r}, nil
+}
+
+// write sends a masked text frame, as clients must
+func (ws *webSocket) write(payload []byte) error {
+	header := []byte{0x81} // FIN, text
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xffff:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	rand.Read(mask[:])
+	header = append(header, mask[:]...)
+
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	if _, err := ws.conn.Write(append(header, masked...)); err != nil {
+		return fmt.Errorf("failed to send to DevTools: %w", err)
+	}
+	return nil
+}
+
+// read returns the next data message, skipping control frames
+func (ws *webSocket) read() ([]byte, error) {
+	var message []byte
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(ws.r, head[:]); err != nil {
+			return nil, fmt.Errorf("failed to read from DevTools: %w", err)
+		}
+		fin, opcode := head[0]&0x80 != 0, head[0]&0x0f
+		n := uint64(head[1] & 0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(ws.r, ext[:]); err != nil {
+				return nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(ws.r, ext[:]); err != nil {
+				return nil, err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		if n > maxSelectionBytes {
+			return nil, fmt.Errorf("DevTools message too large (%d bytes)", n)
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(ws.r, payload); err != nil {
+			return nil, fmt.Errorf("failed to read from DevTools: %w", err)
+		}
+
+		switch opcode {
+		case 0x8:
+			return nil, errors.New("DevTools closed the connection")
+		case 0x9, 0xa: // Ping and pong; DevTools doesn't expect answers
+			continue
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// Close closes the connection
+func (ws *webSocket) Close() error {
+	return ws.conn.Close()
+}
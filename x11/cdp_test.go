@@ -0,0 +1,172 @@
+package x11
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDevTools serves /json/list and answers Runtime.evaluate on
+// /devtools/page/<id> with value, sent after an event and split over two
+// frames
+func fakeDevTools(t *testing.T, value string) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.TrimPrefix(srv.URL, "http://")
+		if r.URL.Path == "/json/list" {
+			fmt.Fprintf(w, `[{"type":"service_worker","title":"sw","webSocketDebuggerUrl":"ws://%[1]s/devtools/page/sw"},
+				{"type":"page","title":"Other","webSocketDebuggerUrl":"ws://%[1]s/devtools/page/other"},
+				{"type":"page","title":"Example Domain","webSocketDebuggerUrl":"ws://%[1]s/devtools/page/example"}]`, host)
+			return
+		}
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Key") == "" {
+			http.Error(w, "not a websocket", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+
+		request, err := readClientFrame(rw.Reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var call struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(request, &call); err != nil || call.Method != "Runtime.evaluate" {
+			t.Errorf("unexpected request %s", request)
+			return
+		}
+
+		writeServerFrame(rw, 0x81, []byte(`{"method":"Runtime.consoleAPICalled","params":{}}`))
+		writeServerFrame(rw, 0x89, nil) // Ping
+		reply := fmt.Sprintf(`{"id":%d,"result":{"result":{"type":"string","value":%s}}}`, call.ID, value)
+		half := len(reply) / 2
+		writeServerFrame(rw, 0x01, []byte(reply[:half]))
+		writeServerFrame(rw, 0x80, []byte(reply[half:]))
+		rw.Flush()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readClientFrame reads one masked frame
+func readClientFrame(r *bufio.Reader) ([]byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	if head[1]&0x80 == 0 {
+		return nil, fmt.Errorf("client frame is not masked")
+	}
+	n := int(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		n = int(binary.BigEndian.Uint64(ext[:]))
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(r, mask[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return payload, nil
+}
+
+// writeServerFrame writes an unmasked frame with a short payload
+func writeServerFrame(w io.Writer, head byte, payload []byte) {
+	w.Write(append([]byte{head, byte(len(payload))}, payload...))
+}
+
+func TestCDPEvaluate(t *testing.T) {
+	srv := fakeDevTools(t, `"{\"ready\":\"complete\",\"resources\":3}"`)
+	wsURL := "ws://" + strings.TrimPrefix(srv.URL, "http://") + "/devtools/page/example"
+
+	value, err := cdpEvaluate(context.Background(), wsURL, pageStateScript)
+	if err != nil {
+		t.Fatalf("cdpEvaluate() error = %v", err)
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil || text != `{"ready":"complete","resources":3}` {
+		t.Errorf("cdpEvaluate() = %s", value)
+	}
+}
+
+func TestCDPEvaluateErrors(t *testing.T) {
+	if _, err := cdpEvaluate(context.Background(), "http://127.0.0.1:1/", "1"); err == nil {
+		t.Error("cdpEvaluate accepted a non-ws URL")
+	}
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	wsURL := "ws://" + strings.TrimPrefix(srv.URL, "http://") + "/devtools/page/x"
+	if _, err := cdpEvaluate(context.Background(), wsURL, "1"); err == nil || !strings.Contains(err.Error(), "handshake") {
+		t.Errorf("cdpEvaluate() against a plain HTTP server error = %v", err)
+	}
+}
+
+func TestDevToolsPage(t *testing.T) {
+	srv := fakeDevTools(t, `""`)
+	_, port, _ := strings.Cut(strings.TrimPrefix(srv.URL, "http://"), ":")
+
+	profile := t.TempDir()
+	if err := os.WriteFile(filepath.Join(profile, "DevToolsActivePort"), []byte(port+"\n/devtools/browser/abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{}
+	c.apps.apps = map[int]*trackedApp{
+		100: {status: AppStatus{PID: 100, Profile: profile}},
+		200: {status: AppStatus{PID: 200}},
+	}
+
+	tests := []struct {
+		name    string
+		pid     int
+		title   string
+		want    string // Suffix of the URL
+		wantErr bool
+	}{
+		{"title match", 100, "Example Domain - Chromium", "/page/example", false},
+		{"first page", 100, "Something else", "/page/other", false},
+		{"no profile", 200, "", "", true},
+		{"not started", 300, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.devToolsPage(context.Background(), tt.pid, tt.title)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("devToolsPage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.HasSuffix(got, tt.want) {
+				t.Errorf("devToolsPage() = %q, want suffix %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Selections that hold text to paste
+const (
+	SelectionClipboard = "CLIPBOARD" // Filled by copy, pasted with ctrl+v
+	SelectionPrimary   = "PRIMARY"   // Filled by selecting text, pasted with a middle click
+)
+
+// pasteTimeout bounds waiting for the focused application to fetch the
+// text PasteTextContext put on the clipboard
+const pasteTimeout = 2 * time.Second
+
+// ParseSelection returns the X selection named clipboard or primary, in any
+// case; an empty name is the clipboard
+func ParseSelection(name string) (string, error) {
+	switch strings.ToUpper(name) {
+	case "", SelectionClipboard:
+		return SelectionClipboard, nil
+	case SelectionPrimary:
+		return SelectionPrimary, nil
+	}
+	return "", fmt.Errorf("unknown selection %q: use clipboard or primary", name)
+}
+
+// GetClipboard returns the text on the clipboard, empty if no application
+// holds one
+func (c *Client) GetClipboard() (string, error) {
+	return c.GetSelectionContext(context.Background(), SelectionClipboard)
+}
+
+// SetClipboard puts text on the clipboard, so ctrl+v in any application
+// pastes it. The text is served until another application copies something.
+func (c *Client) SetClipboard(text string) error {
+	return c.SetSelectionContext(context.Background(), SelectionClipboard, text)
+}
+
+// GetSelectionContext is like ReadSelectionContext, but a selection no
+// application holds is empty rather than an error
+func (c *Client) GetSelectionContext(ctx context.Context, name string) (string, error) {
+	selection := c.getAtom(ctx, name)
+	if selection == 0 {
+		return "", fmt.Errorf("failed to intern selection atom %s", name)
+	}
+	owner, err := await(c, ctx, func() (*x.GetSelectionOwnerReply, error) {
+		return x.GetSelectionOwner(c.conn, selection).Reply(c.conn)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get selection owner: %w", err)
+	}
+	if owner.Owner == x.None {
+		return "", nil
+	}
+	return c.ReadSelectionContext(ctx, name)
+}
+
+// PasteTextContext enters text in the focused window by putting it on the
+// clipboard and pressing ctrl+v. Unlike typing it can't drop characters and
+// needs no keysyms, so any Unicode text works. It waits until another
+// application fetched the text, failing if none did, and leaves the text on
+// the clipboard. Terminals that paste with ctrl+shift+v get a literal ctrl+v.
+func (c *Client) PasteTextContext(ctx context.Context, text string) error {
+	return c.pasteText(ctx, text, false)
+}
+
+// PasteSecretContext pastes secret like PasteTextContext, but marks it with
+// the x-kde-passwordManagerHint target so clipboard managers that honor it
+// don't keep a copy
+func (c *Client) PasteSecretContext(ctx context.Context, secret string) error {
+	return c.pasteText(ctx, secret, true)
+}
+
+func (c *Client) pasteText(ctx context.Context, text string, secret bool) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	if err := c.setSelection(ctx, SelectionClipboard, text, secret); err != nil {
+		return err
+	}
+	// A fetch before the key press, e.g. by a clipboard manager reacting
+	// to the new owner, doesn't count
+	select {
+	case <-c.selection.pasted:
+	default:
+	}
+	if err := c.KeyComboContext(ctx, "ctrl+v"); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(pasteTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.selection.pasted:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("no application fetched the clipboard within %s of ctrl+v; the focused window may not accept pasting", pasteTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
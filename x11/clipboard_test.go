@@ -0,0 +1,139 @@
+package x11
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", SelectionClipboard, false},
+		{"clipboard", SelectionClipboard, false},
+		{"CLIPBOARD", SelectionClipboard, false},
+		{"Primary", SelectionPrimary, false},
+		{"secondary", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSelection(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseSelection(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestClipboard(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if text, err := client.GetClipboard(); err != nil || text != "" {
+		t.Fatalf("GetClipboard of an empty clipboard = %q, %v", text, err)
+	}
+	const text = "Grüße → clipboard\nsecond line"
+	if err := client.SetClipboard(text); err != nil {
+		t.Fatalf("SetClipboard failed: %v", err)
+	}
+	if got, err := client.GetClipboard(); err != nil || got != text {
+		t.Errorf("GetClipboard = %q, %v, want %q", got, err, text)
+	}
+	// PRIMARY is a separate selection
+	if got, err := client.GetSelectionContext(context.Background(), SelectionPrimary); err != nil || got != "" {
+		t.Errorf("PRIMARY = %q, %v, want it empty", got, err)
+	}
+}
+
+func TestPasteText(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// Nothing on the bare display takes the paste
+	if err := client.PasteTextContext(ctx, "unclaimed"); err == nil || !strings.Contains(err.Error(), "no application fetched") {
+		t.Errorf("PasteTextContext without a taker = %v, want an error", err)
+	}
+
+	// Another application reading the clipboard after ctrl+v completes it
+	other, err := ConnectWithOptions(ConnectOptions{Display: client.GetDisplay()})
+	if err != nil {
+		t.Fatalf("Failed to connect a second client: %v", err)
+	}
+	defer other.Close()
+	const text = "ünïcödé ✓"
+	fetched := make(chan string, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		got, _ := other.GetClipboard()
+		fetched <- got
+	}()
+	if err := client.PasteTextContext(ctx, text); err != nil {
+		t.Fatalf("PasteTextContext failed: %v", err)
+	}
+	if got := <-fetched; got != text {
+		t.Errorf("fetched %q, want %q", got, text)
+	}
+}
+
+func TestPasteSecret(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	other, err := ConnectWithOptions(ConnectOptions{Display: client.GetDisplay()})
+	if err != nil {
+		t.Fatalf("Failed to connect a second client: %v", err)
+	}
+	defer other.Close()
+	ctx := context.Background()
+
+	// Clipboard managers see the password hint among the targets
+	hinted := make(chan bool, 1)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		targets, _ := other.selectionTargets(ctx, SelectionClipboard)
+		hinted <- slices.Contains(targets, other.getAtom(ctx, passwordHint))
+		other.GetClipboard()
+	}()
+	if err := client.PasteSecretContext(ctx, "hunter2"); err != nil {
+		t.Fatalf("PasteSecretContext failed: %v", err)
+	}
+	if !<-hinted {
+		t.Errorf("secret clipboard doesn't offer %s", passwordHint)
+	}
+
+	// Plain text has no hint
+	if err := client.SetClipboard("plain"); err != nil {
+		t.Fatalf("SetClipboard failed: %v", err)
+	}
+	targets, err := other.selectionTargets(ctx, SelectionClipboard)
+	if err != nil {
+		t.Fatalf("selectionTargets failed: %v", err)
+	}
+	if slices.Contains(targets, other.getAtom(ctx, passwordHint)) {
+		t.Errorf("plain clipboard offers %s", passwordHint)
+	}
+}
@@ -0,0 +1,154 @@
+package x11
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/xfixes"
+)
+
+// passwordHint is the target password managers such as KeePassXC offer
+// with a copied password, asking clipboard managers not to keep it
+const passwordHint = "x-kde-passwordManagerHint"
+
+// clipboardReadTimeout bounds reading a new clipboard from its owner
+const clipboardReadTimeout = 2 * time.Second
+
+// ClipboardEntry is text an application put on the clipboard
+type ClipboardEntry struct {
+	Time  time.Time // When it was copied
+	Text  string
+	Owner x.Window // Window owning the clipboard
+	Class string   // WM_CLASS of the owner, if it has one
+}
+
+// WatchClipboard calls fn with the text of the clipboard whenever an
+// application copies something, told by XFixes selection notifications. A
+// cleared clipboard, content that isn't text, passwords marked by their
+// password manager and text this client put there itself are skipped. fn runs on its own goroutine, one copy at a
+// time.
+func (c *Client) WatchClipboard(fn func(ClipboardEntry)) error {
+	ext := c.conn.GetExtensionData(xfixes.Ext())
+	if ext == nil || !ext.Present {
+		return fmt.Errorf("the XFIXES extension is not available")
+	}
+
+	ctx := context.Background()
+	_, err := await(c, ctx, func() (*xfixes.QueryVersionReply, error) {
+		return xfixes.QueryVersion(c.conn, xfixes.MajorVersion, xfixes.MinorVersion).Reply(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query XFIXES version: %w", err)
+	}
+	selection := c.getAtom(ctx, SelectionClipboard)
+	if selection == 0 {
+		return fmt.Errorf("failed to intern selection atom %s", SelectionClipboard)
+	}
+	err = awaitCheck(c, ctx, func() error {
+		return xfixes.SelectSelectionInputChecked(c.conn, c.root, selection, xfixes.SelectionEventMaskSetSelectionOwner).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch the clipboard: %w", err)
+	}
+
+	// Reading the clipboard needs replies, which must not block the event
+	// loop; copies beyond the queue are dropped
+	copies := make(chan ClipboardEntry, 16)
+	notifyCode := ext.FirstEvent + xfixes.SelectionNotifyEventCode
+	c.addEventHandler(func(ev x.GenericEvent) {
+		if ev.GetEventCode() != notifyCode {
+			return
+		}
+		nev, err := xfixes.NewSelectionNotifyEvent(ev)
+		if err != nil || nev.Selection != selection || nev.Owner == x.None {
+			return
+		}
+		select {
+		case copies <- ClipboardEntry{Time: time.Now(), Owner: nev.Owner}:
+		default:
+		}
+	})
+	go func() {
+		for entry := range copies {
+			if c.readClipboardEntry(&entry) {
+				fn(entry)
+			}
+		}
+	}()
+	return nil
+}
+
+// readClipboardEntry fills in the text and owner of a new clipboard and
+// reports whether it is worth keeping
+func (c *Client) readClipboardEntry(entry *ClipboardEntry) bool {
+	// Reading our own clipboard would count as an application pasting it
+	// and keep text typed with paste, which may be secret
+	c.selection.mu.Lock()
+	own := c.selection.win != 0 && entry.Owner == c.selection.win
+	c.selection.mu.Unlock()
+	if own {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardReadTimeout)
+	defer cancel()
+
+	targets, err := c.selectionTargets(ctx, SelectionClipboard)
+	if err != nil {
+		slog.Debug("failed to read clipboard targets", "err", err)
+	}
+	if hint := c.getAtom(ctx, passwordHint); hint != 0 && slices.Contains(targets, hint) {
+		return false
+	}
+	text, err := c.GetSelectionContext(ctx, SelectionClipboard)
+	if err != nil {
+		slog.Debug("failed to read clipboard", "err", err)
+		return false
+	}
+	if text == "" {
+		return false
+	}
+	entry.Text = text
+	entry.Class = c.getWindowClass(ctx, entry.Owner)
+	return true
+}
+
+// selectionTargets returns the formats the owner of a selection offers
+func (c *Client) selectionTargets(ctx context.Context, name string) ([]x.Atom, error) {
+	c.selection.mu.Lock()
+	defer c.selection.mu.Unlock()
+
+	win, err := c.selectionWindow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	selection := c.getAtom(ctx, name)
+	targets := c.getAtom(ctx, "TARGETS")
+	property := c.getAtom(ctx, "MCP_X11_SELECTION")
+	if selection == 0 || targets == 0 || property == 0 {
+		return nil, fmt.Errorf("failed to intern selection atoms")
+	}
+	ev, err := c.convertSelection(ctx, win, selection, targets, property)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Property == x.None {
+		return nil, nil
+	}
+	data, err := c.readSelectionProperty(ctx, win, property)
+	if err != nil {
+		return nil, err
+	}
+
+	// The connection is little-endian
+	atoms := make([]x.Atom, 0, len(data)/4)
+	for i := 0; i+4 <= len(data); i += 4 {
+		atoms = append(atoms, x.Atom(binary.LittleEndian.Uint32([]byte(data[i:i+4]))))
+	}
+	return atoms, nil
+}
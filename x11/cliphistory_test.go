@@ -0,0 +1,51 @@
+package x11
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchClipboard(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	copies := make(chan ClipboardEntry, 4)
+	if err := client.WatchClipboard(func(entry ClipboardEntry) { copies <- entry }); err != nil {
+		t.Fatalf("WatchClipboard failed: %v", err)
+	}
+
+	// Text this client puts on the clipboard is not kept
+	if err := client.SetClipboard("own text"); err != nil {
+		t.Fatalf("SetClipboard failed: %v", err)
+	}
+	select {
+	case entry := <-copies:
+		t.Errorf("WatchClipboard saw own copy %+v", entry)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	other, err := ConnectWithOptions(ConnectOptions{Display: client.GetDisplay()})
+	if err != nil {
+		t.Fatalf("Failed to connect a second client: %v", err)
+	}
+	defer other.Close()
+	if err := other.SetClipboard("copied text"); err != nil {
+		t.Fatalf("SetClipboard failed: %v", err)
+	}
+
+	select {
+	case entry := <-copies:
+		if entry.Text != "copied text" || entry.Time.IsZero() {
+			t.Errorf("WatchClipboard saw %+v, want the text set by the other client", entry)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no copy reported")
+	}
+}
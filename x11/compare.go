@@ -0,0 +1,164 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Image comparison parameters
+const (
+	pixelTolerance = 16 // Largest per-channel difference still counted as equal
+	ssimBlock      = 8  // Side of the blocks SSIM is computed over
+
+	// SSIM stabilizers for 8-bit luminance
+	ssimC1 = (0.01 * 255) * (0.01 * 255)
+	ssimC2 = (0.03 * 255) * (0.03 * 255)
+)
+
+// ImageComparison describes how similar two images are
+type ImageComparison struct {
+	PixelSimilarity      float64     // Fraction of pixels equal within tolerance, 0 to 1
+	StructuralSimilarity float64     // Mean luminance SSIM over 8x8 blocks of the overlap, 1 if identical
+	ChangedPixels        int         // Pixels that differ, including those outside the overlap
+	SizeMismatch         bool        // The images differ in size; they were aligned at the top left
+	Diff                 *image.RGBA // Dimmed copy of the first image with differing pixels in red
+}
+
+// CompareWindows captures two windows and compares their contents
+func (c *Client) CompareWindows(ctx context.Context, a, b x.Window) (ImageComparison, error) {
+	for _, win := range []x.Window{a, b} {
+		if err := c.EnsureVisible(ctx, win); err != nil {
+			return ImageComparison{}, err
+		}
+	}
+	shotA, err := c.captureWindow(ctx, a)
+	if err != nil {
+		return ImageComparison{}, fmt.Errorf("failed to capture window 0x%x: %w", uint32(a), err)
+	}
+	defer c.frames.put(shotA)
+
+	shotB, err := c.captureWindow(ctx, b)
+	if err != nil {
+		return ImageComparison{}, fmt.Errorf("failed to capture window 0x%x: %w", uint32(b), err)
+	}
+	defer c.frames.put(shotB)
+
+	return CompareImages(shotA, shotB), nil
+}
+
+// CompareImages compares two images pixel by pixel and structurally. Images
+// of different sizes are aligned at their top-left corners and the pixels
+// outside the overlap count as changed.
+func CompareImages(a, b image.Image) ImageComparison {
+	ra, rb := toRGBA(a), toRGBA(b)
+	wa, ha := ra.Rect.Dx(), ra.Rect.Dy()
+	wb, hb := rb.Rect.Dx(), rb.Rect.Dy()
+	width, height := max(wa, wb), max(ha, hb)
+	overlapW, overlapH := min(wa, wb), min(ha, hb)
+
+	result := ImageComparison{
+		SizeMismatch: wa != wb || ha != hb,
+		Diff:         image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+	if width == 0 || height == 0 {
+		result.PixelSimilarity = 1
+		result.StructuralSimilarity = 1
+		return result
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			d := result.Diff.PixOffset(x, y)
+			if x >= overlapW || y >= overlapH {
+				result.ChangedPixels++
+				copy(result.Diff.Pix[d:d+4], []uint8{0xff, 0, 0, 0xff})
+				continue
+			}
+			pa := ra.Pix[ra.PixOffset(x, y):]
+			pb := rb.Pix[rb.PixOffset(x, y):]
+			if pixelsDiffer(pa, pb) {
+				result.ChangedPixels++
+				copy(result.Diff.Pix[d:d+4], []uint8{0xff, 0, 0, 0xff})
+				continue
+			}
+			// Unchanged pixels are shown as dimmed gray
+			gray := uint8(luminance(pa) / 3)
+			copy(result.Diff.Pix[d:d+4], []uint8{gray, gray, gray, 0xff})
+		}
+	}
+
+	result.PixelSimilarity = 1 - float64(result.ChangedPixels)/float64(width*height)
+	result.StructuralSimilarity = ssim(ra, rb, overlapW, overlapH)
+	return result
+}
+
+// pixelsDiffer reports whether two RGBA pixels differ beyond the tolerance
+func pixelsDiffer(a, b []uint8) bool {
+	for i := 0; i < 3; i++ {
+		d := int(a[i]) - int(b[i])
+		if d > pixelTolerance || d < -pixelTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// luminance returns the Rec. 601 luma of an RGBA pixel
+func luminance(p []uint8) float64 {
+	return 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+}
+
+// ssim returns the mean structural similarity of the top-left width x height
+// area of two images, computed on luminance over ssimBlock-sized blocks
+func ssim(a, b *image.RGBA, width, height int) float64 {
+	if width == 0 || height == 0 {
+		return 0
+	}
+
+	var total float64
+	blocks := 0
+	for by := 0; by < height; by += ssimBlock {
+		for bx := 0; bx < width; bx += ssimBlock {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			n := 0
+			for y := by; y < min(by+ssimBlock, height); y++ {
+				for x := bx; x < min(bx+ssimBlock, width); x++ {
+					la := luminance(a.Pix[a.PixOffset(x, y):])
+					lb := luminance(b.Pix[b.PixOffset(x, y):])
+					sumA += la
+					sumB += lb
+					sumAA += la * la
+					sumBB += lb * lb
+					sumAB += la * lb
+					n++
+				}
+			}
+			fn := float64(n)
+			meanA, meanB := sumA/fn, sumB/fn
+			varA := sumAA/fn - meanA*meanA
+			varB := sumBB/fn - meanB*meanB
+			cov := sumAB/fn - meanA*meanB
+
+			total += ((2*meanA*meanB + ssimC1) * (2*cov + ssimC2)) /
+				((meanA*meanA + meanB*meanB + ssimC1) * (varA + varB + ssimC2))
+			blocks++
+		}
+	}
+	return total / float64(blocks)
+}
+
+// toRGBA returns img as an RGBA image whose bounds start at the origin,
+// copying only if needed
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,89 @@
+package x11
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// checkerboard returns a w x h image of alternating 4x4 black and white squares
+func checkerboard(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{0, 0, 0, 255}
+			if (x/4+y/4)%2 == 0 {
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCompareImagesIdentical(t *testing.T) {
+	a, b := checkerboard(32, 32), checkerboard(32, 32)
+	cmp := CompareImages(a, b)
+	if cmp.PixelSimilarity != 1 || cmp.ChangedPixels != 0 {
+		t.Errorf("identical images: similarity %v, changed %d", cmp.PixelSimilarity, cmp.ChangedPixels)
+	}
+	if math.Abs(cmp.StructuralSimilarity-1) > 1e-9 {
+		t.Errorf("identical images: SSIM = %v, want 1", cmp.StructuralSimilarity)
+	}
+	if cmp.SizeMismatch {
+		t.Error("unexpected size mismatch")
+	}
+}
+
+func TestCompareImagesChanged(t *testing.T) {
+	a, b := checkerboard(32, 32), checkerboard(32, 32)
+	// Invert one 8x8 area: 64 of 1024 pixels
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			p := b.RGBAAt(x, y)
+			b.SetRGBA(x, y, color.RGBA{255 - p.R, 255 - p.G, 255 - p.B, 255})
+		}
+	}
+	// A small shift within tolerance is not a change
+	b.SetRGBA(20, 20, color.RGBA{250, 250, 250, 255})
+
+	cmp := CompareImages(a, b)
+	if cmp.ChangedPixels != 64 {
+		t.Errorf("changed = %d, want 64", cmp.ChangedPixels)
+	}
+	if want := 1 - 64.0/1024; math.Abs(cmp.PixelSimilarity-want) > 1e-9 {
+		t.Errorf("similarity = %v, want %v", cmp.PixelSimilarity, want)
+	}
+	if cmp.StructuralSimilarity >= 1 || cmp.StructuralSimilarity < 0.5 {
+		t.Errorf("SSIM = %v, want between 0.5 and 1", cmp.StructuralSimilarity)
+	}
+	if got := cmp.Diff.RGBAAt(3, 3); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("changed pixel in diff = %v, want red", got)
+	}
+	if got := cmp.Diff.RGBAAt(20, 20); got.R != got.G {
+		t.Errorf("unchanged pixel in diff = %v, want gray", got)
+	}
+}
+
+func TestCompareImagesSizeMismatch(t *testing.T) {
+	cmp := CompareImages(checkerboard(16, 16), checkerboard(16, 8))
+	if !cmp.SizeMismatch {
+		t.Error("expected size mismatch")
+	}
+	if cmp.ChangedPixels != 16*8 {
+		t.Errorf("changed = %d, want %d", cmp.ChangedPixels, 16*8)
+	}
+	if cmp.Diff.Bounds() != image.Rect(0, 0, 16, 16) {
+		t.Errorf("diff bounds = %v", cmp.Diff.Bounds())
+	}
+}
+
+func TestToRGBAOffset(t *testing.T) {
+	src := checkerboard(8, 8)
+	src.Rect = image.Rect(100, 100, 108, 108)
+	got := toRGBA(src)
+	if got.Rect.Min != (image.Point{}) || got.Rect.Dx() != 8 {
+		t.Errorf("bounds = %v, want 8x8 at the origin", got.Rect)
+	}
+}
@@ -0,0 +1,193 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Confinement limits where injected pointer motion may go: either a fixed
+// rectangle in root coordinates or the current frame of a window. The zero
+// value confines nothing.
+type Confinement struct {
+	Rect   image.Rectangle // Region in root coordinates, if Window is 0
+	Window x.Window        // Window whose frame is the region, followed as it moves
+}
+
+// confineState is the active confinement, changed at runtime
+type confineState struct {
+	mu sync.Mutex
+	Confinement
+	floor Confinement // Set when connecting; SetConfinement can only narrow it
+}
+
+// Active returns true if the confinement limits anything
+func (c Confinement) Active() bool {
+	return c.Window != 0 || !c.Rect.Empty()
+}
+
+// String describes the confinement in the syntax ParseConfinement accepts
+func (c Confinement) String() string {
+	switch {
+	case c.Window != 0:
+		return fmt.Sprintf("0x%x", uint32(c.Window))
+	case !c.Rect.Empty():
+		return fmt.Sprintf("%dx%d+%d+%d", c.Rect.Dx(), c.Rect.Dy(), c.Rect.Min.X, c.Rect.Min.Y)
+	}
+	return ""
+}
+
+// ParseConfinement parses an X geometry like 800x600+100+50 or a window ID
+// like 0x1e00003. An empty spec confines nothing.
+func ParseConfinement(spec string) (Confinement, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return Confinement{}, nil
+	}
+	if !strings.Contains(spec, "x") || strings.HasPrefix(spec, "0x") {
+		id, err := strconv.ParseUint(spec, 0, 32)
+		if err != nil || id == 0 {
+			return Confinement{}, fmt.Errorf("invalid confinement %q: use WIDTHxHEIGHT+X+Y or a window ID", spec)
+		}
+		return Confinement{Window: x.Window(id)}, nil
+	}
+
+	var w, h, px, py int
+	var rest string
+	if n, _ := fmt.Sscanf(spec, "%dx%d+%d+%d%s", &w, &h, &px, &py, &rest); n != 4 || w <= 0 || h <= 0 {
+		return Confinement{}, fmt.Errorf("invalid confinement %q: use WIDTHxHEIGHT+X+Y or a window ID", spec)
+	}
+	return Confinement{Rect: image.Rect(px, py, px+w, py+h)}, nil
+}
+
+// SetConfinement confines all later pointer motion to conf, replacing any
+// confinement set before; the zero Confinement lifts it. Targets outside
+// MouseMove's reach are clamped to the nearest point inside, and clicks are
+// refused while the pointer is outside. A confinement given when connecting
+// stays in force: conf can only narrow it, and it can't be lifted.
+func (c *Client) SetConfinement(ctx context.Context, conf Confinement) error {
+	floor := c.ConfinementFloor()
+	if floor.Active() && !conf.Active() {
+		return fmt.Errorf("the confinement to %s set when connecting can't be lifted, only narrowed", floor)
+	}
+	if conf.Window != 0 {
+		if _, err := c.windowRect(ctx, conf.Window); err != nil {
+			return err
+		}
+	} else if conf.Active() && conf.Rect.Intersect(c.screenBounds()).Empty() {
+		return fmt.Errorf("confinement %s is outside the screen %v", conf, c.screenBounds())
+	}
+	if floor.Active() {
+		if _, err := c.confinementRect(ctx, floor, conf); err != nil {
+			return fmt.Errorf("confinement %s is outside the confinement to %s set when connecting", conf, floor)
+		}
+	}
+
+	c.confine.mu.Lock()
+	c.confine.Confinement = conf
+	c.confine.mu.Unlock()
+	return nil
+}
+
+// PointerConfinement returns the active confinement. Motion also stays
+// within ConfinementFloor.
+func (c *Client) PointerConfinement() Confinement {
+	c.confine.mu.Lock()
+	defer c.confine.mu.Unlock()
+	return c.confine.Confinement
+}
+
+// ConfinementFloor returns the confinement given when connecting, which
+// SetConfinement can't widen or lift
+func (c *Client) ConfinementFloor() Confinement {
+	c.confine.mu.Lock()
+	defer c.confine.mu.Unlock()
+	return c.confine.floor
+}
+
+// setConfinementFloor confines the pointer to conf for good
+func (c *Client) setConfinementFloor(ctx context.Context, conf Confinement) error {
+	if err := c.SetConfinement(ctx, conf); err != nil {
+		return err
+	}
+	c.confine.mu.Lock()
+	c.confine.floor = conf
+	c.confine.mu.Unlock()
+	return nil
+}
+
+// confineRect returns the region pointer motion is confined to, clipped to
+// the screen and the floor; ok is false if there is no confinement
+func (c *Client) confineRect(ctx context.Context) (rect image.Rectangle, ok bool, err error) {
+	c.confine.mu.Lock()
+	conf, floor := c.confine.Confinement, c.confine.floor
+	c.confine.mu.Unlock()
+	if !conf.Active() && !floor.Active() {
+		return image.Rectangle{}, false, nil
+	}
+	rect, err = c.confinementRect(ctx, floor, conf)
+	return rect, true, err
+}
+
+// confinementRect returns the part of the screen within all active confs
+func (c *Client) confinementRect(ctx context.Context, confs ...Confinement) (image.Rectangle, error) {
+	rect := c.screenBounds()
+	for _, conf := range confs {
+		if !conf.Active() {
+			continue
+		}
+		r := conf.Rect
+		if conf.Window != 0 {
+			var err error
+			if r, err = c.windowRect(ctx, conf.Window); err != nil {
+				return image.Rectangle{}, fmt.Errorf("failed to get the window the pointer is confined to: %w", err)
+			}
+		}
+		rect = rect.Intersect(r)
+	}
+	if rect.Empty() {
+		return image.Rectangle{}, fmt.Errorf("the region the pointer is confined to (%s) is off screen", c.PointerConfinement())
+	}
+	return rect, nil
+}
+
+// confinePoint clamps a motion target into the confinement
+func (c *Client) confinePoint(ctx context.Context, px, py int) (int, int, error) {
+	rect, ok, err := c.confineRect(ctx)
+	if !ok || err != nil {
+		return px, py, err
+	}
+	cx, cy := clampPoint(px, py, rect)
+	if cx != px || cy != py {
+		slog.Warn("pointer motion clamped to confinement", "x", px, "y", py, "clamped_x", cx, "clamped_y", cy)
+	}
+	return cx, cy, nil
+}
+
+// checkConfined returns an error if the pointer is outside the confinement,
+// so a click can't land on another application
+func (c *Client) checkConfined(ctx context.Context) error {
+	rect, ok, err := c.confineRect(ctx)
+	if !ok || err != nil {
+		return err
+	}
+	px, py, err := c.PointerPositionContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !image.Pt(px, py).In(rect) {
+		return fmt.Errorf("pointer at (%d, %d) is outside the confinement %v; move it inside before clicking", px, py, rect)
+	}
+	return nil
+}
+
+// clampPoint returns the point of rect nearest to (px, py)
+func clampPoint(px, py int, rect image.Rectangle) (int, int) {
+	return max(rect.Min.X, min(px, rect.Max.X-1)), max(rect.Min.Y, min(py, rect.Max.Y-1))
+}
@@ -0,0 +1,106 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestParseConfinement(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Confinement
+		wantErr bool
+	}{
+		{"", Confinement{}, false},
+		{"800x600+100+50", Confinement{Rect: image.Rect(100, 50, 900, 650)}, false},
+		{"0x1e00003", Confinement{Window: 0x1e00003}, false},
+		{"31457283", Confinement{Window: 31457283}, false},
+		{"800x600", Confinement{}, true},
+		{"0x600+1+1", Confinement{}, true},
+		{"800x600+1+1junk", Confinement{}, true},
+		{"0", Confinement{}, true},
+		{"left half", Confinement{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseConfinement(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseConfinement(%q) = %+v, %v; want %+v, error %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConfinementString(t *testing.T) {
+	for _, spec := range []string{"800x600+100+50", "0x1e00003"} {
+		conf, err := ParseConfinement(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := conf.String(); got != spec {
+			t.Errorf("String() = %q, want %q", got, spec)
+		}
+	}
+	if (Confinement{}).Active() || (Confinement{}).String() != "" {
+		t.Error("the zero Confinement must be inactive")
+	}
+}
+
+func TestClampPoint(t *testing.T) {
+	rect := image.Rect(100, 50, 900, 650)
+	tests := []struct {
+		x, y, wantX, wantY int
+	}{
+		{500, 300, 500, 300},
+		{0, 0, 100, 50},
+		{2000, 2000, 899, 649},
+		{900, 300, 899, 300},
+		{500, 10, 500, 50},
+	}
+	for _, tt := range tests {
+		if x, y := clampPoint(tt.x, tt.y, rect); x != tt.wantX || y != tt.wantY {
+			t.Errorf("clampPoint(%d, %d) = (%d, %d), want (%d, %d)", tt.x, tt.y, x, y, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+func TestConfinePointUnconfined(t *testing.T) {
+	// Without a confinement nothing is looked up or changed
+	c := &Client{}
+	if x, y, err := c.confinePoint(context.Background(), 5000, -3); err != nil || x != 5000 || y != -3 {
+		t.Errorf("confinePoint() = (%d, %d), %v; want the point unchanged", x, y, err)
+	}
+	if err := c.checkConfined(context.Background()); err != nil {
+		t.Errorf("checkConfined() = %v without a confinement", err)
+	}
+}
+
+func TestConfinementFloor(t *testing.T) {
+	ctx := context.Background()
+	c := &Client{screen: &x.Screen{WidthInPixels: 1920, HeightInPixels: 1080}}
+	floor := Confinement{Rect: image.Rect(0, 0, 800, 600)}
+	if err := c.setConfinementFloor(ctx, floor); err != nil {
+		t.Fatalf("setConfinementFloor failed: %v", err)
+	}
+
+	// The floor can't be lifted or left
+	if err := c.SetConfinement(ctx, Confinement{}); err == nil {
+		t.Error("lifting the floor succeeded")
+	}
+	if err := c.SetConfinement(ctx, Confinement{Rect: image.Rect(1000, 700, 1200, 900)}); err == nil {
+		t.Error("confining outside the floor succeeded")
+	}
+
+	// A wider confinement is narrowed to the floor
+	if err := c.SetConfinement(ctx, Confinement{Rect: image.Rect(400, 300, 1600, 1000)}); err != nil {
+		t.Fatalf("SetConfinement failed: %v", err)
+	}
+	rect, ok, err := c.confineRect(ctx)
+	if err != nil || !ok || rect != image.Rect(400, 300, 800, 600) {
+		t.Errorf("confineRect() = %v, %v, %v; want the overlap with the floor", rect, ok, err)
+	}
+	if x, y, err := c.confinePoint(ctx, 1500, 900); err != nil || x != 799 || y != 599 {
+		t.Errorf("confinePoint() = (%d, %d), %v; want (799, 599)", x, y, err)
+	}
+}
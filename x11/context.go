@@ -0,0 +1,89 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// DefaultRequestTimeout bounds blocking X requests when the caller's context
+// has no deadline of its own
+const DefaultRequestTimeout = 10 * time.Second
+
+// ErrRequestTimeout is returned when the X server does not answer in time
+var ErrRequestTimeout = errors.New("X server did not respond in time")
+
+// SetRequestTimeout changes the default timeout for blocking X requests
+func (c *Client) SetRequestTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+// requestTimeout returns the effective default request timeout
+func (c *Client) requestTimeout() time.Duration {
+	if c.timeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return c.timeout
+}
+
+// await runs a blocking X round trip and gives up when ctx is done or the
+// default request timeout expires. The round trip itself cannot be cancelled,
+// so a wedged request keeps its goroutine until the connection is closed.
+func await[T any](c *Client, ctx context.Context, fn func() (T, error)) (T, error) {
+	c.noteAction(ctx)
+	value, err := awaitTimeout(ctx, c.requestTimeout(), fn)
+	var xerr *x.Error
+	if errors.As(err, &xerr) {
+		c.recordXError(ctx, xerr, true)
+	}
+	return value, err
+}
+
+// XErrors returns how many X protocol errors the server has sent this
+// client, in replies to requests and for requests sent without waiting for
+// a reply
+func (c *Client) XErrors() uint64 {
+	return c.xErrors.Load()
+}
+
+// awaitTimeout runs a blocking call in the background, applying timeout when
+// ctx has no deadline of its own
+func awaitTimeout[T any](ctx context.Context, timeout time.Duration, fn func() (T, error)) (T, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, fmt.Errorf("%w: %w", ErrRequestTimeout, ctx.Err())
+		}
+		return zero, ctx.Err()
+	}
+}
+
+// awaitCheck is await for checked requests that have no reply
+func awaitCheck(c *Client, ctx context.Context, fn func() error) error {
+	_, err := await(c, ctx, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
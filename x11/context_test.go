@@ -0,0 +1,85 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestAwaitTimeout(t *testing.T) {
+	client := &Client{}
+	client.SetRequestTimeout(50 * time.Millisecond)
+
+	block := make(chan struct{})
+	defer close(block)
+
+	start := time.Now()
+	_, err := await(client, context.Background(), func() (int, error) {
+		<-block
+		return 0, nil
+	})
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("expected ErrRequestTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout took too long: %v", elapsed)
+	}
+}
+
+func TestAwaitCancel(t *testing.T) {
+	client := &Client{}
+
+	block := make(chan struct{})
+	defer close(block)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := awaitCheck(client, ctx, func() error {
+		<-block
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestAwaitResult(t *testing.T) {
+	client := &Client{}
+
+	value, err := await(client, context.Background(), func() (int, error) {
+		return 42, nil
+	})
+	if err != nil || value != 42 {
+		t.Errorf("expected (42, nil), got (%d, %v)", value, err)
+	}
+
+	wantErr := errors.New("request failed")
+	if err := awaitCheck(client, context.Background(), func() error { return wantErr }); !errors.Is(err, wantErr) {
+		t.Errorf("expected %v, got %v", wantErr, err)
+	}
+}
+
+func TestAwaitCountsXErrors(t *testing.T) {
+	client := &Client{}
+
+	awaitCheck(client, context.Background(), func() error { return errors.New("not from X") })
+	awaitCheck(client, context.Background(), func() error { return &x.Error{Code: x.WindowErrorCode} })
+	if got := client.XErrors(); got != 1 {
+		t.Errorf("XErrors() = %d, want 1", got)
+	}
+}
+
+func TestWaitContextCancel(t *testing.T) {
+	client := &Client{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := client.WaitContext(ctx, 5000); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// controlProperty is the root window property holding the control lease
+const controlProperty = "_MCP_X11_CONTROL"
+
+// ControlLease is an advisory claim of the display by one agent. It is kept
+// on the root window, so every server connected to the display sees it.
+type ControlLease struct {
+	Owner   string    // Unique name of the holder, such as its host, PID and session
+	Agent   string    // Name the holder gave itself, for messages
+	Expires time.Time // The lease lapses at this time unless renewed
+}
+
+// ControlHeldError reports that another agent controls the display
+type ControlHeldError struct {
+	Lease ControlLease
+}
+
+func (e *ControlHeldError) Error() string {
+	holder := e.Lease.Agent
+	if holder == "" {
+		holder = e.Lease.Owner
+	}
+	left := time.Until(e.Lease.Expires).Round(time.Second)
+	return fmt.Sprintf("the display is controlled by %s until %s (%s left)", holder, e.Lease.Expires.Format("15:04:05"), left)
+}
+
+// ControlHolder returns the current lease, false if nobody controls the
+// display or the lease lapsed
+func (c *Client) ControlHolder(ctx context.Context) (ControlLease, bool, error) {
+	return c.readControl(ctx)
+}
+
+// AcquireControl makes owner the holder of the display for the given lease,
+// or renews its lease. It fails with a *ControlHeldError while another
+// owner's lease runs. The root window is read and written with the server
+// grabbed, so two servers can't both acquire it.
+func (c *Client) AcquireControl(ctx context.Context, owner, agent string, lease time.Duration) (ControlLease, error) {
+	if owner == "" || strings.Contains(owner, "\n") || strings.Contains(agent, "\n") {
+		return ControlLease{}, fmt.Errorf("invalid control owner %q or agent %q", owner, agent)
+	}
+	if lease <= 0 {
+		return ControlLease{}, fmt.Errorf("lease must be positive")
+	}
+	x.GrabServer(c.conn)
+	defer func() {
+		x.UngrabServer(c.conn)
+		c.conn.Flush()
+	}()
+
+	held, ok, err := c.readControl(ctx)
+	if err != nil {
+		return ControlLease{}, err
+	}
+	if ok && held.Owner != owner {
+		return held, &ControlHeldError{Lease: held}
+	}
+	// Whole milliseconds, as stored
+	next := ControlLease{Owner: owner, Agent: agent, Expires: time.UnixMilli(time.Now().Add(lease).UnixMilli())}
+	err = awaitCheck(c, ctx, func() error {
+		return x.ChangePropertyChecked(c.conn, x.PropModeReplace, c.root, c.getAtom(ctx, controlProperty),
+			x.AtomString, 8, []byte(formatLease(next))).Check(c.conn)
+	})
+	if err != nil {
+		return ControlLease{}, fmt.Errorf("failed to store control lease: %w", err)
+	}
+	return next, nil
+}
+
+// ReleaseControl gives up owner's control of the display, reporting whether
+// it held it. Leases of other owners are left alone.
+func (c *Client) ReleaseControl(ctx context.Context, owner string) (bool, error) {
+	x.GrabServer(c.conn)
+	defer func() {
+		x.UngrabServer(c.conn)
+		c.conn.Flush()
+	}()
+
+	held, ok, err := c.readControl(ctx)
+	if err != nil || !ok || held.Owner != owner {
+		return false, err
+	}
+	err = awaitCheck(c, ctx, func() error {
+		return x.DeletePropertyChecked(c.conn, c.root, c.getAtom(ctx, controlProperty)).Check(c.conn)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to release control: %w", err)
+	}
+	return true, nil
+}
+
+// CheckControl returns a *ControlHeldError if an owner other than owner
+// controls the display, nil if it is free or owner's
+func (c *Client) CheckControl(ctx context.Context, owner string) error {
+	held, ok, err := c.readControl(ctx)
+	if err != nil {
+		return err
+	}
+	if ok && held.Owner != owner {
+		return &ControlHeldError{Lease: held}
+	}
+	return nil
+}
+
+// readControl reads the lease from the root window, ignoring lapsed and
+// unreadable ones
+func (c *Client) readControl(ctx context.Context) (ControlLease, bool, error) {
+	cookie := x.GetProperty(c.conn, false, c.root, c.getAtom(ctx, controlProperty), x.AtomString, 0, 1024)
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil {
+		return ControlLease{}, false, fmt.Errorf("failed to read control lease: %w", err)
+	}
+	lease, ok := parseLease(string(reply.Value))
+	if !ok || !time.Now().Before(lease.Expires) {
+		return ControlLease{}, false, nil
+	}
+	return lease, true, nil
+}
+
+// formatLease stores a lease as its expiry in Unix milliseconds, the owner
+// and the agent, one per line
+func formatLease(l ControlLease) string {
+	return fmt.Sprintf("%d\n%s\n%s", l.Expires.UnixMilli(), l.Owner, l.Agent)
+}
+
+// parseLease reads a lease stored by formatLease
+func parseLease(s string) (ControlLease, bool) {
+	fields := strings.SplitN(s, "\n", 3)
+	if len(fields) != 3 || fields[1] == "" {
+		return ControlLease{}, false
+	}
+	ms, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return ControlLease{}, false
+	}
+	return ControlLease{Owner: fields[1], Agent: fields[2], Expires: time.UnixMilli(ms)}, true
+}
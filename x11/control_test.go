@@ -0,0 +1,82 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLease(t *testing.T) {
+	expires := time.UnixMilli(1760000000123)
+	tests := []struct {
+		value  string
+		want   ControlLease
+		wantOK bool
+	}{
+		{formatLease(ControlLease{Owner: "host:42", Agent: "planner", Expires: expires}), ControlLease{Owner: "host:42", Agent: "planner", Expires: expires}, true},
+		{formatLease(ControlLease{Owner: "host:42", Expires: expires}), ControlLease{Owner: "host:42", Expires: expires}, true},
+		{"", ControlLease{}, false},
+		{"1760000000123\n\nplanner", ControlLease{}, false},
+		{"soon\nhost:42\n", ControlLease{}, false},
+		{"1760000000123\nhost:42", ControlLease{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLease(tt.value)
+		if ok != tt.wantOK || got.Owner != tt.want.Owner || got.Agent != tt.want.Agent || !got.Expires.Equal(tt.want.Expires) {
+			t.Errorf("parseLease(%q) = %+v, %v, want %+v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestControlLease(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if _, ok, err := client.ControlHolder(ctx); err != nil || ok {
+		t.Fatalf("ControlHolder of a new display = %v, %v, want nobody", ok, err)
+	}
+	lease, err := client.AcquireControl(ctx, "a", "first", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireControl failed: %v", err)
+	}
+	if err := client.CheckControl(ctx, "a"); err != nil {
+		t.Errorf("CheckControl of the holder = %v", err)
+	}
+
+	// A second owner is turned away until the holder releases
+	_, err = client.AcquireControl(ctx, "b", "second", time.Minute)
+	var held *ControlHeldError
+	if !errors.As(err, &held) || held.Lease.Owner != "a" || !held.Lease.Expires.Equal(lease.Expires) {
+		t.Fatalf("AcquireControl by another owner = %v, want a ControlHeldError for a", err)
+	}
+	if err := client.CheckControl(ctx, "b"); !errors.As(err, &held) {
+		t.Errorf("CheckControl of another owner = %v, want a ControlHeldError", err)
+	}
+	if released, err := client.ReleaseControl(ctx, "b"); err != nil || released {
+		t.Errorf("ReleaseControl by another owner = %v, %v, want false", released, err)
+	}
+	if released, err := client.ReleaseControl(ctx, "a"); err != nil || !released {
+		t.Errorf("ReleaseControl by the holder = %v, %v, want true", released, err)
+	}
+	if _, err := client.AcquireControl(ctx, "b", "second", 100*time.Millisecond); err != nil {
+		t.Fatalf("AcquireControl after release failed: %v", err)
+	}
+
+	// A lapsed lease is free to take
+	time.Sleep(150 * time.Millisecond)
+	if _, ok, err := client.ControlHolder(ctx); err != nil || ok {
+		t.Errorf("ControlHolder after the lease lapsed = %v, %v, want nobody", ok, err)
+	}
+	if _, err := client.AcquireControl(ctx, "a", "first", time.Minute); err != nil {
+		t.Errorf("AcquireControl of a lapsed lease failed: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// CoordinateError reports a point that lies outside the valid area
+type CoordinateError struct {
+	X, Y   float64
+	Area   string          // "screen" or a window description
+	Bounds image.Rectangle // Valid area in the coordinate space of X and Y
+}
+
+func (e *CoordinateError) Error() string {
+	return fmt.Sprintf("coordinates (%g, %g) are outside the %s; valid range is x=%d..%d, y=%d..%d",
+		e.X, e.Y, e.Area, e.Bounds.Min.X, e.Bounds.Max.X-1, e.Bounds.Min.Y, e.Bounds.Max.Y-1)
+}
+
+// screenBounds returns the screen rectangle in root coordinates
+func (c *Client) screenBounds() image.Rectangle {
+	return image.Rect(0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+}
+
+// ValidatePoint checks that (x, y) lies on the screen and converts it to
+// integer pixel coordinates
+func (c *Client) ValidatePoint(x, y float64) (int, int, error) {
+	return validatePoint(x, y, c.screenBounds(), "screen")
+}
+
+// ValidateWindowPoint checks that the window-relative point (x, y) lies inside
+// the window and on the screen, and returns it in root coordinates
+func (c *Client) ValidateWindowPoint(ctx context.Context, win x.Window, x, y float64) (int, int, error) {
+	if err := c.EnsureVisible(ctx, win); err != nil {
+		return 0, 0, err
+	}
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	local := image.Rect(0, 0, rect.Dx(), rect.Dy())
+	px, py, err := validatePoint(x, y, local, fmt.Sprintf("window %d", win))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rootX, rootY := rect.Min.X+px, rect.Min.Y+py
+	if !image.Pt(rootX, rootY).In(c.screenBounds()) {
+		return 0, 0, fmt.Errorf("point (%g, %g) in window %d is at (%d, %d), which is off screen", x, y, win, rootX, rootY)
+	}
+	return rootX, rootY, nil
+}
+
+// validatePoint checks a point against bounds and truncates it to integers
+func validatePoint(x, y float64, bounds image.Rectangle, area string) (int, int, error) {
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return 0, 0, fmt.Errorf("coordinates (%g, %g) are not finite numbers", x, y)
+	}
+
+	px, py := int(math.Floor(x)), int(math.Floor(y))
+	if !image.Pt(px, py).In(bounds) {
+		return 0, 0, &CoordinateError{X: x, Y: y, Area: area, Bounds: bounds}
+	}
+	return px, py, nil
+}
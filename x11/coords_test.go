@@ -0,0 +1,65 @@
+package x11
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestValidatePoint(t *testing.T) {
+	client := &Client{
+		screen: &x.Screen{WidthInPixels: 1920, HeightInPixels: 1080},
+	}
+
+	tests := []struct {
+		name         string
+		x, y         float64
+		wantX, wantY int
+		wantErr      bool
+	}{
+		{"origin", 0, 0, 0, 0, false},
+		{"fractional", 100.7, 200.2, 100, 200, false},
+		{"bottom right", 1919, 1079, 1919, 1079, false},
+		{"x too large", 1920, 10, 0, 0, true},
+		{"negative", -1, 10, 0, 0, true},
+		{"int16 overflow", 40000, 10, 0, 0, true},
+		{"NaN", math.NaN(), 10, 0, 0, true},
+		{"infinite", 10, math.Inf(1), 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			px, py, err := client.ValidatePoint(tt.x, tt.y)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for (%g, %g)", tt.x, tt.y)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if px != tt.wantX || py != tt.wantY {
+				t.Errorf("expected (%d, %d), got (%d, %d)", tt.wantX, tt.wantY, px, py)
+			}
+		})
+	}
+}
+
+func TestCoordinateErrorMessage(t *testing.T) {
+	client := &Client{
+		screen: &x.Screen{WidthInPixels: 800, HeightInPixels: 600},
+	}
+
+	_, _, err := client.ValidatePoint(900, 100)
+	var coordErr *CoordinateError
+	if !errors.As(err, &coordErr) {
+		t.Fatalf("expected CoordinateError, got %v", err)
+	}
+	if coordErr.Bounds.Dx() != 800 || coordErr.Bounds.Dy() != 600 {
+		t.Errorf("unexpected bounds: %v", coordErr.Bounds)
+	}
+	t.Logf("Error: %v", err)
+}
@@ -0,0 +1,85 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/damage"
+)
+
+// damageState is the DAMAGE object of the root window, created on first
+// use, and the watchers of its reports
+type damageState struct {
+	mu       sync.Mutex
+	tried    bool // Creating the damage object was attempted
+	err      error
+	watchers []func(image.Rectangle)
+}
+
+// WatchDamage calls fn with every area of the screen the X server reports
+// as changed, in root coordinates. It fails if the DAMAGE extension is
+// unavailable. fn runs on the event loop and must not block.
+func (c *Client) WatchDamage(fn func(image.Rectangle)) error {
+	c.damage.mu.Lock()
+	defer c.damage.mu.Unlock()
+	if !c.damage.tried {
+		c.damage.tried = true
+		c.damage.err = c.trackDamage()
+	}
+	if c.damage.err != nil {
+		return c.damage.err
+	}
+	c.damage.watchers = append(c.damage.watchers, fn)
+	return nil
+}
+
+// trackDamage asks the X server to report screen changes on the root
+// window and passes them to the watchers
+func (c *Client) trackDamage() error {
+	ext := c.conn.GetExtensionData(damage.Ext())
+	if ext == nil || !ext.Present {
+		return fmt.Errorf("the DAMAGE extension is not available")
+	}
+
+	ctx := context.Background()
+	_, err := await(c, ctx, func() (*damage.QueryVersionReply, error) {
+		return damage.QueryVersion(c.conn, damage.MajorVersion, damage.MinorVersion).Reply(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query DAMAGE version: %w", err)
+	}
+
+	xid, err := c.conn.AllocID()
+	if err != nil {
+		return err
+	}
+	err = awaitCheck(c, ctx, func() error {
+		return damage.CreateChecked(c.conn, damage.Damage(xid), x.Drawable(c.root),
+			damage.ReportLevelRawRectangles).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to track damage: %w", err)
+	}
+
+	notifyCode := ext.FirstEvent + damage.NotifyEventCode
+	c.addEventHandler(func(ev x.GenericEvent) {
+		if ev.GetEventCode() != notifyCode {
+			return
+		}
+		nev, err := damage.NewNotifyEvent(ev)
+		if err != nil {
+			return
+		}
+		area := image.Rect(int(nev.Area.X), int(nev.Area.Y), int(nev.Area.X)+int(nev.Area.Width), int(nev.Area.Y)+int(nev.Area.Height))
+		c.damage.mu.Lock()
+		watchers := c.damage.watchers
+		c.damage.mu.Unlock()
+		for _, fn := range watchers {
+			fn(area)
+		}
+	})
+	return nil
+}
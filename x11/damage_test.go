@@ -0,0 +1,58 @@
+package x11
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestWatchDamage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	damaged := make(chan image.Rectangle, 64)
+	if err := client.WatchDamage(func(r image.Rectangle) {
+		select {
+		case damaged <- r:
+		default:
+		}
+	}); err != nil {
+		t.Fatalf("WatchDamage failed: %v", err)
+	}
+
+	// Mapping a window with a background paints its area
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 100, 50, 200, 100, 0,
+		x.WindowClassInputOutput, 0, x.CWBackPixel, []uint32{0xff0000}).Check(client.conn)
+	if err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	x.MapWindow(client.conn, win)
+	client.conn.Flush()
+
+	want := image.Rect(100, 50, 300, 150)
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case r := <-damaged:
+			if r.Overlaps(want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no damage reported within %v", want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// WindowDescription is what DescribeWindow gathers about a window
+type WindowDescription struct {
+	ID       x.Window
+	Title    string
+	Class    string
+	Instance string          // Instance name of WM_CLASS, e.g. "Navigator" for Firefox
+	Role     string          // WM_WINDOW_ROLE, set by GTK and Qt for many windows
+	PID      uint32          // _NET_WM_PID, 0 if not set
+	Types    []string        // _NET_WM_WINDOW_TYPE without its prefix, e.g. "DIALOG"
+	Rect     image.Rectangle // Geometry in root coordinates
+	Children int             // Number of X child windows
+	Image    image.Image     // Capture of the window, if requested
+}
+
+// DescribeWindow collects the window manager properties and geometry of a
+// window, and captures it if capture is set. Frames of reparenting window
+// managers are resolved to the application window for the properties.
+func (c *Client) DescribeWindow(ctx context.Context, win x.Window, capture bool) (WindowDescription, error) {
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return WindowDescription{}, fmt.Errorf("failed to describe window 0x%x: %w", uint32(win), err)
+	}
+
+	app := c.clientWindow(ctx, win)
+	desc := WindowDescription{
+		ID:    win,
+		Title: c.getWindowName(ctx, app),
+		Role:  c.getStringProperty(ctx, app, c.getAtom(ctx, "WM_WINDOW_ROLE")),
+		Rect:  rect,
+	}
+	desc.Instance, desc.Class = c.getWMClass(ctx, app)
+	if desc.Class == "" {
+		desc.Class = desc.Instance
+	}
+	desc.PID, _ = c.getCardinalProperty(ctx, app, c.getAtom(ctx, "_NET_WM_PID"))
+
+	for _, atom := range c.getUint32Values(ctx, app, c.getAtom(ctx, "_NET_WM_WINDOW_TYPE"), x.AtomAtom, 16) {
+		if name := c.atomName(ctx, x.Atom(atom)); name != "" {
+			desc.Types = append(desc.Types, strings.TrimPrefix(name, "_NET_WM_WINDOW_TYPE_"))
+		}
+	}
+
+	tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+		return x.QueryTree(c.conn, app).Reply(c.conn)
+	})
+	if err == nil {
+		desc.Children = len(tree.Children)
+	}
+
+	if capture {
+		if err := c.EnsureVisible(ctx, win); err != nil {
+			return desc, err
+		}
+		img, err := c.captureWindow(ctx, win)
+		if err != nil {
+			return desc, err
+		}
+		// Not returned to the frame pool, the description keeps the image
+		desc.Image = img
+	}
+	return desc, nil
+}
+
+// atomName returns the name of an atom, or "" if it cannot be looked up
+func (c *Client) atomName(ctx context.Context, atom x.Atom) string {
+	reply, err := await(c, ctx, func() (*x.GetAtomNameReply, error) {
+		return x.GetAtomName(c.conn, atom).Reply(c.conn)
+	})
+	if err != nil {
+		return ""
+	}
+	return reply.Name
+}
@@ -0,0 +1,134 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+const (
+	// dismissWait is how long Dismiss waits for the popup to go after
+	// each attempt
+	dismissWait = 500 * time.Millisecond
+	// dismissPoll is how often Dismiss checks whether the popup is gone
+	dismissPoll = 50 * time.Millisecond
+)
+
+// Popup kinds Dismiss tells apart
+const (
+	PopupMenu      = "menu"      // Override-redirect window: menu, tooltip or dropdown
+	PopupDialog    = "dialog"    // Focused window typed as a dialog
+	PopupTransient = "transient" // Focused window transient for another
+)
+
+// Dismissal is the outcome of Dismiss
+type Dismissal struct {
+	Window    x.Window // The popup, 0 if there was none
+	Kind      string   // PopupMenu, PopupDialog or PopupTransient
+	Class     string
+	Title     string
+	Clicked   bool        // Escape didn't close it, a click at the neutral point did
+	ClickedAt image.Point // Where the click went, if Clicked
+	Remaining x.Window    // Another popup still open, e.g. the parent of a submenu, else 0
+}
+
+// Dismiss closes the topmost menu or tooltip or, if none is open, the
+// focused transient or dialog window. It presses Escape and checks that
+// the popup is gone; if not and neutral is set, it clicks there and checks
+// again. It doesn't click blindly, since whatever is beside the popup could
+// take the click. With nothing to dismiss it returns a zero Dismissal.
+func (c *Client) Dismiss(ctx context.Context, neutral *image.Point) (Dismissal, error) {
+	if err := c.checkFrozen(); err != nil {
+		return Dismissal{}, err
+	}
+	d, ok := c.dismissTarget(ctx)
+	if !ok {
+		return Dismissal{}, nil
+	}
+
+	if err := c.KeyPressContext(ctx, "Escape"); err != nil {
+		return d, err
+	}
+	gone, err := c.waitGone(ctx, d.Window)
+	if err != nil {
+		return d, err
+	}
+	if !gone && neutral == nil {
+		return d, fmt.Errorf("%s 0x%x is still open after Escape; give a neutral point to click", d.Kind, uint32(d.Window))
+	}
+	if !gone {
+		d.ClickedAt = *neutral
+		if err := c.MouseMoveContext(ctx, d.ClickedAt.X, d.ClickedAt.Y); err != nil {
+			return d, err
+		}
+		if err := c.MouseClickContext(ctx, 1); err != nil {
+			return d, err
+		}
+		d.Clicked = true
+		if gone, err = c.waitGone(ctx, d.Window); err != nil {
+			return d, err
+		}
+	}
+	if !gone {
+		return d, fmt.Errorf("%s 0x%x is still open after Escape and a click at %d,%d",
+			d.Kind, uint32(d.Window), d.ClickedAt.X, d.ClickedAt.Y)
+	}
+
+	if next, ok := c.dismissTarget(ctx); ok {
+		d.Remaining = next.Window
+	}
+	return d, nil
+}
+
+// dismissTarget finds the popup Dismiss would close: the topmost
+// override-redirect window, else the focused window if it is a popup
+func (c *Client) dismissTarget(ctx context.Context) (Dismissal, bool) {
+	if menus := c.overrideRedirectWindows(ctx); len(menus) > 0 {
+		win := menus[0]
+		return Dismissal{
+			Window: win,
+			Kind:   PopupMenu,
+			Class:  c.getWindowClass(ctx, win),
+			Title:  c.getWindowName(ctx, win),
+		}, true
+	}
+
+	focused, err := c.FocusedWindowContext(ctx)
+	if err != nil || focused == 0 || focused == c.root {
+		return Dismissal{}, false
+	}
+	popup, ok := c.inspectPopup(ctx, focused)
+	if !ok {
+		return Dismissal{}, false
+	}
+	kind := PopupTransient
+	if popup.Dialog {
+		kind = PopupDialog
+	}
+	return Dismissal{Window: popup.Window, Kind: kind, Class: popup.Class, Title: popup.Title}, true
+}
+
+// waitGone waits up to dismissWait for win to be unmapped or destroyed
+func (c *Client) waitGone(ctx context.Context, win x.Window) (bool, error) {
+	deadline := time.Now().Add(dismissWait)
+	for {
+		attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+			return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+		})
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		if err != nil || attrs.MapState != x.MapStateViewable {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		if err := c.WaitContext(ctx, int(dismissPoll.Milliseconds())); err != nil {
+			return false, err
+		}
+	}
+}
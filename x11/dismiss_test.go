@@ -0,0 +1,84 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestDismiss(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	d, err := client.Dismiss(ctx, nil)
+	if err != nil || d.Window != 0 {
+		t.Fatalf("Dismiss with nothing open = %+v, %v", d, err)
+	}
+
+	// Menus of this client, like highlight outlines, are never dismissed
+	other, err := ConnectWithOptions(ConnectOptions{Display: client.GetDisplay()})
+	if err != nil {
+		t.Fatalf("Failed to connect a second client: %v", err)
+	}
+	defer other.Close()
+	newMenu := func(owner *Client) x.Window {
+		xid, err := owner.conn.AllocID()
+		if err != nil {
+			t.Fatalf("AllocID failed: %v", err)
+		}
+		win := x.Window(xid)
+		err = x.CreateWindowChecked(owner.conn, 0, win, owner.root, 100, 100, 200, 150, 0,
+			x.WindowClassInputOutput, 0, x.CWOverrideRedirect, []uint32{1}).Check(owner.conn)
+		if err != nil {
+			t.Fatalf("CreateWindow failed: %v", err)
+		}
+		x.MapWindow(owner.conn, win)
+		owner.conn.Flush()
+		return win
+	}
+	newMenu(client)
+	if d, err := client.Dismiss(ctx, nil); err != nil || d.Window != 0 {
+		t.Fatalf("Dismiss with only an own window open = %+v, %v", d, err)
+	}
+
+	// A menu that closes on Escape, played by unmapping it shortly after
+	menu := newMenu(other)
+	time.AfterFunc(100*time.Millisecond, func() {
+		x.UnmapWindow(other.conn, menu)
+		other.conn.Flush()
+	})
+	d, err = client.Dismiss(ctx, nil)
+	if err != nil {
+		t.Fatalf("Dismiss failed: %v", err)
+	}
+	if d.Window != menu || d.Kind != PopupMenu || d.Clicked {
+		t.Errorf("Dismiss = %+v, want menu 0x%x closed by Escape", d, menu)
+	}
+
+	// A menu Escape doesn't close is not clicked beside without a neutral
+	// point, and still open after a click at one
+	stuck := newMenu(other)
+	d, err = client.Dismiss(ctx, nil)
+	if err == nil || !strings.Contains(err.Error(), "still open") || d.Window != stuck || d.Clicked {
+		t.Fatalf("Dismiss of a stuck menu = %+v, %v, want still open without a click", d, err)
+	}
+	d, err = client.Dismiss(ctx, &image.Point{X: 50, Y: 50})
+	if err == nil || !strings.Contains(err.Error(), "still open") {
+		t.Fatalf("Dismiss of a stuck menu = %v, want still open", err)
+	}
+	if d.Window != stuck || !d.Clicked || d.ClickedAt != image.Pt(50, 50) {
+		t.Errorf("Dismiss = %+v, want a click at the neutral point", d)
+	}
+}
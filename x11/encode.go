@@ -0,0 +1,66 @@
+package x11
+
+import (
+	"image"
+	"runtime"
+	"sync"
+)
+
+// maxEncodeWorkers bounds the encode workers; PNG encoding is CPU bound and
+// a few workers already keep up with back-to-back tool calls
+const maxEncodeWorkers = 4
+
+var (
+	encodeQueue chan func()
+	encodeStart sync.Once
+)
+
+// EncodeWorkers returns how many PNGs EncodePNGAsync encodes at once
+func EncodeWorkers() int {
+	return max(1, min(runtime.NumCPU(), maxEncodeWorkers))
+}
+
+// startEncoders starts the encode workers on first use
+func startEncoders() {
+	encodeStart.Do(func() {
+		n := EncodeWorkers()
+		encodeQueue = make(chan func(), 2*n)
+		for i := 0; i < n; i++ {
+			go func() {
+				for job := range encodeQueue {
+					job()
+				}
+			}()
+		}
+	})
+}
+
+// PendingPNG is a PNG being encoded by the encode workers
+type PendingPNG struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// EncodePNGAsync hands img to the encode workers and returns at once, so
+// the caller can hash the image or capture the next one meanwhile. The
+// client's compression level and color mode at the time of the call are
+// used. img must not be changed or recycled before Wait returns.
+func (c *Client) EncodePNGAsync(img image.Image) *PendingPNG {
+	startEncoders()
+	p := &PendingPNG{done: make(chan struct{})}
+	level, colors := c.pngLevel, c.pngColors
+	encodeQueue <- func() {
+		defer close(p.done)
+		p.data, p.err = encodeWith(img, level, colors)
+	}
+	return p
+}
+
+// Wait blocks until the PNG is encoded. It doesn't take a context: the
+// worker reads the image until it is done, so giving up early would let the
+// caller recycle it underneath.
+func (p *PendingPNG) Wait() ([]byte, error) {
+	<-p.done
+	return p.data, p.err
+}
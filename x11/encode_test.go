@@ -0,0 +1,52 @@
+package x11
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestEncodePNGAsync(t *testing.T) {
+	client := &Client{pngColors: PNGColorsFull}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	want, err := client.EncodePNG(img)
+	if err != nil {
+		t.Fatalf("EncodePNG failed: %v", err)
+	}
+
+	// More images than workers queue up and all come back intact
+	pending := make([]*PendingPNG, 3*EncodeWorkers())
+	for i := range pending {
+		pending[i] = client.EncodePNGAsync(img)
+	}
+	for i, p := range pending {
+		got, err := p.Wait()
+		if err != nil {
+			t.Fatalf("encode %d failed: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("encode %d differs from EncodePNG", i)
+		}
+	}
+
+	// The color mode at the time of the call applies
+	client.pngColors = PNGColorsGray
+	p := client.EncodePNGAsync(img)
+	client.pngColors = PNGColorsFull
+	gray, err := p.Wait()
+	if err != nil {
+		t.Fatalf("gray encode failed: %v", err)
+	}
+	if bytes.Equal(gray, want) {
+		t.Error("gray encode is the same as the full color one")
+	}
+}
+
+func TestEncodeWorkers(t *testing.T) {
+	if n := EncodeWorkers(); n < 1 || n > maxEncodeWorkers {
+		t.Errorf("EncodeWorkers() = %d, want 1 to %d", n, maxEncodeWorkers)
+	}
+}
@@ -0,0 +1,70 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// eventHandler receives raw X events read from the connection
+type eventHandler func(ev x.GenericEvent)
+
+// eventDispatcher fans X events out to the registered handlers
+type eventDispatcher struct {
+	mu       sync.Mutex
+	handlers []eventHandler
+	started  bool
+	rootMask uint32 // Events selected on the root window
+}
+
+// addEventHandler registers a handler and starts the event loop on first use
+func (c *Client) addEventHandler(h eventHandler) {
+	c.events.mu.Lock()
+	c.events.handlers = append(c.events.handlers, h)
+	start := !c.events.started && c.conn != nil
+	if start {
+		c.events.started = true
+	}
+	c.events.mu.Unlock()
+
+	if start {
+		go c.eventLoop(c.conn.MakeAndAddEventChan(256))
+	}
+}
+
+// eventLoop delivers events until the connection is closed
+func (c *Client) eventLoop(ch chan x.GenericEvent) {
+	for ev := range ch {
+		if ev.GetEventCode() == x.ResponseTypeError {
+			c.recordXError(context.Background(), c.conn.NewError(ev), false)
+			continue
+		}
+		c.events.mu.Lock()
+		handlers := make([]eventHandler, len(c.events.handlers))
+		copy(handlers, c.events.handlers)
+		c.events.mu.Unlock()
+
+		for _, h := range handlers {
+			h(ev)
+		}
+	}
+}
+
+// selectRootEvents adds mask to the events this client selects on the root
+// window, keeping the ones selected before
+func (c *Client) selectRootEvents(ctx context.Context, mask uint32) error {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+
+	mask |= c.events.rootMask
+	err := awaitCheck(c, ctx, func() error {
+		return x.ChangeWindowAttributesChecked(c.conn, c.root, x.CWEventMask, []uint32{mask}).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to select root window events: %w", err)
+	}
+	c.events.rootMask = mask
+	return nil
+}
@@ -0,0 +1,138 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Pacing of SelectFileInDialog
+const (
+	fileDialogSettle  = 200 * time.Millisecond // After opening the location bar
+	fileDialogTimeout = 3 * time.Second        // For the dialog to close after Enter
+	fileDialogPoll    = 100 * time.Millisecond
+)
+
+// FileDialogResult describes the outcome of SelectFileInDialog
+type FileDialogResult struct {
+	Dialog       x.Window // The file dialog that had the focus
+	Title        string   // Title of the file dialog
+	Verified     bool     // The location bar was read back and held the path
+	Closed       bool     // The dialog closed after Enter
+	Focused      x.Window // Window focused afterwards, e.g. an overwrite confirmation
+	FocusedTitle string
+}
+
+// SelectFileInDialog enters a path into the focused GTK or Qt open/save
+// dialog: Ctrl+L opens the location bar, the path is typed over its
+// contents, and Enter confirms. Before confirming, the typed text is read
+// back through the PRIMARY selection if the dialog's application owns it,
+// and a mismatch is an error; otherwise Verified is false. Afterwards the
+// dialog is expected to close. If it stays open, for example because the
+// path is a directory or a confirmation appeared, the result says so
+// instead of failing.
+func (c *Client) SelectFileInDialog(ctx context.Context, path string) (FileDialogResult, error) {
+	if path == "" {
+		return FileDialogResult{}, fmt.Errorf("path cannot be empty")
+	}
+	if err := c.checkFrozen(); err != nil {
+		return FileDialogResult{}, err
+	}
+
+	dialog, err := c.FocusedWindowContext(ctx)
+	if err != nil {
+		return FileDialogResult{}, fmt.Errorf("no file dialog has the focus: %w", err)
+	}
+	result := FileDialogResult{Dialog: dialog, Title: c.getWindowName(ctx, dialog)}
+
+	if err := c.KeyComboContext(ctx, "ctrl+l"); err != nil {
+		return result, err
+	}
+	if err := c.WaitContext(ctx, int(fileDialogSettle.Milliseconds())); err != nil {
+		return result, err
+	}
+
+	// Replace whatever the location bar holds
+	if err := c.KeyComboContext(ctx, "ctrl+a"); err != nil {
+		return result, err
+	}
+	if err := c.TypeContext(ctx, path); err != nil {
+		return result, err
+	}
+	// GTK completes file names inline, leaving the suggested rest selected
+	if err := c.KeyPressContext(ctx, "Delete"); err != nil {
+		return result, err
+	}
+
+	// Selecting all sets PRIMARY, which shows what the entry really holds
+	if err := c.KeyComboContext(ctx, "ctrl+a"); err != nil {
+		return result, err
+	}
+	if err := c.WaitContext(ctx, int(selectionSettle.Milliseconds())); err != nil {
+		return result, err
+	}
+	// PRIMARY owned by another application, e.g. because this dialog's
+	// entry doesn't export its selection, says nothing about the entry
+	if c.ownsPrimary(ctx, c.clientWindow(ctx, dialog)) {
+		typed, err := c.ReadSelectionContext(ctx, "PRIMARY")
+		switch {
+		case err == nil && typed != path:
+			return result, fmt.Errorf("location bar holds %q instead of %q", typed, path)
+		case err == nil:
+			result.Verified = true
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return result, err
+		}
+	}
+
+	if err := c.KeyPressContext(ctx, "Return"); err != nil {
+		return result, err
+	}
+
+	deadline := time.Now().Add(fileDialogTimeout)
+	for {
+		if err := c.WaitContext(ctx, int(fileDialogPoll.Milliseconds())); err != nil {
+			return result, err
+		}
+		focused, err := c.FocusedWindowContext(ctx)
+		if err == nil {
+			result.Focused = focused
+		}
+		open := c.windowViewable(ctx, dialog)
+		if !open || (err == nil && focused != dialog) {
+			result.Closed = !open
+			break
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	if result.Focused != 0 {
+		result.FocusedTitle = c.getWindowName(ctx, result.Focused)
+	}
+	return result, nil
+}
+
+// ownsPrimary returns true if the PRIMARY selection is owned by the client
+// that created win
+func (c *Client) ownsPrimary(ctx context.Context, win x.Window) bool {
+	primary := c.getAtom(ctx, SelectionPrimary)
+	if primary == 0 {
+		return false
+	}
+	owner, err := await(c, ctx, func() (*x.GetSelectionOwnerReply, error) {
+		return x.GetSelectionOwner(c.conn, primary).Reply(c.conn)
+	})
+	return err == nil && owner.Owner != x.None && c.sameClient(owner.Owner, win)
+}
+
+// windowViewable returns true if the window exists and is mapped
+func (c *Client) windowViewable(ctx context.Context, win x.Window) bool {
+	attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+		return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+	})
+	return err == nil && attrs.MapState == x.MapStateViewable
+}
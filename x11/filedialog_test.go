@@ -0,0 +1,81 @@
+package x11
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestSelectFileInDialogEmptyPath(t *testing.T) {
+	c := &Client{}
+	if _, err := c.SelectFileInDialog(context.Background(), ""); err == nil {
+		t.Error("expected error for an empty path")
+	}
+}
+
+func TestSelectFileInDialogFrozen(t *testing.T) {
+	c := &Client{}
+	c.SetFrozen(true)
+	if _, err := c.SelectFileInDialog(context.Background(), "/tmp/file.txt"); err != ErrFrozen {
+		t.Errorf("expected ErrFrozen, got %v", err)
+	}
+}
+
+func TestSelectFileInDialogReadback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// A focused window that ignores the keys stands in for the dialog
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialog := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, dialog, client.root, 0, 0, 300, 200, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	x.MapWindow(client.conn, dialog)
+	client.conn.Flush()
+	time.Sleep(100 * time.Millisecond)
+	x.SetInputFocus(client.conn, x.InputFocusParent, dialog, x.CurrentTime)
+	client.conn.Flush()
+
+	// PRIMARY held by another application is not the location bar
+	other, err := ConnectWithOptions(ConnectOptions{Display: client.GetDisplay()})
+	if err != nil {
+		t.Fatalf("Failed to connect a second client: %v", err)
+	}
+	defer other.Close()
+	if err := other.SetSelection(SelectionPrimary, "stale text"); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	res, err := client.SelectFileInDialog(ctx, "/tmp/file.txt")
+	if err != nil {
+		t.Fatalf("SelectFileInDialog with a foreign PRIMARY failed: %v", err)
+	}
+	if res.Verified || res.Closed || res.Dialog != dialog {
+		t.Errorf("SelectFileInDialog = %+v, want the open dialog unverified", res)
+	}
+
+	// PRIMARY of the dialog's own application that holds other text is
+	// a mistyped path
+	if err := client.SetSelection(SelectionPrimary, "/tmp/other.txt"); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	if _, err := client.SelectFileInDialog(ctx, "/tmp/file.txt"); err == nil || !strings.Contains(err.Error(), "instead of") {
+		t.Errorf("SelectFileInDialog with a mismatching readback = %v, want an error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package x11
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"mcp-x11-controller/x11/vision"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// MaxImageMatches bounds the matches FindImage returns
+const MaxImageMatches = 20
+
+// FindImage looks for a PNG template, such as a cropped screenshot of a
+// button, on the screen and returns where it matches with a score of at
+// least threshold, best first, in screen coordinates
+func (c *Client) FindImage(template []byte, threshold float64) ([]vision.Match, error) {
+	return c.FindImageContext(context.Background(), template, threshold, 0)
+}
+
+// FindImageContext is like FindImage but only searches the visible area of
+// win unless it is 0, and gives up when ctx is done
+func (c *Client) FindImageContext(ctx context.Context, template []byte, threshold float64, win x.Window) ([]vision.Match, error) {
+	tmpl, err := png.Decode(bytes.NewReader(template))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template PNG: %w", err)
+	}
+	var img image.Image
+	if win == 0 {
+		img, err = c.ScreenshotContext(ctx)
+	} else {
+		img, err = c.ScreenshotWindowContext(ctx, win)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer c.RecycleScreenshot(img)
+	return vision.Find(img, tmpl, threshold, MaxImageMatches)
+}
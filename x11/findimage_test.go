@@ -0,0 +1,75 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestFindImage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// A gray "button" with a dark "label" on it
+	var label x.Window
+	for _, w := range []struct {
+		rect  image.Rectangle
+		pixel uint32
+	}{
+		{image.Rect(300, 200, 420, 240), 0xc0c0c0},
+		{image.Rect(330, 212, 390, 228), 0x202020},
+	} {
+		xid, err := client.conn.AllocID()
+		if err != nil {
+			t.Fatalf("AllocID failed: %v", err)
+		}
+		err = x.CreateWindowChecked(client.conn, 0, x.Window(xid), client.root,
+			int16(w.rect.Min.X), int16(w.rect.Min.Y), uint16(w.rect.Dx()), uint16(w.rect.Dy()), 0,
+			x.WindowClassInputOutput, 0, x.CWBackPixel|x.CWOverrideRedirect, []uint32{w.pixel, 1}).Check(client.conn)
+		if err != nil {
+			t.Fatalf("CreateWindow failed: %v", err)
+		}
+		x.MapWindow(client.conn, x.Window(xid))
+		label = x.Window(xid)
+	}
+	client.conn.Flush()
+	if err := client.WaitContext(ctx, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	button := image.Rect(300, 200, 420, 240)
+	img, err := client.ScreenshotRegionContext(ctx, button.Min.X, button.Min.Y, button.Dx(), button.Dy())
+	if err != nil {
+		t.Fatalf("ScreenshotRegion failed: %v", err)
+	}
+	template, err := client.EncodePNG(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := client.FindImage(template, 0.9)
+	if err != nil {
+		t.Fatalf("FindImage failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Rect != button {
+		t.Fatalf("FindImage = %v, want one match at %v", matches, button)
+	}
+
+	// The label window is too small to hold the button
+	if _, err := client.FindImageContext(ctx, template, 0.9, label); err == nil {
+		t.Error("FindImageContext in a window smaller than the template succeeded")
+	}
+	if _, err := client.FindImage([]byte("not a png"), 0.9); err == nil {
+		t.Error("FindImage with an invalid template succeeded")
+	}
+}
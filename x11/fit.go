@@ -0,0 +1,51 @@
+package x11
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+)
+
+// fitScales are the scales FitPNG tries in turn, each with a palette
+var fitScales = []float64{1, 0.75, 0.5, 0.35, 0.25}
+
+// FittedPNG is a PNG re-encoded by FitPNG
+type FittedPNG struct {
+	PNG    []byte
+	Scale  float64 // Image size relative to the original, 1 if only the colors were reduced
+	Width  int
+	Height int
+	Fits   bool // PNG is within the limit; false if even the smallest scale exceeds it
+}
+
+// FitPNG re-encodes a PNG larger than limit bytes to fit it, first with a
+// 256 color palette and then at smaller and smaller scales. If even the
+// smallest scale is too large it is returned with Fits unset, rather than
+// failing.
+func (c *Client) FitPNG(data []byte, limit int) (FittedPNG, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return FittedPNG{}, fmt.Errorf("failed to decode PNG: %w", err)
+	}
+	rgba := toRGBA(img)
+	bounds := rgba.Bounds()
+
+	var fitted FittedPNG
+	for _, scale := range fitScales {
+		w := max(1, int(float64(bounds.Dx())*scale))
+		h := max(1, int(float64(bounds.Dy())*scale))
+		scaled := rgba
+		if scale < 1 {
+			scaled = scaleImage(rgba, w, h)
+		}
+		data, err := encodeWith(scaled, c.pngLevel, PNGColorsPalette)
+		if err != nil {
+			return FittedPNG{}, err
+		}
+		fitted = FittedPNG{PNG: data, Scale: scale, Width: w, Height: h, Fits: len(data) <= limit}
+		if fitted.Fits {
+			break
+		}
+	}
+	return fitted, nil
+}
@@ -0,0 +1,50 @@
+package x11
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+func TestFitPNG(t *testing.T) {
+	// Noise compresses badly even with a palette, so only scaling helps
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	rng := rand.New(rand.NewSource(1))
+	rng.Read(img.Pix)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{}
+
+	limit := buf.Len() / 10
+	fitted, err := client.FitPNG(buf.Bytes(), limit)
+	if err != nil {
+		t.Fatalf("FitPNG failed: %v", err)
+	}
+	if !fitted.Fits || len(fitted.PNG) > limit || fitted.Scale >= 1 {
+		t.Fatalf("FitPNG = %d bytes at scale %v (fits %v), want at most %d", len(fitted.PNG), fitted.Scale, fitted.Fits, limit)
+	}
+	decoded, err := png.Decode(bytes.NewReader(fitted.PNG))
+	if err != nil {
+		t.Fatalf("FitPNG returned an invalid PNG: %v", err)
+	}
+	if got := decoded.Bounds(); got.Dx() != fitted.Width || got.Dy() != fitted.Height {
+		t.Errorf("image is %v, reported %dx%d", got, fitted.Width, fitted.Height)
+	}
+
+	// An impossible limit still yields the smallest image
+	fitted, err = client.FitPNG(buf.Bytes(), 10)
+	if err != nil {
+		t.Fatalf("FitPNG failed: %v", err)
+	}
+	if fitted.Fits || fitted.Scale != fitScales[len(fitScales)-1] {
+		t.Errorf("FitPNG with a 10 byte limit = scale %v, fits %v", fitted.Scale, fitted.Fits)
+	}
+
+	if _, err := client.FitPNG([]byte("not a png"), 10); err == nil {
+		t.Error("FitPNG of invalid data should fail")
+	}
+}
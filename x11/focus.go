@@ -0,0 +1,44 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// activateTimeout is how long the window manager gets to report a window
+// active after being asked to activate it
+const activateTimeout = 500 * time.Millisecond
+
+// activateWindow asks the window manager to activate the application window
+// app, whose top-level window is frame, with a _NET_ACTIVE_WINDOW request,
+// and reports whether the WM made it the active window within
+// activateTimeout. Without an EWMH window manager it does nothing.
+func (c *Client) activateWindow(ctx context.Context, app, frame x.Window) (bool, error) {
+	if _, ok := c.ewmhCheckWindow(ctx); !ok {
+		return false, nil
+	}
+	activeAtom := c.getAtom(ctx, "_NET_ACTIVE_WINDOW")
+	current, _ := c.getUint32Property(ctx, c.root, activeAtom, x.AtomWindow)
+
+	// Source 2 is a pager or taskbar, which focus stealing prevention lets
+	// through; the last value is the window active so far
+	if err := c.sendClientMessage(ctx, app, activeAtom, [5]uint32{2, x.TimeCurrentTime, current}); err != nil {
+		return false, fmt.Errorf("failed to activate window 0x%x: %w", uint32(app), err)
+	}
+
+	deadline := time.Now().Add(activateTimeout)
+	for {
+		if active, ok := c.getUint32Property(ctx, c.root, activeAtom, x.AtomWindow); ok && (x.Window(active) == app || x.Window(active) == frame) {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		if err := c.WaitContext(ctx, 20); err != nil {
+			return false, err
+		}
+	}
+}
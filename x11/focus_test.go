@@ -0,0 +1,49 @@
+package x11
+
+import (
+	"context"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestFocusWindowWithoutWM(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 0, 0, 300, 200, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	x.MapWindow(client.conn, win)
+	client.conn.Flush()
+
+	// Nothing answers _NET_ACTIVE_WINDOW, so the focus is set directly
+	if active, err := client.activateWindow(ctx, win, win); err != nil || active {
+		t.Errorf("activateWindow without a WM = %v, %v, want false", active, err)
+	}
+	if err := client.FocusWindowContext(ctx, win); err != nil {
+		t.Fatalf("FocusWindow failed: %v", err)
+	}
+	focus, err := x.GetInputFocus(client.conn).Reply(client.conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if focus.Focus != win {
+		t.Errorf("input focus is on 0x%x, want 0x%x", uint32(focus.Focus), uint32(win))
+	}
+}
@@ -0,0 +1,130 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Gallery layout constants
+const (
+	defaultThumbWidth = 320
+	galleryPadding    = 8
+	captionLines      = 2
+	captionLineHeight = 13
+)
+
+// GalleryEntry describes one tile of a window gallery
+type GalleryEntry struct {
+	Index  int
+	Window Window
+	Error  string // Set if the window could not be captured
+}
+
+// WindowGallery captures a thumbnail of every visible window and composites
+// them with ID/title/class captions into a single grid image
+func (c *Client) WindowGallery(ctx context.Context, thumbWidth int) (image.Image, []GalleryEntry, error) {
+	if thumbWidth <= 0 {
+		thumbWidth = defaultThumbWidth
+	}
+	thumbHeight := thumbWidth * 3 / 4
+
+	windows, err := c.ListWindowsContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(windows) == 0 {
+		return nil, nil, fmt.Errorf("no visible windows")
+	}
+
+	cols := int(math.Ceil(math.Sqrt(float64(len(windows)))))
+	rows := (len(windows) + cols - 1) / cols
+	tileWidth := thumbWidth + galleryPadding
+	tileHeight := thumbHeight + captionLines*captionLineHeight + galleryPadding*2
+
+	gallery := image.NewRGBA(image.Rect(0, 0,
+		cols*tileWidth+galleryPadding, rows*tileHeight+galleryPadding))
+	draw.Draw(gallery, gallery.Bounds(), image.NewUniform(color.RGBA{0x30, 0x30, 0x30, 0xff}), image.Point{}, draw.Src)
+
+	entries := make([]GalleryEntry, 0, len(windows))
+	for i, win := range windows {
+		entry := GalleryEntry{Index: i + 1, Window: win}
+		origin := image.Pt(galleryPadding+(i%cols)*tileWidth, galleryPadding+(i/cols)*tileHeight)
+
+		if shot, err := c.captureWindow(ctx, win.ID); err != nil {
+			entry.Error = err.Error()
+		} else {
+			w, h := fitSize(shot.Bounds().Dx(), shot.Bounds().Dy(), thumbWidth, thumbHeight)
+			thumb := scaleImage(shot, w, h)
+			c.frames.put(shot)
+			offset := image.Pt((thumbWidth-w)/2, (thumbHeight-h)/2)
+			draw.Draw(gallery, thumb.Bounds().Add(origin.Add(offset)), thumb, image.Point{}, draw.Src)
+		}
+
+		captionY := origin.Y + thumbHeight + captionLineHeight
+		drawCaption(gallery, origin.X, captionY, thumbWidth,
+			fmt.Sprintf("#%d 0x%x %s", entry.Index, uint32(win.ID), win.Class))
+		drawCaption(gallery, origin.X, captionY+captionLineHeight, thumbWidth, win.Title)
+
+		entries = append(entries, entry)
+	}
+
+	return gallery, entries, nil
+}
+
+// WindowGalleryPNG returns the window gallery encoded as PNG
+func (c *Client) WindowGalleryPNG(ctx context.Context, thumbWidth int) ([]byte, []GalleryEntry, error) {
+	img, entries, err := c.WindowGallery(ctx, thumbWidth)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := c.encodePNG(img)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, entries, nil
+}
+
+// WindowThumbnailPNG captures a window scaled down to fit maxWidth and 3/4 of
+// that in height, encoded as PNG. Hidden windows fail with a
+// *WindowHiddenError and are never restored.
+func (c *Client) WindowThumbnailPNG(ctx context.Context, win x.Window, maxWidth int) ([]byte, error) {
+	if maxWidth <= 0 {
+		maxWidth = defaultThumbWidth
+	}
+	if err := c.checkVisible(ctx, win); err != nil {
+		return nil, err
+	}
+	shot, err := c.captureWindow(ctx, win)
+	if err != nil {
+		return nil, err
+	}
+	defer c.frames.put(shot)
+	w, h := fitSize(shot.Bounds().Dx(), shot.Bounds().Dy(), maxWidth, maxWidth*3/4)
+	return c.encodePNG(scaleImage(shot, w, h))
+}
+
+// drawCaption draws a single line of text, truncated to fit maxWidth
+func drawCaption(dst draw.Image, x, y, maxWidth int, text string) {
+	face := basicfont.Face7x13
+	maxChars := maxWidth / face.Advance
+	if runes := []rune(text); len(runes) > maxChars && maxChars > 3 {
+		text = string(runes[:maxChars-3]) + "..."
+	}
+
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
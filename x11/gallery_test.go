@@ -0,0 +1,55 @@
+package x11
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWindowGallery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{
+		StartXvfb:  true,
+		Resolution: "1024x768",
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	pid, err := client.StartApp("xterm", []string{"-title", "Gallery Test"})
+	if err != nil {
+		t.Skipf("xterm not available: %v", err)
+	}
+	defer client.StopApp(pid)
+
+	// Wait for the window to appear
+	time.Sleep(500 * time.Millisecond)
+
+	img, entries, err := client.WindowGallery(context.Background(), 160)
+	if err != nil {
+		t.Fatalf("Failed to build gallery: %v", err)
+	}
+	if len(entries) == 0 {
+		t.Fatal("Expected at least one gallery entry")
+	}
+
+	found := false
+	for _, entry := range entries {
+		t.Logf("Entry #%d: ID=%d, Title=%q, Class=%q, Error=%q",
+			entry.Index, entry.Window.ID, entry.Window.Title, entry.Window.Class, entry.Error)
+		if entry.Window.Title == "Gallery Test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("xterm window not found in gallery")
+	}
+
+	if img.Bounds().Dx() < 160 {
+		t.Errorf("Gallery image too small: %v", img.Bounds())
+	}
+}
@@ -0,0 +1,129 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Grabs describes the active grabs other clients hold. While the pointer or
+// keyboard is grabbed, as by an open menu or a screen locker, the X server
+// delivers all input of the device to the grabbing client, so injected
+// clicks and keys never reach the window they were aimed at.
+type Grabs struct {
+	Pointer  bool   // The pointer is grabbed by another client
+	Keyboard bool   // The keyboard is grabbed by another client
+	Frozen   bool   // A synchronous grab froze event processing
+	Holder   string // Class of the topmost override-redirect window, likely the grab's owner, if any
+}
+
+// Any returns true if another client holds a grab
+func (g Grabs) Any() bool {
+	return g.Pointer || g.Keyboard || g.Frozen
+}
+
+// String describes the grabs, empty if there are none
+func (g Grabs) String() string {
+	var devices []string
+	if g.Pointer {
+		devices = append(devices, "pointer")
+	}
+	if g.Keyboard {
+		devices = append(devices, "keyboard")
+	}
+	if len(devices) == 0 && !g.Frozen {
+		return ""
+	}
+	text := "input frozen by another client's grab"
+	if len(devices) > 0 {
+		text = strings.Join(devices, " and ") + " grabbed by another client"
+		if g.Frozen {
+			text += ", input frozen"
+		}
+	}
+	if g.Holder != "" {
+		text += fmt.Sprintf(" (probably %s)", g.Holder)
+	}
+	return text
+}
+
+// DetectGrabs tells whether another client holds a pointer or keyboard
+// grab by trying to grab each device on the root window and releasing it
+// right away. The X server has no way to ask who holds a grab, so Holder
+// is a guess from the windows that menus and lockers map. The probe sends
+// focus and crossing events to applications, so it is refused with
+// ErrObserver in observer mode.
+func (c *Client) DetectGrabs(ctx context.Context) (Grabs, error) {
+	if c.observer {
+		return Grabs{}, ErrObserver
+	}
+	var g Grabs
+	pointer, err := await(c, ctx, func() (*x.GrabPointerReply, error) {
+		return x.GrabPointer(c.conn, false, c.root, 0, x.GrabModeAsync, x.GrabModeAsync,
+			x.None, x.None, x.CurrentTime).Reply(c.conn)
+	})
+	if err != nil {
+		return Grabs{}, fmt.Errorf("failed to check the pointer grab: %w", err)
+	}
+	if pointer.Status == x.GrabStatusSuccess {
+		x.UngrabPointer(c.conn, x.CurrentTime)
+	}
+	keyboard, err := await(c, ctx, func() (*x.GrabKeyboardReply, error) {
+		return x.GrabKeyboard(c.conn, false, c.root, x.CurrentTime, x.GrabModeAsync, x.GrabModeAsync).Reply(c.conn)
+	})
+	if err != nil {
+		c.conn.Flush()
+		return Grabs{}, fmt.Errorf("failed to check the keyboard grab: %w", err)
+	}
+	if keyboard.Status == x.GrabStatusSuccess {
+		x.UngrabKeyboard(c.conn, x.CurrentTime)
+	}
+	c.conn.Flush()
+
+	g.Pointer = pointer.Status == x.GrabStatusAlreadyGrabbed
+	g.Keyboard = keyboard.Status == x.GrabStatusAlreadyGrabbed
+	g.Frozen = pointer.Status == x.GrabStatusFrozen || keyboard.Status == x.GrabStatusFrozen
+	if g.Any() {
+		g.Holder = c.grabHolder(ctx)
+	}
+	return g, nil
+}
+
+// grabHolder returns the class of the topmost mapped override-redirect
+// window, the kind menus, popups and screen lockers grab input with
+func (c *Client) grabHolder(ctx context.Context) string {
+	for _, win := range c.overrideRedirectWindows(ctx) {
+		if class := c.getWindowClass(ctx, win); class != "" {
+			return class
+		}
+	}
+	return ""
+}
+
+// overrideRedirectWindows returns the mapped override-redirect children of
+// the root that other clients created, topmost first
+func (c *Client) overrideRedirectWindows(ctx context.Context) []x.Window {
+	tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+		return x.QueryTree(c.conn, c.root).Reply(c.conn)
+	})
+	if err != nil {
+		return nil
+	}
+	var windows []x.Window
+	for i := len(tree.Children) - 1; i >= 0; i-- {
+		win := tree.Children[i]
+		if c.ownWindow(win) {
+			continue
+		}
+		attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+			return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+		})
+		if err != nil || attrs.MapState != x.MapStateViewable || !attrs.OverrideRedirect {
+			continue
+		}
+		windows = append(windows, win)
+	}
+	return windows
+}
@@ -0,0 +1,79 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestGrabsString(t *testing.T) {
+	tests := []struct {
+		grabs Grabs
+		want  string
+	}{
+		{Grabs{}, ""},
+		{Grabs{Holder: "Firefox"}, ""},
+		{Grabs{Pointer: true}, "pointer grabbed by another client"},
+		{Grabs{Pointer: true, Keyboard: true, Holder: "i3lock"}, "pointer and keyboard grabbed by another client (probably i3lock)"},
+		{Grabs{Keyboard: true, Frozen: true}, "keyboard grabbed by another client, input frozen"},
+		{Grabs{Frozen: true}, "input frozen by another client's grab"},
+	}
+	for _, tt := range tests {
+		if got := tt.grabs.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.grabs, got, tt.want)
+		}
+		if tt.grabs.Any() != (tt.want != "") {
+			t.Errorf("%+v.Any() = %v", tt.grabs, tt.grabs.Any())
+		}
+	}
+}
+
+func TestDetectGrabsObserver(t *testing.T) {
+	// The probe grabs the devices, which observer mode rules out
+	c := &Client{observer: true}
+	if _, err := c.DetectGrabs(context.Background()); !errors.Is(err, ErrObserver) {
+		t.Errorf("DetectGrabs in observer mode = %v, want ErrObserver", err)
+	}
+}
+
+func TestDetectGrabs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	grabs, err := client.DetectGrabs(ctx)
+	if err != nil {
+		t.Fatalf("DetectGrabs failed: %v", err)
+	}
+	if grabs.Any() {
+		t.Fatalf("DetectGrabs on an idle display = %+v", grabs)
+	}
+
+	// Another client grabbing the pointer, like an open menu
+	other, err := x.NewConnDisplay(client.GetDisplay())
+	if err != nil {
+		t.Fatalf("Failed to open a second connection: %v", err)
+	}
+	defer other.Close()
+	reply, err := x.GrabPointer(other, false, other.GetDefaultScreen().Root, 0, x.GrabModeAsync, x.GrabModeAsync,
+		x.None, x.None, x.CurrentTime).Reply(other)
+	if err != nil || reply.Status != x.GrabStatusSuccess {
+		t.Fatalf("GrabPointer failed: %v, %+v", err, reply)
+	}
+
+	if grabs, err = client.DetectGrabs(ctx); err != nil {
+		t.Fatalf("DetectGrabs failed: %v", err)
+	}
+	if !grabs.Pointer || grabs.Keyboard {
+		t.Errorf("DetectGrabs with the pointer grabbed = %+v", grabs)
+	}
+}
@@ -0,0 +1,122 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Highlight limits
+const (
+	DefaultHighlightDuration = 1500 * time.Millisecond
+	MaxHighlightDuration     = 10 * time.Second
+	highlightBorder          = 4 // Pixels of the rectangle outline
+)
+
+// highlightColors are the color names ParseHighlightColor accepts
+var highlightColors = map[string]uint32{
+	"red":     0xff3030,
+	"green":   0x30d030,
+	"blue":    0x3070ff,
+	"yellow":  0xffe020,
+	"orange":  0xff9020,
+	"magenta": 0xff30ff,
+	"cyan":    0x30e0e0,
+}
+
+// ParseHighlightColor parses a color name or #RRGGBB, defaulting to red
+func ParseHighlightColor(s string) (uint32, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return highlightColors["red"], nil
+	}
+	if rgb, ok := highlightColors[s]; ok {
+		return rgb, nil
+	}
+	if hex, ok := strings.CutPrefix(s, "#"); ok && len(hex) == 6 {
+		if rgb, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return uint32(rgb), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid highlight color %q: expected #RRGGBB or red, green, blue, yellow, orange, magenta or cyan", s)
+}
+
+// HighlightDuration returns how long Highlight shows an outline asked to
+// stay for duration: the default if it's not positive, capped at the maximum
+func HighlightDuration(duration time.Duration) time.Duration {
+	if duration <= 0 {
+		return DefaultHighlightDuration
+	}
+	return min(duration, MaxHighlightDuration)
+}
+
+// highlightEdges returns the four bars of an outline of width border drawn
+// just outside rect, so the outline never covers what it points at
+func highlightEdges(rect image.Rectangle, border int) [4]image.Rectangle {
+	outer := rect.Inset(-border)
+	return [4]image.Rectangle{
+		image.Rect(outer.Min.X, outer.Min.Y, outer.Max.X, rect.Min.Y),
+		image.Rect(outer.Min.X, rect.Max.Y, outer.Max.X, outer.Max.Y),
+		image.Rect(outer.Min.X, rect.Min.Y, rect.Min.X, rect.Max.Y),
+		image.Rect(rect.Max.X, rect.Min.Y, outer.Max.X, rect.Max.Y),
+	}
+}
+
+// Highlight outlines rect, in root coordinates, in the given 0xRRGGBB color
+// for duration, so a human watching the display can follow what is about
+// to happen. It returns right away; the outline is removed in the
+// background. The outline is made of override-redirect windows around the
+// rectangle, which window managers leave alone and which don't take the
+// clicks meant for the inside, but they do show up in screenshots.
+func (c *Client) Highlight(ctx context.Context, rect image.Rectangle, rgb uint32, duration time.Duration) error {
+	rect = rect.Canon()
+	if rect.Empty() || !rect.Overlaps(c.screenBounds()) {
+		return fmt.Errorf("highlight region %v is empty or outside the screen %v", rect, c.screenBounds())
+	}
+	duration = HighlightDuration(duration)
+
+	var wins []x.Window
+	destroy := func() {
+		for _, win := range wins {
+			x.DestroyWindow(c.conn, win)
+		}
+		c.conn.Flush()
+	}
+	for _, edge := range highlightEdges(rect, highlightBorder) {
+		xid, err := c.conn.AllocID()
+		if err != nil {
+			destroy()
+			return fmt.Errorf("failed to allocate window ID: %w", err)
+		}
+		win := x.Window(xid)
+		err = awaitCheck(c, ctx, func() error {
+			return x.CreateWindowChecked(c.conn, 0, win, c.root,
+				int16(edge.Min.X), int16(edge.Min.Y), uint16(edge.Dx()), uint16(edge.Dy()), 0,
+				x.WindowClassInputOutput, 0, x.CWBackPixel|x.CWOverrideRedirect, []uint32{rgb, 1}).Check(c.conn)
+		})
+		if err != nil {
+			destroy()
+			return fmt.Errorf("failed to create highlight window: %w", err)
+		}
+		wins = append(wins, win)
+		x.MapWindow(c.conn, win)
+	}
+	c.conn.Flush()
+
+	time.AfterFunc(duration, destroy)
+	return nil
+}
+
+// HighlightWindow outlines a window like Highlight does a region
+func (c *Client) HighlightWindow(ctx context.Context, win x.Window, rgb uint32, duration time.Duration) (image.Rectangle, error) {
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	return rect, c.Highlight(ctx, rect, rgb, duration)
+}
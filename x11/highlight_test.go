@@ -0,0 +1,100 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestParseHighlightColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint32
+	}{
+		{"", 0xff3030},
+		{"Blue", 0x3070ff},
+		{"#00ff7F", 0x00ff7f},
+	}
+	for _, tt := range tests {
+		got, err := ParseHighlightColor(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseHighlightColor(%q) = %06x, %v, want %06x", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"purple-ish", "#fff", "#gggggg"} {
+		if _, err := ParseHighlightColor(bad); err == nil {
+			t.Errorf("ParseHighlightColor(%q): expected error", bad)
+		}
+	}
+}
+
+func TestHighlightEdges(t *testing.T) {
+	rect := image.Rect(100, 50, 300, 150)
+	edges := highlightEdges(rect, 4)
+	want := [4]image.Rectangle{
+		image.Rect(96, 46, 304, 50),
+		image.Rect(96, 150, 304, 154),
+		image.Rect(96, 50, 100, 150),
+		image.Rect(300, 50, 304, 150),
+	}
+	if edges != want {
+		t.Errorf("highlightEdges = %v, want %v", edges, want)
+	}
+	for _, edge := range edges {
+		if edge.Overlaps(rect) {
+			t.Errorf("edge %v covers the highlighted region", edge)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Highlight(ctx, image.Rect(100, 100, 200, 150), 0xff0000, 300*time.Millisecond); err != nil {
+		t.Fatalf("Highlight failed: %v", err)
+	}
+	img, err := client.Screenshot()
+	if err != nil {
+		t.Fatalf("Screenshot failed: %v", err)
+	}
+	if r, g, b, _ := img.At(150, 98).RGBA(); r>>8 != 0xff || g>>8 != 0 || b>>8 != 0 {
+		t.Errorf("outline pixel = %02x%02x%02x, want ff0000", r>>8, g>>8, b>>8)
+	}
+
+	client.Wait(600)
+	img, err = client.Screenshot()
+	if err != nil {
+		t.Fatalf("Screenshot failed: %v", err)
+	}
+	if r, _, _, _ := img.At(150, 98).RGBA(); r>>8 == 0xff {
+		t.Error("outline still shown after its duration")
+	}
+
+	if err := client.Highlight(ctx, image.Rect(900, 900, 950, 950), 0xff0000, 0); err == nil {
+		t.Error("expected error for a region outside the screen")
+	}
+}
+
+func TestHighlightDuration(t *testing.T) {
+	tests := []struct{ in, want time.Duration }{
+		{0, DefaultHighlightDuration},
+		{-time.Second, DefaultHighlightDuration},
+		{2 * time.Second, 2 * time.Second},
+		{time.Hour, MaxHighlightDuration},
+	}
+	for _, tt := range tests {
+		if got := HighlightDuration(tt.in); got != tt.want {
+			t.Errorf("HighlightDuration(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
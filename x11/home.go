@@ -0,0 +1,62 @@
+package x11
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// isolatedHomeDirs are the XDG base directories set up in an isolated home,
+// relative to it. XDG_RUNTIME_DIR is left alone so programs still find the
+// user's D-Bus, audio and accessibility sockets.
+var isolatedHomeDirs = map[string]string{
+	"XDG_CONFIG_HOME": ".config",
+	"XDG_DATA_HOME":   ".local/share",
+	"XDG_CACHE_HOME":  ".cache",
+	"XDG_STATE_HOME":  ".local/state",
+}
+
+// setupIsolatedHome creates an empty home directory with the XDG base
+// directories for the programs we start, so they neither read nor change
+// the user's dotfiles
+func (c *Client) setupIsolatedHome() error {
+	home, err := os.MkdirTemp("", "mcp-x11-home-*")
+	if err != nil {
+		return fmt.Errorf("failed to create isolated home: %w", err)
+	}
+	env := map[string]string{"HOME": home}
+	for name, dir := range isolatedHomeDirs {
+		path := filepath.Join(home, dir)
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			os.RemoveAll(home)
+			return fmt.Errorf("failed to create isolated home: %w", err)
+		}
+		env[name] = path
+	}
+
+	// Xlib looks for the display's cookie in ~/.Xauthority when XAUTHORITY
+	// isn't set, which would no longer find the real one
+	if os.Getenv("XAUTHORITY") == "" {
+		if real, err := os.UserHomeDir(); err == nil {
+			if auth := filepath.Join(real, ".Xauthority"); fileExists(auth) {
+				env["XAUTHORITY"] = auth
+			}
+		}
+	}
+
+	c.home = home
+	c.homeEnv = env
+	return nil
+}
+
+// IsolatedHome returns the throwaway home directory of launched programs, or
+// "" if they use the real one
+func (c *Client) IsolatedHome() string {
+	return c.home
+}
+
+// fileExists returns true if path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,64 @@
+package x11
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetupIsolatedHome(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("XAUTHORITY", "/tmp/test-xauthority")
+
+	c := &Client{}
+	if err := c.setupIsolatedHome(); err != nil {
+		t.Fatalf("setupIsolatedHome() error = %v", err)
+	}
+	home := c.IsolatedHome()
+	if !strings.HasPrefix(filepath.Base(home), "mcp-x11-home-") {
+		t.Errorf("IsolatedHome() = %q", home)
+	}
+	if c.homeEnv["HOME"] != home {
+		t.Errorf("HOME = %q, want %q", c.homeEnv["HOME"], home)
+	}
+	for name, dir := range isolatedHomeDirs {
+		want := filepath.Join(home, dir)
+		if c.homeEnv[name] != want {
+			t.Errorf("%s = %q, want %q", name, c.homeEnv[name], want)
+		}
+		if info, err := os.Stat(want); err != nil || !info.IsDir() {
+			t.Errorf("%s was not created: %v", want, err)
+		}
+	}
+	if _, ok := c.homeEnv["XAUTHORITY"]; ok {
+		t.Error("XAUTHORITY overridden although it was set")
+	}
+
+	c.Close()
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Errorf("isolated home not removed on Close: %v", err)
+	}
+}
+
+func TestStartAppIsolatedHome(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	c := &Client{homeEnv: map[string]string{"HOME": "/isolated", "XDG_CONFIG_HOME": "/isolated/.config"}}
+	pid, err := c.startApp("sh", []string{"-c", `echo "$HOME $XDG_CONFIG_HOME" > "$0"`, out}, map[string]string{"XDG_CONFIG_HOME": "/custom"}, AppStatus{})
+	if err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+	c.apps.mu.Lock()
+	done := c.apps.apps[pid].done
+	c.apps.mu.Unlock()
+	<-done
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Explicit environment variables win over the isolated home
+	if got := strings.TrimSpace(string(data)); got != "/isolated /custom" {
+		t.Errorf("environment = %q, want \"/isolated /custom\"", got)
+	}
+}
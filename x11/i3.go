@@ -1,78 +1,312 @@
 package x11
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	x "github.com/linuxdeepin/go-x11-client"
 	"go.i3wm.org/i3/v4"
 )
 
+// DefaultI3Timeout bounds i3 IPC calls when the caller's context has no deadline
+const DefaultI3Timeout = 5 * time.Second
+
+// maxI3Backoff caps the pause between attempts to reconnect to i3
+const maxI3Backoff = 30 * time.Second
+
+// ErrI3Unresponsive is returned when i3 does not answer an IPC call in time
+var ErrI3Unresponsive = errors.New("window manager unresponsive")
+
+// i3State tracks the i3 IPC connection
+type i3State struct {
+	mu           sync.Mutex
+	socketPath   string        // Socket override used by ConnectI3
+	timeout      time.Duration // Timeout for IPC calls
+	reconnecting bool          // Whether a reconnect attempt is running
+	closed       bool          // The client was closed, reconnecting stops
+}
+
+// i3Socket is the socket path the i3 package's SocketPathHook returns.
+// The i3 package reads the hook without locking, so it is installed only
+// once and the path is changed under mu instead.
+var i3Socket struct {
+	mu   sync.Mutex
+	path string // Empty to let the i3 package find the socket
+	once sync.Once
+}
+
+// setI3SocketPath makes i3 IPC calls use path, or find the socket of the
+// running i3 if path is empty. It applies to all clients.
+func setI3SocketPath(path string) {
+	i3Socket.once.Do(func() {
+		lookup := i3.SocketPathHook
+		i3.SocketPathHook = func() (string, error) {
+			i3Socket.mu.Lock()
+			path := i3Socket.path
+			i3Socket.mu.Unlock()
+			if path == "" {
+				return lookup()
+			}
+			return path, nil
+		}
+	})
+	i3Socket.mu.Lock()
+	i3Socket.path = path
+	i3Socket.mu.Unlock()
+}
+
 // I3Enabled returns true if i3 connection is available
 func (c *Client) I3Enabled() bool {
+	c.i3.mu.Lock()
+	defer c.i3.mu.Unlock()
 	return c.i3Connected
 }
 
+// SetI3Timeout changes the timeout for i3 IPC calls
+func (c *Client) SetI3Timeout(d time.Duration) {
+	c.i3.mu.Lock()
+	c.i3.timeout = d
+	c.i3.mu.Unlock()
+}
+
+// i3Timeout returns the effective i3 IPC timeout
+func (c *Client) i3Timeout() time.Duration {
+	c.i3.mu.Lock()
+	defer c.i3.mu.Unlock()
+	if c.i3.timeout <= 0 {
+		return DefaultI3Timeout
+	}
+	return c.i3.timeout
+}
+
 // ConnectI3 establishes a connection to i3 window manager
 func (c *Client) ConnectI3(socketPath string) error {
-	// Test if i3 is running by trying to get version
-	if socketPath != "" {
-		// Override socket path for testing
-		oldHook := i3.SocketPathHook
-		i3.SocketPathHook = func() (string, error) {
-			return socketPath, nil
-		}
-		defer func() {
-			i3.SocketPathHook = oldHook
-		}()
-	}
-	
+	c.i3.mu.Lock()
+	c.i3.socketPath = socketPath
+	c.i3.mu.Unlock()
+	setI3SocketPath(socketPath)
+
 	// Try to get i3 version to test connection
-	_, err := i3.GetVersion()
-	if err != nil {
-		// Not an error if i3 is not running, just means i3 features won't be available
+	_, err := awaitTimeout(context.Background(), c.i3Timeout(), i3.GetVersion)
+
+	// Not an error if i3 is not running, just means i3 features won't be available
+	c.i3.mu.Lock()
+	c.i3Connected = err == nil
+	c.i3.mu.Unlock()
+	return nil
+}
+
+// i3Call runs an i3 IPC call with the i3 timeout. If i3 does not answer in
+// time, it is marked unavailable and a reconnect is attempted in the background.
+func i3Call[T any](c *Client, ctx context.Context, fn func() (T, error)) (T, error) {
+	value, err := awaitTimeout(ctx, c.i3Timeout(), fn)
+	if errors.Is(err, ErrRequestTimeout) {
+		c.i3.mu.Lock()
 		c.i3Connected = false
-		return nil
+		c.i3.mu.Unlock()
+		go c.reconnectI3()
+		return value, fmt.Errorf("%w: i3 did not answer within %v", ErrI3Unresponsive, c.i3Timeout())
+	}
+	return value, err
+}
+
+// reconnectI3 retries the i3 connection with backoff until it answers again
+// or the client is closed
+func (c *Client) reconnectI3() {
+	c.i3.mu.Lock()
+	if c.i3.reconnecting {
+		c.i3.mu.Unlock()
+		return
+	}
+	c.i3.reconnecting = true
+	socketPath := c.i3.socketPath
+	c.i3.mu.Unlock()
+
+	defer func() {
+		c.i3.mu.Lock()
+		c.i3.reconnecting = false
+		c.i3.mu.Unlock()
+	}()
+
+	backoff := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		time.Sleep(backoff)
+		c.i3.mu.Lock()
+		closed := c.i3.closed
+		c.i3.mu.Unlock()
+		if closed {
+			return
+		}
+		c.ConnectI3(socketPath)
+		if c.I3Enabled() {
+			slog.Info("reconnected to i3", "attempts", attempt)
+			return
+		}
+		if attempt == 5 {
+			slog.Warn("i3 is still unresponsive, i3 tools are unavailable until it answers")
+		}
+		backoff = min(2*backoff, maxI3Backoff)
 	}
-	
-	c.i3Connected = true
-	return nil
 }
 
 // I3GetTree returns the i3 window tree as JSON
 func (c *Client) I3GetTree() (string, error) {
+	return c.I3GetTreeContext(context.Background())
+}
+
+// I3GetTreeContext is like I3GetTree but gives up when ctx is done
+func (c *Client) I3GetTreeContext(ctx context.Context) (string, error) {
 	if !c.I3Enabled() {
 		return "", fmt.Errorf("i3 is not connected")
 	}
-	
-	tree, err := i3.GetTree()
+
+	tree, err := i3Call(c, ctx, i3.GetTree)
 	if err != nil {
 		return "", fmt.Errorf("failed to get i3 tree: %w", err)
 	}
-	
+
 	// Convert to JSON for easy consumption
 	jsonData, err := json.MarshalIndent(tree.Root, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal tree: %w", err)
 	}
-	
+
 	return string(jsonData), nil
 }
 
+// I3FocusedWorkspace returns the name of the focused i3 workspace
+func (c *Client) I3FocusedWorkspace(ctx context.Context) (string, error) {
+	if !c.I3Enabled() {
+		return "", fmt.Errorf("i3 is not connected")
+	}
+
+	workspaces, err := i3Call(c, ctx, i3.GetWorkspaces)
+	if err != nil {
+		return "", fmt.Errorf("failed to get i3 workspaces: %w", err)
+	}
+	for _, ws := range workspaces {
+		if ws.Focused {
+			return ws.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no focused i3 workspace")
+}
+
+// i3WindowWorkspace returns the name of the i3 workspace holding an X
+// window, or "" if i3 doesn't manage it
+func (c *Client) i3WindowWorkspace(ctx context.Context, win x.Window) string {
+	tree, err := i3Call(c, ctx, i3.GetTree)
+	if err != nil {
+		return ""
+	}
+	return i3Workspace(tree.Root, int64(win), "")
+}
+
+// i3Workspace searches node for the container of an X window and returns
+// the name of its workspace; workspace is that of node itself
+func i3Workspace(node *i3.Node, win int64, workspace string) string {
+	if node.Type == i3.WorkspaceNode {
+		workspace = node.Name
+	}
+	if node.Window == win {
+		return workspace
+	}
+	for _, children := range [][]*i3.Node{node.Nodes, node.FloatingNodes} {
+		for _, child := range children {
+			if ws := i3Workspace(child, win, workspace); ws != "" {
+				return ws
+			}
+		}
+	}
+	return ""
+}
+
+// I3Focused describes the focused i3 container
+type I3Focused struct {
+	ConID     int64
+	Window    x.Window // 0 for containers without a window, like an empty workspace
+	Class     string
+	Title     string // Window title, or the container's name
+	Workspace string
+}
+
+// I3FocusedContainer returns the focused container. i3 has no request for
+// it alone, so the tree is still read, but callers get a few fields instead
+// of the whole tree.
+func (c *Client) I3FocusedContainer(ctx context.Context) (I3Focused, error) {
+	if !c.I3Enabled() {
+		return I3Focused{}, fmt.Errorf("i3 is not connected")
+	}
+	tree, err := i3Call(c, ctx, i3.GetTree)
+	if err != nil {
+		return I3Focused{}, fmt.Errorf("failed to get i3 tree: %w", err)
+	}
+	focused, ok := i3FindFocused(tree.Root, "")
+	if !ok {
+		return I3Focused{}, fmt.Errorf("i3 reports no focused container")
+	}
+	return focused, nil
+}
+
+// i3FindFocused searches node for the focused container; workspace is that
+// of node itself
+func i3FindFocused(node *i3.Node, workspace string) (I3Focused, bool) {
+	if node.Type == i3.WorkspaceNode {
+		workspace = node.Name
+	}
+	if node.Focused {
+		title := node.WindowProperties.Title
+		if title == "" {
+			title = node.Name
+		}
+		return I3Focused{
+			ConID:     int64(node.ID),
+			Window:    x.Window(node.Window),
+			Class:     node.WindowProperties.Class,
+			Title:     title,
+			Workspace: workspace,
+		}, true
+	}
+
+	for _, children := range [][]*i3.Node{node.Nodes, node.FloatingNodes} {
+		for _, child := range children {
+			if found, ok := i3FindFocused(child, workspace); ok {
+				return found, true
+			}
+		}
+	}
+	return I3Focused{}, false
+}
+
 // I3Command sends a command to i3
 func (c *Client) I3Command(command string) (string, error) {
+	return c.I3CommandContext(context.Background(), command)
+}
+
+// I3CommandContext is like I3Command but gives up when ctx is done
+func (c *Client) I3CommandContext(ctx context.Context, command string) (string, error) {
 	if !c.I3Enabled() {
 		return "", fmt.Errorf("i3 is not connected")
 	}
-	
+
 	if command == "" {
 		return "", fmt.Errorf("command cannot be empty")
 	}
-	
-	replies, err := i3.RunCommand(command)
+
+	replies, err := i3Call(c, ctx, func() ([]i3.CommandResult, error) {
+		return i3.RunCommand(command)
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to run i3 command: %w", err)
 	}
-	
+
 	// Format responses
 	var results []string
 	for _, reply := range replies {
@@ -86,10 +320,92 @@ func (c *Client) I3Command(command string) (string, error) {
 			}
 		}
 	}
-	
+
 	// Return a simple string representation
 	if len(results) == 1 {
 		return results[0], nil
 	}
 	return fmt.Sprintf("%v", results), nil
-}
\ No newline at end of file
+}
+
+// i3 border styles accepted by I3Border
+var i3BorderStyles = []string{"none", "pixel", "normal"}
+
+// i3Criteria returns the criteria selecting win, or "" for the focused
+// container if win is 0
+func i3Criteria(win x.Window) string {
+	if win == 0 {
+		return ""
+	}
+	return fmt.Sprintf("[id=%d] ", uint32(win))
+}
+
+// i3FullscreenCommand builds the command for I3Fullscreen
+func i3FullscreenCommand(win x.Window, mode string) (string, error) {
+	switch mode {
+	case "":
+		mode = "toggle"
+	case "toggle", "enable", "disable":
+	default:
+		return "", fmt.Errorf("unknown fullscreen mode: %s (available: toggle, enable, disable)", mode)
+	}
+	return i3Criteria(win) + "fullscreen " + mode, nil
+}
+
+// i3BorderCommand builds the command for I3Border
+func i3BorderCommand(win x.Window, style string, width int) (string, error) {
+	if !slices.Contains(i3BorderStyles, style) {
+		return "", fmt.Errorf("unknown border style: %s (available: %s)", style, strings.Join(i3BorderStyles, ", "))
+	}
+	if width < 0 || (style == "none" && width != 0) {
+		return "", fmt.Errorf("invalid border width %d for style %s", width, style)
+	}
+	cmd := i3Criteria(win) + "border " + style
+	if width > 0 {
+		cmd += fmt.Sprintf(" %d", width)
+	}
+	return cmd, nil
+}
+
+// I3Fullscreen toggles, enables or disables fullscreen for the window's
+// container, or the focused one if win is 0. An empty mode toggles.
+func (c *Client) I3Fullscreen(ctx context.Context, win x.Window, mode string) error {
+	cmd, err := i3FullscreenCommand(c.i3Target(ctx, win), mode)
+	if err != nil {
+		return err
+	}
+	return c.i3Run(ctx, cmd)
+}
+
+// I3Border sets the border style of the window's container, or the focused
+// one if win is 0: none, pixel or normal (with a title bar), with a width in
+// pixels or 0 for i3's default. Borderless windows keep their content at the
+// same coordinates whatever the theme and font.
+func (c *Client) I3Border(ctx context.Context, win x.Window, style string, width int) error {
+	cmd, err := i3BorderCommand(c.i3Target(ctx, win), style, width)
+	if err != nil {
+		return err
+	}
+	return c.i3Run(ctx, cmd)
+}
+
+// i3Target returns the application window inside win, since i3 criteria
+// match application windows and not the frames around them
+func (c *Client) i3Target(ctx context.Context, win x.Window) x.Window {
+	if win == 0 {
+		return 0
+	}
+	return c.clientWindow(ctx, win)
+}
+
+// i3Run runs an i3 command and turns an unsuccessful reply into an error
+func (c *Client) i3Run(ctx context.Context, command string) error {
+	result, err := c.I3CommandContext(ctx, command)
+	if err != nil {
+		return err
+	}
+	if result != "Success" {
+		return fmt.Errorf("i3 command %q failed: %s", command, result)
+	}
+	return nil
+}
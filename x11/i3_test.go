@@ -1,9 +1,13 @@
 package x11
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	x "github.com/linuxdeepin/go-x11-client"
 	"go.i3wm.org/i3/v4"
 )
 
@@ -252,4 +256,121 @@ func findNodeByTitle(tree *i3.Node, title string) *i3.Node {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+func TestI3CallTimeout(t *testing.T) {
+	client := &Client{i3Connected: true}
+	client.SetI3Timeout(20 * time.Millisecond)
+
+	block := make(chan struct{})
+	defer close(block)
+
+	_, err := i3Call(client, context.Background(), func() (i3.Tree, error) {
+		<-block
+		return i3.Tree{}, nil
+	})
+	if !errors.Is(err, ErrI3Unresponsive) {
+		t.Fatalf("expected ErrI3Unresponsive, got %v", err)
+	}
+	if client.I3Enabled() {
+		t.Error("expected i3 to be marked unavailable after a timeout")
+	}
+}
+
+func TestI3FullscreenCommand(t *testing.T) {
+	tests := []struct {
+		win     uint32
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{0, "", "fullscreen toggle", false},
+		{0x1e00003, "enable", "[id=31457283] fullscreen enable", false},
+		{0, "disable", "fullscreen disable", false},
+		{0, "maximize", "", true},
+	}
+	for _, tt := range tests {
+		got, err := i3FullscreenCommand(x.Window(tt.win), tt.mode)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("i3FullscreenCommand(0x%x, %q) = %q, %v; want %q", tt.win, tt.mode, got, err, tt.want)
+		}
+	}
+}
+
+func TestI3BorderCommand(t *testing.T) {
+	tests := []struct {
+		win     uint32
+		style   string
+		width   int
+		want    string
+		wantErr bool
+	}{
+		{0, "none", 0, "border none", false},
+		{0, "pixel", 2, "border pixel 2", false},
+		{42, "normal", 0, "[id=42] border normal", false},
+		{0, "none", 3, "", true},
+		{0, "pixel", -1, "", true},
+		{0, "thick", 0, "", true},
+	}
+	for _, tt := range tests {
+		got, err := i3BorderCommand(x.Window(tt.win), tt.style, tt.width)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("i3BorderCommand(%d, %q, %d) = %q, %v; want %q", tt.win, tt.style, tt.width, got, err, tt.want)
+		}
+	}
+}
+
+func TestI3FindFocused(t *testing.T) {
+	tree := &i3.Node{
+		Type: i3.Root,
+		Nodes: []*i3.Node{
+			{Type: i3.OutputNode, Name: "screen", Nodes: []*i3.Node{
+				{ID: 2, Type: i3.WorkspaceNode, Name: "1", Nodes: []*i3.Node{{ID: 3, Type: i3.Con, Window: 10}}},
+				{ID: 4, Type: i3.WorkspaceNode, Name: "2: web", FloatingNodes: []*i3.Node{
+					{ID: 5, Type: i3.FloatingCon, Nodes: []*i3.Node{
+						{ID: 6, Type: i3.Con, Window: 20, Focused: true, Name: "Docs - Browser",
+							WindowProperties: i3.WindowProperties{Class: "Firefox", Title: "Docs"}},
+					}},
+				}},
+			}},
+		},
+	}
+	got, ok := i3FindFocused(tree, "")
+	want := I3Focused{ConID: 6, Window: 20, Class: "Firefox", Title: "Docs", Workspace: "2: web"}
+	if !ok || got != want {
+		t.Errorf("i3FindFocused = %+v, %v; want %+v", got, ok, want)
+	}
+
+	// An empty focused workspace has no window and is named by the container
+	tree.Nodes[0].Nodes[1].FloatingNodes[0].Nodes[0].Focused = false
+	tree.Nodes[0].Nodes[0].Nodes = nil
+	tree.Nodes[0].Nodes[0].Focused = true
+	got, ok = i3FindFocused(tree, "")
+	want = I3Focused{ConID: 2, Title: "1", Workspace: "1"}
+	if !ok || got != want {
+		t.Errorf("i3FindFocused on an empty workspace = %+v, %v; want %+v", got, ok, want)
+	}
+}
+
+func TestSetI3SocketPath(t *testing.T) {
+	defer setI3SocketPath("")
+
+	// Once installed, the hook can be read while the path changes
+	setI3SocketPath("/tmp/i3-test-a")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			i3.SocketPathHook()
+		}
+	}()
+	for range 100 {
+		setI3SocketPath("/tmp/i3-test-a")
+		setI3SocketPath("/tmp/i3-test-b")
+	}
+	<-done
+
+	if got, err := i3.SocketPathHook(); err != nil || got != "/tmp/i3-test-b" {
+		t.Errorf("SocketPathHook() = %q, %v, want /tmp/i3-test-b", got, err)
+	}
+}
@@ -0,0 +1,76 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// IconifyWindow minimizes a window without closing it. It asks the window
+// manager with a WM_CHANGE_STATE message, like XIconifyWindow (ICCCM
+// 4.1.4); i3, which has no minimized state, moves it to the scratchpad
+// instead. It waits until the window is no longer viewable.
+func (c *Client) IconifyWindow(ctx context.Context, win x.Window) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	app := c.clientWindow(ctx, win)
+	var err error
+	if c.I3Enabled() {
+		_, err = c.I3CommandContext(ctx, fmt.Sprintf("[id=%d] move scratchpad", uint32(app)))
+	} else {
+		err = c.sendClientMessage(ctx, app, c.getAtom(ctx, "WM_CHANGE_STATE"), [5]uint32{iconicState})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to iconify window 0x%x: %w", uint32(win), err)
+	}
+
+	return c.waitWindowState(ctx, win, false, "the window manager did not iconify it")
+}
+
+// DeiconifyWindow shows a window hidden by IconifyWindow again. Outside i3
+// the window is mapped, which ICCCM window managers take as a request to
+// restore it; unlike activating it, this leaves the focus alone. With i3 it
+// is taken out of the scratchpad back into the current workspace's layout.
+func (c *Client) DeiconifyWindow(ctx context.Context, win x.Window) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	app := c.clientWindow(ctx, win)
+	var err error
+	if c.I3Enabled() {
+		_, err = c.I3CommandContext(ctx, fmt.Sprintf("[id=%d] scratchpad show, floating disable", uint32(app)))
+	} else {
+		err = awaitCheck(c, ctx, func() error {
+			return x.MapWindowChecked(c.conn, app).Check(c.conn)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to deiconify window 0x%x: %w", uint32(win), err)
+	}
+
+	return c.waitWindowState(ctx, win, true, "the window manager did not restore it")
+}
+
+// waitWindowState waits up to restoreTimeout for win to become viewable or
+// hidden, failing with the given reason if it doesn't
+func (c *Client) waitWindowState(ctx context.Context, win x.Window, viewable bool, reason string) error {
+	deadline := time.Now().Add(restoreTimeout)
+	for {
+		err := c.checkVisible(ctx, win)
+		if _, hidden := err.(*WindowHiddenError); err != nil && !hidden {
+			return err
+		}
+		if (err == nil) == viewable {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("window 0x%x is unchanged after %s: %s", uint32(win), restoreTimeout, reason)
+		}
+		if err := c.WaitContext(ctx, 50); err != nil {
+			return err
+		}
+	}
+}
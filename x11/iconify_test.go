@@ -0,0 +1,47 @@
+package x11
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestIconifyWithoutWM(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatalf("AllocID failed: %v", err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 100, 100, 200, 100, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("CreateWindow failed: %v", err)
+	}
+
+	// Without a window manager mapping the window is all it takes
+	ctx := context.Background()
+	if err := client.DeiconifyWindow(ctx, win); err != nil {
+		t.Fatalf("DeiconifyWindow failed: %v", err)
+	}
+	if err := client.checkVisible(ctx, win); err != nil {
+		t.Errorf("window not visible after DeiconifyWindow: %v", err)
+	}
+
+	// but nobody acts on WM_CHANGE_STATE
+	err = client.IconifyWindow(ctx, win)
+	if err == nil || !strings.Contains(err.Error(), "did not iconify") {
+		t.Errorf("IconifyWindow without a window manager = %v, want an error", err)
+	}
+}
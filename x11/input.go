@@ -1,9 +1,15 @@
 package x11
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
 
 	"time"
 
@@ -21,271 +27,572 @@ const (
 	MotionNotify     = 6
 )
 
-// Wait pauses for the specified number of milliseconds
-func (c *Client) Wait(ms int) {
-	time.Sleep(time.Duration(ms) * time.Millisecond)
+// pointerMoveAttempts is how often MouseMove retries a motion that didn't land
+const pointerMoveAttempts = 3
+
+// heldInput tracks keys and buttons that are currently faked down
+type heldInput struct {
+	mu      sync.Mutex
+	keys    map[x.Keycode]bool
+	buttons map[byte]bool
 }
 
-// MouseMove moves the mouse cursor to the specified coordinates
-func (c *Client) MouseMove(x, y int) error {
-	// Use XTEST to move mouse
-	test.FakeInput(c.conn, MotionNotify, 0,
-		0, // time (0 = current time)
-		c.root, int16(x), int16(y), 0)
-	return nil
+// Wait pauses for the specified number of milliseconds. It returns ErrAborted
+// if AbortAll is called while waiting.
+func (c *Client) Wait(ms int) error {
+	return c.WaitContext(context.Background(), ms)
 }
 
-// MouseClick simulates a mouse button click
-func (c *Client) MouseClick(button int) error {
-	// Press and release the button
-	// Button press
-	test.FakeInput(c.conn, ButtonPress, byte(button),
-		0, // time
-		c.root, 0, 0, 0)
+// WaitContext is like Wait but also returns early when ctx is done
+func (c *Client) WaitContext(ctx context.Context, ms int) error {
+	timer := time.NewTimer(time.Duration(ms) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.abortChan():
+		return ErrAborted
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	// Button release
-	test.FakeInput(c.conn, ButtonRelease, byte(button),
-		0, // time
-		c.root, 0, 0, 0)
+// fakeKey sends a fake key press or release and records the held state.
+// Presses are paced while the X server is saturated, releases never are.
+func (c *Client) fakeKey(ctx context.Context, keycode x.Keycode, press bool) error {
+	evType := byte(KeyRelease)
+	if press {
+		evType = KeyPress
+		if err := c.paceInput(ctx); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	err := awaitCheck(c, ctx, func() error {
+		return test.FakeInputChecked(c.conn, evType, uint8(keycode),
+			0, c.root, 0, 0, 0).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fake key event for keycode %d: %w", keycode, err)
+	}
+	c.recordLatency(time.Since(start))
 
+	c.held.mu.Lock()
+	if c.held.keys == nil {
+		c.held.keys = make(map[x.Keycode]bool)
+	}
+	if press {
+		c.held.keys[keycode] = true
+	} else {
+		delete(c.held.keys, keycode)
+	}
+	c.held.mu.Unlock()
 	return nil
 }
 
-// Type simulates typing the given text
-func (c *Client) Type(text string) error {
-	for _, ch := range text {
-		// Handle newline as Enter key
-		if ch == '\n' {
-			if err := c.KeyPress("Enter"); err != nil {
-				return fmt.Errorf("failed to press Enter key: %w", err)
-			}
-		} else {
-			if err := c.typeChar(ch); err != nil {
-				return fmt.Errorf("failed to type character '%c': %w", ch, err)
-			}
+// fakeButton sends a fake button press or release and records the held state
+func (c *Client) fakeButton(ctx context.Context, button byte, press bool) error {
+	evType := byte(ButtonRelease)
+	if press {
+		evType = ButtonPress
+		if err := c.paceInput(ctx); err != nil {
+			return err
 		}
 	}
+	start := time.Now()
+	err := awaitCheck(c, ctx, func() error {
+		return test.FakeInputChecked(c.conn, evType, button,
+			0, // time
+			c.root, 0, 0, 0).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fake button %d event: %w", button, err)
+	}
+	c.recordLatency(time.Since(start))
+	c.trail.button(button, press)
+
+	c.held.mu.Lock()
+	if c.held.buttons == nil {
+		c.held.buttons = make(map[byte]bool)
+	}
+	if press {
+		c.held.buttons[button] = true
+	} else {
+		delete(c.held.buttons, button)
+	}
+	c.held.mu.Unlock()
 	return nil
 }
 
-// typeChar types a single character
-func (c *Client) typeChar(ch rune) error {
-	// Get keysym for the character
-	var keysym x.Keysym
-	var needShift bool
-
-	// Handle special characters that need shift
-	switch ch {
-	case '!': keysym, needShift = keysyms.XK_1, true
-	case '@': keysym, needShift = keysyms.XK_2, true
-	case '#': keysym, needShift = keysyms.XK_3, true
-	case '$': keysym, needShift = keysyms.XK_4, true
-	case '%': keysym, needShift = keysyms.XK_5, true
-	case '^': keysym, needShift = keysyms.XK_6, true
-	case '&': keysym, needShift = keysyms.XK_7, true
-	case '*': keysym, needShift = keysyms.XK_8, true
-	case '(': keysym, needShift = keysyms.XK_9, true
-	case ')': keysym, needShift = keysyms.XK_0, true
-	default:
-		// For regular characters
-		if unicode.IsUpper(ch) {
-			needShift = true
-			keysym = x.Keysym(unicode.ToLower(ch))
-		} else {
-			keysym = x.Keysym(ch)
+// ReleaseAll releases every key and button that is currently held down
+func (c *Client) ReleaseAll() error {
+	c.held.mu.Lock()
+	var keys []x.Keycode
+	for keycode := range c.held.keys {
+		keys = append(keys, keycode)
+	}
+	var buttons []byte
+	for button := range c.held.buttons {
+		buttons = append(buttons, button)
+	}
+	c.held.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	var firstErr error
+	for _, button := range buttons {
+		if err := c.fakeButton(ctx, button, false); err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
+	for _, keycode := range keys {
+		if err := c.fakeKey(ctx, keycode, false); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
 
-	// Get keycode for the keysym
-	keycode, err := c.keysymToKeycode(keysym)
+// fakeMotion sends a single absolute pointer motion without confirming it
+func (c *Client) fakeMotion(ctx context.Context, x, y int) error {
+	err := awaitCheck(c, ctx, func() error {
+		return test.FakeInputChecked(c.conn, MotionNotify, 0,
+			0, // time (0 = current time)
+			c.root, int16(x), int16(y), 0).Check(c.conn)
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to move mouse to (%d, %d): %w", x, y, err)
 	}
+	c.trail.motion(x, y)
+	return nil
+}
 
-	// Press shift if needed
-	if needShift {
-		shiftKeycode, _ := c.keysymToKeycode(keysyms.XK_Shift_L)
-		test.FakeInput(c.conn, KeyPress, uint8(shiftKeycode),
-			0, c.root, 0, 0, 0)
+// MouseMove moves the mouse cursor to the specified coordinates
+func (c *Client) MouseMove(x, y int) error {
+	return c.MouseMoveContext(context.Background(), x, y)
+}
+
+// MouseMoveContext is like MouseMove but gives up when ctx is done
+func (c *Client) MouseMoveContext(ctx context.Context, x, y int) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
 	}
 
-	// Press and release the key
-	test.FakeInput(c.conn, KeyPress, uint8(keycode),
-		0, c.root, 0, 0, 0)
+	if _, _, err := validatePoint(float64(x), float64(y), c.screenBounds(), "screen"); err != nil {
+		return err
+	}
+	x, y, err := c.confinePoint(ctx, x, y)
+	if err != nil {
+		return err
+	}
 
-	test.FakeInput(c.conn, KeyRelease, uint8(keycode),
-		0, c.root, 0, 0, 0)
+	// Move with XTEST, then check where the pointer really ended up. The
+	// server may not have applied the motion yet, or the pointer may still be
+	// on another screen, so retry a few times before giving up.
+	for attempt := 0; attempt < pointerMoveAttempts; attempt++ {
+		if err := c.fakeMotion(ctx, x, y); err != nil {
+			return err
+		}
 
-	// Release shift if it was pressed
-	if needShift {
-		shiftKeycode, _ := c.keysymToKeycode(keysyms.XK_Shift_L)
-		test.FakeInput(c.conn, KeyRelease, uint8(shiftKeycode),
-			0, c.root, 0, 0, 0)
+		reply, err := c.queryPointer(ctx)
+		if err != nil {
+			return err
+		}
+		if reply.SameScreen && int(reply.RootX) == x && int(reply.RootY) == y {
+			return nil
+		}
+		if err := c.WaitContext(ctx, 10); err != nil {
+			return err
+		}
 	}
 
+	// Leave it where the server put it; callers can report PointerPosition
+	if px, py, err := c.PointerPositionContext(ctx); err != nil {
+		slog.Warn("pointer did not reach target", "x", x, "y", y, "err", err)
+	} else {
+		slog.Warn("pointer landed off target", "x", px, "y", py, "target_x", x, "target_y", y)
+	}
 	return nil
 }
 
-// KeyPress simulates pressing a special key
-func (c *Client) KeyPress(key string) error {
-	keysym, err := c.keyNameToKeysym(key)
+// PointerPosition returns the pointer position on this client's screen
+func (c *Client) PointerPosition() (int, int, error) {
+	return c.PointerPositionContext(context.Background())
+}
+
+// PointerPositionContext is like PointerPosition but gives up when ctx is done.
+// It fails if the pointer is on a different screen.
+func (c *Client) PointerPositionContext(ctx context.Context) (int, int, error) {
+	reply, err := c.queryPointer(ctx)
 	if err != nil {
+		return 0, 0, err
+	}
+	if !reply.SameScreen {
+		return 0, 0, fmt.Errorf("pointer is on another screen (root %d)", reply.Root)
+	}
+	return int(reply.RootX), int(reply.RootY), nil
+}
+
+// queryPointer asks the server where the pointer is relative to our root
+func (c *Client) queryPointer(ctx context.Context) (*x.QueryPointerReply, error) {
+	cookie := x.QueryPointer(c.conn, c.root)
+	reply, err := await(c, ctx, func() (*x.QueryPointerReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pointer: %w", err)
+	}
+	return reply, nil
+}
+
+// MouseClick simulates a mouse button click
+func (c *Client) MouseClick(button int) error {
+	return c.MouseClickContext(context.Background(), button)
+}
+
+// MouseClickContext is like MouseClick but gives up when ctx is done
+func (c *Client) MouseClickContext(ctx context.Context, button int) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	if err := c.ValidateButton(ctx, button); err != nil {
+		return err
+	}
+	if err := c.checkConfined(ctx); err != nil {
 		return err
 	}
 
-	keycode, err := c.keysymToKeycode(keysym)
+	// Press and release the button
+	if err := c.fakeButton(ctx, byte(button), true); err != nil {
+		return err
+	}
+	return c.fakeButton(ctx, byte(button), false)
+}
+
+// Drag presses button at (fromX, fromY), moves the pointer to (toX, toY)
+// in steps motion events delayMs apart and releases the button there, for
+// sliders, drag and drop and rearranging items. With steps 0 the motion
+// follows the client's MotionProfile instead.
+func (c *Client) Drag(fromX, fromY, toX, toY, button, steps, delayMs int) error {
+	return c.DragContext(context.Background(), fromX, fromY, toX, toY, button, steps, delayMs)
+}
+
+// DragContext is like Drag but gives up when ctx is done. The button is
+// released even if the drag fails halfway.
+func (c *Client) DragContext(ctx context.Context, fromX, fromY, toX, toY, button, steps, delayMs int) (err error) {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	if err := c.ValidateButton(ctx, button); err != nil {
+		return err
+	}
+	if steps < 0 || steps > maxMotionEvents {
+		return fmt.Errorf("invalid drag steps %d: must be between 0 and %d", steps, maxMotionEvents)
+	}
+	if delayMs < 0 {
+		return fmt.Errorf("invalid drag step delay %dms", delayMs)
+	}
+	if _, _, err := validatePoint(float64(toX), float64(toY), c.screenBounds(), "screen"); err != nil {
+		return err
+	}
+	if err := c.MouseMoveContext(ctx, fromX, fromY); err != nil {
+		return err
+	}
+	// The drag steps bypass MouseMove, so clamp its ends the same way
+	fromX, fromY, err = c.confinePoint(ctx, fromX, fromY)
 	if err != nil {
 		return err
 	}
+	toX, toY, err = c.confinePoint(ctx, toX, toY)
+	if err != nil {
+		return err
+	}
+	if err := c.checkConfined(ctx); err != nil {
+		return err
+	}
 
-	// Press and release the key
-	test.FakeInput(c.conn, KeyPress, uint8(keycode),
-		0, c.root, 0, 0, 0)
+	if err := c.fakeButton(ctx, byte(button), true); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			c.fakeButton(context.WithoutCancel(ctx), byte(button), false)
+		}
+	}()
+	if steps == 0 {
+		if err := c.fakeMotionPath(ctx, fromX, fromY, toX, toY); err != nil {
+			return err
+		}
+	} else {
+		for i := 1; i <= steps; i++ {
+			if err := c.fakeMotion(ctx, fromX+(toX-fromX)*i/steps, fromY+(toY-fromY)*i/steps); err != nil {
+				return err
+			}
+			if delayMs > 0 && i < steps {
+				if err := c.WaitContext(ctx, delayMs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return c.fakeButton(ctx, byte(button), false)
+}
 
-	test.FakeInput(c.conn, KeyRelease, uint8(keycode),
-		0, c.root, 0, 0, 0)
+// Type simulates typing the given text
+func (c *Client) Type(text string) error {
+	return c.TypeContext(context.Background(), text)
+}
 
-	return nil
+// TypeContext is like Type but stops between characters when ctx is done
+func (c *Client) TypeContext(ctx context.Context, text string) error {
+	return c.TypeWithProfileContext(ctx, text, c.typing)
 }
 
-// KeyCombo simulates a key combination like "ctrl+c"
-func (c *Client) KeyCombo(combo string) error {
-	parts := strings.Split(strings.ToLower(combo), "+")
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid key combo: %s", combo)
+// CharError is returned by the typing methods when a character of the text
+// could not be typed
+type CharError struct {
+	Pos  int // Position of the character in the text, counted from 1
+	Char rune
+	Err  error
+}
+
+func (e *CharError) Error() string {
+	return fmt.Sprintf("failed to type character '%c': %v", e.Char, e.Err)
+}
+
+func (e *CharError) Unwrap() error { return e.Err }
+
+// WithholdChar returns err without the character a CharError in it names,
+// for errors about typing confidential text. The cause is kept.
+func WithholdChar(err error) error {
+	var ce *CharError
+	if !errors.As(err, &ce) {
+		return err
 	}
+	return fmt.Errorf("failed to type character %d of the text: %w", ce.Pos, ce.Err)
+}
 
-	var modifiers []x.Keysym
-	var mainKey string
+// TypeWithProfileContext is like TypeContext but paces the keystrokes with
+// the given profile instead of the client's. Characters the keyboard layout
+// lacks are typed by binding them to a free keycode for the time being, and
+// the original mapping is restored afterwards.
+func (c *Client) TypeWithProfileContext(ctx context.Context, text string, profile TypingProfile) (err error) {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	defer func() {
+		if restoreErr := c.restoreSpare(context.WithoutCancel(ctx)); err == nil {
+			err = restoreErr
+		}
+	}()
 
-	// Separate modifiers from main key
-	for i, part := range parts {
-		if i == len(parts)-1 {
-			mainKey = part
+	pos := 0
+	for i, ch := range text {
+		pos++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			if err := c.pause(ctx, profile, profile.InterKeyDelay); err != nil {
+				return err
+			}
+		}
+
+		// Handle newline as Enter key
+		if ch == '\n' {
+			if err := c.KeyPressContext(ctx, "Enter"); err != nil {
+				return fmt.Errorf("failed to press Enter key: %w", err)
+			}
 		} else {
-			switch part {
-			case "ctrl":
-				modifiers = append(modifiers, keysyms.XK_Control_L)
-			case "shift":
-				modifiers = append(modifiers, keysyms.XK_Shift_L)
-			case "alt":
-				modifiers = append(modifiers, keysyms.XK_Alt_L)
-			case "super", "win", "cmd":
-				modifiers = append(modifiers, keysyms.XK_Super_L)
-			default:
-				return fmt.Errorf("unknown modifier: %s", part)
+			if err := c.typeChar(ctx, ch, profile); err != nil {
+				return &CharError{Pos: pos, Char: ch, Err: err}
 			}
 		}
 	}
+	return nil
+}
 
-	// Press all modifiers
-	for _, mod := range modifiers {
-		keycode, err := c.keysymToKeycode(mod)
-		if err != nil {
+// typeChar types a single character, holding the key as the profile says
+func (c *Client) typeChar(ctx context.Context, ch rune, profile TypingProfile) (err error) {
+	// Look the character up in the current keyboard layout
+	loc, err := c.keysymLocation(ctx, runeToKeysym(ch))
+	if err != nil && unicode.IsUpper(ch) {
+		// Fall back to the lowercase key with shift
+		loc, err = c.keysymLocation(ctx, runeToKeysym(unicode.ToLower(ch)))
+		if loc.level == 0 {
+			loc.level = 1
+		}
+	}
+	if err != nil || loc.level > 1 {
+		// Not on the layout or behind AltGr and the like: borrow a free key
+		if loc.keycode, err = c.remapSpare(ctx, runeToKeysym(ch)); err != nil {
 			return err
 		}
-		test.FakeInput(c.conn, KeyPress, uint8(keycode),
-			0, c.root, 0, 0, 0)
+		loc.level = 0
 	}
+	keycode, needShift := loc.keycode, loc.level == 1
 
-	// Press main key
-	var mainKeysym x.Keysym
-	if len(mainKey) == 1 {
-		// Single character
-		mainKeysym = x.Keysym(mainKey[0])
-	} else {
-		// Special key name
-		var err error
-		mainKeysym, err = c.keyNameToKeysym(mainKey)
+	// Press shift if needed, releasing it however this returns
+	if needShift {
+		shiftKeycode, err := c.keysymToKeycode(ctx, keysyms.XK_Shift_L)
 		if err != nil {
 			return err
 		}
+		defer func() {
+			if releaseErr := c.fakeKey(context.WithoutCancel(ctx), shiftKeycode, false); err == nil {
+				err = releaseErr
+			}
+		}()
+		if err := c.fakeKey(ctx, shiftKeycode, true); err != nil {
+			return err
+		}
+	}
+
+	// Press and release the key
+	if err := c.fakeKey(ctx, keycode, true); err != nil {
+		return err
+	}
+	if err := c.pause(ctx, profile, profile.HoldTime); err != nil {
+		c.fakeKey(context.WithoutCancel(ctx), keycode, false)
+		return err
+	}
+	return c.fakeKey(context.WithoutCancel(ctx), keycode, false)
+}
+
+// KeyPress simulates pressing a special key
+func (c *Client) KeyPress(key string) error {
+	return c.KeyPressContext(context.Background(), key)
+}
+
+// KeyPressContext is like KeyPress but gives up when ctx is done
+func (c *Client) KeyPressContext(ctx context.Context, key string) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
 	}
 
-	mainKeycode, err := c.keysymToKeycode(mainKeysym)
+	keysym, err := c.keyNameToKeysym(key)
 	if err != nil {
 		return err
 	}
+	return c.pressKeysym(ctx, keysym)
+}
 
-	test.FakeInput(c.conn, KeyPress, uint8(mainKeycode),
-		0, c.root, 0, 0, 0)
+// comboModifier is a modifier accepted in key combinations
+type comboModifier struct {
+	keysym x.Keysym
+	mask   uint16
+}
 
-	// Release main key
-	test.FakeInput(c.conn, KeyRelease, uint8(mainKeycode),
-		0, c.root, 0, 0, 0)
+// comboModifiers maps modifier names to their keysym and modifier mask
+var comboModifiers = map[string]comboModifier{
+	"ctrl":  {keysyms.XK_Control_L, x.ModMaskControl},
+	"shift": {keysyms.XK_Shift_L, x.ModMaskShift},
+	"alt":   {keysyms.XK_Alt_L, x.ModMask1},
+	"super": {keysyms.XK_Super_L, x.ModMask4},
+	"win":   {keysyms.XK_Super_L, x.ModMask4},
+	"cmd":   {keysyms.XK_Super_L, x.ModMask4},
+}
 
-	// Release all modifiers in reverse order
-	for i := len(modifiers) - 1; i >= 0; i-- {
-		keycode, _ := c.keysymToKeycode(modifiers[i])
-		test.FakeInput(c.conn, KeyRelease, uint8(keycode),
-			0, c.root, 0, 0, 0)
+// parseKeyCombo splits a combo like "ctrl+shift+t" into its modifiers and main key.
+// Modifier names are case-insensitive; the main key is returned as written.
+// A U+XXXX main key keeps its plus sign.
+func parseKeyCombo(combo string) ([]comboModifier, string, error) {
+	parts := strings.Split(combo, "+")
+	if n := len(parts); n > 2 && strings.EqualFold(parts[n-2], "u") {
+		if _, err := strconv.ParseUint(parts[n-1], 16, 32); err == nil {
+			parts = append(parts[:n-2], "U+"+parts[n-1])
+		}
+	}
+	if len(parts) < 2 {
+		return nil, "", fmt.Errorf("invalid key combo: %s", combo)
 	}
 
-	return nil
+	var modifiers []comboModifier
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := comboModifiers[strings.ToLower(part)]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown modifier: %s", strings.ToLower(part))
+		}
+		modifiers = append(modifiers, mod)
+	}
+
+	return modifiers, parts[len(parts)-1], nil
 }
 
-// keyNameToKeysym converts a key name to a keysym
-func (c *Client) keyNameToKeysym(name string) (x.Keysym, error) {
-	switch name {
-	case "Return", "Enter":
-		return keysyms.XK_Return, nil
-	case "Tab":
-		return keysyms.XK_Tab, nil
-	case "Escape", "Esc":
-		return keysyms.XK_Escape, nil
-	case "BackSpace", "Backspace":
-		return keysyms.XK_BackSpace, nil
-	case "Delete", "Del":
-		return keysyms.XK_Delete, nil
-	case "Home":
-		return keysyms.XK_Home, nil
-	case "End":
-		return keysyms.XK_End, nil
-	case "Page_Up", "PageUp", "PgUp":
-		return keysyms.XK_Page_Up, nil
-	case "Page_Down", "PageDown", "PgDn":
-		return keysyms.XK_Page_Down, nil
-	case "Left":
-		return keysyms.XK_Left, nil
-	case "Right":
-		return keysyms.XK_Right, nil
-	case "Up":
-		return keysyms.XK_Up, nil
-	case "Down":
-		return keysyms.XK_Down, nil
-	case "tab":
-		return keysyms.XK_Tab, nil
-	case "delete":
-		return keysyms.XK_Delete, nil
-	default:
-		return 0, fmt.Errorf("unknown key name: %s", name)
-	}
-}
-
-// keysymToKeycode converts a keysym to a keycode
-func (c *Client) keysymToKeycode(keysym x.Keysym) (x.Keycode, error) {
-	setup := c.conn.GetSetup()
-	minKeycode := setup.MinKeycode
-	maxKeycode := setup.MaxKeycode
-
-	// Get keyboard mapping
-	cookie := x.GetKeyboardMapping(c.conn, minKeycode, byte(maxKeycode-minKeycode+1))
-	reply, err := cookie.Reply(c.conn)
+// comboKeysym returns the keysym of a combo's main key. A single character
+// names its key, so ctrl+C is ctrl+c; longer names are case-insensitive.
+func comboKeysym(mainKey string) (x.Keysym, error) {
+	if utf8.RuneCountInString(mainKey) == 1 {
+		mainKey = strings.ToLower(mainKey)
+	}
+	return ParseKeysym(mainKey)
+}
+
+// KeyCombo simulates a key combination like "ctrl+c"
+func (c *Client) KeyCombo(combo string) error {
+	return c.KeyComboContext(context.Background(), combo)
+}
+
+// KeyComboContext is like KeyCombo but gives up when ctx is done. The
+// modifiers it pressed are released however it returns.
+func (c *Client) KeyComboContext(ctx context.Context, combo string) (err error) {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+
+	modifiers, mainKey, err := parseKeyCombo(combo)
+	if err != nil {
+		return err
+	}
+	mainKeysym, err := comboKeysym(mainKey)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get keyboard mapping: %w", err)
+		return err
+	}
+
+	// Look every key up before pressing any
+	keycodes := make([]x.Keycode, len(modifiers))
+	for i, mod := range modifiers {
+		if keycodes[i], err = c.keysymToKeycode(ctx, mod.keysym); err != nil {
+			return err
+		}
 	}
 
-	keysymsPerKeycode := int(reply.KeysymsPerKeycode)
+	// Press all modifiers, releasing them in reverse order on the way out
+	var pressed []x.Keycode
+	defer func() {
+		if releaseErr := c.releaseKeys(context.WithoutCancel(ctx), pressed); err == nil {
+			err = releaseErr
+		}
+	}()
+	for _, keycode := range keycodes {
+		// A press that failed may still have reached the server
+		pressed = append(pressed, keycode)
+		if err := c.fakeKey(ctx, keycode, true); err != nil {
+			return err
+		}
+	}
 
-	// Search for the keysym in the mapping
-	for keycode := minKeycode; keycode <= maxKeycode; keycode++ {
-		for col := 0; col < keysymsPerKeycode; col++ {
-			idx := int(keycode-minKeycode)*keysymsPerKeycode + col
-			if idx < len(reply.Keysyms) && reply.Keysyms[idx] == keysym {
-				return keycode, nil
-			}
+	// Press and release the main key
+	return c.pressKeysym(ctx, mainKeysym)
+}
+
+// releaseKeys releases keycodes in reverse order, all of them even if one
+// fails, and returns the first error
+func (c *Client) releaseKeys(ctx context.Context, keycodes []x.Keycode) error {
+	var firstErr error
+	for i := len(keycodes) - 1; i >= 0; i-- {
+		if err := c.fakeKey(ctx, keycodes[i], false); err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
+	return firstErr
+}
 
-	return 0, fmt.Errorf("no keycode found for keysym %d", keysym)
-}
\ No newline at end of file
+// keyNameToKeysym converts a key name to a keysym, see ParseKeysym
+func (c *Client) keyNameToKeysym(name string) (x.Keysym, error) {
+	return ParseKeysym(name)
+}
@@ -1,7 +1,11 @@
 package x11
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -257,4 +261,141 @@ func TestInputWithXterm(t *testing.T) {
 	}
 	
 	t.Log("Input test with xterm completed")
-}
\ No newline at end of file
+}
+
+// TestMouseMovePointerPosition checks that the pointer ends up where requested
+func TestMouseMovePointerPosition(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{
+		StartXvfb:  true,
+		Resolution: "800x600",
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.MouseMove(321, 123); err != nil {
+		t.Fatalf("Failed to move mouse: %v", err)
+	}
+	x, y, err := client.PointerPosition()
+	if err != nil {
+		t.Fatalf("Failed to query pointer: %v", err)
+	}
+	if x != 321 || y != 123 {
+		t.Errorf("pointer at (%d, %d), want (321, 123)", x, y)
+	}
+}
+
+// TestKeyComboReleasesModifiers checks that failed combos leave no key down
+func TestKeyComboReleasesModifiers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	heldKeys := func() int {
+		client.held.mu.Lock()
+		defer client.held.mu.Unlock()
+		return len(client.held.keys)
+	}
+
+	if err := client.KeyCombo("ctrl+shift+NoSuchKey"); err == nil {
+		t.Error("KeyCombo with an unknown key succeeded")
+	}
+	if n := heldKeys(); n != 0 {
+		t.Errorf("%d keys held after a combo with an unknown key", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.KeyComboContext(ctx, "ctrl+alt+t"); err == nil {
+		t.Error("KeyComboContext with a cancelled context succeeded")
+	}
+	if n := heldKeys(); n != 0 {
+		t.Errorf("%d keys held after a cancelled combo", n)
+	}
+
+	if err := client.KeyCombo("ctrl+shift+t"); err != nil {
+		t.Fatalf("KeyCombo failed: %v", err)
+	}
+	if n := heldKeys(); n != 0 {
+		t.Errorf("%d keys held after a combo", n)
+	}
+}
+
+func TestDrag(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	heldButtons := func() int {
+		client.held.mu.Lock()
+		defer client.held.mu.Unlock()
+		return len(client.held.buttons)
+	}
+
+	for _, steps := range []int{0, 5} {
+		if err := client.Drag(100, 100, 300, 200, 1, steps, 5); err != nil {
+			t.Fatalf("Drag with %d steps failed: %v", steps, err)
+		}
+		x, y, err := client.PointerPosition()
+		if err != nil || x != 300 || y != 200 {
+			t.Errorf("pointer at (%d, %d), %v after a drag with %d steps, want (300, 200)", x, y, err, steps)
+		}
+		if n := heldButtons(); n != 0 {
+			t.Errorf("%d buttons held after a drag", n)
+		}
+	}
+
+	if err := client.Drag(100, 100, 900, 200, 1, 0, 0); err == nil {
+		t.Error("Drag off the screen succeeded")
+	}
+	if err := client.Drag(100, 100, 300, 200, 1, -1, 0); err == nil {
+		t.Error("Drag with negative steps succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.DragContext(ctx, 100, 100, 300, 200, 1, 5, 10); err == nil {
+		t.Error("DragContext with a cancelled context succeeded")
+	}
+	if n := heldButtons(); n != 0 {
+		t.Errorf("%d buttons held after a cancelled drag", n)
+	}
+}
+
+func TestWithholdChar(t *testing.T) {
+	cause := errors.New("key needs modifiers other than shift on this layout")
+	err := fmt.Errorf("wrapped: %w", &CharError{Pos: 3, Char: 'ß', Err: cause})
+	if !strings.Contains(err.Error(), "ß") {
+		t.Errorf("CharError %q doesn't name the character", err)
+	}
+
+	withheld := WithholdChar(err)
+	if strings.Contains(withheld.Error(), "ß") || !strings.Contains(withheld.Error(), "character 3") {
+		t.Errorf("WithholdChar() = %q, want position 3 without the character", withheld)
+	}
+	if !errors.Is(withheld, cause) {
+		t.Errorf("WithholdChar() = %q lost the cause", withheld)
+	}
+
+	other := errors.New("unrelated")
+	if WithholdChar(other) != other {
+		t.Error("WithholdChar changed an error without a CharError")
+	}
+}
@@ -34,13 +34,11 @@ func TestFirefoxIntegration(t *testing.T) {
 	}
 	t.Logf("Screen: %dx%d on display %s", info.Width, info.Height, client.GetDisplay())
 	
-	// Start Firefox with specific profile to avoid first-run dialogs
-	pid, err := client.StartApp("firefox", []string{
-		"--new-instance",
+	// Start Firefox with a throwaway profile to avoid first-run dialogs
+	pid, err := client.StartBrowser("firefox", []string{
 		"--width=1200",
 		"--height=900",
-		"https://example.com",
-	})
+	}, "https://example.com")
 	if err != nil {
 		t.Fatalf("Failed to start Firefox: %v", err)
 	}
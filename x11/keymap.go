@@ -0,0 +1,279 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+	"unicode"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+// keyLocation is where a keysym can be produced on the keyboard
+type keyLocation struct {
+	keycode x.Keycode
+	level   int // Column in the mapping: 0 plain, 1 with Shift, higher needs other modifiers
+}
+
+// remapSettle is how long applications get to pick up a changed keyboard
+// mapping before and after the remapped key is pressed
+const remapSettle = 20 * time.Millisecond
+
+// errNoKeycode is wrapped by keysymLocation with the keysym; typing leaves
+// the keysym out, since it reveals which character of the text failed
+var errNoKeycode = errors.New("no keycode found")
+
+// keymap holds keycode<->keysym lookup tables built from the server's
+// keyboard mapping
+type keymap struct {
+	mu        sync.RWMutex
+	loaded    bool
+	byKeysym  map[x.Keysym]keyLocation
+	byKeycode map[x.Keycode][]x.Keysym
+	spare     x.Keycode // Keycode without keysyms borrowed for characters the layout lacks, 0 until needed
+
+	remapMu  sync.Mutex
+	remapped x.Keysym // Keysym the spare keycode produces for now, 0 if it is restored
+}
+
+// buildKeymap builds lookup tables from a GetKeyboardMapping reply. For every
+// keysym the lowest level, then the lowest keycode, is preferred.
+func buildKeymap(minKeycode x.Keycode, perKeycode int, syms []x.Keysym) (map[x.Keysym]keyLocation, map[x.Keycode][]x.Keysym) {
+	byKeysym := make(map[x.Keysym]keyLocation)
+	byKeycode := make(map[x.Keycode][]x.Keysym)
+	if perKeycode <= 0 {
+		return byKeysym, byKeycode
+	}
+
+	for level := 0; level < perKeycode; level++ {
+		for i := 0; i+level < len(syms); i += perKeycode {
+			keycode := minKeycode + x.Keycode(i/perKeycode)
+			keysym := syms[i+level]
+
+			// A letter without an explicit shifted keysym produces its upper case
+			if level == 1 && keysym == 0 && isLowerLatin(syms[i]) {
+				keysym = syms[i] - 'a' + 'A'
+			}
+			if keysym == 0 {
+				continue
+			}
+			if _, ok := byKeysym[keysym]; !ok {
+				byKeysym[keysym] = keyLocation{keycode: keycode, level: level}
+			}
+		}
+	}
+
+	for i := 0; i < len(syms); i += perKeycode {
+		keycode := minKeycode + x.Keycode(i/perKeycode)
+		end := min(i+perKeycode, len(syms))
+		byKeycode[keycode] = append([]x.Keysym(nil), syms[i:end]...)
+	}
+	return byKeysym, byKeycode
+}
+
+// isLowerLatin reports whether a keysym is a lowercase ASCII letter
+func isLowerLatin(keysym x.Keysym) bool {
+	return keysym >= 'a' && keysym <= 'z'
+}
+
+// loadKeymap fetches the keyboard mapping and rebuilds the lookup tables
+func (c *Client) loadKeymap(ctx context.Context) error {
+	setup := c.conn.GetSetup()
+	minKeycode := setup.MinKeycode
+	maxKeycode := setup.MaxKeycode
+
+	cookie := x.GetKeyboardMapping(c.conn, minKeycode, byte(maxKeycode-minKeycode+1))
+	reply, err := await(c, ctx, func() (*x.GetKeyboardMappingReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get keyboard mapping: %w", err)
+	}
+
+	byKeysym, byKeycode := buildKeymap(minKeycode, int(reply.KeysymsPerKeycode), reply.Keysyms)
+
+	c.keymap.mu.Lock()
+	// The borrowed keycode is restored after typing, so its keysym must be
+	// remapped every time rather than looked up
+	if spare := c.keymap.spare; spare != 0 {
+		for keysym, loc := range byKeysym {
+			if loc.keycode == spare {
+				delete(byKeysym, keysym)
+			}
+		}
+		byKeycode[spare] = nil
+	}
+	c.keymap.byKeysym = byKeysym
+	c.keymap.byKeycode = byKeycode
+	c.keymap.loaded = true
+	c.keymap.mu.Unlock()
+	return nil
+}
+
+// watchKeymap reloads the keymap whenever the server reports a keyboard
+// mapping change
+func (c *Client) watchKeymap() {
+	c.addEventHandler(func(ev x.GenericEvent) {
+		if ev.GetEventCode() != x.MappingNotifyEventCode {
+			return
+		}
+		mev, err := x.NewMappingNotifyEvent(ev)
+		if err != nil || mev.Request != x.MappingKeyboard {
+			return
+		}
+		// Don't block the event loop on the round trip
+		go func() {
+			if err := c.loadKeymap(context.Background()); err != nil {
+				slog.Warn("failed to reload keyboard mapping", "err", err)
+			}
+		}()
+	})
+}
+
+// keysymLocation looks up where a keysym is on the keyboard, loading the
+// keymap on first use
+func (c *Client) keysymLocation(ctx context.Context, keysym x.Keysym) (keyLocation, error) {
+	c.keymap.mu.RLock()
+	loaded := c.keymap.loaded
+	c.keymap.mu.RUnlock()
+
+	if !loaded {
+		if err := c.loadKeymap(ctx); err != nil {
+			return keyLocation{}, err
+		}
+	}
+
+	c.keymap.mu.RLock()
+	defer c.keymap.mu.RUnlock()
+	loc, ok := c.keymap.byKeysym[keysym]
+	if !ok {
+		return keyLocation{}, fmt.Errorf("%w for keysym %d", errNoKeycode, keysym)
+	}
+	return loc, nil
+}
+
+// keysymToKeycode converts a keysym to a keycode
+func (c *Client) keysymToKeycode(ctx context.Context, keysym x.Keysym) (x.Keycode, error) {
+	loc, err := c.keysymLocation(ctx, keysym)
+	if err != nil {
+		return 0, err
+	}
+	return loc.keycode, nil
+}
+
+// keycodeKeysyms returns the keysyms bound to a keycode
+func (c *Client) keycodeKeysyms(keycode x.Keycode) []x.Keysym {
+	c.keymap.mu.RLock()
+	defer c.keymap.mu.RUnlock()
+	return c.keymap.byKeycode[keycode]
+}
+
+// freeKeycode returns the highest keycode without keysyms, 0 if there is
+// none
+func freeKeycode(byKeycode map[x.Keycode][]x.Keysym) x.Keycode {
+	var free x.Keycode
+	for keycode, syms := range byKeycode {
+		if keycode > free && !slices.ContainsFunc(syms, func(s x.Keysym) bool { return s != 0 }) {
+			free = keycode
+		}
+	}
+	return free
+}
+
+// remapSpare binds keysym to a keycode that has no keysyms, so characters
+// the layout lacks or puts behind AltGr can be typed with a plain key press.
+// Both levels get keysym, so a held shift doesn't change it. The mapping
+// stays until restoreSpare.
+func (c *Client) remapSpare(ctx context.Context, keysym x.Keysym) (x.Keycode, error) {
+	c.keymap.remapMu.Lock()
+	defer c.keymap.remapMu.Unlock()
+
+	c.keymap.mu.Lock()
+	if c.keymap.spare == 0 {
+		c.keymap.spare = freeKeycode(c.keymap.byKeycode)
+	}
+	spare := c.keymap.spare
+	c.keymap.mu.Unlock()
+	if spare == 0 {
+		// The keysym is left out: it would reveal the character of the text
+		return 0, fmt.Errorf("not on the keyboard and no free keycode is left to map it to")
+	}
+	if c.keymap.remapped == keysym {
+		return spare, nil
+	}
+
+	if err := c.changeKeyboardMapping(ctx, spare, []x.Keysym{keysym, keysym}); err != nil {
+		return 0, err
+	}
+	c.keymap.remapped = keysym
+	return spare, c.WaitContext(ctx, int(remapSettle.Milliseconds()))
+}
+
+// restoreSpare takes the keysym remapSpare bound off the spare keycode again,
+// once applications had time to handle the key press
+func (c *Client) restoreSpare(ctx context.Context) error {
+	c.keymap.remapMu.Lock()
+	defer c.keymap.remapMu.Unlock()
+	if c.keymap.remapped == 0 {
+		return nil
+	}
+
+	c.keymap.mu.RLock()
+	spare := c.keymap.spare
+	c.keymap.mu.RUnlock()
+	if err := c.WaitContext(ctx, int(remapSettle.Milliseconds())); err != nil {
+		return err
+	}
+	if err := c.changeKeyboardMapping(ctx, spare, []x.Keysym{0}); err != nil {
+		return err
+	}
+	c.keymap.remapped = 0
+	return nil
+}
+
+// changeKeyboardMapping binds keysyms, one per level, to a keycode. The
+// client library lacks ChangeKeyboardMapping, so the request is sent by
+// hand.
+func (c *Client) changeKeyboardMapping(ctx context.Context, keycode x.Keycode, syms []x.Keysym) error {
+	var body x.RequestBody
+	b := body.AddBlock(1 + len(syms)).
+		Write1b(uint8(keycode)).
+		Write1b(uint8(len(syms))).
+		WritePad(2)
+	for _, keysym := range syms {
+		b.Write4b(uint32(keysym))
+	}
+	b.End()
+
+	err := awaitCheck(c, ctx, func() error {
+		seq := c.conn.SendRequest(x.RequestChecked, &x.ProtocolRequest{
+			NoReply: true,
+			Header:  x.RequestHeader{Opcode: x.ChangeKeyboardMappingOpcode, Data: 1},
+			Body:    body,
+		})
+		return x.VoidCookie(seq).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change the mapping of keycode %d: %w", keycode, err)
+	}
+	return nil
+}
+
+// runeToKeysym returns the keysym that produces a character
+func runeToKeysym(ch rune) x.Keysym {
+	switch {
+	case ch == '\t':
+		return keysyms.XK_Tab
+	case ch < 0x100 && unicode.IsPrint(ch):
+		// Latin-1 keysyms match their code points
+		return x.Keysym(ch)
+	default:
+		// Unicode keysyms
+		return x.Keysym(0x01000000 | ch)
+	}
+}
@@ -0,0 +1,134 @@
+package x11
+
+import (
+	"context"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+func TestBuildKeymap(t *testing.T) {
+	// Four keysyms per keycode, starting at keycode 8
+	syms := []x.Keysym{
+		'1', '!', '1', '!', // 8
+		'a', 0, 'a', 'A', // 9: shifted level left empty
+		keysyms.XK_Shift_L, 0, 0, 0, // 10
+		'q', 'Q', '@', 0, // 11: '@' only reachable on level 3
+		'!', 0, 0, 0, // 12: '!' again, but unshifted
+	}
+	byKeysym, byKeycode := buildKeymap(8, 4, syms)
+
+	tests := []struct {
+		keysym x.Keysym
+		want   keyLocation
+	}{
+		{'1', keyLocation{keycode: 8, level: 0}},
+		{'!', keyLocation{keycode: 12, level: 0}},
+		{'a', keyLocation{keycode: 9, level: 0}},
+		{'A', keyLocation{keycode: 9, level: 1}},
+		{'Q', keyLocation{keycode: 11, level: 1}},
+		{'@', keyLocation{keycode: 11, level: 2}},
+		{keysyms.XK_Shift_L, keyLocation{keycode: 10, level: 0}},
+	}
+	for _, tt := range tests {
+		got, ok := byKeysym[tt.keysym]
+		if !ok {
+			t.Errorf("keysym %#x missing from table", tt.keysym)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("keysym %#x: got %+v, want %+v", tt.keysym, got, tt.want)
+		}
+	}
+
+	if _, ok := byKeysym[0]; ok {
+		t.Error("NoSymbol must not be in the table")
+	}
+	if got := byKeycode[11]; len(got) != 4 || got[2] != '@' {
+		t.Errorf("unexpected keysyms for keycode 11: %v", got)
+	}
+}
+
+func TestRuneToKeysym(t *testing.T) {
+	tests := []struct {
+		ch   rune
+		want x.Keysym
+	}{
+		{'a', 'a'},
+		{'~', '~'},
+		{'é', 0xe9},
+		{'\t', keysyms.XK_Tab},
+		{'€', 0x010020ac},
+	}
+	for _, tt := range tests {
+		if got := runeToKeysym(tt.ch); got != tt.want {
+			t.Errorf("runeToKeysym(%q) = %#x, want %#x", tt.ch, got, tt.want)
+		}
+	}
+}
+
+func TestFreeKeycode(t *testing.T) {
+	tests := []struct {
+		name      string
+		byKeycode map[x.Keycode][]x.Keysym
+		want      x.Keycode
+	}{
+		{"highest empty", map[x.Keycode][]x.Keysym{8: {'a'}, 9: {0, 0}, 10: {0}, 11: {'b', 0}}, 10},
+		{"level only", map[x.Keycode][]x.Keysym{8: {0, 'A'}, 9: nil}, 9},
+		{"none", map[x.Keycode][]x.Keysym{8: {'a'}}, 0},
+	}
+	for _, tt := range tests {
+		if got := freeKeycode(tt.byKeycode); got != tt.want {
+			t.Errorf("%s: freeKeycode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRemapSpare(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	mapping := func(keycode x.Keycode) []x.Keysym {
+		reply, err := x.GetKeyboardMapping(client.conn, keycode, 1).Reply(client.conn)
+		if err != nil {
+			t.Fatalf("GetKeyboardMapping failed: %v", err)
+		}
+		return reply.Keysyms
+	}
+
+	arrow := runeToKeysym('→')
+	spare, err := client.remapSpare(ctx, arrow)
+	if err != nil {
+		t.Fatalf("remapSpare failed: %v", err)
+	}
+	if got := mapping(spare); len(got) < 2 || got[0] != arrow || got[1] != arrow {
+		t.Errorf("keycode %d maps to %v, want %#x on both levels", spare, got, arrow)
+	}
+	if err := client.restoreSpare(ctx); err != nil {
+		t.Fatalf("restoreSpare failed: %v", err)
+	}
+	for _, keysym := range mapping(spare) {
+		if keysym != 0 {
+			t.Errorf("keycode %d still maps to %#x after restoring", spare, keysym)
+		}
+	}
+
+	// Typing a character the layout lacks leaves the mapping as it was
+	if err := client.TypeWithProfileContext(ctx, "é→ß", typingProfiles["instant"]); err != nil {
+		t.Fatalf("TypeWithProfileContext failed: %v", err)
+	}
+	for _, keysym := range mapping(spare) {
+		if keysym != 0 {
+			t.Errorf("keycode %d maps to %#x after typing", spare, keysym)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+// keypadNeedsNumLock reports whether the keysym at level of a key with the
+// given keysyms depends on NumLock, as the keypad's KP_1 and KP_End share a
+// key, and if so whether NumLock must be on to produce it. Keypad keys with
+// one meaning, like KP_Enter and KP_Add, don't depend on it.
+func keypadNeedsNumLock(syms []x.Keysym, level int) (depends, on bool) {
+	if len(syms) < 2 || level > 1 {
+		return false, false
+	}
+	plain, locked := syms[0], syms[1]
+	if !keysyms.IsKeypadKey(plain) || !keysyms.IsKeypadKey(locked) || plain == locked {
+		return false, false
+	}
+	return true, level == 1
+}
+
+// parseModifierMapping returns the modifier mask a keycode is mapped to in
+// a GetModifierMapping reply, whose second byte is the number of keycodes
+// per modifier in the eight lists that follow the 32-byte header
+func parseModifierMapping(reply []byte, keycode x.Keycode) (uint16, error) {
+	if len(reply) < 32 {
+		return 0, fmt.Errorf("modifier mapping reply is too short (%d bytes)", len(reply))
+	}
+	perModifier := int(reply[1])
+	if len(reply) < 32+8*perModifier {
+		return 0, fmt.Errorf("modifier mapping reply is too short (%d bytes)", len(reply))
+	}
+	for mod := 0; mod < 8; mod++ {
+		for _, kc := range reply[32+mod*perModifier : 32+(mod+1)*perModifier] {
+			if kc != 0 && x.Keycode(kc) == keycode {
+				return 1 << mod, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("keycode %d is not a modifier", keycode)
+}
+
+// numLockOn returns whether NumLock is on and the keycode of Num_Lock
+func (c *Client) numLockOn(ctx context.Context) (bool, x.Keycode, error) {
+	keycode, err := c.keysymToKeycode(ctx, keysyms.XK_Num_Lock)
+	if err != nil {
+		return false, 0, err
+	}
+	reply, err := await(c, ctx, func() ([]byte, error) {
+		seq := c.conn.SendRequest(x.RequestChecked, &x.ProtocolRequest{
+			Header: x.RequestHeader{Opcode: x.GetModifierMappingOpcode},
+		})
+		return c.conn.WaitForReply(seq)
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to get modifier mapping: %w", err)
+	}
+	mask, err := parseModifierMapping(reply, keycode)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to find the NumLock modifier: %w", err)
+	}
+	pointer, err := await(c, ctx, func() (*x.QueryPointerReply, error) {
+		return x.QueryPointer(c.conn, c.root).Reply(c.conn)
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to query modifier state: %w", err)
+	}
+	return pointer.Mask&mask != 0, keycode, nil
+}
+
+// pressKeysym presses and releases the key producing keysym. Keypad keys
+// whose keysym NumLock selects get NumLock switched as needed for the one
+// press and back, so KP_1 and KP_End, or KP_4 and KP_Left, reach the
+// application as asked whatever the NumLock state.
+func (c *Client) pressKeysym(ctx context.Context, keysym x.Keysym) error {
+	loc, err := c.keysymLocation(ctx, keysym)
+	if err != nil {
+		return err
+	}
+
+	toggle := x.Keycode(0)
+	if depends, wantOn := keypadNeedsNumLock(c.keycodeKeysyms(loc.keycode), loc.level); depends {
+		on, numLock, err := c.numLockOn(ctx)
+		if err != nil {
+			return err
+		}
+		if on != wantOn {
+			toggle = numLock
+		}
+	}
+	if toggle != 0 {
+		if err := c.tapKey(ctx, toggle); err != nil {
+			return err
+		}
+		defer c.tapKey(context.WithoutCancel(ctx), toggle)
+	}
+	return c.tapKey(ctx, loc.keycode)
+}
+
+// tapKey presses and releases a key. The release is sent even if ctx is
+// done by then, so the key is never left down.
+func (c *Client) tapKey(ctx context.Context, keycode x.Keycode) error {
+	if err := c.fakeKey(ctx, keycode, true); err != nil {
+		return err
+	}
+	return c.fakeKey(context.WithoutCancel(ctx), keycode, false)
+}
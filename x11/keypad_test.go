@@ -0,0 +1,81 @@
+package x11
+
+import (
+	"context"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+func TestKeypadNeedsNumLock(t *testing.T) {
+	kp1 := []x.Keysym{keysyms.XK_KP_End, keysyms.XK_KP_1, keysyms.XK_KP_End, keysyms.XK_KP_1}
+	tests := []struct {
+		name          string
+		syms          []x.Keysym
+		level         int
+		depends, isOn bool
+	}{
+		{"KP_1", kp1, 1, true, true},
+		{"KP_End", kp1, 0, true, false},
+		{"KP_Enter", []x.Keysym{keysyms.XK_KP_Enter, 0}, 0, false, false},
+		{"KP_Add", []x.Keysym{keysyms.XK_KP_Add, keysyms.XK_KP_Add}, 0, false, false},
+		{"End", []x.Keysym{keysyms.XK_End, 0}, 0, false, false},
+		{"exclam", []x.Keysym{'1', '!'}, 1, false, false},
+	}
+	for _, tt := range tests {
+		depends, on := keypadNeedsNumLock(tt.syms, tt.level)
+		if depends != tt.depends || on != tt.isOn {
+			t.Errorf("%s: keypadNeedsNumLock = %v, %v, want %v, %v", tt.name, depends, on, tt.depends, tt.isOn)
+		}
+	}
+}
+
+func TestParseModifierMapping(t *testing.T) {
+	// Two keycodes per modifier: shift, lock, control, mod1, mod2, ...
+	reply := make([]byte, 32+16)
+	reply[1] = 2
+	copy(reply[32:], []byte{50, 62, 66, 0, 37, 105, 64, 108, 77, 0})
+	if mask, err := parseModifierMapping(reply, 77); err != nil || mask != x.ModMask2 {
+		t.Errorf("parseModifierMapping(Num_Lock) = %#x, %v, want Mod2", mask, err)
+	}
+	if mask, err := parseModifierMapping(reply, 62); err != nil || mask != x.ModMaskShift {
+		t.Errorf("parseModifierMapping(Shift_R) = %#x, %v, want Shift", mask, err)
+	}
+	if _, err := parseModifierMapping(reply, 38); err == nil {
+		t.Error("expected error for a keycode that is no modifier")
+	}
+	if _, err := parseModifierMapping(reply[:40], 77); err == nil {
+		t.Error("expected error for a truncated reply")
+	}
+}
+
+func TestPressKeypadKeyRestoresNumLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	before, _, err := client.numLockOn(ctx)
+	if err != nil {
+		t.Skipf("NumLock state unavailable: %v", err)
+	}
+	for _, key := range []string{"KP_1", "KP_End", "KP_Enter", "KP_Left", "KP_4"} {
+		if err := client.KeyPress(key); err != nil {
+			t.Fatalf("KeyPress(%s) failed: %v", key, err)
+		}
+		after, _, err := client.numLockOn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after != before {
+			t.Errorf("NumLock changed from %v to %v by %s", before, after, key)
+		}
+	}
+}
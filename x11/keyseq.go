@@ -0,0 +1,104 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChordDelay is the pause between the chords of a key sequence
+const DefaultChordDelay = 100 * time.Millisecond
+
+// emacsModifiers maps the modifier prefixes of emacs key notation to combo
+// modifier names
+var emacsModifiers = map[byte]string{
+	'C': "ctrl",
+	'M': "alt",
+	'S': "shift",
+	's': "super",
+}
+
+// emacsKeys maps the key names of emacs notation that aren't keysym names
+var emacsKeys = map[string]string{
+	"RET": "Return",
+	"SPC": "space",
+	"TAB": "Tab",
+}
+
+// ParseKeySequence splits a sequence of chords separated by spaces, like
+// "ctrl+k ctrl+s" or, in emacs notation, "C-x C-f", into chords in
+// KeyCombo or KeyPress syntax. It checks every chord before any is pressed.
+func ParseKeySequence(seq string) ([]string, error) {
+	fields := strings.Fields(seq)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty key sequence")
+	}
+	chords := make([]string, len(fields))
+	for i, field := range fields {
+		chord := emacsChord(field)
+		if err := checkChord(chord); err != nil {
+			return nil, fmt.Errorf("chord %d (%s): %w", i+1, field, err)
+		}
+		chords[i] = chord
+	}
+	return chords, nil
+}
+
+// emacsChord converts a chord in emacs notation like C-x, C-M-f, s-l or
+// C-RET to a combo or key; other chords are returned as they are
+func emacsChord(chord string) string {
+	var mods []string
+	rest := chord
+	for len(rest) > 2 && rest[1] == '-' && emacsModifiers[rest[0]] != "" {
+		mods = append(mods, emacsModifiers[rest[0]])
+		rest = rest[2:]
+	}
+	if key, ok := emacsKeys[rest]; ok {
+		rest = key
+	}
+	if len(mods) == 0 {
+		return rest
+	}
+	return strings.Join(mods, "+") + "+" + rest
+}
+
+// isCombo reports whether a chord has modifiers; a lone "+" and a code
+// point like U+00E9 are keys
+func isCombo(chord string) bool {
+	if hex, ok := strings.CutPrefix(strings.ToLower(chord), "u+"); ok {
+		if _, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return false
+		}
+	}
+	return len(chord) > 1 && strings.Contains(chord, "+")
+}
+
+// checkChord checks that a chord names known modifiers and keys
+func checkChord(chord string) error {
+	if !isCombo(chord) {
+		_, err := ParseKeysym(chord)
+		return err
+	}
+	_, mainKey, err := parseKeyCombo(chord)
+	if err != nil {
+		return err
+	}
+	_, err = comboKeysym(mainKey)
+	return err
+}
+
+// PressChordContext presses one chord of a key sequence, a combo or a single
+// key, with XTEST or, for InputSendEvent, synthetic events
+func (c *Client) PressChordContext(ctx context.Context, chord, method string) error {
+	switch {
+	case method == InputSendEvent && isCombo(chord):
+		return c.SendKeyComboContext(ctx, chord)
+	case method == InputSendEvent:
+		return c.SendKeyPressContext(ctx, chord)
+	case isCombo(chord):
+		return c.KeyComboContext(ctx, chord)
+	}
+	return c.KeyPressContext(ctx, chord)
+}
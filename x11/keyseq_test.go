@@ -0,0 +1,45 @@
+package x11
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsCombo(t *testing.T) {
+	for chord, want := range map[string]bool{"ctrl+c": true, "+": false, "U+00E9": false, "u+1f600": false, "ctrl+U+00E9": true, "U+xyz": true, "a": false} {
+		if got := isCombo(chord); got != want {
+			t.Errorf("isCombo(%q) = %t, want %t", chord, got, want)
+		}
+	}
+}
+
+func TestParseKeySequence(t *testing.T) {
+	tests := []struct {
+		seq     string
+		want    string
+		wantErr bool
+	}{
+		{"ctrl+k ctrl+s", "ctrl+k ctrl+s", false},
+		{"  ctrl+k   ctrl+shift+Left ", "ctrl+k ctrl+shift+Left", false},
+		{"C-x C-f", "ctrl+x ctrl+f", false},
+		{"C-M-f M-x s-l S-Tab", "ctrl+alt+f alt+x super+l shift+Tab", false},
+		{"Escape : w q Return", "Escape : w q Return", false},
+		{"C- +", "C- +", true},
+		{"+", "+", false},
+		{"U+00E9 ctrl+U+00E9", "U+00E9 ctrl+U+00E9", false},
+		{"C-x RET SPC TAB C-RET", "ctrl+x Return space Tab ctrl+Return", false},
+		{"", "", true},
+		{"ctrl+k hyper+s", "", true},
+		{"ctrl+k NoSuchKey", "", true},
+	}
+	for _, tt := range tests {
+		chords, err := ParseKeySequence(tt.seq)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKeySequence(%q) error = %v, wantErr %v", tt.seq, err, tt.wantErr)
+			continue
+		}
+		if err == nil && strings.Join(chords, " ") != tt.want {
+			t.Errorf("ParseKeySequence(%q) = %q, want %q", tt.seq, chords, tt.want)
+		}
+	}
+}
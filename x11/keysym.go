@@ -0,0 +1,91 @@
+package x11
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+// keysymAliases are friendlier names for common keys, matched
+// case-insensitively
+var keysymAliases = map[string]x.Keysym{
+	"enter":       keysyms.XK_Return,
+	"esc":         keysyms.XK_Escape,
+	"del":         keysyms.XK_Delete,
+	"ins":         keysyms.XK_Insert,
+	"pageup":      keysyms.XK_Page_Up,
+	"pgup":        keysyms.XK_Page_Up,
+	"pagedown":    keysyms.XK_Page_Down,
+	"pgdn":        keysyms.XK_Page_Down,
+	"capslock":    keysyms.XK_Caps_Lock,
+	"printscreen": keysyms.XK_Print,
+}
+
+// foldedKeysyms maps lower-cased keysym names to their keysyms, built on
+// first use. Of names differing only in case, like Aacute and aacute, the
+// lower-case one wins, as it's the key pressed without shift.
+var foldedKeysyms = sync.OnceValue(func() map[string]x.Keysym {
+	folded := make(map[string]x.Keysym, len(keysyms.EngKeysymMap))
+	for name, sym := range keysyms.EngKeysymMap {
+		if strings.ToLower(name) == name {
+			folded[name] = sym
+		}
+	}
+	for name, sym := range keysyms.EngKeysymMap {
+		lower := strings.ToLower(name)
+		prev, ok := folded[lower]
+		// Between mixed-case names, pick the lower keysym so it's deterministic
+		if !ok || (keysyms.EngKeysymMap[lower] != prev && sym < prev) {
+			folded[lower] = sym
+		}
+	}
+	return folded
+})
+
+// ParseKeysym converts a key name to a keysym, like XStringToKeysym and
+// more forgiving: any X keysym name with or without its XK_ prefix, exact
+// or in any case (Return, F11, KP_Enter, XF86AudioMute, aacute), aliases
+// like Enter, Esc or PgUp, a single character, a U+XXXX code point or a
+// 0x keysym value.
+func ParseKeysym(name string) (x.Keysym, error) {
+	if utf8.RuneCountInString(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		return runeToKeysym(r), nil
+	}
+
+	trimmed := name
+	if len(name) > 3 && strings.EqualFold(name[:3], "XK_") {
+		trimmed = name[3:]
+	}
+	if sym, ok := keysyms.EngKeysymMap[trimmed]; ok {
+		return sym, nil
+	}
+	lower := strings.ToLower(trimmed)
+	if sym, ok := keysymAliases[lower]; ok {
+		return sym, nil
+	}
+	if sym, ok := foldedKeysyms()[lower]; ok {
+		return sym, nil
+	}
+
+	if hex, ok := strings.CutPrefix(lower, "u+"); ok {
+		cp, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || cp == 0 || cp > utf8.MaxRune {
+			return 0, fmt.Errorf("invalid code point: %s", name)
+		}
+		return runeToKeysym(rune(cp)), nil
+	}
+	if hex, ok := strings.CutPrefix(lower, "0x"); ok {
+		sym, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || sym == 0 {
+			return 0, fmt.Errorf("invalid keysym value: %s", name)
+		}
+		return x.Keysym(sym), nil
+	}
+	return 0, fmt.Errorf("unknown key name: %s", name)
+}
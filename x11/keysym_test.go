@@ -0,0 +1,62 @@
+package x11
+
+import (
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+func TestParseKeysym(t *testing.T) {
+	tests := []struct {
+		name string
+		want x.Keysym
+	}{
+		{"Return", keysyms.XK_Return},
+		{"Enter", keysyms.XK_Return},
+		{"return", keysyms.XK_Return},
+		{"XK_Escape", keysyms.XK_Escape},
+		{"xk_escape", keysyms.XK_Escape},
+		{"xk_Return", keysyms.XK_Return},
+		{"esc", keysyms.XK_Escape},
+		{"page_up", keysyms.XK_Page_Up},
+		{"PgDn", keysyms.XK_Page_Down},
+		{"F11", keysyms.XK_F11},
+		{"f11", keysyms.XK_F11},
+		{"KP_Enter", keysyms.XK_KP_Enter},
+		{"XF86AudioMute", keysyms.XF86XK_AudioMute},
+		{"xf86audiomute", keysyms.XF86XK_AudioMute},
+		{"Aacute", keysyms.XK_Aacute},
+		{"AACUTE", keysyms.XK_aacute},
+		{"space", keysyms.XK_space},
+		{"a", 'a'},
+		{"é", 0xe9},
+		{"U+00E9", 0xe9},
+		{"u+20ac", 0x010020ac},
+		{"0xff0d", keysyms.XK_Return},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeysym(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseKeysym(%q) = %#x, %v, want %#x", tt.name, got, err, tt.want)
+		}
+	}
+
+	// Main keys of combos: single characters name their key
+	for combo, want := range map[string]x.Keysym{"ctrl+XK_Home": keysyms.XK_Home, "ctrl+xk_home": keysyms.XK_Home, "ctrl+C": 'c', "ctrl+U+00E9": 0xe9} {
+		_, mainKey, err := parseKeyCombo(combo)
+		if err != nil {
+			t.Errorf("parseKeyCombo(%q) failed: %v", combo, err)
+			continue
+		}
+		if got, err := comboKeysym(mainKey); err != nil || got != want {
+			t.Errorf("comboKeysym of %q = %#x, %v, want %#x", combo, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"", "NoSuchKey", "XK_", "U+", "U+zz", "U+110000", "0x"} {
+		if _, err := ParseKeysym(bad); err == nil {
+			t.Errorf("ParseKeysym(%q): expected error", bad)
+		}
+	}
+}
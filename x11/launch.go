@@ -0,0 +1,212 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Readiness checks of launch templates
+const (
+	ReadyNone     = "none"     // Ready as soon as the program started
+	ReadyWindow   = "window"   // Ready once a new window of the template's class is mapped
+	ReadyPageLoad = "pageload" // Like window, then the page in it must have loaded
+)
+
+const (
+	// defaultLaunchTimeout bounds the readiness check of templates without a timeout
+	defaultLaunchTimeout = 30 * time.Second
+	// launchPageQuiet is how long a page must be quiet to count as loaded,
+	// as for x11_wait_page_loaded by default
+	launchPageQuiet = 1500 * time.Millisecond
+	// launchPoll is how often Launch looks for the new window
+	launchPoll = 100 * time.Millisecond
+)
+
+// LaunchTemplate describes how to start an application and how to tell it
+// is ready, so its flags and startup wait live in one place
+type LaunchTemplate struct {
+	Command     string            `json:"command"`
+	Args        []string          `json:"args,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	KeepProfile bool              `json:"keep_profile,omitempty"` // For browsers: use the normal profile instead of a throwaway one
+	WindowClass string            `json:"window_class,omitempty"` // WM_CLASS of the window the program opens, matched case-insensitively
+	Ready       string            `json:"ready,omitempty"`        // none, window or pageload; window if WindowClass is set, else none
+	TimeoutMS   int               `json:"timeout_ms,omitempty"`   // Bound of the readiness check, 30000 if 0
+}
+
+// Readiness returns the readiness check of the template
+func (t LaunchTemplate) Readiness() string {
+	switch {
+	case t.Ready != "":
+		return strings.ToLower(t.Ready)
+	case t.WindowClass != "":
+		return ReadyWindow
+	}
+	return ReadyNone
+}
+
+// Validate checks that the template can be launched as described
+func (t LaunchTemplate) Validate() error {
+	if strings.TrimSpace(t.Command) == "" {
+		return fmt.Errorf("no command")
+	}
+	if t.TimeoutMS < 0 {
+		return fmt.Errorf("timeout_ms cannot be negative")
+	}
+	switch t.Readiness() {
+	case ReadyNone:
+	case ReadyWindow, ReadyPageLoad:
+		if t.WindowClass == "" {
+			return fmt.Errorf("ready %q needs window_class", t.Ready)
+		}
+	default:
+		return fmt.Errorf("invalid ready %q: use %s, %s or %s", t.Ready, ReadyNone, ReadyWindow, ReadyPageLoad)
+	}
+	return nil
+}
+
+// timeout returns the bound of the readiness check
+func (t LaunchTemplate) timeout() time.Duration {
+	if t.TimeoutMS > 0 {
+		return time.Duration(t.TimeoutMS) * time.Millisecond
+	}
+	return defaultLaunchTimeout
+}
+
+// Launched is the outcome of Launch
+type Launched struct {
+	PID      int
+	Window   x.Window      // The new window, 0 for templates without a window class
+	Title    string        // Title of the new window
+	Elapsed  time.Duration // Time from starting the program to it being ready
+	PageLoad *PageLoad     // Outcome of the page load check, for ready pageload
+}
+
+// Launch starts the program of a template, with extra appended to its
+// arguments, and waits until it is ready: for a new mapped window of its
+// class and, with ready pageload, for the page in it to load. A window that
+// doesn't appear in time or a program that fails first is an error, which
+// leaves the program running; a page still loading is reported in PageLoad.
+func (c *Client) Launch(ctx context.Context, t LaunchTemplate, extra []string) (Launched, error) {
+	if err := t.Validate(); err != nil {
+		return Launched{}, err
+	}
+	ready := t.Readiness()
+	match := func(class string) bool { return strings.EqualFold(class, t.WindowClass) }
+	var before map[x.Window]bool
+	if ready != ReadyNone {
+		var err error
+		if before, err = c.windowSet(ctx, match); err != nil {
+			return Launched{}, err
+		}
+	}
+
+	args := append(append([]string{}, t.Args...), extra...)
+	start := time.Now()
+	var pid int
+	var err error
+	if IsBrowser(t.Command) && !t.KeepProfile {
+		pid, err = c.startBrowser(t.Command, args, "", t.Env)
+	} else {
+		pid, err = c.StartAppWithEnv(t.Command, args, t.Env)
+	}
+	if err != nil {
+		return Launched{}, err
+	}
+	launched := Launched{PID: pid}
+	if ready == ReadyNone {
+		launched.Elapsed = time.Since(start)
+		return launched, nil
+	}
+
+	deadline := start.Add(t.timeout())
+	if launched.Window, err = c.waitNewWindow(ctx, pid, t.Command, t.WindowClass, match, before, deadline); err != nil {
+		return launched, err
+	}
+	launched.Title = c.getWindowName(ctx, launched.Window)
+
+	if ready == ReadyPageLoad {
+		remaining := time.Until(deadline)
+		if remaining < launchPageQuiet {
+			remaining = launchPageQuiet
+		}
+		load, err := c.WaitPageLoaded(ctx, launched.Window, remaining, launchPageQuiet)
+		if err != nil {
+			return launched, err
+		}
+		launched.PageLoad = &load
+		launched.Title = load.Title
+	}
+	launched.Elapsed = time.Since(start)
+	return launched, nil
+}
+
+// waitNewWindow waits until deadline for a mapped window whose class
+// matches and that is not in before, failing early if the program started
+// as pid exits with an error. class names the windows in errors.
+func (c *Client) waitNewWindow(ctx context.Context, pid int, program, class string, match func(string) bool, before map[x.Window]bool, deadline time.Time) (x.Window, error) {
+	start := time.Now()
+	for {
+		windows, err := c.classWindows(ctx, match)
+		if err != nil {
+			return 0, err
+		}
+		for _, win := range windows {
+			if !before[win] {
+				return win, nil
+			}
+		}
+		// Launchers may exit once they handed over, but not with an error
+		if status, ok := c.AppStatus(pid); ok && status.Exited && status.ExitCode != 0 {
+			return 0, fmt.Errorf("%s exited with code %d before opening a %s window", program, status.ExitCode, class)
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("no new %s window appeared within %s of starting %s", class, deadline.Sub(start).Round(time.Second), program)
+		}
+		if err := c.WaitContext(ctx, int(launchPoll.Milliseconds())); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// windowSet returns the mapped client windows whose class matches, as a set
+func (c *Client) windowSet(ctx context.Context, match func(string) bool) (map[x.Window]bool, error) {
+	windows, err := c.classWindows(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[x.Window]bool, len(windows))
+	for _, win := range windows {
+		set[win] = true
+	}
+	return set, nil
+}
+
+// classWindows returns the mapped client windows whose WM_CLASS matches,
+// looking through window manager frames
+func (c *Client) classWindows(ctx context.Context, match func(string) bool) ([]x.Window, error) {
+	tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+		return x.QueryTree(c.conn, c.root).Reply(c.conn)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tree: %w", err)
+	}
+	var windows []x.Window
+	for _, win := range tree.Children {
+		attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+			return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+		})
+		if err != nil || attrs.MapState != x.MapStateViewable {
+			continue
+		}
+		app := c.clientWindow(ctx, win)
+		if class := c.getWindowClass(ctx, app); class != "" && match(class) {
+			windows = append(windows, app)
+		}
+	}
+	return windows, nil
+}
@@ -0,0 +1,30 @@
+package x11
+
+import "testing"
+
+func TestLaunchTemplateValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  LaunchTemplate
+		readiness string
+		wantErr   bool
+	}{
+		{"command only", LaunchTemplate{Command: "xterm"}, ReadyNone, false},
+		{"window class", LaunchTemplate{Command: "firefox", WindowClass: "firefox"}, ReadyWindow, false},
+		{"page load", LaunchTemplate{Command: "chromium", WindowClass: "Chromium", Ready: "PageLoad"}, ReadyPageLoad, false},
+		{"no command", LaunchTemplate{WindowClass: "XTerm"}, ReadyWindow, true},
+		{"window without class", LaunchTemplate{Command: "xterm", Ready: "window"}, ReadyWindow, true},
+		{"unknown check", LaunchTemplate{Command: "xterm", Ready: "sleep"}, "sleep", true},
+		{"negative timeout", LaunchTemplate{Command: "xterm", TimeoutMS: -1}, ReadyNone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.template.Readiness(); got != tt.readiness {
+				t.Errorf("Readiness() = %q, want %q", got, tt.readiness)
+			}
+			if err := tt.template.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"go.i3wm.org/i3/v4"
+)
+
+// layoutCell is one window slot of a layout preset, as fractions of the work area
+type layoutCell struct {
+	x, y, w, h float64
+}
+
+// layoutPresets are the presets accepted by ApplyLayout. Windows fill the
+// cells in order.
+var layoutPresets = map[string][]layoutCell{
+	"maximize":     {{0, 0, 1, 1}},
+	"left-half":    {{0, 0, 0.5, 1}},
+	"right-half":   {{0.5, 0, 0.5, 1}},
+	"top-half":     {{0, 0, 1, 0.5}},
+	"bottom-half":  {{0, 0.5, 1, 0.5}},
+	"side-by-side": {{0, 0, 0.5, 1}, {0.5, 0, 0.5, 1}},
+	"grid-2x2":     {{0, 0, 0.5, 0.5}, {0.5, 0, 0.5, 0.5}, {0, 0.5, 0.5, 0.5}, {0.5, 0.5, 0.5, 0.5}},
+}
+
+// EWMH _NET_MOVERESIZE_WINDOW flags: northwest gravity, all four geometry
+// fields present, request from a pager or similar tool
+const moveResizeFlags = 1 | 0xf<<8 | 2<<12
+
+// Placement is where ApplyLayout put a window
+type Placement struct {
+	Window x.Window
+	Rect   image.Rectangle // Outer geometry including decorations, in root coordinates
+}
+
+// LayoutPresetNames returns the names of the layout presets
+func LayoutPresetNames() []string {
+	names := make([]string, 0, len(layoutPresets))
+	for name := range layoutPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// in returns the cell's rectangle within area. Edges are rounded so
+// neighboring cells share them without gaps.
+func (l layoutCell) in(area image.Rectangle) image.Rectangle {
+	dx, dy := float64(area.Dx()), float64(area.Dy())
+	return image.Rect(
+		area.Min.X+int(math.Round(l.x*dx)),
+		area.Min.Y+int(math.Round(l.y*dy)),
+		area.Min.X+int(math.Round((l.x+l.w)*dx)),
+		area.Min.Y+int(math.Round((l.y+l.h)*dy)),
+	)
+}
+
+// ApplyLayout arranges windows according to a preset such as "left-half" or
+// "grid-2x2". Without windows, single-window presets apply to the focused
+// window and the others to the first visible windows. Under i3 the windows
+// are made floating and placed over IPC; otherwise the window manager is
+// asked through EWMH, or the windows are configured directly if none runs.
+func (c *Client) ApplyLayout(ctx context.Context, preset string, windows []x.Window) ([]Placement, error) {
+	cells, ok := layoutPresets[strings.ToLower(preset)]
+	if !ok {
+		return nil, fmt.Errorf("unknown layout preset: %s (available: %s)",
+			preset, strings.Join(LayoutPresetNames(), ", "))
+	}
+	if err := c.checkFrozen(); err != nil {
+		return nil, err
+	}
+
+	if len(windows) == 0 {
+		var err error
+		if windows, err = c.defaultLayoutWindows(ctx, len(cells)); err != nil {
+			return nil, err
+		}
+	}
+	if len(windows) > len(cells) {
+		return nil, fmt.Errorf("preset %s has room for %d windows, got %d", preset, len(cells), len(windows))
+	}
+
+	area := c.workArea(ctx)
+	placements := make([]Placement, 0, len(windows))
+	for i, win := range windows {
+		rect := cells[i].in(area)
+		var err error
+		if c.I3Enabled() {
+			err = c.placeI3(ctx, win, rect)
+		} else {
+			err = c.placeEWMH(ctx, win, rect, len(cells) == 1 && cells[0] == layoutCell{0, 0, 1, 1})
+		}
+		if err != nil {
+			return placements, fmt.Errorf("failed to place window 0x%x: %w", uint32(win), err)
+		}
+		placements = append(placements, Placement{Window: win, Rect: rect})
+	}
+	return placements, nil
+}
+
+// defaultLayoutWindows picks the windows a preset applies to when the
+// caller names none
+func (c *Client) defaultLayoutWindows(ctx context.Context, n int) ([]x.Window, error) {
+	if n == 1 {
+		win, err := c.FocusedWindowContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []x.Window{win}, nil
+	}
+
+	listed, err := c.ListWindowsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(listed) == 0 {
+		return nil, fmt.Errorf("no visible windows to arrange")
+	}
+	var windows []x.Window
+	for _, w := range listed[:min(n, len(listed))] {
+		windows = append(windows, w.ID)
+	}
+	return windows, nil
+}
+
+// workArea returns the part of the screen available to windows: the focused
+// i3 workspace, the EWMH work area of the current desktop, or the whole screen
+func (c *Client) workArea(ctx context.Context) image.Rectangle {
+	screen := image.Rect(0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+
+	if c.I3Enabled() {
+		if workspaces, err := i3Call(c, ctx, i3.GetWorkspaces); err == nil {
+			for _, ws := range workspaces {
+				if ws.Focused {
+					return image.Rect(int(ws.Rect.X), int(ws.Rect.Y),
+						int(ws.Rect.X+ws.Rect.Width), int(ws.Rect.Y+ws.Rect.Height))
+				}
+			}
+		}
+		return screen
+	}
+
+	desktop, _ := c.getCardinalProperty(ctx, c.root, c.getAtom(ctx, "_NET_CURRENT_DESKTOP"))
+	values := c.getUint32Values(ctx, c.root, c.getAtom(ctx, "_NET_WORKAREA"), x.AtomCardinal, 4*int(desktop+1))
+	if len(values) < 4*int(desktop+1) {
+		return screen
+	}
+	v := values[4*desktop:]
+	area := image.Rect(int(v[0]), int(v[1]), int(v[0]+v[2]), int(v[1]+v[3]))
+	if area.Empty() {
+		return screen
+	}
+	return area
+}
+
+// placeI3 floats a window and moves it to rect over i3 IPC
+func (c *Client) placeI3(ctx context.Context, win x.Window, rect image.Rectangle) error {
+	command := fmt.Sprintf("[id=%d] floating enable, resize set %d px %d px, move position %d px %d px",
+		uint32(c.clientWindow(ctx, win)), rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y)
+	replies, err := i3Call(c, ctx, func() ([]i3.CommandResult, error) {
+		return i3.RunCommand(command)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run i3 command: %w", err)
+	}
+	for _, reply := range replies {
+		if !reply.Success {
+			return fmt.Errorf("i3 rejected %q: %s", command, reply.Error)
+		}
+	}
+	return nil
+}
+
+// placeEWMH moves a window to rect through the window manager, or directly
+// when no EWMH window manager is running. Maximizing uses the WM's maximized
+// state so the window follows later work area changes.
+func (c *Client) placeEWMH(ctx context.Context, win x.Window, rect image.Rectangle, maximize bool) error {
+	if _, ok := c.ewmhCheckWindow(ctx); !ok {
+		values := []uint32{uint32(rect.Min.X), uint32(rect.Min.Y), uint32(rect.Dx()), uint32(rect.Dy())}
+		return awaitCheck(c, ctx, func() error {
+			return x.ConfigureWindowChecked(c.conn, win,
+				x.ConfigWindowX|x.ConfigWindowY|x.ConfigWindowWidth|x.ConfigWindowHeight, values).Check(c.conn)
+		})
+	}
+
+	client := c.clientWindow(ctx, win)
+	wmState := c.getAtom(ctx, "_NET_WM_STATE")
+	vert := c.getAtom(ctx, "_NET_WM_STATE_MAXIMIZED_VERT")
+	horz := c.getAtom(ctx, "_NET_WM_STATE_MAXIMIZED_HORZ")
+
+	// Window managers ignore geometry requests for maximized windows
+	action := uint32(0) // _NET_WM_STATE_REMOVE
+	if maximize {
+		action = 1 // _NET_WM_STATE_ADD
+	}
+	if err := c.sendClientMessage(ctx, client, wmState, [5]uint32{action, uint32(vert), uint32(horz), 2}); err != nil {
+		return fmt.Errorf("failed to change window state: %w", err)
+	}
+	if maximize {
+		return nil
+	}
+
+	// The requested size is the client's, so leave room for the frame
+	var left, right, top, bottom uint32
+	if ext := c.getUint32Values(ctx, client, c.getAtom(ctx, "_NET_FRAME_EXTENTS"), x.AtomCardinal, 4); len(ext) == 4 {
+		left, right, top, bottom = ext[0], ext[1], ext[2], ext[3]
+	}
+	width := max(rect.Dx()-int(left+right), 1)
+	height := max(rect.Dy()-int(top+bottom), 1)
+
+	err := c.sendClientMessage(ctx, client, c.getAtom(ctx, "_NET_MOVERESIZE_WINDOW"), [5]uint32{
+		moveResizeFlags, uint32(rect.Min.X), uint32(rect.Min.Y), uint32(width), uint32(height),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request move and resize: %w", err)
+	}
+	return nil
+}
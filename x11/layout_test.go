@@ -0,0 +1,110 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"os/exec"
+	"strings"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestLayoutCellsTile(t *testing.T) {
+	// An odd-sized, offset work area must be covered without gaps or overlap
+	area := image.Rect(10, 25, 1011, 768)
+	for name, cells := range layoutPresets {
+		total := 0
+		for i, cell := range cells {
+			r := cell.in(area)
+			if !r.In(area) {
+				t.Errorf("%s cell %d = %v is outside %v", name, i, r, area)
+			}
+			total += r.Dx() * r.Dy()
+			for j := i + 1; j < len(cells); j++ {
+				if r.Overlaps(cells[j].in(area)) {
+					t.Errorf("%s cells %d and %d overlap", name, i, j)
+				}
+			}
+		}
+		if name == "side-by-side" || name == "grid-2x2" || name == "maximize" {
+			if want := area.Dx() * area.Dy(); total != want {
+				t.Errorf("%s covers %d pixels, want %d", name, total, want)
+			}
+		}
+	}
+}
+
+func TestLayoutCellHalves(t *testing.T) {
+	area := image.Rect(0, 20, 1920, 1080)
+	tests := map[string]image.Rectangle{
+		"left-half":   image.Rect(0, 20, 960, 1080),
+		"right-half":  image.Rect(960, 20, 1920, 1080),
+		"top-half":    image.Rect(0, 20, 1920, 550),
+		"bottom-half": image.Rect(0, 550, 1920, 1080),
+	}
+	for preset, want := range tests {
+		if got := layoutPresets[preset][0].in(area); got != want {
+			t.Errorf("%s = %v, want %v", preset, got, want)
+		}
+	}
+}
+
+func TestApplyLayoutUnknownPreset(t *testing.T) {
+	c := &Client{}
+	_, err := c.ApplyLayout(context.Background(), "cascade", nil)
+	if err == nil || !strings.Contains(err.Error(), "grid-2x2") {
+		t.Errorf("expected unknown preset error listing presets, got %v", err)
+	}
+}
+
+func TestApplyLayout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	if _, err := exec.LookPath("xterm"); err != nil {
+		t.Skip("xterm not available")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	pid, err := client.StartApp("xterm", []string{"-geometry", "40x10+50+60"})
+	if err != nil {
+		t.Fatalf("Failed to start xterm: %v", err)
+	}
+	defer client.StopApp(pid)
+	client.Wait(1000)
+
+	windows, err := client.ListWindows()
+	if err != nil || len(windows) == 0 {
+		t.Fatalf("no windows found: %v", err)
+	}
+
+	ctx := context.Background()
+	placements, err := client.ApplyLayout(ctx, "right-half", []x.Window{windows[0].ID})
+	if err != nil {
+		t.Fatalf("ApplyLayout failed: %v", err)
+	}
+	want := image.Rect(400, 0, 800, 600)
+	if len(placements) != 1 || placements[0].Rect != want {
+		t.Fatalf("placements = %+v, want one at %v", placements, want)
+	}
+	client.Wait(200)
+
+	// Without a window manager the window is configured directly
+	got, err := client.windowRect(ctx, windows[0].ID)
+	if err != nil {
+		t.Fatalf("windowRect failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("window is at %v, want %v", got, want)
+	}
+
+	if _, err := client.ApplyLayout(ctx, "left-half", []x.Window{windows[0].ID, windows[0].ID}); err == nil {
+		t.Error("expected an error for more windows than cells")
+	}
+}
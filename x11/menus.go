@@ -0,0 +1,116 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"sync"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+const (
+	// DefaultMenuWait is how long MenuCapture.Capture waits for a menu by default
+	DefaultMenuWait = time.Second
+	// menuSettle is how long a menu gets to draw itself before it is
+	// captured; short, since menus can close again any moment
+	menuSettle = 50 * time.Millisecond
+)
+
+// menuState fans the mapping of override-redirect windows out to the
+// MenuCaptures waiting for one
+type menuState struct {
+	mu       sync.Mutex
+	watching bool // The MapNotify handler is registered
+	subs     map[chan x.Window]struct{}
+}
+
+// MenuCapture catches a menu, tooltip or other override-redirect window
+// that maps after an action, which may close again before a delayed
+// screenshot is taken
+type MenuCapture struct {
+	c  *Client
+	ch chan x.Window
+}
+
+// Menu is an override-redirect window caught by MenuCapture
+type Menu struct {
+	Window x.Window
+	Class  string      // WM_CLASS, if the window has one
+	Image  *image.RGBA // The whole screen with the menu on top
+}
+
+// WatchMenus starts watching for override-redirect windows. Start it before
+// the action that opens the menu, then call Capture.
+func (c *Client) WatchMenus(ctx context.Context) (*MenuCapture, error) {
+	c.menus.mu.Lock()
+	defer c.menus.mu.Unlock()
+	if !c.menus.watching {
+		if err := c.selectRootEvents(ctx, x.EventMaskSubstructureNotify); err != nil {
+			return nil, err
+		}
+		c.menus.subs = make(map[chan x.Window]struct{})
+		c.addEventHandler(c.notifyMenus)
+		c.menus.watching = true
+	}
+	m := &MenuCapture{c: c, ch: make(chan x.Window, 8)}
+	c.menus.subs[m.ch] = struct{}{}
+	return m, nil
+}
+
+// notifyMenus passes override-redirect windows mapped on the root to the
+// waiting MenuCaptures. It runs on the event loop and never blocks.
+func (c *Client) notifyMenus(ev x.GenericEvent) {
+	if ev.GetEventCode() != x.MapNotifyEventCode {
+		return
+	}
+	mev, err := x.NewMapNotifyEvent(ev)
+	if err != nil || mev.Event != c.root || !mev.OverrideRedirect {
+		return
+	}
+	c.menus.mu.Lock()
+	defer c.menus.mu.Unlock()
+	for ch := range c.menus.subs {
+		select {
+		case ch <- mev.Window:
+		default:
+		}
+	}
+}
+
+// Capture waits up to timeout for an override-redirect window to map, gives
+// it menuSettle to draw and captures the whole screen with it on top. It
+// returns nil if none mapped in time. Either way it stops watching. The
+// image may be given back with RecycleScreenshot.
+func (m *MenuCapture) Capture(ctx context.Context, timeout time.Duration) (*Menu, error) {
+	defer m.Stop()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var win x.Window
+	select {
+	case win = <-m.ch:
+	case <-timer.C:
+		return nil, nil
+	case <-m.c.abortChan():
+		return nil, ErrAborted
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err := m.c.WaitContext(ctx, int(menuSettle.Milliseconds())); err != nil {
+		return nil, err
+	}
+	screen := m.c.screenBounds()
+	img, err := m.c.captureRect(ctx, screen.Min.X, screen.Min.Y, screen.Dx(), screen.Dy())
+	if err != nil {
+		return nil, err
+	}
+	return &Menu{Window: win, Class: m.c.getWindowClass(ctx, win), Image: img}, nil
+}
+
+// Stop stops watching; Capture does so itself
+func (m *MenuCapture) Stop() {
+	m.c.menus.mu.Lock()
+	delete(m.c.menus.subs, m.ch)
+	m.c.menus.mu.Unlock()
+}
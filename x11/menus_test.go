@@ -0,0 +1,59 @@
+package x11
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestCaptureMenu(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	watch, err := client.WatchMenus(ctx)
+	if err != nil {
+		t.Fatalf("WatchMenus failed: %v", err)
+	}
+	menu, err := watch.Capture(ctx, 200*time.Millisecond)
+	if err != nil || menu != nil {
+		t.Fatalf("Capture with no menu = %+v, %v", menu, err)
+	}
+
+	if watch, err = client.WatchMenus(ctx); err != nil {
+		t.Fatalf("WatchMenus failed: %v", err)
+	}
+	// An override-redirect window, the way menus and tooltips map
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatalf("AllocID failed: %v", err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 100, 100, 200, 150, 0,
+		x.WindowClassInputOutput, 0, x.CWOverrideRedirect, []uint32{1}).Check(client.conn)
+	if err != nil {
+		t.Fatalf("CreateWindow failed: %v", err)
+	}
+	x.MapWindow(client.conn, win)
+	client.conn.Flush()
+
+	menu, err = watch.Capture(ctx, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if menu == nil || menu.Window != win {
+		t.Fatalf("Capture = %+v, want window 0x%x", menu, win)
+	}
+	if got := menu.Image.Bounds(); got.Dx() != 800 || got.Dy() != 600 {
+		t.Errorf("Capture image is %v, want the whole screen", got)
+	}
+}
@@ -0,0 +1,101 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/linuxdeepin/go-x11-client/ext/test"
+)
+
+// MotionProfile controls how drags send the pointer motion between their
+// ends. Intermediate events are sent without waiting for the server and
+// only every Batch events a checked one flushes the group, so long drags
+// neither take a round trip per pixel nor flood the server.
+type MotionProfile struct {
+	Step  int // Pixels between intermediate motion events
+	Rate  int // Motion events per second at most, 0 for no limit
+	Batch int // Events per group, the last of which waits for the server
+}
+
+// DefaultMotionProfile is the motion profile of new clients
+var DefaultMotionProfile = MotionProfile{Step: 20, Rate: 500, Batch: 16}
+
+// maxMotionEvents bounds the events of one drag however small the step
+const maxMotionEvents = 2000
+
+// Validate checks that the profile's values are usable
+func (p MotionProfile) Validate() error {
+	if p.Step < 1 {
+		return fmt.Errorf("drag step must be at least 1 pixel, got %d", p.Step)
+	}
+	if p.Rate < 0 {
+		return fmt.Errorf("drag rate must not be negative, got %d", p.Rate)
+	}
+	if p.Batch < 1 {
+		return fmt.Errorf("drag batch must be at least 1 event, got %d", p.Batch)
+	}
+	return nil
+}
+
+// SetMotionProfile sets the profile drags use for pointer motion
+func (c *Client) SetMotionProfile(profile MotionProfile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	c.motion = profile
+	return nil
+}
+
+// MotionProfile returns the profile drags use for pointer motion
+func (c *Client) MotionProfile() MotionProfile {
+	return c.motion
+}
+
+// motionPath returns the points a drag from (fromX, fromY) to (toX, toY)
+// passes through, excluding the start and ending exactly at the end
+func motionPath(fromX, fromY, toX, toY int, step int) [][2]int {
+	dist := math.Hypot(float64(toX-fromX), float64(toY-fromY))
+	n := int(math.Ceil(dist / float64(max(step, 1))))
+	n = min(max(n, 1), maxMotionEvents)
+	path := make([][2]int, n)
+	for i := 1; i <= n; i++ {
+		path[i-1] = [2]int{fromX + (toX-fromX)*i/n, fromY + (toY-fromY)*i/n}
+	}
+	return path
+}
+
+// fakeMotionPath moves the pointer along motionPath with the client's motion
+// profile. Callers press and release the button around it.
+func (c *Client) fakeMotionPath(ctx context.Context, fromX, fromY, toX, toY int) error {
+	profile := c.motion
+	path := motionPath(fromX, fromY, toX, toY, profile.Step)
+	var interval time.Duration
+	if profile.Rate > 0 {
+		interval = time.Second * time.Duration(profile.Batch) / time.Duration(profile.Rate)
+	}
+
+	start := time.Now()
+	for i, p := range path {
+		flush := i == len(path)-1 || (i+1)%profile.Batch == 0
+		if !flush {
+			test.FakeInput(c.conn, MotionNotify, 0, 0, c.root, int16(p[0]), int16(p[1]), 0)
+			c.trail.motion(p[0], p[1])
+			continue
+		}
+		// The checked event flushes the group and waits until it's processed
+		if err := c.fakeMotion(ctx, p[0], p[1]); err != nil {
+			return err
+		}
+		if interval > 0 && i < len(path)-1 {
+			if wait := interval - time.Since(start); wait > 0 {
+				if err := c.WaitContext(ctx, int(wait.Milliseconds())); err != nil {
+					return err
+				}
+			}
+			start = time.Now()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package x11
+
+import "testing"
+
+func TestMotionPath(t *testing.T) {
+	tests := []struct {
+		name                   string
+		fromX, fromY, toX, toY int
+		step                   int
+		wantLen                int
+	}{
+		{"horizontal", 0, 0, 100, 0, 20, 5},
+		{"diagonal", 0, 0, 30, 40, 10, 5},
+		{"partial step", 0, 0, 25, 0, 10, 3},
+		{"no movement", 50, 50, 50, 50, 10, 1},
+		{"capped", 0, 0, 10000, 0, 1, maxMotionEvents},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := motionPath(tt.fromX, tt.fromY, tt.toX, tt.toY, tt.step)
+			if len(path) != tt.wantLen {
+				t.Fatalf("len(path) = %d, want %d", len(path), tt.wantLen)
+			}
+			if end := path[len(path)-1]; end != [2]int{tt.toX, tt.toY} {
+				t.Errorf("path ends at %v, want (%d, %d)", end, tt.toX, tt.toY)
+			}
+		})
+	}
+}
+
+func TestMotionProfileValidate(t *testing.T) {
+	if err := DefaultMotionProfile.Validate(); err != nil {
+		t.Errorf("default profile invalid: %v", err)
+	}
+	if err := (MotionProfile{Step: 5, Rate: 0, Batch: 1}).Validate(); err != nil {
+		t.Errorf("unlimited rate rejected: %v", err)
+	}
+	for _, p := range []MotionProfile{
+		{Step: 0, Rate: 100, Batch: 8},
+		{Step: 5, Rate: -1, Batch: 8},
+		{Step: 5, Rate: 100, Batch: 0},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%+v accepted, want an error", p)
+		}
+	}
+
+	c := &Client{motion: DefaultMotionProfile}
+	if err := c.SetMotionProfile(MotionProfile{}); err == nil || c.MotionProfile() != DefaultMotionProfile {
+		t.Error("an invalid profile must be rejected and leave the current one")
+	}
+}
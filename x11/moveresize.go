@@ -0,0 +1,115 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// moveResizeSettle is how long the window manager gets to carry out a
+// move and resize before the window is configured directly
+const moveResizeSettle = 500 * time.Millisecond
+
+// MoveResizeWindow moves a window so that its outer geometry, including
+// window manager decorations, is width x height at left, top in root
+// coordinates, and returns the geometry it got
+func (c *Client) MoveResizeWindow(win x.Window, left, top, width, height int) (image.Rectangle, error) {
+	return c.MoveResizeWindowContext(context.Background(), win, left, top, width, height)
+}
+
+// MoveResizeWindowContext is like MoveResizeWindow but gives up when ctx is
+// done. Under i3 the window is made floating and placed over IPC. Other
+// EWMH window managers get a _NET_MOVERESIZE_WINDOW request, and if they
+// don't carry it out within moveResizeSettle, a ConfigureWindow request
+// they are bound to handle. Without a window manager the window is
+// configured directly. The WM may still constrain the geometry, e.g. to the
+// window's minimum size.
+func (c *Client) MoveResizeWindowContext(ctx context.Context, win x.Window, left, top, width, height int) (image.Rectangle, error) {
+	if width <= 0 || height <= 0 {
+		return image.Rectangle{}, fmt.Errorf("invalid window size %dx%d", width, height)
+	}
+	if err := c.checkFrozen(); err != nil {
+		return image.Rectangle{}, err
+	}
+	frame, err := c.topLevelWindow(ctx, win)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	rect := image.Rect(left, top, left+width, top+height)
+
+	if c.I3Enabled() {
+		err = c.placeI3(ctx, frame, rect)
+	} else {
+		err = c.placeEWMH(ctx, frame, rect, false)
+	}
+	if err != nil {
+		return image.Rectangle{}, fmt.Errorf("failed to move window 0x%x: %w", uint32(win), err)
+	}
+
+	got, err := c.waitGeometry(ctx, frame, rect, moveResizeSettle)
+	if err != nil || got == rect || c.I3Enabled() {
+		return got, err
+	}
+	if _, ok := c.ewmhCheckWindow(ctx); !ok {
+		return got, nil
+	}
+
+	// The WM ignored the EWMH request; configuring the application window
+	// asks it the ICCCM way, leaving room for the frame
+	app := c.clientWindow(ctx, frame)
+	delta := got.Size().Sub(c.windowSize(ctx, app))
+	values := []uint32{uint32(left), uint32(top), uint32(max(width-delta.X, 1)), uint32(max(height-delta.Y, 1))}
+	err = awaitCheck(c, ctx, func() error {
+		return x.ConfigureWindowChecked(c.conn, app,
+			x.ConfigWindowX|x.ConfigWindowY|x.ConfigWindowWidth|x.ConfigWindowHeight, values).Check(c.conn)
+	})
+	if err != nil {
+		return got, fmt.Errorf("failed to configure window 0x%x: %w", uint32(app), err)
+	}
+	return c.waitGeometry(ctx, frame, rect, moveResizeSettle)
+}
+
+// waitGeometry waits up to timeout for win to have geometry want and
+// returns the geometry it has then
+func (c *Client) waitGeometry(ctx context.Context, win x.Window, want image.Rectangle, timeout time.Duration) (image.Rectangle, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		got, err := c.windowRect(ctx, win)
+		if err != nil || got == want || time.Now().After(deadline) {
+			return got, err
+		}
+		if err := c.WaitContext(ctx, 20); err != nil {
+			return got, err
+		}
+	}
+}
+
+// windowSize returns the size of win, zero if it can't be read
+func (c *Client) windowSize(ctx context.Context, win x.Window) image.Point {
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return image.Point{}
+	}
+	return rect.Size()
+}
+
+// topLevelWindow returns the child of the root window that win is in: the
+// WM frame of an application window under a reparenting window manager,
+// or win itself
+func (c *Client) topLevelWindow(ctx context.Context, win x.Window) (x.Window, error) {
+	for {
+		tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+			return x.QueryTree(c.conn, win).Reply(c.conn)
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to query window tree: %w", err)
+		}
+		if tree.Parent == c.root || tree.Parent == x.None {
+			return win, nil
+		}
+		win = tree.Parent
+	}
+}
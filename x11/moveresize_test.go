@@ -0,0 +1,55 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestMoveResizeWindowInvalidSize(t *testing.T) {
+	c := &Client{}
+	if _, err := c.MoveResizeWindowContext(context.Background(), 1, 0, 0, 0, 100); err == nil {
+		t.Error("expected error for a window without width")
+	}
+}
+
+func TestMoveResizeWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 0, 0, 300, 200, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	x.MapWindow(client.conn, win)
+	client.conn.Flush()
+
+	// Without a window manager the window is configured directly
+	want := image.Rect(120, 80, 520, 380)
+	got, err := client.MoveResizeWindowContext(ctx, win, 120, 80, 400, 300)
+	if err != nil {
+		t.Fatalf("MoveResizeWindow failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("MoveResizeWindow = %v, want %v", got, want)
+	}
+	if rect, err := client.windowRect(ctx, win); err != nil || rect != want {
+		t.Errorf("window is at %v (%v), want %v", rect, err, want)
+	}
+}
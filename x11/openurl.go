@@ -0,0 +1,219 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+const (
+	// defaultBrowser is started by OpenURL when no browser window is open
+	defaultBrowser = "firefox"
+	// addressBarSettle is how long OpenURL waits after a key for the
+	// address bar to react
+	addressBarSettle = 150 * time.Millisecond
+	// addressBarToolbarThreshold is the fraction of toolbar pixels that
+	// must change for the address bar to count as focused
+	addressBarToolbarThreshold = 0.002
+)
+
+// addressBarKeys are tried in turn to focus the address bar: ctrl+l, once
+// more in case the first press went to a page still grabbing keys, then F6
+var addressBarKeys = []string{"ctrl+l", "ctrl+l", "F6"}
+
+// OpenedURL is the outcome of OpenURL
+type OpenedURL struct {
+	Window   x.Window
+	PID      int      // PID of the browser if it was started, else 0
+	Attempts int      // Keys pressed until the address bar was focused, 0 if the browser was started
+	Pasted   bool     // The URL was pasted from CLIPBOARD because typing it didn't take
+	PageLoad PageLoad // How loading the page went
+}
+
+// OpenURL opens url in browser, or in any browser StartBrowser knows if
+// browser is empty. If a window of the browser is open, the focused one or
+// else the first, it focuses the address bar with ctrl+l, checking it took
+// and retrying, types the URL, pasting it from CLIPBOARD instead if the
+// address bar doesn't read back as the URL, and presses Enter. Otherwise it
+// starts the browser with the URL. Either way it waits up to timeout for the
+// page to load; a page still loading is reported in PageLoad.Busy.
+func (c *Client) OpenURL(ctx context.Context, url, browser string, timeout time.Duration) (OpenedURL, error) {
+	if err := c.checkFrozen(); err != nil {
+		return OpenedURL{}, err
+	}
+	if strings.TrimSpace(url) == "" || strings.ContainsAny(url, "\n\r") {
+		return OpenedURL{}, fmt.Errorf("invalid URL %q", url)
+	}
+	if browser != "" && !IsBrowser(browser) {
+		return OpenedURL{}, fmt.Errorf("unknown browser %s (supported: %s)", browser, strings.Join(BrowserNames(), ", "))
+	}
+	match := func(class string) bool { return browserClass(class, browser) }
+
+	var opened OpenedURL
+	win, err := c.browserWindow(ctx, match)
+	if err != nil {
+		return OpenedURL{}, err
+	}
+	start := time.Now()
+	if win == 0 {
+		program := browser
+		if program == "" {
+			program = defaultBrowser
+		}
+		before, err := c.windowSet(ctx, match)
+		if err != nil {
+			return OpenedURL{}, err
+		}
+		if opened.PID, err = c.StartBrowser(program, nil, url); err != nil {
+			return OpenedURL{}, err
+		}
+		if win, err = c.waitNewWindow(ctx, opened.PID, program, program, match, before, start.Add(timeout)); err != nil {
+			return opened, err
+		}
+	} else {
+		if err := c.FocusWindowContext(ctx, win); err != nil {
+			return OpenedURL{}, err
+		}
+		if opened.Attempts, err = c.focusAddressBar(ctx, win); err != nil {
+			return opened, err
+		}
+		if opened.Pasted, err = c.enterAddress(ctx, url); err != nil {
+			return opened, err
+		}
+		if err := c.KeyPressContext(ctx, "Return"); err != nil {
+			return opened, err
+		}
+	}
+	opened.Window = win
+
+	remaining := max(timeout-time.Since(start), launchPageQuiet)
+	if opened.PageLoad, err = c.WaitPageLoaded(ctx, win, remaining, launchPageQuiet); err != nil {
+		return opened, err
+	}
+	return opened, nil
+}
+
+// browserClass reports whether a window class belongs to browser, or to
+// any browser StartBrowser knows if browser is empty
+func browserClass(class, browser string) bool {
+	class = strings.ToLower(class)
+	if browser == "" {
+		return IsBrowser(class)
+	}
+	// google-chrome-stable's windows are of class Google-chrome
+	program := strings.TrimSuffix(filepath.Base(browser), "-stable")
+	return class == program
+}
+
+// browserWindow returns the focused window if it is a browser's, else the
+// first mapped browser window, or 0 if there is none
+func (c *Client) browserWindow(ctx context.Context, match func(string) bool) (x.Window, error) {
+	windows, err := c.classWindows(ctx, match)
+	if err != nil || len(windows) == 0 {
+		return 0, err
+	}
+	if focused, err := c.FocusedWindowContext(ctx); err == nil {
+		focused = c.clientWindow(ctx, focused)
+		for _, win := range windows {
+			if win == focused {
+				return win, nil
+			}
+		}
+	}
+	return windows[0], nil
+}
+
+// focusAddressBar presses addressBarKeys in turn until the address bar of
+// the browser window win reacts and returns how many keys it took. Focusing
+// it selects the current address, which takes PRIMARY from its owner, and
+// draws the focus ring and suggestions over the toolbar; either counts.
+func (c *Client) focusAddressBar(ctx context.Context, win x.Window) (int, error) {
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return 0, err
+	}
+	rect.Max.Y = min(rect.Max.Y, rect.Min.Y+toolbarHeight)
+	if rect = rect.Intersect(c.screenBounds()); rect.Empty() {
+		return 0, fmt.Errorf("window 0x%x is outside the screen", uint32(win))
+	}
+
+	for attempt, key := range addressBarKeys {
+		// Own PRIMARY so an address bar selecting its text shows as a new owner
+		if err := c.SetSelectionContext(ctx, "PRIMARY", ""); err != nil {
+			return attempt, err
+		}
+		before, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+		if err != nil {
+			return attempt, fmt.Errorf("failed to capture toolbar: %w", err)
+		}
+		err = c.KeyComboContext(ctx, key)
+		if err == nil {
+			err = c.WaitContext(ctx, int(addressBarSettle.Milliseconds()))
+		}
+		var after *image.RGBA
+		if err == nil {
+			after, err = c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+		}
+		if err != nil {
+			c.frames.put(before)
+			return attempt + 1, err
+		}
+		changed := changedFraction(before, after) > addressBarToolbarThreshold
+		c.frames.put(before)
+		c.frames.put(after)
+		if changed || c.selectionTaken(ctx, "PRIMARY") {
+			return attempt + 1, nil
+		}
+	}
+	return len(addressBarKeys), fmt.Errorf("the address bar of window 0x%x did not react to %s", uint32(win), strings.Join(addressBarKeys, ", "))
+}
+
+// selectionTaken reports whether another client owns the selection name
+func (c *Client) selectionTaken(ctx context.Context, name string) bool {
+	selection := c.getAtom(ctx, name)
+	if selection == 0 {
+		return false
+	}
+	reply, err := await(c, ctx, func() (*x.GetSelectionOwnerReply, error) {
+		return x.GetSelectionOwner(c.conn, selection).Reply(c.conn)
+	})
+	return err == nil && reply.Owner != 0 && reply.Owner != c.selection.win
+}
+
+// enterAddress types url into the focused address bar and reads it back
+// by selecting it all. If typing fails or the text differs, e.g. because
+// keys were dropped or the keymap lacks a character, it pastes url from
+// CLIPBOARD over the selection instead and returns true.
+func (c *Client) enterAddress(ctx context.Context, url string) (bool, error) {
+	if err := c.TypeContext(ctx, url); err == nil {
+		if c.KeyComboContext(ctx, "ctrl+a") == nil && c.WaitContext(ctx, int(addressBarSettle.Milliseconds())) == nil {
+			if text, err := c.ReadSelectionContext(ctx, "PRIMARY"); err == nil && sameAddress(text, url) {
+				return false, nil
+			}
+		}
+	} else if ctx.Err() != nil || c.checkFrozen() != nil {
+		return false, err
+	}
+
+	if err := c.SetSelectionContext(ctx, "CLIPBOARD", url); err != nil {
+		return true, err
+	}
+	if err := c.KeyComboContext(ctx, "ctrl+a"); err != nil {
+		return true, err
+	}
+	if err := c.KeyComboContext(ctx, "ctrl+v"); err != nil {
+		return true, err
+	}
+	return true, c.WaitContext(ctx, int(addressBarSettle.Milliseconds()))
+}
+
+// sameAddress reports whether the address bar text is url, allowing for the
+// trailing slash browsers complete host names with
+func sameAddress(text, url string) bool {
+	return strings.TrimSuffix(strings.TrimSpace(text), "/") == strings.TrimSuffix(strings.TrimSpace(url), "/")
+}
@@ -0,0 +1,58 @@
+package x11
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBrowserClass(t *testing.T) {
+	tests := []struct {
+		class, browser string
+		want           bool
+	}{
+		{"firefox", "", true},
+		{"Chromium", "", true},
+		{"Google-chrome", "", true},
+		{"XTerm", "", false},
+		{"firefox", "firefox", true},
+		{"firefox", "chromium", false},
+		{"Google-chrome", "google-chrome-stable", true},
+		{"Brave-browser", "/usr/bin/brave-browser", true},
+	}
+	for _, tt := range tests {
+		if got := browserClass(tt.class, tt.browser); got != tt.want {
+			t.Errorf("browserClass(%q, %q) = %v, want %v", tt.class, tt.browser, got, tt.want)
+		}
+	}
+}
+
+func TestSameAddress(t *testing.T) {
+	tests := []struct {
+		text, url string
+		want      bool
+	}{
+		{"example.com/", "example.com", true},
+		{"https://example.com/a", "https://example.com/a", true},
+		{"https://example.com/a ", "https://example.com/a/", true},
+		{"exmple.com", "example.com", false},
+		{"", "example.com", false},
+	}
+	for _, tt := range tests {
+		if got := sameAddress(tt.text, tt.url); got != tt.want {
+			t.Errorf("sameAddress(%q, %q) = %v, want %v", tt.text, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestOpenURLInvalid(t *testing.T) {
+	c := &Client{}
+	for _, tt := range []struct{ url, browser string }{
+		{"", ""},
+		{"example.com\nrm -rf", ""},
+		{"example.com", "lynx"},
+	} {
+		if _, err := c.OpenURL(context.Background(), tt.url, tt.browser, 0); err == nil {
+			t.Errorf("OpenURL(%q, %q) succeeded, want an error", tt.url, tt.browser)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package x11
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// pacingSmoothing is the weight of each new round trip in the average
+	pacingSmoothing = 0.2
+	// pacingFloor is the least baseline latency, so the few microseconds
+	// of an idle local server don't make every hiccup look like load
+	pacingFloor = 2 * time.Millisecond
+	// pacingLoadFactor is how many times the baseline the average round
+	// trip must take for the server to count as saturated
+	pacingLoadFactor = 4
+	// maxPacingDelay bounds the pause added before each press
+	maxPacingDelay = 100 * time.Millisecond
+)
+
+// pacingState tracks the round-trip latency of injected input to slow
+// injection down while the X server is saturated
+type pacingState struct {
+	mu       sync.Mutex
+	fixed    bool          // Adaptive pacing is off
+	avg      time.Duration // Smoothed round trip
+	baseline time.Duration // Lowest smoothed round trip seen
+	loaded   bool          // The last sample found the server saturated
+}
+
+// Pacing describes the X server's responsiveness to injected input
+type Pacing struct {
+	Latency  time.Duration // Smoothed round trip of input requests
+	Baseline time.Duration // Lowest smoothed round trip seen
+	Extra    time.Duration // Pause added before each key and button press, 0 while the server keeps up
+	Fixed    bool          // Adaptive pacing is turned off
+}
+
+// Pacing returns the measured latency and the pause it currently adds
+func (c *Client) Pacing() Pacing {
+	c.pacing.mu.Lock()
+	defer c.pacing.mu.Unlock()
+	return Pacing{
+		Latency:  c.pacing.avg,
+		Baseline: c.pacing.baseline,
+		Extra:    c.pacing.extra(),
+		Fixed:    c.pacing.fixed,
+	}
+}
+
+// recordLatency adds the round trip of an input request to the average
+func (c *Client) recordLatency(d time.Duration) {
+	p := &c.pacing
+	p.mu.Lock()
+	if p.avg == 0 {
+		p.avg = d
+	} else {
+		p.avg += time.Duration(pacingSmoothing * float64(d-p.avg))
+	}
+	if p.baseline == 0 || p.avg < p.baseline {
+		p.baseline = p.avg
+	}
+	extra := p.extra()
+	changed := (extra > 0) != p.loaded
+	p.loaded = extra > 0
+	avg := p.avg
+	p.mu.Unlock()
+
+	if changed && extra > 0 {
+		slog.Info("X server is slow to answer, slowing input down", "latency", avg, "pause", extra)
+	} else if changed {
+		slog.Info("X server keeps up again, input at full speed", "latency", avg)
+	}
+}
+
+// extra returns the pause to add before a press. Callers hold p.mu.
+func (p *pacingState) extra() time.Duration {
+	if p.fixed || p.avg <= pacingLoadFactor*max(p.baseline, pacingFloor) {
+		return 0
+	}
+	// Give the server about two round trips to catch up between presses
+	return min(2*p.avg, maxPacingDelay)
+}
+
+// paceInput waits before a press while the X server is saturated, so
+// events aren't dropped by applications that can't keep up
+func (c *Client) paceInput(ctx context.Context) error {
+	c.pacing.mu.Lock()
+	extra := c.pacing.extra()
+	c.pacing.mu.Unlock()
+	if extra == 0 {
+		return nil
+	}
+	return c.WaitContext(ctx, int(extra.Milliseconds()))
+}
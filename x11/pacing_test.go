@@ -0,0 +1,59 @@
+package x11
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPacing(t *testing.T) {
+	c := &Client{}
+	for i := 0; i < 20; i++ {
+		c.recordLatency(200 * time.Microsecond)
+	}
+	if p := c.Pacing(); p.Extra != 0 || p.Baseline != 200*time.Microsecond {
+		t.Fatalf("Pacing of an idle server = %+v", p)
+	}
+
+	// A few slow answers under the floor don't count as load
+	for i := 0; i < 20; i++ {
+		c.recordLatency(5 * time.Millisecond)
+	}
+	if p := c.Pacing(); p.Extra != 0 {
+		t.Errorf("Pacing at 5ms = %+v, want no pause", p)
+	}
+
+	for i := 0; i < 30; i++ {
+		c.recordLatency(40 * time.Millisecond)
+	}
+	p := c.Pacing()
+	if p.Extra < 50*time.Millisecond || p.Extra > maxPacingDelay {
+		t.Errorf("Pacing at 40ms = %+v, want a pause of about 80ms", p)
+	}
+
+	for i := 0; i < 50; i++ {
+		c.recordLatency(time.Second)
+	}
+	if p := c.Pacing(); p.Extra != maxPacingDelay {
+		t.Errorf("Pacing at 1s = %+v, want the pause capped at %s", p, maxPacingDelay)
+	}
+
+	// The pause goes away once the server keeps up again
+	for i := 0; i < 60; i++ {
+		c.recordLatency(200 * time.Microsecond)
+	}
+	if p := c.Pacing(); p.Extra != 0 {
+		t.Errorf("Pacing after recovery = %+v, want no pause", p)
+	}
+	if err := c.paceInput(context.Background()); err != nil {
+		t.Errorf("paceInput = %v", err)
+	}
+
+	fixed := &Client{}
+	fixed.pacing.fixed = true
+	fixed.recordLatency(time.Millisecond)
+	fixed.recordLatency(time.Second)
+	if p := fixed.Pacing(); p.Extra != 0 || !p.Fixed {
+		t.Errorf("Pacing with fixed pacing = %+v", p)
+	}
+}
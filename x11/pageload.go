@@ -0,0 +1,169 @@
+package x11
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+const (
+	// pageLoadPoll is how often WaitPageLoaded samples the window
+	pageLoadPoll = 250 * time.Millisecond
+	// toolbarHeight is how much of the top of a browser window holds the
+	// tab strip and toolbar, where the loading throbber animates
+	toolbarHeight = 100
+	// toolbarThreshold is the fraction of toolbar pixels that may change
+	// between samples without counting as activity
+	toolbarThreshold = 0.001
+)
+
+// pageStateScript reports the document state and how many resources the
+// page has fetched; a count that stops growing means the network is quiet
+const pageStateScript = `JSON.stringify({ready: document.readyState, resources: performance.getEntriesByType("resource").length})`
+
+// PageLoad is the outcome of WaitPageLoaded
+type PageLoad struct {
+	Loaded     bool          // All signals were quiet for the quiet period
+	Title      string        // Window title at the end
+	Elapsed    time.Duration // Time spent waiting
+	DevTools   bool          // The browser's DevTools were used for network idle
+	ReadyState string        // document.readyState, if DevTools were used
+	Resources  int           // Resources the page fetched, if DevTools were used
+	Busy       []string      // Signals still active when the timeout passed
+}
+
+// pageSample is the state of a browser window at one point in time
+type pageSample struct {
+	title      string
+	toolbar    *image.RGBA
+	ready      string
+	resources  int
+	devToolsOK bool
+}
+
+// WaitPageLoaded waits until the page in a browser window looks loaded: the
+// window title has stopped changing, the toolbar where the loading throbber
+// spins is still and, for Chromium-based browsers started by StartBrowser,
+// the document is complete and no new resources were fetched, all for the
+// quiet period. It returns when that happens or timeout passes, whichever
+// is first; a timeout is not an error but reported in PageLoad.Busy. Waits
+// are interrupted by AbortAll.
+func (c *Client) WaitPageLoaded(ctx context.Context, win x.Window, timeout, quiet time.Duration) (PageLoad, error) {
+	if err := c.EnsureVisible(ctx, win); err != nil {
+		return PageLoad{}, err
+	}
+	start := time.Now()
+	app := c.clientWindow(ctx, win)
+
+	var devTools string
+	if pid, ok := c.getCardinalProperty(ctx, app, c.getAtom(ctx, "_NET_WM_PID")); ok {
+		devTools, _ = c.devToolsPage(ctx, int(pid), c.getWindowName(ctx, app))
+	}
+
+	prev, err := c.samplePage(ctx, win, app, devTools)
+	if err != nil {
+		return PageLoad{}, err
+	}
+	defer func() { c.frames.put(prev.toolbar) }()
+
+	// When each signal last showed activity
+	titleChange, toolbarChange, networkChange := start, start, start
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(pageLoadPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			result := prev.result(start, devTools != "")
+			now := time.Now()
+			if now.Sub(titleChange) < quiet {
+				result.Busy = append(result.Busy, "title changing")
+			}
+			if now.Sub(toolbarChange) < quiet {
+				result.Busy = append(result.Busy, "toolbar animating")
+			}
+			if devTools != "" && (prev.ready != "complete" || now.Sub(networkChange) < quiet) {
+				result.Busy = append(result.Busy, "network active")
+			}
+			return result, nil
+		case <-c.abortChan():
+			return PageLoad{}, ErrAborted
+		case <-ctx.Done():
+			return PageLoad{}, ctx.Err()
+		}
+
+		cur, err := c.samplePage(ctx, win, app, devTools)
+		if err != nil {
+			return PageLoad{}, err
+		}
+		now := time.Now()
+		if cur.title != prev.title {
+			titleChange = now
+		}
+		if cur.toolbar.Bounds().Size() != prev.toolbar.Bounds().Size() ||
+			changedFraction(cur.toolbar, prev.toolbar) > toolbarThreshold {
+			toolbarChange = now
+		}
+		if devTools != "" && (!cur.devToolsOK || cur.ready != "complete" || cur.resources != prev.resources) {
+			networkChange = now
+		}
+		c.frames.put(prev.toolbar)
+		prev = cur
+
+		if now.Sub(titleChange) >= quiet && now.Sub(toolbarChange) >= quiet && now.Sub(networkChange) >= quiet {
+			result := prev.result(start, devTools != "")
+			result.Loaded = true
+			return result, nil
+		}
+	}
+}
+
+// samplePage reads the title, captures the toolbar and, if devTools is set,
+// asks the page for its state
+func (c *Client) samplePage(ctx context.Context, win, app x.Window, devTools string) (pageSample, error) {
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return pageSample{}, err
+	}
+	rect.Max.Y = min(rect.Max.Y, rect.Min.Y+toolbarHeight)
+	rect = rect.Intersect(c.screenBounds())
+	if rect.Empty() {
+		return pageSample{}, fmt.Errorf("window 0x%x is outside the screen", uint32(win))
+	}
+	toolbar, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return pageSample{}, fmt.Errorf("failed to capture window: %w", err)
+	}
+
+	sample := pageSample{title: c.getWindowName(ctx, app), toolbar: toolbar}
+	if devTools != "" {
+		// A failed evaluation, e.g. during navigation, counts as activity
+		if value, err := cdpEvaluate(ctx, devTools, pageStateScript); err == nil {
+			var text string
+			var state struct {
+				Ready     string `json:"ready"`
+				Resources int    `json:"resources"`
+			}
+			if json.Unmarshal(value, &text) == nil && json.Unmarshal([]byte(text), &state) == nil {
+				sample.ready, sample.resources, sample.devToolsOK = state.Ready, state.Resources, true
+			}
+		}
+	}
+	return sample, nil
+}
+
+// result turns the last sample into a PageLoad
+func (s pageSample) result(start time.Time, devTools bool) PageLoad {
+	result := PageLoad{Title: s.title, Elapsed: time.Since(start), DevTools: devTools}
+	if devTools {
+		result.ReadyState, result.Resources = s.ready, s.resources
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package x11
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestPageSampleResult(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	s := pageSample{title: "Example", ready: "complete", resources: 4}
+
+	got := s.result(start, false)
+	if got.Title != "Example" || got.ReadyState != "" || got.Resources != 0 || got.DevTools {
+		t.Errorf("result without DevTools = %+v", got)
+	}
+	if got.Elapsed < time.Second {
+		t.Errorf("Elapsed = %v, want at least 1s", got.Elapsed)
+	}
+
+	got = s.result(start, true)
+	if !got.DevTools || got.ReadyState != "complete" || got.Resources != 4 {
+		t.Errorf("result with DevTools = %+v", got)
+	}
+}
+
+func TestWaitPageLoadedStaticWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := Connect()
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	pid, err := client.StartApp("xterm", []string{"-title", "Page Load Test"})
+	if err != nil {
+		t.Skipf("xterm not available: %v", err)
+	}
+	defer client.StopApp(pid)
+	time.Sleep(500 * time.Millisecond)
+
+	windows, err := client.ListWindows()
+	if err != nil {
+		t.Fatalf("Failed to list windows: %v", err)
+	}
+	var win x.Window
+	for _, w := range windows {
+		if w.Title == "Page Load Test" {
+			win = w.ID
+		}
+	}
+	if win == 0 {
+		t.Fatal("Could not find test window")
+	}
+
+	// Nothing changes in an idle xterm, so it counts as loaded after the quiet period
+	load, err := client.WaitPageLoaded(context.Background(), win, 5*time.Second, 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitPageLoaded() error = %v", err)
+	}
+	if !load.Loaded || load.DevTools || load.Title != "Page Load Test" {
+		t.Errorf("WaitPageLoaded() = %+v", load)
+	}
+}
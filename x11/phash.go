@@ -0,0 +1,98 @@
+package x11
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"image"
+	"math/bits"
+)
+
+// contentSeed makes ContentHash values comparable within this process
+var contentSeed = maphash.MakeSeed()
+
+// PerceptualHash returns a 64-bit difference hash of an image: it is shrunk
+// to 9x8 cells of average brightness and each bit says whether a cell is
+// brighter than its right neighbour. Images that look alike get hashes a
+// small Hamming distance apart; compression noise or a blinking cursor
+// rarely flips a bit, while a new window or page flips many.
+func PerceptualHash(img image.Image) uint64 {
+	const cols, rows = 9, 8
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+	var sum [rows][cols]uint64
+	var count [rows][cols]uint64
+
+	// Sample every few pixels; a 1080p screen still contributes thousands
+	// of samples to each cell
+	step := max(1, min(bounds.Dx(), bounds.Dy())/256)
+	rgba, _ := img.(*image.RGBA)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		row := (y - bounds.Min.Y) * rows / bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			col := (x - bounds.Min.X) * cols / bounds.Dx()
+			var r, g, b uint32
+			if rgba != nil {
+				i := rgba.PixOffset(x, y)
+				r, g, b = uint32(rgba.Pix[i]), uint32(rgba.Pix[i+1]), uint32(rgba.Pix[i+2])
+			} else {
+				r, g, b, _ = img.At(x, y).RGBA()
+				r, g, b = r>>8, g>>8, b>>8
+			}
+			// Rec. 601 luma in integer arithmetic
+			sum[row][col] += uint64(299*r + 587*g + 114*b)
+			count[row][col]++
+		}
+	}
+
+	var hash uint64
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols-1; col++ {
+			// Compare averages without dividing: a/n > b/m  <=>  a*m > b*n
+			left := sum[row][col] * max(count[row][col+1], 1)
+			right := sum[row][col+1] * max(count[row][col], 1)
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HashDistance returns the number of differing bits of two perceptual
+// hashes, from 0 for images that look the same to 64
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// ContentHash returns a hash of an image's exact pixels, for telling
+// whether anything at all changed. Values are only comparable within one
+// process.
+func ContentHash(img image.Image) uint64 {
+	var h maphash.Hash
+	h.SetSeed(contentSeed)
+	bounds := img.Bounds()
+	var size [8]byte
+	binary.LittleEndian.PutUint32(size[:4], uint32(bounds.Dx()))
+	binary.LittleEndian.PutUint32(size[4:], uint32(bounds.Dy()))
+	h.Write(size[:])
+
+	if rgba, ok := img.(*image.RGBA); ok {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			i := rgba.PixOffset(bounds.Min.X, y)
+			h.Write(rgba.Pix[i : i+4*bounds.Dx()])
+		}
+		return h.Sum64()
+	}
+	var px [4]byte
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			px = [4]byte{byte(r >> 8), byte(g >> 8), byte(b >> 8), byte(a >> 8)}
+			h.Write(px[:])
+		}
+	}
+	return h.Sum64()
+}
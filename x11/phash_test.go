@@ -0,0 +1,100 @@
+package x11
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// gradient returns an image getting brighter to the right, with a dark
+// block at the given position
+func gradient(w, h int, block image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / w)
+			if (image.Point{x, y}).In(block) {
+				v = 0
+			}
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	base := gradient(320, 240, image.Rectangle{})
+	hash := PerceptualHash(base)
+	if hash != 0 {
+		// Every cell is darker than its right neighbour
+		t.Errorf("PerceptualHash(gradient) = %016x, want 0", hash)
+	}
+
+	// A few noisy pixels don't change the hash
+	noisy := gradient(320, 240, image.Rectangle{})
+	for i := 0; i < 20; i++ {
+		noisy.SetRGBA(i*13, i*11, color.RGBA{255, 0, 0, 255})
+	}
+	if d := HashDistance(hash, PerceptualHash(noisy)); d > 1 {
+		t.Errorf("noise changed %d bits", d)
+	}
+
+	// A dark window in the middle changes several
+	changed := gradient(320, 240, image.Rect(100, 60, 220, 180))
+	if d := HashDistance(hash, PerceptualHash(changed)); d < 4 {
+		t.Errorf("a new dark block changed only %d bits", d)
+	}
+
+	// Other image types hash like RGBA
+	gray := image.NewGray(base.Rect)
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			gray.Set(x, y, base.At(x, y))
+		}
+	}
+	if got := PerceptualHash(gray); got != hash {
+		t.Errorf("PerceptualHash(gray) = %016x, want %016x", got, hash)
+	}
+
+	if PerceptualHash(image.NewRGBA(image.Rectangle{})) != 0 {
+		t.Error("empty image must hash to 0")
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xff, 0x0f, 4},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		if got := HashDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HashDistance(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	a := gradient(64, 48, image.Rectangle{})
+	b := gradient(64, 48, image.Rectangle{})
+	if ContentHash(a) != ContentHash(b) {
+		t.Error("identical images hash differently")
+	}
+	b.SetRGBA(10, 10, color.RGBA{1, 2, 3, 255})
+	if ContentHash(a) == ContentHash(b) {
+		t.Error("a changed pixel didn't change the hash")
+	}
+	// Only the bounds count, not what lies outside a sub-image
+	sub := a.SubImage(image.Rect(0, 0, 32, 48)).(*image.RGBA)
+	other := gradient(64, 48, image.Rect(40, 0, 64, 48)).SubImage(image.Rect(0, 0, 32, 48))
+	if ContentHash(sub) != ContentHash(other) {
+		t.Error("pixels outside the bounds changed the hash")
+	}
+	if ContentHash(gradient(48, 64, image.Rectangle{})) == ContentHash(gradient(64, 48, image.Rectangle{})) {
+		t.Error("different sizes hash alike")
+	}
+}
@@ -0,0 +1,113 @@
+package x11
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"strings"
+)
+
+// PNG color modes for screenshots
+const (
+	PNGColorsFull    = "full"    // 24-bit RGB, as captured
+	PNGColorsPalette = "palette" // 8-bit palette of the 256 most used colors
+	PNGColorsGray    = "gray"    // 8-bit grayscale
+)
+
+// paletteBits is the precision per channel when counting colors for the
+// palette; 5 bits keep UI colors apart while the histogram stays small
+const paletteBits = 5
+
+// ParsePNGColors validates a PNG color mode, defaulting to full
+func ParsePNGColors(name string) (string, error) {
+	switch mode := strings.ToLower(name); mode {
+	case "":
+		return PNGColorsFull, nil
+	case PNGColorsFull, PNGColorsPalette, PNGColorsGray:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown PNG colors: %s (available: full, palette, gray)", name)
+}
+
+// SetPNGColors changes the color mode used for screenshots
+func (c *Client) SetPNGColors(mode string) {
+	c.pngColors = mode
+}
+
+// reduceColors converts an image to the given color mode before encoding.
+// UI screenshots have few distinct colors, so a palette loses next to
+// nothing and compresses several times better.
+func reduceColors(img image.Image, mode string) image.Image {
+	switch mode {
+	case PNGColorsPalette:
+		return paletted(toRGBA(img))
+	case PNGColorsGray:
+		gray := image.NewGray(img.Bounds())
+		draw.Draw(gray, gray.Rect, img, img.Bounds().Min, draw.Src)
+		return gray
+	}
+	return img
+}
+
+// paletteKey buckets a color by the top paletteBits of each channel
+func paletteKey(r, g, b uint8) int {
+	const shift = 8 - paletteBits
+	return int(r>>shift)<<(2*paletteBits) | int(g>>shift)<<paletteBits | int(b>>shift)
+}
+
+// paletted maps an image to a palette of the mean colors of its 256 most
+// populated buckets; images with at most 256 colors in separate buckets
+// come out unchanged
+func paletted(img *image.RGBA) *image.Paletted {
+	const buckets = 1 << (3 * paletteBits)
+	count := make([]int, buckets)
+	sum := make([][3]int, buckets)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		i := img.PixOffset(bounds.Min.X, y)
+		for x := bounds.Min.X; x < bounds.Max.X; x, i = x+1, i+4 {
+			r, g, b := img.Pix[i], img.Pix[i+1], img.Pix[i+2]
+			key := paletteKey(r, g, b)
+			count[key]++
+			sum[key][0] += int(r)
+			sum[key][1] += int(g)
+			sum[key][2] += int(b)
+		}
+	}
+
+	var used []int
+	for key, n := range count {
+		if n > 0 {
+			used = append(used, key)
+		}
+	}
+	popular := append([]int(nil), used...)
+	sort.SliceStable(popular, func(i, j int) bool { return count[popular[i]] > count[popular[j]] })
+	popular = popular[:min(len(popular), 256)]
+
+	pal := make(color.Palette, len(popular))
+	for i, key := range popular {
+		n := count[key]
+		pal[i] = color.RGBA{uint8(sum[key][0] / n), uint8(sum[key][1] / n), uint8(sum[key][2] / n), 0xff}
+	}
+
+	// Every used bucket maps to its nearest palette entry
+	lut := make([]uint8, buckets)
+	for _, key := range used {
+		n := count[key]
+		mean := color.RGBA{uint8(sum[key][0] / n), uint8(sum[key][1] / n), uint8(sum[key][2] / n), 0xff}
+		lut[key] = uint8(pal.Index(mean))
+	}
+
+	out := image.NewPaletted(bounds, pal)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		i := img.PixOffset(bounds.Min.X, y)
+		o := out.PixOffset(bounds.Min.X, y)
+		for x := bounds.Min.X; x < bounds.Max.X; x, i, o = x+1, i+4, o+1 {
+			out.Pix[o] = lut[paletteKey(img.Pix[i], img.Pix[i+1], img.Pix[i+2])]
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package x11
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestParsePNGColors(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", PNGColorsFull, false},
+		{"full", PNGColorsFull, false},
+		{"Palette", PNGColorsPalette, false},
+		{"gray", PNGColorsGray, false},
+		{"16bit", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePNGColors(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePNGColors(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPalettedKeepsFewColors(t *testing.T) {
+	// A UI-like image with a handful of flat colors survives unchanged
+	colors := []color.RGBA{{255, 255, 255, 255}, {30, 30, 30, 255}, {52, 101, 164, 255}, {204, 0, 0, 255}}
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetRGBA(x, y, colors[(x/16+y/16)%len(colors)])
+		}
+	}
+	out := paletted(img)
+	if len(out.Palette) != len(colors) {
+		t.Fatalf("palette has %d colors, want %d", len(out.Palette), len(colors))
+	}
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if got := color.RGBAModel.Convert(out.At(x, y)); got != img.At(x, y) {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, img.At(x, y))
+			}
+		}
+	}
+}
+
+func TestPalettedLimitsColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	out := paletted(img)
+	if len(out.Palette) != 256 {
+		t.Errorf("palette has %d colors, want 256", len(out.Palette))
+	}
+	if out.Bounds() != img.Bounds() {
+		t.Errorf("bounds = %v, want %v", out.Bounds(), img.Bounds())
+	}
+}
+
+func TestReduceColorsEncoding(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	tests := []struct {
+		mode  string
+		model color.Model
+	}{
+		{PNGColorsFull, color.RGBAModel},
+		{PNGColorsGray, color.GrayModel},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, reduceColors(img, tt.mode)); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ColorModel() != tt.model {
+			t.Errorf("%s: decoded color model %v, want %v", tt.mode, decoded.ColorModel(), tt.model)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, reduceColors(img, PNGColorsPalette)); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*image.Paletted); !ok {
+		t.Errorf("palette mode decoded as %T, want *image.Paletted", decoded)
+	}
+}
@@ -0,0 +1,68 @@
+package x11
+
+import (
+	"image"
+	"sync"
+)
+
+// maxPooledFrames bounds how many idle frames the pool keeps around
+const maxPooledFrames = 4
+
+// framePool recycles RGBA frames between captures so repeated screenshots
+// don't allocate (and later collect) a full-screen buffer every time
+type framePool struct {
+	mu   sync.Mutex
+	free []*image.RGBA
+}
+
+// get returns a frame of the given size, reusing a pooled buffer if one is
+// large enough. The pixel contents are undefined.
+func (p *framePool) get(width, height int) *image.RGBA {
+	size := 4 * width * height
+
+	p.mu.Lock()
+	for i, img := range p.free {
+		if cap(img.Pix) >= size {
+			p.free = append(p.free[:i], p.free[i+1:]...)
+			p.mu.Unlock()
+
+			img.Pix = img.Pix[:size]
+			img.Stride = 4 * width
+			img.Rect = image.Rect(0, 0, width, height)
+			return img
+		}
+	}
+	p.mu.Unlock()
+
+	return image.NewRGBA(image.Rect(0, 0, width, height))
+}
+
+// put hands a frame back to the pool. The caller must not use it afterwards.
+func (p *framePool) put(img *image.RGBA) {
+	if img == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.free) < maxPooledFrames {
+		p.free = append(p.free, img)
+		return
+	}
+	// Keep the biggest buffers, they can serve any smaller capture
+	for i, old := range p.free {
+		if cap(old.Pix) < cap(img.Pix) {
+			p.free[i] = img
+			return
+		}
+	}
+}
+
+// RecycleScreenshot returns an image obtained from Screenshot to the capture
+// pool so its buffer can be reused. The image must not be used afterwards.
+func (c *Client) RecycleScreenshot(img image.Image) {
+	if rgba, ok := img.(*image.RGBA); ok {
+		c.frames.put(rgba)
+	}
+}
@@ -0,0 +1,71 @@
+package x11
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFramePoolReuse(t *testing.T) {
+	var pool framePool
+
+	first := pool.get(100, 50)
+	if first.Bounds() != image.Rect(0, 0, 100, 50) {
+		t.Fatalf("unexpected bounds %v", first.Bounds())
+	}
+	pool.put(first)
+
+	// A smaller frame can reuse the larger buffer
+	second := pool.get(40, 30)
+	if &second.Pix[0] != &first.Pix[0] {
+		t.Error("expected pooled buffer to be reused")
+	}
+	if second.Bounds() != image.Rect(0, 0, 40, 30) || second.Stride != 160 || len(second.Pix) != 40*30*4 {
+		t.Errorf("reused frame has wrong geometry: bounds=%v stride=%d len=%d",
+			second.Bounds(), second.Stride, len(second.Pix))
+	}
+
+	// Nothing left in the pool, so a new frame is allocated
+	third := pool.get(40, 30)
+	if &third.Pix[0] == &second.Pix[0] {
+		t.Error("expected a fresh buffer while the pooled one is in use")
+	}
+}
+
+func TestFramePoolLimit(t *testing.T) {
+	var pool framePool
+
+	for i := 0; i < maxPooledFrames+2; i++ {
+		pool.put(image.NewRGBA(image.Rect(0, 0, 10+i, 10)))
+	}
+	if len(pool.free) != maxPooledFrames {
+		t.Fatalf("expected %d pooled frames, got %d", maxPooledFrames, len(pool.free))
+	}
+
+	// The largest frame must have displaced a smaller one
+	largest := 4 * (10 + maxPooledFrames + 1) * 10
+	found := false
+	for _, img := range pool.free {
+		if cap(img.Pix) == largest {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("largest frame was not kept in the pool")
+	}
+}
+
+func BenchmarkDecodeZPixmapPooled(b *testing.B) {
+	const width, height = 1920, 1080
+	data := make([]byte, width*height*4)
+	format := pixelFormat{bitsPerPixel: 32, scanlinePad: 32, redMask: 0xff0000, greenMask: 0xff00, blueMask: 0xff}
+	var pool framePool
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		img := pool.get(width, height)
+		if err := decodeZPixmapInto(img, data, format); err != nil {
+			b.Fatal(err)
+		}
+		pool.put(img)
+	}
+}
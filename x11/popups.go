@@ -0,0 +1,111 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"slices"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// popupSettle is how long a popup gets to draw itself before it is captured
+const popupSettle = 300 * time.Millisecond
+
+// Popup is a transient or dialog window that was mapped while watching
+type Popup struct {
+	Window       x.Window // Application window of the popup
+	Frame        x.Window // Top-level window, the WM frame under reparenting WMs
+	Title        string
+	Class        string
+	TransientFor x.Window    // Window the popup belongs to, 0 if not set
+	Dialog       bool        // _NET_WM_WINDOW_TYPE includes _NET_WM_WINDOW_TYPE_DIALOG
+	Time         time.Time   // When the popup was mapped
+	Image        image.Image // Capture of the popup in screen coordinates, if requested
+}
+
+// WatchPopups calls fn for every newly mapped top-level window that is
+// transient for another window (WM_TRANSIENT_FOR) or typed as a dialog
+// (_NET_WM_WINDOW_TYPE_DIALOG), such as an unexpected "Restore session?"
+// prompt. Menus and tooltips, which bypass the window manager, are ignored.
+// With capture set, the popup is captured after a short settle delay. fn
+// runs on its own goroutine.
+func (c *Client) WatchPopups(capture bool, fn func(Popup)) error {
+	if err := c.selectRootEvents(context.Background(), x.EventMaskSubstructureNotify); err != nil {
+		return err
+	}
+
+	c.addEventHandler(func(ev x.GenericEvent) {
+		if ev.GetEventCode() != x.MapNotifyEventCode {
+			return
+		}
+		mev, err := x.NewMapNotifyEvent(ev)
+		if err != nil || mev.Event != c.root || mev.OverrideRedirect {
+			return
+		}
+		// Inspecting the window needs replies, which must not block the event loop
+		go func(frame x.Window, mapped time.Time) {
+			ctx := context.Background()
+			popup, ok := c.inspectPopup(ctx, frame)
+			if !ok {
+				return
+			}
+			popup.Time = mapped
+			if capture {
+				time.Sleep(popupSettle)
+				img, err := c.captureWindow(ctx, frame)
+				if err != nil {
+					slog.Warn("failed to capture popup", "window", fmt.Sprintf("0x%x", uint32(popup.Window)), "err", err)
+				} else {
+					// Place the image at the popup's screen position. It is not
+					// returned to the frame pool, the popup keeps it.
+					if rect, err := c.windowRect(ctx, frame); err == nil {
+						screen := image.Rect(0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+						min := rect.Intersect(screen).Min
+						img.Rect = image.Rectangle{Min: min, Max: min.Add(img.Rect.Size())}
+					}
+					popup.Image = img
+				}
+			}
+			fn(popup)
+		}(mev.Window, time.Now())
+	})
+	return nil
+}
+
+// inspectPopup reports whether a newly mapped top-level window is a popup
+func (c *Client) inspectPopup(ctx context.Context, frame x.Window) (Popup, bool) {
+	win := c.clientWindow(ctx, frame)
+	popup := Popup{Window: win, Frame: frame}
+
+	if owner, ok := c.getUint32Property(ctx, win, x.AtomWMTransientFor, x.AtomWindow); ok && owner != 0 {
+		popup.TransientFor = x.Window(owner)
+	}
+	types := c.getUint32Values(ctx, win, c.getAtom(ctx, "_NET_WM_WINDOW_TYPE"), x.AtomAtom, 16)
+	popup.Dialog = slices.Contains(types, uint32(c.getAtom(ctx, "_NET_WM_WINDOW_TYPE_DIALOG")))
+
+	if popup.TransientFor == 0 && !popup.Dialog {
+		return Popup{}, false
+	}
+	popup.Title = c.getWindowName(ctx, win)
+	popup.Class = c.getWindowClass(ctx, win)
+	return popup, true
+}
+
+// String describes the popup for tool results and logs
+func (p Popup) String() string {
+	kind := "Transient window"
+	if p.Dialog {
+		kind = "Dialog"
+	}
+	s := fmt.Sprintf("%s %q (window 0x%x", kind, p.Title, uint32(p.Window))
+	if p.Class != "" {
+		s += ", class " + p.Class
+	}
+	if p.TransientFor != 0 {
+		s += fmt.Sprintf(", transient for 0x%x", uint32(p.TransientFor))
+	}
+	return s + ")"
+}
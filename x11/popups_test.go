@@ -0,0 +1,82 @@
+package x11
+
+import (
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestPopupString(t *testing.T) {
+	tests := []struct {
+		popup Popup
+		want  string
+	}{
+		{
+			Popup{Window: 0x400001, Title: "Restore session?", Class: "firefox", Dialog: true},
+			`Dialog "Restore session?" (window 0x400001, class firefox)`,
+		},
+		{
+			Popup{Window: 0x400002, Title: "Save as", TransientFor: 0x400000},
+			`Transient window "Save as" (window 0x400002, transient for 0x400000)`,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.popup.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestWatchPopups(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	seen := make(chan Popup, 4)
+	if err := client.WatchPopups(true, func(p Popup) { seen <- p }); err != nil {
+		t.Fatalf("WatchPopups failed: %v", err)
+	}
+
+	newWindow := func() x.Window {
+		xid, err := client.conn.AllocID()
+		if err != nil {
+			t.Fatalf("AllocID failed: %v", err)
+		}
+		win := x.Window(xid)
+		err = x.CreateWindowChecked(client.conn, 0, win, client.root, 100, 100, 200, 100, 0,
+			x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+		if err != nil {
+			t.Fatalf("CreateWindow failed: %v", err)
+		}
+		return win
+	}
+
+	// A plain window is not reported
+	plain := newWindow()
+	x.MapWindow(client.conn, plain)
+
+	dialog := newWindow()
+	x.ChangeProperty(client.conn, x.PropModeReplace, dialog, x.AtomWMTransientFor, x.AtomWindow, 32,
+		[]byte{byte(plain), byte(plain >> 8), byte(plain >> 16), byte(plain >> 24)})
+	x.MapWindow(client.conn, dialog)
+	client.conn.Flush()
+
+	select {
+	case p := <-seen:
+		if p.Window != dialog || p.TransientFor != plain {
+			t.Errorf("popup = %+v, want window %d transient for %d", p, dialog, plain)
+		}
+		if p.Image == nil || p.Image.Bounds().Min.X != 100 {
+			t.Errorf("expected a capture at x=100, got %v", p.Image)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("popup was not reported")
+	}
+}
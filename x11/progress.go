@@ -0,0 +1,175 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+)
+
+// minProgressContrast is the smallest distance between the fill and track
+// colors, as a fraction of the largest possible RGB distance, for a reading
+// to count as confident
+const minProgressContrast = 0.08
+
+// ProgressReading is the estimated state of a progress bar
+type ProgressReading struct {
+	Percent     float64 // Share of the bar that is filled, 0 to 100
+	Orientation string  // "horizontal" (filling left to right) or "vertical" (bottom to top)
+	Fill        color.RGBA
+	Track       color.RGBA
+	Contrast    float64 // Distance between fill and track colors, 0 to 1
+	Confident   bool    // Fill and track were told apart; otherwise the bar is empty or full and Percent is 0
+}
+
+// ReadProgressBar captures rect and estimates how far the progress bar in
+// it has filled. Orientation is "horizontal", "vertical" or "" to pick by the
+// region's shape.
+func (c *Client) ReadProgressBar(ctx context.Context, rect image.Rectangle, orientation string) (ProgressReading, error) {
+	screen := image.Rect(0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+	rect = rect.Canon().Intersect(screen)
+	if rect.Empty() {
+		return ProgressReading{}, fmt.Errorf("region is outside the screen")
+	}
+	img, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return ProgressReading{}, fmt.Errorf("failed to capture region: %w", err)
+	}
+	defer c.frames.put(img)
+	return ReadProgress(img, orientation)
+}
+
+// ReadProgress estimates the fill of a progress bar that covers img. The
+// bar is reduced to a color profile along its axis, taking the per-channel
+// median across the middle half so a percentage label doesn't disturb it,
+// and the profile is split where it best divides into two flat colors: the
+// fill before the split and the track after it.
+func ReadProgress(img *image.RGBA, orientation string) (ProgressReading, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return ProgressReading{}, fmt.Errorf("empty progress bar region")
+	}
+	switch orientation {
+	case "":
+		orientation = "horizontal"
+		if bounds.Dy() > bounds.Dx() {
+			orientation = "vertical"
+		}
+	case "horizontal", "vertical":
+	default:
+		return ProgressReading{}, fmt.Errorf("unknown orientation: %s (available: horizontal, vertical)", orientation)
+	}
+
+	profile := progressProfile(img, orientation == "vertical")
+	split, fill, track := splitProfile(profile)
+	reading := ProgressReading{
+		Orientation: orientation,
+		Fill:        fill,
+		Track:       track,
+		Contrast:    colorDistance(fill, track),
+	}
+	reading.Confident = reading.Contrast >= minProgressContrast
+	if reading.Confident {
+		reading.Percent = 100 * float64(split) / float64(len(profile))
+	}
+	return reading, nil
+}
+
+// progressProfile returns one color per position along the bar, from where
+// it starts filling: left to right, or bottom to top if vertical
+func progressProfile(img *image.RGBA, vertical bool) []color.RGBA {
+	bounds := img.Bounds()
+	length, across := bounds.Dx(), bounds.Dy()
+	if vertical {
+		length, across = across, length
+	}
+	lo, hi := across/4, max(across*3/4, across/4+1)
+
+	profile := make([]color.RGBA, length)
+	var channels [3][]uint8
+	for i := range profile {
+		for ch := range channels {
+			channels[ch] = channels[ch][:0]
+		}
+		for j := lo; j < hi; j++ {
+			x, y := i, j
+			if vertical {
+				x, y = j, length-1-i
+			}
+			p := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			channels[0] = append(channels[0], p.R)
+			channels[1] = append(channels[1], p.G)
+			channels[2] = append(channels[2], p.B)
+		}
+		for ch := range channels {
+			slices.Sort(channels[ch])
+		}
+		mid := len(channels[0]) / 2
+		profile[i] = color.RGBA{channels[0][mid], channels[1][mid], channels[2][mid], 255}
+	}
+	return profile
+}
+
+// splitProfile finds the split of the profile into two runs that minimizes
+// their summed color variance and returns it with the runs' mean colors. A
+// split at either end means the profile is a single color.
+func splitProfile(profile []color.RGBA) (split int, fill, track color.RGBA) {
+	n := len(profile)
+	// Prefix sums of each channel and of its square
+	sum := make([][3]float64, n+1)
+	sq := make([][3]float64, n+1)
+	for i, p := range profile {
+		v := [3]float64{float64(p.R), float64(p.G), float64(p.B)}
+		for ch := range v {
+			sum[i+1][ch] = sum[i][ch] + v[ch]
+			sq[i+1][ch] = sq[i][ch] + v[ch]*v[ch]
+		}
+	}
+	sse := func(from, to int) float64 {
+		count := float64(to - from)
+		if count == 0 {
+			return 0
+		}
+		total := 0.0
+		for ch := 0; ch < 3; ch++ {
+			s := sum[to][ch] - sum[from][ch]
+			total += sq[to][ch] - sq[from][ch] - s*s/count
+		}
+		return total
+	}
+	mean := func(from, to int) color.RGBA {
+		count := float64(to - from)
+		if count == 0 {
+			return color.RGBA{A: 255}
+		}
+		var v [3]uint8
+		for ch := range v {
+			v[ch] = uint8(math.Round((sum[to][ch] - sum[from][ch]) / count))
+		}
+		return color.RGBA{v[0], v[1], v[2], 255}
+	}
+
+	best, bestErr := 0, sse(0, n)
+	for i := 1; i < n; i++ {
+		if e := sse(0, i) + sse(i, n); e < bestErr {
+			best, bestErr = i, e
+		}
+	}
+	if best == 0 {
+		// A single color, fill and track can't be told apart
+		c := mean(0, n)
+		return 0, c, c
+	}
+	return best, mean(0, best), mean(best, n)
+}
+
+// colorDistance returns the Euclidean RGB distance of two colors, scaled
+// to 0..1
+func colorDistance(a, b color.RGBA) float64 {
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	return math.Sqrt(dr*dr+dg*dg+db*db) / math.Sqrt(3*255*255)
+}
@@ -0,0 +1,72 @@
+package x11
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// progressImage draws a bar of the given size filled to percent, with a
+// dark label across the middle to check it doesn't disturb the reading
+func progressImage(width, height int, percent float64, vertical bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fill := color.RGBA{53, 132, 228, 255}
+	track := color.RGBA{224, 224, 224, 255}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pos, length := float64(x), float64(width)
+			if vertical {
+				pos, length = float64(height-1-y), float64(height)
+			}
+			c := track
+			if pos < length*percent/100 {
+				c = fill
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	// A thin label in the center, like "42%"
+	for x := width/2 - 2; x < width/2+2; x++ {
+		img.SetRGBA(x, height/2, color.RGBA{0, 0, 0, 255})
+	}
+	return img
+}
+
+func TestReadProgress(t *testing.T) {
+	tests := []struct {
+		name        string
+		img         *image.RGBA
+		orientation string
+		want        float64
+		wantOrient  string
+		confident   bool
+	}{
+		{"horizontal 42%", progressImage(200, 16, 42, false), "", 42, "horizontal", true},
+		{"horizontal 90%", progressImage(200, 16, 90, false), "horizontal", 90, "horizontal", true},
+		{"vertical 25%", progressImage(16, 200, 25, true), "", 25, "vertical", true},
+		{"empty", progressImage(200, 16, 0, false), "", 0, "horizontal", false},
+		{"full", progressImage(200, 16, 100, false), "", 0, "horizontal", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading, err := ReadProgress(tt.img, tt.orientation)
+			if err != nil {
+				t.Fatalf("ReadProgress failed: %v", err)
+			}
+			if reading.Confident != tt.confident {
+				t.Errorf("Confident = %v, want %v", reading.Confident, tt.confident)
+			}
+			if math.Abs(reading.Percent-tt.want) > 1 {
+				t.Errorf("Percent = %.1f, want %.1f", reading.Percent, tt.want)
+			}
+			if reading.Orientation != tt.wantOrient {
+				t.Errorf("Orientation = %s, want %s", reading.Orientation, tt.wantOrient)
+			}
+		})
+	}
+
+	if _, err := ReadProgress(progressImage(10, 10, 50, false), "diagonal"); err == nil {
+		t.Error("expected error for unknown orientation")
+	}
+}
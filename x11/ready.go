@@ -0,0 +1,195 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// readyDial bounds each connection attempt of a port probe
+const readyDial = 200 * time.Millisecond
+
+// ReadyProbe describes when a started program is ready. Every check that is
+// set must pass; with none set the program is ready once started.
+type ReadyProbe struct {
+	Window      bool   // A new window is mapped
+	WindowClass string // The new window has this WM_CLASS, matched case-insensitively; implies Window
+	Title       string // Regular expression the title of a mapped window, the new one with Window, matches
+	Text        string // Text on the screen, recognized by OCR and matched case-insensitively
+	Port        int    // TCP port on localhost that accepts connections
+	TimeoutMS   int    // Bound of the checks, 30000 if 0
+}
+
+// OCRFunc recognizes the text in an image, for the text check of ReadyProbe
+type OCRFunc func(ctx context.Context, img image.Image) (string, error)
+
+// Ready is the outcome of StartReady
+type Ready struct {
+	PID     int
+	Window  x.Window      // The window that passed the window or title check, else 0
+	Title   string        // Its title
+	Elapsed time.Duration // Time from starting the program to it being ready
+}
+
+// Empty reports whether the probe checks nothing
+func (p ReadyProbe) Empty() bool {
+	return !p.Window && p.WindowClass == "" && p.Title == "" && p.Text == "" && p.Port == 0
+}
+
+// Validate checks the probe's values
+func (p ReadyProbe) Validate() error {
+	if p.TimeoutMS < 0 {
+		return fmt.Errorf("timeout_ms cannot be negative")
+	}
+	if p.Port < 0 || p.Port > 65535 {
+		return fmt.Errorf("invalid port %d", p.Port)
+	}
+	if _, err := regexp.Compile(p.Title); err != nil {
+		return fmt.Errorf("invalid title pattern: %w", err)
+	}
+	return nil
+}
+
+// newWindow reports whether the probe waits for a new window
+func (p ReadyProbe) newWindow() bool {
+	return p.Window || p.WindowClass != ""
+}
+
+// StartReady runs start, which starts a program and returns its PID, and
+// waits until the program passes the probe's checks. The windows mapped
+// before start don't count as new. ocr is only needed for the text check.
+// A program that fails its checks in time or exits with an error first is
+// an error, which leaves it running.
+func (c *Client) StartReady(ctx context.Context, p ReadyProbe, ocr OCRFunc, start func() (int, error)) (Ready, error) {
+	if err := p.Validate(); err != nil {
+		return Ready{}, err
+	}
+	if p.Text != "" && ocr == nil {
+		return Ready{}, fmt.Errorf("the text check needs text recognition, which is not available")
+	}
+	title := regexp.MustCompile(p.Title)
+	match := func(class string) bool { return p.WindowClass == "" || strings.EqualFold(class, p.WindowClass) }
+	var before map[x.Window]bool
+	if p.newWindow() {
+		var err error
+		if before, err = c.windowSet(ctx, match); err != nil {
+			return Ready{}, err
+		}
+	}
+
+	begin := time.Now()
+	pid, err := start()
+	if err != nil {
+		return Ready{}, err
+	}
+	ready := Ready{PID: pid}
+	timeout := defaultLaunchTimeout
+	if p.TimeoutMS > 0 {
+		timeout = time.Duration(p.TimeoutMS) * time.Millisecond
+	}
+	deadline := begin.Add(timeout)
+	for {
+		var pending []string
+		ready.Window, ready.Title, pending, err = c.probeWindows(ctx, p, title, match, before)
+		if err != nil {
+			return ready, err
+		}
+		if p.Port != 0 && !portOpen(ctx, p.Port) {
+			pending = append(pending, fmt.Sprintf("port %d is not open", p.Port))
+		}
+		// Text recognition takes long, so it waits for the cheap checks
+		if p.Text != "" && len(pending) == 0 {
+			if found, err := c.screenHasText(ctx, p.Text, ocr); err != nil {
+				return ready, err
+			} else if !found {
+				pending = append(pending, fmt.Sprintf("text %q is not on the screen", p.Text))
+			}
+		}
+		if len(pending) == 0 {
+			ready.Elapsed = time.Since(begin)
+			return ready, nil
+		}
+
+		if status, ok := c.AppStatus(pid); ok && status.Exited && status.ExitCode != 0 {
+			return ready, fmt.Errorf("program exited with code %d before it was ready: %s", status.ExitCode, strings.Join(pending, "; "))
+		}
+		if time.Now().After(deadline) {
+			return ready, fmt.Errorf("program not ready within %s: %s", timeout.Round(time.Millisecond), strings.Join(pending, "; "))
+		}
+		if err := c.WaitContext(ctx, int(launchPoll.Milliseconds())); err != nil {
+			return ready, err
+		}
+	}
+}
+
+// probeWindows runs the window and title checks of p, returning the window
+// that passed them and what is still missing
+func (c *Client) probeWindows(ctx context.Context, p ReadyProbe, title *regexp.Regexp, match func(string) bool, before map[x.Window]bool) (x.Window, string, []string, error) {
+	if !p.newWindow() && p.Title == "" {
+		return 0, "", nil, nil
+	}
+	windows, err := c.classWindows(ctx, match)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	var candidate x.Window
+	for _, win := range windows {
+		if before[win] {
+			continue
+		}
+		if candidate == 0 {
+			candidate = win
+		}
+		if p.Title == "" {
+			break
+		}
+		if name := c.getWindowName(ctx, win); title.MatchString(name) {
+			return win, name, nil, nil
+		}
+	}
+
+	switch {
+	case candidate == 0 && p.newWindow():
+		what := "window"
+		if p.WindowClass != "" {
+			what = p.WindowClass + " window"
+		}
+		return 0, "", []string{"no new " + what + " is mapped"}, nil
+	case p.Title != "":
+		return 0, "", []string{fmt.Sprintf("no window title matches %q", p.Title)}, nil
+	}
+	return candidate, c.getWindowName(ctx, candidate), nil, nil
+}
+
+// screenHasText reports whether text is on the screen, recognized by ocr
+func (c *Client) screenHasText(ctx context.Context, text string, ocr OCRFunc) (bool, error) {
+	screen := c.screenBounds()
+	img, err := c.captureRect(ctx, screen.Min.X, screen.Min.Y, screen.Dx(), screen.Dy())
+	if err != nil {
+		return false, err
+	}
+	defer c.RecycleScreenshot(img)
+	recognized, err := ocr(ctx, img)
+	if err != nil {
+		return false, fmt.Errorf("failed to recognize text: %w", err)
+	}
+	return strings.Contains(strings.ToLower(recognized), strings.ToLower(text)), nil
+}
+
+// portOpen reports whether a TCP port on localhost accepts connections
+func portOpen(ctx context.Context, port int) bool {
+	dialer := net.Dialer{Timeout: readyDial}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("localhost", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -0,0 +1,109 @@
+package x11
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestReadyProbeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		probe   ReadyProbe
+		empty   bool
+		wantErr bool
+	}{
+		{"nothing", ReadyProbe{}, true, false},
+		{"timeout only", ReadyProbe{TimeoutMS: 5000}, true, false},
+		{"window class", ReadyProbe{WindowClass: "XTerm"}, false, false},
+		{"title", ReadyProbe{Title: `^Example .* Firefox$`}, false, false},
+		{"bad title", ReadyProbe{Title: `(`}, false, true},
+		{"port", ReadyProbe{Port: 8080}, false, false},
+		{"bad port", ReadyProbe{Port: 70000}, false, true},
+		{"negative timeout", ReadyProbe{Window: true, TimeoutMS: -1}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.probe.Empty(); got != tt.empty {
+				t.Errorf("Empty() = %v, want %v", got, tt.empty)
+			}
+			if err := tt.probe.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPortOpen(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	if !portOpen(context.Background(), port) {
+		t.Errorf("portOpen(%d) = false with a listener", port)
+	}
+	ln.Close()
+	if portOpen(context.Background(), port) {
+		t.Errorf("portOpen(%d) = true after closing the listener", port)
+	}
+}
+
+func TestStartReady(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// A "program" that maps a titled window some time after it started
+	var win x.Window
+	start := func() (int, error) {
+		xid, err := client.conn.AllocID()
+		if err != nil {
+			return 0, err
+		}
+		win = x.Window(xid)
+		err = x.CreateWindowChecked(client.conn, 0, win, client.root, 0, 0, 300, 200, 0,
+			x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+		if err != nil {
+			return 0, err
+		}
+		x.ChangeProperty(client.conn, x.PropModeReplace, win, client.getAtom(ctx, "WM_CLASS"), x.AtomString, 8, []byte("probe\x00Probe\x00"))
+		x.ChangeProperty(client.conn, x.PropModeReplace, win, x.AtomWMName, x.AtomString, 8, []byte("Loading..."))
+		time.AfterFunc(300*time.Millisecond, func() {
+			x.MapWindow(client.conn, win)
+			client.conn.Flush()
+		})
+		time.AfterFunc(600*time.Millisecond, func() {
+			x.ChangeProperty(client.conn, x.PropModeReplace, win, x.AtomWMName, x.AtomString, 8, []byte("Probe - ready"))
+			client.conn.Flush()
+		})
+		return 0, nil
+	}
+	ready, err := client.StartReady(ctx, ReadyProbe{WindowClass: "probe", Title: "ready$"}, nil, start)
+	if err != nil {
+		t.Fatalf("StartReady failed: %v", err)
+	}
+	if ready.Window != win || ready.Title != "Probe - ready" || ready.Elapsed < 500*time.Millisecond {
+		t.Errorf("StartReady = %+v, want window 0x%x once retitled", ready, win)
+	}
+
+	// A port nothing listens on times out, naming the failed check
+	_, err = client.StartReady(ctx, ReadyProbe{Port: 1, TimeoutMS: 300}, nil, func() (int, error) { return 0, nil })
+	if err == nil || !strings.Contains(err.Error(), "port 1 is not open") {
+		t.Errorf("StartReady with a closed port = %v, want a timeout naming the port", err)
+	}
+	if _, err := client.StartReady(ctx, ReadyProbe{Text: "OK"}, nil, start); err == nil {
+		t.Error("StartReady with a text check and no OCR succeeded")
+	}
+}
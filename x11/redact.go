@@ -0,0 +1,129 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// Redaction lists what is blacked out of every capture, so credentials on
+// the desktop never reach a screenshot, a recording or text recognition.
+// The zero value redacts nothing.
+type Redaction struct {
+	Regions []image.Rectangle // Fixed regions in root coordinates
+	Classes []string          // WM_CLASS names whose windows are covered, matched case-insensitively
+}
+
+// Active returns true if the redaction covers anything
+func (r Redaction) Active() bool {
+	return len(r.Regions) > 0 || len(r.Classes) > 0
+}
+
+// String lists the redacted regions and classes, empty if there are none
+func (r Redaction) String() string {
+	var parts []string
+	for _, rect := range r.Regions {
+		parts = append(parts, fmt.Sprintf("%dx%d+%d+%d", rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y))
+	}
+	for _, class := range r.Classes {
+		parts = append(parts, "class "+class)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseRedaction builds a Redaction from X geometries like 400x300+0+0 and
+// window class names
+func ParseRedaction(regions, classes []string) (Redaction, error) {
+	var r Redaction
+	for _, spec := range regions {
+		var w, h, px, py int
+		var rest string
+		if n, _ := fmt.Sscanf(strings.TrimSpace(spec), "%dx%d+%d+%d%s", &w, &h, &px, &py, &rest); n != 4 || w <= 0 || h <= 0 {
+			return Redaction{}, fmt.Errorf("invalid redaction region %q: use WIDTHxHEIGHT+X+Y", spec)
+		}
+		r.Regions = append(r.Regions, image.Rect(px, py, px+w, py+h))
+	}
+	for _, class := range classes {
+		if class = strings.TrimSpace(class); class == "" {
+			return Redaction{}, fmt.Errorf("empty redaction window class")
+		}
+		r.Classes = append(r.Classes, class)
+	}
+	return r, nil
+}
+
+// Redaction returns what captures are redacted of
+func (c *Client) Redaction() Redaction {
+	return c.redaction
+}
+
+// redactAreas returns the areas to black out in root coordinates: the fixed
+// regions and the frames of the mapped top-level windows of the redacted
+// classes
+func (c *Client) redactAreas(ctx context.Context) ([]image.Rectangle, error) {
+	areas := c.redaction.Regions
+	if len(c.redaction.Classes) == 0 {
+		return areas, nil
+	}
+
+	tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+		return x.QueryTree(c.conn, c.root).Reply(c.conn)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows to redact: %w", err)
+	}
+	for _, win := range tree.Children {
+		attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+			return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+		})
+		if err != nil || attrs.MapState != x.MapStateViewable {
+			continue
+		}
+		if !redactedClass(c.getWindowClass(ctx, c.clientWindow(ctx, win)), c.redaction.Classes) {
+			continue
+		}
+		rect, err := c.windowRect(ctx, win)
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate window 0x%x to redact: %w", uint32(win), err)
+		}
+		areas = append(areas, rect)
+	}
+	return areas, nil
+}
+
+// redactedClass reports whether a window class is one of classes
+func redactedClass(class string, classes []string) bool {
+	for _, c := range classes {
+		if class != "" && strings.EqualFold(class, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureRedaction returns the areas to black out of a capture about to be
+// taken, nil if nothing is redacted. Captures look them up before fetching
+// the pixels, so a lookup that fails stops the capture before it starts and
+// the pixels are never older than the window positions.
+func (c *Client) captureRedaction(ctx context.Context) ([]image.Rectangle, error) {
+	if !c.redaction.Active() {
+		return nil, nil
+	}
+	return c.redactAreas(ctx)
+}
+
+// blackOut fills areas, in root coordinates, of img captured at origin
+func blackOut(img *image.RGBA, areas []image.Rectangle, origin image.Point) {
+	black := image.NewUniform(color.RGBA{0, 0, 0, 0xff})
+	for _, area := range areas {
+		area = area.Sub(origin).Add(img.Rect.Min).Intersect(img.Rect)
+		if !area.Empty() {
+			draw.Draw(img, area, black, image.Point{}, draw.Src)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package x11
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestParseRedaction(t *testing.T) {
+	r, err := ParseRedaction([]string{"400x300+0+0", " 100x50+1500+20 "}, []string{"KeePassXC"})
+	if err != nil {
+		t.Fatalf("ParseRedaction failed: %v", err)
+	}
+	want := []image.Rectangle{image.Rect(0, 0, 400, 300), image.Rect(1500, 20, 1600, 70)}
+	if len(r.Regions) != 2 || r.Regions[0] != want[0] || r.Regions[1] != want[1] {
+		t.Errorf("Regions = %v, want %v", r.Regions, want)
+	}
+	if got := r.String(); got != "400x300+0+0, 100x50+1500+20, class KeePassXC" {
+		t.Errorf("String() = %q", got)
+	}
+	if (Redaction{}).Active() {
+		t.Error("zero Redaction is active")
+	}
+
+	for _, bad := range []string{"400x300", "0x10+0+0", "400x300+0+0junk", "0x1e00003"} {
+		if _, err := ParseRedaction([]string{bad}, nil); err == nil {
+			t.Errorf("ParseRedaction(%q): expected error", bad)
+		}
+	}
+	if _, err := ParseRedaction(nil, []string{" "}); err == nil {
+		t.Error("ParseRedaction with an empty class: expected error")
+	}
+}
+
+func TestRedactedClass(t *testing.T) {
+	classes := []string{"KeePassXC", "1Password"}
+	tests := []struct {
+		class string
+		want  bool
+	}{
+		{"keepassxc", true},
+		{"1Password", true},
+		{"Firefox", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := redactedClass(tt.class, classes); got != tt.want {
+			t.Errorf("redactedClass(%q) = %v, want %v", tt.class, got, tt.want)
+		}
+	}
+}
+
+func TestBlackOut(t *testing.T) {
+	// A capture of the region 100x100+50+50 of the screen
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	blackOut(img, []image.Rectangle{image.Rect(0, 0, 60, 60), image.Rect(500, 500, 600, 600)}, image.Pt(50, 50))
+
+	black := color.RGBA{0, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	tests := []struct {
+		p    image.Point
+		want color.RGBA
+	}{
+		{image.Pt(0, 0), black},
+		{image.Pt(9, 9), black},
+		{image.Pt(10, 10), white},
+		{image.Pt(99, 99), white},
+	}
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.p.X, tt.p.Y); got != tt.want {
+			t.Errorf("pixel %v = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RegionChange is a change of a watched region's content
+type RegionChange struct {
+	Time    time.Time
+	Changed float64     // Fraction of the region's pixels that differ from the previous state
+	Image   *image.RGBA // The region after the change
+}
+
+// RegionWatch is a region of the screen polled by WatchRegion
+type RegionWatch struct {
+	Rect     image.Rectangle // Watched region in root coordinates, clipped to the screen
+	Baseline *image.RGBA     // Capture of the region when the watch started
+
+	c      *Client
+	cancel context.CancelFunc
+	signal chan struct{} // Holds a token while a change is pending
+
+	mu      sync.Mutex
+	pending *RegionChange // Latest change not yet returned by Wait
+	changes int
+}
+
+// regionWatches keeps the running watches so Close can stop them
+type regionWatches struct {
+	mu     sync.Mutex
+	active map[*RegionWatch]bool
+	closed bool
+}
+
+// add registers a new watch, failing once the client is closed
+func (r *regionWatches) add(w *RegionWatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return fmt.Errorf("client is closed")
+	}
+	if r.active == nil {
+		r.active = make(map[*RegionWatch]bool)
+	}
+	r.active[w] = true
+	return nil
+}
+
+// remove forgets a stopped watch
+func (r *regionWatches) remove(w *RegionWatch) {
+	r.mu.Lock()
+	delete(r.active, w)
+	r.mu.Unlock()
+}
+
+// stopAll stops every running watch and refuses new ones
+func (r *regionWatches) stopAll() {
+	r.mu.Lock()
+	r.closed = true
+	active := r.active
+	r.active = nil
+	r.mu.Unlock()
+	for w := range active {
+		w.cancel()
+	}
+}
+
+// WatchRegion captures rect every interval and records a change whenever
+// more than threshold (a fraction of the region's pixels) differs from the
+// last recorded state, such as a progress bar advancing or new lines in a
+// build log. fn, if not nil, is called for every change. The watch runs on
+// its own goroutine until Stop is called or the client is closed.
+func (c *Client) WatchRegion(rect image.Rectangle, interval time.Duration, threshold float64, fn func(RegionChange)) (*RegionWatch, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive")
+	}
+	screen := image.Rect(0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+	rect = rect.Canon().Intersect(screen)
+	if rect.Empty() {
+		return nil, fmt.Errorf("region is outside the screen")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	baseline, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to capture region: %w", err)
+	}
+	w := &RegionWatch{Rect: rect, Baseline: baseline, c: c, cancel: cancel, signal: make(chan struct{}, 1)}
+	if err := c.watches.add(w); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := baseline
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			img, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("failed to capture watched region", "region", rect.String(), "err", err)
+				}
+				continue
+			}
+			changed := changedFraction(last, img)
+			if changed <= threshold {
+				c.frames.put(img)
+				continue
+			}
+			// The previous state may still be held by a caller, so it is not pooled
+			last = img
+			change := RegionChange{Time: time.Now(), Changed: changed, Image: img}
+			w.record(change)
+			if fn != nil {
+				fn(change)
+			}
+		}
+	}()
+	return w, nil
+}
+
+// record makes change the pending change and wakes a waiting Wait
+func (w *RegionWatch) record(change RegionChange) {
+	w.mu.Lock()
+	w.pending = &change
+	w.changes++
+	w.mu.Unlock()
+
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Wait returns the latest change not returned before, waiting up to timeout
+// for one. ok is false if the timeout passed without a change. Waits are
+// interrupted by AbortAll.
+func (w *RegionWatch) Wait(ctx context.Context, timeout time.Duration) (change RegionChange, ok bool, err error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		w.mu.Lock()
+		pending := w.pending
+		w.pending = nil
+		w.mu.Unlock()
+		if pending != nil {
+			return *pending, true, nil
+		}
+
+		select {
+		case <-w.signal:
+		case <-timer.C:
+			return RegionChange{}, false, nil
+		case <-w.c.abortChan():
+			return RegionChange{}, false, ErrAborted
+		case <-ctx.Done():
+			return RegionChange{}, false, ctx.Err()
+		}
+	}
+}
+
+// Changes returns how many changes were recorded since the watch started
+func (w *RegionWatch) Changes() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.changes
+}
+
+// Stop ends the watch
+func (w *RegionWatch) Stop() {
+	w.cancel()
+	w.c.watches.remove(w)
+}
+
+// changedFraction returns the fraction of pixels that differ beyond
+// pixelTolerance between two images of the same size
+func changedFraction(a, b *image.RGBA) float64 {
+	bounds := a.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+	changed := 0
+	for y := 0; y < bounds.Dy(); y++ {
+		rowA := a.Pix[y*a.Stride : y*a.Stride+4*bounds.Dx()]
+		rowB := b.Pix[y*b.Stride : y*b.Stride+4*bounds.Dx()]
+		for i := 0; i < len(rowA); i += 4 {
+			if pixelsDiffer(rowA[i:i+4], rowB[i:i+4]) {
+				changed++
+			}
+		}
+	}
+	return float64(changed) / float64(bounds.Dx()*bounds.Dy())
+}
@@ -0,0 +1,94 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestChangedFraction(t *testing.T) {
+	base := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	tests := []struct {
+		name    string
+		changed int // Pixels in the first rows set to white
+		value   uint8
+		want    float64
+	}{
+		{"identical", 0, 255, 0},
+		{"within tolerance", 100, pixelTolerance, 0},
+		{"one row", 10, 255, 0.1},
+		{"all", 100, 255, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(base.Rect)
+			for i := 0; i < tt.changed; i++ {
+				img.Set(i%10, i/10, color.RGBA{tt.value, tt.value, tt.value, 255})
+			}
+			if got := changedFraction(base, img); got != tt.want {
+				t.Errorf("changedFraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegionWatchWait(t *testing.T) {
+	c := &Client{}
+	w := &RegionWatch{c: c, signal: make(chan struct{}, 1), cancel: func() {}}
+
+	// Nothing changed: the wait times out
+	if _, ok, err := w.Wait(context.Background(), 10*time.Millisecond); ok || err != nil {
+		t.Fatalf("Wait without change = %v, %v", ok, err)
+	}
+
+	// A change recorded before the wait is returned at once, and only once
+	w.record(RegionChange{Changed: 0.5})
+	change, ok, err := w.Wait(context.Background(), time.Second)
+	if !ok || err != nil || change.Changed != 0.5 {
+		t.Fatalf("Wait after change = %+v, %v, %v", change, ok, err)
+	}
+	if _, ok, _ := w.Wait(context.Background(), 10*time.Millisecond); ok {
+		t.Error("change returned twice")
+	}
+
+	// A change during the wait wakes it
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w.record(RegionChange{Changed: 0.25})
+	}()
+	if change, ok, _ := w.Wait(context.Background(), time.Second); !ok || change.Changed != 0.25 {
+		t.Errorf("Wait during change = %+v, %v", change, ok)
+	}
+	if w.Changes() != 2 {
+		t.Errorf("Changes() = %d, want 2", w.Changes())
+	}
+
+	// AbortAll interrupts the wait
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.AbortAll()
+	}()
+	if _, _, err := w.Wait(context.Background(), time.Second); !errors.Is(err, ErrAborted) {
+		t.Errorf("expected ErrAborted, got %v", err)
+	}
+}
+
+func TestRegionWatchesStopAll(t *testing.T) {
+	c := &Client{}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &RegionWatch{c: c, cancel: cancel}
+	if err := c.watches.add(w); err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	c.watches.stopAll()
+	if ctx.Err() == nil {
+		t.Error("stopAll left the watch running")
+	}
+	if err := c.watches.add(&RegionWatch{c: c, cancel: func() {}}); err == nil {
+		t.Error("add after stopAll succeeded")
+	}
+	w.Stop()
+}
@@ -0,0 +1,67 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// WindowIdentity is what finds an application window again after its X ID
+// changed, e.g. because the application was restarted
+type WindowIdentity struct {
+	Window x.Window
+	Class  string
+	Title  string // Title when last seen; titles change, so it only breaks ties
+}
+
+// IdentifyWindow returns the identity of win, looking through a window
+// manager frame to the application window
+func (c *Client) IdentifyWindow(ctx context.Context, win x.Window) (WindowIdentity, error) {
+	_, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+		return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+	})
+	if err != nil {
+		return WindowIdentity{}, fmt.Errorf("failed to find window 0x%x: %w", uint32(win), err)
+	}
+	app := c.clientWindow(ctx, win)
+	id := WindowIdentity{Window: app, Class: c.getWindowClass(ctx, app), Title: c.getWindowName(ctx, app)}
+	if id.Class == "" {
+		return WindowIdentity{}, fmt.Errorf("window 0x%x has no WM_CLASS to find it again by", uint32(win))
+	}
+	return id, nil
+}
+
+// ResolveWindow finds the window of id: id.Window while it still exists
+// with the same class, else the mapped window of that class with the same
+// title, else the only mapped window of that class. The identity returned
+// has the window's current ID and title.
+func (c *Client) ResolveWindow(ctx context.Context, id WindowIdentity) (WindowIdentity, error) {
+	if _, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+		return x.GetWindowAttributes(c.conn, id.Window).Reply(c.conn)
+	}); err == nil && c.getWindowClass(ctx, id.Window) == id.Class {
+		id.Title = c.getWindowName(ctx, id.Window)
+		return id, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return id, err
+	}
+
+	windows, err := c.classWindows(ctx, func(class string) bool { return class == id.Class })
+	if err != nil {
+		return id, err
+	}
+	for _, win := range windows {
+		if title := c.getWindowName(ctx, win); title == id.Title {
+			return WindowIdentity{Window: win, Class: id.Class, Title: title}, nil
+		}
+	}
+	switch len(windows) {
+	case 0:
+		return id, fmt.Errorf("window 0x%x is gone and no %s window is open", uint32(id.Window), id.Class)
+	case 1:
+		return WindowIdentity{Window: windows[0], Class: id.Class, Title: c.getWindowName(ctx, windows[0])}, nil
+	default:
+		return id, fmt.Errorf("window 0x%x is gone and %d %s windows are open, none titled %q", uint32(id.Window), len(windows), id.Class, id.Title)
+	}
+}
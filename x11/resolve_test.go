@@ -0,0 +1,71 @@
+package x11
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestResolveWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	newWindow := func(class, title string) x.Window {
+		xid, err := client.conn.AllocID()
+		if err != nil {
+			t.Fatalf("AllocID failed: %v", err)
+		}
+		win := x.Window(xid)
+		err = x.CreateWindowChecked(client.conn, 0, win, client.root, 50, 50, 200, 150, 0,
+			x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+		if err != nil {
+			t.Fatalf("CreateWindow failed: %v", err)
+		}
+		wmClass := []byte("app\x00" + class + "\x00")
+		x.ChangeProperty(client.conn, x.PropModeReplace, win, client.getAtom(ctx, "WM_CLASS"), x.AtomString, 8, wmClass)
+		x.ChangeProperty(client.conn, x.PropModeReplace, win, x.AtomWMName, x.AtomString, 8, []byte(title))
+		x.MapWindow(client.conn, win)
+		client.conn.Flush()
+		return win
+	}
+
+	editor := newWindow("Editor", "notes.txt")
+	id, err := client.IdentifyWindow(ctx, editor)
+	if err != nil {
+		t.Fatalf("IdentifyWindow failed: %v", err)
+	}
+	if id.Window != editor || id.Class != "Editor" || id.Title != "notes.txt" {
+		t.Fatalf("IdentifyWindow = %+v", id)
+	}
+	if got, err := client.ResolveWindow(ctx, id); err != nil || got.Window != editor {
+		t.Errorf("ResolveWindow of a live window = %+v, %v", got, err)
+	}
+
+	// The application restarts with a new window
+	x.DestroyWindow(client.conn, editor)
+	restarted := newWindow("Editor", "untitled")
+	if got, err := client.ResolveWindow(ctx, id); err != nil || got.Window != restarted || got.Title != "untitled" {
+		t.Errorf("ResolveWindow after a restart = %+v, %v, want 0x%x", got, err, restarted)
+	}
+
+	// With two windows of the class only the title tells them apart
+	second := newWindow("Editor", "notes.txt")
+	if got, err := client.ResolveWindow(ctx, id); err != nil || got.Window != second {
+		t.Errorf("ResolveWindow by title = %+v, %v, want 0x%x", got, err, second)
+	}
+	x.DestroyWindow(client.conn, second)
+	newWindow("Editor", "other.txt")
+	if _, err := client.ResolveWindow(ctx, id); err == nil || !strings.Contains(err.Error(), "2 Editor windows") {
+		t.Errorf("ResolveWindow with two untitled candidates = %v, want an ambiguity error", err)
+	}
+}
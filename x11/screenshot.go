@@ -0,0 +1,468 @@
+package x11
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"math/bits"
+	"strings"
+	"sync"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// pixelFormat describes how pixels are laid out in a ZPixmap image
+type pixelFormat struct {
+	bitsPerPixel int
+	scanlinePad  int
+	msbFirst     bool
+	redMask      uint32
+	greenMask    uint32
+	blueMask     uint32
+}
+
+// Screenshot captures the whole screen
+func (c *Client) Screenshot() (image.Image, error) {
+	return c.ScreenshotContext(context.Background())
+}
+
+// ScreenshotContext is like Screenshot but gives up when ctx is done
+func (c *Client) ScreenshotContext(ctx context.Context) (image.Image, error) {
+	return c.captureScreen(ctx)
+}
+
+// ScreenshotFocusedWindow captures only the focused window, clipped to the
+// screen, and returns the window it captured. The image bounds are the
+// captured area in screen coordinates.
+func (c *Client) ScreenshotFocusedWindow() (image.Image, x.Window, error) {
+	return c.ScreenshotFocusedWindowContext(context.Background())
+}
+
+// ScreenshotFocusedWindowContext is like ScreenshotFocusedWindow but gives up
+// when ctx is done
+func (c *Client) ScreenshotFocusedWindowContext(ctx context.Context) (image.Image, x.Window, error) {
+	win, err := c.FocusedWindowContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return nil, 0, err
+	}
+	rect = rect.Intersect(c.screenBounds())
+	if rect.Empty() {
+		return nil, 0, fmt.Errorf("focused window 0x%x is off screen", uint32(win))
+	}
+	img, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return nil, 0, err
+	}
+	img.Rect = rect
+	return img, win, nil
+}
+
+// ScreenshotRegion captures a rectangle of the screen at full resolution,
+// clipped to the screen. The image bounds are the captured area in screen
+// coordinates.
+func (c *Client) ScreenshotRegion(x0, y0, width, height int) (image.Image, error) {
+	return c.ScreenshotRegionContext(context.Background(), x0, y0, width, height)
+}
+
+// ScreenshotRegionContext is like ScreenshotRegion but gives up when ctx is
+// done
+func (c *Client) ScreenshotRegionContext(ctx context.Context, x0, y0, width, height int) (image.Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid region size %dx%d", width, height)
+	}
+	rect := image.Rect(x0, y0, x0+width, y0+height).Intersect(c.screenBounds())
+	if rect.Empty() {
+		return nil, fmt.Errorf("region %dx%d+%d+%d is outside the screen", width, height, x0, y0)
+	}
+	img, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return nil, err
+	}
+	img.Rect = rect
+	return img, nil
+}
+
+// ScreenshotWindow captures the visible area of a window, clipped to the
+// screen. The image bounds are the captured area in screen coordinates.
+// Minimized windows and windows on other workspaces are handled as by
+// EnsureVisible.
+func (c *Client) ScreenshotWindow(win x.Window) (image.Image, error) {
+	return c.ScreenshotWindowContext(context.Background(), win)
+}
+
+// ScreenshotWindowContext is like ScreenshotWindow but gives up when ctx is
+// done
+func (c *Client) ScreenshotWindowContext(ctx context.Context, win x.Window) (image.Image, error) {
+	if err := c.EnsureVisible(ctx, win); err != nil {
+		return nil, err
+	}
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return nil, err
+	}
+	rect = rect.Intersect(c.screenBounds())
+	if rect.Empty() {
+		return nil, fmt.Errorf("window 0x%x is off screen", uint32(win))
+	}
+	img, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return nil, err
+	}
+	img.Rect = rect
+	return img, nil
+}
+
+// ScreenshotPNG captures the whole screen and encodes it as PNG
+func (c *Client) ScreenshotPNG() ([]byte, error) {
+	return c.ScreenshotPNGContext(context.Background())
+}
+
+// ScreenshotPNGContext is like ScreenshotPNG but gives up when ctx is done
+func (c *Client) ScreenshotPNGContext(ctx context.Context) ([]byte, error) {
+	img, err := c.captureScreen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.frames.put(img)
+	return c.encodePNG(img)
+}
+
+// pngBufferPool reuses the PNG encoder's internal buffers between screenshots
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *pngBufferPool) Get() *png.EncoderBuffer {
+	buf, _ := p.pool.Get().(*png.EncoderBuffer)
+	return buf
+}
+
+func (p *pngBufferPool) Put(buf *png.EncoderBuffer) {
+	p.pool.Put(buf)
+}
+
+var encoderBuffers = &pngBufferPool{}
+
+// ParsePNGCompression converts a compression name (fast, default, best, none)
+// into a PNG compression level
+func ParsePNGCompression(name string) (png.CompressionLevel, error) {
+	switch strings.ToLower(name) {
+	case "", "fast":
+		return png.BestSpeed, nil
+	case "default":
+		return png.DefaultCompression, nil
+	case "best":
+		return png.BestCompression, nil
+	case "none":
+		return png.NoCompression, nil
+	}
+	return png.BestSpeed, fmt.Errorf("unknown PNG compression: %s", name)
+}
+
+// SetPNGCompression changes the compression level used for screenshots
+func (c *Client) SetPNGCompression(level png.CompressionLevel) {
+	c.pngLevel = level
+}
+
+// EncodePNG encodes an image as PNG with the client's compression level and
+// color mode
+func (c *Client) EncodePNG(img image.Image) ([]byte, error) {
+	return c.encodePNG(img)
+}
+
+// encodePNG encodes an image as PNG with the client's compression level and
+// color mode
+func (c *Client) encodePNG(img image.Image) ([]byte, error) {
+	return encodeWith(img, c.pngLevel, c.pngColors)
+}
+
+// encodeWith encodes an image as PNG with the given compression level and
+// color mode
+func encodeWith(img image.Image, level png.CompressionLevel, colors string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: level, BufferPool: encoderBuffers}
+	if err := enc.Encode(&buf, reduceColors(img, colors)); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// captureScreen grabs the full root window
+func (c *Client) captureScreen(ctx context.Context) (*image.RGBA, error) {
+	return c.captureRect(ctx, 0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+}
+
+// captureRect grabs a rectangle of the root window in root coordinates.
+// Large areas are fetched in horizontal tiles that fit the server's maximum
+// request length.
+func (c *Client) captureRect(ctx context.Context, x0, y0, width, height int) (*image.RGBA, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid capture size %dx%d", width, height)
+	}
+	if x0 < math.MinInt16 || y0 < math.MinInt16 || x0+width > math.MaxInt16 || y0+height > math.MaxInt16 {
+		return nil, fmt.Errorf("capture area %dx%d+%d+%d exceeds X11 coordinate range", width, height, x0, y0)
+	}
+
+	format, err := c.rootPixelFormat()
+	if err != nil {
+		return nil, err
+	}
+
+	redacted, err := c.captureRedaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	img := c.frames.get(width, height)
+	rows := tileRows(width, height, format, c.maxRequestBytes)
+	var tiles []image.Rectangle
+	for y := 0; y < height; y += rows {
+		tiles = append(tiles, image.Rect(0, y, width, min(y+rows, height)))
+	}
+	if err := c.captureTiles(ctx, img, x0, y0, tiles, format); err != nil {
+		c.frames.put(img)
+		return nil, err
+	}
+	blackOut(img, redacted, image.Pt(x0, y0))
+	return img, nil
+}
+
+// captureTiles fetches tiles of img (in image coordinates, offset by x0/y0 on
+// the root window). All requests are sent before waiting so the round trips
+// overlap. A tile the server refuses is split in half and retried.
+func (c *Client) captureTiles(ctx context.Context, img *image.RGBA, x0, y0 int, tiles []image.Rectangle, format pixelFormat) error {
+	cookies := make([]x.GetImageCookie, len(tiles))
+	for i, tile := range tiles {
+		cookies[i] = x.GetImage(c.conn, x.ImageFormatZPixmap, x.Drawable(c.root),
+			int16(x0+tile.Min.X), int16(y0+tile.Min.Y), uint16(tile.Dx()), uint16(tile.Dy()), 0xffffffff)
+	}
+
+	for i, tile := range tiles {
+		reply, err := await(c, ctx, func() (*x.GetImageReply, error) {
+			return cookies[i].Reply(c.conn)
+		})
+		if err != nil {
+			if !isSizeError(err) || tile.Dy() == 1 {
+				return fmt.Errorf("failed to get image: %w", err)
+			}
+			// Drain the remaining replies before retrying with smaller tiles
+			for _, cookie := range cookies[i+1:] {
+				await(c, ctx, func() (*x.GetImageReply, error) {
+					return cookie.Reply(c.conn)
+				})
+			}
+			mid := tile.Min.Y + tile.Dy()/2
+			split := []image.Rectangle{
+				image.Rect(tile.Min.X, tile.Min.Y, tile.Max.X, mid),
+				image.Rect(tile.Min.X, mid, tile.Max.X, tile.Max.Y),
+			}
+			return c.captureTiles(ctx, img, x0, y0, append(split, tiles[i+1:]...), format)
+		}
+
+		sub := img.SubImage(tile).(*image.RGBA)
+		if err := decodeZPixmapInto(sub, reply.Data, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSizeError reports whether the server refused a request for being too large
+func isSizeError(err error) bool {
+	var xerr *x.Error
+	if !errors.As(err, &xerr) {
+		return false
+	}
+	return xerr.Code == x.AllocErrorCode || xerr.Code == x.LengthErrorCode
+}
+
+// tileRows returns how many rows of a capture fit into one request of at most
+// maxBytes. Zero means no limit is known.
+func tileRows(width, height int, format pixelFormat, maxBytes int) int {
+	if maxBytes <= 0 {
+		return height
+	}
+	stride := (width*format.bitsPerPixel + 7) / 8
+	// Leave room for the reply header
+	rows := (maxBytes - 32) / max(stride, 1)
+	return max(1, min(rows, height))
+}
+
+// captureWindow grabs the visible area of a window from the root window
+func (c *Client) captureWindow(ctx context.Context, win x.Window) (*image.RGBA, error) {
+	rect, err := c.windowRect(ctx, win)
+	if err != nil {
+		return nil, err
+	}
+
+	screenRect := image.Rect(0, 0, int(c.screen.WidthInPixels), int(c.screen.HeightInPixels))
+	rect = rect.Intersect(screenRect)
+	if rect.Empty() {
+		return nil, fmt.Errorf("window %d is outside the screen", win)
+	}
+
+	return c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+}
+
+// rootPixelFormat looks up the pixel layout of the root window's visual
+func (c *Client) rootPixelFormat() (pixelFormat, error) {
+	setup := c.conn.GetSetup()
+	format := pixelFormat{msbFirst: setup.ImageByteOrder == x.ImageOrderMSBFirst}
+
+	for _, f := range setup.PixmapFormats {
+		if f.Depth == c.screen.RootDepth {
+			format.bitsPerPixel = int(f.BitsPerPixel)
+			format.scanlinePad = int(f.ScanlinePad)
+			break
+		}
+	}
+	if format.bitsPerPixel == 0 {
+		return format, fmt.Errorf("no pixmap format for depth %d", c.screen.RootDepth)
+	}
+
+	for _, depth := range c.screen.AllowedDepths {
+		for _, visual := range depth.Visuals {
+			if visual.Id == c.screen.RootVisual {
+				format.redMask = visual.RedMask
+				format.greenMask = visual.GreenMask
+				format.blueMask = visual.BlueMask
+				return format, nil
+			}
+		}
+	}
+	return format, fmt.Errorf("root visual %d not found", c.screen.RootVisual)
+}
+
+// decodeZPixmap converts raw ZPixmap data into a new RGBA image
+func decodeZPixmap(data []byte, width, height int, format pixelFormat) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if err := decodeZPixmapInto(img, data, format); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// decodeZPixmapInto converts raw ZPixmap data into img, which must have the
+// same size as the captured area
+func decodeZPixmapInto(img *image.RGBA, data []byte, format pixelFormat) error {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	bytesPerPixel := format.bitsPerPixel / 8
+	switch format.bitsPerPixel {
+	case 16, 24, 32:
+	default:
+		return fmt.Errorf("unsupported bits per pixel: %d", format.bitsPerPixel)
+	}
+
+	stride := zpixmapStride(width, format)
+	if len(data) < stride*height {
+		return fmt.Errorf("image data too short: got %d bytes, need %d", len(data), stride*height)
+	}
+
+	redShift, redMax := maskShift(format.redMask)
+	greenShift, greenMax := maskShift(format.greenMask)
+	blueShift, blueMax := maskShift(format.blueMask)
+
+	for y := 0; y < height; y++ {
+		row := data[y*stride:]
+		out := img.Pix[y*img.Stride:]
+		for xPos := 0; xPos < width; xPos++ {
+			p := row[xPos*bytesPerPixel : xPos*bytesPerPixel+bytesPerPixel]
+			var pixel uint32
+			if format.msbFirst {
+				for _, b := range p {
+					pixel = pixel<<8 | uint32(b)
+				}
+			} else {
+				for i := len(p) - 1; i >= 0; i-- {
+					pixel = pixel<<8 | uint32(p[i])
+				}
+			}
+
+			o := out[xPos*4 : xPos*4+4]
+			o[0] = scaleChannel(pixel&format.redMask>>redShift, redMax)
+			o[1] = scaleChannel(pixel&format.greenMask>>greenShift, greenMax)
+			o[2] = scaleChannel(pixel&format.blueMask>>blueShift, blueMax)
+			o[3] = 0xff
+		}
+	}
+	return nil
+}
+
+// scaleImage resizes an image to the given size using box filtering
+func scaleImage(src image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sb := src.Bounds()
+	if width <= 0 || height <= 0 || sb.Empty() {
+		return dst
+	}
+
+	for dy := 0; dy < height; dy++ {
+		sy0 := sb.Min.Y + dy*sb.Dy()/height
+		sy1 := sb.Min.Y + (dy+1)*sb.Dy()/height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for dx := 0; dx < width; dx++ {
+			sx0 := sb.Min.X + dx*sb.Dx()/width
+			sx1 := sb.Min.X + (dx+1)*sb.Dx()/width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(dx, dy)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+// fitSize scales width and height down to fit within maxWidth x maxHeight,
+// preserving the aspect ratio
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, height*maxWidth/width)
+	}
+	return max(1, width*maxHeight/height), maxHeight
+}
+
+// maskShift returns the shift and maximum value of a color mask
+func maskShift(mask uint32) (uint, uint32) {
+	if mask == 0 {
+		return 0, 0
+	}
+	shift := uint(bits.TrailingZeros32(mask))
+	return shift, mask >> shift
+}
+
+// scaleChannel scales a channel value with the given maximum to 8 bits
+func scaleChannel(v, maxVal uint32) uint8 {
+	if maxVal == 0 {
+		return 0
+	}
+	if maxVal == 0xff {
+		return uint8(v)
+	}
+	return uint8(v * 0xff / maxVal)
+}
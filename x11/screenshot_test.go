@@ -0,0 +1,267 @@
+package x11
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestDecodeZPixmap(t *testing.T) {
+	// Two BGRX pixels (little-endian 0x00RRGGBB) on one row
+	data := []byte{
+		0x30, 0x20, 0x10, 0x00, // r=0x10 g=0x20 b=0x30
+		0xff, 0x00, 0x80, 0x00, // r=0x80 g=0x00 b=0xff
+	}
+	format := pixelFormat{
+		bitsPerPixel: 32,
+		scanlinePad:  32,
+		redMask:      0xff0000,
+		greenMask:    0x00ff00,
+		blueMask:     0x0000ff,
+	}
+
+	img, err := decodeZPixmap(data, 2, 1, format)
+	if err != nil {
+		t.Fatalf("decodeZPixmap failed: %v", err)
+	}
+
+	want := []color.RGBA{
+		{0x10, 0x20, 0x30, 0xff},
+		{0x80, 0x00, 0xff, 0xff},
+	}
+	for i, w := range want {
+		if got := img.RGBAAt(i, 0); got != w {
+			t.Errorf("pixel %d: expected %v, got %v", i, w, got)
+		}
+	}
+
+	// Short data must be rejected instead of panicking
+	if _, err := decodeZPixmap(data[:4], 2, 1, format); err == nil {
+		t.Error("expected error for short image data")
+	}
+
+	format.bitsPerPixel = 8
+	if _, err := decodeZPixmap(data, 2, 1, format); err == nil {
+		t.Error("expected error for unsupported bits per pixel")
+	}
+}
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		w, h, maxW, maxH int
+		wantW, wantH     int
+	}{
+		{100, 50, 200, 200, 100, 50},
+		{1920, 1080, 320, 240, 320, 180},
+		{1080, 1920, 320, 240, 135, 240},
+		{4000, 1, 100, 100, 100, 1},
+	}
+
+	for _, tt := range tests {
+		w, h := fitSize(tt.w, tt.h, tt.maxW, tt.maxH)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("fitSize(%d, %d, %d, %d) = %dx%d, expected %dx%d",
+				tt.w, tt.h, tt.maxW, tt.maxH, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				src.SetRGBA(x, y, color.RGBA{0xff, 0, 0, 0xff})
+			} else {
+				src.SetRGBA(x, y, color.RGBA{0, 0, 0xff, 0xff})
+			}
+		}
+	}
+
+	dst := scaleImage(src, 2, 2)
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{0xff, 0, 0, 0xff}) {
+		t.Errorf("left pixel: expected red, got %v", got)
+	}
+	if got := dst.RGBAAt(1, 1); got != (color.RGBA{0, 0, 0xff, 0xff}) {
+		t.Errorf("right pixel: expected blue, got %v", got)
+	}
+}
+
+func TestParsePNGCompression(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    png.CompressionLevel
+		wantErr bool
+	}{
+		{"", png.BestSpeed, false},
+		{"fast", png.BestSpeed, false},
+		{"Default", png.DefaultCompression, false},
+		{"best", png.BestCompression, false},
+		{"none", png.NoCompression, false},
+		{"turbo", png.BestSpeed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePNGCompression(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePNGCompression(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePNGCompression(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodePNGRoundTrip(t *testing.T) {
+	src := testScreen(64, 48)
+	client := &Client{pngLevel: png.BestSpeed}
+
+	data, err := client.encodePNG(src)
+	if err != nil {
+		t.Fatalf("encodePNG failed: %v", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode PNG: %v", err)
+	}
+	for _, p := range []image.Point{{0, 0}, {31, 17}, {63, 47}} {
+		r1, g1, b1, _ := src.At(p.X, p.Y).RGBA()
+		r2, g2, b2, _ := decoded.At(p.X, p.Y).RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 {
+			t.Errorf("pixel %v changed after round trip", p)
+		}
+	}
+}
+
+// testScreen builds an opaque image with gradients and flat areas, roughly
+// like a desktop screenshot
+func testScreen(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{0xee, 0xee, 0xee, 0xff}
+			if y < height/3 {
+				c = color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func benchmarkEncodePNG(b *testing.B, level png.CompressionLevel) {
+	img := testScreen(1920, 1080)
+	client := &Client{pngLevel: level}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := client.encodePNG(img)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.SetBytes(int64(len(data)))
+	}
+}
+
+func BenchmarkEncodePNGFast(b *testing.B)    { benchmarkEncodePNG(b, png.BestSpeed) }
+func BenchmarkEncodePNGDefault(b *testing.B) { benchmarkEncodePNG(b, png.DefaultCompression) }
+
+func TestTileRows(t *testing.T) {
+	format := pixelFormat{bitsPerPixel: 32, scanlinePad: 32}
+	tests := []struct {
+		name          string
+		width, height int
+		maxBytes      int
+		want          int
+	}{
+		{"no limit", 1920, 1080, 0, 1080},
+		{"fits in one request", 1920, 1080, 16 << 20, 1080},
+		{"4K with big requests", 3840, 2160, 16 << 20, 1092},
+		{"core request limit", 3840, 2160, 262140, 17},
+		{"row larger than limit", 100000, 10, 1024, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tileRows(tt.width, tt.height, format, tt.maxBytes); got != tt.want {
+				t.Errorf("tileRows(%d, %d, %d) = %d, want %d", tt.width, tt.height, tt.maxBytes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeZPixmapIntoTile(t *testing.T) {
+	format := pixelFormat{bitsPerPixel: 32, scanlinePad: 32, redMask: 0xff0000, greenMask: 0xff00, blueMask: 0xff}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 3))
+
+	// Decode a one-row tile into the middle row only
+	tile := img.SubImage(image.Rect(0, 1, 2, 2)).(*image.RGBA)
+	data := []byte{0x03, 0x02, 0x01, 0x00, 0x06, 0x05, 0x04, 0x00}
+	if err := decodeZPixmapInto(tile, data, format); err != nil {
+		t.Fatalf("decodeZPixmapInto failed: %v", err)
+	}
+
+	if got := img.RGBAAt(1, 1); got != (color.RGBA{0x04, 0x05, 0x06, 0xff}) {
+		t.Errorf("pixel (1,1) = %v", got)
+	}
+	if got := img.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("row outside the tile was modified: %v", got)
+	}
+}
+
+func TestScreenshotRegionAndWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// Regions are clipped to the screen and keep their screen position
+	img, err := client.ScreenshotRegion(700, 500, 200, 50)
+	if err != nil {
+		t.Fatalf("ScreenshotRegion failed: %v", err)
+	}
+	if got, want := img.Bounds(), image.Rect(700, 500, 800, 550); got != want {
+		t.Errorf("region bounds = %v, want %v", got, want)
+	}
+	if _, err := client.ScreenshotRegion(900, 0, 10, 10); err == nil {
+		t.Error("ScreenshotRegion outside the screen succeeded")
+	}
+	if _, err := client.ScreenshotRegion(0, 0, 0, 10); err == nil {
+		t.Error("ScreenshotRegion with no width succeeded")
+	}
+
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatalf("AllocID failed: %v", err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 100, 50, 200, 100, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("CreateWindow failed: %v", err)
+	}
+	if _, err := client.ScreenshotWindow(win); err == nil {
+		t.Error("ScreenshotWindow of an unmapped window succeeded")
+	}
+	x.MapWindow(client.conn, win)
+	client.conn.Flush()
+	time.Sleep(100 * time.Millisecond)
+	img, err = client.ScreenshotWindow(win)
+	if err != nil {
+		t.Fatalf("ScreenshotWindow failed: %v", err)
+	}
+	if got, want := img.Bounds(), image.Rect(100, 50, 300, 150); got != want {
+		t.Errorf("window bounds = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// maxScrollSteps bounds the wheel clicks of one MouseScroll
+	maxScrollSteps = 100
+	// scrollStepDelay spaces wheel clicks so applications that coalesce or
+	// animate scrolling count each one
+	scrollStepDelay = 20 * time.Millisecond
+)
+
+// scrollButtons maps scroll directions to wheel buttons
+var scrollButtons = map[string]int{
+	"up":    ButtonScrollUp,
+	"down":  ButtonScrollDown,
+	"left":  ButtonScrollLeft,
+	"right": ButtonScrollRight,
+}
+
+// ScrollButton returns the wheel button for a direction: up, down, left or
+// right
+func ScrollButton(direction string) (int, error) {
+	button, ok := scrollButtons[strings.ToLower(strings.TrimSpace(direction))]
+	if !ok {
+		return 0, fmt.Errorf("invalid scroll direction %q: use up, down, left or right", direction)
+	}
+	return button, nil
+}
+
+// MouseScroll turns the mouse wheel amount steps in direction at the
+// current pointer position
+func (c *Client) MouseScroll(direction string, amount int) error {
+	return c.MouseScrollContext(context.Background(), direction, amount)
+}
+
+// MouseScrollContext is like MouseScroll but gives up when ctx is done
+func (c *Client) MouseScrollContext(ctx context.Context, direction string, amount int) error {
+	button, err := ScrollButton(direction)
+	if err != nil {
+		return err
+	}
+	if amount < 1 || amount > maxScrollSteps {
+		return fmt.Errorf("invalid scroll amount %d: must be between 1 and %d", amount, maxScrollSteps)
+	}
+	for i := 0; i < amount; i++ {
+		if i > 0 {
+			if err := c.WaitContext(ctx, int(scrollStepDelay.Milliseconds())); err != nil {
+				return err
+			}
+		}
+		if err := c.MouseClickContext(ctx, button); err != nil {
+			return fmt.Errorf("failed to scroll %s: %w", direction, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package x11
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScrollButton(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"up", ButtonScrollUp, false},
+		{"Down", ButtonScrollDown, false},
+		{" left ", ButtonScrollLeft, false},
+		{"right", ButtonScrollRight, false},
+		{"sideways", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ScrollButton(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ScrollButton(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMouseScroll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.MouseMoveContext(ctx, 400, 300); err != nil {
+		t.Fatalf("MouseMove failed: %v", err)
+	}
+	if err := client.MouseScrollContext(ctx, "down", 3); err != nil {
+		t.Errorf("MouseScroll down failed: %v", err)
+	}
+	for _, amount := range []int{0, maxScrollSteps + 1} {
+		if err := client.MouseScrollContext(ctx, "up", amount); err == nil {
+			t.Errorf("MouseScroll with amount %d should fail", amount)
+		}
+	}
+	if err := client.MouseScrollContext(ctx, "diagonal", 1); err == nil {
+		t.Error("MouseScroll in an unknown direction should fail")
+	}
+}
@@ -0,0 +1,424 @@
+package x11
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+// selectionSettle is how long to wait after releasing the button for the
+// app to claim PRIMARY
+const selectionSettle = 100 * time.Millisecond
+
+// maxSelectionBytes bounds how much of a selection is read
+const maxSelectionBytes = 16 << 20
+
+// ErrSelectionUnreadable is returned by SelectText when the selection was
+// made but its text could not be read back
+var ErrSelectionUnreadable = errors.New("could not read the selection")
+
+// selectionState holds the hidden window used to receive converted selections
+// and to own the selections set by SetSelection
+type selectionState struct {
+	mu     sync.Mutex // Serializes reads, which share the window and property
+	win    x.Window
+	notify chan *x.SelectionNotifyEvent
+
+	ownMu     sync.Mutex
+	owned     map[x.Atom][]byte // UTF-8 text of the selections this client owns
+	secret    map[x.Atom]bool   // Owned selections holding confidential text
+	serving   bool              // The SelectionRequest handler is registered
+	targets   x.Atom
+	utf8      x.Atom
+	textAtom  x.Atom
+	hint      x.Atom        // passwordHint, offered with confidential text
+	clipboard x.Atom        // Only pastes of the clipboard signal pasted
+	pasted    chan struct{} // Signaled when another application fetches the owned clipboard
+}
+
+// ReadSelection returns the text content of an X selection such as
+// "PRIMARY" or "CLIPBOARD"
+func (c *Client) ReadSelection(name string) (string, error) {
+	return c.ReadSelectionContext(context.Background(), name)
+}
+
+// ReadSelectionContext is like ReadSelection but gives up when ctx is done.
+// Without a deadline it waits up to the request timeout for the owner.
+func (c *Client) ReadSelectionContext(ctx context.Context, name string) (string, error) {
+	c.selection.mu.Lock()
+	defer c.selection.mu.Unlock()
+
+	win, err := c.selectionWindow(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	selection := c.getAtom(ctx, name)
+	property := c.getAtom(ctx, "MCP_X11_SELECTION")
+	if selection == 0 || property == 0 {
+		return "", fmt.Errorf("failed to intern selection atoms")
+	}
+
+	// Prefer UTF-8 and fall back to Latin-1 for old owners
+	for _, target := range []x.Atom{c.getAtom(ctx, "UTF8_STRING"), x.AtomString} {
+		if target == 0 {
+			continue
+		}
+		ev, err := c.convertSelection(ctx, win, selection, target, property)
+		if err != nil {
+			return "", err
+		}
+		if ev.Property == x.None {
+			continue
+		}
+		return c.readSelectionProperty(ctx, win, property)
+	}
+	return "", fmt.Errorf("selection %s is empty or not text", name)
+}
+
+// selectionWindow returns the window that receives selection data, creating
+// it on first use. Callers hold c.selection.mu.
+func (c *Client) selectionWindow(ctx context.Context) (x.Window, error) {
+	if c.selection.win != 0 {
+		return c.selection.win, nil
+	}
+
+	xid, err := c.conn.AllocID()
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate window ID: %w", err)
+	}
+	win := x.Window(xid)
+	err = awaitCheck(c, ctx, func() error {
+		return x.CreateWindowChecked(c.conn, 0, win, c.root, -10, -10, 1, 1, 0,
+			x.WindowClassInputOnly, 0, 0, nil).Check(c.conn)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create selection window: %w", err)
+	}
+
+	c.selection.win = win
+	c.selection.notify = make(chan *x.SelectionNotifyEvent, 1)
+	c.addEventHandler(func(ev x.GenericEvent) {
+		if ev.GetEventCode() != x.SelectionNotifyEventCode {
+			return
+		}
+		sev, err := x.NewSelectionNotifyEvent(ev)
+		if err != nil || sev.Requestor != win {
+			return
+		}
+		select {
+		case c.selection.notify <- sev:
+		default:
+		}
+	})
+	return win, nil
+}
+
+// convertSelection asks the selection owner to store the selection as target
+// in property on win and waits for its answer
+func (c *Client) convertSelection(ctx context.Context, win x.Window, selection, target, property x.Atom) (*x.SelectionNotifyEvent, error) {
+	// Drop an answer to an earlier request that timed out
+	select {
+	case <-c.selection.notify:
+	default:
+	}
+
+	err := awaitCheck(c, ctx, func() error {
+		return x.ConvertSelectionChecked(c.conn, win, selection, target, property, x.TimeCurrentTime).Check(c.conn)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request selection: %w", err)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.requestTimeout())
+		defer cancel()
+	}
+	select {
+	case ev := <-c.selection.notify:
+		return ev, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("selection owner did not respond: %w", ctx.Err())
+	}
+}
+
+// readSelectionProperty reads and deletes the converted selection
+func (c *Client) readSelectionProperty(ctx context.Context, win x.Window, property x.Atom) (string, error) {
+	cookie := x.GetProperty(c.conn, true, win, property, x.GetPropertyTypeAny, 0, maxSelectionBytes/4)
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read selection: %w", err)
+	}
+	if incr := c.getAtom(ctx, "INCR"); incr != 0 && reply.Type == incr {
+		return "", fmt.Errorf("selection is too large to transfer at once (INCR is not supported)")
+	}
+	return string(reply.Value), nil
+}
+
+// SetSelection makes this client the owner of an X selection such as
+// "PRIMARY" or "CLIPBOARD" holding text, so the next paste in any
+// application inserts it. The client answers requests for the text until
+// another application takes the selection.
+func (c *Client) SetSelection(name, text string) error {
+	return c.SetSelectionContext(context.Background(), name, text)
+}
+
+// SetSelectionContext is like SetSelection but gives up when ctx is done
+func (c *Client) SetSelectionContext(ctx context.Context, name, text string) error {
+	return c.setSelection(ctx, name, text, false)
+}
+
+// setSelection takes selection name holding text. Secret text is offered
+// with the passwordHint target, which asks clipboard managers not to keep it.
+func (c *Client) setSelection(ctx context.Context, name, text string, secret bool) error {
+	// Answers are sent in one property, which must fit in a request
+	if len(text) > c.maxRequestBytes-64 {
+		return fmt.Errorf("text is too large for the selection (%d bytes, at most %d)", len(text), c.maxRequestBytes-64)
+	}
+
+	c.selection.mu.Lock()
+	win, err := c.selectionWindow(ctx)
+	c.selection.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	selection := c.getAtom(ctx, name)
+	if selection == 0 {
+		return fmt.Errorf("failed to intern selection atom %s", name)
+	}
+
+	c.selection.ownMu.Lock()
+	if !c.selection.serving {
+		// The handler runs on the event loop and can't intern atoms there
+		c.selection.targets = c.getAtom(ctx, "TARGETS")
+		c.selection.utf8 = c.getAtom(ctx, "UTF8_STRING")
+		c.selection.textAtom = c.getAtom(ctx, "TEXT")
+		c.selection.hint = c.getAtom(ctx, passwordHint)
+		c.selection.clipboard = c.getAtom(ctx, SelectionClipboard)
+		c.selection.owned = make(map[x.Atom][]byte)
+		c.selection.secret = make(map[x.Atom]bool)
+		c.selection.pasted = make(chan struct{}, 1)
+		c.selection.serving = true
+		c.addEventHandler(func(ev x.GenericEvent) { c.serveSelection(win, ev) })
+	}
+	c.selection.owned[selection] = []byte(text)
+	c.selection.secret[selection] = secret
+	c.selection.ownMu.Unlock()
+
+	err = awaitCheck(c, ctx, func() error {
+		return x.SetSelectionOwnerChecked(c.conn, win, selection, x.TimeCurrentTime).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to take selection %s: %w", name, err)
+	}
+	owner, err := await(c, ctx, func() (*x.GetSelectionOwnerReply, error) {
+		return x.GetSelectionOwner(c.conn, selection).Reply(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check selection owner: %w", err)
+	}
+	if owner.Owner != win {
+		return fmt.Errorf("another client kept selection %s", name)
+	}
+	return nil
+}
+
+// serveSelection answers requests for the selections owned by win and
+// forgets them when another client takes over. It runs on the event loop, so
+// it only sends requests and never waits for replies.
+func (c *Client) serveSelection(win x.Window, ev x.GenericEvent) {
+	switch ev.GetEventCode() {
+	case x.SelectionClearEventCode:
+		cev, err := x.NewSelectionClearEvent(ev)
+		if err != nil || cev.Owner != win {
+			return
+		}
+		c.selection.ownMu.Lock()
+		delete(c.selection.owned, cev.Selection)
+		delete(c.selection.secret, cev.Selection)
+		c.selection.ownMu.Unlock()
+
+	case x.SelectionRequestEventCode:
+		rev, err := x.NewSelectionRequestEvent(ev)
+		if err != nil || rev.Owner != win {
+			return
+		}
+		c.selection.ownMu.Lock()
+		text, ok := c.selection.owned[rev.Selection]
+		secret := c.selection.secret[rev.Selection] && c.selection.hint != 0
+		s := &c.selection
+		c.selection.ownMu.Unlock()
+
+		// Only another client fetching the clipboard is a paste; reading
+		// back our own selection or PRIMARY is not
+		pasted := rev.Requestor != win && rev.Selection == s.clipboard
+
+		// Obsolete clients leave the property to the owner
+		property := rev.Property
+		if property == x.None {
+			property = rev.Target
+		}
+		switch {
+		case !ok:
+			property = x.None
+		case rev.Target == s.targets:
+			atoms := []x.Atom{s.targets, s.utf8, s.textAtom, x.AtomString}
+			if secret {
+				atoms = append(atoms, s.hint)
+			}
+			// The connection is little-endian
+			data := make([]byte, 4*len(atoms))
+			for i, atom := range atoms {
+				binary.LittleEndian.PutUint32(data[4*i:], uint32(atom))
+			}
+			x.ChangeProperty(c.conn, x.PropModeReplace, rev.Requestor, property, x.AtomAtom, 32, data)
+		case rev.Target == s.utf8 || rev.Target == s.textAtom:
+			x.ChangeProperty(c.conn, x.PropModeReplace, rev.Requestor, property, s.utf8, 8, text)
+			if pasted {
+				s.signalPasted()
+			}
+		case rev.Target == x.AtomString:
+			x.ChangeProperty(c.conn, x.PropModeReplace, rev.Requestor, property, x.AtomString, 8, latin1(text))
+			if pasted {
+				s.signalPasted()
+			}
+		case secret && rev.Target == s.hint:
+			x.ChangeProperty(c.conn, x.PropModeReplace, rev.Requestor, property, s.utf8, 8, []byte("secret"))
+		default:
+			property = x.None
+		}
+
+		w := x.NewWriter()
+		x.WriteSelectionNotifyEvent(w, &x.SelectionNotifyEvent{
+			Time:      rev.Time,
+			Requestor: rev.Requestor,
+			Selection: rev.Selection,
+			Target:    rev.Target,
+			Property:  property,
+		})
+		x.SendEvent(c.conn, false, rev.Requestor, 0, w.Bytes())
+	}
+}
+
+// signalPasted notes that an application fetched the owned clipboard, without
+// blocking the event loop when nobody waits for it
+func (s *selectionState) signalPasted() {
+	select {
+	case s.pasted <- struct{}{}:
+	default:
+	}
+}
+
+// latin1 converts UTF-8 text to Latin-1, replacing characters it can't
+// represent with '?'
+func latin1(text []byte) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range string(text) {
+		if r > 0xff {
+			r = '?'
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+// PastePrimaryAt moves the pointer to (px, py) and middle-clicks, which
+// pastes the PRIMARY selection in X applications such as terminals
+func (c *Client) PastePrimaryAt(ctx context.Context, px, py int) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	if err := c.MouseMoveContext(ctx, px, py); err != nil {
+		return err
+	}
+	return c.MouseClickContext(ctx, 2)
+}
+
+// SelectText selects text by dragging with the left button from one point to
+// another, or by clicking the start and shift-clicking the end, and returns
+// the selected text from the PRIMARY selection. PRIMARY is emptied first,
+// so text this client or an earlier selection left there isn't taken for
+// the new selection. If only the readback fails or finds no text the error
+// wraps ErrSelectionUnreadable.
+func (c *Client) SelectText(fromX, fromY, toX, toY int, shiftClick bool) (string, error) {
+	return c.SelectTextContext(context.Background(), fromX, fromY, toX, toY, shiftClick)
+}
+
+// SelectTextContext is like SelectText but gives up when ctx is done
+func (c *Client) SelectTextContext(ctx context.Context, fromX, fromY, toX, toY int, shiftClick bool) (string, error) {
+	if err := c.checkFrozen(); err != nil {
+		return "", err
+	}
+	if err := c.SetSelectionContext(ctx, SelectionPrimary, ""); err != nil {
+		return "", err
+	}
+	if err := c.MouseMoveContext(ctx, fromX, fromY); err != nil {
+		return "", err
+	}
+
+	if shiftClick {
+		if err := c.MouseClickContext(ctx, 1); err != nil {
+			return "", err
+		}
+		if err := c.MouseMoveContext(ctx, toX, toY); err != nil {
+			return "", err
+		}
+		shift, err := c.keysymToKeycode(ctx, keysyms.XK_Shift_L)
+		if err != nil {
+			return "", err
+		}
+		if err := c.fakeKey(ctx, shift, true); err != nil {
+			return "", err
+		}
+		err = c.MouseClickContext(ctx, 1)
+		if rerr := c.fakeKey(ctx, shift, false); err == nil {
+			err = rerr
+		}
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// The drag steps bypass MouseMove, so clamp its ends the same way
+		fromX, fromY, err := c.confinePoint(ctx, fromX, fromY)
+		if err != nil {
+			return "", err
+		}
+		toX, toY, err := c.confinePoint(ctx, toX, toY)
+		if err != nil {
+			return "", err
+		}
+		if err := c.fakeButton(ctx, 1, true); err != nil {
+			return "", err
+		}
+		// Intermediate motion makes apps see a drag rather than a jump
+		if err := c.fakeMotionPath(ctx, fromX, fromY, toX, toY); err != nil {
+			c.fakeButton(context.Background(), 1, false)
+			return "", err
+		}
+		if err := c.fakeButton(ctx, 1, false); err != nil {
+			return "", err
+		}
+	}
+
+	if err := c.WaitContext(ctx, int(selectionSettle.Milliseconds())); err != nil {
+		return "", err
+	}
+	text, err := c.ReadSelectionContext(ctx, "PRIMARY")
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSelectionUnreadable, err)
+	}
+	if text == "" {
+		return "", fmt.Errorf("%w: the application didn't take the PRIMARY selection", ErrSelectionUnreadable)
+	}
+	return text, nil
+}
@@ -0,0 +1,131 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestReadSelectionWithoutOwner checks that an unowned selection is an error
+func TestReadSelectionWithoutOwner(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.ReadSelection("PRIMARY"); err == nil {
+		t.Error("expected an error for a selection without owner")
+	}
+}
+
+// TestSetSelectionRoundTrip owns PRIMARY and reads the text back through
+// the server
+func TestSetSelectionRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	const text = "ls -la | grep ü"
+	if err := client.SetSelection("PRIMARY", text); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	got, err := client.ReadSelection("PRIMARY")
+	if err != nil {
+		t.Fatalf("ReadSelection failed: %v", err)
+	}
+	if got != text {
+		t.Errorf("read back %q, want %q", got, text)
+	}
+}
+
+func TestLatin1(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"grün", "gr\xfcn"},
+		{"€5", "?5"},
+	}
+	for _, tt := range tests {
+		if got := string(latin1([]byte(tt.in))); got != tt.want {
+			t.Errorf("latin1(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPastePrimaryFrozen(t *testing.T) {
+	client := &Client{}
+	client.SetFrozen(true)
+	if err := client.PastePrimaryAt(context.Background(), 10, 10); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen, got %v", err)
+	}
+}
+
+// TestSelectTextInXterm drags across text printed in an xterm and reads it back
+func TestSelectTextInXterm(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	if _, err := exec.LookPath("xterm"); err != nil {
+		t.Skip("xterm not available")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	pid, err := client.StartApp("xterm", []string{"-geometry", "80x10+0+0", "-e", "echo selectme; sleep 30"})
+	if err != nil {
+		t.Fatalf("Failed to start xterm: %v", err)
+	}
+	defer client.StopApp(pid)
+	client.Wait(1000)
+
+	// Drag across the whole first line
+	text, err := client.SelectText(2, 5, 400, 5, false)
+	if errors.Is(err, ErrSelectionUnreadable) {
+		t.Fatalf("selection was made but not readable: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("SelectText failed: %v", err)
+	}
+	if !strings.Contains(text, "selectme") {
+		t.Errorf("selected %q, want it to contain %q", text, "selectme")
+	}
+}
+
+func TestSelectTextStalePrimary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// Dragging over the bare root takes no selection; the old text mustn't count
+	if err := client.SetSelection(SelectionPrimary, "stale"); err != nil {
+		t.Fatalf("SetSelection failed: %v", err)
+	}
+	text, err := client.SelectText(100, 100, 300, 100, false)
+	if !errors.Is(err, ErrSelectionUnreadable) {
+		t.Errorf("SelectText = %q, %v, want ErrSelectionUnreadable", text, err)
+	}
+}
@@ -0,0 +1,301 @@
+package x11
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+// Input methods of the input tools
+const (
+	InputXTest     = "xtest"     // Fake device input through XTEST, as if typed or clicked
+	InputSendEvent = "sendevent" // Synthetic events sent straight to the target window
+)
+
+// InputMethods are the accepted input methods
+var InputMethods = []string{InputXTest, InputSendEvent}
+
+// ParseInputMethod parses an input method name, defaulting to XTEST
+func ParseInputMethod(method string) (string, error) {
+	if method == "" {
+		return InputXTest, nil
+	}
+	method = strings.ToLower(method)
+	for _, m := range InputMethods {
+		if m == method {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unknown input method: %s (available: %s)", method, strings.Join(InputMethods, ", "))
+}
+
+// inputEvent is a core key or button event as SendEvent delivers it
+type inputEvent struct {
+	code           byte // KeyPress, KeyRelease, ButtonPress or ButtonRelease
+	detail         byte // Keycode or button
+	root, event    x.Window
+	child          x.Window
+	rootX, rootY   int16
+	eventX, eventY int16
+	state          uint16 // Modifier and button mask
+}
+
+// encode returns the 32-byte wire form of the event. The sequence number is
+// filled in by the server and the time is CurrentTime.
+func (ev inputEvent) encode() []byte {
+	b := make([]byte, 32)
+	b[0] = ev.code
+	b[1] = ev.detail
+	binary.LittleEndian.PutUint32(b[8:], uint32(ev.root))
+	binary.LittleEndian.PutUint32(b[12:], uint32(ev.event))
+	binary.LittleEndian.PutUint32(b[16:], uint32(ev.child))
+	binary.LittleEndian.PutUint16(b[20:], uint16(ev.rootX))
+	binary.LittleEndian.PutUint16(b[22:], uint16(ev.rootY))
+	binary.LittleEndian.PutUint16(b[24:], uint16(ev.eventX))
+	binary.LittleEndian.PutUint16(b[26:], uint16(ev.eventY))
+	binary.LittleEndian.PutUint16(b[28:], ev.state)
+	b[30] = 1 // same-screen
+	return b
+}
+
+// buttonMask returns the state mask bit of a held button, 0 beyond button 5
+func buttonMask(button byte) uint16 {
+	if button < 1 || button > 5 {
+		return 0
+	}
+	return x.ButtonMask1 << (button - 1)
+}
+
+// sendInputEvent sends ev to its event window, propagating up to the first
+// ancestor that selected it
+func (c *Client) sendInputEvent(ctx context.Context, ev inputEvent) error {
+	var mask uint32
+	switch ev.code {
+	case KeyPress:
+		mask = x.EventMaskKeyPress
+	case KeyRelease:
+		mask = x.EventMaskKeyRelease
+	case ButtonPress:
+		mask = x.EventMaskButtonPress
+	case ButtonRelease:
+		mask = x.EventMaskButtonRelease
+	}
+	err := awaitCheck(c, ctx, func() error {
+		return x.SendEventChecked(c.conn, true, ev.event, mask, ev.encode()).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send event to window 0x%x: %w", ev.event, err)
+	}
+	return nil
+}
+
+// pointerTarget returns the innermost window under (px, py) with the point
+// in its coordinates, the root window if none covers it
+func (c *Client) pointerTarget(ctx context.Context, px, py int) (x.Window, int16, int16, error) {
+	at, err := c.WindowAtPoint(ctx, px, py)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if at.Deepest == 0 {
+		return c.root, int16(px), int16(py), nil
+	}
+	trans, err := await(c, ctx, func() (*x.TranslateCoordinatesReply, error) {
+		return x.TranslateCoordinates(c.conn, c.root, at.Deepest, int16(px), int16(py)).Reply(c.conn)
+	})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to translate coordinates: %w", err)
+	}
+	return at.Deepest, trans.DstX, trans.DstY, nil
+}
+
+// keyTarget returns the window key events go to: the focus window, or the
+// one under the pointer when focus follows the pointer
+func (c *Client) keyTarget(ctx context.Context) (inputEvent, error) {
+	focus, err := await(c, ctx, func() (*x.GetInputFocusReply, error) {
+		return x.GetInputFocus(c.conn).Reply(c.conn)
+	})
+	if err != nil {
+		return inputEvent{}, fmt.Errorf("failed to get input focus: %w", err)
+	}
+	pointer, err := c.queryPointer(ctx)
+	if err != nil {
+		return inputEvent{}, err
+	}
+	ev := inputEvent{root: c.root, event: focus.Focus, rootX: pointer.RootX, rootY: pointer.RootY}
+	if focus.Focus == x.None || focus.Focus == x.InputFocusPointerRoot || focus.Focus == c.root {
+		win, ex, ey, err := c.pointerTarget(ctx, int(pointer.RootX), int(pointer.RootY))
+		if err != nil {
+			return inputEvent{}, err
+		}
+		ev.event, ev.eventX, ev.eventY = win, ex, ey
+	}
+	return ev, nil
+}
+
+// sendKeysym sends a press and release of the key producing keysym to the
+// target, with the given modifiers and shift if the keysym needs it
+func (c *Client) sendKeysym(ctx context.Context, target inputEvent, keysym x.Keysym, state uint16) error {
+	loc, err := c.keysymLocation(ctx, keysym)
+	if err != nil {
+		return err
+	}
+	if loc.level > 1 {
+		return fmt.Errorf("key needs modifiers other than shift on this layout")
+	}
+	if loc.level == 1 {
+		state |= x.ModMaskShift
+	}
+	target.detail = byte(loc.keycode)
+	target.state = state
+	target.code = KeyPress
+	if err := c.sendInputEvent(ctx, target); err != nil {
+		return err
+	}
+	target.code = KeyRelease
+	return c.sendInputEvent(ctx, target)
+}
+
+// SendKeyPressContext is like KeyPressContext but sends synthetic key events
+// to the focused window instead of faking them with XTEST, for applications
+// or nested servers that don't see XTEST input. Many applications ignore
+// synthetic events, xterm unless its allowSendEvents resource is set.
+func (c *Client) SendKeyPressContext(ctx context.Context, key string) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	keysym, err := c.keyNameToKeysym(key)
+	if err != nil {
+		return err
+	}
+	target, err := c.keyTarget(ctx)
+	if err != nil {
+		return err
+	}
+	return c.sendKeysym(ctx, target, keysym, 0)
+}
+
+// SendKeyComboContext is like KeyComboContext with synthetic events, see
+// SendKeyPressContext. The modifiers are only set in the events' state, as
+// no modifier key is pressed.
+func (c *Client) SendKeyComboContext(ctx context.Context, combo string) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	modifiers, mainKey, err := parseKeyCombo(combo)
+	if err != nil {
+		return err
+	}
+	keysym, err := comboKeysym(mainKey)
+	if err != nil {
+		return err
+	}
+	var state uint16
+	for _, mod := range modifiers {
+		state |= mod.mask
+	}
+	target, err := c.keyTarget(ctx)
+	if err != nil {
+		return err
+	}
+	return c.sendKeysym(ctx, target, keysym, state)
+}
+
+// SendTypeContext is like TypeWithProfileContext with synthetic events, see
+// SendKeyPressContext
+func (c *Client) SendTypeContext(ctx context.Context, text string, profile TypingProfile) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	target, err := c.keyTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	pos := 0
+	for i, ch := range text {
+		pos++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			if err := c.pause(ctx, profile, profile.InterKeyDelay); err != nil {
+				return err
+			}
+		}
+
+		keysym := runeToKeysym(ch)
+		if ch == '\n' {
+			keysym = keysyms.XK_Return
+		} else if _, err := c.keysymLocation(ctx, keysym); err != nil && unicode.IsUpper(ch) {
+			// Fall back to the lowercase key with shift
+			if err := c.sendKeysym(ctx, target, runeToKeysym(unicode.ToLower(ch)), x.ModMaskShift); err != nil {
+				if errors.Is(err, errNoKeycode) {
+					err = errNoKeycode
+				}
+				return &CharError{Pos: pos, Char: ch, Err: err}
+			}
+			continue
+		}
+		if err := c.sendKeysym(ctx, target, keysym, 0); err != nil {
+			if errors.Is(err, errNoKeycode) {
+				err = errNoKeycode
+			}
+			return &CharError{Pos: pos, Char: ch, Err: err}
+		}
+	}
+	return nil
+}
+
+// SendClickAtContext clicks at (px, py) in root coordinates with synthetic
+// button events sent to the innermost window there, see SendKeyPressContext.
+// The pointer is warped there first with the core protocol so hover state
+// matches, which works without XTEST.
+func (c *Client) SendClickAtContext(ctx context.Context, px, py, button int) error {
+	if err := c.checkFrozen(); err != nil {
+		return err
+	}
+	if err := c.ValidateButton(ctx, button); err != nil {
+		return err
+	}
+	if _, _, err := validatePoint(float64(px), float64(py), c.screenBounds(), "screen"); err != nil {
+		return err
+	}
+	px, py, err := c.confinePoint(ctx, px, py)
+	if err != nil {
+		return err
+	}
+
+	err = awaitCheck(c, ctx, func() error {
+		return x.WarpPointerChecked(c.conn, x.None, c.root, 0, 0, 0, 0, int16(px), int16(py)).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to warp pointer to (%d, %d): %w", px, py, err)
+	}
+	c.trail.motion(px, py)
+
+	win, ex, ey, err := c.pointerTarget(ctx, px, py)
+	if err != nil {
+		return err
+	}
+	ev := inputEvent{
+		code: ButtonPress, detail: byte(button),
+		root: c.root, event: win,
+		rootX: int16(px), rootY: int16(py), eventX: ex, eventY: ey,
+	}
+	if err := c.sendInputEvent(ctx, ev); err != nil {
+		return err
+	}
+	c.trail.button(byte(button), true)
+	ev.code, ev.state = ButtonRelease, buttonMask(byte(button))
+	if err := c.sendInputEvent(ctx, ev); err != nil {
+		return err
+	}
+	c.trail.button(byte(button), false)
+	return nil
+}
@@ -0,0 +1,84 @@
+package x11
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestParseInputMethod(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", InputXTest},
+		{"xtest", InputXTest},
+		{"SendEvent", InputSendEvent},
+	}
+	for _, tt := range tests {
+		got, err := ParseInputMethod(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseInputMethod(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseInputMethod("uinput"); err == nil {
+		t.Error("ParseInputMethod(uinput): expected error")
+	}
+}
+
+func TestInputEventEncode(t *testing.T) {
+	ev := inputEvent{
+		code: ButtonRelease, detail: 3,
+		root: 0x123, event: 0x400001, child: 0,
+		rootX: 300, rootY: -2, eventX: 10, eventY: 20,
+		state: buttonMask(3),
+	}
+	want := []byte{
+		ButtonRelease, 3, 0, 0, 0, 0, 0, 0,
+		0x23, 0x01, 0, 0, 0x01, 0, 0x40, 0,
+		0, 0, 0, 0, 0x2c, 0x01, 0xfe, 0xff,
+		10, 0, 20, 0, 0x00, 0x04, 1, 0,
+	}
+	if got := ev.encode(); !bytes.Equal(got, want) {
+		t.Errorf("encode = % x, want % x", got, want)
+	}
+}
+
+func TestButtonMask(t *testing.T) {
+	tests := []struct {
+		button byte
+		want   uint16
+	}{
+		{1, 0x100},
+		{5, 0x1000},
+		{8, 0},
+	}
+	for _, tt := range tests {
+		if got := buttonMask(tt.button); got != tt.want {
+			t.Errorf("buttonMask(%d) = %#x, want %#x", tt.button, got, tt.want)
+		}
+	}
+}
+
+func TestSendClickAt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if err := client.SendClickAtContext(context.Background(), 120, 80, 1); err != nil {
+		t.Fatalf("SendClickAtContext failed: %v", err)
+	}
+	x, y, err := client.PointerPosition()
+	if err != nil {
+		t.Fatalf("PointerPosition failed: %v", err)
+	}
+	if x != 120 || y != 80 {
+		t.Errorf("pointer at (%d, %d), want (120, 80)", x, y)
+	}
+}
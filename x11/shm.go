@@ -0,0 +1,96 @@
+//go:build linux && (amd64 || arm64)
+
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"syscall"
+	"unsafe"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/shm"
+)
+
+// System V IPC constants (not exported by package syscall)
+const (
+	ipcPrivate = 0
+	ipcCreat   = 01000
+	ipcRmid    = 0
+)
+
+// shmSegment is a System V shared memory segment attached to the X server
+type shmSegment struct {
+	seg  shm.Seg
+	data []byte
+}
+
+// newSHMSegment creates a shared memory segment of the given size and
+// attaches it to both this process and the X server
+func (c *Client) newSHMSegment(ctx context.Context, size int) (*shmSegment, error) {
+	if ext := c.conn.GetExtensionData(shm.Ext()); ext == nil || !ext.Present {
+		return nil, fmt.Errorf("MIT-SHM extension not present")
+	}
+
+	id, _, errno := syscall.Syscall(syscall.SYS_SHMGET, ipcPrivate, uintptr(size), ipcCreat|0600)
+	if errno != 0 {
+		return nil, fmt.Errorf("failed to create shared memory segment: %w", errno)
+	}
+	// Mark for removal right away: the kernel frees it once both sides detach
+	defer syscall.Syscall(syscall.SYS_SHMCTL, id, ipcRmid, 0)
+
+	addr, _, errno := syscall.Syscall(syscall.SYS_SHMAT, id, 0, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("failed to attach shared memory segment: %w", errno)
+	}
+	// Convert without a uintptr->Pointer cast; the mapping isn't Go-managed memory
+	data := unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size)
+
+	xid, err := c.conn.AllocID()
+	if err != nil {
+		syscall.Syscall(syscall.SYS_SHMDT, addr, 0, 0)
+		return nil, fmt.Errorf("failed to allocate shm segment id: %w", err)
+	}
+	seg := shm.Seg(xid)
+	err = awaitCheck(c, ctx, func() error {
+		return shm.AttachChecked(c.conn, seg, uint32(id), false).Check(c.conn)
+	})
+	if err != nil {
+		syscall.Syscall(syscall.SYS_SHMDT, addr, 0, 0)
+		return nil, fmt.Errorf("failed to attach shared memory to X server: %w", err)
+	}
+
+	return &shmSegment{seg: seg, data: data}, nil
+}
+
+// close detaches the segment from the X server and this process
+func (s *shmSegment) close(c *Client) {
+	shm.Detach(c.conn, s.seg)
+	c.conn.Flush()
+	syscall.Syscall(syscall.SYS_SHMDT, uintptr(unsafe.Pointer(&s.data[0])), 0, 0)
+}
+
+// captureSHM grabs the full screen through the shared memory segment, which
+// avoids copying the image through the X socket
+func (c *Client) captureSHM(ctx context.Context, seg *shmSegment, img *image.RGBA) error {
+	format, err := c.rootPixelFormat()
+	if err != nil {
+		return err
+	}
+
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	cookie := shm.GetImage(c.conn, x.Drawable(c.root), 0, 0, uint16(width), uint16(height),
+		0xffffffff, x.ImageFormatZPixmap, seg.seg, 0)
+	reply, err := await(c, ctx, func() (*shm.GetImageReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get shm image: %w", err)
+	}
+	if int(reply.Size) > len(seg.data) {
+		return fmt.Errorf("shm image larger than segment: %d > %d", reply.Size, len(seg.data))
+	}
+
+	return decodeZPixmapInto(img, seg.data[:reply.Size], format)
+}
@@ -0,0 +1,23 @@
+//go:build !linux || !(amd64 || arm64)
+
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+)
+
+// shmSegment is unavailable on this platform
+type shmSegment struct{}
+
+// newSHMSegment always fails; captures fall back to GetImage
+func (c *Client) newSHMSegment(ctx context.Context, size int) (*shmSegment, error) {
+	return nil, fmt.Errorf("shared memory capture not supported on this platform")
+}
+
+func (s *shmSegment) close(c *Client) {}
+
+func (c *Client) captureSHM(ctx context.Context, seg *shmSegment, img *image.RGBA) error {
+	return fmt.Errorf("shared memory capture not supported on this platform")
+}
@@ -0,0 +1,259 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxStreamFPS bounds the frame rate a subscriber can ask for
+const maxStreamFPS = 60
+
+// Frame is one screen capture delivered by StreamFrames. The image is shared
+// by every subscriber and must not be modified.
+type Frame struct {
+	Image   *image.RGBA
+	Seq     uint64       // Increases for every newly captured frame
+	Time    time.Time    // When the frame was captured
+	Changed bool         // False if the screen was not damaged since the previous frame
+	Trail   []TrailEvent // Injected pointer events of the TrailAge before delivery, see Annotated
+}
+
+// frameSub is one StreamFrames subscriber
+type frameSub struct {
+	ch       chan Frame
+	interval time.Duration
+	next     time.Time
+}
+
+// frameHub runs a single capture loop and fans frames out to all subscribers
+type frameHub struct {
+	mu      sync.Mutex
+	subs    map[*frameSub]struct{}
+	running bool
+	wake    chan struct{} // Signals the loop that the subscriber set changed
+	dirty   bool          // Damage seen since the last capture
+	tracked bool          // Whether damage events are available
+	last    Frame
+
+	// capture grabs a new screen image; set by the client, replaced in tests
+	capture func(ctx context.Context) (*image.RGBA, error)
+	// trail returns the recent pointer events; set by the client
+	trail func(now time.Time) []TrailEvent
+}
+
+// StreamFrames delivers screen captures at up to fps frames per second until
+// ctx is done, then closes the channel. All streams share one capture loop
+// that uses MIT-SHM when available and skips capturing while DAMAGE reports
+// no changes. Slow receivers miss frames instead of stalling the loop.
+func (c *Client) StreamFrames(ctx context.Context, fps int) (<-chan Frame, error) {
+	if fps <= 0 || fps > maxStreamFPS {
+		return nil, fmt.Errorf("fps must be between 1 and %d", maxStreamFPS)
+	}
+
+	c.stream.mu.Lock()
+	if c.stream.capture == nil {
+		c.stream.capture = c.streamCapture()
+		c.stream.trail = c.trail.since
+		c.stream.tracked = c.WatchDamage(func(image.Rectangle) { c.stream.markDirty() }) == nil
+	}
+	c.stream.mu.Unlock()
+
+	return c.stream.subscribe(ctx, fps), nil
+}
+
+// subscribe adds a subscriber and starts the capture loop if needed
+func (h *frameHub) subscribe(ctx context.Context, fps int) <-chan Frame {
+	sub := &frameSub{
+		ch:       make(chan Frame, 1),
+		interval: time.Second / time.Duration(fps),
+	}
+
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[*frameSub]struct{})
+		h.wake = make(chan struct{}, 1)
+	}
+	h.subs[sub] = struct{}{}
+	start := !h.running
+	h.running = true
+	h.mu.Unlock()
+
+	if start {
+		go h.loop()
+	}
+	h.poke()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs, sub)
+		close(sub.ch)
+		h.mu.Unlock()
+		h.poke()
+	}()
+
+	return sub.ch
+}
+
+// poke wakes the loop so it picks up subscriber changes
+func (h *frameHub) poke() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// markDirty records that the screen changed
+func (h *frameHub) markDirty() {
+	h.mu.Lock()
+	h.dirty = true
+	h.mu.Unlock()
+}
+
+// loop captures frames at the fastest subscriber's rate until nobody is left
+func (h *frameHub) loop() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		h.mu.Lock()
+		if len(h.subs) == 0 {
+			h.running = false
+			h.mu.Unlock()
+			return
+		}
+		now := time.Now()
+		wait := time.Duration(-1)
+		var due []*frameSub
+		for sub := range h.subs {
+			if d := sub.next.Sub(now); d > 0 {
+				if wait < 0 || d < wait {
+					wait = d
+				}
+				continue
+			}
+			due = append(due, sub)
+		}
+		h.mu.Unlock()
+
+		if len(due) == 0 {
+			select {
+			case <-time.After(wait):
+			case <-h.wake:
+			}
+			continue
+		}
+
+		frame, err := h.nextFrame(ctx)
+		if err != nil {
+			slog.Warn("frame capture failed", "err", err)
+		} else if h.trail != nil {
+			// The trail moves on even when the screen doesn't
+			frame.Trail = h.trail(time.Now())
+		}
+
+		h.mu.Lock()
+		for _, sub := range due {
+			if _, ok := h.subs[sub]; !ok {
+				continue
+			}
+			sub.next = now.Add(sub.interval)
+			if err != nil {
+				continue
+			}
+			// Drop the stale frame if the receiver hasn't taken it yet
+			select {
+			case <-sub.ch:
+			default:
+			}
+			sub.ch <- frame
+		}
+		h.mu.Unlock()
+	}
+}
+
+// nextFrame captures a new frame, or reuses the previous one if damage
+// tracking says nothing changed
+func (h *frameHub) nextFrame(ctx context.Context) (Frame, error) {
+	h.mu.Lock()
+	reuse := h.tracked && !h.dirty && h.last.Image != nil
+	h.dirty = false
+	last := h.last
+	h.mu.Unlock()
+
+	if reuse {
+		last.Changed = false
+		return last, nil
+	}
+
+	img, err := h.capture(ctx)
+	if err != nil {
+		// Capture again next time instead of trusting stale damage state
+		h.markDirty()
+		return Frame{}, err
+	}
+
+	frame := Frame{Image: img, Seq: last.Seq + 1, Time: time.Now(), Changed: true}
+	h.mu.Lock()
+	h.last = frame
+	h.mu.Unlock()
+	return frame, nil
+}
+
+// streamCapture returns the capture function used by the stream loop. It
+// prefers a shared memory segment and falls back to GetImage.
+func (c *Client) streamCapture() func(ctx context.Context) (*image.RGBA, error) {
+	var seg *shmSegment
+	shmFailed := false
+
+	return func(ctx context.Context) (*image.RGBA, error) {
+		width, height := int(c.screen.WidthInPixels), int(c.screen.HeightInPixels)
+
+		if seg == nil && !shmFailed {
+			format, err := c.rootPixelFormat()
+			if err == nil {
+				seg, err = c.newSHMSegment(ctx, zpixmapStride(width, format)*height)
+			}
+			if err != nil {
+				slog.Info("shared memory capture unavailable, using GetImage", "err", err)
+				shmFailed = true
+			}
+		}
+
+		if seg != nil {
+			redacted, err := c.captureRedaction(ctx)
+			if err != nil {
+				return nil, err
+			}
+			// Frames are shared with subscribers, so each one gets its own
+			// image, from the pool like those of GetImage captures so that
+			// RecycleScreenshot takes either
+			img := c.frames.get(width, height)
+			err = c.captureSHM(ctx, seg, img)
+			if err == nil {
+				blackOut(img, redacted, image.Point{})
+				return img, nil
+			}
+			c.frames.put(img)
+			slog.Warn("shared memory capture failed, using GetImage", "err", err)
+			seg.close(c)
+			seg = nil
+			shmFailed = true
+		}
+
+		return c.captureScreen(ctx)
+	}
+}
+
+// zpixmapStride returns the length of one padded ZPixmap scanline in bytes
+func zpixmapStride(width int, format pixelFormat) int {
+	pad := format.scanlinePad
+	if pad == 0 {
+		pad = 32
+	}
+	return ((width*format.bitsPerPixel + pad - 1) / pad) * pad / 8
+}
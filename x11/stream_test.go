@@ -0,0 +1,102 @@
+package x11
+
+import (
+	"context"
+	"image"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeHub returns a frame hub whose capture function counts calls
+func fakeHub(tracked bool) (*frameHub, *atomic.Int32) {
+	var captures atomic.Int32
+	hub := &frameHub{
+		tracked: tracked,
+		capture: func(ctx context.Context) (*image.RGBA, error) {
+			captures.Add(1)
+			return image.NewRGBA(image.Rect(0, 0, 4, 4)), nil
+		},
+	}
+	return hub, &captures
+}
+
+func receive(t *testing.T, ch <-chan Frame) Frame {
+	t.Helper()
+	select {
+	case frame, ok := <-ch:
+		if !ok {
+			t.Fatal("frame channel closed unexpectedly")
+		}
+		return frame
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a frame")
+	}
+	return Frame{}
+}
+
+func TestStreamFramesSharedLoop(t *testing.T) {
+	hub, _ := fakeHub(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := hub.subscribe(ctx, 50)
+	b := hub.subscribe(ctx, 50)
+
+	fa, fb := receive(t, a), receive(t, b)
+	if fa.Image == nil || fb.Image == nil {
+		t.Fatal("expected frames with images")
+	}
+	next := receive(t, a)
+	if next.Seq <= fa.Seq || !next.Changed {
+		t.Errorf("expected a newer changed frame, got seq %d after %d", next.Seq, fa.Seq)
+	}
+}
+
+func TestStreamFramesSkipsUndamaged(t *testing.T) {
+	hub, captures := fakeHub(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := hub.subscribe(ctx, 50)
+	first := receive(t, ch)
+	second := receive(t, ch)
+	if second.Changed || second.Seq != first.Seq || second.Image != first.Image {
+		t.Error("expected the previous frame to be reused without damage")
+	}
+
+	hub.markDirty()
+	for {
+		frame := receive(t, ch)
+		if frame.Changed {
+			if frame.Seq != first.Seq+1 {
+				t.Errorf("expected seq %d after damage, got %d", first.Seq+1, frame.Seq)
+			}
+			break
+		}
+	}
+	if n := captures.Load(); n != 2 {
+		t.Errorf("expected 2 captures, got %d", n)
+	}
+}
+
+func TestStreamFramesCancel(t *testing.T) {
+	hub, _ := fakeHub(false)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := hub.subscribe(ctx, 10)
+	receive(t, ch)
+	cancel()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package x11
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTranscriptBytes is how much of the end of a transcript ReadTerminal reads
+const maxTranscriptBytes = 1 << 20
+
+// terminalExecArgs are the arguments terminal emulators take before the
+// command they should run instead of a shell
+var terminalExecArgs = map[string][]string{
+	"xterm":          {"-e"},
+	"uxterm":         {"-e"},
+	"urxvt":          {"-e"},
+	"rxvt":           {"-e"},
+	"st":             {"-e"},
+	"alacritty":      {"-e"},
+	"konsole":        {"-e"},
+	"lxterminal":     {"-e"},
+	"mate-terminal":  {"-x"},
+	"xfce4-terminal": {"-x"},
+	"terminator":     {"-x"},
+	"gnome-terminal": {"--"},
+	"kitty":          {},
+}
+
+// StartTerminal starts a terminal emulator whose shell, or command if not
+// empty, runs under script(1). script keeps its own pty between the terminal
+// and the shell and records everything printed there in a transcript, which
+// ReadTerminal turns into text without OCR. args are passed to the terminal
+// before the command.
+func (c *Client) StartTerminal(program string, args []string, command string) (int, error) {
+	execArgs, ok := terminalExecArgs[filepath.Base(program)]
+	if !ok {
+		return 0, fmt.Errorf("don't know how to run a command in %s (supported: %s)", program, strings.Join(TerminalNames(), ", "))
+	}
+	script, err := exec.LookPath("script")
+	if err != nil {
+		return 0, fmt.Errorf("script(1) from util-linux is needed for terminal transcripts: %w", err)
+	}
+
+	f, err := os.CreateTemp("", "mcp-x11-term-*.log")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transcript: %w", err)
+	}
+	transcript := f.Name()
+	f.Close()
+
+	// -f flushes after every write so the transcript is always current
+	scriptArgs := []string{script, "-q", "-f"}
+	if command != "" {
+		scriptArgs = append(scriptArgs, "-c", command)
+	}
+	scriptArgs = append(scriptArgs, transcript)
+
+	full := append(append(append([]string{}, args...), execArgs...), scriptArgs...)
+	pid, err := c.startApp(program, full, nil, AppStatus{Transcript: transcript})
+	if err != nil {
+		os.Remove(transcript)
+		return 0, err
+	}
+	return pid, nil
+}
+
+// TerminalNames returns the terminal emulators StartTerminal supports
+func TerminalNames() []string {
+	names := make([]string, 0, len(terminalExecArgs))
+	for name := range terminalExecArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadTerminal returns up to the last n lines of text printed in a terminal
+// started by StartTerminal, with escape sequences removed and carriage
+// returns and backspaces applied. Full-screen programs such as editors draw
+// with cursor movements this doesn't follow, so their output reads poorly.
+func (c *Client) ReadTerminal(pid, n int) ([]string, error) {
+	status, ok := c.AppStatus(pid)
+	if !ok {
+		return nil, fmt.Errorf("no program with PID %d was started by this server", pid)
+	}
+	if status.Transcript == "" {
+		return nil, fmt.Errorf("PID %d was not started with a transcript", pid)
+	}
+
+	f, err := os.Open(status.Transcript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	offset := max(info.Size()-maxTranscriptBytes, 0)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	lines := renderTranscript(data)
+	if offset > 0 && len(lines) > 0 {
+		// The first line was cut off
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "Script started on ") {
+		lines = lines[1:]
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// renderTranscript turns raw terminal output into lines of text. It follows
+// newlines, carriage returns, backspaces, tabs and the cursor movements and
+// line erasing shells use when editing the command line, and drops all other
+// control and escape sequences. Trailing empty lines are removed.
+func renderTranscript(data []byte) []string {
+	var lines []string
+	var line []rune
+	col := 0
+
+	put := func(r rune) {
+		for len(line) < col {
+			line = append(line, ' ')
+		}
+		if col < len(line) {
+			line[col] = r
+		} else {
+			line = append(line, r)
+		}
+		col++
+	}
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == '\n':
+			lines = append(lines, strings.TrimRight(string(line), " "))
+			line, col = nil, 0
+			i++
+		case b == '\r':
+			col = 0
+			i++
+		case b == '\b':
+			col = max(col-1, 0)
+			i++
+		case b == '\t':
+			for put(' '); col%8 != 0; {
+				put(' ')
+			}
+			i++
+		case b == 0x1b:
+			i = skipEscape(data, i, func(final byte, param int) {
+				switch final {
+				case 'K': // Erase in line: 0 to the end, 1 to the start, 2 all
+					switch param {
+					case 0:
+						line = line[:min(col, len(line))]
+					case 2:
+						line = nil
+					}
+				case 'C': // Cursor forward
+					col += max(param, 1)
+				case 'D': // Cursor back
+					col = max(col-max(param, 1), 0)
+				case 'G': // Cursor to column
+					col = max(param-1, 0)
+				}
+			})
+		case b < 0x20 || b == 0x7f:
+			i++
+		default:
+			r, size := utf8.DecodeRune(data[i:])
+			put(r)
+			i += size
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, strings.TrimRight(string(line), " "))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// skipEscape skips the escape sequence starting at data[i] and returns the
+// index after it. For CSI sequences, csi is called with the final byte and
+// the first numeric parameter (0 if absent).
+func skipEscape(data []byte, i int, csi func(final byte, param int)) int {
+	i++ // ESC
+	if i >= len(data) {
+		return i
+	}
+	switch data[i] {
+	case '[': // CSI: parameters and intermediates up to a final byte
+		i++
+		param, first := 0, true
+		for ; i < len(data); i++ {
+			b := data[i]
+			switch {
+			case b >= '0' && b <= '9' && first:
+				param = param*10 + int(b-'0')
+			case b == ';':
+				first = false
+			case b >= 0x40 && b <= 0x7e:
+				csi(b, param)
+				return i + 1
+			}
+		}
+		return i
+	case ']', 'P', '_', '^': // OSC and other strings, ended by BEL or ESC \
+		for i++; i < len(data); i++ {
+			if data[i] == 0x07 {
+				return i + 1
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return i
+	case '(', ')', '*', '+', '#': // Character set selection and similar, one more byte
+		return min(i+2, len(data))
+	default:
+		return i + 1
+	}
+}
@@ -0,0 +1,81 @@
+package x11
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderTranscript(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"plain lines", "one\r\ntwo\r\n", []string{"one", "two"}},
+		{"colors", "\x1b[01;34mdir\x1b[0m file\r\n", []string{"dir file"}},
+		{"title", "\x1b]0;user@host: ~\x07$ ls\r\n", []string{"$ ls"}},
+		{"progress overwrite", "10%\r50%\r100%\r\ndone\n", []string{"100%", "done"}},
+		{"backspace", "lss\b \b\r\n", []string{"ls"}},
+		{"erase line", "$ wrong\r\x1b[K$ right\r\n", []string{"$ right"}},
+		{"cursor back", "abc\x1b[2DX\n", []string{"aXc"}},
+		{"tab", "a\tb\n", []string{"a       b"}},
+		{"charset", "\x1b(Bok\n", []string{"ok"}},
+		{"unicode", "grün ✓\n", []string{"grün ✓"}},
+		{"no trailing newline", "$ ", []string{"$"}},
+		{"trailing blank lines", "x\n\n\n", []string{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderTranscript([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderTranscript(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTerminal(t *testing.T) {
+	transcript := filepath.Join(t.TempDir(), "term.log")
+	data := "Script started on 2024-01-02 15:04:05+00:00 [TERM=\"xterm\"]\n$ echo hi\r\nhi\r\n$ "
+	if err := os.WriteFile(transcript, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{}
+	c.apps.apps = map[int]*trackedApp{
+		100: {status: AppStatus{PID: 100, Program: "xterm", Transcript: transcript}},
+		200: {status: AppStatus{PID: 200, Program: "firefox"}},
+	}
+
+	lines, err := c.ReadTerminal(100, 0)
+	if err != nil {
+		t.Fatalf("ReadTerminal failed: %v", err)
+	}
+	if want := []string{"$ echo hi", "hi", "$"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if lines, _ := c.ReadTerminal(100, 1); len(lines) != 1 || lines[0] != "$" {
+		t.Errorf("last line = %q", lines)
+	}
+
+	if _, err := c.ReadTerminal(200, 0); err == nil || !strings.Contains(err.Error(), "transcript") {
+		t.Errorf("expected transcript error, got %v", err)
+	}
+	if _, err := c.ReadTerminal(300, 0); err == nil {
+		t.Error("expected error for unknown PID")
+	}
+
+	c.removeAppFiles()
+	if _, err := os.Stat(transcript); !os.IsNotExist(err) {
+		t.Errorf("transcript not removed: %v", err)
+	}
+}
+
+func TestStartTerminalUnknown(t *testing.T) {
+	c := &Client{}
+	if _, err := c.StartTerminal("firefox", nil, ""); err == nil {
+		t.Error("expected error for a program that is not a known terminal")
+	}
+}
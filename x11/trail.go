@@ -0,0 +1,201 @@
+package x11
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+)
+
+// TrailAge is how long injected pointer events stay in a frame's trail
+const TrailAge = 2 * time.Second
+
+// maxTrailEvents bounds the events kept, however fast a drag moves
+const maxTrailEvents = 512
+
+// TrailEvent is one injected pointer motion or button event
+type TrailEvent struct {
+	Time   time.Time
+	X, Y   int  // Pointer position in root coordinates
+	Button byte // 0 for motion
+	Press  bool // For button events, press or release
+}
+
+// trailState keeps the recent injected pointer events for recordings
+type trailState struct {
+	mu     sync.Mutex
+	events []TrailEvent
+	pos    image.Point // Where the last motion put the pointer
+}
+
+// add records an event, dropping the oldest beyond maxTrailEvents
+func (t *trailState) add(ev TrailEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.events) == maxTrailEvents {
+		t.events = append(t.events[:0], t.events[1:]...)
+	}
+	t.events = append(t.events, ev)
+}
+
+// motion records the pointer moving to (x, y)
+func (t *trailState) motion(x, y int) {
+	t.mu.Lock()
+	t.pos = image.Pt(x, y)
+	t.mu.Unlock()
+	t.add(TrailEvent{Time: time.Now(), X: x, Y: y})
+}
+
+// button records a button event at the last motion's position
+func (t *trailState) button(button byte, press bool) {
+	t.mu.Lock()
+	pos := t.pos
+	t.mu.Unlock()
+	t.add(TrailEvent{Time: time.Now(), X: pos.X, Y: pos.Y, Button: button, Press: press})
+}
+
+// since returns a copy of the events of the TrailAge before now, oldest first
+func (t *trailState) since(now time.Time) []TrailEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var trail []TrailEvent
+	for _, ev := range t.events {
+		if now.Sub(ev.Time) <= TrailAge && !ev.Time.After(now) {
+			trail = append(trail, ev)
+		}
+	}
+	return trail
+}
+
+// WithPointerTrail returns a copy of img, a capture in root coordinates,
+// with the pointer events of the last TrailAge drawn in, or img itself when
+// there are none
+func (c *Client) WithPointerTrail(img image.Image) image.Image {
+	now := time.Now()
+	trail := c.trail.since(now)
+	if len(trail) == 0 {
+		return img
+	}
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Rect, img, out.Rect.Min, draw.Src)
+	DrawTrail(out, trail, now)
+	return out
+}
+
+// Annotated returns a copy of the frame's image with its pointer trail and
+// click markers drawn in, since the captured image shows neither the cursor
+// nor what was clicked
+func (f Frame) Annotated() *image.RGBA {
+	img := image.NewRGBA(f.Image.Rect)
+	copy(img.Pix, f.Image.Pix)
+	// A reused frame is older than the trail delivered with it
+	at := f.Time
+	if n := len(f.Trail); n > 0 && f.Trail[n-1].Time.After(at) {
+		at = f.Trail[n-1].Time
+	}
+	DrawTrail(img, f.Trail, at)
+	return img
+}
+
+// Trail colors; clicks are colored by button
+var (
+	trailMotionColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	trailOutline     = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	trailButtonColor = map[byte]color.RGBA{
+		ButtonLeft:   {0xff, 0x30, 0x30, 0xff},
+		ButtonMiddle: {0x30, 0xd0, 0x30, 0xff},
+		ButtonRight:  {0x30, 0x70, 0xff, 0xff},
+	}
+	trailOtherButton = color.RGBA{0xff, 0xe0, 0x20, 0xff}
+)
+
+// buttonColor returns the marker color of a button
+func buttonColor(button byte) color.RGBA {
+	if c, ok := trailButtonColor[button]; ok {
+		return c
+	}
+	return trailOtherButton
+}
+
+// DrawTrail draws the pointer path of trail onto img, fading with age at
+// the given time, with a ring at every button press, drags in the color of
+// the held button and a crosshair at the pointer's last position
+func DrawTrail(img *image.RGBA, trail []TrailEvent, at time.Time) {
+	fade := func(t time.Time) float64 {
+		return min(1, max(0, 1-float64(at.Sub(t))/float64(TrailAge)))
+	}
+
+	var held byte
+	var prev *TrailEvent
+	for i := range trail {
+		ev := &trail[i]
+		if ev.Button != 0 {
+			if ev.Press {
+				if held == 0 && ev.Button <= ButtonRight {
+					held = ev.Button
+				}
+				drawRing(img, ev.X, ev.Y, 10, buttonColor(ev.Button), fade(ev.Time))
+			} else if ev.Button == held {
+				held = 0
+			}
+			continue
+		}
+		if prev != nil {
+			c := trailMotionColor
+			if held != 0 {
+				c = buttonColor(held)
+			}
+			drawLine(img, prev.X, prev.Y, ev.X, ev.Y, c, fade(ev.Time))
+		}
+		prev = ev
+	}
+	if prev != nil {
+		drawCrosshair(img, prev.X, prev.Y)
+	}
+}
+
+// blend mixes c into the pixel at (x, y) with the given opacity
+func blend(img *image.RGBA, x, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{x, y}.In(img.Rect)) || alpha <= 0 {
+		return
+	}
+	i := img.PixOffset(x, y)
+	px := img.Pix[i : i+3 : i+3]
+	for k, v := range [3]uint8{c.R, c.G, c.B} {
+		px[k] = uint8(float64(px[k])*(1-alpha) + float64(v)*alpha + 0.5)
+	}
+}
+
+// drawLine draws a 2 pixel wide line from (x0, y0) to (x1, y1)
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA, alpha float64) {
+	steps := int(max(math.Abs(float64(x1-x0)), math.Abs(float64(y1-y0)), 1))
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		for _, d := range [4]image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			blend(img, x+d.X, y+d.Y, c, alpha)
+		}
+	}
+}
+
+// drawRing draws a 3 pixel wide circle of radius r around (cx, cy)
+func drawRing(img *image.RGBA, cx, cy, r int, c color.RGBA, alpha float64) {
+	for y := cy - r - 2; y <= cy+r+2; y++ {
+		for x := cx - r - 2; x <= cx+r+2; x++ {
+			if d := math.Hypot(float64(x-cx), float64(y-cy)); math.Abs(d-float64(r)) <= 1.5 {
+				blend(img, x, y, c, alpha)
+			}
+		}
+	}
+}
+
+// drawCrosshair marks the pointer position with an outlined cross
+func drawCrosshair(img *image.RGBA, x, y int) {
+	const arm = 8
+	draw.Draw(img, image.Rect(x-arm-1, y-2, x+arm+2, y+2).Intersect(img.Rect), image.NewUniform(trailOutline), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(x-2, y-arm-1, x+2, y+arm+2).Intersect(img.Rect), image.NewUniform(trailOutline), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(x-arm, y-1, x+arm+1, y+1).Intersect(img.Rect), image.NewUniform(trailMotionColor), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(x-1, y-arm, x+1, y+arm+1).Intersect(img.Rect), image.NewUniform(trailMotionColor), image.Point{}, draw.Src)
+}
@@ -0,0 +1,86 @@
+package x11
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestTrailStateSince(t *testing.T) {
+	var trail trailState
+	trail.motion(10, 20)
+	trail.button(ButtonLeft, true)
+	trail.button(ButtonLeft, false)
+
+	now := time.Now()
+	events := trail.since(now)
+	if len(events) != 3 {
+		t.Fatalf("since returned %d events, want 3", len(events))
+	}
+	if ev := events[1]; ev.Button != ButtonLeft || !ev.Press || ev.X != 10 || ev.Y != 20 {
+		t.Errorf("button event = %+v, want a left press at the last motion", ev)
+	}
+	if got := trail.since(now.Add(TrailAge + time.Second)); len(got) != 0 {
+		t.Errorf("since after TrailAge returned %d events, want none", len(got))
+	}
+
+	for i := 0; i < maxTrailEvents+10; i++ {
+		trail.motion(i, 0)
+	}
+	events = trail.since(time.Now())
+	if len(events) != maxTrailEvents || events[len(events)-1].X != maxTrailEvents+9 {
+		t.Errorf("kept %d events ending at x=%d, want the newest %d", len(events), events[len(events)-1].X, maxTrailEvents)
+	}
+}
+
+func TestFrameAnnotated(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	now := time.Now()
+	frame := Frame{
+		Image: img,
+		Time:  now.Add(-time.Second), // Reused frame, older than its trail
+		Trail: []TrailEvent{
+			{Time: now.Add(-100 * time.Millisecond), X: 10, Y: 50},
+			{Time: now.Add(-50 * time.Millisecond), X: 50, Y: 50},
+			{Time: now, X: 50, Y: 50, Button: ButtonLeft, Press: true},
+		},
+	}
+	out := frame.Annotated()
+
+	if img.RGBAAt(60, 50) != (color.RGBA{}) {
+		t.Error("Annotated modified the shared frame image")
+	}
+	if c := out.RGBAAt(60, 50); c.R != 0xff || c.G != 0x30 {
+		t.Errorf("click ring pixel = %v, want the left button's red", c)
+	}
+	if c := out.RGBAAt(30, 50); c.R < 0xf0 || c.G < 0xf0 {
+		t.Errorf("motion path pixel = %v, want it nearly white", c)
+	}
+	if c := out.RGBAAt(50, 50); c != trailMotionColor {
+		t.Errorf("crosshair pixel = %v, want %v", c, trailMotionColor)
+	}
+	if c := out.RGBAAt(90, 10); c != (color.RGBA{}) {
+		t.Errorf("pixel away from the trail = %v, want it untouched", c)
+	}
+}
+
+func TestWithPointerTrail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	c := &Client{}
+	if got := c.WithPointerTrail(img); got != image.Image(img) {
+		t.Error("WithPointerTrail without events returned a copy, want the image itself")
+	}
+
+	c.trail.motion(50, 50)
+	got, ok := c.WithPointerTrail(img).(*image.RGBA)
+	if !ok || got == img {
+		t.Fatal("WithPointerTrail with events didn't return a new RGBA image")
+	}
+	if px := got.RGBAAt(50, 50); px != trailMotionColor {
+		t.Errorf("crosshair pixel = %v, want %v", px, trailMotionColor)
+	}
+	if px := img.RGBAAt(50, 50); px != (color.RGBA{}) {
+		t.Errorf("source pixel = %v, want it untouched", px)
+	}
+}
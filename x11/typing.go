@@ -0,0 +1,110 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TypingProfile controls the pacing of Type
+type TypingProfile struct {
+	Name          string
+	InterKeyDelay time.Duration // Pause between characters
+	HoldTime      time.Duration // How long each key stays down
+	Jitter        time.Duration // Random +/- variation applied to both
+}
+
+// typingProfiles are the named profiles accepted by ParseTypingProfile
+var typingProfiles = map[string]TypingProfile{
+	// As fast as the server accepts events; fine for terminals and most toolkits
+	"instant": {Name: "instant"},
+	// Short pauses so input handlers that debounce or autocomplete keep up
+	"fast": {Name: "fast", InterKeyDelay: 15 * time.Millisecond, HoldTime: 5 * time.Millisecond, Jitter: 5 * time.Millisecond},
+	// Roughly the cadence of a person, for apps that drop or reorder fast input
+	"human": {Name: "human", InterKeyDelay: 90 * time.Millisecond, HoldTime: 40 * time.Millisecond, Jitter: 40 * time.Millisecond},
+}
+
+// TypingProfileNames returns the names of the built-in typing profiles
+func TypingProfileNames() []string {
+	names := make([]string, 0, len(typingProfiles))
+	for name := range typingProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTypingProfile looks up a typing profile by name. An empty name
+// selects "instant".
+func ParseTypingProfile(name string) (TypingProfile, error) {
+	if name == "" {
+		name = "instant"
+	}
+	profile, ok := typingProfiles[strings.ToLower(name)]
+	if !ok {
+		return TypingProfile{}, fmt.Errorf("unknown typing profile: %s (available: %s)",
+			name, strings.Join(TypingProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// SetTypingProfile sets the profile used by Type
+func (c *Client) SetTypingProfile(profile TypingProfile) {
+	c.typing = profile
+}
+
+// TypingProfile returns the profile used by Type
+func (c *Client) TypingProfile() TypingProfile {
+	return c.typing
+}
+
+// jittered varies d by up to the profile's jitter in either direction,
+// never going below zero
+func (p TypingProfile) jittered(d time.Duration) time.Duration {
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Int64N(int64(2*p.Jitter)+1)) - p.Jitter
+	}
+	return max(d, 0)
+}
+
+// pause waits for a jittered duration, skipping the wait entirely if the
+// result is zero
+func (c *Client) pause(ctx context.Context, p TypingProfile, d time.Duration) error {
+	d = p.jittered(d)
+	if d == 0 {
+		return nil
+	}
+	return c.WaitContext(ctx, int(d.Milliseconds()))
+}
+
+// secretTypingErrors are the errors TypeSecretContext passes on; all others
+// are replaced since they can name the character that failed
+var secretTypingErrors = []error{ErrAborted, ErrFrozen, ErrObserver, context.Canceled, context.DeadlineExceeded}
+
+// TypeSecretContext types secret like TypeWithProfileContext, but its errors
+// never contain any of the text, only the position of the character that
+// failed
+func (c *Client) TypeSecretContext(ctx context.Context, secret string, profile TypingProfile) error {
+	n := 0
+	for _, ch := range secret {
+		n++
+		if n > 1 {
+			if err := c.pause(ctx, profile, profile.InterKeyDelay); err != nil {
+				return err
+			}
+		}
+		if err := c.TypeWithProfileContext(ctx, string(ch), profile); err != nil {
+			for _, known := range secretTypingErrors {
+				if errors.Is(err, known) {
+					return fmt.Errorf("failed to type character %d of the secret: %w", n, known)
+				}
+			}
+			return fmt.Errorf("failed to type character %d of the secret (cause withheld so the secret is not revealed)", n)
+		}
+	}
+	return nil
+}
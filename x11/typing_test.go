@@ -0,0 +1,62 @@
+package x11
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTypingProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", "instant", false},
+		{"instant", "instant", false},
+		{"Fast", "fast", false},
+		{"human", "human", false},
+		{"sloth", "", true},
+	}
+
+	for _, tt := range tests {
+		profile, err := ParseTypingProfile(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTypingProfile(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if profile.Name != tt.want {
+			t.Errorf("ParseTypingProfile(%q) = %q, want %q", tt.name, profile.Name, tt.want)
+		}
+	}
+
+	instant, _ := ParseTypingProfile("instant")
+	if instant.InterKeyDelay != 0 || instant.HoldTime != 0 || instant.Jitter != 0 {
+		t.Errorf("instant profile must not pause: %+v", instant)
+	}
+}
+
+func TestTypingProfileJitter(t *testing.T) {
+	p := TypingProfile{Jitter: 10 * time.Millisecond}
+	seenLow, seenHigh := false, false
+	for i := 0; i < 1000; i++ {
+		d := p.jittered(20 * time.Millisecond)
+		if d < 10*time.Millisecond || d > 30*time.Millisecond {
+			t.Fatalf("jittered(20ms) = %v, want within 10ms..30ms", d)
+		}
+		seenLow = seenLow || d < 20*time.Millisecond
+		seenHigh = seenHigh || d > 20*time.Millisecond
+	}
+	if !seenLow || !seenHigh {
+		t.Error("jitter should vary in both directions")
+	}
+
+	// Never negative
+	for i := 0; i < 100; i++ {
+		if d := p.jittered(0); d < 0 {
+			t.Fatalf("jittered(0) = %v", d)
+		}
+	}
+	if d := (TypingProfile{}).jittered(5 * time.Millisecond); d != 5*time.Millisecond {
+		t.Errorf("without jitter got %v, want 5ms", d)
+	}
+}
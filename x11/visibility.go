@@ -0,0 +1,160 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// restoreTimeout is how long EnsureVisible waits for a restored window to
+// become viewable
+const restoreTimeout = 2 * time.Second
+
+// Reasons a window can't be seen, in WindowHiddenError.Reason
+const (
+	HiddenUnmapped       = "unmapped"
+	HiddenMinimized      = "minimized"
+	HiddenOtherWorkspace = "on another workspace"
+)
+
+// iconicState is the WM_STATE of minimized windows (ICCCM 4.1.3.1)
+const iconicState = 3
+
+// WindowHiddenError reports that a window can't be captured or clicked
+// because it isn't on screen
+type WindowHiddenError struct {
+	Window    x.Window
+	Title     string
+	Reason    string // HiddenUnmapped, HiddenMinimized or HiddenOtherWorkspace
+	Workspace string // Workspace of the window, if known
+	Current   string // Current workspace, if known
+}
+
+func (e *WindowHiddenError) Error() string {
+	msg := fmt.Sprintf("window 0x%x %q is %s", uint32(e.Window), e.Title, e.Reason)
+	switch e.Reason {
+	case HiddenOtherWorkspace:
+		if e.Workspace != "" {
+			msg += fmt.Sprintf(" (%s, current: %s)", e.Workspace, orUnknown(e.Current))
+		}
+		msg += "; focus it first to switch there"
+	case HiddenMinimized:
+		msg += "; focus it first to restore it"
+	default:
+		msg += ": it was closed, withdrawn or is in a scratchpad, so it can't be shown"
+	}
+	return msg
+}
+
+// checkVisible returns a *WindowHiddenError if win is minimized, on another
+// workspace or unmapped, and nil if it is viewable
+func (c *Client) checkVisible(ctx context.Context, win x.Window) error {
+	attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+		return x.GetWindowAttributes(c.conn, win).Reply(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get attributes of window 0x%x: %w", uint32(win), err)
+	}
+	if attrs.MapState == x.MapStateViewable {
+		return nil
+	}
+
+	app := c.clientWindow(ctx, win)
+	hidden := &WindowHiddenError{Window: win, Title: c.getWindowName(ctx, app), Reason: HiddenUnmapped}
+
+	wmState := c.getAtom(ctx, "WM_STATE")
+	if state, ok := c.getUint32Property(ctx, app, wmState, wmState); ok && state == iconicState {
+		hidden.Reason = HiddenMinimized
+	}
+	hiddenAtom := c.getAtom(ctx, "_NET_WM_STATE_HIDDEN")
+	for _, atom := range c.getUint32Values(ctx, app, c.getAtom(ctx, "_NET_WM_STATE"), x.AtomAtom, 32) {
+		if x.Atom(atom) == hiddenAtom {
+			hidden.Reason = HiddenMinimized
+		}
+	}
+
+	// Windows on other workspaces are unmapped by the WM but keep their
+	// WM_STATE; 0xFFFFFFFF means on all desktops
+	desktop, ok := c.getCardinalProperty(ctx, app, c.getAtom(ctx, "_NET_WM_DESKTOP"))
+	current, currentOK := c.getCardinalProperty(ctx, c.root, c.getAtom(ctx, "_NET_CURRENT_DESKTOP"))
+	if ok && currentOK && desktop != 0xFFFFFFFF && desktop != current && hidden.Reason != HiddenMinimized {
+		hidden.Reason = HiddenOtherWorkspace
+		hidden.Workspace = c.desktopName(ctx, desktop)
+		hidden.Current = c.currentWorkspace(ctx)
+	} else if hidden.Reason == HiddenUnmapped && c.I3Enabled() {
+		// i3 also unmaps windows on invisible workspaces
+		if ws := c.i3WindowWorkspace(ctx, app); ws != "" && !strings.HasPrefix(ws, "__i3") {
+			hidden.Reason = HiddenOtherWorkspace
+			hidden.Workspace = ws
+			hidden.Current = c.currentWorkspace(ctx)
+		}
+	}
+	return hidden
+}
+
+// EnsureVisible checks that win is on screen before it is captured or
+// clicked. If it is minimized or on another workspace and the client was
+// connected with RestoreHidden, it is restored; otherwise the
+// *WindowHiddenError is returned.
+func (c *Client) EnsureVisible(ctx context.Context, win x.Window) error {
+	err := c.checkVisible(ctx, win)
+	if hidden, ok := err.(*WindowHiddenError); ok && c.restoreHidden && hidden.Reason != HiddenUnmapped {
+		return c.restoreWindow(ctx, win, hidden)
+	}
+	return err
+}
+
+// restoreWindow activates a minimized window or one on another workspace,
+// which makes the WM show it and switch workspaces, and waits until it is
+// viewable
+func (c *Client) restoreWindow(ctx context.Context, win x.Window, hidden *WindowHiddenError) error {
+	app := c.clientWindow(ctx, win)
+	var err error
+	if c.I3Enabled() {
+		_, err = c.I3CommandContext(ctx, fmt.Sprintf("[id=%d] focus", uint32(app)))
+	} else {
+		// Source 2 is a pager or taskbar, which WMs obey unconditionally
+		err = c.sendClientMessage(ctx, app, c.getAtom(ctx, "_NET_ACTIVE_WINDOW"), [5]uint32{2, x.TimeCurrentTime, 0})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore window 0x%x: %w", uint32(win), err)
+	}
+
+	deadline := time.Now().Add(restoreTimeout)
+	for c.checkVisible(ctx, win) != nil {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("window 0x%x was %s and did not appear after activating it", uint32(win), hidden.Reason)
+		}
+		if err := c.WaitContext(ctx, 50); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// desktopName returns the EWMH name of a desktop, or its 1-based number if
+// the WM publishes no names
+func (c *Client) desktopName(ctx context.Context, desktop uint32) string {
+	cookie := x.GetProperty(c.conn, false, c.root, c.getAtom(ctx, "_NET_DESKTOP_NAMES"), x.GetPropertyTypeAny, 0, 2048)
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err == nil {
+		names := strings.Split(string(reply.Value), "\x00")
+		if int(desktop) < len(names) && names[desktop] != "" {
+			return names[desktop]
+		}
+	}
+	return fmt.Sprintf("%d", desktop+1)
+}
+
+// orUnknown returns s, or "unknown" if it is empty
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package x11
+
+import (
+	"strings"
+	"testing"
+
+	"go.i3wm.org/i3/v4"
+)
+
+func TestWindowHiddenError(t *testing.T) {
+	tests := []struct {
+		err  WindowHiddenError
+		want []string
+	}{
+		{WindowHiddenError{Window: 0x400001, Title: "Editor", Reason: HiddenMinimized},
+			[]string{`0x400001 "Editor" is minimized`, "restore"}},
+		{WindowHiddenError{Window: 0x400002, Title: "Browser", Reason: HiddenOtherWorkspace, Workspace: "2", Current: "1"},
+			[]string{"on another workspace (2, current: 1)", "switch"}},
+		{WindowHiddenError{Window: 0x400003, Reason: HiddenOtherWorkspace, Workspace: "web"},
+			[]string{"(web, current: unknown)"}},
+		{WindowHiddenError{Window: 0x400004, Title: "Gone", Reason: HiddenUnmapped},
+			[]string{"is unmapped", "can't be shown"}},
+	}
+	for _, tt := range tests {
+		msg := tt.err.Error()
+		for _, want := range tt.want {
+			if !strings.Contains(msg, want) {
+				t.Errorf("Error() = %q, want it to contain %q", msg, want)
+			}
+		}
+	}
+}
+
+func TestI3Workspace(t *testing.T) {
+	tree := &i3.Node{
+		Type: i3.Root,
+		Nodes: []*i3.Node{
+			{Type: i3.OutputNode, Name: "__i3", Nodes: []*i3.Node{
+				{Type: i3.WorkspaceNode, Name: "__i3_scratch", FloatingNodes: []*i3.Node{
+					{Type: i3.FloatingCon, Nodes: []*i3.Node{{Type: i3.Con, Window: 30}}},
+				}},
+			}},
+			{Type: i3.OutputNode, Name: "screen", Nodes: []*i3.Node{
+				{Type: i3.WorkspaceNode, Name: "1", Nodes: []*i3.Node{{Type: i3.Con, Window: 10}}},
+				{Type: i3.WorkspaceNode, Name: "2: web", FloatingNodes: []*i3.Node{
+					{Type: i3.FloatingCon, Nodes: []*i3.Node{{Type: i3.Con, Window: 20}}},
+				}},
+			}},
+		},
+	}
+	tests := []struct {
+		win  int64
+		want string
+	}{
+		{10, "1"},
+		{20, "2: web"},
+		{30, "__i3_scratch"},
+		{40, ""},
+	}
+	for _, tt := range tests {
+		if got := i3Workspace(tree, tt.win, ""); got != tt.want {
+			t.Errorf("i3Workspace(%d) = %q, want %q", tt.win, got, tt.want)
+		}
+	}
+}
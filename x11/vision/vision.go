@@ -0,0 +1,341 @@
+// Package vision finds a template image, such as a cropped button, within
+// a larger image like a screenshot
+package vision
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultThreshold is the least score that counts as a match by default
+	DefaultThreshold = 0.9
+	// minCoarseArea and minCoarseSide are the least template size in pixels
+	// and the least side the coarse search scales down to
+	minCoarseArea = 64
+	minCoarseSide = 3
+	// maxFactor bounds how far the coarse search scales down
+	maxFactor = 16
+	// coarseSlack is how far below the threshold coarse scores stay
+	// candidates, since scaling down blurs the match
+	coarseSlack = 0.15
+	// maxCandidates bounds the coarse matches refined at full resolution
+	maxCandidates = 64
+)
+
+// Match is a place where the template was found
+type Match struct {
+	Rect  image.Rectangle // Area covered by the template, in the image's coordinates
+	Score float64         // Normalized cross-correlation of the brightness, 1 for a perfect match
+}
+
+// Center returns the middle of the match, where a click would go
+func (m Match) Center() image.Point {
+	return image.Pt((m.Rect.Min.X+m.Rect.Max.X)/2, (m.Rect.Min.Y+m.Rect.Max.Y)/2)
+}
+
+// Find returns where template appears in img with a score of at least
+// threshold, best first and at most limit of them, all if limit is 0.
+// Overlapping matches are reported once. The search compares brightness,
+// so it tolerates color shifts but not scaling or rotation.
+func Find(img, template image.Image, threshold float64, limit int) ([]Match, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, fmt.Errorf("threshold %g is not between 0 and 1", threshold)
+	}
+	screen, tmpl := toGray(img), toGray(template)
+	if tmpl.w == 0 || tmpl.h == 0 {
+		return nil, fmt.Errorf("template is empty")
+	}
+	if tmpl.w > screen.w || tmpl.h > screen.h {
+		return nil, fmt.Errorf("template %dx%d is larger than the image %dx%d", tmpl.w, tmpl.h, screen.w, screen.h)
+	}
+	full, ok := prepare(tmpl)
+	if !ok {
+		return nil, fmt.Errorf("template is a single color, which matches anywhere")
+	}
+
+	candidates := coarseCandidates(screen, tmpl, threshold)
+	origin := img.Bounds().Min
+	sheet := newIntegral(screen)
+	var matches []Match
+	for _, c := range candidates {
+		best, bestScore := c.pos, -1.0
+		for y := max(0, c.pos.Y-c.reach); y <= min(screen.h-tmpl.h, c.pos.Y+c.reach); y++ {
+			for x := max(0, c.pos.X-c.reach); x <= min(screen.w-tmpl.w, c.pos.X+c.reach); x++ {
+				if score := ncc(screen, sheet, full, x, y); score > bestScore {
+					best, bestScore = image.Pt(x, y), score
+				}
+			}
+		}
+		if bestScore >= threshold {
+			rect := image.Rect(0, 0, tmpl.w, tmpl.h).Add(best).Add(origin)
+			matches = append(matches, Match{Rect: rect, Score: bestScore})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	var kept []Match
+	var covered []image.Rectangle
+	for _, m := range matches {
+		if overlapsAny(m.Rect, covered) {
+			continue
+		}
+		kept = append(kept, m)
+		covered = append(covered, m.Rect)
+		if limit > 0 && len(kept) == limit {
+			break
+		}
+	}
+	return kept, nil
+}
+
+// candidate is a full-resolution position to refine, within reach pixels
+type candidate struct {
+	pos   image.Point
+	reach int
+}
+
+// coarseCandidates compares a scaled-down template with the blurred image
+// at every few positions and returns the best separate ones. Templates too
+// small or too fine to scale down are compared at every position in full.
+func coarseCandidates(screen, tmpl gray, threshold float64) []candidate {
+	factor := coarseFactor(tmpl.w, tmpl.h)
+	small, ok := prepare(tmpl.downscale(factor))
+	if !ok {
+		// Fine detail that averages away; the full template has contrast
+		factor, small = 1, mustPrepare(tmpl)
+	}
+	minScore, step := threshold, max(1, factor/4)
+	var score func(x, y int) float64
+	if factor == 1 {
+		sheet := newIntegral(screen)
+		score = func(x, y int) float64 { return ncc(screen, sheet, small, x, y) }
+	} else {
+		minScore -= coarseSlack
+		blurred := screen.blur(factor)
+		score = func(x, y int) float64 { return sparseNCC(blurred, small, x, y, factor) }
+	}
+
+	type scored struct {
+		pos   image.Point
+		score float64
+	}
+	var found []scored
+	for y := 0; y <= screen.h-tmpl.h; y += step {
+		for x := 0; x <= screen.w-tmpl.w; x += step {
+			if s := score(x, y); s >= minScore {
+				found = append(found, scored{image.Pt(x, y), s})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+
+	var taken []image.Rectangle
+	var candidates []candidate
+	for _, f := range found {
+		rect := image.Rect(0, 0, tmpl.w, tmpl.h).Add(f.pos)
+		if overlapsAny(rect, taken) {
+			continue
+		}
+		taken = append(taken, rect)
+		candidates = append(candidates, candidate{pos: f.pos, reach: step - 1})
+		if len(candidates) == maxCandidates {
+			break
+		}
+	}
+	return candidates
+}
+
+// coarseFactor picks how far to scale a w x h template down: as far as
+// possible up to maxFactor while minCoarseArea pixels remain
+func coarseFactor(w, h int) int {
+	factor := 1
+	for factor < maxFactor && (w/(factor*2))*(h/(factor*2)) >= minCoarseArea && min(w, h)/(factor*2) >= minCoarseSide {
+		factor *= 2
+	}
+	return factor
+}
+
+// gray is a brightness image
+type gray struct {
+	w, h int
+	pix  []float64
+}
+
+// toGray returns the Rec. 601 luma of img
+func toGray(img image.Image) gray {
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		bounds := img.Bounds()
+		rgba = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	}
+	w, h := rgba.Rect.Dx(), rgba.Rect.Dy()
+	g := gray{w: w, h: h, pix: make([]float64, w*h)}
+	for y := 0; y < h; y++ {
+		row := rgba.Pix[y*rgba.Stride:]
+		for x := 0; x < w; x++ {
+			p := row[x*4:]
+			g.pix[y*w+x] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+		}
+	}
+	return g
+}
+
+// downscale averages factor x factor blocks, dropping partial ones
+func (g gray) downscale(factor int) gray {
+	if factor <= 1 {
+		return g
+	}
+	out := gray{w: g.w / factor, h: g.h / factor}
+	out.pix = make([]float64, out.w*out.h)
+	area := float64(factor * factor)
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			var sum float64
+			for dy := 0; dy < factor; dy++ {
+				row := g.pix[(y*factor+dy)*g.w+x*factor:]
+				for dx := 0; dx < factor; dx++ {
+					sum += row[dx]
+				}
+			}
+			out.pix[y*out.w+x] = sum / area
+		}
+	}
+	return out
+}
+
+// blur averages the factor x factor block at every pixel. The result is
+// factor-1 pixels narrower and shorter, as blocks must lie inside g.
+func (g gray) blur(factor int) gray {
+	sheet := newIntegral(g)
+	out := gray{w: g.w - factor + 1, h: g.h - factor + 1}
+	out.pix = make([]float64, out.w*out.h)
+	area := float64(factor * factor)
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			sum, _ := sheet.window(x, y, factor, factor)
+			out.pix[y*out.w+x] = sum / area
+		}
+	}
+	return out
+}
+
+// integral holds summed-area tables of an image and its squares, to get the
+// mean and variance under the template at any position in constant time
+type integral struct {
+	w       int // Width of the tables, one more than the image's
+	sum, sq []float64
+}
+
+// newIntegral builds the summed-area tables of g
+func newIntegral(g gray) integral {
+	t := integral{w: g.w + 1, sum: make([]float64, (g.w+1)*(g.h+1)), sq: make([]float64, (g.w+1)*(g.h+1))}
+	for y := 0; y < g.h; y++ {
+		var rowSum, rowSq float64
+		for x := 0; x < g.w; x++ {
+			v := g.pix[y*g.w+x]
+			rowSum += v
+			rowSq += v * v
+			i := (y+1)*t.w + x + 1
+			t.sum[i] = t.sum[i-t.w] + rowSum
+			t.sq[i] = t.sq[i-t.w] + rowSq
+		}
+	}
+	return t
+}
+
+// window returns the sum and the sum of squares of the w x h area at x, y
+func (t integral) window(x, y, w, h int) (sum, sq float64) {
+	a, b := y*t.w+x, y*t.w+x+w
+	c, d := (y+h)*t.w+x, (y+h)*t.w+x+w
+	return t.sum[d] - t.sum[b] - t.sum[c] + t.sum[a], t.sq[d] - t.sq[b] - t.sq[c] + t.sq[a]
+}
+
+// prepared is a template with its mean taken out
+type prepared struct {
+	g    gray
+	zero []float64 // Brightness minus the mean
+	norm float64   // Square root of the sum of zero's squares
+}
+
+// prepare readies a template for ncc; false if it has no contrast
+func prepare(g gray) (prepared, bool) {
+	p := mustPrepare(g)
+	return p, p.norm > 1e-6
+}
+
+// mustPrepare is prepare without the contrast check
+func mustPrepare(g gray) prepared {
+	var mean float64
+	for _, v := range g.pix {
+		mean += v
+	}
+	mean /= float64(len(g.pix))
+	p := prepared{g: g, zero: make([]float64, len(g.pix))}
+	var sq float64
+	for i, v := range g.pix {
+		p.zero[i] = v - mean
+		sq += p.zero[i] * p.zero[i]
+	}
+	p.norm = math.Sqrt(sq)
+	return p
+}
+
+// ncc returns the normalized cross-correlation of the template with the
+// image area at x, y: 1 for the same picture up to brightness and contrast,
+// 0 for a flat area
+func ncc(img gray, sheet integral, t prepared, x, y int) float64 {
+	n := float64(len(t.zero))
+	sum, sq := sheet.window(x, y, t.g.w, t.g.h)
+	variance := sq - sum*sum/n
+	if variance <= 1e-6 {
+		return 0
+	}
+	// The template's zero mean makes the image's mean drop out
+	var dot float64
+	for ty := 0; ty < t.g.h; ty++ {
+		row := img.pix[(y+ty)*img.w+x : (y+ty)*img.w+x+t.g.w]
+		trow := t.zero[ty*t.g.w : (ty+1)*t.g.w]
+		for i, v := range row {
+			dot += v * trow[i]
+		}
+	}
+	return dot / (math.Sqrt(variance) * t.norm)
+}
+
+// sparseNCC is ncc of a template scaled down by factor with the blurred
+// image, sampled every factor pixels from x, y
+func sparseNCC(blurred gray, t prepared, x, y, factor int) float64 {
+	var sum, sq, dot float64
+	for ty := 0; ty < t.g.h; ty++ {
+		row := blurred.pix[(y+ty*factor)*blurred.w+x:]
+		trow := t.zero[ty*t.g.w : (ty+1)*t.g.w]
+		for i, tv := range trow {
+			v := row[i*factor]
+			sum += v
+			sq += v * v
+			dot += v * tv
+		}
+	}
+	variance := sq - sum*sum/float64(len(t.zero))
+	if variance <= 1e-6 {
+		return 0
+	}
+	return dot / (math.Sqrt(variance) * t.norm)
+}
+
+// overlapsAny reports whether r shares more than half its area with one of
+// the rectangles of the same size taken before
+func overlapsAny(r image.Rectangle, taken []image.Rectangle) bool {
+	for _, t := range taken {
+		in := r.Intersect(t)
+		if 2*in.Dx()*in.Dy() > r.Dx()*r.Dy() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package vision
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+// noise returns an image of random gray pixels
+func noise(r *rand.Rand, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		v := uint8(r.Intn(256))
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 0xff
+	}
+	return img
+}
+
+func TestFind(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	screen := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	draw.Draw(screen, screen.Rect, &image.Uniform{color.RGBA{0x30, 0x30, 0x40, 0xff}}, image.Point{}, draw.Src)
+
+	tests := []struct {
+		name string
+		w, h int
+		at   []image.Point
+	}{
+		{"small icon", 10, 10, []image.Point{{37, 21}}},
+		{"button", 64, 24, []image.Point{{301, 155}}},
+		{"repeated", 32, 32, []image.Point{{400, 300}, {500, 40}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := noise(r, tt.w, tt.h)
+			img := image.NewRGBA(screen.Rect)
+			copy(img.Pix, screen.Pix)
+			for _, p := range tt.at {
+				draw.Draw(img, tmpl.Rect.Add(p), tmpl, image.Point{}, draw.Src)
+			}
+
+			matches, err := Find(img, tmpl, DefaultThreshold, 0)
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			if len(matches) != len(tt.at) {
+				t.Fatalf("Find = %v, want %d matches", matches, len(tt.at))
+			}
+			for _, p := range tt.at {
+				found := false
+				for _, m := range matches {
+					found = found || (m.Rect.Min == p && m.Score > 0.99)
+				}
+				if !found {
+					t.Errorf("no match at %v in %v", p, matches)
+				}
+			}
+		})
+	}
+}
+
+func TestFindOffsetAndLimit(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	tmpl := noise(r, 20, 20)
+	// A capture of a region: bounds in screen coordinates
+	img := image.NewRGBA(image.Rect(100, 200, 300, 300))
+	draw.Draw(img, img.Rect, &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(150, 250, 170, 270), tmpl, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(250, 210, 270, 230), tmpl, image.Point{}, draw.Src)
+
+	matches, err := Find(img, tmpl, 0.95, 1)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("Find with limit 1 = %v", matches)
+	}
+	if m := matches[0]; m.Rect.Min != image.Pt(150, 250) && m.Rect.Min != image.Pt(250, 210) {
+		t.Errorf("match at %v, want screen coordinates", m.Rect)
+	} else if c := m.Center(); c != m.Rect.Min.Add(image.Pt(10, 10)) {
+		t.Errorf("Center() = %v", c)
+	}
+}
+
+func TestFindNoMatch(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	matches, err := Find(noise(r, 200, 100), noise(r, 16, 16), DefaultThreshold, 0)
+	if err != nil || len(matches) != 0 {
+		t.Errorf("Find of an absent template = %v, %v", matches, err)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	flat := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	tests := []struct {
+		name      string
+		img, tmpl image.Image
+		threshold float64
+	}{
+		{"flat template", noise(r, 50, 50), flat, DefaultThreshold},
+		{"template too large", noise(r, 10, 10), noise(r, 20, 5), DefaultThreshold},
+		{"empty template", noise(r, 10, 10), image.NewRGBA(image.Rectangle{}), DefaultThreshold},
+		{"zero threshold", noise(r, 50, 50), noise(r, 8, 8), 0},
+		{"threshold above 1", noise(r, 50, 50), noise(r, 8, 8), 1.5},
+	}
+	for _, tt := range tests {
+		if _, err := Find(tt.img, tt.tmpl, tt.threshold, 0); err == nil {
+			t.Errorf("%s: Find succeeded", tt.name)
+		}
+	}
+}
+
+func BenchmarkFind(b *testing.B) {
+	r := rand.New(rand.NewSource(5))
+	screen := noise(r, 1920, 1080)
+	tmpl := image.NewRGBA(image.Rect(0, 0, 96, 32))
+	draw.Draw(tmpl, tmpl.Rect, screen, image.Pt(900, 500), draw.Src)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Find(screen, tmpl, DefaultThreshold, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
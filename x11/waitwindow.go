@@ -0,0 +1,93 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WindowMatcher selects windows by title and WM_CLASS. Both are regular
+// expressions; the class is matched case-insensitively, and an empty
+// pattern matches anything.
+type WindowMatcher struct {
+	Title string
+	Class string
+}
+
+// Validate checks that the matcher has a valid pattern
+func (m WindowMatcher) Validate() error {
+	if m.Title == "" && m.Class == "" {
+		return fmt.Errorf("give a title or class pattern")
+	}
+	if _, err := regexp.Compile(m.Title); err != nil {
+		return fmt.Errorf("invalid title pattern: %w", err)
+	}
+	if _, err := regexp.Compile("(?i)" + m.Class); err != nil {
+		return fmt.Errorf("invalid class pattern: %w", err)
+	}
+	return nil
+}
+
+// Match reports whether a window with title and class matches; the
+// patterns must be valid
+func (m WindowMatcher) Match(title, class string) bool {
+	return regexp.MustCompile(m.Title).MatchString(title) && regexp.MustCompile("(?i)"+m.Class).MatchString(class)
+}
+
+// String describes the matcher for messages
+func (m WindowMatcher) String() string {
+	var parts []string
+	if m.Title != "" {
+		parts = append(parts, fmt.Sprintf("title %q", m.Title))
+	}
+	if m.Class != "" {
+		parts = append(parts, fmt.Sprintf("class %q", m.Class))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// NoWindowError is returned by WaitForWindow when no window matched
+type NoWindowError struct {
+	Matcher WindowMatcher
+	Timeout time.Duration // How long it waited, 0 if it only checked
+}
+
+func (e *NoWindowError) Error() string {
+	if e.Timeout <= 0 {
+		return fmt.Sprintf("no window with %s is open", e.Matcher)
+	}
+	return fmt.Sprintf("no window with %s appeared within %s", e.Matcher, e.Timeout.Round(time.Millisecond))
+}
+
+// WaitForWindow waits until a mapped window matching m exists and returns
+// it, looking through window manager frames. A window that is already open
+// returns at once, and a timeout of 0 only checks for one. No match within
+// timeout is a *NoWindowError; waits are interrupted by AbortAll.
+func (c *Client) WaitForWindow(ctx context.Context, m WindowMatcher, timeout time.Duration) (Window, error) {
+	if err := m.Validate(); err != nil {
+		return Window{}, err
+	}
+	title := regexp.MustCompile(m.Title)
+	class := regexp.MustCompile("(?i)" + m.Class)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		windows, err := c.classWindows(ctx, class.MatchString)
+		if err != nil {
+			return Window{}, err
+		}
+		for _, win := range windows {
+			if name := c.getWindowName(ctx, win); title.MatchString(name) {
+				return Window{ID: win, Title: name, Class: c.getWindowClass(ctx, win)}, nil
+			}
+		}
+		if timeout <= 0 || time.Now().After(deadline) {
+			return Window{}, &NoWindowError{Matcher: m, Timeout: timeout}
+		}
+		if err := c.WaitContext(ctx, int(launchPoll.Milliseconds())); err != nil {
+			return Window{}, err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestWindowMatcherValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher WindowMatcher
+		want    string
+		wantErr bool
+	}{
+		{"title", WindowMatcher{Title: `- Mozilla Firefox$`}, `title "- Mozilla Firefox$"`, false},
+		{"class", WindowMatcher{Class: `^xterm$`}, `class "^xterm$"`, false},
+		{"both", WindowMatcher{Title: `Save`, Class: `gedit`}, `title "Save" and class "gedit"`, false},
+		{"nothing", WindowMatcher{}, "", true},
+		{"bad title", WindowMatcher{Title: `(`}, `title "("`, true},
+		{"bad class", WindowMatcher{Class: `[`}, `class "["`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.matcher.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := tt.matcher.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 0, 0, 300, 200, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	x.ChangeProperty(client.conn, x.PropModeReplace, win, client.getAtom(ctx, "WM_CLASS"), x.AtomString, 8, []byte("waiter\x00Waiter\x00"))
+	x.ChangeProperty(client.conn, x.PropModeReplace, win, x.AtomWMName, x.AtomString, 8, []byte("Waiter - started"))
+	time.AfterFunc(300*time.Millisecond, func() {
+		x.MapWindow(client.conn, win)
+		client.conn.Flush()
+	})
+
+	start := time.Now()
+	got, err := client.WaitForWindow(ctx, WindowMatcher{Title: "started$", Class: "^waiter$"}, 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForWindow failed: %v", err)
+	}
+	if got.ID != win || got.Title != "Waiter - started" || got.Class != "Waiter" || time.Since(start) < 250*time.Millisecond {
+		t.Errorf("WaitForWindow = %+v, want window 0x%x once mapped", got, win)
+	}
+
+	// An open window matches at once; others time out naming the pattern
+	if _, err := client.WaitForWindow(ctx, WindowMatcher{Class: "waiter"}, 0); err != nil {
+		t.Errorf("WaitForWindow for an open window failed: %v", err)
+	}
+	_, err = client.WaitForWindow(ctx, WindowMatcher{Title: "^Other$"}, 300*time.Millisecond)
+	var missing *NoWindowError
+	if !errors.As(err, &missing) || !strings.Contains(err.Error(), `title "^Other$"`) {
+		t.Errorf("WaitForWindow for a missing window = %v, want a timeout naming the pattern", err)
+	}
+}
+
+func TestWindowMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		matcher      WindowMatcher
+		title, class string
+		want         bool
+	}{
+		{"title", WindowMatcher{Title: `^Save`}, "Save As", "Gedit", true},
+		{"class ignores case", WindowMatcher{Class: `^gedit$`}, "Save As", "Gedit", true},
+		{"both", WindowMatcher{Title: `Save`, Class: `gedit`}, "Save As", "Gedit", true},
+		{"title differs", WindowMatcher{Title: `^Open`, Class: `gedit`}, "Save As", "Gedit", false},
+		{"title is case-sensitive", WindowMatcher{Title: `save`}, "Save As", "Gedit", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.Match(tt.title, tt.class); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.title, tt.class, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package x11
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"strings"
 
 	x "github.com/linuxdeepin/go-x11-client"
@@ -12,22 +14,41 @@ type Window struct {
 	ID    x.Window
 	Title string
 	Class string
+	
+	X, Y          int      // Position in root coordinates
+	Width, Height int
+	PID           uint32   // _NET_WM_PID, 0 if not set
+	Workspace     int      // _NET_WM_DESKTOP counted from 0, -1 if not set, on all workspaces or not looked up
+	State         []string // _NET_WM_STATE without its prefix, e.g. "MAXIMIZED_VERT" or "HIDDEN"
 }
 
 // ListWindows returns a list of all windows
 func (c *Client) ListWindows() ([]Window, error) {
+	return c.ListWindowsContext(context.Background())
+}
+
+// ListWindowsContext is like ListWindows but gives up when ctx is done
+func (c *Client) ListWindowsContext(ctx context.Context) ([]Window, error) {
 	// Get root window children
 	cookie := x.QueryTree(c.conn, c.root)
-	reply, err := cookie.Reply(c.conn)
+	reply, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+		return cookie.Reply(c.conn)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tree: %w", err)
 	}
 
 	var windows []Window
 	for _, win := range reply.Children {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Check if window is mapped (visible)
 		attrCookie := x.GetWindowAttributes(c.conn, win)
-		attrs, err := attrCookie.Reply(c.conn)
+		attrs, err := await(c, ctx, func() (*x.GetWindowAttributesReply, error) {
+			return attrCookie.Reply(c.conn)
+		})
 		if err != nil {
 			continue
 		}
@@ -41,12 +62,12 @@ func (c *Client) ListWindows() ([]Window, error) {
 		window := Window{ID: win}
 		
 		// Try to get window name
-		if name := c.getWindowName(win); name != "" {
+		if name := c.getWindowName(ctx, win); name != "" {
 			window.Title = name
 		}
 		
 		// Try to get window class
-		if class := c.getWindowClass(win); class != "" {
+		if class := c.getWindowClass(ctx, win); class != "" {
 			window.Class = class
 		}
 
@@ -59,35 +80,150 @@ func (c *Client) ListWindows() ([]Window, error) {
 	return windows, nil
 }
 
-// FocusWindow sets input focus to the specified window
+// ListWindowsWithDetails is like ListWindows but also fills in each window's
+// geometry, PID, workspace and state, which costs several round trips per
+// window
+func (c *Client) ListWindowsWithDetails() ([]Window, error) {
+	return c.ListWindowsWithDetailsContext(context.Background())
+}
+
+// ListWindowsWithDetailsContext is like ListWindowsWithDetails but gives up
+// when ctx is done
+func (c *Client) ListWindowsWithDetailsContext(ctx context.Context) ([]Window, error) {
+	windows, err := c.ListWindowsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range windows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// A window can vanish while listed; keep it without details
+		c.addWindowDetails(ctx, &windows[i])
+	}
+	return windows, nil
+}
+
+// FocusWindow raises the specified window and gives it the input focus.
+// Under an EWMH window manager it asks the WM to activate the window, the
+// way a taskbar does, and only sets the focus itself if the WM doesn't
+// comply.
 func (c *Client) FocusWindow(windowID x.Window) error {
+	return c.FocusWindowContext(context.Background(), windowID)
+}
+
+// FocusWindowContext is like FocusWindow but gives up when ctx is done
+func (c *Client) FocusWindowContext(ctx context.Context, windowID x.Window) error {
+	// Windows that aren't shown can't take the focus; bring them back first
+	if err := c.checkVisible(ctx, windowID); err != nil {
+		hidden, ok := err.(*WindowHiddenError)
+		if !ok || hidden.Reason == HiddenUnmapped {
+			return err
+		}
+		if err := c.restoreWindow(ctx, windowID, hidden); err != nil {
+			return err
+		}
+	}
+
+	// Reparenting WMs such as i3 and openbox undo focus set behind their
+	// back, so ask the WM first
+	app := c.clientWindow(ctx, windowID)
+	if active, err := c.activateWindow(ctx, app, windowID); err != nil || active {
+		return err
+	}
+
 	// First, try to raise the window
 	values := []uint32{x.StackModeAbove}
-	x.ConfigureWindowChecked(c.conn, windowID, x.ConfigWindowStackMode, values).Check(c.conn)
+	err := awaitCheck(c, ctx, func() error {
+		return x.ConfigureWindowChecked(c.conn, windowID, x.ConfigWindowStackMode, values).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to raise window %d: %w", windowID, err)
+	}
 	
 	// Set input focus
-	x.SetInputFocus(c.conn, x.InputFocusPointerRoot, windowID, x.TimeCurrentTime)
-	
-	// Ensure the commands are sent
-	// Note: go-x11-client doesn't have Sync, but commands are sent immediately
+	err = awaitCheck(c, ctx, func() error {
+		return x.SetInputFocusChecked(c.conn, x.InputFocusPointerRoot, app, x.TimeCurrentTime).Check(c.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to focus window %d: %w", windowID, err)
+	}
 	
 	return nil
 }
 
+// FocusedWindow returns the top-level window that has the input focus
+func (c *Client) FocusedWindow() (x.Window, error) {
+	return c.FocusedWindowContext(context.Background())
+}
+
+// FocusedWindowContext is like FocusedWindow but gives up when ctx is done.
+// It asks the window manager via _NET_ACTIVE_WINDOW first and falls back to
+// the input focus, walking up to the window's top-level ancestor.
+func (c *Client) FocusedWindowContext(ctx context.Context) (x.Window, error) {
+	if active, ok := c.getUint32Property(ctx, c.root, c.getAtom(ctx, "_NET_ACTIVE_WINDOW"), x.AtomWindow); ok && active != 0 {
+		return x.Window(active), nil
+	}
+
+	focus, err := await(c, ctx, func() (*x.GetInputFocusReply, error) {
+		return x.GetInputFocus(c.conn).Reply(c.conn)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get input focus: %w", err)
+	}
+	win := focus.Focus
+	if win == x.None || win == x.InputFocusPointerRoot || win == c.root {
+		return 0, fmt.Errorf("no window has the input focus")
+	}
+
+	for {
+		tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+			return x.QueryTree(c.conn, win).Reply(c.conn)
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to query window tree: %w", err)
+		}
+		if tree.Parent == c.root || tree.Parent == x.None {
+			return win, nil
+		}
+		win = tree.Parent
+	}
+}
+
+// windowRect returns the window's geometry in root coordinates
+func (c *Client) windowRect(ctx context.Context, win x.Window) (image.Rectangle, error) {
+	geom, err := await(c, ctx, func() (*x.GetGeometryReply, error) {
+		return x.GetGeometry(c.conn, x.Drawable(win)).Reply(c.conn)
+	})
+	if err != nil {
+		return image.Rectangle{}, fmt.Errorf("failed to get window geometry: %w", err)
+	}
+
+	trans, err := await(c, ctx, func() (*x.TranslateCoordinatesReply, error) {
+		return x.TranslateCoordinates(c.conn, win, c.root, 0, 0).Reply(c.conn)
+	})
+	if err != nil {
+		return image.Rectangle{}, fmt.Errorf("failed to translate window coordinates: %w", err)
+	}
+
+	x0, y0 := int(trans.DstX), int(trans.DstY)
+	return image.Rect(x0, y0, x0+int(geom.Width), y0+int(geom.Height)), nil
+}
+
 // getWindowName retrieves the window name
-func (c *Client) getWindowName(win x.Window) string {
+func (c *Client) getWindowName(ctx context.Context, win x.Window) string {
 	// Try _NET_WM_NAME first (UTF-8)
-	netWmName := c.getAtom("_NET_WM_NAME")
+	netWmName := c.getAtom(ctx, "_NET_WM_NAME")
 	if netWmName != 0 {
-		if name := c.getStringProperty(win, netWmName); name != "" {
+		if name := c.getStringProperty(ctx, win, netWmName); name != "" {
 			return name
 		}
 	}
 	
 	// Fall back to WM_NAME
-	wmName := c.getAtom("WM_NAME")
+	wmName := c.getAtom(ctx, "WM_NAME")
 	if wmName != 0 {
-		if name := c.getStringProperty(win, wmName); name != "" {
+		if name := c.getStringProperty(ctx, win, wmName); name != "" {
 			return name
 		}
 	}
@@ -96,32 +232,42 @@ func (c *Client) getWindowName(win x.Window) string {
 }
 
 // getWindowClass retrieves the window class
-func (c *Client) getWindowClass(win x.Window) string {
-	wmClass := c.getAtom("WM_CLASS")
+func (c *Client) getWindowClass(ctx context.Context, win x.Window) string {
+	instance, class := c.getWMClass(ctx, win)
+	if class != "" {
+		return class
+	}
+	return instance // Return the instance name if class is empty
+}
+
+// getWMClass retrieves both names of WM_CLASS, the instance and the class
+func (c *Client) getWMClass(ctx context.Context, win x.Window) (instance, class string) {
+	wmClass := c.getAtom(ctx, "WM_CLASS")
 	if wmClass == 0 {
-		return ""
+		return "", ""
 	}
 	cookie := x.GetProperty(c.conn, false, win, wmClass, x.GetPropertyTypeAny, 0, 2048)
-	reply, err := cookie.Reply(c.conn)
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
 	if err != nil || len(reply.Value) == 0 {
-		return ""
+		return "", ""
 	}
 	
 	// WM_CLASS contains two null-terminated strings
 	parts := strings.Split(string(reply.Value), "\x00")
-	if len(parts) >= 2 && parts[1] != "" {
-		return parts[1] // Return the class name (second part)
-	} else if len(parts) >= 1 && parts[0] != "" {
-		return parts[0] // Return the instance name if class is empty
+	if len(parts) >= 2 {
+		class = parts[1]
 	}
-	
-	return ""
+	return parts[0], class
 }
 
 // getStringProperty gets a string property from a window
-func (c *Client) getStringProperty(win x.Window, prop x.Atom) string {
+func (c *Client) getStringProperty(ctx context.Context, win x.Window, prop x.Atom) string {
 	cookie := x.GetProperty(c.conn, false, win, prop, x.GetPropertyTypeAny, 0, 2048)
-	reply, err := cookie.Reply(c.conn)
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
 	if err != nil || len(reply.Value) == 0 {
 		return ""
 	}
@@ -131,10 +277,167 @@ func (c *Client) getStringProperty(win x.Window, prop x.Atom) string {
 	return strings.TrimSpace(str)
 }
 
+// getCardinalProperty reads the first CARDINAL value of a property
+func (c *Client) getCardinalProperty(ctx context.Context, win x.Window, prop x.Atom) (uint32, bool) {
+	return c.getUint32Property(ctx, win, prop, x.AtomCardinal)
+}
+
+// getUint32Property reads the first 32-bit value of a property of the given type
+func (c *Client) getUint32Property(ctx context.Context, win x.Window, prop, propType x.Atom) (uint32, bool) {
+	cookie := x.GetProperty(c.conn, false, win, prop, propType, 0, 1)
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return 0, false
+	}
+	return uint32(reply.Value[0]) | uint32(reply.Value[1])<<8 |
+		uint32(reply.Value[2])<<16 | uint32(reply.Value[3])<<24, true
+}
+
+// getUint32Values reads up to n 32-bit values of a property of the given type
+func (c *Client) getUint32Values(ctx context.Context, win x.Window, prop, propType x.Atom, n int) []uint32 {
+	cookie := x.GetProperty(c.conn, false, win, prop, propType, 0, uint32(n))
+	reply, err := await(c, ctx, func() (*x.GetPropertyReply, error) {
+		return cookie.Reply(c.conn)
+	})
+	if err != nil || reply.Format != 32 {
+		return nil
+	}
+	values := make([]uint32, 0, len(reply.Value)/4)
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		values = append(values, uint32(reply.Value[i])|uint32(reply.Value[i+1])<<8|
+			uint32(reply.Value[i+2])<<16|uint32(reply.Value[i+3])<<24)
+	}
+	return values
+}
+
+// currentWorkspace returns the name of the current workspace, asking i3 if
+// it is connected and falling back to the EWMH current desktop
+func (c *Client) currentWorkspace(ctx context.Context) string {
+	if c.I3Enabled() {
+		if name, err := c.I3FocusedWorkspace(ctx); err == nil {
+			return name
+		}
+	}
+
+	desktop, ok := c.getCardinalProperty(ctx, c.root, c.getAtom(ctx, "_NET_CURRENT_DESKTOP"))
+	if !ok {
+		return ""
+	}
+
+	return c.desktopName(ctx, desktop)
+}
+
+// DetectWM returns the name of the running EWMH-compliant window manager,
+// found through _NET_SUPPORTING_WM_CHECK. If the WM doesn't advertise itself,
+// the window manager we started (if any) is reported instead.
+func (c *Client) DetectWM(ctx context.Context) string {
+	if name := c.ewmhWMName(ctx); name != "" {
+		return name
+	}
+	if c.wmName != "" {
+		return c.wmName
+	}
+	if c.I3Enabled() {
+		return "i3"
+	}
+	return ""
+}
+
+// ewmhCheckWindow returns the WM's _NET_SUPPORTING_WM_CHECK window, which
+// exists only while an EWMH-compliant window manager is running
+func (c *Client) ewmhCheckWindow(ctx context.Context) (x.Window, bool) {
+	checkAtom := c.getAtom(ctx, "_NET_SUPPORTING_WM_CHECK")
+	check, ok := c.getUint32Property(ctx, c.root, checkAtom, x.AtomWindow)
+	if !ok || check == 0 {
+		return 0, false
+	}
+
+	// The check window must point at itself, otherwise it is stale
+	self, ok := c.getUint32Property(ctx, x.Window(check), checkAtom, x.AtomWindow)
+	if !ok || self != check {
+		return 0, false
+	}
+	return x.Window(check), true
+}
+
+// ewmhWMName reads _NET_WM_NAME from the WM's supporting check window
+func (c *Client) ewmhWMName(ctx context.Context) string {
+	check, ok := c.ewmhCheckWindow(ctx)
+	if !ok {
+		return ""
+	}
+	if name := c.getStringProperty(ctx, check, c.getAtom(ctx, "_NET_WM_NAME")); name != "" {
+		return name
+	}
+	return c.getStringProperty(ctx, check, x.AtomWMName)
+}
+
+// clientWindow returns the application window for a top-level window. Under
+// reparenting window managers the top-level window is the WM's frame, and the
+// application window is the descendant carrying WM_STATE.
+func (c *Client) clientWindow(ctx context.Context, win x.Window) x.Window {
+	wmState := c.getAtom(ctx, "WM_STATE")
+	if wmState == 0 {
+		return win
+	}
+	if _, ok := c.getUint32Property(ctx, win, wmState, wmState); ok {
+		return win
+	}
+
+	tree, err := await(c, ctx, func() (*x.QueryTreeReply, error) {
+		return x.QueryTree(c.conn, win).Reply(c.conn)
+	})
+	if err != nil {
+		return win
+	}
+	for _, child := range tree.Children {
+		if _, ok := c.getUint32Property(ctx, child, wmState, wmState); ok {
+			return child
+		}
+	}
+	return win
+}
+
+// sameClient returns true if windows a and b were created by the same X
+// client, which the server tells by the resource ID base in their IDs
+func (c *Client) sameClient(a, b x.Window) bool {
+	mask := c.conn.GetSetup().ResourceIdMask
+	return uint32(a)&^mask == uint32(b)&^mask
+}
+
+// ownWindow returns true if this client created win, like the outlines of
+// Highlight
+func (c *Client) ownWindow(win x.Window) bool {
+	setup := c.conn.GetSetup()
+	return uint32(win)&^setup.ResourceIdMask == setup.ResourceIdBase
+}
+
+// sendClientMessage sends a 32-bit client message about win to the root
+// window, the way EWMH requests to the window manager are made
+func (c *Client) sendClientMessage(ctx context.Context, win x.Window, msgType x.Atom, data [5]uint32) error {
+	var payload x.ClientMessageData
+	payload.SetData32(&data)
+	w := x.NewWriter()
+	x.WriteClientMessageEvent(w, &x.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   msgType,
+		Data:   payload,
+	})
+	return awaitCheck(c, ctx, func() error {
+		return x.SendEventChecked(c.conn, false, c.root,
+			x.EventMaskSubstructureNotify|x.EventMaskSubstructureRedirect, w.Bytes()).Check(c.conn)
+	})
+}
+
 // getAtom gets or creates an atom
-func (c *Client) getAtom(name string) x.Atom {
+func (c *Client) getAtom(ctx context.Context, name string) x.Atom {
 	cookie := x.InternAtom(c.conn, false, name)
-	reply, err := cookie.Reply(c.conn)
+	reply, err := await(c, ctx, func() (*x.InternAtomReply, error) {
+		return cookie.Reply(c.conn)
+	})
 	if err != nil {
 		return 0
 	}
@@ -180,4 +180,26 @@ func TestWindowManagerStartup(t *testing.T) {
 	}
 
 	t.Logf("Windows with WM: %d", len(windows2))
-}
\ No newline at end of file
+}
+
+func TestFocusWindowInvalid(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{
+		StartXvfb:  true,
+		Resolution: "800x600",
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// A window ID that doesn't exist must produce BadWindow instead of nil
+	if err := client.FocusWindow(x.Window(0x7fffffff)); err == nil {
+		t.Error("expected error when focusing a nonexistent window")
+	} else {
+		t.Logf("Got expected error: %v", err)
+	}
+}
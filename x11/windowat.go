@@ -0,0 +1,63 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// WindowAt is the window WindowAtPoint found at a point
+type WindowAt struct {
+	Window                  // Application window with its title and class; ID 0 if only the desktop is there
+	Frame   x.Window        // Top-level window containing the point, the WM's frame if it reparents
+	Deepest x.Window        // Innermost subwindow containing the point, which receives a click
+	Rect    image.Rectangle // Geometry of the application window in root coordinates
+	PID     uint32          // _NET_WM_PID of the application window, 0 if unset
+}
+
+// WindowAtPoint returns the topmost window at (px, py) in root coordinates,
+// the one a click there would hit. Override-redirect windows like menus and
+// tooltips count. If no window covers the point the result has ID 0.
+func (c *Client) WindowAtPoint(ctx context.Context, px, py int) (WindowAt, error) {
+	if _, _, err := validatePoint(float64(px), float64(py), c.screenBounds(), "screen"); err != nil {
+		return WindowAt{}, err
+	}
+
+	// TranslateCoordinates reports the mapped child containing the point,
+	// topmost in stacking order; repeat it to descend to the deepest one
+	var result WindowAt
+	parent, x0, y0 := c.root, int16(px), int16(py)
+	for {
+		trans, err := await(c, ctx, func() (*x.TranslateCoordinatesReply, error) {
+			return x.TranslateCoordinates(c.conn, c.root, parent, x0, y0).Reply(c.conn)
+		})
+		if err != nil {
+			return WindowAt{}, fmt.Errorf("failed to translate coordinates: %w", err)
+		}
+		if trans.Child == x.None {
+			break
+		}
+		if result.Frame == 0 {
+			result.Frame = trans.Child
+		}
+		result.Deepest = trans.Child
+		parent = trans.Child
+	}
+	if result.Frame == 0 {
+		return result, nil
+	}
+
+	app := c.clientWindow(ctx, result.Frame)
+	result.ID = app
+	result.Title = c.getWindowName(ctx, app)
+	result.Class = c.getWindowClass(ctx, app)
+	result.PID, _ = c.getCardinalProperty(ctx, app, c.getAtom(ctx, "_NET_WM_PID"))
+	rect, err := c.windowRect(ctx, app)
+	if err != nil {
+		return WindowAt{}, err
+	}
+	result.Rect = rect
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package x11
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestWindowAtPoint(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	if _, err := exec.LookPath("xterm"); err != nil {
+		t.Skip("xterm not available")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	pid, err := client.StartApp("xterm", []string{"-geometry", "40x10+50+60"})
+	if err != nil {
+		t.Fatalf("Failed to start xterm: %v", err)
+	}
+	defer client.StopApp(pid)
+	client.Wait(1000)
+
+	windows, err := client.ListWindows()
+	if err != nil || len(windows) == 0 {
+		t.Fatalf("no windows found: %v", err)
+	}
+
+	ctx := context.Background()
+	rect, err := client.windowRect(ctx, windows[0].ID)
+	if err != nil {
+		t.Fatalf("windowRect failed: %v", err)
+	}
+	center := rect.Min.Add(rect.Size().Div(2))
+	got, err := client.WindowAtPoint(ctx, center.X, center.Y)
+	if err != nil {
+		t.Fatalf("WindowAtPoint failed: %v", err)
+	}
+	if got.ID != windows[0].ID || got.Rect != rect || got.Class == "" {
+		t.Errorf("WindowAtPoint(%v) = %+v, want window 0x%x at %v", center, got, uint32(windows[0].ID), rect)
+	}
+
+	// Without a window manager nothing covers the far corner
+	if got, err := client.WindowAtPoint(ctx, 799, 599); err != nil || got.ID != 0 {
+		t.Errorf("WindowAtPoint(799, 599) = %+v, %v; want only the desktop", got, err)
+	}
+	if _, err := client.WindowAtPoint(ctx, 800, 10); err == nil {
+		t.Error("expected an error for a point off screen")
+	}
+}
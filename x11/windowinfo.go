@@ -0,0 +1,60 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// allWorkspaces is the _NET_WM_DESKTOP of windows shown on every workspace
+const allWorkspaces = 0xFFFFFFFF
+
+// GetWindowInfo returns the title, class, geometry, PID, workspace and state
+// of a window
+func (c *Client) GetWindowInfo(win x.Window) (Window, error) {
+	return c.GetWindowInfoContext(context.Background(), win)
+}
+
+// GetWindowInfoContext is like GetWindowInfo but gives up when ctx is done.
+// Frames of reparenting window managers are resolved to the application
+// window for the properties; the geometry is that of win itself.
+func (c *Client) GetWindowInfoContext(ctx context.Context, win x.Window) (Window, error) {
+	info := Window{ID: win}
+	if err := c.addWindowDetails(ctx, &info); err != nil {
+		return Window{}, fmt.Errorf("failed to get info of window 0x%x: %w", uint32(win), err)
+	}
+	app := c.clientWindow(ctx, win)
+	info.Title = c.getWindowName(ctx, app)
+	info.Class = c.getWindowClass(ctx, app)
+	return info, nil
+}
+
+// addWindowDetails fills in the geometry of w.ID and the PID, workspace and
+// state of its application window. Properties that can't be read are left
+// unset; only failing to get the geometry is an error.
+func (c *Client) addWindowDetails(ctx context.Context, w *Window) error {
+	w.Workspace = -1
+	rect, err := c.windowRect(ctx, w.ID)
+	if err != nil {
+		return err
+	}
+	w.X, w.Y, w.Width, w.Height = rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()
+	app := c.clientWindow(ctx, w.ID)
+	w.PID, _ = c.getCardinalProperty(ctx, app, c.getAtom(ctx, "_NET_WM_PID"))
+	if desktop, ok := c.getCardinalProperty(ctx, app, c.getAtom(ctx, "_NET_WM_DESKTOP")); ok && desktop != allWorkspaces {
+		w.Workspace = int(desktop)
+	}
+	for _, atom := range c.getUint32Values(ctx, app, c.getAtom(ctx, "_NET_WM_STATE"), x.AtomAtom, 32) {
+		if name := c.atomName(ctx, x.Atom(atom)); name != "" {
+			w.State = append(w.State, strings.TrimPrefix(name, "_NET_WM_STATE_"))
+		}
+	}
+	return nil
+}
+
+// Geometry formats the window's geometry as WIDTHxHEIGHT+X+Y
+func (w Window) Geometry() string {
+	return fmt.Sprintf("%dx%d+%d+%d", w.Width, w.Height, w.X, w.Y)
+}
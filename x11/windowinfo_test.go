@@ -0,0 +1,88 @@
+package x11
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestWindowGeometry(t *testing.T) {
+	tests := []struct {
+		win  Window
+		want string
+	}{
+		{Window{X: 10, Y: 20, Width: 300, Height: 200}, "300x200+10+20"},
+		{Window{X: -5, Y: 0, Width: 800, Height: 600}, "800x600+-5+0"},
+		{Window{}, "0x0+0+0"},
+	}
+	for _, tt := range tests {
+		if got := tt.win.Geometry(); got != tt.want {
+			t.Errorf("Geometry() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestGetWindowInfo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	xid, err := client.conn.AllocID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	win := x.Window(xid)
+	err = x.CreateWindowChecked(client.conn, 0, win, client.root, 40, 30, 300, 200, 0,
+		x.WindowClassInputOutput, 0, 0, nil).Check(client.conn)
+	if err != nil {
+		t.Fatalf("Failed to create window: %v", err)
+	}
+	defer x.DestroyWindow(client.conn, win)
+	x.ChangeProperty(client.conn, x.PropModeReplace, win, client.getAtom(ctx, "WM_CLASS"), x.AtomString, 8, []byte("info\x00Info\x00"))
+	x.ChangeProperty(client.conn, x.PropModeReplace, win, x.AtomWMName, x.AtomString, 8, []byte("Info window"))
+	x.ChangeProperty(client.conn, x.PropModeReplace, win, client.getAtom(ctx, "_NET_WM_PID"), x.AtomCardinal, 32, []byte{0x39, 0x30, 0, 0})
+	state := client.getAtom(ctx, "_NET_WM_STATE_MAXIMIZED_VERT")
+	x.ChangeProperty(client.conn, x.PropModeReplace, win, client.getAtom(ctx, "_NET_WM_STATE"), x.AtomAtom, 32,
+		[]byte{byte(state), byte(state >> 8), byte(state >> 16), byte(state >> 24)})
+	if err := x.MapWindowChecked(client.conn, win).Check(client.conn); err != nil {
+		t.Fatalf("Failed to map window: %v", err)
+	}
+
+	info, err := client.GetWindowInfo(win)
+	if err != nil {
+		t.Fatalf("GetWindowInfo failed: %v", err)
+	}
+	if info.Title != "Info window" || info.Class != "Info" || info.Geometry() != "300x200+40+30" {
+		t.Errorf("GetWindowInfo = %+v, want the window's title, class and geometry", info)
+	}
+	if info.PID != 12345 || info.Workspace != -1 || !slices.Equal(info.State, []string{"MAXIMIZED_VERT"}) {
+		t.Errorf("GetWindowInfo = %+v, want PID 12345, no workspace and state MAXIMIZED_VERT", info)
+	}
+
+	windows, err := client.ListWindowsWithDetails()
+	if err != nil {
+		t.Fatalf("ListWindowsWithDetails failed: %v", err)
+	}
+	found := slices.IndexFunc(windows, func(w Window) bool { return w.ID == win })
+	if found < 0 || windows[found].Geometry() != "300x200+40+30" || windows[found].PID != 12345 {
+		t.Errorf("ListWindowsWithDetails = %+v, want window 0x%x with its geometry and PID", windows, win)
+	}
+
+	gone := Window{ID: 0x1fffff}
+	if err := client.addWindowDetails(context.Background(), &gone); err == nil || gone.Workspace != -1 {
+		t.Errorf("addWindowDetails of a missing window = %v with workspace %d, want an error and -1", err, gone.Workspace)
+	}
+
+	if _, err := client.GetWindowInfo(0x1fffff); err == nil {
+		t.Error("GetWindowInfo of a missing window succeeded")
+	}
+}
@@ -0,0 +1,134 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// birthSettle is how long a newly mapped window gets to draw itself before
+// its thumbnail is taken again; the first capture right away catches
+// windows that close again quickly
+const birthSettle = 150 * time.Millisecond
+
+// WindowEvent is a change to the top-level windows seen by WatchWindowEvents
+type WindowEvent struct {
+	Time   time.Time
+	Kind   string   // "mapped", "unmapped", "destroyed" or "focused"
+	Window x.Window // Top-level window, the WM frame under reparenting WMs
+	Title  string   // Title of the application window, if known
+	Class  string   // WM_CLASS of the application window, if known
+	// Thumbnail is a PNG of a mapped window taken shortly after it
+	// appeared, if requested and the window was still shown
+	Thumbnail []byte
+}
+
+// birthThumbnail captures a newly mapped window at once and again after
+// birthSettle, when it has likely drawn itself, and returns the last capture
+// that succeeded
+func (c *Client) birthThumbnail(ctx context.Context, win x.Window, width int) []byte {
+	thumb, err := c.WindowThumbnailPNG(ctx, win, width)
+	if err != nil {
+		slog.Debug("failed to capture new window", "window", fmt.Sprintf("0x%x", uint32(win)), "err", err)
+	}
+	time.Sleep(birthSettle)
+	if settled, err := c.WindowThumbnailPNG(ctx, win, width); err == nil {
+		return settled
+	} else if thumb != nil {
+		slog.Debug("failed to capture new window again, keeping the first capture", "window", fmt.Sprintf("0x%x", uint32(win)), "err", err)
+	}
+	return thumb
+}
+
+// WatchWindowEvents calls fn whenever a top-level window is mapped, unmapped
+// or destroyed, and whenever the window manager's active window changes.
+// Titles and classes are looked up when a window is mapped or focused and
+// remembered for its later events, since a destroyed window can no longer be
+// asked. With thumbWidth above 0, mapped windows are captured as thumbnails
+// that wide before fn is called for them, so their event shows what
+// appeared even if it closed again. fn runs on its own goroutine for events
+// that need a title lookup, so a mapped event may arrive after later ones.
+func (c *Client) WatchWindowEvents(thumbWidth int, fn func(WindowEvent)) error {
+	if err := c.selectRootEvents(context.Background(), x.EventMaskSubstructureNotify|x.EventMaskPropertyChange); err != nil {
+		return err
+	}
+	activeWindow := c.getAtom(context.Background(), "_NET_ACTIVE_WINDOW")
+
+	type names struct{ title, class string }
+	var mu sync.Mutex
+	seen := make(map[x.Window]names)
+	lookup := func(kind string, win x.Window, at time.Time) {
+		ctx := context.Background()
+		if kind == "focused" {
+			active, ok := c.getUint32Property(ctx, c.root, activeWindow, x.AtomWindow)
+			if !ok || active == 0 {
+				return
+			}
+			win = x.Window(active)
+		}
+		app := c.clientWindow(ctx, win)
+		known := names{title: c.getWindowName(ctx, app), class: c.getWindowClass(ctx, app)}
+		mu.Lock()
+		seen[win] = known
+		mu.Unlock()
+		ev := WindowEvent{Time: at, Kind: kind, Window: win, Title: known.title, Class: known.class}
+		if kind == "mapped" && thumbWidth > 0 {
+			ev.Thumbnail = c.birthThumbnail(ctx, win, thumbWidth)
+		}
+		fn(ev)
+	}
+	forget := func(kind string, win x.Window, at time.Time) {
+		mu.Lock()
+		known := seen[win]
+		if kind == "destroyed" {
+			delete(seen, win)
+		}
+		mu.Unlock()
+		fn(WindowEvent{Time: at, Kind: kind, Window: win, Title: known.title, Class: known.class})
+	}
+
+	c.addEventHandler(func(ev x.GenericEvent) {
+		now := time.Now()
+		switch ev.GetEventCode() {
+		case x.MapNotifyEventCode:
+			mev, err := x.NewMapNotifyEvent(ev)
+			if err != nil || mev.Event != c.root || mev.OverrideRedirect {
+				return
+			}
+			// Looking up the title needs replies, which must not block the event loop
+			go lookup("mapped", mev.Window, now)
+		case x.UnmapNotifyEventCode:
+			uev, err := x.NewUnmapNotifyEvent(ev)
+			if err != nil || uev.Event != c.root {
+				return
+			}
+			forget("unmapped", uev.Window, now)
+		case x.DestroyNotifyEventCode:
+			dev, err := x.NewDestroyNotifyEvent(ev)
+			if err != nil || dev.Event != c.root {
+				return
+			}
+			forget("destroyed", dev.Window, now)
+		case x.PropertyNotifyEventCode:
+			pev, err := x.NewPropertyNotifyEvent(ev)
+			if err != nil || pev.Window != c.root || pev.Atom != activeWindow {
+				return
+			}
+			go lookup("focused", 0, now)
+		}
+	})
+	return nil
+}
+
+// String describes the event for logs and tool results
+func (e WindowEvent) String() string {
+	s := fmt.Sprintf("%s window 0x%x", e.Kind, uint32(e.Window))
+	if e.Title != "" {
+		s += fmt.Sprintf(" %q", e.Title)
+	}
+	return e.Time.Format("15:04:05.000") + " " + s
+}
@@ -0,0 +1,22 @@
+package x11
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowEventString(t *testing.T) {
+	at := time.Date(2024, 1, 2, 15, 4, 5, 123e6, time.Local)
+	tests := []struct {
+		ev   WindowEvent
+		want string
+	}{
+		{WindowEvent{Time: at, Kind: "mapped", Window: 0x400001, Title: "Save As"}, `15:04:05.123 mapped window 0x400001 "Save As"`},
+		{WindowEvent{Time: at, Kind: "destroyed", Window: 0x400001}, "15:04:05.123 destroyed window 0x400001"},
+	}
+	for _, tt := range tests {
+		if got := tt.ev.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package x11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+	"go.i3wm.org/i3/v4"
+)
+
+// ignoredMods are lock modifiers that do not change which binding fires
+const ignoredMods = keysyms.ModMaskCapsLock | keysyms.ModMaskNumLock
+
+// WMBinding is a window manager key binding that a key press would trigger
+// instead of reaching the focused application
+type WMBinding struct {
+	Keys    string // The binding as the WM config writes it, e.g. "Mod1+Tab"
+	Command string // i3 command run by the binding, empty if only a grab was detected
+}
+
+// Routable returns true if the binding's action can be run over i3 IPC
+func (b WMBinding) Routable() bool {
+	return b.Command != ""
+}
+
+// i3Binding is a bindsym line from the i3 config
+type i3Binding struct {
+	WMBinding
+	mask   uint16
+	keysym x.Keysym
+}
+
+// i3Modifiers maps i3 modifier names to modifier masks
+var i3Modifiers = map[string]uint16{
+	"shift":   x.ModMaskShift,
+	"lock":    x.ModMaskLock,
+	"control": x.ModMaskControl,
+	"ctrl":    x.ModMaskControl,
+	"mod1":    x.ModMask1,
+	"mod2":    x.ModMask2,
+	"mod3":    x.ModMask3,
+	"mod4":    x.ModMask4,
+	"mod5":    x.ModMask5,
+}
+
+// resolveKeys turns a key name like "Tab" or a combo like "alt+Tab" into a
+// modifier mask and keysym, resolving names the same way KeyPress and
+// KeyCombo do
+func (c *Client) resolveKeys(keys string) (uint16, x.Keysym, error) {
+	modifiers, mainKey, err := parseKeyCombo(keys)
+	if err != nil {
+		keysym, err := c.keyNameToKeysym(keys)
+		return 0, keysym, err
+	}
+
+	var mask uint16
+	for _, mod := range modifiers {
+		mask |= mod.mask
+	}
+	mainKey = strings.ToLower(mainKey)
+	if len(mainKey) == 1 {
+		return mask, x.Keysym(mainKey[0]), nil
+	}
+	keysym, err := c.keyNameToKeysym(mainKey)
+	return mask, keysym, err
+}
+
+// WMBindingFor returns the window manager binding that pressing keys (a key
+// name or a combo like "alt+Tab") would trigger, or nil if the keys reach the
+// focused application. With i3 the binding is looked up in its config so it
+// can be routed; otherwise a key grab by another client is detected.
+func (c *Client) WMBindingFor(ctx context.Context, keys string) (*WMBinding, error) {
+	mask, keysym, err := c.resolveKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.I3Enabled() {
+		bindings, err := c.i3Bindings(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if b := matchI3Binding(bindings, mask, keysym); b != nil {
+			return &b.WMBinding, nil
+		}
+		return nil, nil
+	}
+
+	keycode, err := c.keysymToKeycode(ctx, keysym)
+	if err != nil {
+		// Keys that are not on the keyboard cannot have been grabbed
+		return nil, nil
+	}
+	grabbed, err := c.keyGrabbed(ctx, keycode, mask)
+	if err != nil || !grabbed {
+		return nil, err
+	}
+	return &WMBinding{Keys: keys}, nil
+}
+
+// RunWMBinding runs a binding's i3 command over IPC instead of pressing its keys
+func (c *Client) RunWMBinding(ctx context.Context, b WMBinding) (string, error) {
+	if !b.Routable() {
+		return "", fmt.Errorf("binding %s has no i3 command to run", b.Keys)
+	}
+	return c.I3CommandContext(ctx, b.Command)
+}
+
+// i3Bindings returns the default-mode key bindings of the running i3
+func (c *Client) i3Bindings(ctx context.Context) ([]i3Binding, error) {
+	config, err := i3Call(c, ctx, i3.GetConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get i3 config: %w", err)
+	}
+
+	// Newer i3 versions list every loaded file, the main one included
+	text := config.Config
+	if len(config.IncludedConfigs) > 0 {
+		var parts []string
+		for _, included := range config.IncludedConfigs {
+			parts = append(parts, included.VariableReplacedContents)
+		}
+		text = strings.Join(parts, "\n")
+	}
+	return parseI3Bindings(text), nil
+}
+
+// parseI3Bindings extracts the bindsym lines of the default mode from an i3
+// config, substituting variables set with "set $name value". Bindings in
+// other modes and bindings on unknown keys are skipped.
+func parseI3Bindings(config string) []i3Binding {
+	var bindings []i3Binding
+	vars := map[string]string{}
+	var names []string // Longest first so $mod does not clobber $modkey
+	depth := 0
+
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.Fields(line); fields[0] == "set" && len(fields) >= 3 && strings.HasPrefix(fields[1], "$") {
+			if _, ok := vars[fields[1]]; !ok {
+				names = append(names, fields[1])
+				sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+			}
+			vars[fields[1]] = substituteVars(strings.Join(fields[2:], " "), names, vars)
+			continue
+		}
+		line = substituteVars(line, names, vars)
+
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasSuffix(line, "{"):
+			depth++
+			continue
+		case line == "}":
+			depth = max(depth-1, 0)
+			continue
+		case depth > 0:
+			continue
+		case fields[0] != "bindsym":
+			continue
+		}
+
+		// Skip options like --release before the key
+		rest := fields[1:]
+		for len(rest) > 0 && strings.HasPrefix(rest[0], "--") {
+			rest = rest[1:]
+		}
+		if len(rest) < 2 {
+			continue
+		}
+		mask, keysym, ok := parseI3Keys(rest[0])
+		if !ok {
+			continue
+		}
+		bindings = append(bindings, i3Binding{
+			WMBinding: WMBinding{Keys: rest[0], Command: strings.Join(rest[1:], " ")},
+			mask:      mask,
+			keysym:    keysym,
+		})
+	}
+	return bindings
+}
+
+// substituteVars replaces i3 variables in a line, trying longer names first
+func substituteVars(line string, names []string, vars map[string]string) string {
+	for _, name := range names {
+		line = strings.ReplaceAll(line, name, vars[name])
+	}
+	return line
+}
+
+// parseI3Keys parses an i3 key spec like "Mod4+Shift+Return"
+func parseI3Keys(spec string) (uint16, x.Keysym, bool) {
+	parts := strings.Split(spec, "+")
+	var mask uint16
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := i3Modifiers[strings.ToLower(part)]
+		if !ok {
+			return 0, 0, false
+		}
+		mask |= mod
+	}
+
+	name := parts[len(parts)-1]
+	keysym, ok := keysyms.StringToKeysym(name)
+	if !ok {
+		if len(name) != 1 {
+			return 0, 0, false
+		}
+		keysym = x.Keysym(name[0])
+	}
+	return mask, keysym, true
+}
+
+// matchI3Binding returns the binding for a mask and keysym, ignoring lock
+// modifiers and letter case as i3 does
+func matchI3Binding(bindings []i3Binding, mask uint16, keysym x.Keysym) *i3Binding {
+	lower, _ := keysyms.ConvertCase(keysym)
+	for i, b := range bindings {
+		bLower, _ := keysyms.ConvertCase(b.keysym)
+		if bLower == lower && b.mask&^ignoredMods == mask&^ignoredMods {
+			return &bindings[i]
+		}
+	}
+	return nil
+}
+
+// keyGrabbed returns true if another client holds a passive grab on the key
+// with these modifiers on the root window. It probes by grabbing the key,
+// which the server refuses with BadAccess when the combination is taken.
+func (c *Client) keyGrabbed(ctx context.Context, keycode x.Keycode, mask uint16) (bool, error) {
+	// Our own emergency stop grab would be replaced, not refused
+	c.abort.mu.Lock()
+	own := c.abort.hotkey == keycode && c.abort.hotkeyMask == mask
+	c.abort.mu.Unlock()
+	if own {
+		return false, nil
+	}
+
+	err := awaitCheck(c, ctx, func() error {
+		return x.GrabKeyChecked(c.conn, false, c.root, mask, keycode,
+			x.GrabModeAsync, x.GrabModeAsync).Check(c.conn)
+	})
+	var xerr *x.Error
+	if errors.As(err, &xerr) && xerr.Code == x.AccessErrorCode {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to probe key grab: %w", err)
+	}
+
+	err = awaitCheck(c, ctx, func() error {
+		return x.UngrabKeyChecked(c.conn, keycode, c.root, mask).Check(c.conn)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to release probe grab: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,101 @@
+package x11
+
+import (
+	"testing"
+
+	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/util/keysyms"
+)
+
+const testI3Config = `
+# i3 config
+set $mod Mod1
+set $modkey Mod4
+
+bindsym $mod+Return exec i3-sensible-terminal
+bindsym $mod+Shift+q kill
+bindsym --release $modkey+x exec xdotool key --clearmodifiers ctrl+x
+bindsym $mod+Tab focus right
+bindsym button2 kill
+bindsym $mod+NoSuchKey nop
+
+mode "resize" {
+	bindsym Left resize shrink width 10 px
+	bindsym Return mode "default"
+}
+
+bar {
+	status_command i3status
+}
+
+bindsym $mod+r mode "resize"
+`
+
+func TestParseI3Bindings(t *testing.T) {
+	bindings := parseI3Bindings(testI3Config)
+
+	want := []struct {
+		keys    string
+		command string
+		mask    uint16
+		keysym  x.Keysym
+	}{
+		{"Mod1+Return", "exec i3-sensible-terminal", x.ModMask1, keysyms.XK_Return},
+		{"Mod1+Shift+q", "kill", x.ModMask1 | x.ModMaskShift, keysyms.XK_q},
+		{"Mod4+x", "exec xdotool key --clearmodifiers ctrl+x", x.ModMask4, keysyms.XK_x},
+		{"Mod1+Tab", "focus right", x.ModMask1, keysyms.XK_Tab},
+		{"Mod1+r", `mode "resize"`, x.ModMask1, keysyms.XK_r},
+	}
+	if len(bindings) != len(want) {
+		t.Fatalf("got %d bindings, want %d: %+v", len(bindings), len(want), bindings)
+	}
+	for i, w := range want {
+		b := bindings[i]
+		if b.Keys != w.keys || b.Command != w.command || b.mask != w.mask || b.keysym != w.keysym {
+			t.Errorf("binding %d = %+v, want %+v", i, b, w)
+		}
+	}
+}
+
+func TestMatchI3Binding(t *testing.T) {
+	bindings := parseI3Bindings(testI3Config)
+	c := &Client{}
+
+	tests := []struct {
+		keys    string
+		command string // Empty if no binding should match
+	}{
+		{"alt+Tab", "focus right"},
+		{"alt+tab", "focus right"},
+		{"alt+shift+Q", "kill"},
+		{"alt+q", ""},
+		{"super+x", "exec xdotool key --clearmodifiers ctrl+x"},
+		{"ctrl+Tab", ""},
+		{"Return", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keys, func(t *testing.T) {
+			mask, keysym, err := c.resolveKeys(tt.keys)
+			if err != nil {
+				t.Fatalf("resolveKeys failed: %v", err)
+			}
+			b := matchI3Binding(bindings, mask|keysyms.ModMaskNumLock, keysym)
+			switch {
+			case tt.command == "" && b != nil:
+				t.Errorf("unexpected match %+v", b)
+			case tt.command != "" && b == nil:
+				t.Errorf("expected binding %q, got none", tt.command)
+			case b != nil && b.Command != tt.command:
+				t.Errorf("command = %q, want %q", b.Command, tt.command)
+			}
+		})
+	}
+}
+
+func TestParseI3KeysRejects(t *testing.T) {
+	for _, spec := range []string{"Hyper+a", "Mod1+NoSuchKey", "button1"} {
+		if _, _, ok := parseI3Keys(spec); ok {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
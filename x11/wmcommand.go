@@ -0,0 +1,117 @@
+package x11
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// wmConfigArgs are the arguments window managers take before a config file
+var wmConfigArgs = map[string]string{
+	"i3":           "-c",
+	"awesome":      "-c",
+	"bspwm":        "-c",
+	"herbstluftwm": "-c",
+	"icewm":        "-c",
+	"openbox":      "--config-file",
+	"fluxbox":      "-rc",
+	"jwm":          "-f",
+}
+
+// WMCommand splits WMName into a program and arguments with shell quoting
+// rules, then appends WMArgs and, if WMConfig is set, the window manager's
+// option for loading that config file
+func (opts ConnectOptions) WMCommand() (string, []string, error) {
+	parts, err := SplitCommand(opts.WMName)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid window manager command: %w", err)
+	}
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("empty window manager command")
+	}
+	program, args := parts[0], append(parts[1:], opts.WMArgs...)
+
+	if opts.WMConfig != "" {
+		flag, ok := wmConfigArgs[filepath.Base(program)]
+		if !ok {
+			names := make([]string, 0, len(wmConfigArgs))
+			for name := range wmConfigArgs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "", nil, fmt.Errorf("don't know how to pass a config file to %s (supported: %s); pass it in the command instead",
+				program, strings.Join(names, ", "))
+		}
+		if _, err := os.Stat(opts.WMConfig); err != nil {
+			return "", nil, fmt.Errorf("failed to read window manager config: %w", err)
+		}
+		args = append(args, flag, opts.WMConfig)
+	}
+	return program, args, nil
+}
+
+// SplitCommand splits a command line into words like a POSIX shell, without
+// expansions: words are separated by unquoted whitespace, single quotes keep
+// everything literal, and in double quotes a backslash escapes only $, `,
+// ", \ and newline
+func SplitCommand(cmd string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(cmd); i++ {
+		ch := cmd[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case ch == '\\':
+			inWord = true
+			if i+1 < len(cmd) {
+				i++
+				if cmd[i] != '\n' { // Backslash-newline continues the line
+					word.WriteByte(cmd[i])
+				}
+			}
+		case ch == '\'':
+			inWord = true
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+		case ch == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(cmd); i++ {
+				if cmd[i] == '"' {
+					closed = true
+					break
+				}
+				if cmd[i] == '\\' && i+1 < len(cmd) && strings.IndexByte("$`\"\\\n", cmd[i+1]) >= 0 {
+					i++
+					if cmd[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(cmd[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			inWord = true
+			word.WriteByte(ch)
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,83 @@
+package x11
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		cmd     string
+		want    []string
+		wantErr bool
+	}{
+		{"i3 -a", []string{"i3", "-a"}, false},
+		{"  openbox   --sm-disable ", []string{"openbox", "--sm-disable"}, false},
+		{"", nil, false},
+		{`i3 -c "/home/me/my config"`, []string{"i3", "-c", "/home/me/my config"}, false},
+		{`i3 -c '/tmp/a "b"'`, []string{"i3", "-c", `/tmp/a "b"`}, false},
+		{`echo a\ b`, []string{"echo", "a b"}, false},
+		{`echo "a\"b" "\$x" "\n"`, []string{"echo", `a"b`, "$x", `\n`}, false},
+		{`echo ''`, []string{"echo", ""}, false},
+		{`pre"mid"'end'`, []string{"premidend"}, false},
+		{"a \\\nb", []string{"a", "b"}, false},
+		{`i3 -c "unterminated`, nil, true},
+		{`i3 -c 'unterminated`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			got, err := SplitCommand(tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitCommand(%q) error = %v, wantErr %v", tt.cmd, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitCommand(%q) = %q, want %q", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWMCommand(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "wm config")
+	if err := os.WriteFile(config, []byte("# empty\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     ConnectOptions
+		wantProg string
+		wantArgs []string
+		wantErr  string
+	}{
+		{"default", ConnectOptions{WMName: "i3 -a"}, "i3", []string{"-a"}, ""},
+		{"extra args", ConnectOptions{WMName: "openbox", WMArgs: []string{"--startup", "xterm -e top"}},
+			"openbox", []string{"--startup", "xterm -e top"}, ""},
+		{"i3 config", ConnectOptions{WMName: "/usr/bin/i3 -a", WMConfig: config}, "/usr/bin/i3", []string{"-a", "-c", config}, ""},
+		{"openbox config", ConnectOptions{WMName: "openbox", WMConfig: config}, "openbox", []string{"--config-file", config}, ""},
+		{"unknown config option", ConnectOptions{WMName: "dwm", WMConfig: config}, "", nil, "don't know how"},
+		{"missing config", ConnectOptions{WMName: "i3", WMConfig: config + ".missing"}, "", nil, "config"},
+		{"bad quoting", ConnectOptions{WMName: `i3 -c "x`}, "", nil, "quote"},
+		{"empty", ConnectOptions{WMName: "  "}, "", nil, "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, args, err := tt.opts.WMCommand()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("WMCommand() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WMCommand() error = %v", err)
+			}
+			if prog != tt.wantProg || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("WMCommand() = %q %q, want %q %q", prog, args, tt.wantProg, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.i3wm.org/i3/v4"
+)
+
+// i3WorkspaceName quotes a workspace name for an i3 command
+func i3WorkspaceName(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// findWorkspace returns the workspace numbered num and the focused one
+func findWorkspace(workspaces []i3.Workspace, num int) (target, focused i3.Workspace, ok bool) {
+	for _, ws := range workspaces {
+		if ws.Num == int64(num) && !ok {
+			target, ok = ws, true
+		}
+		if ws.Focused {
+			focused = ws
+		}
+	}
+	return target, focused, ok
+}
+
+// I3ScreenshotWorkspace captures i3 workspace number num without leaving
+// the current one for good: it switches there, waits settle for its windows
+// to repaint, captures the output the workspace is shown on and switches
+// back to the workspace focused before. It returns the capture, with bounds
+// in screen coordinates, and the workspace's name.
+func (c *Client) I3ScreenshotWorkspace(ctx context.Context, num int, settle time.Duration) (*image.RGBA, string, error) {
+	if err := c.checkFrozen(); err != nil {
+		return nil, "", err
+	}
+	if !c.I3Enabled() {
+		return nil, "", fmt.Errorf("i3 is not connected")
+	}
+	workspaces, err := i3Call(c, ctx, i3.GetWorkspaces)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get i3 workspaces: %w", err)
+	}
+	target, previous, ok := findWorkspace(workspaces, num)
+	if !ok {
+		// Switching would create it; an empty workspace has nothing to see
+		return nil, "", fmt.Errorf("i3 has no workspace %d", num)
+	}
+
+	if !target.Focused {
+		if err := c.i3Run(ctx, fmt.Sprintf("workspace number %d", num)); err != nil {
+			return nil, "", err
+		}
+		defer func() {
+			if err := c.i3Run(context.WithoutCancel(ctx), "workspace "+i3WorkspaceName(previous.Name)); err != nil {
+				slog.Warn("failed to return to the previous workspace", "workspace", previous.Name, "err", err)
+			}
+		}()
+		if target, err = c.waitFocusedWorkspace(ctx, num); err != nil {
+			return nil, "", err
+		}
+		if err := c.WaitContext(ctx, int(settle.Milliseconds())); err != nil {
+			return nil, "", err
+		}
+	}
+
+	rect := image.Rect(int(target.Rect.X), int(target.Rect.Y),
+		int(target.Rect.X+target.Rect.Width), int(target.Rect.Y+target.Rect.Height)).Intersect(c.screenBounds())
+	if rect.Empty() {
+		rect = c.screenBounds()
+	}
+	img, err := c.captureRect(ctx, rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy())
+	if err != nil {
+		return nil, "", err
+	}
+	img.Rect = rect
+	return img, target.Name, nil
+}
+
+// waitFocusedWorkspace waits up to restoreTimeout for workspace num to be
+// focused and returns it
+func (c *Client) waitFocusedWorkspace(ctx context.Context, num int) (i3.Workspace, error) {
+	deadline := time.Now().Add(restoreTimeout)
+	for {
+		workspaces, err := i3Call(c, ctx, i3.GetWorkspaces)
+		if err != nil {
+			return i3.Workspace{}, fmt.Errorf("failed to get i3 workspaces: %w", err)
+		}
+		if target, _, ok := findWorkspace(workspaces, num); ok && target.Focused {
+			return target, nil
+		}
+		if time.Now().After(deadline) {
+			return i3.Workspace{}, fmt.Errorf("workspace %d was not shown after switching to it", num)
+		}
+		if err := c.WaitContext(ctx, 20); err != nil {
+			return i3.Workspace{}, err
+		}
+	}
+}
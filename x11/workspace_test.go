@@ -0,0 +1,38 @@
+package x11
+
+import (
+	"testing"
+
+	"go.i3wm.org/i3/v4"
+)
+
+func TestI3WorkspaceName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"2: web", `"2: web"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`back\slash`, `"back\\slash"`},
+	}
+	for _, tt := range tests {
+		if got := i3WorkspaceName(tt.name); got != tt.want {
+			t.Errorf("i3WorkspaceName(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindWorkspace(t *testing.T) {
+	workspaces := []i3.Workspace{
+		{Num: 1, Name: "1", Focused: true},
+		{Num: 2, Name: "2: web"},
+		{Num: -1, Name: "notes"},
+	}
+	target, focused, ok := findWorkspace(workspaces, 2)
+	if !ok || target.Name != "2: web" || focused.Name != "1" {
+		t.Errorf("findWorkspace(2) = %q, %q, %v", target.Name, focused.Name, ok)
+	}
+	if _, _, ok := findWorkspace(workspaces, 3); ok {
+		t.Error("findWorkspace(3) found a workspace that doesn't exist")
+	}
+}
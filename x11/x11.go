@@ -1,13 +1,19 @@
 package x11
 
 import (
+	"context"
 	"fmt"
+	"image/png"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	x "github.com/linuxdeepin/go-x11-client"
+	"github.com/linuxdeepin/go-x11-client/ext/bigrequests"
 	"github.com/linuxdeepin/go-x11-client/ext/test"
 )
 
@@ -16,25 +22,76 @@ type Client struct {
 	conn        *x.Conn
 	screen      *x.Screen
 	root        x.Window
-	xvfbProcess *exec.Cmd // Track Xvfb if we started it
-	display     string    // The display we're connected to
-	i3Connected bool      // Whether i3 is available
+	xvfbProcess *exec.Cmd            // Track Xvfb if we started it
+	display     string               // The display we're connected to
+	wmName      string               // Window manager we started, if any
+	i3Connected bool                 // Whether i3 is available
+	timeout     time.Duration        // Default timeout for blocking X requests
+	pngLevel    png.CompressionLevel // Compression level for PNG screenshots
+	pngColors   string               // Color mode of PNG screenshots, see ParsePNGColors
+	typing      TypingProfile        // Keystroke pacing used by Type
+	motion      MotionProfile        // Pointer motion pacing of drags
+	observer    bool                 // XTEST was not initialized, input is refused
+	home        string               // Isolated home of launched programs, if any
+	homeEnv     map[string]string    // HOME and XDG variables pointing into home
+
+	maxRequestBytes int  // Largest request the server accepts, used to size capture tiles
+	restoreHidden   bool // EnsureVisible activates hidden windows instead of failing
+
+	events    eventDispatcher // Fans out X events to internal handlers
+	abort     abortState      // Emergency stop state
+	held      heldInput       // Keys and buttons currently faked down
+	trail     trailState      // Recent injected pointer events for recordings
+	i3        i3State         // i3 IPC connection state
+	frames    framePool       // Recycled capture buffers
+	stream    frameHub        // Shared capture loop for StreamFrames
+	damage    damageState     // DAMAGE reports of the root window, see WatchDamage
+	keymap    keymap          // Keycode/keysym lookup tables
+	apps      appTracker      // Programs started by StartApp
+	selection selectionState  // Window for reading selections
+	confine   confineState    // Region injected pointer motion is confined to
+	redaction Redaction       // Areas blacked out of every capture
+	pacing    pacingState     // X server latency and the input slowdown it calls for
+	menus     menuState       // Waiters for override-redirect windows to map
+	watches   regionWatches   // Running WatchRegion watches, stopped by Close
+	xErrors   atomic.Uint64   // X protocol errors received, see XErrors
+	xErrs     xErrorState     // Recent X errors and their handler, see XErrorSummary
 }
 
 // ScreenInfo contains display information
 type ScreenInfo struct {
-	Width  uint16
-	Height uint16
-	Root   x.Window
+	Width     uint16
+	Height    uint16
+	Root      x.Window
+	Depth     uint8  // Color depth of the root window
+	Display   string // X11 display string
+	WMName    string // Window manager, if known
+	Workspace string // Current workspace or desktop, if known
 }
 
 // ConnectOptions allows configuring the X11 connection
 type ConnectOptions struct {
-	Display      string // X11 display to use
-	StartXvfb    bool   // Whether to start Xvfb if no display
-	Resolution   string // Xvfb resolution (default: 1920x1080)
-	StartWM      bool   // Whether to start a window manager
-	WMName       string // Window manager command (default: "i3 -a")
+	Display        string        // X11 display to use
+	StartXvfb      bool          // Whether to start Xvfb if no display
+	Resolution     string        // Xvfb resolution (default: 1920x1080)
+	StartWM        bool          // Whether to start a window manager
+	WMName         string        // Window manager command with shell-style quoting (default: "i3 -a")
+	WMArgs         []string      // Extra window manager arguments, passed unsplit after those in WMName
+	WMConfig       string        // Config file for the window manager, passed with its config option
+	RequestTimeout time.Duration // Default timeout for blocking X requests (default: DefaultRequestTimeout)
+	I3Timeout      time.Duration // Timeout for i3 IPC calls (default: DefaultI3Timeout)
+	PNGCompression string        // PNG compression: fast, default, best or none (default: fast)
+	PNGColors      string        // PNG colors: full, palette or gray (default: full)
+	TypingProfile  string        // Typing profile: instant, fast or human (default: instant)
+	Motion         MotionProfile // Pointer motion of drags (default: DefaultMotionProfile)
+	Observer       bool          // Skip XTEST so servers without it work; input methods return ErrObserver
+	IsolatedHome   bool          // Give launched programs, the window manager included, a throwaway HOME
+	RestoreHidden  bool          // Restore minimized windows or switch workspaces for tools targeting them, instead of failing
+	Confine        string        // Confine pointer motion to a WIDTHxHEIGHT+X+Y region or a window ID (see ParseConfinement)
+	RedactRegions  []string      // WIDTHxHEIGHT+X+Y regions blacked out of every capture
+	RedactClasses  []string      // Window classes blacked out of every capture, e.g. KeePassXC
+	FixedPacing    bool          // Don't slow input down while the X server is slow to answer
+	XErrorLog      string        // How X errors are logged: unchecked, all or off (default: unchecked)
 }
 
 // Connect establishes a connection to the X server with default options
@@ -49,7 +106,55 @@ func Connect() (*Client, error) {
 
 // ConnectWithOptions establishes a connection to the X server with options
 func ConnectWithOptions(opts ConnectOptions) (*Client, error) {
-	client := &Client{}
+	client := &Client{timeout: opts.RequestTimeout, restoreHidden: opts.RestoreHidden}
+	client.pacing.fixed = opts.FixedPacing
+	client.i3.timeout = opts.I3Timeout
+
+	pngLevel, err := ParsePNGCompression(opts.PNGCompression)
+	if err != nil {
+		return nil, err
+	}
+	client.pngLevel = pngLevel
+
+	client.pngColors, err = ParsePNGColors(opts.PNGColors)
+	if err != nil {
+		return nil, err
+	}
+
+	xErrorLog, err := ParseXErrorLog(opts.XErrorLog)
+	if err != nil {
+		return nil, err
+	}
+	client.xErrs.handler = XErrorLogger(xErrorLog)
+
+	client.typing, err = ParseTypingProfile(opts.TypingProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	client.motion = DefaultMotionProfile
+	if opts.Motion != (MotionProfile{}) {
+		if err := client.SetMotionProfile(opts.Motion); err != nil {
+			return nil, err
+		}
+	}
+
+	confine, err := ParseConfinement(opts.Confine)
+	if err != nil {
+		return nil, err
+	}
+	client.redaction, err = ParseRedaction(opts.RedactRegions, opts.RedactClasses)
+	if err != nil {
+		return nil, err
+	}
+
+	var wmProgram string
+	var wmArgs []string
+	if opts.StartWM && opts.WMName != "" {
+		if wmProgram, wmArgs, err = opts.WMCommand(); err != nil {
+			return nil, err
+		}
+	}
 	
 	// Use provided display or environment variable
 	display := opts.Display
@@ -140,65 +245,118 @@ func ConnectWithOptions(opts ConnectOptions) (*Client, error) {
 
 	screen := &setup.Roots[0]
 
-	// Initialize XTEST extension
-	extReply, err := x.QueryExtension(conn, "XTEST").Reply(conn)
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to query XTEST extension: %w", err)
-	}
-	if !extReply.Present {
-		conn.Close()
-		return nil, fmt.Errorf("XTEST extension not present")
-	}
-
-	// Query XTEST version
-	cookie := test.GetVersion(conn, test.MajorVersion, test.MinorVersion)
-	_, err = cookie.Reply(conn)
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to get XTEST version: %w", err)
+	// Initialize XTEST extension, which observers don't need
+	client.observer = opts.Observer
+	if !opts.Observer {
+		if err := initXTest(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
 	}
 
 	client.conn = conn
 	client.screen = screen
+	// Errors of requests nobody waits for would otherwise pass unnoticed
+	conn.SetErrorCallback(func(err *x.Error) { client.recordXError(context.Background(), err, false) })
 	client.root = screen.Root
 	client.display = display
+	client.maxRequestBytes = int(setup.MaximumRequestLength) * 4
+
+	// Build the keymap up front and keep it current on layout changes
+	if err := client.loadKeymap(context.Background()); err != nil {
+		slog.Warn("failed to load keymap", "err", err)
+	}
+	client.watchKeymap()
+
+	// Enable BIG-REQUESTS so large screens can be captured in fewer tiles
+	if ext := conn.GetExtensionData(bigrequests.Ext()); ext != nil && ext.Present {
+		if reply, err := bigrequests.Enable(conn).Reply(conn); err == nil {
+			client.maxRequestBytes = int(reply.MaximumRequestLength) * 4
+		}
+	}
 	
+	if opts.IsolatedHome {
+		if err := client.setupIsolatedHome(); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
 	// Start window manager if requested
-	if opts.StartWM && opts.WMName != "" {
-		// Split the window manager command into program and args
-		parts := strings.Fields(opts.WMName)
-		if len(parts) > 0 {
-			program := parts[0]
-			args := parts[1:]
-			if _, err := client.StartApp(program, args); err != nil {
-				// Log warning but don't fail - window manager is optional
-				fmt.Fprintf(os.Stderr, "Warning: failed to start window manager %s: %v\n", opts.WMName, err)
-			}
-			
-			// If we started i3, wait a bit and try to connect
-			if strings.Contains(program, "i3") {
-				time.Sleep(500 * time.Millisecond)
-				if err := client.ConnectI3(""); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to connect to i3: %v\n", err)
-				}
+	if wmProgram != "" {
+		if _, err := client.StartApp(wmProgram, wmArgs); err != nil {
+			// Log warning but don't fail - window manager is optional
+			slog.Warn("failed to start window manager", "wm", opts.WMName, "err", err)
+		} else {
+			client.wmName = filepath.Base(wmProgram)
+		}
+		
+		// If we started i3, wait a bit and try to connect
+		if strings.Contains(wmProgram, "i3") {
+			time.Sleep(500 * time.Millisecond)
+			if err := client.ConnectI3(""); err != nil {
+				slog.Warn("failed to connect to i3", "err", err)
 			}
 		}
 	} else {
 		// Try to connect to i3 if it's already running
 		client.ConnectI3("")
 	}
+
+	if confine.Active() {
+		if err := client.setConfinementFloor(context.Background(), confine); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to confine the pointer: %w", err)
+		}
+	}
 	
 	return client, nil
 }
 
+// initXTest checks that the XTEST extension used for input is available
+func initXTest(conn *x.Conn) error {
+	extReply, err := x.QueryExtension(conn, "XTEST").Reply(conn)
+	if err != nil {
+		return fmt.Errorf("failed to query XTEST extension: %w", err)
+	}
+	if !extReply.Present {
+		return fmt.Errorf("XTEST extension not present (use observer mode for screenshots only)")
+	}
+
+	// Query XTEST version
+	cookie := test.GetVersion(conn, test.MajorVersion, test.MinorVersion)
+	if _, err := cookie.Reply(conn); err != nil {
+		return fmt.Errorf("failed to get XTEST version: %w", err)
+	}
+	return nil
+}
+
+// Observer returns true if the client was connected without XTEST and
+// refuses input
+func (c *Client) Observer() bool {
+	return c.observer
+}
+
 // Close closes the X11 connection
 func (c *Client) Close() error {
+	// Region watches would keep capturing from a closed connection
+	c.watches.stopAll()
+	
 	if c.conn != nil {
+		// XTEST keys stay down after we disconnect unless released
+		if !c.observer {
+			if err := c.ReleaseAll(); err != nil {
+				slog.Warn("failed to release held input", "err", err)
+			}
+		}
 		c.conn.Close()
 	}
 	
-	// No need to close i3 connection as the library manages it internally
+	// No need to close i3 connection as the library manages it internally,
+	// but a reconnect in the background stops
+	c.i3.mu.Lock()
+	c.i3.closed = true
+	c.i3.mu.Unlock()
 	
 	// If we started Xvfb, stop it
 	if c.xvfbProcess != nil {
@@ -206,16 +364,30 @@ func (c *Client) Close() error {
 		c.xvfbProcess.Wait()
 	}
 	
+	c.removeAppFiles()
+	if c.home != "" {
+		os.RemoveAll(c.home)
+	}
 	return nil
 }
 
 // GetScreenInfo returns information about the screen
 func (c *Client) GetScreenInfo() (*ScreenInfo, error) {
-	return &ScreenInfo{
-		Width:  c.screen.WidthInPixels,
-		Height: c.screen.HeightInPixels,
-		Root:   c.root,
-	}, nil
+	return c.GetScreenInfoContext(context.Background())
+}
+
+// GetScreenInfoContext is like GetScreenInfo but gives up when ctx is done
+func (c *Client) GetScreenInfoContext(ctx context.Context) (*ScreenInfo, error) {
+	info := &ScreenInfo{
+		Width:     c.screen.WidthInPixels,
+		Height:    c.screen.HeightInPixels,
+		Root:      c.root,
+		Depth:     c.screen.RootDepth,
+		Display:   c.display,
+		WMName:    c.DetectWM(ctx),
+		Workspace: c.currentWorkspace(ctx),
+	}
+	return info, nil
 }
 
 // GetDisplay returns the display string we're connected to
@@ -2,6 +2,7 @@ package x11
 
 import (
 	"os"
+	"os/exec"
 	"testing"
 )
 
@@ -86,8 +87,15 @@ func TestGetScreenInfo(t *testing.T) {
 	if info.Width == 0 || info.Height == 0 {
 		t.Errorf("Invalid screen dimensions: %dx%d", info.Width, info.Height)
 	}
+	if info.Depth == 0 {
+		t.Error("Expected a color depth")
+	}
+	if info.Display != client.GetDisplay() {
+		t.Errorf("Display mismatch: %q vs %q", info.Display, client.GetDisplay())
+	}
 
-	t.Logf("Screen info: %dx%d on display %s", info.Width, info.Height, client.GetDisplay())
+	t.Logf("Screen info: %dx%d depth %d on display %s, wm=%q workspace=%q",
+		info.Width, info.Height, info.Depth, info.Display, info.WMName, info.Workspace)
 }
 
 // TestMultipleConnections tests that we can handle multiple connections
@@ -124,4 +132,47 @@ func TestMultipleConnections(t *testing.T) {
 	if display1 == display2 {
 		t.Errorf("Expected different displays, got %s for both", display1)
 	}
-}
\ No newline at end of file
+}
+
+// TestScreenshotFocusedWindow checks that the capture is cropped to the focused window
+func TestScreenshotFocusedWindow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode")
+	}
+	if _, err := exec.LookPath("xterm"); err != nil {
+		t.Skip("xterm not available")
+	}
+
+	client, err := ConnectWithOptions(ConnectOptions{StartXvfb: true, Resolution: "800x600"})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	pid, err := client.StartApp("xterm", []string{"-geometry", "40x10+50+60"})
+	if err != nil {
+		t.Fatalf("Failed to start xterm: %v", err)
+	}
+	defer client.StopApp(pid)
+	client.Wait(1000)
+
+	windows, err := client.ListWindows()
+	if err != nil || len(windows) == 0 {
+		t.Fatalf("no windows found: %v", err)
+	}
+	if err := client.FocusWindow(windows[0].ID); err != nil {
+		t.Fatalf("Failed to focus window: %v", err)
+	}
+
+	img, win, err := client.ScreenshotFocusedWindow()
+	if err != nil {
+		t.Fatalf("ScreenshotFocusedWindow failed: %v", err)
+	}
+	if win == 0 {
+		t.Error("expected the captured window ID")
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() >= 800 || bounds.Min.X < 50 || bounds.Min.Y < 60 {
+		t.Errorf("capture %v is not cropped to the xterm at +50+60", bounds)
+	}
+}
@@ -0,0 +1,236 @@
+package x11
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+// maxRecentXErrors bounds the X errors kept for XErrorSummary
+const maxRecentXErrors = 20
+
+// xErrorLogQueue bounds the X errors waiting to be logged; beyond it they
+// are dropped and counted
+const xErrorLogQueue = 64
+
+// How X errors are logged by default, see ParseXErrorLog
+const (
+	XErrorLogUnchecked = "unchecked" // Errors of requests nobody waits for as warnings, the others at debug level
+	XErrorLogAll       = "all"       // All errors as warnings
+	XErrorLogOff       = "off"       // Errors are only counted
+)
+
+// xErrorPattern takes the error and request names out of x.Error.Error()
+var xErrorPattern = regexp.MustCompile(`^x\.Error: (\d+)(?: \((\w+)\))?, .*major code: (\d+)(?: \((\w+)\))?, minor code: (\d+)(?: \((\w+)\))?$`)
+
+// XError is an X protocol error the server sent this client
+type XError struct {
+	Time     time.Time
+	Name     string // Error name such as BadWindow
+	Request  string // Request that failed such as ConfigureWindow, prefixed by its extension
+	Resource uint32 // Resource the request named, for errors like BadWindow
+	Checked  bool   // A caller waited for the request and got the error
+	Action   string // What the client was doing, see WithAction
+}
+
+// String describes the error, e.g. "BadWindow in ConfigureWindow on
+// 0x1a00003 during x11_click_at"
+func (e XError) String() string {
+	s := e.Name + " in " + e.Request
+	if e.Resource != 0 {
+		s += fmt.Sprintf(" on 0x%x", e.Resource)
+	}
+	if e.Action != "" {
+		s += " during " + e.Action
+	}
+	return s
+}
+
+// XErrorSummary is what X errors the client got
+type XErrorSummary struct {
+	Total  uint64
+	Counts map[string]int // By error name
+	Recent []XError       // Oldest first, at most maxRecentXErrors
+}
+
+// xErrorState records X errors and the action requests are made for
+type xErrorState struct {
+	mu      sync.Mutex
+	handler func(XError)
+	counts  map[string]int
+	recent  []XError
+	action  string // Last action requests were awaited for
+}
+
+// actionKey is the context key of WithAction
+type actionKey struct{}
+
+// WithAction returns a context naming the action requests made with it
+// are for, such as a tool call. X errors they cause name the action, and
+// errors of requests nobody waits for name the last one.
+func WithAction(ctx context.Context, action string) context.Context {
+	return context.WithValue(ctx, actionKey{}, action)
+}
+
+// ParseXErrorLog validates how X errors are logged, defaulting to unchecked
+func ParseXErrorLog(name string) (string, error) {
+	switch mode := strings.ToLower(name); mode {
+	case "":
+		return XErrorLogUnchecked, nil
+	case XErrorLogUnchecked, XErrorLogAll, XErrorLogOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown X error log mode: %s (available: unchecked, all, off)", name)
+}
+
+// xErrorLogEntry is an X error waiting to be logged at a level
+type xErrorLogEntry struct {
+	level slog.Level
+	err   XError
+}
+
+// xErrorLogs hands X errors from the connection's reader to the goroutine
+// logging them, so a slow log handler doesn't hold up event delivery
+var xErrorLogs struct {
+	once    sync.Once
+	queue   chan xErrorLogEntry
+	dropped atomic.Uint64
+}
+
+// logXErrors logs the queued X errors and how many were dropped
+func logXErrors() {
+	for entry := range xErrorLogs.queue {
+		if n := xErrorLogs.dropped.Swap(0); n > 0 {
+			slog.Warn("X errors not logged, too many at once", "dropped", n)
+		}
+		e := entry.err
+		slog.Log(context.Background(), entry.level, "X error", "error", e.Name, "request", e.Request,
+			"resource", fmt.Sprintf("0x%x", e.Resource), "action", e.Action, "checked", e.Checked)
+	}
+}
+
+// XErrorLogger returns a handler for SetXErrorHandler logging errors as
+// mode says, or nil for XErrorLogOff. Errors are logged on a goroutine of
+// their own.
+func XErrorLogger(mode string) func(XError) {
+	if mode == XErrorLogOff {
+		return nil
+	}
+	return func(e XError) {
+		level := slog.LevelWarn
+		if mode == XErrorLogUnchecked && e.Checked {
+			level = slog.LevelDebug
+		}
+		if !slog.Default().Enabled(context.Background(), level) {
+			return
+		}
+		xErrorLogs.once.Do(func() {
+			xErrorLogs.queue = make(chan xErrorLogEntry, xErrorLogQueue)
+			go logXErrors()
+		})
+		select {
+		case xErrorLogs.queue <- xErrorLogEntry{level, e}:
+		default:
+			xErrorLogs.dropped.Add(1)
+		}
+	}
+}
+
+// SetXErrorHandler sets what is done with every X error besides recording
+// it; ConnectWithOptions installs an XErrorLogger. fn runs on the goroutine
+// reading the connection for errors of requests nobody waits for, so it
+// must not make X requests.
+func (c *Client) SetXErrorHandler(fn func(XError)) {
+	c.xErrs.mu.Lock()
+	defer c.xErrs.mu.Unlock()
+	c.xErrs.handler = fn
+}
+
+// XErrorSummary returns the X errors received so far
+func (c *Client) XErrorSummary() XErrorSummary {
+	c.xErrs.mu.Lock()
+	defer c.xErrs.mu.Unlock()
+	return XErrorSummary{
+		Total:  c.xErrors.Load(),
+		Counts: maps.Clone(c.xErrs.counts),
+		Recent: slices.Clone(c.xErrs.recent),
+	}
+}
+
+// noteAction remembers the action of ctx for later unchecked errors
+func (c *Client) noteAction(ctx context.Context) {
+	if action, ok := ctx.Value(actionKey{}).(string); ok {
+		c.xErrs.mu.Lock()
+		c.xErrs.action = action
+		c.xErrs.mu.Unlock()
+	}
+}
+
+// recordXError counts an X error, keeps it for XErrorSummary and passes it
+// to the handler. Checked errors name the action of ctx, unchecked ones the
+// last action.
+func (c *Client) recordXError(ctx context.Context, err *x.Error, checked bool) {
+	c.xErrors.Add(1)
+	e := describeXError(err)
+	e.Time = time.Now()
+	e.Checked = checked
+
+	c.xErrs.mu.Lock()
+	if action, ok := ctx.Value(actionKey{}).(string); ok && checked {
+		e.Action = action
+	} else {
+		e.Action = c.xErrs.action
+	}
+	if c.xErrs.counts == nil {
+		c.xErrs.counts = make(map[string]int)
+	}
+	c.xErrs.counts[e.Name]++
+	c.xErrs.recent = append(c.xErrs.recent, e)
+	if len(c.xErrs.recent) > maxRecentXErrors {
+		c.xErrs.recent = slices.Clone(c.xErrs.recent[len(c.xErrs.recent)-maxRecentXErrors:])
+	}
+	handler := c.xErrs.handler
+	c.xErrs.mu.Unlock()
+
+	if handler != nil {
+		handler(e)
+	}
+}
+
+// describeXError names an error and its request. x.Error knows the names
+// through its connection; errors without a request, which no server sends,
+// are described by their codes.
+func describeXError(err *x.Error) XError {
+	e := XError{
+		Name:     "error " + strconv.Itoa(int(err.Code)),
+		Request:  fmt.Sprintf("request %d.%d", err.MajorCode, err.MinorCode),
+		Resource: err.ResourceID,
+	}
+	if err.MajorCode == 0 {
+		return e
+	}
+	m := xErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return e
+	}
+	if m[2] != "" {
+		e.Name = m[2]
+	}
+	switch {
+	case err.MajorCode < 128 && m[4] != "":
+		e.Request = m[4]
+	case err.MajorCode >= 128 && m[4] != "" && m[6] != "":
+		e.Request = m[4] + " " + m[6]
+	}
+	return e
+}
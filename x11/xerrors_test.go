@@ -0,0 +1,118 @@
+package x11
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	x "github.com/linuxdeepin/go-x11-client"
+)
+
+func TestParseXErrorLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", XErrorLogUnchecked, false},
+		{"ALL", XErrorLogAll, false},
+		{"off", XErrorLogOff, false},
+		{"loud", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseXErrorLog(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseXErrorLog(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestDescribeXError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *x.Error
+		want string
+	}{
+		{"core", &x.Error{Code: x.WindowErrorCode, MajorCode: x.ConfigureWindowOpcode, ResourceID: 0x1a00003}, "BadWindow in ConfigureWindow on 0x1a00003"},
+		{"no resource", &x.Error{Code: x.MatchErrorCode, MajorCode: x.SetInputFocusOpcode}, "BadMatch in SetInputFocus"},
+		{"no request", &x.Error{Code: x.ValueErrorCode}, "error 2 in request 0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeXError(tt.err).String(); got != tt.want {
+				t.Errorf("describeXError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordXError(t *testing.T) {
+	client := &Client{}
+	var handled []XError
+	client.SetXErrorHandler(func(e XError) { handled = append(handled, e) })
+
+	ctx := WithAction(context.Background(), "x11_click_at")
+	badWindow := &x.Error{Code: x.WindowErrorCode, MajorCode: x.ConfigureWindowOpcode, ResourceID: 7}
+	awaitCheck(client, ctx, func() error { return badWindow })
+	// An unchecked error names the last action requests were made for
+	client.recordXError(context.Background(), &x.Error{Code: x.MatchErrorCode, MajorCode: x.SetInputFocusOpcode}, false)
+
+	if len(handled) != 2 || !handled[0].Checked || handled[0].Action != "x11_click_at" || handled[1].Checked || handled[1].Action != "x11_click_at" {
+		t.Fatalf("handled %+v, want a checked and an unchecked error during x11_click_at", handled)
+	}
+	for i := 0; i < maxRecentXErrors; i++ {
+		client.recordXError(ctx, badWindow, true)
+	}
+	s := client.XErrorSummary()
+	if s.Total != maxRecentXErrors+2 || s.Counts["BadWindow"] != maxRecentXErrors+1 || s.Counts["BadMatch"] != 1 || len(s.Recent) != maxRecentXErrors {
+		t.Errorf("XErrorSummary() = %d errors, counts %v, %d recent", s.Total, s.Counts, len(s.Recent))
+	}
+	if got := fmt.Sprint(s.Recent[0]); got != "BadWindow in ConfigureWindow on 0x7 during x11_click_at" {
+		t.Errorf("oldest recent error = %q", got)
+	}
+}
+
+func TestXErrorLogger(t *testing.T) {
+	if XErrorLogger(XErrorLogOff) != nil {
+		t.Error("XErrorLogger(off) returned a handler, want none")
+	}
+
+	var buf syncBuffer
+	saved := slog.Default()
+	defer slog.SetDefault(saved)
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	log := XErrorLogger(XErrorLogUnchecked)
+	log(XError{Name: "BadMatch", Request: "SetInputFocus", Checked: true})
+	log(XError{Name: "BadWindow", Request: "ConfigureWindow"})
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(buf.String(), "BadWindow") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := buf.String(); !strings.Contains(got, "level=WARN") || !strings.Contains(got, "BadWindow") || strings.Contains(got, "BadMatch") {
+		t.Errorf("log = %q, want only the unchecked BadWindow as a warning", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for the logging goroutine and the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}